@@ -80,6 +80,28 @@ export class ChatAPI {
 		return result;
 	}
 
+	/**
+	 * Exchange a list request for every page of the result, following the
+	 * cursors of the server, and return the items of all the pages.
+	 */
+	async exchangeList<T>(
+		method: string,
+		key: string,
+		opts?: { sessionId?: string },
+	): Promise<T[]> {
+		const items: T[] = [];
+		let cursor: string | undefined;
+		do {
+			const params = cursor ? { cursor } : {};
+			const page = (await this.exchange(method, params, opts)) as {
+				nextCursor?: string;
+			} & Record<string, unknown>;
+			items.push(...((page[key] as T[] | undefined) ?? []));
+			cursor = page.nextCursor === cursor ? undefined : page.nextCursor;
+		} while (cursor);
+		return items;
+	}
+
 	async callMCPTool<T>(
 		name: string,
 		opts?: {
@@ -557,24 +579,22 @@ export class ChatService {
 
 	listPrompts = async (opts?: { useDefaultSession?: boolean }) => {
 		const sessionId = opts?.useDefaultSession ? undefined : this.chatId;
-		return (await this.api.exchange(
+		const prompts = await this.api.exchangeList<Prompt>(
 			"prompts/list",
-			{},
-			{
-				sessionId,
-			},
-		)) as Prompts;
+			"prompts",
+			{ sessionId },
+		);
+		return { prompts } as Prompts;
 	};
 
 	listResources = async (opts?: { useDefaultSession?: boolean }) => {
 		const sessionId = opts?.useDefaultSession ? undefined : this.chatId;
-		return (await this.api.exchange(
+		const resources = await this.api.exchangeList<Resource>(
 			"resources/list",
-			{},
-			{
-				sessionId,
-			},
-		)) as Resources;
+			"resources",
+			{ sessionId },
+		);
+		return { resources } as Resources;
 	};
 
 	private subscribe(chatId: string) {
@@ -442,21 +442,32 @@ export class SimpleClient {
 				: [opts.prefix]
 			: undefined;
 
-		const { result } = await this.exchange(
-			"resources/list",
-			{
-				...(prefixes && {
-					_meta: {
-						"ai.nanobot": {
-							prefix: prefixes.length === 1 ? prefixes[0] : prefixes,
+		const list = async (cursor?: string) => {
+			const { result } = await this.exchange(
+				"resources/list",
+				{
+					...(prefixes && {
+						_meta: {
+							"ai.nanobot": {
+								prefix: prefixes.length === 1 ? prefixes[0] : prefixes,
+							},
 						},
-					},
-				}),
-			},
-			{ abort: opts?.abort },
-		);
+					}),
+					...(cursor && { cursor }),
+				},
+				{ abort: opts?.abort },
+			);
+			return result as T;
+		};
 
-		const typedResult = result as T;
+		// Follow the cursors of the server until every page is listed
+		const typedResult = await list();
+		for (let cursor = typedResult.nextCursor; cursor; ) {
+			const page = await list(cursor);
+			typedResult.resources.push(...page.resources);
+			cursor = page.nextCursor === cursor ? undefined : page.nextCursor;
+		}
+		delete typedResult.nextCursor;
 
 		if (prefixes) {
 			return {
@@ -165,10 +165,12 @@ export interface Notification {
 
 export interface Prompts {
 	prompts: Prompt[];
+	nextCursor?: string;
 }
 
 export interface Resources {
 	resources: Resource[];
+	nextCursor?: string;
 }
 
 export interface ResourceContents {
@@ -277,10 +279,15 @@ export interface Elicitation {
 
 export interface ElicitationResult {
 	action: 'accept' | 'decline' | 'cancel';
-	content?: { [key: string]: string | number | boolean };
+	content?: { [key: string]: string | number | boolean | string[] };
 }
 
-export type PrimitiveSchemaDefinition = StringSchema | NumberSchema | BooleanSchema | EnumSchema;
+export type PrimitiveSchemaDefinition =
+	| StringSchema
+	| NumberSchema
+	| BooleanSchema
+	| EnumSchema
+	| MultiSelectSchema;
 
 export interface StringSchema {
 	type: 'string';
@@ -289,6 +296,8 @@ export interface StringSchema {
 	minLength?: number;
 	maxLength?: number;
 	format?: 'email' | 'uri' | 'date' | 'date-time';
+	pattern?: string;
+	default?: string;
 }
 
 export interface NumberSchema {
@@ -297,6 +306,7 @@ export interface NumberSchema {
 	description?: string;
 	minimum?: number;
 	maximum?: number;
+	default?: number;
 }
 
 export interface BooleanSchema {
@@ -312,6 +322,21 @@ export interface EnumSchema {
 	description?: string;
 	enum: string[];
 	enumNames?: string[]; // Display names for enum values
+	default?: string;
+}
+
+export interface MultiSelectSchema {
+	type: 'array';
+	title?: string;
+	description?: string;
+	items: {
+		type: 'string';
+		enum: string[];
+		enumNames?: string[]; // Display names for enum values
+	};
+	minItems?: number;
+	maxItems?: number;
+	default?: string[];
 }
 
 export const MessageMimeType = 'application/vnd.nanobot.chat.message+json';
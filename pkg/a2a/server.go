@@ -0,0 +1,483 @@
+// Package a2a serves the published agents of nanobot serve over the A2A
+// (Agent-to-Agent) protocol. Every agent has an agent card, and every
+// message an A2A client sends is a task. The context of a task is the
+// session the agent answers in, which the server talks to over the loopback
+// URL of the server, and the files the agent writes to the session are the
+// artifacts of the task.
+package a2a
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/types"
+	"github.com/obot-platform/nanobot/pkg/uuid"
+)
+
+const (
+	// CardPath is the agent card of the first entrypoint agent.
+	CardPath = "/.well-known/agent-card.json"
+	// CardsPathPrefix is the path of the agent cards of all published
+	// agents, at /.well-known/agent-card/<agent>.json.
+	CardsPathPrefix = "/.well-known/agent-card/"
+	// PathPrefix is the JSON-RPC endpoint of the published agents, at
+	// /a2a/<agent>. /a2a is the endpoint of the first entrypoint agent.
+	PathPrefix = "/a2a"
+
+	maxPayload = 32 << 20
+	runTimeout = 30 * time.Minute
+	// taskRetention is how long finished tasks can still be read with
+	// tasks/get.
+	taskRetention = time.Hour
+)
+
+// chat is a session the agent answers the messages of a context in.
+type chat interface {
+	id() string
+	// send returns the reply of the agent to the message and the files it
+	// wrote to the session while answering.
+	send(ctx context.Context, msg Message) (string, []Artifact, error)
+	close()
+}
+
+// Server serves the agent cards and the JSON-RPC endpoint of the published
+// agents.
+type Server struct {
+	ctx         context.Context
+	wg          sync.WaitGroup
+	loopbackURL string
+	config      func(ctx context.Context) (types.Config, error)
+
+	mu    sync.Mutex
+	tasks map[string]*task
+	// running is the task in progress of every context.
+	running map[string]string
+
+	// open resumes the session of contextID, or creates one when it is
+	// empty. The authorization is the one of the request of the client.
+	open func(ctx context.Context, agent, contextID, authorization string) (chat, error)
+}
+
+type task struct {
+	Task
+	agent string
+	// owner is the user that sent the message of the task, the only one that
+	// can read or cancel it.
+	owner    string
+	cancel   context.CancelFunc
+	done     chan struct{}
+	finished time.Time
+}
+
+// NewServer creates the A2A server of the agents of config. Tasks in
+// progress are cancelled with ctx.
+func NewServer(ctx context.Context, loopbackURL string, config func(ctx context.Context) (types.Config, error)) *Server {
+	s := &Server{
+		ctx:         ctx,
+		loopbackURL: loopbackURL,
+		config:      config,
+		tasks:       map[string]*task{},
+		running:     map[string]string{},
+	}
+	s.open = s.openSession
+	return s
+}
+
+// Wait waits for the tasks in progress.
+func (s *Server) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Server) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == CardPath || strings.HasPrefix(req.URL.Path, CardsPathPrefix) {
+		s.serveCard(rw, req)
+		return
+	}
+
+	name, ok := strings.CutPrefix(req.URL.Path, PathPrefix)
+	if !ok || (name != "" && !strings.HasPrefix(name, "/")) {
+		http.NotFound(rw, req)
+		return
+	}
+	_, agent, ok := s.agent(rw, req, strings.TrimPrefix(name, "/"))
+	if !ok {
+		return
+	}
+	if req.Method != http.MethodPost {
+		rw.Header().Set("Allow", http.MethodPost)
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxPayload+1))
+	if err != nil {
+		http.Error(rw, "failed to read payload", http.StatusBadRequest)
+		return
+	} else if len(body) > maxPayload {
+		http.Error(rw, "payload too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var rpc request
+	if err := json.Unmarshal(body, &rpc); err != nil {
+		writeResponse(rw, response{Error: errParse})
+		return
+	}
+	if rpc.JSONRPC != "2.0" || rpc.Method == "" {
+		writeResponse(rw, response{ID: rpc.ID, Error: errInvalidRequest})
+		return
+	}
+
+	result, err := s.call(req, agent, rpc)
+	resp := response{ID: rpc.ID, Result: result}
+	if rpcErr := (*Error)(nil); errors.As(err, &rpcErr) {
+		resp.Error = rpcErr
+	} else if err != nil {
+		slog.Error("a2a: request failed", "agent", agent, "method", rpc.Method, "error", err)
+		resp.Error = &Error{Code: -32603, Message: err.Error()}
+	}
+	writeResponse(rw, resp)
+}
+
+func (s *Server) call(req *http.Request, agent string, rpc request) (any, error) {
+	switch rpc.Method {
+	case "message/send":
+		var params MessageSendParams
+		if err := json.Unmarshal(rpc.Params, &params); err != nil {
+			return nil, errInvalidParams("invalid message/send params: " + err.Error())
+		}
+		return s.sendMessage(req, agent, params)
+	case "tasks/get":
+		var params TaskQueryParams
+		if err := json.Unmarshal(rpc.Params, &params); err != nil {
+			return nil, errInvalidParams("invalid tasks/get params: " + err.Error())
+		}
+		return s.getTask(agent, requestUser(req), params.ID, params.HistoryLength)
+	case "tasks/cancel":
+		var params TaskIDParams
+		if err := json.Unmarshal(rpc.Params, &params); err != nil {
+			return nil, errInvalidParams("invalid tasks/cancel params: " + err.Error())
+		}
+		return s.cancelTask(agent, requestUser(req), params.ID)
+	default:
+		return nil, errMethodNotFound(rpc.Method)
+	}
+}
+
+// agent returns the published agent of the path, which is the first
+// entrypoint agent for an empty name.
+func (s *Server) agent(rw http.ResponseWriter, req *http.Request, name string) (types.Config, string, bool) {
+	cfg, err := s.config(req.Context())
+	if err != nil {
+		slog.Error("a2a: failed to load config", "error", err)
+		http.Error(rw, "failed to load config", http.StatusInternalServerError)
+		return cfg, "", false
+	}
+	agents := publishedAgents(cfg)
+	if name == "" && len(agents) > 0 {
+		return cfg, agents[0], true
+	}
+	if !slices.Contains(agents, name) {
+		http.NotFound(rw, req)
+		return cfg, "", false
+	}
+	return cfg, name, true
+}
+
+func (s *Server) serveCard(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		rw.Header().Set("Allow", http.MethodGet)
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimSuffix(strings.TrimPrefix(req.URL.Path, CardsPathPrefix), ".json")
+	if req.URL.Path == CardPath {
+		name = ""
+	}
+	cfg, agent, ok := s.agent(rw, req, name)
+	if !ok {
+		return
+	}
+
+	endpoint := baseURL(req) + PathPrefix
+	if name != "" {
+		endpoint += "/" + agent
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(rw).Encode(agentCard(cfg, agent, endpoint))
+}
+
+// publishedAgents returns the entrypoint agents of the config, or its only
+// agent when it has no entrypoint.
+func publishedAgents(cfg types.Config) []string {
+	if len(cfg.Publish.Entrypoint) > 0 {
+		var agents []string
+		for _, name := range cfg.Publish.Entrypoint {
+			if _, ok := cfg.Agents[name]; ok {
+				agents = append(agents, name)
+			}
+		}
+		return agents
+	}
+	if len(cfg.Agents) == 1 {
+		return slices.Collect(maps.Keys(cfg.Agents))
+	}
+	return nil
+}
+
+func agentCard(cfg types.Config, name, endpoint string) AgentCard {
+	agent := cfg.Agents[name]
+	card := AgentCard{
+		ProtocolVersion:    ProtocolVersion,
+		Name:               agent.Name,
+		Description:        agent.Description,
+		URL:                endpoint,
+		PreferredTransport: "JSONRPC",
+		IconURL:            agent.Icon,
+		Version:            cfg.Publish.Version,
+		DefaultInputModes:  []string{"text/plain", "application/json", "*/*"},
+		DefaultOutputModes: []string{"text/plain", "*/*"},
+		Skills: []AgentSkill{{
+			ID:          name,
+			Name:        agent.Name,
+			Description: agent.Description,
+			Tags:        []string{"chat"},
+			Examples:    agent.StarterMessages,
+		}},
+	}
+	if card.Name == "" {
+		card.Name = name
+		card.Skills[0].Name = name
+	}
+	if card.Description == "" {
+		card.Description = cfg.Publish.Name
+	}
+	if card.Skills[0].Description == "" {
+		card.Skills[0].Description = types.AgentToolDescription
+	}
+	if card.Version == "" {
+		card.Version = "0.0.1"
+	}
+	return card
+}
+
+func (s *Server) sendMessage(req *http.Request, agent string, params MessageSendParams) (*Task, error) {
+	msg := params.Message
+	if msg.Role != "user" {
+		return nil, errInvalidParams("the role of the message must be user")
+	}
+	if len(msg.Parts) == 0 {
+		return nil, errInvalidParams("the message has no parts")
+	}
+
+	contextID := msg.ContextID
+	if msg.TaskID != "" {
+		prev, err := s.getTask(agent, requestUser(req), msg.TaskID, nil)
+		if err != nil {
+			return nil, err
+		}
+		if contextID != "" && contextID != prev.ContextID {
+			return nil, errInvalidParams("the task " + msg.TaskID + " is not in context " + contextID)
+		}
+		contextID = prev.ContextID
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, runTimeout)
+	c, err := s.open(ctx, agent, contextID, req.Header.Get("Authorization"))
+	if err != nil {
+		cancel()
+		if contextID != "" {
+			return nil, errInvalidParams(fmt.Sprintf("failed to resume context %s: %v", contextID, err))
+		}
+		return nil, err
+	}
+
+	msg.Kind = "message"
+	msg.ContextID = c.id()
+	if msg.MessageID == "" {
+		msg.MessageID = uuid.String()
+	}
+	t := &task{
+		Task: Task{
+			Kind:      "task",
+			ID:        uuid.String(),
+			ContextID: c.id(),
+			Status:    status(TaskWorking, nil),
+		},
+		agent:  agent,
+		owner:  requestUser(req),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	msg.TaskID = t.ID
+	t.History = []Message{msg}
+
+	if err := s.start(t); err != nil {
+		cancel()
+		c.close()
+		return nil, err
+	}
+
+	s.wg.Go(func() {
+		defer cancel()
+		defer c.close()
+		reply, artifacts, err := c.send(ctx, msg)
+		s.finish(t, reply, artifacts, err)
+	})
+
+	if params.Configuration == nil || params.Configuration.Blocking == nil || *params.Configuration.Blocking {
+		select {
+		case <-t.done:
+		case <-req.Context().Done():
+		}
+	}
+
+	var historyLength *int
+	if params.Configuration != nil {
+		historyLength = params.Configuration.HistoryLength
+	}
+	return s.getTask(agent, t.owner, t.ID, historyLength)
+}
+
+// start adds the task to the tasks in progress. A context has one task in
+// progress at a time.
+func (s *Server) start(t *task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune()
+	if running, ok := s.running[t.ContextID]; ok {
+		return errInvalidParams("context " + t.ContextID + " has task " + running + " in progress")
+	}
+	s.running[t.ContextID] = t.ID
+	s.tasks[t.ID] = t
+	return nil
+}
+
+func (s *Server) finish(t *task, reply string, artifacts []Artifact, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer close(t.done)
+
+	delete(s.running, t.ContextID)
+	t.finished = time.Now()
+	if t.Status.State == TaskCanceled {
+		return
+	}
+
+	t.Artifacts = artifacts
+	if err != nil {
+		slog.Error("a2a: task failed", "task", t.ID, "context", t.ContextID, "error", err)
+		t.Status = status(TaskFailed, agentMessage(t, err.Error()))
+		return
+	}
+	answer := agentMessage(t, reply)
+	t.History = append(t.History, *answer)
+	t.Status = status(TaskCompleted, answer)
+}
+
+// prune drops the tasks that finished longer than taskRetention ago.
+func (s *Server) prune() {
+	for id, t := range s.tasks {
+		if !t.finished.IsZero() && time.Since(t.finished) > taskRetention {
+			delete(s.tasks, id)
+		}
+	}
+}
+
+// requestUser returns the ID of the authenticated user of the request, empty
+// when the server doesn't authenticate users.
+func requestUser(req *http.Request) string {
+	return types.NanobotContext(req.Context()).User.ID
+}
+
+func (s *Server) getTask(agent, owner, id string, historyLength *int) (*Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tasks[id]
+	if !ok || t.agent != agent || t.owner != owner {
+		return nil, errTaskNotFound
+	}
+
+	result := t.Task
+	result.Artifacts = slices.Clone(t.Artifacts)
+	result.History = slices.Clone(t.History)
+	if historyLength != nil && *historyLength >= 0 && *historyLength < len(result.History) {
+		result.History = result.History[len(result.History)-*historyLength:]
+	}
+	return &result, nil
+}
+
+func (s *Server) cancelTask(agent, owner, id string) (*Task, error) {
+	s.mu.Lock()
+	t, ok := s.tasks[id]
+	if !ok || t.agent != agent || t.owner != owner {
+		s.mu.Unlock()
+		return nil, errTaskNotFound
+	}
+	if t.Status.State.Terminal() {
+		s.mu.Unlock()
+		return nil, errTaskNotCancelable
+	}
+	t.Status = status(TaskCanceled, nil)
+	t.cancel()
+	s.mu.Unlock()
+
+	<-t.done
+	return s.getTask(agent, owner, id, nil)
+}
+
+func status(state TaskState, msg *Message) TaskStatus {
+	return TaskStatus{
+		State:     state,
+		Message:   msg,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+func agentMessage(t *task, text string) *Message {
+	return &Message{
+		Kind:      "message",
+		MessageID: uuid.String(),
+		Role:      "agent",
+		Parts:     []Part{{Kind: "text", Text: text}},
+		ContextID: t.ContextID,
+		TaskID:    t.ID,
+	}
+}
+
+func writeResponse(rw http.ResponseWriter, resp response) {
+	resp.JSONRPC = "2.0"
+	if resp.ID == nil {
+		resp.ID = json.RawMessage("null")
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(rw).Encode(resp)
+}
+
+func baseURL(req *http.Request) string {
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+	if proto := req.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	host := req.Host
+	if forwarded := req.Header.Get("X-Forwarded-Host"); forwarded != "" {
+		host = forwarded
+	}
+	return scheme + "://" + host
+}
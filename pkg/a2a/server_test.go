@@ -0,0 +1,229 @@
+package a2a
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+type fakeChat struct {
+	contextID string
+	reply     func(ctx context.Context, msg Message) (string, []Artifact, error)
+}
+
+func (f *fakeChat) id() string {
+	return f.contextID
+}
+
+func (f *fakeChat) send(ctx context.Context, msg Message) (string, []Artifact, error) {
+	return f.reply(ctx, msg)
+}
+
+func (f *fakeChat) close() {}
+
+func newTestServer(t *testing.T, reply func(ctx context.Context, msg Message) (string, []Artifact, error)) (*Server, *[]string) {
+	t.Helper()
+
+	cfg := types.Config{
+		Publish: types.Publish{Version: "1.2.3", Entrypoint: []string{"main", "helper"}},
+		Agents: map[string]types.Agent{
+			"main":   {HookAgent: types.HookAgent{Name: "Main", Description: "Does everything"}},
+			"helper": {},
+			"hidden": {},
+		},
+	}
+	s := NewServer(t.Context(), "", func(context.Context) (types.Config, error) {
+		return cfg, nil
+	})
+	t.Cleanup(s.Wait)
+
+	var (
+		mu     sync.Mutex
+		opened []string
+	)
+	s.open = func(_ context.Context, agent, contextID, _ string) (chat, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		opened = append(opened, agent+":"+contextID)
+		if contextID == "" {
+			contextID = "session-" + agent
+		}
+		return &fakeChat{contextID: contextID, reply: reply}, nil
+	}
+	return s, &opened
+}
+
+func rpc(t *testing.T, s *Server, path, method string, params any) (json.RawMessage, *Error) {
+	t.Helper()
+	return rpcAs(t, s, "", path, method, params)
+}
+
+// rpcAs calls the method as the user.
+func rpcAs(t *testing.T, s *Server, user, path, method string, params any) (json.RawMessage, *Error) {
+	t.Helper()
+
+	body, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(string(body)))
+	req = req.WithContext(types.WithNanobotContext(req.Context(), types.Context{User: mcp.User{ID: user}}))
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *Error          `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp.Result, resp.Error
+}
+
+func sendMessage(t *testing.T, s *Server, path string, params map[string]any) Task {
+	t.Helper()
+
+	result, rpcErr := rpc(t, s, path, "message/send", params)
+	if rpcErr != nil {
+		t.Fatalf("message/send failed: %v", rpcErr)
+	}
+	var task Task
+	if err := json.Unmarshal(result, &task); err != nil {
+		t.Fatal(err)
+	}
+	return task
+}
+
+func userMessage(text string, extra map[string]any) map[string]any {
+	msg := map[string]any{
+		"kind":      "message",
+		"messageId": "m1",
+		"role":      "user",
+		"parts":     []map[string]any{{"kind": "text", "text": text}},
+	}
+	for k, v := range extra {
+		msg[k] = v
+	}
+	return map[string]any{"message": msg}
+}
+
+func TestAgentCard(t *testing.T) {
+	s, _ := newTestServer(t, nil)
+
+	for _, tt := range []struct {
+		path, name, url string
+	}{
+		{CardPath, "Main", "http://example.com/a2a"},
+		{CardsPathPrefix + "helper.json", "helper", "http://example.com/a2a/helper"},
+	} {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		var card AgentCard
+		if err := json.Unmarshal(rec.Body.Bytes(), &card); err != nil {
+			t.Fatal(err)
+		}
+		if card.Name != tt.name || card.URL != tt.url || card.Version != "1.2.3" || card.ProtocolVersion != ProtocolVersion || len(card.Skills) != 1 {
+			t.Errorf("unexpected card of %s: %+v", tt.path, card)
+		}
+	}
+
+	for _, path := range []string{CardsPathPrefix + "hidden.json", PathPrefix + "/hidden", "/a2afoo"} {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected %s not to be found, got %d", path, rec.Code)
+		}
+	}
+}
+
+func TestMessageSend(t *testing.T) {
+	s, opened := newTestServer(t, func(_ context.Context, msg Message) (string, []Artifact, error) {
+		return "echo: " + msg.Parts[0].Text, []Artifact{{ArtifactID: "file:///report.md", Name: "report.md"}}, nil
+	})
+
+	task := sendMessage(t, s, PathPrefix, userMessage("hi", nil))
+	if task.Status.State != TaskCompleted || task.ContextID != "session-main" {
+		t.Fatalf("unexpected task %+v", task)
+	}
+	if task.Status.Message == nil || task.Status.Message.Parts[0].Text != "echo: hi" {
+		t.Errorf("unexpected reply %+v", task.Status.Message)
+	}
+	if len(task.Artifacts) != 1 || task.Artifacts[0].Name != "report.md" {
+		t.Errorf("unexpected artifacts %+v", task.Artifacts)
+	}
+	if len(task.History) != 2 || task.History[0].TaskID != task.ID {
+		t.Errorf("unexpected history %+v", task.History)
+	}
+
+	next := sendMessage(t, s, PathPrefix, userMessage("again", map[string]any{"taskId": task.ID}))
+	if next.ContextID != task.ContextID || next.ID == task.ID {
+		t.Errorf("expected a new task in the same context, got %+v", next)
+	}
+	if got := strings.Join(*opened, ","); got != "main:,main:session-main" {
+		t.Errorf("unexpected sessions %s", got)
+	}
+
+	if _, rpcErr := rpc(t, s, PathPrefix+"/helper", "tasks/get", map[string]any{"id": task.ID}); rpcErr == nil || rpcErr.Code != errTaskNotFound.Code {
+		t.Errorf("expected the task of another agent not to be found, got %v", rpcErr)
+	}
+	if _, rpcErr := rpcAs(t, s, "other", PathPrefix, "tasks/get", map[string]any{"id": task.ID}); rpcErr == nil || rpcErr.Code != errTaskNotFound.Code {
+		t.Errorf("expected the task of another user not to be found, got %v", rpcErr)
+	}
+	if _, rpcErr := rpc(t, s, PathPrefix, "tasks/cancel", map[string]any{"id": task.ID}); rpcErr == nil || rpcErr.Code != errTaskNotCancelable.Code {
+		t.Errorf("expected a completed task not to be cancelable, got %v", rpcErr)
+	}
+	if _, rpcErr := rpc(t, s, PathPrefix, "message/send", map[string]any{"message": map[string]any{"role": "agent"}}); rpcErr == nil || rpcErr.Code != -32602 {
+		t.Errorf("expected a message of the agent to be rejected, got %v", rpcErr)
+	}
+	if _, rpcErr := rpc(t, s, PathPrefix, "message/stream", nil); rpcErr == nil || rpcErr.Code != -32601 {
+		t.Errorf("expected an unknown method to be rejected, got %v", rpcErr)
+	}
+}
+
+func TestCancelTask(t *testing.T) {
+	started := make(chan struct{})
+	s, _ := newTestServer(t, func(ctx context.Context, _ Message) (string, []Artifact, error) {
+		close(started)
+		<-ctx.Done()
+		return "", nil, ctx.Err()
+	})
+
+	task := sendMessage(t, s, PathPrefix, map[string]any{
+		"message":       userMessage("work", nil)["message"],
+		"configuration": map[string]any{"blocking": false},
+	})
+	if task.Status.State != TaskWorking {
+		t.Fatalf("expected a working task, got %+v", task)
+	}
+	<-started
+
+	if _, rpcErr := rpc(t, s, PathPrefix, "message/send", userMessage("more", map[string]any{"contextId": task.ContextID})); rpcErr == nil {
+		t.Error("expected a message to a context with a task in progress to be rejected")
+	}
+
+	if _, rpcErr := rpcAs(t, s, "other", PathPrefix, "tasks/cancel", map[string]any{"id": task.ID}); rpcErr == nil || rpcErr.Code != errTaskNotFound.Code {
+		t.Errorf("expected another user not to cancel the task, got %v", rpcErr)
+	}
+
+	result, rpcErr := rpc(t, s, PathPrefix, "tasks/cancel", map[string]any{"id": task.ID})
+	if rpcErr != nil {
+		t.Fatal(rpcErr)
+	}
+	if err := json.Unmarshal(result, &task); err != nil {
+		t.Fatal(err)
+	}
+	if task.Status.State != TaskCanceled {
+		t.Errorf("expected the task to be canceled, got %+v", task.Status)
+	}
+}
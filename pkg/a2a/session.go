@@ -0,0 +1,238 @@
+package a2a
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/fileuri"
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+	"github.com/obot-platform/nanobot/pkg/uuid"
+)
+
+const (
+	maxFile = 20 << 20
+	// maxInlineArtifact is the largest artifact sent with its bytes. Larger
+	// ones are sent with their file URI, which a client reads from the
+	// session over MCP.
+	maxInlineArtifact = 1 << 20
+)
+
+// session is the session of a context, which the server talks to over the
+// loopback URL.
+type session struct {
+	client *mcp.Client
+}
+
+func (s *Server) openSession(ctx context.Context, agent, contextID, authorization string) (chat, error) {
+	var state *mcp.SessionState
+	if contextID != "" {
+		state = &mcp.SessionState{ID: contextID}
+	}
+
+	headers := map[string]string{
+		"X-Nanobot-Description":   "A2A: " + agent,
+		"X-Nanobot-Default-Agent": agent,
+	}
+	if authorization != "" {
+		headers["Authorization"] = authorization
+	}
+	client, err := mcp.NewClient(ctx, "nanobot-a2a", mcp.Server{
+		BaseURL: s.loopbackURL,
+		Headers: headers,
+	}, mcp.ClientOption{
+		ClientName:   "nanobot-a2a",
+		SessionState: state,
+		OnMessage: func(context.Context, mcp.Message) error {
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session: %w", err)
+	}
+	return &session{client: client}, nil
+}
+
+func (s *session) id() string {
+	return s.client.Session.ID()
+}
+
+func (s *session) close() {
+	s.client.Close(false)
+}
+
+func (s *session) send(ctx context.Context, msg Message) (string, []Artifact, error) {
+	var (
+		text        []string
+		attachments []types.Attachment
+		uploaded    = map[string]bool{}
+	)
+	for i, part := range msg.Parts {
+		switch part.Kind {
+		case "text":
+			text = append(text, part.Text)
+		case "data":
+			data, err := json.MarshalIndent(part.Data, "", "  ")
+			if err != nil {
+				return "", nil, fmt.Errorf("invalid data of part %d: %w", i, err)
+			}
+			text = append(text, "```json\n"+string(data)+"\n```")
+		case "file":
+			if part.File == nil {
+				return "", nil, fmt.Errorf("part %d has no file", i)
+			}
+			attachment, err := s.attach(ctx, *part.File)
+			if err != nil {
+				return "", nil, err
+			}
+			if attachment.URL == "" {
+				// Files outside of the session are passed to the agent
+				// as links.
+				text = append(text, fmt.Sprintf("Attached file %s: %s", part.File.Name, part.File.URI))
+				continue
+			}
+			uploaded[attachment.URL] = true
+			attachments = append(attachments, attachment)
+		default:
+			return "", nil, fmt.Errorf("part %d has unsupported kind %q", i, part.Kind)
+		}
+	}
+
+	started := time.Now().Truncate(time.Second)
+	result, err := s.client.Call(ctx, types.AgentTool+"nanobot", map[string]any{
+		"prompt":      strings.Join(text, "\n\n"),
+		"attachments": attachments,
+	}, mcp.CallOption{
+		ProgressToken: uuid.String(),
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	if result.IsError {
+		return "", nil, fmt.Errorf("agent failed: %s", resultText(result))
+	}
+
+	artifacts, err := s.artifacts(ctx, started, uploaded)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to list artifacts: %w", err)
+	}
+	return resultText(result), artifacts, nil
+}
+
+// attach uploads the bytes of the file to the files of the session with the
+// published uploadFile tool. Files of the session are attached with their
+// URI, and the attachment of other URIs has no URL.
+func (s *session) attach(ctx context.Context, file FileContent) (types.Attachment, error) {
+	if file.Bytes == "" {
+		if strings.HasPrefix(file.URI, "file:///") {
+			return types.Attachment{Name: file.Name, URL: file.URI, MimeType: file.MimeType}, nil
+		}
+		return types.Attachment{}, nil
+	}
+
+	data, err := base64.StdEncoding.DecodeString(file.Bytes)
+	if err != nil {
+		return types.Attachment{}, fmt.Errorf("invalid bytes of file %s: %w", file.Name, err)
+	}
+	if len(data) > maxFile {
+		return types.Attachment{}, fmt.Errorf("file %s is larger than %d bytes", file.Name, maxFile)
+	}
+
+	name := file.Name
+	if name == "" {
+		name = uuid.String()
+	}
+	result, err := s.client.Call(ctx, "uploadFile", map[string]any{
+		"name": "attachments/" + fileuri.SafeFilename(path.Base("/"+name)),
+		"blob": file.Bytes,
+	})
+	if err != nil {
+		return types.Attachment{}, fmt.Errorf("failed to upload %s: %w", name, err)
+	}
+	if result.IsError {
+		return types.Attachment{}, fmt.Errorf("failed to upload %s: %s", name, resultText(result))
+	}
+
+	attachment := types.Attachment{Name: name, MimeType: file.MimeType}
+	for _, content := range result.Content {
+		if content.Type == "resource_link" {
+			attachment.URL = content.URI
+		}
+	}
+	if attachment.URL == "" {
+		attachment.URL, _ = result.StructuredContent["uri"].(string)
+	}
+	if attachment.URL == "" {
+		return types.Attachment{}, fmt.Errorf("failed to upload %s: no file URI in the result", name)
+	}
+	return attachment, nil
+}
+
+// artifacts returns the files of the session modified since the agent
+// started answering, except for the uploaded attachments.
+func (s *session) artifacts(ctx context.Context, since time.Time, uploaded map[string]bool) ([]Artifact, error) {
+	resources, err := s.client.ListResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var artifacts []Artifact
+	for _, resource := range resources.Resources {
+		if !strings.HasPrefix(resource.URI, "file:///") || uploaded[resource.URI] ||
+			resource.Annotations == nil || resource.Annotations.LastModified.Before(since) {
+			continue
+		}
+
+		// The name of a file of the session is <session>/<path>.
+		_, name, ok := strings.Cut(resource.Name, "/")
+		if !ok {
+			name = resource.Name
+		}
+		file := &FileContent{
+			Name:     path.Base(name),
+			MimeType: resource.MimeType,
+			URI:      resource.URI,
+		}
+		if resource.Size <= maxInlineArtifact {
+			content, err := s.client.ReadResource(ctx, resource.URI)
+			if err != nil {
+				return nil, err
+			}
+			if len(content.Contents) > 0 {
+				file.URI = ""
+				file.Bytes = contentBytes(content.Contents[0])
+			}
+		}
+		artifacts = append(artifacts, Artifact{
+			ArtifactID: resource.URI,
+			Name:       name,
+			Parts:      []Part{{Kind: "file", File: file}},
+		})
+	}
+	return artifacts, nil
+}
+
+func contentBytes(content mcp.ResourceContent) string {
+	if content.Blob != nil {
+		return *content.Blob
+	}
+	if content.Text != nil {
+		return base64.StdEncoding.EncodeToString([]byte(*content.Text))
+	}
+	return ""
+}
+
+func resultText(result *mcp.CallToolResult) string {
+	var text []string
+	for _, content := range result.Content {
+		if content.Type == "text" && content.Text != "" {
+			text = append(text, content.Text)
+		}
+	}
+	return strings.Join(text, "\n\n")
+}
@@ -0,0 +1,160 @@
+package a2a
+
+import "encoding/json"
+
+// ProtocolVersion is the version of the A2A protocol the server implements.
+const ProtocolVersion = "0.3.0"
+
+// AgentCard describes a published agent to A2A clients.
+type AgentCard struct {
+	ProtocolVersion    string            `json:"protocolVersion"`
+	Name               string            `json:"name"`
+	Description        string            `json:"description"`
+	URL                string            `json:"url"`
+	PreferredTransport string            `json:"preferredTransport"`
+	IconURL            string            `json:"iconUrl,omitempty"`
+	Version            string            `json:"version"`
+	Capabilities       AgentCapabilities `json:"capabilities"`
+	DefaultInputModes  []string          `json:"defaultInputModes"`
+	DefaultOutputModes []string          `json:"defaultOutputModes"`
+	Skills             []AgentSkill      `json:"skills"`
+}
+
+type AgentCapabilities struct {
+	Streaming              bool `json:"streaming"`
+	PushNotifications      bool `json:"pushNotifications"`
+	StateTransitionHistory bool `json:"stateTransitionHistory"`
+}
+
+type AgentSkill struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+	Examples    []string `json:"examples,omitempty"`
+}
+
+type TaskState string
+
+const (
+	TaskSubmitted TaskState = "submitted"
+	TaskWorking   TaskState = "working"
+	TaskCompleted TaskState = "completed"
+	TaskFailed    TaskState = "failed"
+	TaskCanceled  TaskState = "canceled"
+)
+
+// Terminal indicates if the task won't change anymore.
+func (s TaskState) Terminal() bool {
+	return s == TaskCompleted || s == TaskFailed || s == TaskCanceled
+}
+
+// Task is one message of a client and the answer of the agent. Its context
+// is the session the agent answers in.
+type Task struct {
+	Kind      string     `json:"kind"`
+	ID        string     `json:"id"`
+	ContextID string     `json:"contextId"`
+	Status    TaskStatus `json:"status"`
+	Artifacts []Artifact `json:"artifacts,omitempty"`
+	History   []Message  `json:"history,omitempty"`
+}
+
+type TaskStatus struct {
+	State     TaskState `json:"state"`
+	Message   *Message  `json:"message,omitempty"`
+	Timestamp string    `json:"timestamp,omitempty"`
+}
+
+type Message struct {
+	Kind      string         `json:"kind"`
+	MessageID string         `json:"messageId"`
+	Role      string         `json:"role"`
+	Parts     []Part         `json:"parts"`
+	ContextID string         `json:"contextId,omitempty"`
+	TaskID    string         `json:"taskId,omitempty"`
+	Metadata  map[string]any `json:"metadata,omitempty"`
+}
+
+// Part is a text, file, or data part of a message or an artifact, by Kind.
+type Part struct {
+	Kind string         `json:"kind"`
+	Text string         `json:"text,omitempty"`
+	File *FileContent   `json:"file,omitempty"`
+	Data map[string]any `json:"data,omitempty"`
+}
+
+// FileContent is a file with its base64 encoded Bytes or at a URI.
+type FileContent struct {
+	Name     string `json:"name,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+	Bytes    string `json:"bytes,omitempty"`
+	URI      string `json:"uri,omitempty"`
+}
+
+// Artifact is a file the agent wrote to the session while working on a
+// task.
+type Artifact struct {
+	ArtifactID string `json:"artifactId"`
+	Name       string `json:"name,omitempty"`
+	Parts      []Part `json:"parts"`
+}
+
+type MessageSendParams struct {
+	Message       Message                   `json:"message"`
+	Configuration *MessageSendConfiguration `json:"configuration,omitempty"`
+}
+
+type MessageSendConfiguration struct {
+	// Blocking waits for the task to finish. Defaults to true.
+	Blocking      *bool `json:"blocking,omitempty"`
+	HistoryLength *int  `json:"historyLength,omitempty"`
+}
+
+type TaskQueryParams struct {
+	ID            string `json:"id"`
+	HistoryLength *int   `json:"historyLength,omitempty"`
+}
+
+type TaskIDParams struct {
+	ID string `json:"id"`
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Error is a JSON-RPC error of the A2A protocol.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+var (
+	errParse             = &Error{Code: -32700, Message: "invalid JSON payload"}
+	errInvalidRequest    = &Error{Code: -32600, Message: "invalid JSON-RPC request"}
+	errTaskNotFound      = &Error{Code: -32001, Message: "task not found"}
+	errTaskNotCancelable = &Error{Code: -32002, Message: "task cannot be canceled"}
+)
+
+func errMethodNotFound(method string) *Error {
+	return &Error{Code: -32601, Message: "method not found: " + method}
+}
+
+func errInvalidParams(message string) *Error {
+	return &Error{Code: -32602, Message: message}
+}
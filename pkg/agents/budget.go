@@ -0,0 +1,107 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+	"github.com/obot-platform/nanobot/pkg/uuid"
+)
+
+// runUsage is how much of its budgets a run has used so far.
+type runUsage struct {
+	turns       int
+	totalTokens int
+	toolCalls   int
+	// reported is the usage the provider reported for the turns.
+	reported *types.Usage
+}
+
+// add records a finished turn. Tokens are estimated from the request sent to
+// the model and its response, so a run pays for its history on every turn,
+// just like it does with the provider.
+func (u *runUsage) add(run *types.Execution) {
+	u.turns++
+	u.toolCalls += len(run.ToolOutputs)
+	if run.Response != nil && run.Response.Usage != nil {
+		if u.reported == nil {
+			u.reported = &types.Usage{}
+		}
+		u.reported.Add(*run.Response.Usage)
+	}
+	if req := run.PopulatedRequest; req != nil && run.Response != nil {
+		messages := append(slices.Clone(req.Input), run.Response.Output)
+		u.totalTokens += estimateTokens(req.Model, messages, req.SystemPrompt, req.Tools)
+	}
+}
+
+// budgetLimit returns the lower of two limits, where zero means unbounded.
+func budgetLimit(a, b int) int {
+	switch {
+	case a <= 0:
+		return max(b, 0)
+	case b <= 0:
+		return a
+	default:
+		return min(a, b)
+	}
+}
+
+// budgetExhausted returns the budget that keeps the run from taking another
+// turn, or nil if it can. The agent's limits apply along with the limits in
+// the options.
+func budgetExhausted(opt types.CompletionOptions, agent types.Agent, usage runUsage) *types.BudgetExhausted {
+	for _, budget := range []types.BudgetExhausted{
+		{Budget: types.BudgetTurns, Limit: budgetLimit(opt.MaxTurns, agent.MaxTurns), Used: usage.turns},
+		{Budget: types.BudgetTotalTokens, Limit: budgetLimit(opt.MaxTotalTokens, agent.MaxTotalTokens), Used: usage.totalTokens},
+		{Budget: types.BudgetToolCalls, Limit: budgetLimit(opt.MaxToolCalls, agent.MaxToolCalls), Used: usage.toolCalls},
+	} {
+		if budget.Limit > 0 && budget.Used >= budget.Limit {
+			return &budget
+		}
+	}
+	return nil
+}
+
+// budgetExhaustedPrefix starts the message that asks the model to finish.
+const budgetExhaustedPrefix = "[budget-exhausted]"
+
+func budgetExhaustedMessage(budget *types.BudgetExhausted) types.Message {
+	var reason string
+	switch budget.Budget {
+	case types.BudgetTurns:
+		reason = fmt.Sprintf("You have used all %d of your turns.", budget.Limit)
+	case types.BudgetTotalTokens:
+		reason = fmt.Sprintf("You have used about %d of your %d tokens.", budget.Used, budget.Limit)
+	case types.BudgetToolCalls:
+		reason = fmt.Sprintf("You have made %d of your %d tool calls.", budget.Used, budget.Limit)
+	}
+
+	return types.Message{
+		ID:   uuid.String(),
+		Role: "user",
+		Items: []types.CompletionItem{
+			{
+				Content: &mcp.Content{
+					Type: "text",
+					Text: budgetExhaustedPrefix + " " + reason + " Stop now and reply with what you have so far, without calling any tools.",
+				},
+			},
+		},
+	}
+}
+
+// notifyBudgetExhausted runs the agent's budgetExhausted hooks. The run
+// finishes either way, so failures are only logged.
+func (a *Agents) notifyBudgetExhausted(ctx context.Context, config types.Config, agentName, sessionID string, budget *types.BudgetExhausted) {
+	if _, err := mcp.InvokeHooks(ctx, a.registry, config.Agents[agentName].Hooks, &types.AgentBudgetHook{
+		Agent:     agentName,
+		SessionID: sessionID,
+		Budget:    budget,
+	}, "budgetExhausted", nil); err != nil {
+		slog.ErrorContext(ctx, "failed to invoke budget exhausted hook", "agent", agentName, "error", err)
+	}
+}
@@ -0,0 +1,78 @@
+package agents
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+func TestRunUsage(t *testing.T) {
+	var usage runUsage
+	run := &types.Execution{
+		PopulatedRequest: &types.CompletionRequest{
+			Model: "gpt-4.1",
+			Input: []types.Message{
+				{
+					Role: "user",
+					Items: []types.CompletionItem{
+						{Content: &mcp.Content{Type: "text", Text: strings.Repeat("search the repository ", 200)}},
+					},
+				},
+			},
+		},
+		Response: &types.CompletionResponse{},
+		ToolOutputs: map[string]types.ToolOutput{
+			"call-1": {Done: true},
+			"call-2": {Done: true},
+		},
+	}
+
+	usage.add(run)
+	first := usage.totalTokens
+	if usage.turns != 1 || usage.toolCalls != 2 || first == 0 {
+		t.Fatalf("unexpected usage after one turn %+v", usage)
+	}
+
+	usage.add(run)
+	if usage.turns != 2 || usage.toolCalls != 4 || usage.totalTokens != 2*first {
+		t.Errorf("expected usage to accumulate across turns, got %+v", usage)
+	}
+}
+
+func TestBudgetExhausted(t *testing.T) {
+	usage := runUsage{turns: 3, totalTokens: 5_000, toolCalls: 12}
+
+	if budget := budgetExhausted(types.CompletionOptions{}, types.Agent{}, usage); budget != nil {
+		t.Errorf("expected no budget to be unbounded, got %+v", budget)
+	}
+	if budget := budgetExhausted(types.CompletionOptions{MaxTurns: 4}, types.Agent{}, usage); budget != nil {
+		t.Errorf("expected turns to remain, got %+v", budget)
+	}
+	if budget := budgetExhausted(types.CompletionOptions{MaxTurns: 3}, types.Agent{}, usage); budget == nil || budget.Budget != types.BudgetTurns {
+		t.Errorf("expected turns to be exhausted, got %+v", budget)
+	}
+	if budget := budgetExhausted(types.CompletionOptions{MaxTotalTokens: 100_000}, types.Agent{}, usage); budget != nil {
+		t.Errorf("expected tokens to remain, got %+v", budget)
+	}
+
+	// The lower of the option and agent limits wins.
+	agent := types.Agent{HookAgent: types.HookAgent{MaxTotalTokens: 1_000, MaxToolCalls: 20}}
+	if budget := budgetExhausted(types.CompletionOptions{MaxTotalTokens: 100_000}, agent, usage); budget == nil || budget.Budget != types.BudgetTotalTokens || budget.Limit != 1_000 || budget.Used != 5_000 {
+		t.Errorf("expected the agent's token budget to be exhausted, got %+v", budget)
+	}
+	if budget := budgetExhausted(types.CompletionOptions{MaxToolCalls: 10}, types.Agent{HookAgent: types.HookAgent{MaxToolCalls: 20}}, usage); budget == nil || budget.Budget != types.BudgetToolCalls || budget.Limit != 10 {
+		t.Errorf("expected the option's tool call budget to be exhausted, got %+v", budget)
+	}
+}
+
+func TestBudgetExhaustedMessage(t *testing.T) {
+	msg := budgetExhaustedMessage(&types.BudgetExhausted{Budget: types.BudgetToolCalls, Limit: 10, Used: 12})
+	if msg.Role != "user" || len(msg.Items) != 1 || msg.Items[0].Content == nil {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+	if text := msg.Items[0].Content.Text; !strings.HasPrefix(text, budgetExhaustedPrefix) || !strings.Contains(text, "12 of your 10 tool calls") {
+		t.Errorf("unexpected message text %q", text)
+	}
+}
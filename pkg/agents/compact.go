@@ -15,27 +15,45 @@ const (
 	compactionThreshold      = 0.835
 	compactionSummaryMetaKey = "ai.nanobot.meta/compaction-summary"
 	defaultContextWindow     = 200_000
+	// defaultLocalContextWindow is the context window of local models whose
+	// window isn't configured, local servers like Ollama run with small ones.
+	defaultLocalContextWindow = 8_192
 )
 
 // getContextWindowSize returns the context window size for the given model.
-// If configOverride is > 0, it is used directly. Otherwise, defaults to 200k.
-func getContextWindowSize(configOverride int) int {
+// If configOverride is > 0, it is used directly, then the window the completer
+// knows for the model, configured or well known. Otherwise, defaults to 8k for
+// local models and 200k for the rest.
+func getContextWindowSize(configOverride int, model types.ModelInfo) int {
 	if configOverride > 0 {
 		return configOverride
 	}
+	if model.ContextWindow > 0 {
+		return model.ContextWindow
+	}
+	if model.Local {
+		return defaultLocalContextWindow
+	}
 	return defaultContextWindow
 }
 
-// shouldCompact returns true if the estimated token count of the request
-// exceeds the compaction threshold of the context window.
-func shouldCompact(req types.CompletionRequest, contextWindowSize int) bool {
+// modelInfo returns what the completer knows about a model.
+func (a *Agents) modelInfo(ctx context.Context, model string) types.ModelInfo {
+	if describer, ok := a.completer.(types.ModelDescriber); ok {
+		return describer.ModelInfo(ctx, model)
+	}
+	return types.ModelInfo{}
+}
+
+// shouldCompact returns true if the token count of the request exceeds the
+// compaction threshold of the context window.
+func (a *Agents) shouldCompact(ctx context.Context, req types.CompletionRequest, contextWindowSize int) bool {
 	if contextWindowSize <= 0 {
 		return false
 	}
 
-	estimated := estimateTokens(req.Model, req.Input, req.SystemPrompt, req.Tools)
 	threshold := int(float64(contextWindowSize) * compactionThreshold)
-	return estimated > threshold
+	return a.countRequestTokens(ctx, req) > threshold
 }
 
 // IsCompactionSummary checks whether a message is a compaction summary
@@ -83,7 +101,10 @@ type compactResult struct {
 // On re-compaction, only the messages since the previous summary are summarized
 // (with the previous summary included as context). This keeps the summarization
 // input bounded rather than growing with the full conversation.
-func (a *Agents) compact(ctx context.Context, req types.CompletionRequest, currentRequestInput []types.Message, previousCompacted []types.Message) (*compactResult, error) {
+//
+// Pinned messages are not summarized. They are carried forward after the
+// summary as they are, up to pinnedBudget tokens.
+func (a *Agents) compact(ctx context.Context, req types.CompletionRequest, currentRequestInput []types.Message, previousCompacted []types.Message, pinning *types.AgentPinning, pinnedBudget int) (*compactResult, error) {
 	history, newInput := splitHistoryAndNewInput(req.Input, currentRequestInput)
 
 	// Split history into: messages before/including the previous summary, and messages after it.
@@ -105,8 +126,22 @@ func (a *Agents) compact(ctx context.Context, req types.CompletionRequest, curre
 		sinceLastSummary = history
 	}
 
+	// Pinned messages are carried forward instead of summarized.
+	var (
+		pinned      = pinnedMessages(req.Model, sinceLastSummary, pinning, pinnedBudget)
+		carried     []types.Message
+		toSummarize []types.Message
+	)
+	for i, msg := range sinceLastSummary {
+		if pinned[i] {
+			carried = append(carried, msg)
+		} else {
+			toSummarize = append(toSummarize, msg)
+		}
+	}
+
 	// Build summarization transcript from only the messages since the last summary
-	transcript := buildTranscript(sinceLastSummary)
+	transcript := buildTranscript(toSummarize)
 
 	var summaryPrompt string
 	if previousSummaryText != "" {
@@ -138,6 +173,7 @@ func (a *Agents) compact(ctx context.Context, req types.CompletionRequest, curre
 	if err != nil {
 		return nil, fmt.Errorf("compaction summarization failed: %w", err)
 	}
+	recordUsage(ctx, summaryReq.Model, resp)
 
 	// Extract summary text from response
 	summaryText := extractTextFromResponse(resp)
@@ -165,14 +201,17 @@ func (a *Agents) compact(ctx context.Context, req types.CompletionRequest, curre
 		},
 	}
 
-	// Build the compacted input: summary + new user messages
+	// Build the compacted input: summary + pinned messages + new user messages
 	compactedInput := []types.Message{summaryMessage}
+	compactedInput = append(compactedInput, carried...)
 	compactedInput = append(compactedInput, newInput...)
 
 	// Build archived messages: previous compacted + all history from this compaction (including old summaries)
+	// except the pinned messages, which are still part of the input.
 	archivedMessages := make([]types.Message, 0, len(previousCompacted)+len(history))
 	archivedMessages = append(archivedMessages, previousCompacted...)
-	archivedMessages = append(archivedMessages, history...)
+	archivedMessages = append(archivedMessages, history[:len(history)-len(sinceLastSummary)]...)
+	archivedMessages = append(archivedMessages, toSummarize...)
 
 	return &compactResult{
 		compactedInput:   compactedInput,
@@ -8,19 +8,28 @@ import (
 )
 
 func TestGetContextWindowSize_ConfigOverride(t *testing.T) {
-	size := getContextWindowSize(50000)
+	size := getContextWindowSize(50000, types.ModelInfo{Local: true, ContextWindow: 32_000})
 	if size != 50000 {
 		t.Errorf("expected config override 50000, got %d", size)
 	}
 }
 
 func TestGetContextWindowSize_Default(t *testing.T) {
-	size := getContextWindowSize(0)
+	size := getContextWindowSize(0, types.ModelInfo{})
 	if size != defaultContextWindow {
 		t.Errorf("expected default %d, got %d", defaultContextWindow, size)
 	}
 }
 
+func TestGetContextWindowSize_Local(t *testing.T) {
+	if size := getContextWindowSize(0, types.ModelInfo{Local: true}); size != defaultLocalContextWindow {
+		t.Errorf("expected local default %d, got %d", defaultLocalContextWindow, size)
+	}
+	if size := getContextWindowSize(0, types.ModelInfo{Local: true, ContextWindow: 32_000}); size != 32_000 {
+		t.Errorf("expected the model's window 32000, got %d", size)
+	}
+}
+
 func TestShouldCompact_BelowThreshold(t *testing.T) {
 	req := types.CompletionRequest{
 		Input: []types.Message{
@@ -33,7 +42,7 @@ func TestShouldCompact_BelowThreshold(t *testing.T) {
 		},
 	}
 
-	if shouldCompact(req, 128_000) {
+	if new(Agents).shouldCompact(t.Context(), req, 128_000) {
 		t.Error("should not compact small input")
 	}
 }
@@ -50,21 +59,21 @@ func TestShouldCompact_ZeroContextWindow(t *testing.T) {
 		},
 	}
 
-	if shouldCompact(req, 0) {
+	if new(Agents).shouldCompact(t.Context(), req, 0) {
 		t.Error("should not compact with zero context window")
 	}
 }
 
 func TestShouldCompact_NegativeContextWindow(t *testing.T) {
 	req := types.CompletionRequest{}
-	if shouldCompact(req, -1) {
+	if new(Agents).shouldCompact(t.Context(), req, -1) {
 		t.Error("should not compact with negative context window")
 	}
 }
 
 func TestShouldCompact_EmptyInput(t *testing.T) {
 	req := types.CompletionRequest{}
-	if shouldCompact(req, 128_000) {
+	if new(Agents).shouldCompact(t.Context(), req, 128_000) {
 		t.Error("should not compact empty input")
 	}
 }
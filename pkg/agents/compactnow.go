@@ -0,0 +1,137 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+var (
+	// ErrNothingToCompact is returned by CompactConversation when the chat has
+	// no messages to summarize.
+	ErrNothingToCompact = errors.New("there is nothing to compact")
+	// ErrNothingToRestore is returned by RestoreCompactedMessages when no
+	// messages were archived by compaction.
+	ErrNothingToRestore = errors.New("there are no compacted messages to restore")
+)
+
+type CompactResult struct {
+	// Archived is the number of messages moved out of the conversation into
+	// the archive.
+	Archived int `json:"archived"`
+	// Kept is the number of messages in the conversation after compaction,
+	// including the summary.
+	Kept int `json:"kept"`
+}
+
+type RestoreResult struct {
+	// Restored is the number of archived messages put back into the
+	// conversation.
+	Restored int `json:"restored"`
+}
+
+// conversation returns the messages of the session's chat, the input of the
+// last run followed by its reply.
+func conversation(run types.Execution) []types.Message {
+	messages := slices.Clone(run.PopulatedRequest.Input)
+	if run.Response != nil && len(run.Response.Output.Items) > 0 {
+		messages = append(messages, run.Response.Output)
+	}
+	return messages
+}
+
+// setConversation replaces the messages of the session's chat, the way
+// UndoLastTurn does.
+func setConversation(run *types.Execution, messages []types.Message) {
+	run.ToolOutputs = nil
+	if run.Response == nil {
+		run.Response = &types.CompletionResponse{}
+	}
+	run.Response.InternalMessages = nil
+	run.Response.BudgetExhausted = nil
+	if len(messages) == 0 {
+		run.PopulatedRequest.Input = nil
+		run.Response.Output = types.Message{}
+	} else {
+		run.PopulatedRequest.Input = messages[:len(messages)-1]
+		run.Response.Output = messages[len(messages)-1]
+	}
+}
+
+// CompactConversation compacts the session's chat now instead of waiting for
+// it to fill the context window. The summarized messages are archived with
+// the ones of earlier compactions.
+func (a *Agents) CompactConversation(ctx context.Context) (*CompactResult, error) {
+	session := mcp.SessionFromContext(ctx).Root()
+
+	var run types.Execution
+	if !session.Get(types.PreviousExecutionKey, &run) || run.PopulatedRequest == nil {
+		return nil, ErrNothingToCompact
+	}
+
+	messages := conversation(run)
+	if !slices.ContainsFunc(messages, func(msg types.Message) bool {
+		return !IsCompactionSummary(msg)
+	}) {
+		return nil, ErrNothingToCompact
+	}
+
+	req := *run.PopulatedRequest
+	req.Input = messages
+
+	config := types.ConfigFromContext(ctx)
+	agent := config.Agents[req.GetAgent()]
+	ctxWindowSize := getContextWindowSize(agent.ContextWindow, a.modelInfo(ctx, req.Model))
+
+	result, err := a.compact(ctx, req, nil, run.CompactedMessages, agent.Pinning, pinnedTokenBudget(agent.Pinning, ctxWindowSize))
+	if err != nil {
+		return nil, err
+	}
+
+	archived := len(result.archivedMessages) - len(run.CompactedMessages)
+	run.CompactedMessages = result.archivedMessages
+	setConversation(&run, result.compactedInput)
+	session.Set(types.PreviousExecutionKey, &run)
+
+	a.notifyHooks(ctx, req.GetAgent(), "compaction", nil, &types.AgentCompactionHook{
+		Agent:     req.GetAgent(),
+		SessionID: session.ID(),
+		Archived:  archived,
+		Kept:      len(result.compactedInput),
+	})
+
+	return &CompactResult{
+		Archived: archived,
+		Kept:     len(result.compactedInput),
+	}, nil
+}
+
+// RestoreCompactedMessages puts the messages archived by compaction back into
+// the session's chat in place of the compaction summaries. Messages that
+// compaction carried forward stay after the restored ones. The conversation
+// is compacted again when it no longer fits the context window.
+func (a *Agents) RestoreCompactedMessages(ctx context.Context) (*RestoreResult, error) {
+	session := mcp.SessionFromContext(ctx).Root()
+
+	var run types.Execution
+	if !session.Get(types.PreviousExecutionKey, &run) || run.PopulatedRequest == nil || len(run.CompactedMessages) == 0 {
+		return nil, ErrNothingToRestore
+	}
+
+	isSummary := func(msg types.Message) bool {
+		return IsCompactionSummary(msg)
+	}
+	restored := slices.DeleteFunc(slices.Clone(run.CompactedMessages), isSummary)
+	messages := append(restored, slices.DeleteFunc(conversation(run), isSummary)...)
+
+	run.CompactedMessages = nil
+	setConversation(&run, messages)
+	session.Set(types.PreviousExecutionKey, &run)
+
+	return &RestoreResult{
+		Restored: len(restored),
+	}, nil
+}
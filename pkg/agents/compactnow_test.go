@@ -0,0 +1,87 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+func conversationIDs(run types.Execution) (ids []string) {
+	for _, msg := range conversation(run) {
+		ids = append(ids, msg.ID)
+	}
+	return
+}
+
+func TestCompactConversationAndRestore(t *testing.T) {
+	a := &Agents{
+		completer: completerFunc(func(context.Context, types.CompletionRequest, ...types.CompletionOptions) (*types.CompletionResponse, error) {
+			return &types.CompletionResponse{
+				Output: textMessage("", "assistant", "summary", nil),
+			}, nil
+		}),
+	}
+
+	session := mcp.NewEmptySession(t.Context())
+	ctx := mcp.WithSession(t.Context(), session)
+
+	if _, err := a.CompactConversation(ctx); !errors.Is(err, ErrNothingToCompact) {
+		t.Fatalf("expected ErrNothingToCompact for a new chat, got %v", err)
+	}
+
+	session.Set(types.PreviousExecutionKey, &types.Execution{
+		PopulatedRequest: &types.CompletionRequest{
+			Model: "gpt-4.1",
+			Input: []types.Message{
+				textMessage("m1", "user", "Hello", nil),
+				textMessage("m2", "assistant", "Hi", nil),
+				textMessage("m3", "user", "How are you?", nil),
+			},
+		},
+		Response: &types.CompletionResponse{
+			Output: textMessage("m4", "assistant", "Fine", nil),
+		},
+	})
+
+	result, err := a.CompactConversation(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Archived != 4 || result.Kept != 1 {
+		t.Errorf("result = %+v, want 4 archived and 1 kept", result)
+	}
+
+	var run types.Execution
+	session.Get(types.PreviousExecutionKey, &run)
+	if msgs := conversation(run); len(msgs) != 1 || !IsCompactionSummary(msgs[0]) {
+		t.Errorf("conversation = %v, want only the summary", conversationIDs(run))
+	}
+	if len(run.CompactedMessages) != 4 {
+		t.Errorf("expected 4 archived messages, got %d", len(run.CompactedMessages))
+	}
+
+	restored, err := a.RestoreCompactedMessages(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Restored != 4 {
+		t.Errorf("expected 4 restored messages, got %d", restored.Restored)
+	}
+
+	run = types.Execution{}
+	session.Get(types.PreviousExecutionKey, &run)
+	if ids := conversationIDs(run); !slices.Equal(ids, []string{"m1", "m2", "m3", "m4"}) {
+		t.Errorf("conversation = %v, want the original messages", ids)
+	}
+	if len(run.CompactedMessages) != 0 {
+		t.Errorf("expected the archive to be empty, got %d messages", len(run.CompactedMessages))
+	}
+
+	if _, err := a.RestoreCompactedMessages(ctx); !errors.Is(err, ErrNothingToRestore) {
+		t.Errorf("expected ErrNothingToRestore, got %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package agents
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// notifyHooks runs the agent's hooks for an event of a run, like runStart or
+// toolCall, in the background so that slow webhooks don't hold up the run.
+// The hooks only observe the run, so their output is ignored and failures are
+// only logged.
+func (a *Agents) notifyHooks(ctx context.Context, agentName, name string, params map[string]string, in any) {
+	sendActivity(ctx, name, in)
+
+	hooks := types.ConfigFromContext(ctx).Agents[agentName].Hooks
+	if !slices.ContainsFunc(hooks, func(hook mcp.HookMapping) bool {
+		return hook.Matches(name, params)
+	}) {
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		if _, err := mcp.InvokeHooks(ctx, a.registry, hooks, &in, name, params); err != nil {
+			slog.ErrorContext(ctx, "failed to invoke run hook", "agent", agentName, "hook", name, "error", err)
+		}
+	}()
+}
+
+// notifyToolCalls runs the toolCall hooks for the tool calls of the run that
+// have a result.
+func (a *Agents) notifyToolCalls(ctx context.Context, run *types.Execution, runID string) {
+	if run.Response == nil {
+		return
+	}
+	for _, item := range run.Response.Output.Items {
+		if item.ToolCall == nil {
+			continue
+		}
+		output, ok := run.ToolOutputs[item.ToolCall.CallID]
+		if !ok || !output.Done {
+			continue
+		}
+
+		var result *types.ToolCallResult
+		if len(output.Output.Items) > 0 {
+			result = output.Output.Items[0].ToolCallResult
+		}
+		a.notifyHooks(ctx, run.Request.GetAgent(), "toolCall", map[string]string{
+			"tool": item.ToolCall.Name,
+		}, &types.AgentToolCallHook{
+			Agent:     run.Request.GetAgent(),
+			SessionID: mcp.SessionFromContext(ctx).Root().ID(),
+			RunID:     runID,
+			ToolCall:  item.ToolCall,
+			Result:    result,
+		})
+	}
+}
+
+// maxActivityText is the length of the reply sent with a messageCompleted
+// activity event.
+const maxActivityText = 500
+
+// sendActivity tells the root session about an event of a run, for the
+// session activity feed. Events without an activity counterpart are skipped.
+func sendActivity(ctx context.Context, name string, in any) {
+	activity, ok := newActivity(name, in)
+	if !ok {
+		return
+	}
+	session := mcp.SessionFromContext(ctx)
+	if session == nil {
+		return
+	}
+	activity.Time = time.Now()
+	_ = session.Root().SendPayload(ctx, types.ActivityNotification, activity)
+}
+
+// newActivity summarizes the payload of a run hook as an activity event.
+func newActivity(name string, in any) (types.SessionActivity, bool) {
+	switch hook := in.(type) {
+	case *types.AgentRunHook:
+		activity := types.SessionActivity{
+			Agent: hook.Agent,
+			RunID: hook.RunID,
+		}
+		switch name {
+		case "runStart":
+			activity.Type = types.ActivityTurnStarted
+		case "runFinish":
+			activity.Type = types.ActivityMessageCompleted
+			if hook.Response != nil {
+				activity.Text = replyText(hook.Response.Output)
+				activity.IsError = hook.Response.Error != ""
+			}
+		case "error":
+			activity.Type = types.ActivityTurnFailed
+			activity.IsError = true
+			activity.Error = hook.Error
+		default:
+			return types.SessionActivity{}, false
+		}
+		return activity, true
+	case *types.AgentToolCallHook:
+		activity := types.SessionActivity{
+			Type:  types.ActivityToolCalled,
+			Agent: hook.Agent,
+			RunID: hook.RunID,
+		}
+		if hook.ToolCall != nil {
+			activity.Tool = hook.ToolCall.Name
+			activity.Target = hook.ToolCall.Target
+		}
+		if hook.Result != nil {
+			activity.IsError = hook.Result.Output.IsError
+		}
+		return activity, true
+	case *types.AgentCompactionHook:
+		return types.SessionActivity{
+			Type:     types.ActivityCompaction,
+			Agent:    hook.Agent,
+			RunID:    hook.RunID,
+			Archived: hook.Archived,
+			Kept:     hook.Kept,
+		}, true
+	case *types.AgentHandoffHook:
+		return types.SessionActivity{
+			Type:  types.ActivityHandoff,
+			Agent: hook.Agent,
+			RunID: hook.RunID,
+			To:    hook.Handoff.To,
+		}, true
+	default:
+		return types.SessionActivity{}, false
+	}
+}
+
+// replyText returns the text of a reply, truncated to maxActivityText.
+func replyText(msg types.Message) string {
+	var texts []string
+	for _, item := range msg.Items {
+		if item.Content != nil && item.Content.Text != "" {
+			texts = append(texts, item.Content.Text)
+		}
+	}
+	text := []rune(strings.Join(texts, "\n"))
+	if len(text) > maxActivityText {
+		return string(text[:maxActivityText]) + "…"
+	}
+	return string(text)
+}
@@ -0,0 +1,67 @@
+package agents
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+func TestNewActivity(t *testing.T) {
+	reply := &types.CompletionResponse{Output: types.Message{Items: []types.CompletionItem{
+		{Content: &mcp.Content{Type: "text", Text: strings.Repeat("a", maxActivityText+10)}},
+	}}}
+
+	for _, test := range []struct {
+		name string
+		in   any
+		want types.SessionActivity
+		skip bool
+	}{
+		{
+			name: "runStart",
+			in:   &types.AgentRunHook{Agent: "main", RunID: "r1"},
+			want: types.SessionActivity{Type: types.ActivityTurnStarted, Agent: "main", RunID: "r1"},
+		},
+		{
+			name: "runFinish",
+			in:   &types.AgentRunHook{Agent: "main", RunID: "r1", Response: reply},
+			want: types.SessionActivity{Type: types.ActivityMessageCompleted, Agent: "main", RunID: "r1",
+				Text: strings.Repeat("a", maxActivityText) + "…"},
+		},
+		{
+			name: "error",
+			in:   &types.AgentRunHook{Agent: "main", RunID: "r1", Error: "boom"},
+			want: types.SessionActivity{Type: types.ActivityTurnFailed, Agent: "main", RunID: "r1", IsError: true, Error: "boom"},
+		},
+		{
+			name: "toolCall",
+			in: &types.AgentToolCallHook{Agent: "main", RunID: "r1",
+				ToolCall: &types.ToolCall{Name: "bash", Target: "nanobot.system"},
+				Result:   &types.ToolCallResult{Output: types.CallResult{IsError: true}}},
+			want: types.SessionActivity{Type: types.ActivityToolCalled, Agent: "main", RunID: "r1",
+				Tool: "bash", Target: "nanobot.system", IsError: true},
+		},
+		{
+			name: "compaction",
+			in:   &types.AgentCompactionHook{Agent: "main", RunID: "r1", Archived: 12, Kept: 3},
+			want: types.SessionActivity{Type: types.ActivityCompaction, Agent: "main", RunID: "r1", Archived: 12, Kept: 3},
+		},
+		{
+			name: "guardrail",
+			in:   &types.AgentGuardrailHook{Agent: "main"},
+			skip: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := newActivity(test.name, test.in)
+			if ok == test.skip {
+				t.Fatalf("expected ok=%v, got %v", !test.skip, ok)
+			}
+			if got != test.want {
+				t.Errorf("got %+v, expected %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FanOutToolName is the name of the tool that runs a task in several
+// sub-agents at once. Like the task tool, it is never offered to sub-agents.
+const FanOutToolName = "fanout"
+
+const (
+	// MaxFanOutBranches is how many sub-agents a fan-out can run at once.
+	MaxFanOutBranches = 10
+	// DefaultBranchTimeout is how long a branch runs when its timeout isn't
+	// set.
+	DefaultBranchTimeout = 5 * time.Minute
+)
+
+// FanOut is a task run by several sub-agents concurrently. The fan-out joins
+// when all of them finished, failed, or timed out.
+type FanOut struct {
+	// Prompt is the task of the branches that don't have a prompt of their
+	// own.
+	Prompt   string
+	Branches []Branch
+}
+
+// Branch is one sub-agent of a fan-out. It runs as Agent, with the
+// instructions and tools of that agent.
+type Branch struct {
+	Agent  string
+	Prompt string
+	// Tools limits the sub-agent to these tools, all of the agent's tools
+	// when empty.
+	Tools     []string
+	Timeout   time.Duration
+	MaxTurns  int
+	MaxTokens int
+}
+
+type BranchStatus string
+
+const (
+	BranchCompleted BranchStatus = "completed"
+	BranchFailed    BranchStatus = "failed"
+	BranchTimedOut  BranchStatus = "timedOut"
+)
+
+type BranchResult struct {
+	Agent  string       `json:"agent"`
+	Status BranchStatus `json:"status"`
+	Report string       `json:"report,omitempty"`
+	Error  string       `json:"error,omitempty"`
+	// BudgetExhausted is set when the sub-agent was stopped by its turn or
+	// token budget and reported what it had so far.
+	BudgetExhausted bool  `json:"budgetExhausted,omitempty"`
+	DurationMS      int64 `json:"durationMs"`
+}
+
+// FanOutResult has the results of the branches in the order of the branches.
+type FanOutResult struct {
+	Results   []BranchResult `json:"results"`
+	Completed int            `json:"completed"`
+	Failed    int            `json:"failed"`
+	TimedOut  int            `json:"timedOut"`
+}
+
+// RunFanOut runs the branches of the fan-out concurrently and waits for all
+// of them. A branch that fails or times out doesn't stop the others, its
+// result says what happened.
+func (a *Agents) RunFanOut(ctx context.Context, fanOut FanOut) (*FanOutResult, error) {
+	if len(fanOut.Branches) == 0 {
+		return nil, errors.New("a fan-out needs at least one branch")
+	}
+	if len(fanOut.Branches) > MaxFanOutBranches {
+		return nil, fmt.Errorf("a fan-out can have at most %d branches, got %d", MaxFanOutBranches, len(fanOut.Branches))
+	}
+	for i, branch := range fanOut.Branches {
+		if strings.TrimSpace(branch.Prompt) == "" && strings.TrimSpace(fanOut.Prompt) == "" {
+			return nil, fmt.Errorf("branch %d has no prompt", i)
+		}
+	}
+
+	var (
+		wg     sync.WaitGroup
+		result = &FanOutResult{
+			Results: make([]BranchResult, len(fanOut.Branches)),
+		}
+	)
+	for i, branch := range fanOut.Branches {
+		if branch.Prompt == "" {
+			branch.Prompt = fanOut.Prompt
+		}
+		wg.Go(func() {
+			result.Results[i] = a.runBranch(ctx, branch)
+		})
+	}
+	wg.Wait()
+
+	for _, branch := range result.Results {
+		switch branch.Status {
+		case BranchCompleted:
+			result.Completed++
+		case BranchFailed:
+			result.Failed++
+		case BranchTimedOut:
+			result.TimedOut++
+		}
+	}
+	return result, nil
+}
+
+func (a *Agents) runBranch(ctx context.Context, branch Branch) BranchResult {
+	timeout := branch.Timeout
+	if timeout <= 0 {
+		timeout = DefaultBranchTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type taskResult struct {
+		result *TaskResult
+		err    error
+	}
+
+	var (
+		start = time.Now()
+		done  = make(chan taskResult, 1)
+	)
+	go func() {
+		result, err := a.RunTask(ctx, Task{
+			Agent:     branch.Agent,
+			Prompt:    branch.Prompt,
+			Tools:     branch.Tools,
+			MaxTurns:  branch.MaxTurns,
+			MaxTokens: branch.MaxTokens,
+		})
+		done <- taskResult{result: result, err: err}
+	}()
+
+	result := BranchResult{
+		Agent: branch.Agent,
+	}
+
+	// The branch is done when its timeout passes, even if a tool it called
+	// doesn't give up right away.
+	select {
+	case task := <-done:
+		if task.err != nil {
+			result.Status = BranchFailed
+			result.Error = task.err.Error()
+		} else {
+			result.Status = BranchCompleted
+			result.Report = task.result.Report
+			result.BudgetExhausted = task.result.BudgetExhausted
+		}
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			result.Status = BranchTimedOut
+			result.Error = fmt.Sprintf("the branch didn't finish within %s", timeout)
+		} else {
+			result.Status = BranchFailed
+			result.Error = context.Cause(ctx).Error()
+		}
+	}
+	result.DurationMS = time.Since(start).Milliseconds()
+	return result
+}
@@ -0,0 +1,40 @@
+package agents
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+func TestRunFanOut(t *testing.T) {
+	ctx := types.WithConfig(t.Context(), types.Config{})
+	a := &Agents{}
+
+	if _, err := a.RunFanOut(ctx, FanOut{}); err == nil {
+		t.Error("expected a fan-out without branches to fail")
+	}
+	if _, err := a.RunFanOut(ctx, FanOut{Branches: make([]Branch, MaxFanOutBranches+1)}); err == nil {
+		t.Error("expected a fan-out with too many branches to fail")
+	}
+	if _, err := a.RunFanOut(ctx, FanOut{Branches: []Branch{{Agent: "a", Prompt: "look"}, {Agent: "b"}}}); err == nil ||
+		!strings.Contains(err.Error(), "branch 1") {
+		t.Errorf("expected the branch without a prompt to be reported, got %v", err)
+	}
+
+	result, err := a.RunFanOut(ctx, FanOut{
+		Prompt:   "look around",
+		Branches: []Branch{{Agent: "missing"}, {Agent: "other", Prompt: "look elsewhere"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Results) != 2 || result.Failed != 2 || result.Completed != 0 {
+		t.Fatalf("unexpected fan-out result %+v", result)
+	}
+	for i, agent := range []string{"missing", "other"} {
+		if branch := result.Results[i]; branch.Agent != agent || branch.Status != BranchFailed || !strings.Contains(branch.Error, agent) {
+			t.Errorf("unexpected result of branch %d: %+v", i, branch)
+		}
+	}
+}
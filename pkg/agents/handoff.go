@@ -0,0 +1,192 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/complete"
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+	"github.com/obot-platform/nanobot/pkg/uuid"
+)
+
+const handoffPrefix = "[handoff]"
+
+type handoffArgs struct {
+	Agent   string `json:"agent"`
+	Summary string `json:"summary"`
+}
+
+// handoffTool is the tool an agent with handoffs calls to hand the
+// conversation to one of them. It is handled by the run, not an MCP server.
+func handoffTool(config types.Config, agent types.Agent) types.TargetMapping[types.TargetTool] {
+	var description strings.Builder
+	description.WriteString("Hands the conversation to an agent that is better suited to continue it. " +
+		"The agent takes over with the conversation so far and your summary, and replies to the user from then on. The agents are:")
+	for _, name := range agent.Handoffs {
+		fmt.Fprintf(&description, "\n- %s", name)
+		if target := config.Agents[name]; target.Description != "" {
+			fmt.Fprintf(&description, ": %s", strings.TrimSpace(target.Description))
+		}
+	}
+
+	inputSchema, _ := json.Marshal(map[string]any{
+		"type":     "object",
+		"required": []string{"agent", "summary"},
+		"properties": map[string]any{
+			"agent": map[string]any{
+				"type":        "string",
+				"description": "The agent to hand the conversation to",
+				"enum":        agent.Handoffs,
+			},
+			"summary": map[string]any{
+				"type":        "string",
+				"description": "What the user wants and what was done so far, for the agent taking over",
+			},
+		},
+	})
+
+	return types.TargetMapping[types.TargetTool]{
+		TargetName: types.HandoffToolName,
+		Target: types.TargetTool{
+			Tool: mcp.Tool{
+				Name:        types.HandoffToolName,
+				Description: description.String(),
+				InputSchema: inputSchema,
+			},
+		},
+	}
+}
+
+func isHandoff(target types.TargetMapping[types.TargetTool]) bool {
+	return target.MCPServer == "" && target.TargetName == types.HandoffToolName && !target.Target.External
+}
+
+// handoff records the handoff asked for by the tool call on the run, so that
+// the next run is made with the agent taking over.
+func (a *Agents) handoff(ctx context.Context, run *types.Execution, item types.CompletionItem, opts []types.CompletionOptions) types.ToolOutput {
+	var (
+		functionCall = item.ToolCall
+		from         = run.Request.GetAgent()
+		args         handoffArgs
+		text         string
+		isError      = true
+	)
+
+	if err := json.Unmarshal([]byte(functionCall.Arguments), &args); err != nil {
+		text = fmt.Sprintf("invalid handoff arguments: %v", err)
+	} else if !slices.Contains(types.ConfigFromContext(ctx).Agents[from].Handoffs, args.Agent) {
+		text = fmt.Sprintf("agent %s can not hand off to %q", from, args.Agent)
+	} else if run.Handoff != nil {
+		text = fmt.Sprintf("the conversation was already handed off to %s", run.Handoff.To)
+	} else {
+		run.Handoff = &types.Handoff{
+			From:    from,
+			To:      args.Agent,
+			Summary: args.Summary,
+			Created: time.Now(),
+		}
+		text = fmt.Sprintf("Handed the conversation off to %s.", args.Agent)
+		isError = false
+	}
+
+	tcResult := &types.ToolCallResult{
+		CallID: functionCall.CallID,
+		Output: types.CallResult{
+			Content: []mcp.Content{
+				{
+					Type: "text",
+					Text: text,
+				},
+			},
+			IsError: isError,
+		},
+	}
+
+	if progressToken := complete.Complete(opts...).ProgressToken; progressToken != nil {
+		_ = mcp.SessionFromContext(ctx).SendPayload(ctx, "notifications/progress", mcp.NotificationProgressRequest{
+			ProgressToken: progressToken,
+			Meta: map[string]any{
+				types.CompletionProgressMetaKey: types.CompletionProgress{
+					MessageID: run.Response.Output.ID,
+					Item: types.CompletionItem{
+						ID:             item.ID,
+						ToolCall:       functionCall,
+						ToolCallResult: tcResult,
+					},
+				},
+			},
+		})
+	}
+
+	return types.ToolOutput{
+		Output: types.Message{
+			Role: "user",
+			Items: []types.CompletionItem{
+				{
+					ID:             item.ID,
+					ToolCallResult: tcResult,
+				},
+			},
+		},
+		Done: true,
+	}
+}
+
+// handoffRequest is the request of the runs after a handoff, which are made
+// with the agent taking over and its own instructions.
+func handoffRequest(req types.CompletionRequest, to string) types.CompletionRequest {
+	req.Agent = to
+	req.Model = to
+	req.SystemPrompt = ""
+	return req
+}
+
+// handoffMessage tells the agent taking over why it was handed the
+// conversation.
+func handoffMessage(handoff types.Handoff) types.Message {
+	text := fmt.Sprintf("%s The conversation was handed off to you by %s.", handoffPrefix, handoff.From)
+	if handoff.Summary != "" {
+		text += " Their summary of it: " + handoff.Summary
+	}
+	return types.Message{
+		ID:   uuid.String(),
+		Role: "user",
+		Items: []types.CompletionItem{
+			{
+				Content: &mcp.Content{
+					Type: "text",
+					Text: text + "\nContinue the conversation with the user from here.",
+				},
+			},
+		},
+	}
+}
+
+func handoffsKey(threadName string) string {
+	if threadName == "" {
+		return types.HandoffsSessionKey
+	}
+	return types.HandoffsSessionKey + "/" + threadName
+}
+
+// recordHandoff adds the handoff to the ones of the session's chat and makes
+// the agent taking over the current agent.
+func recordHandoff(session *mcp.Session, threadName string, handoff types.Handoff) {
+	var handoffs []types.Handoff
+	session.Get(handoffsKey(threadName), &handoffs)
+	session.Set(handoffsKey(threadName), append(handoffs, handoff))
+	session.Set(types.CurrentAgentSessionKey, mcp.SavedString(handoff.To))
+}
+
+// handoffTarget returns the agent that has the conversation of agent in the
+// session's chat, after the handoffs made in it.
+func handoffTarget(session *mcp.Session, threadName, agent string) string {
+	var handoffs []types.Handoff
+	session.Get(handoffsKey(threadName), &handoffs)
+	return types.HandoffTarget(handoffs, agent)
+}
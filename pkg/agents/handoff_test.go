@@ -0,0 +1,79 @@
+package agents
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+func TestHandoff(t *testing.T) {
+	config := types.Config{
+		Agents: map[string]types.Agent{
+			"triage":  {HookAgent: types.HookAgent{Handoffs: types.StringList{"billing"}}},
+			"billing": {HookAgent: types.HookAgent{Description: "Answers questions about invoices", Handoffs: types.StringList{"triage"}}},
+			"sales":   {},
+		},
+	}
+	session := mcp.NewEmptySession(t.Context())
+	ctx := types.WithConfig(mcp.WithSession(t.Context(), session), config)
+
+	tool := handoffTool(config, config.Agents["triage"])
+	if !isHandoff(tool) || !strings.Contains(tool.Target.Description, "billing: Answers questions about invoices") {
+		t.Fatalf("unexpected handoff tool %+v", tool)
+	}
+
+	call := func(run *types.Execution, args string) types.ToolOutput {
+		return (&Agents{}).handoff(ctx, run, types.CompletionItem{
+			ID: "item-1",
+			ToolCall: &types.ToolCall{
+				CallID:    "call-1",
+				Name:      types.HandoffToolName,
+				Arguments: args,
+			},
+		}, nil)
+	}
+
+	run := &types.Execution{
+		Request:  types.CompletionRequest{Model: "triage"},
+		Response: &types.CompletionResponse{},
+	}
+	output := call(run, `{"agent": "sales", "summary": "wants a discount"}`)
+	if result := output.Output.Items[0].ToolCallResult; !result.Output.IsError || run.Handoff != nil {
+		t.Fatalf("expected a handoff to an agent that isn't a handoff of triage to fail, got %+v", result)
+	}
+
+	output = call(run, `{"agent": "billing", "summary": "was charged twice"}`)
+	if result := output.Output.Items[0].ToolCallResult; result.Output.IsError || result.CallID != "call-1" {
+		t.Fatalf("unexpected handoff result %+v", result)
+	}
+	if run.Handoff == nil || run.Handoff.From != "triage" || run.Handoff.To != "billing" || run.Handoff.Summary != "was charged twice" {
+		t.Fatalf("unexpected handoff %+v", run.Handoff)
+	}
+
+	req := handoffRequest(types.CompletionRequest{Model: "triage", SystemPrompt: "Triage requests."}, "billing")
+	if req.GetAgent() != "billing" || req.SystemPrompt != "" {
+		t.Errorf("expected the request to be made with billing and its instructions, got %+v", req)
+	}
+	if msg := handoffMessage(*run.Handoff); !strings.HasPrefix(msg.Items[0].Content.Text, handoffPrefix) ||
+		!strings.Contains(msg.Items[0].Content.Text, "was charged twice") {
+		t.Errorf("unexpected handoff message %+v", msg.Items[0].Content)
+	}
+
+	recordHandoff(session, "", *run.Handoff)
+	if target := handoffTarget(session, "", "triage"); target != "billing" {
+		t.Errorf("expected chats with triage to go to billing, got %s", target)
+	}
+	if target := handoffTarget(session, "other", "triage"); target != "triage" {
+		t.Errorf("expected other threads to keep their agent, got %s", target)
+	}
+	if current := types.CurrentAgent(ctx); current != "billing" {
+		t.Errorf("expected billing to be the current agent, got %s", current)
+	}
+
+	recordHandoff(session, "", types.Handoff{From: "billing", To: "triage"})
+	if target := handoffTarget(session, "", "triage"); target != "triage" {
+		t.Errorf("expected the conversation to be back with triage, got %s", target)
+	}
+}
@@ -0,0 +1,70 @@
+package agents
+
+import (
+	"context"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// runKey marks the context of a run, so that the runs of its subagents are
+// known to be part of it.
+type runKey struct{}
+
+// accountLimiter returns the limiter of the server with the account of the
+// session, if the server limits accounts.
+func accountLimiter(ctx context.Context) (types.AccountLimiter, string, bool) {
+	var limiter types.AccountLimiter
+	if !mcp.SessionFromContext(ctx).Root().Get(types.LimiterSessionKey, &limiter) {
+		return nil, "", false
+	}
+	_, accountID := types.GetSessionAndAccountID(ctx)
+	return limiter, accountID, true
+}
+
+// startRun checks that the account can chat with the agent and takes a slot
+// of its concurrent runs, for a run that isn't part of another one. done frees
+// the slot.
+func startRun(ctx context.Context, agent string) (_ context.Context, done func(), _ error) {
+	if ctx.Value(runKey{}) != nil {
+		return ctx, func() {}, nil
+	}
+
+	var settings types.AccountSettings
+	if _, isAgent := types.ConfigFromContext(ctx).Agents[agent]; isAgent &&
+		mcp.SessionFromContext(ctx).Root().Get(types.AccountSettingsSessionKey, &settings) && !settings.AgentAllowed(agent) {
+		return ctx, nil, mcp.ErrRPCInvalidRequest.WithMessage("agent %q is not allowed for the account", agent)
+	}
+
+	done = func() {}
+	if limiter, accountID, ok := accountLimiter(ctx); ok {
+		var err error
+		if done, err = limiter.StartRun(accountID); err != nil {
+			return ctx, nil, err
+		}
+	}
+	return context.WithValue(ctx, runKey{}, true), done, nil
+}
+
+// checkCompletion fails when the account isn't allowed to use the model or
+// used its tokens of the day. The default model, an empty one, is allowed.
+func checkCompletion(ctx context.Context, model string) error {
+	var settings types.AccountSettings
+	if model != "" && mcp.SessionFromContext(ctx).Root().Get(types.AccountSettingsSessionKey, &settings) && !settings.ModelAllowed(model) {
+		return mcp.ErrRPCInvalidRequest.WithMessage("model %q is not allowed for the account", model)
+	}
+
+	limiter, accountID, ok := accountLimiter(ctx)
+	if !ok {
+		return nil
+	}
+	return limiter.CheckTokens(accountID)
+}
+
+// addTokens counts the tokens of a completion against the tokens of the day
+// of the account.
+func addTokens(ctx context.Context, usage types.Usage) {
+	if limiter, accountID, ok := accountLimiter(ctx); ok {
+		limiter.AddTokens(accountID, usage.TotalTokens())
+	}
+}
@@ -0,0 +1,177 @@
+package agents
+
+import (
+	"log/slog"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// defaultPinnedFraction is the part of the context window pinned messages
+// may take when the agent doesn't set a limit.
+const defaultPinnedFraction = 0.25
+
+// isPinned checks whether a message is pinned by the pinned meta key on its
+// content or tool results.
+func isPinned(msg types.Message) bool {
+	for _, item := range msg.Items {
+		if item.Content != nil && item.Content.Meta[types.PinnedMetaKey] == true {
+			return true
+		}
+		if item.ToolCallResult != nil && item.ToolCallResult.Output.Meta[types.PinnedMetaKey] == true {
+			return true
+		}
+	}
+	return false
+}
+
+type pinRule struct {
+	types.PinRule
+	match *regexp.Regexp
+}
+
+func compilePinRules(pinning *types.AgentPinning) []pinRule {
+	if pinning == nil {
+		return nil
+	}
+	rules := make([]pinRule, 0, len(pinning.Rules))
+	for _, rule := range pinning.Rules {
+		// Rules are validated with the config, so this only skips bad rules
+		// that came from a hook.
+		match, err := regexp.Compile(rule.Match)
+		if err != nil {
+			slog.Error("skipping invalid pin rule", "match", rule.Match, "error", err)
+			continue
+		}
+		rules = append(rules, pinRule{PinRule: rule, match: match})
+	}
+	return rules
+}
+
+// matches checks the rule against a message. Tool results are matched by the
+// name of the tool that was called, which is only in the message of the call.
+func (r pinRule) matches(msg types.Message, toolNames map[string]string) bool {
+	if r.Role != "" && r.Role != msg.Role {
+		return false
+	}
+
+	var (
+		texts   []string
+		toolHit = r.Tool == ""
+	)
+	for _, item := range msg.Items {
+		if item.Content != nil {
+			texts = append(texts, item.Content.Text)
+		}
+		if item.ToolCallResult != nil {
+			if toolNames[item.ToolCallResult.CallID] == r.Tool {
+				toolHit = true
+			}
+			for _, content := range item.ToolCallResult.Output.Content {
+				texts = append(texts, content.Text)
+			}
+		}
+	}
+	if !toolHit {
+		return false
+	}
+
+	return r.Match == "" || r.match.MatchString(strings.Join(texts, "\n"))
+}
+
+// pinnedMessages returns the indexes of the messages that compaction carries
+// forward. A pinned tool call or result brings along the other side of the
+// call, so the carried messages stay valid. Pinned messages are kept oldest
+// first while they fit in maxTokens, the rest are summarized.
+func pinnedMessages(model string, messages []types.Message, pinning *types.AgentPinning, maxTokens int) map[int]bool {
+	var (
+		rules       = compilePinRules(pinning)
+		toolNames   = map[string]string{}
+		callIndex   = map[string]int{}
+		resultIndex = map[string][]int{}
+		seeds       []int
+	)
+	for i, msg := range messages {
+		for _, item := range msg.Items {
+			if item.ToolCall != nil {
+				toolNames[item.ToolCall.CallID] = item.ToolCall.Name
+				callIndex[item.ToolCall.CallID] = i
+			}
+			if item.ToolCallResult != nil {
+				resultIndex[item.ToolCallResult.CallID] = append(resultIndex[item.ToolCallResult.CallID], i)
+			}
+		}
+	}
+
+	for i, msg := range messages {
+		if IsCompactionSummary(msg) {
+			continue
+		}
+		if isPinned(msg) || slices.ContainsFunc(rules, func(rule pinRule) bool {
+			return rule.matches(msg, toolNames)
+		}) {
+			seeds = append(seeds, i)
+		}
+	}
+
+	var (
+		pinned = map[int]bool{}
+		used   int
+	)
+	for _, seed := range seeds {
+		if pinned[seed] {
+			continue
+		}
+
+		group := map[int]bool{seed: true}
+		for queue := []int{seed}; len(queue) > 0; queue = queue[1:] {
+			for _, item := range messages[queue[0]].Items {
+				var linked []int
+				if item.ToolCall != nil {
+					linked = slices.Clone(resultIndex[item.ToolCall.CallID])
+				}
+				if item.ToolCallResult != nil {
+					if i, ok := callIndex[item.ToolCallResult.CallID]; ok {
+						linked = append(linked, i)
+					}
+				}
+				for _, i := range linked {
+					if !group[i] {
+						group[i] = true
+						queue = append(queue, i)
+					}
+				}
+			}
+		}
+
+		var groupMessages []types.Message
+		for _, i := range slices.Sorted(maps.Keys(group)) {
+			if !pinned[i] {
+				groupMessages = append(groupMessages, messages[i])
+			}
+		}
+		tokens := estimateTokens(model, groupMessages, "", nil)
+		if used+tokens > maxTokens {
+			slog.Warn("pinned message exceeds the pinned token budget, it will be summarized", "messageID", messages[seed].ID, "tokens", tokens, "budget", maxTokens)
+			continue
+		}
+		used += tokens
+		for i := range group {
+			pinned[i] = true
+		}
+	}
+
+	return pinned
+}
+
+// pinnedTokenBudget returns how many tokens of pinned messages compaction
+// carries forward.
+func pinnedTokenBudget(pinning *types.AgentPinning, contextWindowSize int) int {
+	if pinning != nil && pinning.MaxTokens > 0 {
+		return pinning.MaxTokens
+	}
+	return int(float64(contextWindowSize) * defaultPinnedFraction)
+}
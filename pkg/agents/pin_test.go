@@ -0,0 +1,127 @@
+package agents
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+type completerFunc func(ctx context.Context, req types.CompletionRequest, opts ...types.CompletionOptions) (*types.CompletionResponse, error)
+
+func (f completerFunc) Complete(ctx context.Context, req types.CompletionRequest, opts ...types.CompletionOptions) (*types.CompletionResponse, error) {
+	return f(ctx, req, opts...)
+}
+
+func textMessage(id, role, text string, meta map[string]any) types.Message {
+	return types.Message{
+		ID:   id,
+		Role: role,
+		Items: []types.CompletionItem{
+			{Content: &mcp.Content{Type: "text", Text: text, Meta: meta}},
+		},
+	}
+}
+
+func pinTestHistory() []types.Message {
+	return []types.Message{
+		textMessage("m0", "user", "The output MUST be valid YAML.", map[string]any{types.PinnedMetaKey: true}),
+		textMessage("m1", "assistant", "Understood.", nil),
+		{
+			ID:   "m2",
+			Role: "assistant",
+			Items: []types.CompletionItem{
+				{ToolCall: &types.ToolCall{CallID: "call-1", Name: "read_schema", Arguments: "{}"}},
+			},
+		},
+		{
+			ID:   "m3",
+			Role: "user",
+			Items: []types.CompletionItem{
+				{ToolCallResult: &types.ToolCallResult{
+					CallID: "call-1",
+					Output: types.CallResult{Content: []mcp.Content{{Type: "text", Text: "type: object"}}},
+				}},
+			},
+		},
+		textMessage("m4", "user", "Legal: never include customer names.", nil),
+		textMessage("m5", "assistant", "Done with the draft.", nil),
+	}
+}
+
+func TestPinnedMessages(t *testing.T) {
+	pinning := &types.AgentPinning{
+		Rules: []types.PinRule{
+			{Tool: "read_schema"},
+			{Role: "user", Match: `(?i)^legal:`},
+		},
+	}
+
+	pinned := pinnedMessages("gpt-4.1", pinTestHistory(), pinning, 10_000)
+	got := slices.Sorted(maps.Keys(pinned))
+	if want := []int{0, 2, 3, 4}; !slices.Equal(got, want) {
+		t.Errorf("pinned = %v, want %v", got, want)
+	}
+}
+
+func TestPinnedMessagesBudget(t *testing.T) {
+	history := pinTestHistory()
+	history[1] = textMessage("m1", "user", strings.Repeat("a long pinned requirement ", 500), map[string]any{types.PinnedMetaKey: true})
+
+	pinned := pinnedMessages("gpt-4.1", history, nil, 200)
+	got := slices.Sorted(maps.Keys(pinned))
+	if want := []int{0}; !slices.Equal(got, want) {
+		t.Errorf("pinned = %v, want %v, messages past the budget should be summarized", got, want)
+	}
+}
+
+func TestCompactCarriesPinnedMessages(t *testing.T) {
+	var transcript string
+	a := &Agents{
+		completer: completerFunc(func(_ context.Context, req types.CompletionRequest, _ ...types.CompletionOptions) (*types.CompletionResponse, error) {
+			transcript = req.Input[0].Items[0].Content.Text
+			return &types.CompletionResponse{
+				Output: types.Message{
+					Role:  "assistant",
+					Items: []types.CompletionItem{{Content: &mcp.Content{Type: "text", Text: "summary"}}},
+				},
+			}, nil
+		}),
+	}
+
+	newInput := []types.Message{textMessage("m6", "user", "Next step?", nil)}
+	req := types.CompletionRequest{
+		Model: "gpt-4.1",
+		Input: append(pinTestHistory(), newInput...),
+	}
+	pinning := &types.AgentPinning{Rules: []types.PinRule{{Tool: "read_schema"}}}
+
+	result, err := a.compact(t.Context(), req, newInput, nil, pinning, 10_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for _, msg := range result.compactedInput {
+		ids = append(ids, msg.ID)
+	}
+	if !IsCompactionSummary(result.compactedInput[0]) || !slices.Equal(ids[1:], []string{"m0", "m2", "m3", "m6"}) {
+		t.Errorf("compacted input = %v, want the summary followed by m0, m2, m3, m6", ids)
+	}
+
+	var archived []string
+	for _, msg := range result.archivedMessages {
+		archived = append(archived, msg.ID)
+	}
+	if !slices.Equal(archived, []string{"m1", "m4", "m5"}) {
+		t.Errorf("archived = %v, want only the summarized messages", archived)
+	}
+
+	if strings.Contains(transcript, "valid YAML") || strings.Contains(transcript, "read_schema") {
+		t.Errorf("pinned messages should not be summarized, transcript:\n%s", transcript)
+	}
+}
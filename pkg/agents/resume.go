@@ -0,0 +1,75 @@
+package agents
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// interruptedToolResult is the result of a tool call that was in flight when
+// the process running it stopped.
+const interruptedToolResult = "The tool call was interrupted by a restart and did not complete. " +
+	"It may have partially run, check its effects before calling it again."
+
+// checkpoint stores the session with the progress of the run so that it can
+// be resumed, or marked interrupted, after a restart. Failing to store it
+// doesn't fail the run, it only loses the progress on a restart.
+func checkpoint(ctx context.Context, session *mcp.Session) {
+	var checkpointer types.SessionCheckpointer
+	if !session.Get(types.CheckpointerSessionKey, &checkpointer) {
+		return
+	}
+	if err := checkpointer.CheckpointSession(ctx, session.ID()); err != nil {
+		slog.WarnContext(ctx, "failed to checkpoint the run", "session", session.ID(), "error", err)
+	}
+}
+
+// markInterrupted finishes a run that was stored while in progress, so that
+// the conversation can go on. Each of its tool calls that has no result gets
+// an error result saying it was interrupted. A request without new input then
+// continues the run from there.
+func markInterrupted(run *types.Execution) {
+	if run.Done {
+		return
+	}
+
+	run.Done = true
+	run.Interrupted = true
+	if run.Response == nil {
+		return
+	}
+
+	for _, output := range run.Response.Output.Items {
+		if output.ToolCall == nil || run.ToolOutputs[output.ToolCall.CallID].Done {
+			continue
+		}
+		if run.ToolOutputs == nil {
+			run.ToolOutputs = make(map[string]types.ToolOutput)
+		}
+		run.ToolOutputs[output.ToolCall.CallID] = types.ToolOutput{
+			Output: types.Message{
+				Role: "user",
+				Items: []types.CompletionItem{
+					{
+						ID: output.ID,
+						ToolCallResult: &types.ToolCallResult{
+							CallID: output.ToolCall.CallID,
+							Output: types.CallResult{
+								Content: []mcp.Content{
+									{
+										Type: "text",
+										Text: interruptedToolResult,
+									},
+								},
+								IsError: true,
+							},
+						},
+					},
+				},
+			},
+			Done: true,
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package agents
+
+import (
+	"context"
+	"testing"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+type checkpointerFunc func(ctx context.Context, id string) error
+
+func (f checkpointerFunc) CheckpointSession(ctx context.Context, id string) error {
+	return f(ctx, id)
+}
+
+func TestInterruptedRunContinues(t *testing.T) {
+	session := mcp.NewEmptySession(t.Context())
+	ctx := mcp.WithSession(t.Context(), session)
+
+	var checkpointed []string
+	session.Set(types.CheckpointerSessionKey, checkpointerFunc(func(_ context.Context, id string) error {
+		checkpointed = append(checkpointed, id)
+		return nil
+	}))
+
+	// The process stopped while the second of two tool calls was running.
+	run := &types.Execution{
+		PopulatedRequest: &types.CompletionRequest{
+			Input: []types.Message{textMessage("m1", "user", "Deploy it", nil)},
+		},
+		Response: &types.CompletionResponse{
+			Output: types.Message{
+				ID:   "m2",
+				Role: "assistant",
+				Items: []types.CompletionItem{
+					{ID: "i1", ToolCall: &types.ToolCall{CallID: "call-1", Name: "build"}},
+					{ID: "i2", ToolCall: &types.ToolCall{CallID: "call-2", Name: "deploy"}},
+				},
+			},
+		},
+		ToolOutputs: map[string]types.ToolOutput{
+			"call-1": {
+				Output: types.Message{
+					Role: "user",
+					Items: []types.CompletionItem{{ToolCallResult: &types.ToolCallResult{
+						CallID: "call-1",
+						Output: types.CallResult{Content: []mcp.Content{{Type: "text", Text: "built"}}},
+					}}},
+				},
+				Done: true,
+			},
+		},
+	}
+	session.Set(types.PreviousExecutionKey, run)
+	checkpoint(ctx, session)
+	if len(checkpointed) != 1 || checkpointed[0] != session.ID() {
+		t.Fatalf("expected the session to be checkpointed, got %v", checkpointed)
+	}
+
+	markInterrupted(run)
+	if !run.Done || !run.Interrupted {
+		t.Fatalf("expected the run to be done and interrupted, got %+v", run)
+	}
+	if output := run.ToolOutputs["call-1"].Output.Items[0].ToolCallResult.Output; output.IsError {
+		t.Errorf("expected the finished tool call to keep its result, got %+v", output)
+	}
+
+	// A request without new input continues the conversation from the
+	// interrupted tool calls.
+	a := &Agents{}
+	req, _, err := a.populateRequest(ctx, types.Config{}, &types.Execution{}, run, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(req.Input) != 4 {
+		t.Fatalf("expected the input, the tool calls and their two results, got %d messages", len(req.Input))
+	}
+	result := req.Input[3].Items[0].ToolCallResult
+	if result == nil || result.CallID != "call-2" || !result.Output.IsError || result.Output.Content[0].Text != interruptedToolResult {
+		t.Errorf("expected an interrupted result for the unfinished call, got %+v", req.Input[3])
+	}
+}
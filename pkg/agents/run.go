@@ -13,6 +13,7 @@ import (
 
 	"github.com/obot-platform/nanobot/pkg/complete"
 	"github.com/obot-platform/nanobot/pkg/llm/progress"
+	"github.com/obot-platform/nanobot/pkg/log"
 	"github.com/obot-platform/nanobot/pkg/mcp"
 	"github.com/obot-platform/nanobot/pkg/schema"
 	"github.com/obot-platform/nanobot/pkg/sessiondata"
@@ -22,8 +23,9 @@ import (
 )
 
 type Agents struct {
-	completer types.Completer
-	registry  *tools.Service
+	completer   types.Completer
+	registry    *tools.Service
+	tokenCounts tokenCountCache
 }
 
 type ToolListOptions struct {
@@ -56,6 +58,9 @@ func (a *Agents) addTools(ctx context.Context, req *types.CompletionRequest, age
 	if err != nil {
 		return nil, fmt.Errorf("failed to build tool mappings: %w", err)
 	}
+	if len(agent.Handoffs) > 0 {
+		toolMappings[types.HandoffToolName] = handoffTool(types.ConfigFromContext(ctx), *agent)
+	}
 
 	switch opt.ToolIncludeContext {
 	case "none":
@@ -70,6 +75,15 @@ func (a *Agents) addTools(ctx context.Context, req *types.CompletionRequest, age
 		toolMappings = newMappings
 	}
 
+	if len(opt.AllowedTools) > 0 {
+		maps.DeleteFunc(toolMappings, func(key string, _ types.TargetMapping[types.TargetTool]) bool {
+			return !slices.Contains(opt.AllowedTools, key)
+		})
+	}
+	maps.DeleteFunc(toolMappings, func(key string, _ types.TargetMapping[types.TargetTool]) bool {
+		return slices.Contains(opt.DeniedTools, key)
+	})
+
 	for _, key := range slices.Sorted(maps.Keys(toolMappings)) {
 		toolMapping := toolMappings[key]
 
@@ -102,6 +116,28 @@ func (a *Agents) addTools(ctx context.Context, req *types.CompletionRequest, age
 	return toolMappings, nil
 }
 
+// toolOutputOrder returns the call IDs of the run's tool outputs in the order
+// the model made the calls, followed by any others sorted by ID.
+func toolOutputOrder(run *types.Execution) []string {
+	order := make([]string, 0, len(run.ToolOutputs))
+	for _, msg := range append(run.Response.InternalMessages, run.Response.Output) {
+		for _, item := range msg.Items {
+			if item.ToolCall == nil || slices.Contains(order, item.ToolCall.CallID) {
+				continue
+			}
+			if _, ok := run.ToolOutputs[item.ToolCall.CallID]; ok {
+				order = append(order, item.ToolCall.CallID)
+			}
+		}
+	}
+	for _, callID := range slices.Sorted(maps.Keys(run.ToolOutputs)) {
+		if !slices.Contains(order, callID) {
+			order = append(order, callID)
+		}
+	}
+	return order
+}
+
 func (a *Agents) populateRequest(ctx context.Context, config types.Config, run *types.Execution, previousRun *types.Execution, opts []types.CompletionOptions) (types.CompletionRequest, types.ToolMappings, error) {
 	req := run.Request
 
@@ -127,7 +163,7 @@ func (a *Agents) populateRequest(ctx context.Context, config types.Config, run *
 			}
 		}
 
-		for _, callID := range slices.Sorted(maps.Keys(previousRun.ToolOutputs)) {
+		for _, callID := range toolOutputOrder(previousRun) {
 			toolCall := previousRun.ToolOutputs[callID]
 			if toolCall.Done {
 				input = append(input, toolCall.Output)
@@ -185,6 +221,14 @@ func (a *Agents) populateRequest(ctx context.Context, config types.Config, run *
 		req.ToolChoice = agent.ToolChoice
 	}
 
+	if req.ParallelToolCalls == nil && agent.ParallelToolCalls != nil {
+		req.ParallelToolCalls = agent.ParallelToolCalls
+	}
+
+	if req.PromptCaching == nil && agent.PromptCaching != nil {
+		req.PromptCaching = agent.PromptCaching
+	}
+
 	if previousRun != nil {
 		// Don't allow tool choice if this is a follow-on request
 		req.ToolChoice = ""
@@ -207,7 +251,8 @@ func (a *Agents) populateRequest(ctx context.Context, config types.Config, run *
 		req.ThreadName = agent.ThreadName
 	}
 
-	req.Model = agent.Model
+	req.Model = agent.Model.Primary()
+	req.FallbackModels = agent.Model.Fallbacks()
 
 	toolMapping, err := a.addTools(ctx, &req, &agent, opts)
 	if err != nil {
@@ -390,8 +435,19 @@ func (a *Agents) Complete(ctx context.Context, req types.CompletionRequest, opts
 		currentRun           = &types.Execution{}
 		baseConfig           = types.ConfigFromContext(ctx)
 		startID              = ""
+		opt                  = complete.Complete(opts...)
+		usage                runUsage
+		exhausted            *types.BudgetExhausted
 	)
 
+	ctx = log.WithAttrs(ctx, slog.String(log.AgentKey, req.GetAgent()))
+
+	ctx, runDone, err := startRun(ctx, req.GetAgent())
+	if err != nil {
+		return nil, err
+	}
+	defer runDone()
+
 	if len(req.Input) > 0 {
 		startID = req.Input[0].ID
 		if startID == "" {
@@ -400,6 +456,23 @@ func (a *Agents) Complete(ctx context.Context, req types.CompletionRequest, opts
 		}
 	}
 
+	a.notifyHooks(ctx, req.GetAgent(), "runStart", nil, &types.AgentRunHook{
+		Agent:     req.GetAgent(),
+		SessionID: session.ID(),
+		RunID:     startID,
+		Input:     req.Input,
+	})
+	defer func() {
+		if err != nil {
+			a.notifyHooks(ctx, req.GetAgent(), "error", nil, &types.AgentRunHook{
+				Agent:     req.GetAgent(),
+				SessionID: session.ID(),
+				RunID:     startID,
+				Error:     err.Error(),
+			})
+		}
+	}()
+
 	if req.ThreadName != "" {
 		previousExecutionKey = fmt.Sprintf("%s/%s", previousExecutionKey, req.ThreadName)
 	}
@@ -408,10 +481,18 @@ func (a *Agents) Complete(ctx context.Context, req types.CompletionRequest, opts
 		isChat = false
 	}
 
-	if ch := complete.Complete(opts...).Chat; ch != nil {
+	if ch := opt.Chat; ch != nil {
 		isChat = *ch
 	}
 
+	if isChat {
+		// An agent that handed the conversation off no longer has it, the
+		// agent it was handed to continues it.
+		if target := handoffTarget(session, req.ThreadName, req.GetAgent()); target != req.GetAgent() {
+			req = handoffRequest(req, target)
+		}
+	}
+
 	// Save the original request to the Execution status
 	currentRun.Request = req
 
@@ -422,6 +503,13 @@ func (a *Agents) Complete(ctx context.Context, req types.CompletionRequest, opts
 			previousRun = &lookup
 		}
 
+		if previousRun != nil && !previousRun.Done {
+			// The run was stored in progress by a process that stopped
+			// before it finished.
+			markInterrupted(previousRun)
+			session.Set(previousExecutionKey, previousRun)
+		}
+
 		if req.NewThread && previousRun != nil {
 			session.Set(previousExecutionKey+"/"+time.Now().Format(time.RFC3339), previousRun)
 			session.Set(previousExecutionKey, nil)
@@ -432,6 +520,13 @@ func (a *Agents) Complete(ctx context.Context, req types.CompletionRequest, opts
 				session.Set(previousExecutionKey, fallBack)
 			}
 		}()
+
+		if startID != "" {
+			// Files changed by tools in this turn are journaled under its ID so
+			// the turn can be undone, until the turn ends.
+			session.Set(types.TurnSessionKey, startID)
+			defer session.Delete(types.TurnSessionKey)
+		}
 	}
 
 	for {
@@ -443,7 +538,7 @@ func (a *Agents) Complete(ctx context.Context, req types.CompletionRequest, opts
 		// Use a new context so that we don't leak values.
 		runCtx := types.WithConfig(ctx, config)
 
-		if err := a.run(runCtx, config, currentRun, previousRun, opts); err != nil {
+		if err := a.run(runCtx, config, currentRun, previousRun, startID, opts); err != nil {
 			return nil, err
 		}
 
@@ -455,10 +550,35 @@ func (a *Agents) Complete(ctx context.Context, req types.CompletionRequest, opts
 
 		if isChat {
 			session.Set(previousExecutionKey, currentRun)
+			checkpoint(ctx, session)
 		}
 
-		// This doesn't return an error because any issues we run into should be returned to the LLM for further processing.
-		a.toolCalls(runCtx, currentRun, opts)
+		if exhausted != nil {
+			// The budget is spent, so this is the last turn even if the model
+			// asked for tools anyway.
+			currentRun.Done = true
+		} else {
+			// This doesn't return an error because any issues we run into should be returned to the LLM for further processing.
+			a.toolCalls(runCtx, currentRun, opts)
+			a.notifyToolCalls(runCtx, currentRun, startID)
+			if handoff := currentRun.Handoff; handoff != nil {
+				handoff.RunID = startID
+				if isChat {
+					recordHandoff(session, req.ThreadName, *handoff)
+				}
+				a.notifyHooks(runCtx, handoff.From, "handoff", nil, &types.AgentHandoffHook{
+					Agent:     handoff.From,
+					SessionID: session.ID(),
+					RunID:     startID,
+					Handoff:   *handoff,
+				})
+			}
+		}
+		usage.add(currentRun)
+
+		if isChat && !currentRun.Done {
+			checkpoint(ctx, session)
+		}
 
 		if currentRun.Done {
 			if isChat {
@@ -466,6 +586,8 @@ func (a *Agents) Complete(ctx context.Context, req types.CompletionRequest, opts
 			}
 
 			finalResponse := *currentRun.Response
+			finalResponse.BudgetExhausted = exhausted
+			finalResponse.Usage = usage.reported
 
 			if startID != "" && currentRun.PopulatedRequest != nil {
 				i := slices.IndexFunc(currentRun.PopulatedRequest.Input, func(msg types.Message) bool {
@@ -476,13 +598,33 @@ func (a *Agents) Complete(ctx context.Context, req types.CompletionRequest, opts
 				}
 			}
 
+			a.notifyHooks(ctx, req.GetAgent(), "runFinish", nil, &types.AgentRunHook{
+				Agent:     req.GetAgent(),
+				SessionID: session.ID(),
+				RunID:     startID,
+				Response:  &finalResponse,
+			})
 			return &finalResponse, nil
 		}
 
 		previousRun = currentRun
+		if handoff := previousRun.Handoff; handoff != nil {
+			req = handoffRequest(req, handoff.To)
+		}
 		currentRun = &types.Execution{
 			Request: req.Reset(),
 		}
+		if handoff := previousRun.Handoff; handoff != nil {
+			currentRun.Request.Input = []types.Message{handoffMessage(*handoff)}
+		}
+
+		if exhausted = budgetExhausted(opt, config.Agents[previousRun.Request.GetAgent()], usage); exhausted != nil {
+			a.notifyBudgetExhausted(runCtx, config, previousRun.Request.GetAgent(), session.ID(), exhausted)
+			currentRun.Request.Input = append(currentRun.Request.Input, budgetExhaustedMessage(exhausted))
+			opts = append(slices.Clone(opts), types.CompletionOptions{
+				ToolChoice: &mcp.ToolChoice{Mode: "none"},
+			})
+		}
 	}
 }
 
@@ -532,10 +674,21 @@ func (a *Agents) configHook(ctx context.Context, baseConfig types.Config, agentN
 	}) {
 		agent.Hooks = append(agent.Hooks, mcp.HookMapping{Name: "config", Targets: []mcp.HookTarget{{Target: "nanobot.system/config"}}})
 	}
+	// The memory server is only registered when sessions are persisted, and the
+	// knowledge server only when an embedding model is configured.
+	for _, server := range []string{"nanobot.subagent", "nanobot.memory", "nanobot.knowledge"} {
+		target := mcp.HookTarget{Target: server + "/config"}
+		if a.registry.HasServer(server) && !slices.ContainsFunc(agent.Hooks, func(hook mcp.HookMapping) bool {
+			return hook.Name == "config" && slices.Contains(hook.Targets, target)
+		}) {
+			agent.Hooks = append(agent.Hooks, mcp.HookMapping{Name: "config", Targets: []mcp.HookTarget{target}})
+		}
+	}
 	hookResult, err := mcp.InvokeHooks(ctx, a.registry, agent.Hooks, &types.AgentConfigHook{
-		Agent:     &agent.HookAgent,
-		Meta:      sessionInit.Meta,
-		SessionID: session.ID(),
+		Agent:              &agent.HookAgent,
+		Meta:               sessionInit.Meta,
+		SessionID:          session.ID(),
+		ClientCapabilities: session.ClientCapabilities(),
 	}, "config", nil)
 	if err != nil {
 		return types.Config{}, fmt.Errorf("failed to invoke config hook: %w", err)
@@ -601,7 +754,9 @@ func (a *Agents) runAfter(ctx context.Context, config types.Config, req types.Co
 	return resp, nil
 }
 
-func (a *Agents) run(ctx context.Context, config types.Config, run *types.Execution, prev *types.Execution, opts []types.CompletionOptions) error {
+func (a *Agents) run(ctx context.Context, config types.Config, run *types.Execution, prev *types.Execution, runID string, opts []types.CompletionOptions) error {
+	blocked := a.guardInput(ctx, config, run, runID)
+
 	completionRequest, toolMapping, err := a.populateRequest(ctx, config, run, prev, opts)
 	if err != nil {
 		return err
@@ -610,19 +765,27 @@ func (a *Agents) run(ctx context.Context, config types.Config, run *types.Execut
 	// Check if compaction is needed
 	agent, agentExists := config.Agents[completionRequest.GetAgent()]
 	if agentExists {
-		ctxWindowSize := getContextWindowSize(agent.ContextWindow)
-		if shouldCompact(completionRequest, ctxWindowSize) {
+		ctxWindowSize := getContextWindowSize(agent.ContextWindow, a.modelInfo(ctx, completionRequest.Model))
+		if a.shouldCompact(ctx, completionRequest, ctxWindowSize) {
 			var prevCompacted []types.Message
 			if prev != nil {
 				prevCompacted = prev.CompactedMessages
 			}
 
-			result, compactErr := a.compact(ctx, completionRequest, run.Request.Input, prevCompacted)
+			result, compactErr := a.compact(ctx, completionRequest, run.Request.Input, prevCompacted,
+				agent.Pinning, pinnedTokenBudget(agent.Pinning, ctxWindowSize))
 			if compactErr != nil {
-				slog.Error("compaction failed, continuing without", "error", compactErr)
+				slog.ErrorContext(ctx, "compaction failed, continuing without", "error", compactErr)
 			} else if result != nil {
 				completionRequest.Input = result.compactedInput
 				run.CompactedMessages = result.archivedMessages
+				a.notifyHooks(ctx, completionRequest.GetAgent(), "compaction", nil, &types.AgentCompactionHook{
+					Agent:     completionRequest.GetAgent(),
+					SessionID: mcp.SessionFromContext(ctx).Root().ID(),
+					RunID:     runID,
+					Archived:  len(result.archivedMessages) - len(prevCompacted),
+					Kept:      len(result.compactedInput),
+				})
 			}
 		}
 	}
@@ -630,6 +793,9 @@ func (a *Agents) run(ctx context.Context, config types.Config, run *types.Execut
 	if run.CompactedMessages == nil && prev != nil {
 		run.CompactedMessages = prev.CompactedMessages
 	}
+	if prev != nil {
+		run.UndoneMessages = prev.UndoneMessages
+	}
 
 	// Don't forget about old tools that might not be in use anymore. If the old name mapped to a
 	// different tool we will have a problem but, oh well?
@@ -641,6 +807,13 @@ func (a *Agents) run(ctx context.Context, config types.Config, run *types.Execut
 
 	run.ToolToMCPServer = allToolMappings
 
+	if blocked {
+		run.PopulatedRequest = &completionRequest
+		run.Response = blockedResponse(ctx, config, completionRequest.GetAgent(), opts)
+		run.Response.InputReplacement = guardrailMessage(config.Agents[completionRequest.GetAgent()].Guardrails)
+		return nil
+	}
+
 	completionRequest, resp, err := a.runBefore(ctx, config, completionRequest)
 	if err != nil {
 		return fmt.Errorf("failed to run before agent: %w", err)
@@ -660,15 +833,27 @@ func (a *Agents) run(ctx context.Context, config types.Config, run *types.Execut
 		return nil
 	}
 
-	resp, err = a.completer.Complete(ctx, modifiedRequest, opts...)
+	if err := checkCompletion(ctx, modifiedRequest.Model); err != nil {
+		return err
+	}
+
+	completeOpts := opts
+	if hasOutputGuardrails(config, completionRequest.GetAgent()) {
+		// Nothing is shown before the output guardrails passed, guardOutput
+		// sends the answer.
+		completeOpts = withoutProgress(opts)
+	}
+	resp, err = a.completer.Complete(ctx, modifiedRequest, completeOpts...)
 	if err != nil {
 		return err
 	}
+	recordUsage(ctx, modifiedRequest.Model, resp)
 
 	resp, err = a.runAfter(ctx, config, completionRequest, resp)
 	if err != nil {
 		return fmt.Errorf("failed to run after agent: %w", err)
 	}
+	a.guardOutput(ctx, config, completionRequest.GetAgent(), runID, resp, opts)
 
 	run.Response = resp
 	return nil
@@ -0,0 +1,103 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+	"github.com/obot-platform/nanobot/pkg/uuid"
+)
+
+// TaskToolName is the name of the tool that delegates work to a sub-agent. It
+// is never offered to sub-agents, so tasks don't nest.
+const TaskToolName = "task"
+
+const taskInstructions = `
+
+## Sub-agent
+
+You are a sub-agent working on a task delegated by another agent. You can't ask the user questions, so make reasonable assumptions and note them.
+When you are done, reply with a concise report of what you found or did, including the specific file paths, names, and values the other agent will need. The report is the only part of your work the other agent sees.`
+
+// Task is work delegated to a sub-agent. The sub-agent runs as Agent with its
+// own conversation, so only its final report reaches the caller.
+type Task struct {
+	Agent  string
+	Prompt string
+	// Tools limits the sub-agent to these tools, all of the agent's tools
+	// when empty.
+	Tools     []string
+	MaxTurns  int
+	MaxTokens int
+}
+
+type TaskResult struct {
+	Report string `json:"report"`
+	// BudgetExhausted is set when the sub-agent was stopped by its turn or
+	// token budget and reported what it had so far.
+	BudgetExhausted bool `json:"budgetExhausted,omitempty"`
+}
+
+// RunTask runs a task to completion in a sub-agent and returns its report.
+func (a *Agents) RunTask(ctx context.Context, task Task) (*TaskResult, error) {
+	config := types.ConfigFromContext(ctx)
+	agent, ok := config.Agents[task.Agent]
+	if !ok {
+		return nil, fmt.Errorf("agent %q not found", task.Agent)
+	}
+
+	// The sub-agent shares the configuration of the agent it runs as, with
+	// instructions that ask for a report at the end.
+	config.Agents = maps.Clone(config.Agents)
+	agent.Instructions.Instructions += taskInstructions
+	config.Agents[task.Agent] = agent
+
+	resp, err := a.Complete(types.WithConfig(ctx, config), types.CompletionRequest{
+		Agent: task.Agent,
+		Input: []types.Message{
+			{
+				ID:   uuid.String(),
+				Role: "user",
+				Items: []types.CompletionItem{
+					{
+						Content: &mcp.Content{
+							Type: "text",
+							Text: task.Prompt,
+						},
+					},
+				},
+			},
+		},
+	}, types.CompletionOptions{
+		Chat:           new(false),
+		AllowedTools:   task.Tools,
+		DeniedTools:    []string{TaskToolName, FanOutToolName},
+		MaxTurns:       task.MaxTurns,
+		MaxTotalTokens: task.MaxTokens,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TaskResult{
+		Report:          messageText(resp.Output),
+		BudgetExhausted: resp.BudgetExhausted != nil,
+	}
+	if result.Report == "" {
+		result.Report = "The sub-agent finished without a report."
+	}
+	return result, nil
+}
+
+func messageText(msg types.Message) string {
+	var texts []string
+	for _, item := range msg.Items {
+		if item.Content != nil && item.Content.Type == "text" && item.Content.Text != "" {
+			texts = append(texts, item.Content.Text)
+		}
+	}
+	return strings.TrimSpace(strings.Join(texts, "\n"))
+}
@@ -2,14 +2,18 @@ package agents
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"image"
 	_ "image/gif"
 	_ "image/jpeg"
 	_ "image/png"
+	"log/slog"
 	"math"
 	"strings"
+	"sync"
 
 	"github.com/obot-platform/nanobot/pkg/types"
 	tiktoken "github.com/pkoukk/tiktoken-go"
@@ -24,6 +28,76 @@ func init() {
 	image.RegisterFormat("webp", "RIFF????WEBP", webp.Decode, webp.DecodeConfig)
 }
 
+// maxTokenCounts bounds the number of provider token counts that are cached.
+const maxTokenCounts = 10_000
+
+// tokenCountKey identifies a request by the last of its messages.
+type tokenCountKey struct {
+	model     string
+	messageID string
+	messages  int
+}
+
+// tokenCountCache holds the token counts of requests, as counted by their
+// provider, by the ID of their last message. The zero value is ready to use.
+type tokenCountCache struct {
+	lock   sync.Mutex
+	counts map[tokenCountKey]int
+}
+
+func (c *tokenCountCache) get(key tokenCountKey) (int, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	tokens, ok := c.counts[key]
+	return tokens, ok
+}
+
+func (c *tokenCountCache) set(key tokenCountKey, tokens int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.counts == nil || len(c.counts) >= maxTokenCounts {
+		c.counts = map[tokenCountKey]int{}
+	}
+	c.counts[key] = tokens
+}
+
+// countRequestTokens returns the number of input tokens of the request. When
+// the completer can count tokens the provider is asked, once per last message.
+// If the provider can't count them, the messages after the last counted
+// request are estimated, or the whole request when none was counted.
+func (a *Agents) countRequestTokens(ctx context.Context, req types.CompletionRequest) int {
+	counter, ok := a.completer.(types.TokenCounter)
+	if !ok || len(req.Input) == 0 {
+		return estimateTokens(req.Model, req.Input, req.SystemPrompt, req.Tools)
+	}
+
+	keyAt := func(i int) tokenCountKey {
+		return tokenCountKey{model: req.Model, messageID: req.Input[i].ID, messages: i + 1}
+	}
+
+	last := len(req.Input) - 1
+	if req.Input[last].ID != "" {
+		if tokens, ok := a.tokenCounts.get(keyAt(last)); ok {
+			return tokens
+		}
+
+		tokens, err := counter.CountTokens(ctx, req)
+		if err == nil {
+			a.tokenCounts.set(keyAt(last), tokens)
+			return tokens
+		} else if !errors.Is(err, types.ErrTokenCountingUnsupported) {
+			slog.WarnContext(ctx, "failed to count tokens with the provider, estimating them", "model", req.Model, "error", err)
+		}
+	}
+
+	for i := last - 1; i >= 0; i-- {
+		if tokens, ok := a.tokenCounts.get(keyAt(i)); ok {
+			return tokens + estimateTokens(req.Model, req.Input[i+1:], "", nil)
+		}
+	}
+	return estimateTokens(req.Model, req.Input, req.SystemPrompt, req.Tools)
+}
+
 // estimateTokens estimates the total token count for a set of messages, a system prompt, and tool definitions.
 // It uses the cl100k_base encoding (reasonable for both OpenAI and Anthropic models).
 // Falls back to len(text)/4 heuristic if tiktoken encoding fails.
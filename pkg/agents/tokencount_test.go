@@ -2,7 +2,9 @@ package agents
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
+	"errors"
 	"image"
 	"image/color"
 	"image/jpeg"
@@ -14,6 +16,62 @@ import (
 	"github.com/pkoukk/tiktoken-go"
 )
 
+// countingCompleter counts tokens with count, recording the requests.
+type countingCompleter struct {
+	completerFunc
+	count    func(types.CompletionRequest) (int, error)
+	requests int
+}
+
+func (c *countingCompleter) CountTokens(_ context.Context, req types.CompletionRequest) (int, error) {
+	c.requests++
+	return c.count(req)
+}
+
+func TestCountRequestTokens(t *testing.T) {
+	completer := &countingCompleter{
+		count: func(req types.CompletionRequest) (int, error) {
+			return 1000 * len(req.Input), nil
+		},
+	}
+	a := &Agents{completer: completer}
+	req := types.CompletionRequest{
+		Model: "claude-sonnet-4-5",
+		Input: []types.Message{
+			textMessage("m1", "user", "Hello", nil),
+			textMessage("m2", "assistant", "Hi", nil),
+		},
+	}
+
+	if tokens := a.countRequestTokens(t.Context(), req); tokens != 2000 {
+		t.Errorf("expected the provider count of 2000, got %d", tokens)
+	}
+	if tokens := a.countRequestTokens(t.Context(), req); tokens != 2000 || completer.requests != 1 {
+		t.Errorf("expected the cached count of 2000 after 1 request, got %d after %d", tokens, completer.requests)
+	}
+
+	// When the provider fails, the new messages are estimated on top of the
+	// last count.
+	completer.count = func(types.CompletionRequest) (int, error) {
+		return 0, errors.New("overloaded")
+	}
+	req.Input = append(req.Input, textMessage("m3", "user", "How are you?", nil))
+	want := 2000 + estimateTokens(req.Model, req.Input[2:], "", nil)
+	if tokens := a.countRequestTokens(t.Context(), req); tokens != want {
+		t.Errorf("expected %d, got %d", want, tokens)
+	}
+
+	// Completers that can't count tokens get the estimate.
+	completer.count = func(types.CompletionRequest) (int, error) {
+		return 0, types.ErrTokenCountingUnsupported
+	}
+	req.Model = "gpt-5.4"
+	want = estimateTokens(req.Model, req.Input, "", nil)
+	if tokens := a.countRequestTokens(t.Context(), req); tokens != want {
+		t.Errorf("expected the estimate %d, got %d", want, tokens)
+	}
+}
+
 func TestEstimateTokens_BasicMessages(t *testing.T) {
 	messages := []types.Message{
 		{
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/obot-platform/nanobot/pkg/complete"
 	"github.com/obot-platform/nanobot/pkg/mcp"
@@ -72,6 +73,7 @@ func (a *Agents) toolCalls(ctx context.Context, run *types.Execution, opts []typ
 		return
 	}
 
+	var calls []pendingToolCall
 	for _, output := range run.Response.Output.Items {
 		functionCall := output.ToolCall
 
@@ -133,7 +135,19 @@ func (a *Agents) toolCalls(ctx context.Context, run *types.Execution, opts []typ
 				Done:   true,
 			}
 
-			return
+			// Calls after this one are left for the model to make again.
+			break
+		}
+
+		if isHandoff(targetServer) {
+			// The calls the model made before the handoff run first.
+			a.runToolCalls(ctx, run, calls, opts)
+			calls = nil
+			if run.ToolOutputs == nil {
+				run.ToolOutputs = make(map[string]types.ToolOutput)
+			}
+			run.ToolOutputs[functionCall.CallID] = a.handoff(ctx, run, output, opts)
+			continue
 		}
 
 		if targetServer.Target.External {
@@ -142,63 +156,190 @@ func (a *Agents) toolCalls(ctx context.Context, run *types.Execution, opts []typ
 			continue
 		}
 
-		callOutput, err := a.invoke(ctx, targetServer, tools.ToolCallInvocation{
-			MessageID: run.Response.Output.ID,
-			ItemID:    output.ID,
-			ToolCall:  *functionCall,
-		}, opts)
-		cancelCause := context.Cause(mcp.UserContext(ctx))
-		if err != nil || cancelCause != nil {
-			// Check if this was a client-initiated cancellation
-			cancelErr, ok := errors.AsType[*mcp.RequestCancelledError](cancelCause)
-			if ok && cancelErr != nil {
-				err = cancelErr
+		calls = append(calls, pendingToolCall{
+			item:   output,
+			target: targetServer,
+		})
+	}
+
+	a.runToolCalls(ctx, run, calls, opts)
+
+	if len(run.ToolOutputs) == 0 {
+		run.Done = true
+	}
+}
+
+// runToolCalls runs the calls in the order the model made them and records
+// their results. Consecutive calls of read-only tools run concurrently, the
+// other calls run one at a time.
+func (a *Agents) runToolCalls(ctx context.Context, run *types.Execution, calls []pendingToolCall, opts []types.CompletionOptions) {
+	for _, batch := range toolCallBatches(calls) {
+		for i, result := range a.invokeAll(ctx, run, batch, opts) {
+			if result.cancelled {
 				// Preserve what we have and stop processing further tool calls
 				run.Done = true
-			} else {
-				err = fmt.Errorf("failed to invoke tool %s on MCP server %s: %w", functionCall.Name, targetServer.MCPServer, err)
 			}
 
-			if callOutput == nil ||
-				len(callOutput.Items) == 0 ||
-				callOutput.Items[0].ToolCallResult == nil ||
-				len(callOutput.Items[0].ToolCallResult.Output.Content) == 0 {
-				callOutput = &types.Message{
-					Role: "user",
-					Items: []types.CompletionItem{
-						{
-							ID: output.ID,
-							ToolCallResult: &types.ToolCallResult{
-								CallID: functionCall.CallID,
-								Output: types.CallResult{
-									Content: []mcp.Content{
-										{
-											Type: "text",
-											Text: err.Error(),
-										},
-									},
-								},
-							},
-						},
-					},
-				}
+			if run.ToolOutputs == nil {
+				run.ToolOutputs = make(map[string]types.ToolOutput)
 			}
+
+			run.ToolOutputs[batch[i].item.ToolCall.CallID] = types.ToolOutput{
+				Output: *result.output,
+				Done:   true,
+			}
+		}
+	}
+}
+
+// toolCallBatches splits the calls into the batches that run one after the
+// other: consecutive calls of tools annotated with readOnlyHint share a batch,
+// every other call is a batch of its own.
+func toolCallBatches(calls []pendingToolCall) (batches [][]pendingToolCall) {
+	for _, call := range calls {
+		if last := len(batches) - 1; last >= 0 && call.readOnly() && batches[last][0].readOnly() {
+			batches[last] = append(batches[last], call)
+			continue
 		}
+		batches = append(batches, []pendingToolCall{call})
+	}
+	return batches
+}
+
+// defaultMaxParallelToolCalls is how many read-only tool calls from one
+// response run at once when the agent doesn't set maxParallelToolCalls.
+const defaultMaxParallelToolCalls = 10
 
-		callOutput = truncateToolResult(ctx, functionCall.Name, functionCall.CallID, callOutput)
+// maxParallelToolCalls returns how many of the agent's tool calls may run at
+// once.
+func maxParallelToolCalls(ctx context.Context, agentName string) int {
+	if limit := types.ConfigFromContext(ctx).Agents[agentName].MaxParallelToolCalls; limit > 0 {
+		return limit
+	}
+	return defaultMaxParallelToolCalls
+}
+
+type pendingToolCall struct {
+	item   types.CompletionItem
+	target types.TargetMapping[types.TargetTool]
+}
 
-		if run.ToolOutputs == nil {
-			run.ToolOutputs = make(map[string]types.ToolOutput)
+// readOnly returns whether the tool of the call is annotated as not changing
+// its environment, so the call can run at the same time as others.
+func (p pendingToolCall) readOnly() bool {
+	return p.target.Target.Annotations != nil && p.target.Target.Annotations.ReadOnlyHint
+}
+
+type toolCallResult struct {
+	output *types.Message
+	// cancelled is set when the client cancelled the request while the call
+	// was running or waiting to run.
+	cancelled bool
+}
+
+// invokeAll runs the tool calls, at most maxParallelToolCalls at a time, and
+// returns their results in the same order as the calls. Calls still waiting
+// for their turn when the client cancels the request are not started.
+func (a *Agents) invokeAll(ctx context.Context, run *types.Execution, calls []pendingToolCall, opts []types.CompletionOptions) []toolCallResult {
+	results := make([]toolCallResult, len(calls))
+	if len(calls) == 1 {
+		results[0] = a.invokeToolCall(ctx, run, calls[0], opts)
+		return results
+	}
+
+	var (
+		wg    sync.WaitGroup
+		slots = make(chan struct{}, maxParallelToolCalls(ctx, run.Request.GetAgent()))
+	)
+	for i, call := range calls {
+		select {
+		case slots <- struct{}{}:
+		case <-mcp.UserContext(ctx).Done():
+			results[i] = cancelledToolCall(ctx, call)
+			continue
+		}
+		wg.Go(func() {
+			defer func() { <-slots }()
+			results[i] = a.invokeToolCall(ctx, run, call, opts)
+		})
+	}
+	wg.Wait()
+
+	return results
+}
+
+func (a *Agents) invokeToolCall(ctx context.Context, run *types.Execution, call pendingToolCall, opts []types.CompletionOptions) toolCallResult {
+	functionCall := call.item.ToolCall
+
+	if context.Cause(mcp.UserContext(ctx)) != nil {
+		return cancelledToolCall(ctx, call)
+	}
+
+	callOutput, err := a.invoke(ctx, call.target, tools.ToolCallInvocation{
+		MessageID: run.Response.Output.ID,
+		ItemID:    call.item.ID,
+		ToolCall:  *functionCall,
+	}, opts)
+
+	var cancelled bool
+	cancelCause := context.Cause(mcp.UserContext(ctx))
+	if err != nil || cancelCause != nil {
+		// Check if this was a client-initiated cancellation
+		cancelErr, ok := errors.AsType[*mcp.RequestCancelledError](cancelCause)
+		if ok && cancelErr != nil {
+			err = cancelErr
+			cancelled = true
+		} else {
+			err = fmt.Errorf("failed to invoke tool %s on MCP server %s: %w", functionCall.Name, call.target.MCPServer, err)
 		}
 
-		run.ToolOutputs[functionCall.CallID] = types.ToolOutput{
-			Output: *callOutput,
-			Done:   true,
+		if callOutput == nil ||
+			len(callOutput.Items) == 0 ||
+			callOutput.Items[0].ToolCallResult == nil ||
+			len(callOutput.Items[0].ToolCallResult.Output.Content) == 0 {
+			callOutput = errorToolCallOutput(call, err)
 		}
 	}
 
-	if len(run.ToolOutputs) == 0 {
-		run.Done = true
+	return toolCallResult{
+		output:    truncateToolResultToSize(ctx, functionCall.Name, functionCall.CallID, callOutput, toolResultBudget(ctx, call.target), toolResultTailPercent(ctx, call.target)),
+		cancelled: cancelled,
+	}
+}
+
+// cancelledToolCall is the result of a call that never started because the
+// client cancelled the request.
+func cancelledToolCall(ctx context.Context, call pendingToolCall) toolCallResult {
+	err := context.Cause(mcp.UserContext(ctx))
+	cancelErr, ok := errors.AsType[*mcp.RequestCancelledError](err)
+	if !ok || cancelErr == nil {
+		err = fmt.Errorf("failed to invoke tool %s on MCP server %s: %w", call.item.ToolCall.Name, call.target.MCPServer, err)
+	}
+	return toolCallResult{
+		output:    errorToolCallOutput(call, err),
+		cancelled: ok && cancelErr != nil,
+	}
+}
+
+func errorToolCallOutput(call pendingToolCall, err error) *types.Message {
+	return &types.Message{
+		Role: "user",
+		Items: []types.CompletionItem{
+			{
+				ID: call.item.ID,
+				ToolCallResult: &types.ToolCallResult{
+					CallID: call.item.ToolCall.CallID,
+					Output: types.CallResult{
+						Content: []mcp.Content{
+							{
+								Type: "text",
+								Text: err.Error(),
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 }
 
@@ -0,0 +1,108 @@
+package agents
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+func TestMaxParallelToolCalls(t *testing.T) {
+	ctx := types.WithConfig(t.Context(), types.Config{
+		Agents: map[string]types.Agent{
+			"serial": {HookAgent: types.HookAgent{MaxParallelToolCalls: 1}},
+		},
+	})
+
+	if got := maxParallelToolCalls(ctx, "serial"); got != 1 {
+		t.Errorf("limit for configured agent = %d, want 1", got)
+	}
+	if got := maxParallelToolCalls(ctx, "other"); got != defaultMaxParallelToolCalls {
+		t.Errorf("limit for default agent = %d, want %d", got, defaultMaxParallelToolCalls)
+	}
+}
+
+func TestToolCallBatches(t *testing.T) {
+	call := func(name string, readOnly bool) pendingToolCall {
+		var target types.TargetMapping[types.TargetTool]
+		if readOnly {
+			target.Target.Annotations = &mcp.ToolAnnotations{ReadOnlyHint: true}
+		}
+		return pendingToolCall{item: types.CompletionItem{ToolCall: &types.ToolCall{CallID: name, Name: name}}, target: target}
+	}
+
+	batches := toolCallBatches([]pendingToolCall{
+		call("read-1", true),
+		call("grep", true),
+		call("edit", false),
+		call("todoWrite", false),
+		call("read-2", true),
+		call("glob", true),
+	})
+
+	var got [][]string
+	for _, batch := range batches {
+		var names []string
+		for _, call := range batch {
+			names = append(names, call.item.ToolCall.Name)
+		}
+		got = append(got, names)
+	}
+	want := [][]string{{"read-1", "grep"}, {"edit"}, {"todoWrite"}, {"read-2", "glob"}}
+	if !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("toolCallBatches() = %v, want %v", got, want)
+	}
+}
+
+func TestToolOutputOrder(t *testing.T) {
+	run := &types.Execution{
+		Response: &types.CompletionResponse{
+			Output: types.Message{
+				Items: []types.CompletionItem{
+					{ToolCall: &types.ToolCall{CallID: "call-c"}},
+					{Content: &mcp.Content{Type: "text", Text: "thinking"}},
+					{ToolCall: &types.ToolCall{CallID: "call-a"}},
+					{ToolCall: &types.ToolCall{CallID: "call-b"}},
+				},
+			},
+		},
+		ToolOutputs: map[string]types.ToolOutput{
+			"call-a":     {Done: true},
+			"call-b":     {Done: true},
+			"call-c":     {Done: true},
+			"call-other": {Done: true},
+		},
+	}
+
+	want := []string{"call-c", "call-a", "call-b", "call-other"}
+	if got := toolOutputOrder(run); !slices.Equal(got, want) {
+		t.Errorf("toolOutputOrder() = %v, want %v", got, want)
+	}
+}
+
+func TestInvokeAllCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(t.Context())
+	cancel(&mcp.RequestCancelledError{Reason: "user stopped"})
+
+	calls := []pendingToolCall{
+		{item: types.CompletionItem{ID: "item-1", ToolCall: &types.ToolCall{CallID: "call-1", Name: "read"}}},
+		{item: types.CompletionItem{ID: "item-2", ToolCall: &types.ToolCall{CallID: "call-2", Name: "grep"}}},
+		{item: types.CompletionItem{ID: "item-3", ToolCall: &types.ToolCall{CallID: "call-3", Name: "glob"}}},
+	}
+
+	results := (&Agents{}).invokeAll(ctx, &types.Execution{Response: &types.CompletionResponse{}}, calls, nil)
+	if len(results) != len(calls) {
+		t.Fatalf("got %d results, want %d", len(results), len(calls))
+	}
+	for i, result := range results {
+		if !result.cancelled {
+			t.Errorf("result %d: expected the call to be cancelled", i)
+		}
+		item := result.output.Items[0]
+		if item.ID != calls[i].item.ID || item.ToolCallResult.CallID != calls[i].item.ToolCall.CallID {
+			t.Errorf("result %d is for %s/%s, want %s/%s", i, item.ID, item.ToolCallResult.CallID, calls[i].item.ID, calls[i].item.ToolCall.CallID)
+		}
+	}
+}
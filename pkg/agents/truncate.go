@@ -3,14 +3,19 @@ package agents
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	"log/slog"
 
+	"github.com/obot-platform/nanobot/pkg/encryption"
 	"github.com/obot-platform/nanobot/pkg/mcp"
 	"github.com/obot-platform/nanobot/pkg/types"
 )
@@ -31,7 +36,160 @@ func hasSkipTruncation(content []mcp.Content) bool {
 	return false
 }
 
+// bytesPerToken approximates the size of a token when converting a
+// configured token limit to a result size.
+const bytesPerToken = 4
+
+// toolResultSettings returns the toolResults settings of the config.
+func toolResultSettings(ctx context.Context) types.ToolResultSettings {
+	if settings := types.ConfigFromContext(ctx).ToolResults; settings != nil {
+		return *settings
+	}
+	return types.ToolResultSettings{}
+}
+
+// toolResultBudget returns the size at which the tool's results are truncated,
+// honoring the maxResultTokens setting of its MCP server and then the
+// toolResults settings.
+func toolResultBudget(ctx context.Context, target types.TargetMapping[types.TargetTool]) int {
+	settings := types.ConfigFromContext(ctx).MCPServers[target.MCPServer].SettingsForTool(target.TargetName)
+	if settings.MaxResultTokens > 0 {
+		return settings.MaxResultTokens * bytesPerToken
+	}
+	if maxTokens := toolResultSettings(ctx).MaxTokens; maxTokens > 0 {
+		return maxTokens * bytesPerToken
+	}
+	return maxToolResultSize
+}
+
+// defaultTruncationTailPercent is the share of a truncated result kept from
+// its end, where logs and test output usually have the error.
+const defaultTruncationTailPercent = 30
+
+// toolResultTailPercent returns the share of the tool's truncated results
+// kept from the end, honoring the truncationTailPercent setting of its MCP
+// server and then the toolResults settings.
+func toolResultTailPercent(ctx context.Context, target types.TargetMapping[types.TargetTool]) int {
+	settings := types.ConfigFromContext(ctx).MCPServers[target.MCPServer].SettingsForTool(target.TargetName)
+	if settings.TruncationTailPercent != nil {
+		return min(max(*settings.TruncationTailPercent, 0), 100)
+	}
+	if tail := toolResultSettings(ctx).TailPercent; tail != nil {
+		return min(max(*tail, 0), 100)
+	}
+	return defaultTruncationTailPercent
+}
+
+// TruncatedOutputsDir returns the absolute directory the full output of the
+// session's truncated tool results is written to.
+func TruncatedOutputsDir(ctx context.Context) string {
+	sessionID, _ := types.GetSessionAndAccountID(ctx)
+	dir := filepath.Join(sessionsDir, sessionID, "truncated-outputs")
+	if toolResultSettings(ctx).Storage == types.ToolResultStorageNanobot {
+		dir = filepath.Join(".nanobot", sessionID, "truncated-outputs")
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		dir = filepath.Join(cwd, dir)
+	}
+	return dir
+}
+
+// TruncatedOutput is the full output of a truncated tool result.
+type TruncatedOutput struct {
+	Name     string
+	MimeType string
+	Size     int64
+	Modified time.Time
+}
+
+// truncatedOutputMimeType returns the MIME type of the file of a full output,
+// text for text results and JSON for the rest.
+func truncatedOutputMimeType(name string) string {
+	if filepath.Ext(name) == ".json" {
+		return "application/json"
+	}
+	return "text/plain"
+}
+
+// ListTruncatedOutputs lists the full outputs of the session's truncated tool
+// results, oldest first.
+func ListTruncatedOutputs(ctx context.Context) ([]TruncatedOutput, error) {
+	entries, err := os.ReadDir(TruncatedOutputsDir(ctx))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var outputs []TruncatedOutput
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		outputs = append(outputs, TruncatedOutput{
+			Name:     entry.Name(),
+			MimeType: truncatedOutputMimeType(entry.Name()),
+			Size:     info.Size(),
+			Modified: info.ModTime(),
+		})
+	}
+	slices.SortStableFunc(outputs, func(a, b TruncatedOutput) int {
+		return a.Modified.Compare(b.Modified)
+	})
+	return outputs, nil
+}
+
+// ReadTruncatedOutput reads the full output of one of the session's truncated
+// tool results by its file name.
+func ReadTruncatedOutput(ctx context.Context, name string) ([]byte, string, error) {
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return nil, "", fmt.Errorf("invalid truncated output name %q: %w", name, fs.ErrNotExist)
+	}
+	data, err := os.ReadFile(filepath.Join(TruncatedOutputsDir(ctx), name))
+	if err != nil {
+		return nil, "", err
+	}
+	data, err = encryption.Open(ctx, data)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, truncatedOutputMimeType(name), nil
+}
+
+// pruneTruncatedOutputs removes the full outputs past the retention of the
+// toolResults settings, those older than retentionHours and then the oldest
+// over maxFiles.
+func pruneTruncatedOutputs(ctx context.Context, now time.Time) {
+	settings := toolResultSettings(ctx)
+	if settings.RetentionHours <= 0 && settings.MaxFiles <= 0 {
+		return
+	}
+
+	outputs, err := ListTruncatedOutputs(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to list truncated tool results", "error", err)
+		return
+	}
+
+	dir := TruncatedOutputsDir(ctx)
+	for i, output := range outputs {
+		expired := settings.RetentionHours > 0 && now.Sub(output.Modified) > time.Duration(settings.RetentionHours)*time.Hour
+		overLimit := settings.MaxFiles > 0 && len(outputs)-i > settings.MaxFiles
+		if !expired && !overLimit {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, output.Name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			slog.ErrorContext(ctx, "failed to remove truncated tool result", "name", output.Name, "error", err)
+		}
+	}
+}
+
 func truncateToolResult(ctx context.Context, toolName, callID string, msg *types.Message) *types.Message {
+	return truncateToolResultToSize(ctx, toolName, callID, msg, maxToolResultSize, defaultTruncationTailPercent)
+}
+
+func truncateToolResultToSize(ctx context.Context, toolName, callID string, msg *types.Message, budget, tailPercent int) *types.Message {
 	if msg == nil || len(msg.Items) == 0 {
 		return msg
 	}
@@ -51,7 +209,7 @@ func truncateToolResult(ctx context.Context, toolName, callID string, msg *types
 	}
 
 	size := contentSize(content)
-	if size <= maxToolResultSize {
+	if size <= budget {
 		return msg
 	}
 
@@ -64,18 +222,26 @@ func truncateToolResult(ctx context.Context, toolName, callID string, msg *types
 		}
 	}
 
-	// Build absolute file path (store under the session's working directory)
-	sessionID, _ := types.GetSessionAndAccountID(ctx)
+	// Build absolute file path (store under the configured directory of the session)
 	fileName := sanitizePathComponent(toolName) + "-" + sanitizePathComponent(callID) + ext
-	filePath := filepath.Join(sessionsDir, sessionID, "truncated-outputs", fileName)
-	if cwd, err := os.Getwd(); err == nil {
-		filePath = filepath.Join(cwd, filePath)
-	}
+	filePath := filepath.Join(TruncatedOutputsDir(ctx), fileName)
 
-	writeErr := writeFullResult(content, filePath)
-	truncated := buildTruncatedContent(content, maxToolResultSize, filePath)
+	writeErr := writeFullResult(ctx, content, filePath)
+	if writeErr == nil {
+		pruneTruncatedOutputs(ctx, time.Now())
+		if session := mcp.SessionFromContext(ctx); session != nil {
+			_ = session.Root().SendPayload(ctx, "notifications/resources/list_changed", struct{}{})
+		}
+	}
+	// Encrypted files can't be read with file tools, point to the resource
+	// that decrypts them instead.
+	location := filePath
+	if encryption.FromContext(ctx) != nil {
+		location = fmt.Sprintf(types.TruncatedOutputURI, fileName)
+	}
+	truncated := buildTruncatedContent(content, budget, tailPercent, location)
 	if writeErr != nil {
-		slog.Error("failed to write truncated tool result", "path", filePath, "error", writeErr)
+		slog.ErrorContext(ctx, "failed to write truncated tool result", "path", filePath, "error", writeErr)
 
 		noticePart := mcp.Content{
 			Type: "text",
@@ -124,7 +290,9 @@ func contentSize(content []mcp.Content) int {
 	return total
 }
 
-func writeFullResult(content []mcp.Content, filePath string) error {
+// writeFullResult writes the full content to a file, sealed when the session
+// is encrypted.
+func writeFullResult(ctx context.Context, content []mcp.Content, filePath string) error {
 	if err := os.MkdirAll(filepath.Dir(filePath), 0700); err != nil {
 		return err
 	}
@@ -137,6 +305,7 @@ func writeFullResult(content []mcp.Content, filePath string) error {
 		}
 	}
 
+	var data []byte
 	if allText {
 		var sb strings.Builder
 		for i, c := range content {
@@ -145,46 +314,102 @@ func writeFullResult(content []mcp.Content, filePath string) error {
 			}
 			sb.WriteString(c.Text)
 		}
-		return os.WriteFile(filePath, []byte(sb.String()), 0600)
+		data = []byte(sb.String())
+	} else {
+		var err error
+		data, err = json.MarshalIndent(content, "", "  ")
+		if err != nil {
+			return err
+		}
 	}
 
-	data, err := json.MarshalIndent(content, "", "  ")
+	data, err := encryption.Seal(ctx, data)
 	if err != nil {
 		return err
 	}
 	return os.WriteFile(filePath, data, 0600)
 }
 
-func buildTruncatedContent(content []mcp.Content, budget int, filePath string) []mcp.Content {
+// buildTruncatedContent keeps the start and end of the content within the
+// budget, with tailPercent of it taken from the end, and replaces the middle
+// with a marker counting what was left out. Cuts are moved to line boundaries
+// where possible. Non-text content is replaced by a note.
+func buildTruncatedContent(content []mcp.Content, budget, tailPercent int, filePath string) []mcp.Content {
 	suffix := fmt.Sprintf("\n\n[Truncated: full output available at %s]", filePath)
 	remaining := max(budget-len(suffix), 0)
 
-	var result []mcp.Content
-
+	type piece struct {
+		text  string
+		start int
+		note  bool
+	}
+	var (
+		pieces []piece
+		total  int
+	)
 	for _, c := range content {
-		if remaining <= 0 {
-			break
+		p := piece{text: c.Text, start: total}
+		if c.Type != "text" && c.Type != "" {
+			p.text = fmt.Sprintf("[%s content written to %s]", c.Type, filePath)
+			p.note = true
 		}
+		pieces = append(pieces, p)
+		total += len(p.text)
+	}
 
-		switch c.Type {
-		case "text", "":
-			text := c.Text
-			if len(text) > remaining {
-				text = text[:remaining]
+	headEnd, tailStart := total, total
+	if total > remaining {
+		headEnd = remaining * (100 - tailPercent) / 100
+		tailStart = total - (remaining - headEnd)
+		for _, p := range pieces {
+			end := p.start + len(p.text)
+			if headEnd > p.start && headEnd < end {
+				// Notes are kept whole, text is cut after its last complete line.
+				if p.note {
+					headEnd = p.start
+				} else if i := strings.LastIndexByte(p.text[:headEnd-p.start], '\n'); i >= 0 {
+					headEnd = p.start + i + 1
+				}
+			}
+			if tailStart > p.start && tailStart < end {
+				// The tail starts at the next complete line.
+				if p.note {
+					tailStart = end
+				} else if i := strings.IndexByte(p.text[tailStart-p.start:], '\n'); i >= 0 {
+					tailStart += i + 1
+				}
 			}
-			result = append(result, mcp.Content{
-				Type: "text",
-				Text: text,
-			})
-			remaining -= len(text)
-		default:
-			note := fmt.Sprintf("[%s content written to %s]", c.Type, filePath)
-			result = append(result, mcp.Content{
-				Type: "text",
-				Text: note,
-			})
-			remaining -= len(note)
 		}
+		tailStart = max(tailStart, headEnd)
+	}
+
+	var (
+		result                       []mcp.Content
+		omittedBytes, omittedLines   int
+		markerAdded, anythingOmitted = false, tailStart > headEnd
+	)
+	for _, p := range pieces {
+		end := p.start + len(p.text)
+		if text := p.text[:min(max(headEnd-p.start, 0), len(p.text))]; text != "" {
+			result = append(result, mcp.Content{Type: "text", Text: text})
+		}
+		if from, to := max(headEnd, p.start), min(tailStart, end); from < to {
+			omitted := p.text[from-p.start : to-p.start]
+			omittedBytes += len(omitted)
+			omittedLines += strings.Count(omitted, "\n")
+		}
+		if tailStart < end {
+			if anythingOmitted && !markerAdded {
+				result = append(result, elisionMarker(omittedBytes, omittedLines))
+				markerAdded = true
+			}
+			if text := p.text[max(tailStart-p.start, 0):]; text != "" {
+				result = append(result, mcp.Content{Type: "text", Text: text})
+			}
+		}
+	}
+	if anythingOmitted && !markerAdded {
+		result = append(result, elisionMarker(omittedBytes, omittedLines))
 	}
 
 	result = append(result, mcp.Content{
@@ -195,6 +420,13 @@ func buildTruncatedContent(content []mcp.Content, budget int, filePath string) [
 	return result
 }
 
+func elisionMarker(bytes, lines int) mcp.Content {
+	return mcp.Content{
+		Type: "text",
+		Text: fmt.Sprintf("\n[... %d lines (%d bytes) omitted ...]\n", lines, bytes),
+	}
+}
+
 func sanitizePathComponent(s string) string {
 	s = sanitizeRe.ReplaceAllString(s, "_")
 	s = strings.TrimLeft(s, ".")
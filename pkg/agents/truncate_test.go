@@ -3,11 +3,13 @@ package agents
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/obot-platform/nanobot/pkg/encryption"
 	"github.com/obot-platform/nanobot/pkg/mcp"
 	"github.com/obot-platform/nanobot/pkg/types"
 )
@@ -203,7 +205,7 @@ func TestWriteFullResult_AllText(t *testing.T) {
 		{Type: "text", Text: "line two"},
 	}
 
-	if err := writeFullResult(content, path); err != nil {
+	if err := writeFullResult(context.Background(), content, path); err != nil {
 		t.Fatalf("writeFullResult error: %v", err)
 	}
 
@@ -218,6 +220,37 @@ func TestWriteFullResult_AllText(t *testing.T) {
 	}
 }
 
+func TestWriteFullResult_Encrypted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "output.txt")
+
+	key, err := encryption.NewLocalKey("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := encryption.WithEnvelope(context.Background(), encryption.New(key))
+
+	content := []mcp.Content{{Type: "text", Text: "secret output"}}
+	if err := writeFullResult(ctx, content, path); err != nil {
+		t.Fatalf("writeFullResult error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile error: %v", err)
+	}
+	if strings.Contains(string(data), "secret output") {
+		t.Fatalf("expected the file to be encrypted, got %q", data)
+	}
+
+	data, err = encryption.Open(ctx, data)
+	if err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	if string(data) != "secret output" {
+		t.Errorf("file content = %q, want %q", data, "secret output")
+	}
+}
+
 func TestWriteFullResult_SingleText(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "output.txt")
@@ -226,7 +259,7 @@ func TestWriteFullResult_SingleText(t *testing.T) {
 		{Type: "text", Text: "only one"},
 	}
 
-	if err := writeFullResult(content, path); err != nil {
+	if err := writeFullResult(context.Background(), content, path); err != nil {
 		t.Fatalf("writeFullResult error: %v", err)
 	}
 
@@ -249,7 +282,7 @@ func TestWriteFullResult_MixedContent(t *testing.T) {
 		{Type: "image", Data: "base64img"},
 	}
 
-	if err := writeFullResult(content, path); err != nil {
+	if err := writeFullResult(context.Background(), content, path); err != nil {
 		t.Fatalf("writeFullResult error: %v", err)
 	}
 
@@ -283,7 +316,7 @@ func TestWriteFullResult_CreatesDirectories(t *testing.T) {
 		{Type: "text", Text: "deep"},
 	}
 
-	if err := writeFullResult(content, path); err != nil {
+	if err := writeFullResult(context.Background(), content, path); err != nil {
 		t.Fatalf("writeFullResult error: %v", err)
 	}
 
@@ -306,7 +339,7 @@ func TestBuildTruncatedContent_TruncatesLongText(t *testing.T) {
 		{Type: "text", Text: bigText},
 	}
 
-	result := buildTruncatedContent(content, 200, filePath)
+	result := buildTruncatedContent(content, 200, defaultTruncationTailPercent, filePath)
 
 	// Last item should be the truncation notice
 	last := result[len(result)-1]
@@ -336,7 +369,7 @@ func TestBuildTruncatedContent_NonTextReplaced(t *testing.T) {
 		{Type: "image", Data: strings.Repeat("x", 1000)},
 	}
 
-	result := buildTruncatedContent(content, 500, filePath)
+	result := buildTruncatedContent(content, 500, defaultTruncationTailPercent, filePath)
 
 	// Should have: text item, image replacement note, truncation notice
 	foundImageNote := false
@@ -359,7 +392,7 @@ func TestBuildTruncatedContent_MultipleTextItems(t *testing.T) {
 	}
 
 	// Budget small enough to cut off mid-way
-	result := buildTruncatedContent(content, 200, filePath)
+	result := buildTruncatedContent(content, 200, defaultTruncationTailPercent, filePath)
 
 	// Should have at least one text item and the truncation notice
 	if len(result) < 2 {
@@ -379,17 +412,71 @@ func TestBuildTruncatedContent_DropsItemsAfterBudget(t *testing.T) {
 		{Type: "text", Text: "should not appear"},
 	}
 
-	// Budget smaller than first item
-	result := buildTruncatedContent(content, 200, filePath)
+	// Budget smaller than first item, keeping only the head
+	result := buildTruncatedContent(content, 200, 0, filePath)
 
-	// Should only have the first (truncated) text and the notice
-	if len(result) != 2 {
-		t.Fatalf("expected 2 items, got %d", len(result))
+	// Should only have the first (truncated) text, the elision marker, and the notice
+	if len(result) != 3 {
+		t.Fatalf("expected 3 items, got %d", len(result))
 	}
 
 	if strings.Contains(result[0].Text, "should not appear") {
 		t.Error("second text item should have been dropped")
 	}
+	if !strings.Contains(result[1].Text, "omitted") {
+		t.Errorf("expected an elision marker, got %q", result[1].Text)
+	}
+}
+
+func TestBuildTruncatedContent_KeepsHeadAndTail(t *testing.T) {
+	var lines []string
+	for i := range 100 {
+		lines = append(lines, fmt.Sprintf("line %03d", i))
+	}
+	lines[99] = "FAIL: TestSomething"
+	content := []mcp.Content{
+		{Type: "text", Text: strings.Join(lines, "\n")},
+	}
+
+	result := buildTruncatedContent(content, 300, 50, "/tmp/test/output.txt")
+	if len(result) != 4 {
+		t.Fatalf("expected head, marker, tail, and notice, got %d items", len(result))
+	}
+
+	head, marker, tail := result[0].Text, result[1].Text, result[2].Text
+	if !strings.HasPrefix(head, "line 000\n") || !strings.HasSuffix(head, "\n") {
+		t.Errorf("head should start at the beginning and end on a line boundary, got %q", head)
+	}
+	if !strings.HasSuffix(tail, "FAIL: TestSomething") || !strings.HasPrefix(tail, "line ") {
+		t.Errorf("tail should keep the last lines whole, got %q", tail)
+	}
+
+	keptLines := strings.Count(head, "\n") + strings.Count(tail, "\n") + 1
+	if want := fmt.Sprintf("[... %d lines (%d bytes) omitted ...]", 100-keptLines, len(content[0].Text)-len(head)-len(tail)); !strings.Contains(marker, want) {
+		t.Errorf("marker = %q, want %q", marker, want)
+	}
+}
+
+func TestBuildTruncatedContent_TailAcrossItems(t *testing.T) {
+	content := []mcp.Content{
+		{Type: "text", Text: strings.Repeat("a", 500)},
+		{Type: "image", Data: strings.Repeat("x", 1000)},
+		{Type: "text", Text: "the end"},
+	}
+
+	result := buildTruncatedContent(content, 200, defaultTruncationTailPercent, "/tmp/test/output.json")
+
+	var texts []string
+	for _, c := range result {
+		texts = append(texts, c.Text)
+	}
+	joined := strings.Join(texts, "")
+	if !strings.Contains(joined, "omitted") || !strings.Contains(joined, "the end") {
+		t.Errorf("expected the marker and the final item, got %q", joined)
+	}
+	if strings.Count(joined, "a") > 200 {
+		t.Errorf("expected the first item to be cut, got %q", joined)
+	}
 }
 
 // --- truncateToolResult integration tests ---
@@ -691,3 +778,71 @@ func TestTruncateToolResult_SpecialCharsInNames(t *testing.T) {
 		t.Errorf("expected file at %s", filePath)
 	}
 }
+
+func TestToolResultBudget(t *testing.T) {
+	ctx := types.WithConfig(context.Background(), types.Config{
+		MCPServers: map[string]mcp.Server{
+			"chatty": {
+				ToolSettings: map[string]mcp.ToolSettings{
+					"dump": {MaxResultTokens: 100},
+				},
+			},
+		},
+	})
+
+	if got := toolResultBudget(ctx, types.TargetMapping[types.TargetTool]{MCPServer: "chatty", TargetName: "dump"}); got != 100*bytesPerToken {
+		t.Errorf("budget for configured tool = %d, want %d", got, 100*bytesPerToken)
+	}
+	if got := toolResultBudget(ctx, types.TargetMapping[types.TargetTool]{MCPServer: "chatty", TargetName: "other"}); got != maxToolResultSize {
+		t.Errorf("budget for default tool = %d, want %d", got, maxToolResultSize)
+	}
+}
+
+func TestToolResultSettings(t *testing.T) {
+	origDir, _ := os.Getwd()
+	tmpDir := t.TempDir()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	ctx := types.WithConfig(contextWithSessionID(t, "default"), types.Config{
+		ToolResults: &types.ToolResultSettings{
+			MaxTokens: 10,
+			Storage:   types.ToolResultStorageNanobot,
+			MaxFiles:  2,
+		},
+	})
+	target := types.TargetMapping[types.TargetTool]{MCPServer: "chatty", TargetName: "dump"}
+
+	budget := toolResultBudget(ctx, target)
+	if budget != 10*bytesPerToken {
+		t.Errorf("budget = %d, want %d", budget, 10*bytesPerToken)
+	}
+
+	for _, callID := range []string{"call1", "call2", "call3"} {
+		msg := makeToolResultMessage(callID, makeTextContent(strings.Repeat("x", 100)), false)
+		truncateToolResultToSize(ctx, "dump", callID, msg, budget, toolResultTailPercent(ctx, target))
+	}
+
+	outputs, err := ListTruncatedOutputs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, output := range outputs {
+		names = append(names, output.Name)
+	}
+	if strings.Join(names, ",") != "dump-call2.txt,dump-call3.txt" {
+		t.Errorf("outputs = %v, want the 2 newest", names)
+	}
+	if _, err := os.Stat(filepath.Join(".nanobot", "default", "truncated-outputs", "dump-call3.txt")); err != nil {
+		t.Errorf("expected the output in the nanobot directory: %v", err)
+	}
+
+	data, mimeType, err := ReadTruncatedOutput(ctx, "dump-call3.txt")
+	if err != nil || string(data) != strings.Repeat("x", 100) || mimeType != "text/plain" {
+		t.Errorf("ReadTruncatedOutput = %q, %q, %v", data, mimeType, err)
+	}
+	if _, _, err := ReadTruncatedOutput(ctx, "../default/truncated-outputs/dump-call3.txt"); err == nil {
+		t.Error("expected paths out of the directory to be rejected")
+	}
+}
@@ -0,0 +1,97 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/obot-platform/nanobot/pkg/journal"
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// ErrNothingToUndo is returned by UndoLastTurn when the chat has no turn left
+// to undo.
+var ErrNothingToUndo = errors.New("there is no turn to undo")
+
+type UndoResult struct {
+	// Messages is the number of messages removed from the conversation.
+	Messages      int      `json:"messages"`
+	RevertedFiles []string `json:"revertedFiles,omitempty"`
+}
+
+// UndoLastTurn undoes the most recent turn of the session's chat, from the
+// user message that started it to the final reply. The files its tools
+// changed are reverted and its messages are moved out of the conversation
+// into the execution's UndoneMessages. Changes made by bash commands are not
+// journaled, so they are not reverted.
+func (a *Agents) UndoLastTurn(ctx context.Context) (*UndoResult, error) {
+	session := mcp.SessionFromContext(ctx).Root()
+
+	var run types.Execution
+	if !session.Get(types.PreviousExecutionKey, &run) || run.PopulatedRequest == nil {
+		return nil, ErrNothingToUndo
+	}
+
+	messages := slices.Clone(run.PopulatedRequest.Input)
+	if run.Response != nil && len(run.Response.Output.Items) > 0 {
+		messages = append(messages, run.Response.Output)
+	}
+
+	start := lastTurnStart(messages)
+	if start < 0 {
+		return nil, ErrNothingToUndo
+	}
+
+	sessionID, _ := types.GetSessionAndAccountID(ctx)
+	reverted, err := journal.Revert(sessionID, messages[start].ID)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := messages[:start]
+	run.UndoneMessages = append(run.UndoneMessages, messages[start:]...)
+	run.ToolOutputs = nil
+	if run.Response == nil {
+		run.Response = &types.CompletionResponse{}
+	}
+	run.Response.InternalMessages = nil
+	run.Response.BudgetExhausted = nil
+	if len(kept) == 0 {
+		run.PopulatedRequest.Input = nil
+		run.Response.Output = types.Message{}
+	} else {
+		run.PopulatedRequest.Input = kept[:len(kept)-1]
+		run.Response.Output = kept[len(kept)-1]
+	}
+
+	session.Set(types.PreviousExecutionKey, &run)
+
+	return &UndoResult{
+		Messages:      len(messages) - start,
+		RevertedFiles: reverted,
+	}, nil
+}
+
+// lastTurnStart returns the index of the user message that started the last
+// turn, or -1 if there is none. Tool results, compaction summaries, and
+// budget and handoff messages are sent as user messages too, but don't start
+// a turn.
+func lastTurnStart(messages []types.Message) int {
+	for i := len(messages) - 1; i >= 0; i-- {
+		msg := messages[i]
+		if msg.Role != "user" || msg.ID == "" || IsCompactionSummary(msg) {
+			continue
+		}
+		if slices.ContainsFunc(msg.Items, func(item types.CompletionItem) bool {
+			return item.ToolCallResult != nil ||
+				item.Content != nil && (strings.HasPrefix(item.Content.Text, budgetExhaustedPrefix) ||
+					strings.HasPrefix(item.Content.Text, handoffPrefix))
+		}) {
+			continue
+		}
+		return i
+	}
+	return -1
+}
@@ -0,0 +1,44 @@
+package agents
+
+import (
+	"testing"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+func TestLastTurnStart(t *testing.T) {
+	text := func(id, role, text string) types.Message {
+		return types.Message{
+			ID:    id,
+			Role:  role,
+			Items: []types.CompletionItem{{Content: &mcp.Content{Type: "text", Text: text}}},
+		}
+	}
+	messages := []types.Message{
+		text("m1", "user", "list the files"),
+		text("m2", "assistant", "here they are"),
+		text("m3", "user", "delete the logs"),
+		{
+			ID:    "m4",
+			Role:  "assistant",
+			Items: []types.CompletionItem{{ToolCall: &types.ToolCall{CallID: "call-1", Name: "deleteFile"}}},
+		},
+		{
+			Role:  "user",
+			Items: []types.CompletionItem{{ToolCallResult: &types.ToolCallResult{CallID: "call-1"}}},
+		},
+		budgetExhaustedMessage(&types.BudgetExhausted{Budget: types.BudgetTurns, Limit: 2, Used: 2}),
+		text("m5", "assistant", "done"),
+	}
+
+	if got := lastTurnStart(messages); got != 2 {
+		t.Errorf("lastTurnStart() = %d, want 2", got)
+	}
+	if got := lastTurnStart(messages[:2]); got != 0 {
+		t.Errorf("lastTurnStart() of the first turn = %d, want 0", got)
+	}
+	if got := lastTurnStart(messages[1:2]); got != -1 {
+		t.Errorf("lastTurnStart() without a user message = %d, want -1", got)
+	}
+}
@@ -0,0 +1,37 @@
+package agents
+
+import (
+	"context"
+	"maps"
+	"sync"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// usageLock serializes updates of the session usage, completions of
+// subagents run concurrently.
+var usageLock sync.Mutex
+
+// recordUsage adds the usage reported for a completion to the totals of the
+// session, by the model that served it.
+func recordUsage(ctx context.Context, model string, resp *types.CompletionResponse) {
+	session := mcp.SessionFromContext(ctx).Root()
+	if session == nil || resp == nil || resp.Usage == nil {
+		return
+	}
+	if resp.Model != "" {
+		model = resp.Model
+	}
+	addTokens(ctx, *resp.Usage)
+
+	usageLock.Lock()
+	defer usageLock.Unlock()
+
+	var usage types.SessionUsage
+	session.Get(types.UsageSessionKey, &usage)
+	// The session holds the stored value, so don't change its map in place.
+	usage = types.SessionUsage{Models: maps.Clone(usage.Models)}
+	usage.Add(model, *resp.Usage)
+	session.Set(types.UsageSessionKey, &usage)
+}
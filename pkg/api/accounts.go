@@ -0,0 +1,135 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/session"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// AdminHandler serves the account management API to the admins of the
+// session manager.
+func AdminHandler(sessionManager *session.Manager) http.Handler {
+	a := &admin{
+		sessionManager: sessionManager,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/admin/accounts", a.listAccounts)
+	mux.HandleFunc("GET /api/admin/accounts/{account_id}", a.getAccount)
+	mux.HandleFunc("PATCH /api/admin/accounts/{account_id}", a.updateAccount)
+	mux.HandleFunc("GET /api/admin/accounts/{account_id}/sessions", a.listSessions)
+	return a.requireAdmin(mux)
+}
+
+type admin struct {
+	sessionManager *session.Manager
+}
+
+// AccountDetails is an account with the token usage of its sessions.
+type AccountDetails struct {
+	session.Account
+	Usage types.SessionUsage `json:"usage"`
+}
+
+// AccountSession is a session of an account, without its state.
+type AccountSession struct {
+	SessionID   string    `json:"sessionId"`
+	Type        string    `json:"type,omitempty"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// AccountUpdate changes an account, fields that aren't set are kept.
+type AccountUpdate struct {
+	Disabled *bool                  `json:"disabled,omitempty"`
+	Settings *types.AccountSettings `json:"settings,omitempty"`
+}
+
+func (a *admin) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if !a.sessionManager.IsAdmin(types.NanobotContext(req.Context()).User.ID) {
+			http.Error(rw, "only admins can manage accounts", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(rw, req)
+	})
+}
+
+func (a *admin) listAccounts(rw http.ResponseWriter, req *http.Request) {
+	accounts, err := a.sessionManager.DB.ListAccounts(req.Context())
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(rw, accounts)
+}
+
+func (a *admin) getAccount(rw http.ResponseWriter, req *http.Request) {
+	accountID := req.PathValue("account_id")
+	account, err := a.sessionManager.DB.GetAccount(req.Context(), accountID)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	usage, err := a.sessionManager.DB.AccountUsage(req.Context(), accountID)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(rw, AccountDetails{
+		Account: *account,
+		Usage:   usage,
+	})
+}
+
+func (a *admin) updateAccount(rw http.ResponseWriter, req *http.Request) {
+	var update AccountUpdate
+	if err := json.NewDecoder(req.Body).Decode(&update); err != nil {
+		http.Error(rw, "invalid account update: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	account, err := a.sessionManager.DB.GetAccount(req.Context(), req.PathValue("account_id"))
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if update.Disabled != nil {
+		account.Disabled = *update.Disabled
+	}
+	if update.Settings != nil {
+		account.Settings = *update.Settings
+	}
+	if err := a.sessionManager.DB.SaveAccount(req.Context(), account); err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(rw, account)
+}
+
+func (a *admin) listSessions(rw http.ResponseWriter, req *http.Request) {
+	sessions, err := a.sessionManager.DB.ListAccountSessions(req.Context(), req.PathValue("account_id"))
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	result := make([]AccountSession, 0, len(sessions))
+	for _, s := range sessions {
+		result = append(result, AccountSession{
+			SessionID:   s.SessionID,
+			Type:        s.Type,
+			Description: s.Description,
+			CreatedAt:   s.CreatedAt,
+			UpdatedAt:   s.UpdatedAt,
+		})
+	}
+	writeJSON(rw, result)
+}
+
+func writeJSON(rw http.ResponseWriter, obj any) {
+	rw.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(rw).Encode(obj)
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// Activity streams the high-level activity of a session as server-sent
+// events, one event per SessionActivity named after its type, for dashboards
+// that follow a session without being an MCP client.
+func Activity(rw http.ResponseWriter, req *http.Request) error {
+	apiContext := getContext(req.Context())
+
+	state, err := apiContext.ChatClient.Session.State()
+	if err != nil {
+		return err
+	}
+
+	activities := make(chan types.SessionActivity)
+	subClient, err := mcp.NewClient(req.Context(), "nanobot.activity", apiContext.MCPServer, mcp.ClientOption{
+		OnNotify: func(ctx context.Context, msg mcp.Message) error {
+			activity, ok := parseActivity(msg)
+			if !ok {
+				return nil
+			}
+			select {
+			case activities <- activity:
+			case <-req.Context().Done():
+				return req.Context().Err()
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			return nil
+		},
+		SessionState: state,
+	})
+	if err != nil {
+		return err
+	}
+	defer subClient.Close(false)
+
+	rw.Header().Set("Content-Type", "text/event-stream")
+	rw.Header().Set("Cache-Control", "no-cache")
+	rw.WriteHeader(http.StatusOK)
+	f := newFlusher(rw)
+	f.Flush()
+
+	wl := sync.Mutex{}
+	for {
+		select {
+		case activity := <-activities:
+			if err := writeEvent(&wl, f, nil, activity.Type, activity); err != nil {
+				return nil
+			}
+		case <-req.Context().Done():
+			return nil
+		}
+	}
+}
+
+// parseActivity returns the activity of an activity notification.
+func parseActivity(msg mcp.Message) (types.SessionActivity, bool) {
+	var activity types.SessionActivity
+	if msg.Method != types.ActivityNotification || json.Unmarshal(msg.Params, &activity) != nil || activity.Type == "" {
+		return types.SessionActivity{}, false
+	}
+	return activity, true
+}
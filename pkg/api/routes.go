@@ -4,5 +4,6 @@ import "net/http"
 
 func routes(s *server, mux *http.ServeMux) {
 	mux.Handle("GET /api/events/{thread_id}", s.withContext(Events))
+	mux.Handle("GET /api/sessions/{thread_id}/events", s.withContext(Activity))
 	mux.Handle("GET /api/version", s.api(Version))
 }
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/obot-platform/mcp-oauth-proxy/pkg/oauth/validate"
@@ -30,14 +31,30 @@ type Auth struct {
 	EncryptionKey        string   `usage:"Encryption key for storing sensitive data"`
 	APIKeyAuthWebhookURL string   `usage:"URL for API key authentication webhook"`
 	MCPServerID          string   `usage:"ID of the MCP server to validate API keys for"`
+	OIDCIssuerURL        string   `usage:"Issuer URL of an OpenID Connect provider, such as https://example.okta.com/oauth2/default, to accept its tokens as bearer tokens instead of running the OAuth proxy" name:"oidc-issuer-url"`
+	OIDCAudiences        []string `usage:"Audiences accepted in OIDC tokens (default: the OAuth client ID)" name:"oidc-audiences"`
+	OIDCAccountClaim     string   `usage:"Claim of OIDC tokens used as the account ID, such as email" default:"sub" name:"oidc-account-claim"`
 }
 
-func Wrap(ctx context.Context, env map[string]string, auth Auth, dsn, healthzPath string, next http.Handler) (http.Handler, error) {
+// Wrap authenticates the requests to next. The probePaths, such as the
+// healthz and readyz paths, are served without authentication.
+func Wrap(ctx context.Context, env map[string]string, auth Auth, dsn string, probePaths []string, next http.Handler) (http.Handler, error) {
+	probePaths = slices.DeleteFunc(slices.Clone(probePaths), func(path string) bool { return path == "" })
+
+	if auth.OIDCIssuerURL != "" {
+		verifier, err := newOIDCVerifier(ctx, auth)
+		if err != nil {
+			return nil, err
+		}
+		slog.Info("oidc auth enabled", "issuer", verifier.issuer, "account_claim", verifier.accountClaim, "probe_paths", probePaths)
+		return verifier.wrap(next, probePaths), nil
+	}
+
 	if auth.OAuthClientID == "" {
 		slog.Info("auth middleware disabled, oauth client ID not configured")
 		return next, nil
 	}
-	slog.Info("auth middleware enabled", "healthz_path", healthzPath)
+	slog.Info("auth middleware enabled", "probe_paths", probePaths)
 
 	next, err := setupContext(auth, next)
 	if err != nil {
@@ -58,9 +75,9 @@ func Wrap(ctx context.Context, env map[string]string, auth Auth, dsn, healthzPat
 		}
 		slog.Info("oauth proxy created")
 
-		if healthzPath != "" {
+		if len(probePaths) > 0 {
 			return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
-				if req.URL.Path == healthzPath {
+				if slices.Contains(probePaths, req.URL.Path) {
 					next.ServeHTTP(rw, req)
 					return
 				}
@@ -155,7 +172,7 @@ func mcpProxy(auth Auth, dsn string, next http.Handler) (http.Handler, error) {
 		ScopesSupported:      strings.Join(auth.OAuthScopes, ","),
 		EncryptionKey:        base64.StdEncoding.EncodeToString(hash[:]),
 		Mode:                 "middleware",
-		MCPPaths:             []string{"/mcp", "/api"},
+		MCPPaths:             []string{"/mcp", "/api", "/a2a"},
 		OAuthJWKSURL:         auth.OAuthJWKSURL,
 		CookieNamePrefix:     "nanobot_",
 		APIKeyAuthWebhookURL: auth.APIKeyAuthWebhookURL,
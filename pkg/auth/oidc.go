@@ -0,0 +1,383 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// jwksRefreshInterval is how often the keys of the provider can be fetched
+// again for a token signed by a key that isn't known yet.
+const jwksRefreshInterval = time.Minute
+
+// oidcSigningMethods are the algorithms accepted for tokens, the ones
+// providers sign with their published keys.
+var oidcSigningMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// oidcProtectedPaths are the paths that require a token, as the OAuth proxy
+// protects them.
+var oidcProtectedPaths = []string{"/mcp", "/api", "/a2a"}
+
+// providerMetadata is the part of the OpenID provider metadata used to
+// validate tokens.
+type providerMetadata struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint,omitempty"`
+	TokenEndpoint         string   `json:"token_endpoint,omitempty"`
+	JWKSURI               string   `json:"jwks_uri"`
+	ScopesSupported       []string `json:"scopes_supported,omitempty"`
+}
+
+// oidcVerifier accepts the tokens of an OpenID provider as bearer tokens and
+// maps their claims to the user of the request.
+type oidcVerifier struct {
+	issuer       string
+	audiences    []string
+	accountClaim string
+	scopes       []string
+	keys         *jwks
+}
+
+func newOIDCVerifier(ctx context.Context, auth Auth) (*oidcVerifier, error) {
+	audiences := auth.OIDCAudiences
+	if len(audiences) == 0 && auth.OAuthClientID != "" {
+		audiences = []string{auth.OAuthClientID}
+	}
+	if len(audiences) == 0 {
+		return nil, fmt.Errorf("oidcAudiences or oauthClientID is required to validate OIDC tokens")
+	}
+
+	metadata, err := discover(ctx, auth.OIDCIssuerURL)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := auth.OAuthScopes
+	if len(scopes) == 0 {
+		scopes = metadata.ScopesSupported
+	}
+
+	accountClaim := auth.OIDCAccountClaim
+	if accountClaim == "" {
+		accountClaim = "sub"
+	}
+
+	return &oidcVerifier{
+		issuer:       metadata.Issuer,
+		audiences:    audiences,
+		accountClaim: accountClaim,
+		scopes:       scopes,
+		keys: &jwks{
+			url:    metadata.JWKSURI,
+			client: http.DefaultClient,
+		},
+	}, nil
+}
+
+// discover reads the metadata of the provider from its issuer URL.
+func discover(ctx context.Context, issuer string) (*providerMetadata, error) {
+	issuer = strings.TrimSuffix(issuer, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover OIDC provider %s: %w", issuer, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to discover OIDC provider %s: status %d", issuer, resp.StatusCode)
+	}
+
+	var metadata providerMetadata
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		return nil, fmt.Errorf("failed to decode metadata of OIDC provider %s: %w", issuer, err)
+	}
+	if strings.TrimSuffix(metadata.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("OIDC provider %s reports issuer %q", issuer, metadata.Issuer)
+	}
+	if metadata.JWKSURI == "" {
+		return nil, fmt.Errorf("OIDC provider %s has no jwks_uri", issuer)
+	}
+	return &metadata, nil
+}
+
+// verify validates the token and returns its user.
+func (v *oidcVerifier) verify(ctx context.Context, token string) (mcp.User, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return v.keys.key(ctx, kid)
+	},
+		jwt.WithValidMethods(oidcSigningMethods),
+		jwt.WithIssuer(v.issuer),
+		jwt.WithAudience(v.audiences...),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30*time.Second),
+	)
+	if err != nil {
+		return mcp.User{}, err
+	}
+
+	var user mcp.User
+	if err := mcp.JSONCoerce(claims, &user); err != nil {
+		return mcp.User{}, err
+	}
+	if user.Login == "" {
+		user.Login, _ = claims["preferred_username"].(string)
+	}
+	user.ID, _ = claims[v.accountClaim].(string)
+	if user.ID == "" {
+		return mcp.User{}, fmt.Errorf("token has no %s claim", v.accountClaim)
+	}
+	return user, nil
+}
+
+// wrap requires a valid token on the MCP and API paths, and serves the
+// protected resource metadata that points clients at the provider.
+func (v *oidcVerifier) wrap(next http.Handler, probePaths []string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/oauth-protected-resource", v.protectedResourceMetadata)
+	mux.HandleFunc("GET /.well-known/oauth-protected-resource/{path...}", v.protectedResourceMetadata)
+	mux.Handle("/", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if slices.Contains(probePaths, req.URL.Path) || !isProtected(req.URL.Path) {
+			next.ServeHTTP(rw, req)
+			return
+		}
+
+		token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			respondWithUnauthorized(rw, req, "")
+			return
+		}
+
+		user, err := v.verify(req.Context(), token)
+		if err != nil {
+			slog.Info("rejected OIDC token", "path", req.URL.Path, "error", err)
+			respondWithUnauthorized(rw, req, "invalid_token")
+			return
+		}
+
+		nctx := types.NanobotContext(req.Context())
+		nctx.User = user
+		ctx := types.WithNanobotContext(mcp.WithUser(req.Context(), user), nctx)
+		ctx = mcp.WithToken(ctx, token)
+		next.ServeHTTP(rw, req.WithContext(ctx))
+	}))
+	return mux
+}
+
+func isProtected(path string) bool {
+	for _, prefix := range oidcProtectedPaths {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *oidcVerifier) protectedResourceMetadata(rw http.ResponseWriter, req *http.Request) {
+	rw.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(rw).Encode(map[string]any{
+		"resource":                 strings.TrimSuffix(baseURL(req)+"/"+req.PathValue("path"), "/"),
+		"authorization_servers":    []string{v.issuer},
+		"scopes_supported":         v.scopes,
+		"bearer_methods_supported": []string{"header"},
+	})
+}
+
+func baseURL(req *http.Request) string {
+	host := req.Header.Get("X-Forwarded-Host")
+	if host == "" {
+		host = req.Host
+	}
+	scheme := req.Header.Get("X-Forwarded-Proto")
+	if scheme == "" {
+		if strings.HasPrefix(host, "localhost") || strings.HasPrefix(host, "127.0.0.1") {
+			scheme = "http"
+		} else {
+			scheme = "https"
+		}
+	}
+	return scheme + "://" + host
+}
+
+func respondWithUnauthorized(rw http.ResponseWriter, req *http.Request, oauthError string) {
+	resourceMetadata := strings.TrimSuffix(baseURL(req)+"/.well-known/oauth-protected-resource/"+strings.TrimPrefix(req.URL.Path, "/"), "/")
+	challenge := fmt.Sprintf(`Bearer resource_metadata="%s"`, resourceMetadata)
+	if oauthError != "" {
+		challenge = fmt.Sprintf(`Bearer error="%s", resource_metadata="%s"`, oauthError, resourceMetadata)
+	}
+	rw.Header().Set("WWW-Authenticate", challenge)
+	rw.Header().Set("Content-Type", "application/json")
+	http.Error(rw, `{"http_error": "unauthorized"}`, http.StatusUnauthorized)
+}
+
+// jwks holds the published keys of the provider, fetched again when a token
+// is signed by a key that isn't known yet, as after a key rotation.
+type jwks struct {
+	url    string
+	client *http.Client
+
+	lock      sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (j *jwks) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if key, ok := j.lookup(kid); ok {
+		return key, nil
+	}
+	if time.Since(j.fetchedAt) < jwksRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if err := j.fetch(ctx); err != nil {
+		return nil, err
+	}
+	if key, ok := j.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookup returns the key with the ID, or the only key for tokens without a
+// key ID.
+func (j *jwks) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(j.keys) == 1 {
+		for _, key := range j.keys {
+			return key, true
+		}
+	}
+	key, ok := j.keys[kid]
+	return key, ok
+}
+
+func (j *jwks) fetch(ctx context.Context) error {
+	j.fetchedAt = time.Now()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch OIDC keys: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch OIDC keys: status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("failed to decode OIDC keys: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			slog.Warn("skipping OIDC key", "kid", jwk.Kid, "error", err)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	j.keys = keys
+	return nil
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+func newTestProvider(t *testing.T, key *rsa.PrivateKey) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/.well-known/openid-configuration":
+			_ = json.NewEncoder(rw).Encode(providerMetadata{
+				Issuer:          server.URL,
+				JWKSURI:         server.URL + "/keys",
+				ScopesSupported: []string{"openid", "email"},
+			})
+		case "/keys":
+			_ = json.NewEncoder(rw).Encode(map[string]any{
+				"keys": []jsonWebKey{{
+					Kid: "key1",
+					Kty: "RSA",
+					Use: "sig",
+					N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+					E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+				}},
+			})
+		default:
+			http.NotFound(rw, req)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "key1"
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestOIDC(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := newTestProvider(t, key)
+
+	handler, err := Wrap(t.Context(), nil, Auth{
+		OIDCIssuerURL:    provider.URL,
+		OIDCAudiences:    []string{"nanobot"},
+		OIDCAccountClaim: "email",
+	}, "", []string{"/healthz"}, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = rw.Write([]byte(types.NanobotContext(req.Context()).User.ID))
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := func(audience string) jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":   provider.URL,
+			"aud":   audience,
+			"sub":   "00u1",
+			"email": "alice@example.com",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+		}
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		token  string
+		status int
+		body   string
+	}{
+		{name: "valid token", path: "/mcp", token: signToken(t, key, claims("nanobot")), status: http.StatusOK, body: "alice@example.com"},
+		{name: "other audience", path: "/mcp", token: signToken(t, key, claims("other")), status: http.StatusUnauthorized},
+		{name: "no token", path: "/mcp", status: http.StatusUnauthorized},
+		{name: "garbage token", path: "/api/events/1", token: "not-a-jwt", status: http.StatusUnauthorized},
+		{name: "healthz", path: "/healthz", status: http.StatusOK},
+		{name: "UI", path: "/index.html", status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+			if tt.body != "" && rec.Body.String() != tt.body {
+				t.Fatalf("expected account %q, got %q", tt.body, rec.Body.String())
+			}
+			if tt.status == http.StatusUnauthorized &&
+				!strings.Contains(rec.Header().Get("WWW-Authenticate"), "/.well-known/oauth-protected-resource"+tt.path) {
+				t.Fatalf("expected a resource_metadata challenge, got %q", rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://example.com/.well-known/oauth-protected-resource/mcp", nil))
+	var metadata struct {
+		Resource             string   `json:"resource"`
+		AuthorizationServers []string `json:"authorization_servers"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &metadata); err != nil {
+		t.Fatal(err)
+	}
+	if metadata.Resource != "https://example.com/mcp" || len(metadata.AuthorizationServers) != 1 || metadata.AuthorizationServers[0] != provider.URL {
+		t.Fatalf("unexpected protected resource metadata %+v", metadata)
+	}
+}
+
+func TestOIDCRequiresAudience(t *testing.T) {
+	if _, err := newOIDCVerifier(t.Context(), Auth{OIDCIssuerURL: "https://example.com"}); err == nil {
+		t.Fatal("expected an error without audiences")
+	}
+}
@@ -0,0 +1,277 @@
+package channels
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+type answers struct {
+	mu       sync.Mutex
+	messages []message
+}
+
+func (a *answers) answer(_ context.Context, agent string, msg message) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.messages = append(a.messages, msg)
+	return "hello from " + agent, nil
+}
+
+func newTestServer(t *testing.T, channels map[string]types.Channel) (*Server, *answers) {
+	t.Helper()
+	s, err := NewServer(t.Context(), nil, "", channels, map[string]string{
+		"SLACK_TOKEN":        "xoxb-test",
+		mcp.WebhookSecretEnv: "s3cret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &answers{}
+	s.answer = a.answer
+	return s, a
+}
+
+func signSlack(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + strconv.FormatInt(timestamp, 10) + ":"))
+	mac.Write(body)
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func slackRequestFor(t *testing.T, secret string, body string, headers ...string) *http.Request {
+	t.Helper()
+	timestamp := time.Now().Unix()
+	req := httptest.NewRequest(http.MethodPost, "/channels/support", strings.NewReader(body))
+	req.Header.Set("X-Slack-Request-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Slack-Signature", signSlack(secret, timestamp, []byte(body)))
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	return req
+}
+
+func TestSlack(t *testing.T) {
+	var (
+		postsMu sync.Mutex
+		posts   []map[string]string
+	)
+	api := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/chat.postMessage" || req.Header.Get("Authorization") != "Bearer xoxb-test" {
+			t.Errorf("unexpected request %s with %q", req.URL.Path, req.Header.Get("Authorization"))
+		}
+		var post map[string]string
+		_ = json.NewDecoder(req.Body).Decode(&post)
+		postsMu.Lock()
+		posts = append(posts, post)
+		postsMu.Unlock()
+		_, _ = rw.Write([]byte(`{"ok":true}`))
+	}))
+	defer api.Close()
+	slackAPIURL = api.URL
+	t.Cleanup(func() { slackAPIURL = "https://slack.com/api" })
+
+	s, a := newTestServer(t, map[string]types.Channel{
+		"support": {Agent: "helper", Slack: &types.SlackChannel{BotToken: "${SLACK_TOKEN}", SigningSecret: "signing"}},
+	})
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, slackRequestFor(t, "signing", `{"type":"url_verification","challenge":"abc"}`))
+	if rec.Code != http.StatusOK || rec.Body.String() != "abc" {
+		t.Fatalf("unexpected url verification response %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, slackRequestFor(t, "other", `{"type":"url_verification","challenge":"abc"}`))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a bad signature to be rejected, got %d", rec.Code)
+	}
+
+	for _, event := range []string{
+		`{"type":"event_callback","event":{"type":"app_mention","channel":"C1","text":"<@U123> what's up?","ts":"1.2","thread_ts":"1.1"}}`,
+		`{"type":"event_callback","event":{"type":"message","channel":"C1","text":"my own reply","ts":"1.3","bot_id":"B1"}}`,
+		`{"type":"event_callback","event":{"type":"message","channel_type":"channel","channel":"C1","text":"chatter","ts":"1.4"}}`,
+	} {
+		rec = httptest.NewRecorder()
+		s.ServeHTTP(rec, slackRequestFor(t, "signing", event))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("unexpected event response %d: %s", rec.Code, rec.Body)
+		}
+	}
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, slackRequestFor(t, "signing",
+		`{"type":"event_callback","event":{"type":"message","channel_type":"im","channel":"D1","text":"retried","ts":"2.1"}}`,
+		"X-Slack-Retry-Num", "1"))
+	s.Wait()
+
+	if len(a.messages) != 1 {
+		t.Fatalf("expected only the mention to be answered, got %+v", a.messages)
+	}
+	if msg := a.messages[0]; msg.threadKey != "C1:1.1" || msg.text != "what's up?" || msg.channel != "support" {
+		t.Errorf("unexpected message %+v", msg)
+	}
+	if len(posts) != 1 || posts[0]["channel"] != "C1" || posts[0]["thread_ts"] != "1.1" || posts[0]["text"] != "hello from helper" {
+		t.Errorf("unexpected posts %v", posts)
+	}
+}
+
+func TestEmail(t *testing.T) {
+	var sent []string
+	sendMail = func(addr string, _ smtp.Auth, from string, to []string, msg []byte) error {
+		sent = append(sent, addr+" "+from+" "+strings.Join(to, ",")+"\n"+string(msg))
+		return nil
+	}
+	t.Cleanup(func() { sendMail = smtp.SendMail })
+
+	s, a := newTestServer(t, map[string]types.Channel{
+		"support": {Email: &types.EmailChannel{SMTP: types.SMTPServer{Host: "smtp.example.com", From: "Bot <bot@example.com>"}}},
+	})
+
+	body, _ := json.Marshal(inboundEmail{
+		MessageID:  "<m2@mail.example.com>",
+		InReplyTo:  "<r1@example.com>",
+		References: []string{"<m1@mail.example.com>", "<r1@example.com>"},
+		From:       "Ada <ada@example.org>",
+		Subject:    "Invoice",
+		Text:       "See attached.",
+		Attachments: []emailAttachment{
+			{Name: "invoice.txt", ContentType: "text/plain", Content: "MTIz"},
+		},
+	})
+	timestamp := time.Now().Unix()
+	req := httptest.NewRequest(http.MethodPost, "/channels/support", strings.NewReader(string(body)))
+	req.Header.Set(mcp.WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(mcp.WebhookSignatureHeader, mcp.SignWebhook("s3cret", timestamp, body))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("unexpected response %d: %s", rec.Code, rec.Body)
+	}
+	s.Wait()
+
+	if len(a.messages) != 1 {
+		t.Fatalf("expected the email to be answered, got %+v", a.messages)
+	}
+	msg := a.messages[0]
+	if msg.threadKey != "ada@example.org:<m1@mail.example.com>" || msg.text != "Subject: Invoice\n\nSee attached." ||
+		len(msg.attachments) != 1 || string(msg.attachments[0].data) != "123" {
+		t.Errorf("unexpected message %+v", msg)
+	}
+
+	if len(sent) != 1 {
+		t.Fatalf("expected one reply, got %d", len(sent))
+	}
+	for _, want := range []string{
+		"smtp.example.com:587 bot@example.com ada@example.org\n",
+		"Subject: Re: Invoice\r\n",
+		"In-Reply-To: <m2@mail.example.com>\r\n",
+		"References: <m1@mail.example.com> <r1@example.com> <m2@mail.example.com>\r\n",
+		"\r\n\r\nhello from \r\n",
+	} {
+		if !strings.Contains(sent[0], want) {
+			t.Errorf("expected the reply to contain %q, got:\n%s", want, sent[0])
+		}
+	}
+
+	// A replayed email is acknowledged but not answered again.
+	req = httptest.NewRequest(http.MethodPost, "/channels/support", strings.NewReader(string(body)))
+	req.Header.Set(mcp.WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(mcp.WebhookSignatureHeader, mcp.SignWebhook("s3cret", timestamp, body))
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	s.Wait()
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("expected a replayed email to be acknowledged, got %d", rec.Code)
+	}
+	if len(a.messages) != 1 || len(sent) != 1 {
+		t.Errorf("expected a replayed email not to be answered again, got %d messages and %d replies", len(a.messages), len(sent))
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/channels/support", strings.NewReader(string(body)))
+	req.Header.Set(mcp.WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(mcp.WebhookSignatureHeader, mcp.SignWebhook("other", timestamp, body))
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected a bad signature to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestSlackReplay(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		_, _ = rw.Write([]byte(`{"ok":true}`))
+	}))
+	defer api.Close()
+	slackAPIURL = api.URL
+	t.Cleanup(func() { slackAPIURL = "https://slack.com/api" })
+
+	s, a := newTestServer(t, map[string]types.Channel{
+		"support": {Agent: "helper", Slack: &types.SlackChannel{BotToken: "${SLACK_TOKEN}", SigningSecret: "signing"}},
+	})
+
+	req := slackRequestFor(t, "signing", `{"type":"event_callback","event":{"type":"app_mention","channel":"C1","text":"<@U123> hi","ts":"1.1"}}`)
+	for range 2 {
+		replay := req.Clone(t.Context())
+		replay.Body = io.NopCloser(strings.NewReader(`{"type":"event_callback","event":{"type":"app_mention","channel":"C1","text":"<@U123> hi","ts":"1.1"}}`))
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, replay)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("unexpected event response %d: %s", rec.Code, rec.Body)
+		}
+	}
+	s.Wait()
+
+	if len(a.messages) != 1 {
+		t.Errorf("expected a replayed event to be answered once, got %+v", a.messages)
+	}
+}
+
+func TestServeHTTPRejects(t *testing.T) {
+	s, _ := newTestServer(t, map[string]types.Channel{
+		"off": {Disabled: true, Slack: &types.SlackChannel{BotToken: "t", SigningSecret: "s"}},
+	})
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/channels/missing", nil),
+		httptest.NewRequest(http.MethodPost, "/channels/off", nil),
+	} {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s: status = %d, want 404", req.URL.Path, rec.Code)
+		}
+	}
+}
+
+func TestSlackSigningSecretRequired(t *testing.T) {
+	for _, secret := range []string{"", "${SLACK_SIGNING_SECRET}"} {
+		_, err := NewServer(t.Context(), nil, "", map[string]types.Channel{
+			"support": {Slack: &types.SlackChannel{BotToken: "t", SigningSecret: secret}},
+		}, nil)
+		if err == nil {
+			t.Errorf("expected the signing secret %q to be rejected", secret)
+		}
+	}
+}
+
+func TestDownloadSlackFileOnlyFromSlack(t *testing.T) {
+	for _, fileURL := range []string{"https://attacker.example/file", "http://files.slack.com/file"} {
+		if _, err := downloadSlackFile(t.Context(), "xoxb-test", fileURL); err == nil {
+			t.Errorf("expected %s to be refused", fileURL)
+		}
+	}
+}
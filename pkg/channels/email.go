@@ -0,0 +1,178 @@
+package channels
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/envvar"
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+	"github.com/obot-platform/nanobot/pkg/uuid"
+)
+
+var sendMail = smtp.SendMail
+
+// inboundEmail is an email parsed by a mail service.
+type inboundEmail struct {
+	MessageID   string            `json:"messageId"`
+	InReplyTo   string            `json:"inReplyTo,omitempty"`
+	References  []string          `json:"references,omitempty"`
+	From        string            `json:"from"`
+	Subject     string            `json:"subject,omitempty"`
+	Text        string            `json:"text"`
+	Attachments []emailAttachment `json:"attachments,omitempty"`
+}
+
+type emailAttachment struct {
+	Name        string `json:"name"`
+	ContentType string `json:"contentType,omitempty"`
+	// Content is base64 encoded.
+	Content string `json:"content"`
+}
+
+func (s *Server) serveEmail(rw http.ResponseWriter, req *http.Request, name string, channel types.Channel, body []byte) {
+	secret := s.env[mcp.WebhookSecretEnv]
+	if channel.Email.Secret != "" {
+		secret = envvar.ReplaceString(s.env, channel.Email.Secret)
+	}
+	if secret == "" {
+		http.Error(rw, "channel has no secret", http.StatusForbidden)
+		return
+	}
+	timestamp, signature := req.Header.Get(mcp.WebhookTimestampHeader), req.Header.Get(mcp.WebhookSignatureHeader)
+	if err := mcp.VerifyWebhook(secret, timestamp, signature, body, time.Now()); err != nil {
+		http.Error(rw, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if !s.acceptDelivery(name, timestamp, signature, time.Now()) {
+		// The email was received before, it is only answered once.
+		rw.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	var email inboundEmail
+	if err := json.Unmarshal(body, &email); err != nil {
+		http.Error(rw, "invalid email", http.StatusBadRequest)
+		return
+	}
+	msg, err := emailMessage(name, email)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	smtpServer := channel.Email.SMTP
+	s.handle(channel.Agent, msg, func(_ context.Context, reply string) error {
+		return s.sendReply(smtpServer, email, reply)
+	})
+	rw.WriteHeader(http.StatusAccepted)
+}
+
+// emailMessage returns the message of an email. Its thread is the first
+// email of the thread, the first of its references, of the sender, so knowing
+// the Message-ID of an email doesn't let another sender join its thread.
+func emailMessage(channel string, email inboundEmail) (message, error) {
+	if email.MessageID == "" || email.From == "" {
+		return message{}, fmt.Errorf("email needs a messageId and a from address")
+	}
+	from, err := mail.ParseAddress(email.From)
+	if err != nil {
+		return message{}, fmt.Errorf("invalid from address %q", email.From)
+	}
+
+	msg := message{
+		channel:     channel,
+		threadKey:   strings.ToLower(from.Address) + ":" + cmp.Or(firstOf(email.References), email.InReplyTo, email.MessageID),
+		description: truncate(cmp.Or(strings.TrimSpace(email.Subject), "Email from "+email.From), 80),
+		text:        strings.TrimSpace(email.Text),
+	}
+	if email.Subject != "" {
+		msg.text = "Subject: " + email.Subject + "\n\n" + msg.text
+	}
+	for _, a := range email.Attachments {
+		data, err := base64.StdEncoding.DecodeString(a.Content)
+		if err != nil {
+			return message{}, fmt.Errorf("attachment %q is not base64 encoded", a.Name)
+		}
+		if len(data) > maxAttachment {
+			return message{}, fmt.Errorf("attachment %q is larger than %d bytes", a.Name, maxAttachment)
+		}
+		msg.attachments = append(msg.attachments, attachment{name: cmp.Or(a.Name, "attachment"), mimeType: a.ContentType, data: data})
+	}
+	return msg, nil
+}
+
+// sendReply sends the reply to the sender of the email, in the thread of the
+// email.
+func (s *Server) sendReply(server types.SMTPServer, email inboundEmail, reply string) error {
+	var (
+		host     = envvar.ReplaceString(s.env, server.Host)
+		from     = envvar.ReplaceString(s.env, server.From)
+		username = envvar.ReplaceString(s.env, server.Username)
+		password = envvar.ReplaceString(s.env, server.Password)
+		port     = cmp.Or(server.Port, 587)
+	)
+
+	to, err := mail.ParseAddress(email.From)
+	if err != nil {
+		return err
+	}
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return fmt.Errorf("invalid smtp from address %q", from)
+	}
+
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return sendMail(net.JoinHostPort(host, strconv.Itoa(port)), auth, sender.Address, []string{to.Address}, replyEmail(sender, to, email, reply, time.Now()))
+}
+
+func replyEmail(from, to *mail.Address, email inboundEmail, reply string, now time.Time) []byte {
+	subject := cmp.Or(email.Subject, "Your message")
+	if !strings.HasPrefix(strings.ToLower(subject), "re:") {
+		subject = "Re: " + subject
+	}
+	domain := from.Address[strings.LastIndex(from.Address, "@")+1:]
+	references := headerValue(strings.Join(slices.Concat(email.References, []string{email.MessageID}), " "))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", to)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Message-ID: <%s@%s>\r\n", uuid.String(), domain)
+	fmt.Fprintf(&b, "In-Reply-To: %s\r\n", headerValue(email.MessageID))
+	fmt.Fprintf(&b, "References: %s\r\n", references)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(reply, "\r\n", "\n"), "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
+
+// headerValue keeps values of the inbound email from adding headers.
+func headerValue(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+func firstOf(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
@@ -0,0 +1,314 @@
+// Package channels bridges front ends that don't speak MCP, like Slack and
+// email, to the agents of nanobot serve. Every thread of a channel is a
+// session, which the channel talks to over the loopback URL of the server.
+package channels
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/envvar"
+	"github.com/obot-platform/nanobot/pkg/fileuri"
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/session"
+	"github.com/obot-platform/nanobot/pkg/types"
+	"github.com/obot-platform/nanobot/pkg/uuid"
+	"gorm.io/gorm"
+)
+
+// PathPrefix is the path of the channels, which receive their events at
+// /channels/<name>.
+const PathPrefix = "/channels/"
+
+const (
+	maxPayload    = 32 << 20
+	maxAttachment = 20 << 20
+	replyTimeout  = 30 * time.Minute
+	failedReply   = "Sorry, I couldn't answer this message."
+)
+
+// message is a message a channel received.
+type message struct {
+	channel   string
+	threadKey string
+	// description is the description of the session of a new thread.
+	description string
+	text        string
+	attachments []attachment
+}
+
+type attachment struct {
+	name     string
+	mimeType string
+	data     []byte
+}
+
+// Server answers the messages of the channels of the config.
+type Server struct {
+	ctx         context.Context
+	wg          sync.WaitGroup
+	db          *session.Store
+	loopbackURL string
+	env         map[string]string
+	channels    map[string]types.Channel
+
+	mu      sync.Mutex
+	threads map[string]*threadLock
+	// deliveries are the signatures of the webhook payloads that were
+	// accepted, until their timestamp is too old to be accepted again.
+	deliveries map[string]time.Time
+
+	// answer returns the reply of the agent to a message.
+	answer func(ctx context.Context, agent string, msg message) (string, error)
+}
+
+type threadLock struct {
+	sync.Mutex
+	waiting int
+}
+
+// NewServer creates the server of the channels. Replies in progress are
+// cancelled with ctx. It fails if a Slack channel has no signing secret once
+// the env is substituted.
+func NewServer(ctx context.Context, db *session.Store, loopbackURL string, channels map[string]types.Channel, env map[string]string) (*Server, error) {
+	for name, channel := range channels {
+		if channel.Disabled || channel.Slack == nil {
+			continue
+		}
+		if secret := envvar.ReplaceString(env, channel.Slack.SigningSecret); secret == "" || strings.Contains(secret, "${") {
+			return nil, fmt.Errorf("channel %q has no slack signingSecret", name)
+		}
+	}
+
+	s := &Server{
+		ctx:         ctx,
+		db:          db,
+		loopbackURL: loopbackURL,
+		env:         env,
+		channels:    channels,
+		threads:     map[string]*threadLock{},
+	}
+	s.answer = s.reply
+	return s, nil
+}
+
+// Wait waits for the replies in progress.
+func (s *Server) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Server) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	name := strings.TrimPrefix(req.URL.Path, PathPrefix)
+	channel, ok := s.channels[name]
+	if !ok || channel.Disabled {
+		http.NotFound(rw, req)
+		return
+	}
+	if req.Method != http.MethodPost {
+		rw.Header().Set("Allow", http.MethodPost)
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxPayload+1))
+	if err != nil {
+		http.Error(rw, "failed to read payload", http.StatusBadRequest)
+		return
+	} else if len(body) > maxPayload {
+		http.Error(rw, "payload too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	switch {
+	case channel.Slack != nil:
+		s.serveSlack(rw, req, name, channel, body)
+	case channel.Email != nil:
+		s.serveEmail(rw, req, name, channel, body)
+	default:
+		http.NotFound(rw, req)
+	}
+}
+
+// acceptDelivery records the signature of a verified webhook payload of a
+// channel, and returns false if it was already accepted, so that a payload
+// can't be replayed while its timestamp is still accepted.
+func (s *Server) acceptDelivery(name, timestamp, signature string, now time.Time) bool {
+	ts, _ := strconv.ParseInt(timestamp, 10, 64)
+	key := name + "/" + signature
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, expires := range s.deliveries {
+		if now.After(expires) {
+			delete(s.deliveries, key)
+		}
+	}
+	if _, ok := s.deliveries[key]; ok {
+		return false
+	}
+	if s.deliveries == nil {
+		s.deliveries = map[string]time.Time{}
+	}
+	s.deliveries[key] = time.Unix(ts, 0).Add(mcp.WebhookMaxAge)
+	return true
+}
+
+// handle answers the message in the background and sends the reply. The
+// messages of a thread are answered one after another.
+func (s *Server) handle(agent string, msg message, send func(ctx context.Context, reply string) error) {
+	s.wg.Go(func() {
+		ctx, cancel := context.WithTimeout(s.ctx, replyTimeout)
+		defer cancel()
+
+		unlock := s.lockThread(msg.channel + "/" + msg.threadKey)
+		reply, err := s.answer(ctx, agent, msg)
+		unlock()
+		if err != nil {
+			slog.Error("channel: failed to answer message", "channel", msg.channel, "thread", msg.threadKey, "error", err)
+			reply = failedReply
+		}
+		if strings.TrimSpace(reply) == "" {
+			return
+		}
+		if err := send(ctx, reply); err != nil {
+			slog.Error("channel: failed to send reply", "channel", msg.channel, "thread", msg.threadKey, "error", err)
+		}
+	})
+}
+
+func (s *Server) lockThread(key string) (unlock func()) {
+	s.mu.Lock()
+	lock, ok := s.threads[key]
+	if !ok {
+		lock = &threadLock{}
+		s.threads[key] = lock
+	}
+	lock.waiting++
+	s.mu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		s.mu.Lock()
+		if lock.waiting--; lock.waiting == 0 {
+			delete(s.threads, key)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// reply chats with the agent in the session of the thread, which is created
+// for the first message of the thread.
+func (s *Server) reply(ctx context.Context, agent string, msg message) (string, error) {
+	var state *mcp.SessionState
+	thread, err := s.db.GetChannelThread(ctx, msg.channel, msg.threadKey)
+	if err == nil {
+		state = &mcp.SessionState{ID: thread.SessionID}
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+
+	headers := map[string]string{
+		"X-Nanobot-Description": msg.description,
+	}
+	if agent != "" {
+		headers["X-Nanobot-Default-Agent"] = agent
+	}
+	client, err := mcp.NewClient(ctx, "nanobot-channels", mcp.Server{
+		BaseURL: s.loopbackURL,
+		Headers: headers,
+	}, mcp.ClientOption{
+		ClientName:   "nanobot-channels",
+		SessionState: state,
+		OnMessage: func(context.Context, mcp.Message) error {
+			return nil
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to open session: %w", err)
+	}
+	defer client.Close(false)
+
+	if state == nil {
+		if err := s.db.SaveChannelThread(ctx, &session.ChannelThread{
+			Channel:   msg.channel,
+			ThreadKey: msg.threadKey,
+			SessionID: client.Session.ID(),
+		}); err != nil {
+			return "", fmt.Errorf("failed to save thread: %w", err)
+		}
+	}
+
+	attachments := make([]types.Attachment, 0, len(msg.attachments))
+	for _, a := range msg.attachments {
+		uploaded, err := upload(ctx, client, a)
+		if err != nil {
+			return "", err
+		}
+		attachments = append(attachments, uploaded)
+	}
+
+	result, err := client.Call(ctx, types.AgentTool+"nanobot", map[string]any{
+		"prompt":      msg.text,
+		"attachments": attachments,
+	}, mcp.CallOption{
+		ProgressToken: uuid.String(),
+	})
+	if err != nil {
+		return "", err
+	}
+	if result.IsError {
+		return "", fmt.Errorf("agent failed: %s", resultText(result))
+	}
+	return resultText(result), nil
+}
+
+// upload writes the attachment to the files of the session with the
+// published uploadFile tool.
+func upload(ctx context.Context, client *mcp.Client, a attachment) (types.Attachment, error) {
+	name := "attachments/" + fileuri.SafeFilename(path.Base("/"+a.name))
+	result, err := client.Call(ctx, "uploadFile", map[string]any{
+		"name": name,
+		"blob": base64.StdEncoding.EncodeToString(a.data),
+	})
+	if err != nil {
+		return types.Attachment{}, fmt.Errorf("failed to upload %s: %w", a.name, err)
+	}
+	if result.IsError {
+		return types.Attachment{}, fmt.Errorf("failed to upload %s: %s", a.name, resultText(result))
+	}
+
+	uploaded := types.Attachment{Name: a.name, MimeType: a.mimeType}
+	for _, content := range result.Content {
+		if content.Type == "resource_link" {
+			uploaded.URL = content.URI
+		}
+	}
+	if uploaded.URL == "" {
+		uploaded.URL, _ = result.StructuredContent["uri"].(string)
+	}
+	if uploaded.URL == "" {
+		return types.Attachment{}, fmt.Errorf("failed to upload %s: no file URI in the result", a.name)
+	}
+	return uploaded, nil
+}
+
+func resultText(result *mcp.CallToolResult) string {
+	var text []string
+	for _, content := range result.Content {
+		if content.Type == "text" && content.Text != "" {
+			text = append(text, content.Text)
+		}
+	}
+	return strings.Join(text, "\n\n")
+}
@@ -0,0 +1,237 @@
+package channels
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/envvar"
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// slackFilesHost is the host of the files of messages.
+const slackFilesHost = "files.slack.com"
+
+var (
+	slackAPIURL = "https://slack.com/api"
+	slackClient = &http.Client{Timeout: time.Minute}
+
+	slackMention = regexp.MustCompile(`<@[A-Z0-9]+>`)
+)
+
+type slackRequest struct {
+	Type      string     `json:"type"`
+	Challenge string     `json:"challenge,omitempty"`
+	Event     slackEvent `json:"event"`
+}
+
+type slackEvent struct {
+	Type        string      `json:"type"`
+	Subtype     string      `json:"subtype,omitempty"`
+	ChannelType string      `json:"channel_type,omitempty"`
+	Channel     string      `json:"channel"`
+	User        string      `json:"user,omitempty"`
+	BotID       string      `json:"bot_id,omitempty"`
+	Text        string      `json:"text"`
+	TS          string      `json:"ts"`
+	ThreadTS    string      `json:"thread_ts,omitempty"`
+	Files       []slackFile `json:"files,omitempty"`
+}
+
+type slackFile struct {
+	Name               string `json:"name"`
+	MimeType           string `json:"mimetype"`
+	Size               int    `json:"size"`
+	URLPrivateDownload string `json:"url_private_download"`
+}
+
+func (s *Server) serveSlack(rw http.ResponseWriter, req *http.Request, name string, channel types.Channel, body []byte) {
+	var (
+		slack         = channel.Slack
+		signingSecret = envvar.ReplaceString(s.env, slack.SigningSecret)
+		botToken      = envvar.ReplaceString(s.env, slack.BotToken)
+	)
+	timestamp, signature := req.Header.Get("X-Slack-Request-Timestamp"), req.Header.Get("X-Slack-Signature")
+	if err := verifySlack(signingSecret, timestamp, signature, body, time.Now()); err != nil {
+		http.Error(rw, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var slackReq slackRequest
+	if err := json.Unmarshal(body, &slackReq); err != nil {
+		http.Error(rw, "invalid event", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case slackReq.Type == "url_verification":
+		rw.Header().Set("Content-Type", "text/plain")
+		_, _ = rw.Write([]byte(slackReq.Challenge))
+		return
+	case req.Header.Get("X-Slack-Retry-Num") != "":
+		// The event was received before, Slack retries events that weren't
+		// acknowledged quickly.
+	case !s.acceptDelivery(name, timestamp, signature, time.Now()):
+		// The same signed event was received before, it is only answered
+		// once.
+	case slackReq.Type == "event_callback":
+		if msg, ok := slackMessage(name, slackReq.Event); ok {
+			event := slackReq.Event
+			s.wg.Go(func() {
+				msg.attachments = s.slackFiles(botToken, name, event.Files)
+				s.handle(channel.Agent, msg, func(ctx context.Context, reply string) error {
+					return postSlackMessage(ctx, botToken, event.Channel, cmp.Or(event.ThreadTS, event.TS), reply)
+				})
+			})
+		}
+	}
+	rw.WriteHeader(http.StatusOK)
+}
+
+// slackMessage returns the message of an event the app answers: mentions of
+// the app and direct messages. The messages of bots, including the replies
+// of the app, are ignored.
+func slackMessage(channel string, event slackEvent) (message, bool) {
+	if event.BotID != "" || (event.Subtype != "" && event.Subtype != "file_share") {
+		return message{}, false
+	}
+	if event.Type != "app_mention" && (event.Type != "message" || event.ChannelType != "im") {
+		return message{}, false
+	}
+
+	text := strings.TrimSpace(slackMention.ReplaceAllString(event.Text, ""))
+	if text == "" && len(event.Files) == 0 {
+		return message{}, false
+	}
+	threadTS := cmp.Or(event.ThreadTS, event.TS)
+	return message{
+		channel:     channel,
+		threadKey:   event.Channel + ":" + threadTS,
+		description: truncate(cmp.Or(text, "Slack thread"), 80),
+		text:        text,
+	}, true
+}
+
+// slackFiles downloads the files of a message. Files that fail to download
+// are left out.
+func (s *Server) slackFiles(botToken, channel string, files []slackFile) (result []attachment) {
+	for _, file := range files {
+		if file.URLPrivateDownload == "" || file.Size > maxAttachment {
+			slog.Warn("channel: skipping slack file", "channel", channel, "file", file.Name, "size", file.Size)
+			continue
+		}
+		data, err := downloadSlackFile(s.ctx, botToken, file.URLPrivateDownload)
+		if err != nil {
+			slog.Error("channel: failed to download slack file", "channel", channel, "file", file.Name, "error", err)
+			continue
+		}
+		result = append(result, attachment{name: file.Name, mimeType: file.MimeType, data: data})
+	}
+	return
+}
+
+// downloadSlackFile downloads a file of a message. The bot token is only sent
+// to the file host of Slack.
+func downloadSlackFile(ctx context.Context, botToken, fileURL string) ([]byte, error) {
+	if u, err := url.Parse(fileURL); err != nil || u.Scheme != "https" || u.Host != slackFilesHost {
+		return nil, fmt.Errorf("file is not hosted on %s", slackFilesHost)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+botToken)
+
+	resp, err := slackClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download failed with status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxAttachment+1))
+	if err != nil {
+		return nil, err
+	} else if len(data) > maxAttachment {
+		return nil, fmt.Errorf("file is larger than %d bytes", maxAttachment)
+	}
+	return data, nil
+}
+
+func postSlackMessage(ctx context.Context, botToken, channel, threadTS, text string) error {
+	body, err := json.Marshal(map[string]string{
+		"channel":   channel,
+		"thread_ts": threadTS,
+		"text":      text,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, slackAPIURL+"/chat.postMessage", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+botToken)
+
+	resp, err := slackClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode chat.postMessage response with status %d: %w", resp.StatusCode, err)
+	}
+	if !result.OK {
+		return fmt.Errorf("chat.postMessage failed: %s", result.Error)
+	}
+	return nil
+}
+
+// verifySlack checks the signature of a request of Slack, the hex encoded
+// HMAC-SHA256 of "v0:<timestamp>:<body>".
+func verifySlack(signingSecret, timestamp, signature string, body []byte, now time.Time) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid X-Slack-Request-Timestamp header")
+	}
+	if age := now.Sub(time.Unix(ts, 0)); age > mcp.WebhookMaxAge || age < -mcp.WebhookMaxAge {
+		return fmt.Errorf("slack request timestamp is too old")
+	}
+
+	mac := hmac.New(sha256.New, []byte(signingSecret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	if !hmac.Equal([]byte(signature), []byte("v0="+hex.EncodeToString(mac.Sum(nil)))) {
+		return fmt.Errorf("invalid slack signature")
+	}
+	return nil
+}
+
+func truncate(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n-1]) + "…"
+	}
+	return s
+}
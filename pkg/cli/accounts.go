@@ -0,0 +1,198 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/api"
+	"github.com/obot-platform/nanobot/pkg/llm"
+	"github.com/spf13/cobra"
+)
+
+type Accounts struct {
+	Nanobot *Nanobot
+	Output  string `usage:"Output format (json, yaml, table)" short:"o" default:"table"`
+}
+
+func NewAccounts(n *Nanobot) *Accounts {
+	return &Accounts{
+		Nanobot: n,
+	}
+}
+
+func (a *Accounts) Customize(cmd *cobra.Command) {
+	cmd.Use = "accounts [flags]"
+	cmd.Short = "List the accounts that have sessions"
+	cmd.Aliases = []string{"account"}
+	cmd.Args = cobra.NoArgs
+}
+
+func (a *Accounts) Run(cmd *cobra.Command, _ []string) error {
+	store, err := a.Nanobot.NewSessionStore("")
+	if err != nil {
+		return err
+	}
+
+	accounts, err := store.ListAccounts(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	if display(accounts, a.Output) {
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, err = tw.Write([]byte("ACCT\tSESSIONS\tLAST ACTIVE\tDISABLED\tALLOWED AGENTS\tALLOWED MODELS\n"))
+	if err != nil {
+		return err
+	}
+
+	for _, account := range accounts {
+		var lastActive string
+		if account.LastActiveAt != nil {
+			lastActive = account.LastActiveAt.Format(time.RFC3339)
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%s\t%t\t%s\t%s\n", trim(account.AccountID), account.Sessions, lastActive,
+			account.Disabled, strings.Join(account.Settings.AllowedAgents, ","), strings.Join(account.Settings.AllowedModels, ","))
+	}
+
+	return tw.Flush()
+}
+
+type AccountsShow struct {
+	Nanobot *Nanobot
+	Output  string `usage:"Output format (json, yaml, table)" short:"o" default:"table"`
+}
+
+func NewAccountsShow(n *Nanobot) *AccountsShow {
+	return &AccountsShow{
+		Nanobot: n,
+	}
+}
+
+func (a *AccountsShow) Customize(cmd *cobra.Command) {
+	cmd.Use = "show [flags] ACCOUNT_ID"
+	cmd.Short = "Show the settings, sessions, and token usage of an account"
+	cmd.Args = cobra.ExactArgs(1)
+}
+
+func (a *AccountsShow) Run(cmd *cobra.Command, args []string) error {
+	store, err := a.Nanobot.NewSessionStore("")
+	if err != nil {
+		return err
+	}
+
+	account, err := store.GetAccount(cmd.Context(), args[0])
+	if err != nil {
+		return err
+	}
+	usage, err := store.AccountUsage(cmd.Context(), args[0])
+	if err != nil {
+		return err
+	}
+	sessions, err := store.ListAccountSessions(cmd.Context(), args[0])
+	if err != nil {
+		return err
+	}
+
+	if display(api.AccountDetails{Account: *account, Usage: usage}, a.Output) {
+		return nil
+	}
+
+	fmt.Printf("Account:        %s\n", account.AccountID)
+	fmt.Printf("Disabled:       %t\n", account.Disabled)
+	fmt.Printf("Allowed agents: %s\n", orAll(account.Settings.AllowedAgents))
+	fmt.Printf("Allowed models: %s\n", orAll(account.Settings.AllowedModels))
+	fmt.Println()
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = tw.Write([]byte("SESSION\tUPDATED\tDESCRIPTION\n"))
+	for _, session := range sessions {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\n", session.SessionID, session.UpdatedAt.Format(time.RFC3339), trim(session.Description))
+	}
+	_, _ = tw.Write([]byte("\n"))
+
+	report := llm.UsageReport(usage)
+	_, _ = tw.Write([]byte("MODEL\tCOMPLETIONS\tINPUT\tOUTPUT\tCACHE READ\tCACHE WRITE\tCOST\n"))
+	for _, model := range report.Models {
+		writeUsageRow(tw, model.Model, model.Usage, model.Cost)
+	}
+	if len(report.Models) > 1 {
+		writeUsageRow(tw, "TOTAL", report.Total, &report.Cost)
+	}
+
+	return tw.Flush()
+}
+
+func orAll(values []string) string {
+	if len(values) == 0 {
+		return "all"
+	}
+	return strings.Join(values, ", ")
+}
+
+type AccountsSet struct {
+	Nanobot       *Nanobot
+	Disabled      *bool    `usage:"Disable the account, --disabled=false enables it again"`
+	AllowedAgents []string `usage:"Agents the account can chat with, all when empty"`
+	AllowedModels []string `usage:"Models the agents of the account can use, as names or patterns like gpt-4.1*, all when empty"`
+}
+
+func NewAccountsSet(n *Nanobot) *AccountsSet {
+	return &AccountsSet{
+		Nanobot: n,
+	}
+}
+
+func (a *AccountsSet) Customize(cmd *cobra.Command) {
+	cmd.Use = "set [flags] ACCOUNT_ID"
+	cmd.Short = "Change what an account can use, or disable it"
+	cmd.Args = cobra.ExactArgs(1)
+	cmd.Example = `
+  # Only let an account chat with the support agent using small models.
+  nanobot accounts set alice --allowed-agents support --allowed-models 'gpt-4.1-mini*'
+
+  # Let the account use all models again.
+  nanobot accounts set alice --allowed-models ''
+
+  # Disable an account.
+  nanobot accounts set alice --disabled
+`
+}
+
+func (a *AccountsSet) Run(cmd *cobra.Command, args []string) error {
+	store, err := a.Nanobot.NewSessionStore("")
+	if err != nil {
+		return err
+	}
+
+	account, err := store.GetAccount(cmd.Context(), args[0])
+	if err != nil {
+		return err
+	}
+
+	if a.Disabled != nil {
+		account.Disabled = *a.Disabled
+	}
+	if cmd.Flags().Changed("allowed-agents") {
+		account.Settings.AllowedAgents = nonEmpty(a.AllowedAgents)
+	}
+	if cmd.Flags().Changed("allowed-models") {
+		account.Settings.AllowedModels = nonEmpty(a.AllowedModels)
+	}
+
+	return store.SaveAccount(cmd.Context(), account)
+}
+
+func nonEmpty(values []string) (result []string) {
+	for _, value := range values {
+		if value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
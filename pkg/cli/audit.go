@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/session"
+	"github.com/spf13/cobra"
+)
+
+type Audit struct {
+	Nanobot *Nanobot
+	Session string `usage:"Only show the tool calls of this session"`
+	Account string `usage:"Only show the tool calls of this account"`
+	Tool    string `usage:"Only show the calls of this tool, or of the tools of this server"`
+	Since   string `usage:"Only show the tool calls made in this long, like 24h"`
+	Errors  bool   `usage:"Only show the tool calls that failed"`
+	Limit   int    `usage:"Show at most this many of the latest tool calls" default:"100"`
+	Output  string `usage:"Output format (json, yaml, table)" short:"o" default:"table"`
+}
+
+func NewAudit(n *Nanobot) *Audit {
+	return &Audit{
+		Nanobot: n,
+	}
+}
+
+func (a *Audit) Customize(cmd *cobra.Command) {
+	cmd.Use = "audit [flags]"
+	cmd.Short = "Show the tool call audit log"
+	cmd.Args = cobra.NoArgs
+	cmd.Example = `
+  # Show the failed tool calls of the last day.
+  nanobot audit --since 24h --errors
+
+  # Show the calls to the tools of the github server as JSON.
+  nanobot audit --tool github -o json
+`
+}
+
+func (a *Audit) Run(cmd *cobra.Command, _ []string) error {
+	query := session.ToolCallAuditQuery{
+		SessionID:  a.Session,
+		AccountID:  a.Account,
+		Tool:       a.Tool,
+		ErrorsOnly: a.Errors,
+		Limit:      a.Limit,
+	}
+	if a.Since != "" {
+		since, err := time.ParseDuration(a.Since)
+		if err != nil {
+			return fmt.Errorf("invalid --since: %w", err)
+		}
+		query.Since = time.Now().Add(-since)
+	}
+
+	store, err := a.Nanobot.NewSessionStore("")
+	if err != nil {
+		return err
+	}
+
+	records, err := store.ToolCallAudits(cmd.Context(), query)
+	if err != nil {
+		return err
+	}
+
+	if display(records, a.Output) {
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, err = tw.Write([]byte("TIME\tSESSION\tACCT\tAGENT\tTOOL\tDURATION\tRESULT\n"))
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		tool := record.Server
+		if record.Tool != "" {
+			tool += "/" + record.Tool
+		}
+		result := "ok"
+		if record.IsError {
+			result = "error"
+			if record.Error != "" {
+				result += ": " + trim(strings.ReplaceAll(record.Error, "\n", " "))
+			}
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", record.CreatedAt.Format(time.RFC3339),
+			record.SessionID, trim(record.AccountID), record.Agent, tool,
+			time.Duration(record.DurationMS)*time.Millisecond, result)
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/obot-platform/nanobot/pkg/config"
+	"github.com/obot-platform/nanobot/pkg/runtime"
+	"github.com/spf13/cobra"
+)
+
+type Config struct {
+	n *Nanobot
+}
+
+func NewConfig(n *Nanobot) *Config {
+	return &Config{
+		n: n,
+	}
+}
+
+func (c *Config) Customize(cmd *cobra.Command) {
+	cmd.Use = "config"
+	cmd.Short = "Check and inspect nanobot configs"
+	cmd.Args = cobra.NoArgs
+}
+
+func (c *Config) Run(cmd *cobra.Command, _ []string) error {
+	return cmd.Help()
+}
+
+type ConfigValidate struct {
+	Profile []string `usage:"Profile to apply while validating, repeat to check several profiles together"`
+	Output  string   `usage:"Output format (json, yaml, text)" short:"o" default:"text"`
+	n       *Nanobot
+}
+
+func NewConfigValidate(n *Nanobot) *ConfigValidate {
+	return &ConfigValidate{
+		n: n,
+	}
+}
+
+func (c *ConfigValidate) Customize(cmd *cobra.Command) {
+	cmd.Use = "validate [flags] [CONFIG...]"
+	cmd.Short = "Validate configs against the schema and check their references"
+	cmd.Example = `
+  # Validate the config of the current directory, or the ones passed with --config.
+  nanobot config validate
+
+  # Validate a config together with its production profile, as in CI.
+  nanobot config validate ./nanobot.yaml --profile production
+
+  # Report the problems as JSON.
+  nanobot config validate ./nanobot.yaml -o json
+`
+}
+
+func (c *ConfigValidate) Run(cmd *cobra.Command, args []string) error {
+	paths := args
+	if len(paths) == 0 {
+		paths = c.n.ConfigPaths()
+	}
+
+	env, err := c.n.loadEnv()
+	if err != nil {
+		return fmt.Errorf("failed to load environment: %w", err)
+	}
+	ctx := config.WithTemplateEnv(cmd.Context(), env)
+
+	problems := []config.Problem{}
+	for _, path := range paths {
+		pathProblems, err := config.Check(ctx, path, c.Profile...)
+		if err != nil {
+			return err
+		}
+		problems = append(problems, pathProblems...)
+		if len(pathProblems) == 0 && c.Output == "text" {
+			fmt.Fprintf(os.Stderr, "%s: valid\n", path)
+		}
+	}
+
+	if !display(problems, c.Output) {
+		for _, problem := range problems {
+			fmt.Fprintln(os.Stderr, problem)
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("config validation failed")
+	}
+	return nil
+}
+
+type ConfigShow struct {
+	Profile     []string `usage:"Profile to apply, repeat to apply several in order"`
+	Output      string   `usage:"Output format (json, yaml)" short:"o" default:"yaml"`
+	ShowSecrets bool     `usage:"Print env values and headers of MCP servers instead of redacting them"`
+	n           *Nanobot
+}
+
+func NewConfigShow(n *Nanobot) *ConfigShow {
+	return &ConfigShow{
+		n: n,
+	}
+}
+
+func (c *ConfigShow) Customize(cmd *cobra.Command) {
+	cmd.Use = "show [flags] [CONFIG...]"
+	cmd.Short = "Print the config with its extends and profiles resolved"
+	cmd.Example = `
+  # Print the config of the current directory as the production profile sees it.
+  nanobot config show --profile production
+
+  # Print the merged configs as JSON.
+  nanobot config show ./base.yaml ./local.yaml -o json
+`
+}
+
+func (c *ConfigShow) Run(cmd *cobra.Command, args []string) error {
+	paths := args
+	if len(paths) == 0 {
+		paths = c.n.ConfigPaths()
+	}
+
+	cfg, err := c.n.ReadConfig(cmd.Context(), paths, !c.n.ExcludeBuiltInAgents, runtime.Options{
+		Profiles: c.Profile,
+	})
+	if err != nil {
+		return err
+	}
+
+	resolved := *cfg
+	if !c.ShowSecrets {
+		resolved = resolved.Redacted()
+	}
+	if !display(resolved, c.Output) {
+		return fmt.Errorf("unsupported output format %q, must be json or yaml", c.Output)
+	}
+	return nil
+}
@@ -7,23 +7,29 @@ import (
 	"fmt"
 	"io/fs"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/obot-platform/nanobot/pkg/a2a"
 	"github.com/obot-platform/nanobot/pkg/api"
 	"github.com/obot-platform/nanobot/pkg/auth"
+	"github.com/obot-platform/nanobot/pkg/channels"
 	"github.com/obot-platform/nanobot/pkg/cmd"
 	"github.com/obot-platform/nanobot/pkg/complete"
 	"github.com/obot-platform/nanobot/pkg/config"
+	"github.com/obot-platform/nanobot/pkg/encryption"
+	"github.com/obot-platform/nanobot/pkg/limits"
 	"github.com/obot-platform/nanobot/pkg/llm"
 	"github.com/obot-platform/nanobot/pkg/log"
 	"github.com/obot-platform/nanobot/pkg/mcp"
 	"github.com/obot-platform/nanobot/pkg/mcp/auditlogs"
 	"github.com/obot-platform/nanobot/pkg/runtime"
 	"github.com/obot-platform/nanobot/pkg/server"
+	"github.com/obot-platform/nanobot/pkg/servers/tasks"
 	"github.com/obot-platform/nanobot/pkg/session"
 	"github.com/obot-platform/nanobot/pkg/telemetry"
 	"github.com/obot-platform/nanobot/pkg/types"
@@ -39,7 +45,13 @@ func New() *cobra.Command {
 	root := cmd.Command(n,
 		NewCall(n),
 		NewTargets(n),
-		NewSessions(n),
+		cmd.Command(NewSessions(n), NewSessionsUsage(n), NewSessionsFork(n), NewSessionsExport(n), NewSessionsImport(n), NewSessionsTranscript(n), NewSessionsPrune(n)),
+		cmd.Command(NewAccounts(n), NewAccountsShow(n), NewAccountsSet(n)),
+		NewAudit(n),
+		cmd.Command(NewConfig(n), NewConfigValidate(n), NewConfigShow(n)),
+		NewSchema(n),
+		cmd.Command(NewSkills(n), NewSkillsInstall(n), NewSkillsRemove(n)),
+		cmd.Command(NewServers(n), NewServersStatus(n)),
 		NewRun(n))
 	return root
 }
@@ -47,16 +59,21 @@ func New() *cobra.Command {
 type Nanobot struct {
 	Debug                bool     `usage:"Enable debug logging"`
 	Trace                bool     `usage:"Enable trace logging"`
+	LogFormat            string   `usage:"Format of the logs, text or json" default:"text" env:"NANOBOT_LOG_FORMAT" name:"log-format"`
+	LogLevel             string   `usage:"Lowest level logged: debug, info, warn, or error" default:"info" env:"NANOBOT_LOG_LEVEL" name:"log-level"`
 	Env                  []string `usage:"Environment variables to set in the form of KEY=VALUE, or KEY to load from current environ" short:"e"`
 	EnvFile              string   `usage:"Path to the environment file (default: ./nanobot.env)" default:"./nanobot.env"`
 	EmptyEnv             bool     `usage:"Do not load environment variables from the environment by default"`
 	DefaultModel         string   `usage:"Default model to use for completions" default:"gpt-4.1" env:"NANOBOT_DEFAULT_MODEL" name:"default-model"`
 	DefaultMiniModel     string   `usage:"Default model to use for things like thread summaries" default:"gpt-4.1" env:"NANOBOT_DEFAULT_MINI_MODEL" name:"default-mini-model"`
+	EmbeddingModel       string   `usage:"Model to use for embeddings, enables semantic search of workspace files" env:"NANOBOT_EMBEDDING_MODEL" name:"embedding-model"`
 	MaxConcurrency       int      `usage:"The maximum number of concurrent tasks in a parallel loop" default:"10" hidden:"true"`
 	Chdir                string   `usage:"Change directory to this path before running the nanobot" default:"." short:"C"`
 	State                string   `usage:"Path to the state file" default:"./nanobot.db"`
 	ConfigPath           []string `usage:"Configuration file, directory, URL, or repo ref. Repeat to merge multiple configs; later entries override earlier ones" name:"config" short:"c"`
 	ExcludeBuiltInAgents bool     `usage:"Exclude built-in agents from the configuration"`
+	EncryptSessions      bool     `usage:"Encrypt session messages, env values, and truncated tool outputs at rest with the encryption key or --session-kms" env:"NANOBOT_ENCRYPT_SESSIONS" name:"encrypt-sessions"`
+	SessionKMS           string   `usage:"URL of a Vault transit key to wrap session encryption keys with, such as https://vault:8200/v1/transit/keys/nanobot, authenticated by $VAULT_TOKEN" env:"NANOBOT_SESSION_KMS" name:"session-kms"`
 
 	otel *telemetry.Otel
 }
@@ -80,6 +97,46 @@ func ensureDirectoryForDSN(dsn string) error {
 	return os.MkdirAll(dir, 0o700)
 }
 
+// encryptionKeyEnv is the env of the --encryption-key flag of run. Commands
+// reading sessions outside of run use it to decrypt them.
+const encryptionKeyEnv = "NANOBOT_RUN_ENCRYPTION_KEY"
+
+// sessionEncryption returns the envelope that encrypts sessions, nil unless
+// --encrypt-sessions is set. Data keys are wrapped by the KMS key if there is
+// one and by the encryption key otherwise.
+func (n *Nanobot) sessionEncryption(key string) (*encryption.Envelope, error) {
+	if !n.EncryptSessions {
+		return nil, nil
+	}
+	if n.SessionKMS != "" {
+		kms, err := encryption.NewVaultTransit(n.SessionKMS, "")
+		if err != nil {
+			return nil, err
+		}
+		return encryption.New(kms), nil
+	}
+	if key == "" {
+		key = os.Getenv(encryptionKeyEnv)
+	}
+	local, err := encryption.NewLocalKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("--encrypt-sessions requires an encryption key (%s) or --session-kms: %w", encryptionKeyEnv, err)
+	}
+	return encryption.New(local), nil
+}
+
+// NewSessionStore opens the session store of the state file, encrypted with
+// key as configured by --encrypt-sessions.
+func (n *Nanobot) NewSessionStore(key string) (*session.Store, error) {
+	envelope, err := n.sessionEncryption(key)
+	if err != nil {
+		return nil, err
+	}
+	return session.NewStoreFromDSN(n.DSN(), session.StoreOptions{
+		Encryption: envelope,
+	})
+}
+
 func (n *Nanobot) DSN() string {
 	dsn := os.Expand(n.State, func(s string) string {
 		if s == "XDG_CONFIG_HOME" {
@@ -118,7 +175,14 @@ func (n *Nanobot) PersistentPre(cmd *cobra.Command, _ []string) error {
 		log.EnableProgress = true
 	}
 
-	log.ConfigureSlog(n.Debug, n.Trace)
+	if err := log.Configure(log.Options{
+		Debug:  n.Debug,
+		Trace:  n.Trace,
+		Format: n.LogFormat,
+		Level:  n.LogLevel,
+	}); err != nil {
+		return err
+	}
 	log.EnableMessages = log.EnableMessages || n.Debug || n.Trace
 
 	if n.otel == nil {
@@ -161,6 +225,7 @@ func (n *Nanobot) llmConfig() llm.Config {
 	return llm.Config{
 		DefaultModel:     n.DefaultModel,
 		DefaultMiniModel: n.DefaultMiniModel,
+		EmbeddingModel:   n.EmbeddingModel,
 		// Built-in default providers for backwards compatibility.
 		// These are overridden by any providers defined in the YAML config.
 		LLMProviders: map[string]llm.LLMProviderConfig{
@@ -174,6 +239,11 @@ func (n *Nanobot) llmConfig() llm.Config {
 				APIKey:  "${ANTHROPIC_API_KEY}",
 				BaseURL: "${ANTHROPIC_BASE_URL}",
 			},
+			"ollama": {
+				Dialect: types.DialectOpenAIChatCompletions,
+				BaseURL: "${OLLAMA_BASE_URL}",
+				Local:   true,
+			},
 		},
 	}
 }
@@ -225,8 +295,24 @@ func (n *Nanobot) loadEnv() (map[string]string, error) {
 }
 
 func (n *Nanobot) ReadConfig(ctx context.Context, cfgPaths []string, includeDefaultAgents bool, opts ...runtime.Options) (*types.Config, error) {
+	env, err := n.loadEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load environment: %w", err)
+	}
+	ctx = config.WithTemplateEnv(ctx, env)
+
 	cfg, _, err := config.LoadMany(ctx, cfgPaths, includeDefaultAgents, complete.Complete(opts...).Profiles...)
-	return cfg, err
+	if err != nil {
+		return nil, err
+	}
+
+	// Agents defined at runtime through the meta server are kept in an
+	// overlay next to the config.
+	withOverlay, err := config.ApplyOverlay(*cfg, runtimeConfigDir(cfgPaths))
+	if err != nil {
+		return nil, err
+	}
+	return &withOverlay, nil
 }
 
 func (n *Nanobot) ConfigPaths() []string {
@@ -271,8 +357,18 @@ type mcpOpts struct {
 	Auth               auth.Auth
 	ListenAddress      string
 	HealthzPath        string
+	ReadyzPath         string
 	ForceFetchToolList bool
 	StartUI            bool
+	Retention          *types.RetentionSettings
+	Limits             *types.LimitSettings
+	Broker             session.Broker
+	Distributed        bool
+	AuditAdmins        []string
+	Admins             []string
+	// ShutdownGracePeriod is how long requests in flight get to finish on
+	// shutdown.
+	ShutdownGracePeriod time.Duration
 }
 
 func (n *Nanobot) runMCP(ctx context.Context, baseConfig types.ConfigFactory, runt *runtime.Runtime, oauthCallbackHandler mcp.CallbackServer, auditLogCollector *auditlogs.Collector, store *session.Store, opts mcpOpts) error {
@@ -305,11 +401,26 @@ func (n *Nanobot) runMCP(ctx context.Context, baseConfig types.ConfigFactory, ru
 		return fmt.Errorf("https:// is not supported, use http:// instead")
 	}
 
-	sessionManager := session.NewManager(store)
+	sessionManager := session.NewManager(store, session.ManagerOptions{
+		Broker:      opts.Broker,
+		Distributed: opts.Distributed,
+		AuditAdmins: opts.AuditAdmins,
+		Admins:      opts.Admins,
+	})
+	if opts.Retention != nil {
+		go sessionManager.RunGC(ctx, *opts.Retention)
+	}
 
-	var mcpServer mcp.MessageHandler = server.NewServer(runt, config, sessionManager, server.Options{
+	serverOpts := server.Options{
 		ForceFetchToolList: opts.ForceFetchToolList,
-	})
+	}
+	var limiter *limits.Limiter
+	if opts.Limits != nil {
+		limiter = limits.NewLimiter(*opts.Limits, sessionManager)
+		serverOpts.Limiter = limiter
+	}
+
+	var mcpServer mcp.MessageHandler = server.NewServer(runt, config, sessionManager, serverOpts)
 
 	if address == "stdio" {
 		stdio := mcp.NewStdioServer(envProvider, mcpServer)
@@ -321,9 +432,27 @@ func (n *Nanobot) runMCP(ctx context.Context, baseConfig types.ConfigFactory, ru
 		return nil
 	}
 
+	readyChecks := []mcp.HealthCheck{{
+		Name: "llm",
+		// Reaching the provider takes a round trip, so it is checked at most
+		// once a minute.
+		Check: mcp.CachedHealthCheck(time.Minute, func(ctx context.Context) error {
+			env, err := envProvider()
+			if err != nil {
+				return err
+			}
+			return runt.PingLLM(ctx, env)
+		}),
+	}}
+	if store != nil {
+		readyChecks = append(readyChecks, mcp.HealthCheck{Name: "db", Check: store.Ping})
+	}
+
 	httpServer, err := mcp.NewHTTPServer(ctx, envProvider, mcpServer, mcp.HTTPServerOptions{
 		HealthCheckPath:   opts.HealthzPath,
-		RunHealthChecker:  opts.HealthzPath != "" && os.Getenv("NANOBOT_DISABLE_HEALTH_CHECKER") != "true",
+		ReadyCheckPath:    opts.ReadyzPath,
+		ReadyChecks:       readyChecks,
+		RunHealthChecker:  (opts.HealthzPath != "" || opts.ReadyzPath != "") && os.Getenv("NANOBOT_DISABLE_HEALTH_CHECKER") != "true",
 		SessionStore:      sessionManager,
 		AuditLogCollector: auditLogCollector,
 	})
@@ -331,27 +460,60 @@ func (n *Nanobot) runMCP(ctx context.Context, baseConfig types.ConfigFactory, ru
 		return fmt.Errorf("failed to create HTTP server: %w", err)
 	}
 
+	var mcpHandler http.Handler = httpServer
+	if limiter != nil {
+		mcpHandler = limiter.Wrap(httpServer)
+	}
+
 	mux := http.NewServeMux()
 	if oauthCallbackHandler != nil {
 		mux.Handle("/oauth/callback", oauthCallbackHandler)
 	}
+	if len(opts.Admins) > 0 {
+		mux.Handle("/api/admin/", api.AdminHandler(sessionManager))
+	}
+
+	// A2A clients talk to the published agents over the loopback URL, as
+	// the user of their request.
+	a2aServer := a2a.NewServer(ctx, "http://"+address+"/mcp/chat", func(ctx context.Context) (types.Config, error) {
+		return config(ctx, "")
+	})
+	mux.Handle(a2a.CardPath, a2aServer)
+	mux.Handle(a2a.CardsPathPrefix, a2aServer)
+	mux.Handle(a2a.PathPrefix, a2aServer)
+	mux.Handle(a2a.PathPrefix+"/", a2aServer)
 	if opts.StartUI {
-		mux.Handle("/", session.UISession(httpServer, sessionManager, api.Handler(sessionManager, address)))
+		mux.Handle("/", session.UISession(mcpHandler, sessionManager, api.Handler(sessionManager, address)))
 	} else {
-		mux.Handle("/", httpServer)
+		mux.Handle("/", mcpHandler)
 	}
 
-	handler, err := auth.Wrap(ctx, env, opts.Auth, n.DSN(), opts.HealthzPath, mux)
+	handler, err := auth.Wrap(ctx, env, opts.Auth, n.DSN(), []string{opts.HealthzPath, opts.ReadyzPath}, mux)
 	if err != nil {
 		return fmt.Errorf("failed to setup auth: %w", err)
 	}
 
+	// Trigger webhooks and channels are authenticated by their signatures
+	// instead.
+	triggers, channelHandler := runt.TriggerHandler(), runt.ChannelHandler()
+	if triggers != nil || channelHandler != nil {
+		signed := http.NewServeMux()
+		if triggers != nil {
+			signed.Handle(tasks.TriggerPathPrefix, triggers)
+		}
+		if channelHandler != nil {
+			signed.Handle(channels.PathPrefix, channelHandler)
+		}
+		signed.Handle("/", handler)
+		handler = signed
+	}
+
 	s := &http.Server{
 		Addr: address,
 		Handler: otelhttp.NewHandler(api.Cors(handler), "nanobot/http",
 			otelhttp.WithFilter(func(req *http.Request) bool {
 				switch req.URL.Path {
-				case "/mcp/chat", "/mcp/ui", opts.HealthzPath:
+				case "/mcp/chat", "/mcp/ui", opts.HealthzPath, opts.ReadyzPath:
 					return false
 				default:
 					return true
@@ -360,15 +522,30 @@ func (n *Nanobot) runMCP(ctx context.Context, baseConfig types.ConfigFactory, ru
 		),
 	}
 
+	// Requests aren't canceled by the signal, the shutdown coordinator lets
+	// them finish first.
+	requestsCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+	s.BaseContext = func(net.Listener) context.Context {
+		return requestsCtx
+	}
+
+	shutdownDone := make(chan struct{})
 	context.AfterFunc(ctx, func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-		_ = s.Shutdown(ctx)
+		defer close(shutdownDone)
+		shutdownCoordinator{
+			server:         s,
+			mcpServer:      httpServer,
+			sessions:       sessionManager,
+			cancelRequests: cancelRequests,
+			gracePeriod:    opts.ShutdownGracePeriod,
+		}.shutdown()
 	})
 
 	slog.Info("Starting server", "url", "http://"+address)
 	err = s.ListenAndServe()
 	if errors.Is(err, http.ErrServerClosed) {
+		<-shutdownDone
 		return nil
 	}
 	slog.Debug("Server stopped", "error", err)
@@ -0,0 +1,197 @@
+package cli
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/obot-platform/nanobot/pkg/cmd"
+	"github.com/obot-platform/nanobot/pkg/llm"
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/runtime"
+	"github.com/obot-platform/nanobot/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+// exitAgentError is the exit code of a run whose agent replied with an
+// error, as opposed to 1 for a run that failed to complete.
+const exitAgentError = 2
+
+// maxStdinSize is the size of the largest input piped to a run.
+const maxStdinSize = 20 << 20
+
+// RunResult is the result of a single agent run, printed by run --prompt.
+type RunResult struct {
+	Agent string `json:"agent"`
+	// Text is the text of the final reply of the agent.
+	Text string `json:"text"`
+	// StructuredOutput is the final reply of an agent with an output schema.
+	StructuredOutput map[string]any    `json:"structuredOutput,omitempty"`
+	IsError          bool              `json:"isError,omitempty"`
+	ToolCalls        []RunToolCall     `json:"toolCalls,omitempty"`
+	Usage            types.UsageReport `json:"usage"`
+}
+
+// RunToolCall is a tool call made during a run.
+type RunToolCall struct {
+	Name      string          `json:"name"`
+	Target    string          `json:"target,omitempty"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+	Output    string          `json:"output,omitempty"`
+	IsError   bool            `json:"isError,omitempty"`
+}
+
+// runOnce runs the agent once with the prompt, without serving, and prints the
+// result. The args are the agent, and "-" to attach stdin to the prompt.
+func (r *Run) runOnce(command *cobra.Command, args []string) error {
+	switch r.Output {
+	case "text", "json", "yaml":
+	default:
+		return fmt.Errorf("invalid output format %q, must be text, json, or yaml", r.Output)
+	}
+
+	request := types.SampleCallRequest{
+		Prompt: r.Prompt,
+	}
+	if len(args) > 1 {
+		if args[1] != "-" {
+			return fmt.Errorf("invalid argument %q, only - is supported to read stdin", args[1])
+		}
+		attachment, err := stdinAttachment(os.Stdin)
+		if err != nil {
+			return err
+		}
+		request.Attachments = append(request.Attachments, attachment)
+	}
+	if request.Prompt == "" && len(request.Attachments) == 0 {
+		return fmt.Errorf("a prompt is required, set it with --prompt or pipe it to stdin with -")
+	}
+
+	cfg, err := r.n.ReadConfig(command.Context(), r.n.ConfigPaths(), !r.n.ExcludeBuiltInAgents)
+	if err != nil {
+		return err
+	}
+
+	agent := r.EntrypointAgent
+	if len(args) > 0 {
+		agent = args[0]
+	}
+	if agent == "" && len(cfg.Publish.Entrypoint) > 0 {
+		agent = cfg.Publish.Entrypoint[0]
+	}
+	if _, ok := cfg.Agents[agent]; !ok {
+		if agent == "" {
+			return fmt.Errorf("no agent to run, set one with --agent")
+		}
+		return fmt.Errorf("agent %q not found in configuration", agent)
+	}
+
+	runtime, err := r.n.GetRuntime(command.Context(), runtime.Options{
+		MaxConcurrency: r.n.MaxConcurrency,
+		DSN:            r.n.DSN(),
+		DefaultModel:   r.n.DefaultModel,
+		ConfigDir:      r.n.RuntimeConfigDir(),
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx := runtime.WithTempSession(command.Context(), cfg)
+	callResult, err := runtime.ChatFromCLI(ctx, agent, request)
+	if err != nil {
+		return err
+	}
+
+	result := newRunResult(ctx, agent, callResult)
+	if !display(result, r.Output) {
+		fmt.Println(result.Text)
+	}
+	if result.IsError {
+		return &cmd.ExitError{Code: exitAgentError}
+	}
+	return nil
+}
+
+// stdinAttachment reads stdin into an attachment of the prompt, as a data URI
+// of the type of its content.
+func stdinAttachment(stdin io.Reader) (types.Attachment, error) {
+	data, err := io.ReadAll(io.LimitReader(stdin, maxStdinSize+1))
+	if err != nil {
+		return types.Attachment{}, fmt.Errorf("failed to read stdin: %w", err)
+	}
+	if len(data) > maxStdinSize {
+		return types.Attachment{}, fmt.Errorf("stdin is larger than %d bytes", maxStdinSize)
+	}
+
+	mimeType, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	return types.Attachment{
+		Name:     "stdin",
+		URL:      "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data),
+		MimeType: mimeType,
+	}, nil
+}
+
+// newRunResult collects the result of a run from its reply and the session
+// it ran in.
+func newRunResult(ctx context.Context, agent string, callResult *mcp.CallToolResult) RunResult {
+	var (
+		session = mcp.SessionFromContext(ctx).Root()
+		run     types.Execution
+		usage   types.SessionUsage
+		texts   []string
+	)
+	session.Get(types.PreviousExecutionKey, &run)
+	session.Get(types.UsageSessionKey, &usage)
+
+	for _, content := range callResult.Content {
+		if content.Text != "" {
+			texts = append(texts, content.Text)
+		}
+	}
+
+	result := RunResult{
+		Agent:            agent,
+		Text:             strings.Join(texts, "\n"),
+		StructuredOutput: callResult.StructuredContent,
+		IsError:          callResult.IsError,
+		Usage:            llm.UsageReport(usage),
+	}
+
+	outputs := map[string]types.CallResult{}
+	for _, msg := range run.Messages() {
+		for _, item := range msg.Items {
+			if item.ToolCallResult != nil {
+				outputs[item.ToolCallResult.CallID] = item.ToolCallResult.Output
+			}
+		}
+	}
+	for _, msg := range run.Messages() {
+		for _, item := range msg.Items {
+			if item.ToolCall == nil {
+				continue
+			}
+			call := RunToolCall{
+				Name:   item.ToolCall.Name,
+				Target: item.ToolCall.Target,
+			}
+			if json.Valid([]byte(item.ToolCall.Arguments)) {
+				call.Arguments = json.RawMessage(item.ToolCall.Arguments)
+			}
+			if output, ok := outputs[item.ToolCall.CallID]; ok {
+				call.IsError = output.IsError
+				for _, content := range output.Content {
+					call.Output += content.Text
+				}
+			}
+			result.ToolCalls = append(result.ToolCalls, call)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+func TestNewRunResult(t *testing.T) {
+	session := mcp.NewEmptySession(t.Context())
+	session.Set(types.PreviousExecutionKey, &types.Execution{
+		PopulatedRequest: &types.CompletionRequest{
+			Input: []types.Message{{Role: "user", Items: []types.CompletionItem{{
+				Content: &mcp.Content{Type: "text", Text: "count the files"},
+			}}}},
+		},
+		Response: &types.CompletionResponse{
+			Output: types.Message{Role: "assistant", Items: []types.CompletionItem{
+				{ToolCall: &types.ToolCall{CallID: "1", Name: "bash", Target: "nanobot.system", Arguments: `{"command":"ls | wc -l"}`}},
+				{ToolCallResult: &types.ToolCallResult{CallID: "1", Output: types.CallResult{
+					Content: []mcp.Content{{Type: "text", Text: "3\n"}},
+				}}},
+				{Content: &mcp.Content{Type: "text", Text: "There are 3 files."}},
+			}},
+		},
+	})
+	usage := types.SessionUsage{}
+	usage.Add("gpt-4.1", types.Usage{InputTokens: 10, OutputTokens: 5, Completions: 2})
+	session.Set(types.UsageSessionKey, &usage)
+
+	result := newRunResult(mcp.WithSession(t.Context(), session), "counter", &mcp.CallToolResult{
+		Content:           []mcp.Content{{Type: "text", Text: `{"files":3}`}},
+		StructuredContent: map[string]any{"files": float64(3)},
+	})
+
+	if result.Agent != "counter" || result.Text != `{"files":3}` || result.StructuredOutput["files"] != float64(3) {
+		t.Errorf("unexpected reply: %+v", result)
+	}
+	if len(result.ToolCalls) != 1 {
+		t.Fatalf("expected 1 tool call, got %+v", result.ToolCalls)
+	}
+	call := result.ToolCalls[0]
+	if call.Name != "bash" || call.Target != "nanobot.system" || string(call.Arguments) != `{"command":"ls | wc -l"}` || call.Output != "3\n" {
+		t.Errorf("unexpected tool call: %+v", call)
+	}
+	if result.Usage.Total.InputTokens != 10 || result.Usage.Total.Completions != 2 {
+		t.Errorf("unexpected usage: %+v", result.Usage)
+	}
+}
+
+func TestStdinAttachment(t *testing.T) {
+	attachment, err := stdinAttachment(strings.NewReader("name,count\nfiles,3\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attachment.Name != "stdin" || attachment.MimeType != "text/plain" {
+		t.Errorf("unexpected attachment: %+v", attachment)
+	}
+	want := "data:text/plain;base64," + base64.StdEncoding.EncodeToString([]byte("name,count\nfiles,3\n"))
+	if attachment.URL != want {
+		t.Errorf("expected URL %q, got %q", want, attachment.URL)
+	}
+
+	if _, err := stdinAttachment(strings.NewReader(strings.Repeat("x", maxStdinSize+1))); err == nil {
+		t.Error("expected input larger than the limit to fail")
+	}
+}
@@ -0,0 +1,191 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/obot-platform/nanobot/pkg/runtime"
+	"github.com/obot-platform/nanobot/pkg/schema"
+	"github.com/obot-platform/nanobot/pkg/types"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+type Schema struct {
+	Name        string   `usage:"Name of the output schema"`
+	Description string   `usage:"Description of the output schema"`
+	Validate    string   `usage:"Agent to call to validate its live output against the schema"`
+	Input       []string `usage:"Input to send to the agent when validating, repeat to make several calls" short:"i"`
+	n           *Nanobot
+}
+
+func NewSchema(n *Nanobot) *Schema {
+	return &Schema{
+		n: n,
+	}
+}
+
+func (s *Schema) Customize(cmd *cobra.Command) {
+	cmd.Hidden = true
+	cmd.Use = "schema [flags] [EXAMPLE_FILE...]"
+	cmd.Short = "Generate an agent output schema from example JSON outputs"
+	cmd.Example = `
+  # Print an output block, for pasting into an agent's config, that matches the example outputs.
+  nanobot schema example1.json example2.json
+
+  # Read a JSON array of examples from stdin.
+  cat examples.json | nanobot schema -
+
+  # Generate the schema, then call agent1 and check that its output matches.
+  nanobot schema -c . --validate agent1 -i "Summarize the weather in Paris" example1.json
+
+  # Check agent1's live output against the output schema already in its config.
+  nanobot schema -c . --validate agent1 -i "Summarize the weather in Paris"
+`
+}
+
+func (s *Schema) Run(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 && s.Validate == "" {
+		return fmt.Errorf("at least one example file is required")
+	}
+
+	var output *types.OutputSchema
+	if len(args) > 0 {
+		examples, err := readExamples(args)
+		if err != nil {
+			return err
+		}
+
+		fields, err := schema.FieldsFromExamples(examples)
+		if err != nil {
+			return err
+		}
+
+		output = &types.OutputSchema{
+			Name:        s.Name,
+			Description: s.Description,
+			Fields:      fields,
+		}
+
+		data, err := yaml.Marshal(map[string]any{"output": output})
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(data))
+
+		for i, example := range examples {
+			if err := schema.ValidateOutput(output.ToSchema(), example); err != nil {
+				return fmt.Errorf("example %d does not match the generated schema: %w", i+1, err)
+			}
+		}
+	}
+
+	if s.Validate == "" {
+		return nil
+	}
+	return s.validateAgent(cmd, output)
+}
+
+// validateAgent calls the agent with each input and checks its structured
+// output against output, or against the agent's configured output schema when
+// output is nil.
+func (s *Schema) validateAgent(cmd *cobra.Command, output *types.OutputSchema) error {
+	cfg, err := s.n.ReadConfig(cmd.Context(), s.n.ConfigPaths(), !s.n.ExcludeBuiltInAgents)
+	if err != nil {
+		return err
+	}
+
+	agent, ok := cfg.Agents[s.Validate]
+	if !ok {
+		return fmt.Errorf("agent %q not found", s.Validate)
+	}
+	if output == nil {
+		if agent.Output == nil {
+			return fmt.Errorf("agent %q has no output schema, pass example files to generate one", s.Validate)
+		}
+		output = agent.Output
+	} else {
+		// Have the agent produce the candidate schema rather than the
+		// configured one.
+		agent.Output = output
+		cfg.Agents[s.Validate] = agent
+	}
+
+	if len(s.Input) == 0 {
+		return fmt.Errorf("--input is required with --validate")
+	}
+
+	runtime, err := s.n.GetRuntime(cmd.Context(), runtime.Options{
+		MaxConcurrency: s.n.MaxConcurrency,
+		DSN:            s.n.DSN(),
+		DefaultModel:   s.n.DefaultModel,
+		ConfigDir:      s.n.RuntimeConfigDir(),
+	})
+	if err != nil {
+		return err
+	}
+
+	var failed int
+	for _, input := range s.Input {
+		ctx := runtime.WithTempSession(cmd.Context(), cfg)
+		result, err := runtime.CallFromCLI(ctx, s.Validate, input)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case result.IsError:
+			err = fmt.Errorf("agent returned an error")
+		case result.StructuredContent == nil:
+			err = fmt.Errorf("agent did not return structured output")
+		default:
+			err = schema.ValidateOutput(output.ToSchema(), result.StructuredContent)
+		}
+
+		if err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "FAIL %q: %v\n", input, err)
+			continue
+		}
+		data, _ := json.Marshal(result.StructuredContent)
+		fmt.Fprintf(os.Stderr, "PASS %q: %s\n", input, data)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d agent outputs did not match the schema", failed, len(s.Input))
+	}
+	return nil
+}
+
+// readExamples reads example outputs from files, or stdin for "-". A file
+// holding a JSON array contributes each element as an example.
+func readExamples(files []string) ([]any, error) {
+	var examples []any
+	for _, file := range files {
+		var (
+			data []byte
+			err  error
+		)
+		if file == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(file)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read example %s: %w", file, err)
+		}
+
+		var example any
+		if err := yaml.Unmarshal(data, &example); err != nil {
+			return nil, fmt.Errorf("failed to parse example %s: %w", file, err)
+		}
+		if list, ok := example.([]any); ok {
+			examples = append(examples, list...)
+		} else {
+			examples = append(examples, example)
+		}
+	}
+	return examples, nil
+}
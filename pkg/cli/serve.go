@@ -26,13 +26,23 @@ type Run struct {
 	DisableUI                    bool              `usage:"Disable the UI"`
 	ForceFetchToolList           bool              `usage:"Always fetch tools when listing instead of using session cache"`
 	HealthzPath                  string            `usage:"Path to serve healthz on"`
+	ReadyzPath                   string            `usage:"Path to serve readyz on, checking the state database, LLM provider, and MCP servers"`
 	AuditLogSendURL              string            `usage:"URL to send audit logs to"`
 	AuditLogToken                string            `usage:"Token to send audit logs with"`
 	AuditLogMetadata             map[string]string `usage:"Metadata to send with audit logs"`
 	AuditLogBatchSize            int               `usage:"Batch size for sending audit logs" default:"1000"`
 	AuditLogFlushIntervalSeconds int               `usage:"Interval for flushing audit logs" default:"5"`
+	AuditToolCalls               bool              `usage:"Record every tool call to the audit log in the state database, read it with nanobot audit"`
+	AuditToolArguments           bool              `usage:"Record the redacted arguments of tool calls in the audit log, not only their hash"`
+	AuditAdmins                  []string          `usage:"IDs of the accounts that can read the tool call audit log of all accounts"`
+	Admins                       []string          `usage:"IDs of the accounts that can manage accounts through /api/admin/accounts and read the audit log of all accounts"`
 	Roots                        []string          `usage:"Roots to expose the MCP server in the form of name:directory" short:"r"`
 	EntrypointAgent              string            `usage:"ID of the agent to use for chat" name:"agent"`
+	Prompt                       string            `usage:"Run the agent once with this prompt and print its reply instead of serving" short:"p"`
+	Output                       string            `usage:"Output format of a --prompt run (text, json, yaml)" default:"text" short:"o"`
+	RedisURL                     string            `usage:"URL of a Redis server that relays session notifications between replicas, such as redis://redis:6379/0"`
+	Distributed                  bool              `usage:"Let any replica serve any session without sticky sessions, by keeping event streams in the shared state database and reloading sessions other replicas changed"`
+	ShutdownGracePeriodSeconds   int               `usage:"Seconds in-flight tool calls and agent turns get to finish on shutdown before they are checkpointed and canceled" default:"30"`
 	n                            *Nanobot
 }
 
@@ -45,8 +55,8 @@ func NewRun(n *Nanobot) *Run {
 }
 
 func (r *Run) Customize(cmd *cobra.Command) {
-	cmd.Args = cobra.NoArgs
-	cmd.Use = "run [flags]"
+	cmd.Args = cobra.MaximumNArgs(2)
+	cmd.Use = "run [flags] [AGENT [-]]"
 	cmd.Short = "Run the nanobot"
 	cmd.Long = `Run the nanobot using the specified configuration.
 
@@ -68,6 +78,11 @@ The configuration location can also be a URL, in which case the contents will be
 
 Lastly, the configuration location can be a GitHub repository in the form of "owner/repo". For the time being, this
 only supports YAML configuration files (i.e., nanobot.yaml) and not a directory of markdown files.
+
+With --prompt or an AGENT argument, the agent (AGENT, --agent, or the first entrypoint) runs once with the prompt
+instead of serving, and its reply is printed. A "-" argument after the agent attaches stdin to the prompt, so agents
+can be composed in pipelines. With --output json or yaml, the reply is printed with its structured output, the tool calls of the
+run, and the token usage. The exit code is 0 on success, 1 if the run fails, and 2 if the agent replies with an error.
 `
 
 	cmd.Example = `
@@ -82,6 +97,12 @@ only supports YAML configuration files (i.e., nanobot.yaml) and not a directory
 
   # Merge multiple configs, with later values overriding earlier ones
   nanobot run -c .nanobot/ -c ./team.yaml -c ./local.yaml
+
+  # Run an agent once without serving, printing its reply, tool calls, and usage as JSON
+  nanobot run --agent reviewer --prompt "Review the changes in diff.patch" --output json
+
+  # Pipe a file to an agent and write its reply to another file
+  cat data.csv | nanobot run analyst - --prompt "Summarize this data" > summary.md
 `
 }
 
@@ -118,6 +139,10 @@ func (r *Run) getRoots() ([]mcp.Root, error) {
 }
 
 func (r *Run) Run(cmd *cobra.Command, args []string) (err error) {
+	if r.Prompt != "" || len(args) > 0 {
+		return r.runOnce(cmd, args)
+	}
+
 	if (r.TrustedIssuer != "") != (len(r.TrustedAudiences) != 0) {
 		return fmt.Errorf("trusted issuer and audience must be set together")
 	}
@@ -181,25 +206,68 @@ func (r *Run) Run(cmd *cobra.Command, args []string) (err error) {
 		defer auditLogCollector.Close()
 	}
 
-	store, err := session.NewStoreFromDSN(r.n.DSN())
+	encryptionKey := r.Auth.EncryptionKey
+	if encryptionKey == "" && once.Auth != nil {
+		encryptionKey = once.Auth.EncryptionKey
+	}
+	store, err := r.n.NewSessionStore(encryptionKey)
 	if err != nil {
 		return fmt.Errorf("failed to create session store: %w", err)
 	}
 
+	var toolCallRecorder auditlogs.ToolCallRecorder
+	if r.AuditToolCalls {
+		toolCallRecorder = store
+	}
+
+	env, err := r.n.loadEnv()
+	if err != nil {
+		return fmt.Errorf("failed to load environment: %w", err)
+	}
+
 	runtime, err := r.n.GetRuntime(cmd.Context(), runtimeOpt, runtime.Options{
-		OAuthRedirectURL:  "http://" + strings.Replace(r.ListenAddress, "127.0.0.1", "localhost", 1) + "/oauth/callback",
-		Store:             store,
-		AuditLogCollector: auditLogCollector,
+		OAuthRedirectURL:   "http://" + strings.Replace(r.ListenAddress, "127.0.0.1", "localhost", 1) + "/oauth/callback",
+		Store:              store,
+		AuditLogCollector:  auditLogCollector,
+		ToolCallRecorder:   toolCallRecorder,
+		AuditToolArguments: r.AuditToolArguments,
+		Schedules:          once.Schedules,
+		Triggers:           once.Triggers,
+		Channels:           once.Channels,
+		Env:                env,
 	})
 	if err != nil {
 		return err
 	}
 
+	// Eager MCP servers start in the background so the server can listen
+	// while they do.
+	go runtime.WarmUp(cmd.Context(), once, env)
+	go reportServerProcesses(cmd.Context(), store, runtime)
+
+	var broker session.Broker
+	if r.RedisURL != "" {
+		redisBroker, err := session.NewRedisBroker(cmd.Context(), r.RedisURL)
+		if err != nil {
+			return err
+		}
+		defer redisBroker.Close()
+		broker = redisBroker
+	}
+
 	return r.n.runMCP(cmd.Context(), cfgFactory, runtime, callbackHandler, auditLogCollector, store, mcpOpts{
-		Auth:               auth.Auth(r.Auth),
-		ListenAddress:      r.ListenAddress,
-		HealthzPath:        r.HealthzPath,
-		ForceFetchToolList: r.ForceFetchToolList,
-		StartUI:            !r.DisableUI,
+		Auth:                auth.Auth(r.Auth),
+		ListenAddress:       r.ListenAddress,
+		HealthzPath:         r.HealthzPath,
+		ReadyzPath:          r.ReadyzPath,
+		ForceFetchToolList:  r.ForceFetchToolList,
+		StartUI:             !r.DisableUI,
+		Retention:           once.Retention,
+		Limits:              once.Limits,
+		Broker:              broker,
+		Distributed:         r.Distributed,
+		AuditAdmins:         r.AuditAdmins,
+		Admins:              r.Admins,
+		ShutdownGracePeriod: time.Duration(r.ShutdownGracePeriodSeconds) * time.Second,
 	})
 }
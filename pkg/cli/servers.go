@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/runtime"
+	"github.com/obot-platform/nanobot/pkg/session"
+	"github.com/spf13/cobra"
+)
+
+// serve reports the processes of its stdio MCP servers to the session store
+// every processReportInterval. Reports older than processReportStale are
+// from instances that stopped.
+const (
+	processReportInterval = 10 * time.Second
+	processReportStale    = 3 * processReportInterval
+)
+
+// reportServerProcesses stores the processes of the stdio MCP servers of the
+// runtime until the context is done, then removes them.
+func reportServerProcesses(ctx context.Context, store *session.Store, runtime *runtime.Runtime) {
+	hostname, _ := os.Hostname()
+	instance := hostname + ":" + strconv.Itoa(os.Getpid())
+
+	ticker := time.NewTicker(processReportInterval)
+	defer ticker.Stop()
+	for {
+		if err := store.ReplaceServerProcesses(ctx, instance, runtime.ServerProcesses()); err != nil && ctx.Err() == nil {
+			slog.Warn("failed to report MCP server processes", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+			defer cancel()
+			_ = store.ReplaceServerProcesses(ctx, instance, nil)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type Servers struct {
+	n *Nanobot
+}
+
+func NewServers(n *Nanobot) *Servers {
+	return &Servers{
+		n: n,
+	}
+}
+
+func (s *Servers) Customize(cmd *cobra.Command) {
+	cmd.Use = "servers"
+	cmd.Short = "Inspect the MCP servers run by nanobot"
+	cmd.Aliases = []string{"server"}
+	cmd.Args = cobra.NoArgs
+}
+
+func (s *Servers) Run(cmd *cobra.Command, _ []string) error {
+	return cmd.Help()
+}
+
+type ServersStatus struct {
+	Output string `usage:"Output format (json, yaml, table)" short:"o" default:"table"`
+	n      *Nanobot
+}
+
+func NewServersStatus(n *Nanobot) *ServersStatus {
+	return &ServersStatus{
+		n: n,
+	}
+}
+
+func (s *ServersStatus) Customize(cmd *cobra.Command) {
+	cmd.Use = "status [flags]"
+	cmd.Short = "Show the processes of the stdio MCP servers of the running nanobot servers"
+	cmd.Args = cobra.NoArgs
+	cmd.Example = `
+  # Show the processes started by the nanobot servers using the state file.
+  nanobot servers status
+
+  # Show them as JSON.
+  nanobot servers status -o json
+`
+}
+
+func (s *ServersStatus) Run(cmd *cobra.Command, _ []string) error {
+	store, err := s.n.NewSessionStore("")
+	if err != nil {
+		return err
+	}
+
+	processes, err := store.ListServerProcesses(cmd.Context(), time.Now().Add(-processReportStale))
+	if err != nil {
+		return err
+	}
+
+	if display(processes, s.Output) {
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, err = tw.Write([]byte("INSTANCE\tSERVER\tSESSION\tPID\tSTATE\tSTARTED\tLAST USED\tRESTARTS\tLAST ERROR\n"))
+	if err != nil {
+		return err
+	}
+
+	for _, process := range processes {
+		var pid string
+		if process.PID != 0 {
+			pid = strconv.Itoa(process.PID)
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\n", process.Instance, process.Server, process.SessionID, pid,
+			process.State, formatTime(process.StartedAt), formatTime(process.LastUsedAt), process.Restarts, trim(process.LastError))
+	}
+
+	return tw.Flush()
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
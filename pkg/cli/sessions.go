@@ -1,11 +1,18 @@
 package cli
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"os"
 	"text/tabwriter"
 	"time"
 
+	"github.com/obot-platform/nanobot/pkg/llm"
+	"github.com/obot-platform/nanobot/pkg/mcp"
 	"github.com/obot-platform/nanobot/pkg/session"
+	"github.com/obot-platform/nanobot/pkg/transcript"
+	"github.com/obot-platform/nanobot/pkg/types"
 	"github.com/spf13/cobra"
 )
 
@@ -29,7 +36,7 @@ func (t *Sessions) Customize(cmd *cobra.Command) {
 }
 
 func (t *Sessions) Run(cmd *cobra.Command, args []string) error {
-	store, err := session.NewStoreFromDSN(t.Nanobot.DSN())
+	store, err := t.Nanobot.NewSessionStore("")
 	if err != nil {
 		return err
 	}
@@ -53,3 +60,411 @@ func (t *Sessions) Run(cmd *cobra.Command, args []string) error {
 
 	return tw.Flush()
 }
+
+type SessionsUsage struct {
+	Nanobot *Nanobot
+	Output  string `usage:"Output format (json, yaml, table)" short:"o" default:"table"`
+}
+
+func NewSessionsUsage(n *Nanobot) *SessionsUsage {
+	return &SessionsUsage{
+		Nanobot: n,
+	}
+}
+
+func (s *SessionsUsage) Customize(cmd *cobra.Command) {
+	cmd.Use = "usage [flags] SESSION_ID"
+	cmd.Short = "Show the tokens used by a session and their estimated cost"
+	cmd.Args = cobra.ExactArgs(1)
+	cmd.Example = `
+  # Show the usage of a session by a prefix of its ID.
+  nanobot session usage 3f2a
+
+  # Show the usage of the most recently updated session.
+  nanobot session usage last
+`
+}
+
+func (s *SessionsUsage) Run(cmd *cobra.Command, args []string) error {
+	store, err := s.Nanobot.NewSessionStore("")
+	if err != nil {
+		return err
+	}
+
+	sessions, err := store.FindByPrefix(cmd.Context(), args[0])
+	if err != nil {
+		return err
+	}
+	if len(sessions) == 0 {
+		return fmt.Errorf("session %q not found", args[0])
+	} else if len(sessions) > 1 {
+		return fmt.Errorf("session ID prefix %q matches %d sessions", args[0], len(sessions))
+	}
+
+	var usage types.SessionUsage
+	if data, ok := sessions[0].State.Attributes[types.UsageSessionKey]; ok {
+		if err := mcp.JSONCoerce(data, &usage); err != nil {
+			return fmt.Errorf("failed to read usage of session %s: %w", sessions[0].SessionID, err)
+		}
+	}
+
+	report := llm.UsageReport(usage)
+	if display(report, s.Output) {
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, err = tw.Write([]byte("MODEL\tCOMPLETIONS\tINPUT\tOUTPUT\tCACHE READ\tCACHE WRITE\tCOST\n"))
+	if err != nil {
+		return err
+	}
+
+	for _, model := range report.Models {
+		writeUsageRow(tw, model.Model, model.Usage, model.Cost)
+	}
+	if len(report.Models) > 1 {
+		writeUsageRow(tw, "TOTAL", report.Total, &report.Cost)
+	}
+
+	return tw.Flush()
+}
+
+func writeUsageRow(tw *tabwriter.Writer, model string, usage types.Usage, cost *float64) {
+	costText := "-"
+	if cost != nil {
+		costText = fmt.Sprintf("$%.4f", *cost)
+	}
+	_, _ = fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%s\n", model, usage.Completions, usage.InputTokens,
+		usage.OutputTokens, usage.CacheReadTokens, usage.CacheWriteTokens, costText)
+}
+
+type SessionsFork struct {
+	Nanobot   *Nanobot
+	Title     string `usage:"Description of the forked session, defaults to the description of the session"`
+	CopyFiles bool   `usage:"Copy the files of the session to the fork"`
+}
+
+func NewSessionsFork(n *Nanobot) *SessionsFork {
+	return &SessionsFork{
+		Nanobot: n,
+	}
+}
+
+func (s *SessionsFork) Customize(cmd *cobra.Command) {
+	cmd.Use = "fork [flags] SESSION_ID"
+	cmd.Short = "Fork a session into a new session with a copy of its conversation"
+	cmd.Args = cobra.ExactArgs(1)
+	cmd.Example = `
+  # Fork a session by a prefix of its ID and print the ID of the fork.
+  nanobot session fork 3f2a
+
+  # Fork the most recently updated session along with its files.
+  nanobot session fork --copy-files last
+`
+}
+
+func (s *SessionsFork) Run(cmd *cobra.Command, args []string) error {
+	store, err := s.Nanobot.NewSessionStore("")
+	if err != nil {
+		return err
+	}
+
+	id, err := findSessionID(cmd.Context(), store, args[0])
+	if err != nil {
+		return err
+	}
+
+	fork, err := session.NewManager(store).Fork(cmd.Context(), id, session.ForkOptions{
+		Description: s.Title,
+		CopyFiles:   s.CopyFiles,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(fork.SessionID)
+	return nil
+}
+
+// findSessionID returns the ID of the one session matching a prefix of its ID.
+func findSessionID(ctx context.Context, store *session.Store, prefix string) (string, error) {
+	sessions, err := store.FindByPrefix(ctx, prefix)
+	if err != nil {
+		return "", err
+	}
+	if len(sessions) == 0 {
+		return "", fmt.Errorf("session %q not found", prefix)
+	} else if len(sessions) > 1 {
+		return "", fmt.Errorf("session ID prefix %q matches %d sessions", prefix, len(sessions))
+	}
+	return sessions[0].SessionID, nil
+}
+
+type SessionsExport struct {
+	Nanobot *Nanobot
+	File    string `usage:"File to write the bundle to, defaults to stdout" short:"f"`
+	Format  string `usage:"Bundle format, tar for the session and its files, json for the session and a manifest of its files" default:"tar"`
+}
+
+func NewSessionsExport(n *Nanobot) *SessionsExport {
+	return &SessionsExport{
+		Nanobot: n,
+	}
+}
+
+func (s *SessionsExport) Customize(cmd *cobra.Command) {
+	cmd.Use = "export [flags] SESSION_ID"
+	cmd.Short = "Export a session with its messages and files to a portable bundle"
+	cmd.Args = cobra.ExactArgs(1)
+	cmd.Example = `
+  # Export the most recently updated session with its files.
+  nanobot session export -f session.tar.gz last
+
+  # Export a session without file contents, to attach to a bug report.
+  nanobot session export --format json 3f2a > session.json
+`
+}
+
+func (s *SessionsExport) Run(cmd *cobra.Command, args []string) (err error) {
+	store, err := s.Nanobot.NewSessionStore("")
+	if err != nil {
+		return err
+	}
+
+	id, err := findSessionID(cmd.Context(), store, args[0])
+	if err != nil {
+		return err
+	}
+
+	out := io.Writer(os.Stdout)
+	if s.File != "" {
+		f, err := os.Create(s.File)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+		out = f
+	}
+
+	return session.NewManager(store).Export(cmd.Context(), id, out, session.ExportOptions{
+		Format: s.Format,
+	})
+}
+
+type SessionsImport struct {
+	Nanobot *Nanobot
+	NewID   bool   `usage:"Give the imported session a new ID instead of the exported one"`
+	Account string `usage:"Account the imported session belongs to, defaults to the account of the exported session"`
+}
+
+func NewSessionsImport(n *Nanobot) *SessionsImport {
+	return &SessionsImport{
+		Nanobot: n,
+	}
+}
+
+func (s *SessionsImport) Customize(cmd *cobra.Command) {
+	cmd.Use = "import [flags] FILE"
+	cmd.Short = "Import a session from a bundle made by session export"
+	cmd.Args = cobra.ExactArgs(1)
+	cmd.Example = `
+  # Import a session into the current directory and print its ID.
+  nanobot session import session.tar.gz
+
+  # Import a session from stdin under a new ID.
+  ssh server nanobot session export last | nanobot session import --new-id -
+`
+}
+
+func (s *SessionsImport) Run(cmd *cobra.Command, args []string) error {
+	store, err := s.Nanobot.NewSessionStore("")
+	if err != nil {
+		return err
+	}
+
+	in := io.Reader(os.Stdin)
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	imported, err := session.NewManager(store).Import(cmd.Context(), in, session.ImportOptions{
+		AccountID: s.Account,
+		NewID:     s.NewID,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(imported.SessionID)
+	return nil
+}
+
+type SessionsTranscript struct {
+	Nanobot   *Nanobot
+	File      string `usage:"File to write the transcript to, defaults to stdout" short:"f"`
+	Format    string `usage:"Transcript format (markdown, html)" default:"markdown"`
+	Reasoning bool   `usage:"Include the model's reasoning"`
+}
+
+func NewSessionsTranscript(n *Nanobot) *SessionsTranscript {
+	return &SessionsTranscript{
+		Nanobot: n,
+	}
+}
+
+func (s *SessionsTranscript) Customize(cmd *cobra.Command) {
+	cmd.Use = "transcript [flags] SESSION_ID"
+	cmd.Short = "Render the chat of a session as a Markdown or HTML transcript"
+	cmd.Args = cobra.ExactArgs(1)
+	cmd.Example = `
+  # Print the transcript of the most recently updated session as Markdown.
+  nanobot session transcript last
+
+  # Write the transcript of a session with the model's reasoning to an HTML file.
+  nanobot session transcript --format html --reasoning -f chat.html 3f2a
+`
+}
+
+func (s *SessionsTranscript) Run(cmd *cobra.Command, args []string) (err error) {
+	store, err := s.Nanobot.NewSessionStore("")
+	if err != nil {
+		return err
+	}
+
+	id, err := findSessionID(cmd.Context(), store, args[0])
+	if err != nil {
+		return err
+	}
+
+	stored, err := store.Get(cmd.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	messages, err := session.NewManager(store).Messages(cmd.Context(), id, "")
+	if err != nil {
+		return err
+	}
+
+	out := io.Writer(os.Stdout)
+	if s.File != "" {
+		f, err := os.Create(s.File)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+		out = f
+	}
+
+	return transcript.Render(out, s.Format, messages, transcript.Options{
+		Title:     stored.Description,
+		Reasoning: s.Reasoning,
+	})
+}
+
+type SessionsPrune struct {
+	Nanobot *Nanobot
+	MaxAge  string `usage:"Remove sessions that weren't updated for this long, like 720h, defaults to retention.maxAgeHours of the config"`
+	MaxDisk int    `usage:"Remove the least recently updated sessions while the files of all sessions take more than this many megabytes, defaults to retention.maxDiskMB of the config"`
+	DryRun  bool   `usage:"Print the sessions that would be removed without removing them"`
+}
+
+func NewSessionsPrune(n *Nanobot) *SessionsPrune {
+	return &SessionsPrune{
+		Nanobot: n,
+	}
+}
+
+func (s *SessionsPrune) Customize(cmd *cobra.Command) {
+	cmd.Use = "prune [flags]"
+	cmd.Short = "Remove old sessions with their files"
+	cmd.Args = cobra.NoArgs
+	cmd.Example = `
+  # Show the sessions that weren't updated for 30 days.
+  nanobot session prune --max-age 720h --dry-run
+
+  # Remove the oldest sessions until the files of all sessions take at most 1 GiB.
+  nanobot session prune --max-disk 1024
+`
+}
+
+func (s *SessionsPrune) Run(cmd *cobra.Command, _ []string) error {
+	opts := session.PruneOptions{
+		MaxDiskBytes: int64(s.MaxDisk) << 20,
+		DryRun:       s.DryRun,
+	}
+	if s.MaxAge != "" {
+		maxAge, err := time.ParseDuration(s.MaxAge)
+		if err != nil {
+			return fmt.Errorf("invalid --max-age: %w", err)
+		}
+		opts.MaxAge = maxAge
+	}
+	if opts.MaxAge == 0 && opts.MaxDiskBytes == 0 {
+		cfg, err := s.Nanobot.ReadConfig(cmd.Context(), s.Nanobot.ConfigPaths(), false)
+		if err != nil {
+			return err
+		}
+		if cfg.Retention != nil {
+			opts = session.PruneOptionsFromRetention(*cfg.Retention)
+			opts.DryRun = s.DryRun
+		}
+	}
+	if opts.MaxAge <= 0 && opts.MaxDiskBytes <= 0 {
+		return fmt.Errorf("set --max-age or --max-disk, or retention in the config")
+	}
+
+	store, err := s.Nanobot.NewSessionStore("")
+	if err != nil {
+		return err
+	}
+
+	result, err := session.NewManager(store).Prune(cmd.Context(), opts)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = tw.Write([]byte("ID\tUPDATED\tSIZE\tREASON\tDESCRIPTION\n"))
+	for _, removed := range result.Removed {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", removed.SessionID, removed.UpdatedAt.Format(time.RFC3339),
+			formatBytes(removed.Bytes), removed.Reason, trim(removed.Description))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	verb := "Removed"
+	if s.DryRun {
+		verb = "Would remove"
+	}
+	fmt.Printf("%s %d sessions, freeing %s. The remaining sessions take %s.\n", verb, len(result.Removed),
+		formatBytes(result.FreedBytes), formatBytes(result.DiskBytes))
+	return nil
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/session"
+)
+
+const (
+	defaultShutdownGracePeriod = 30 * time.Second
+	// shutdownStepTimeout bounds the checkpoint and the HTTP shutdown.
+	shutdownStepTimeout = 10 * time.Second
+)
+
+// shutdownCoordinator stops the server gracefully: it stops accepting new
+// sessions, lets the requests in flight finish within the grace period,
+// checkpoints the sessions so that interrupted runs can resume, cancels what
+// is left, and then closes the event streams, sessions, and their clients.
+type shutdownCoordinator struct {
+	server         *http.Server
+	mcpServer      *mcp.HTTPServer
+	sessions       *session.Manager
+	cancelRequests context.CancelFunc
+	gracePeriod    time.Duration
+}
+
+func (c shutdownCoordinator) shutdown() {
+	gracePeriod := c.gracePeriod
+	if gracePeriod <= 0 {
+		gracePeriod = defaultShutdownGracePeriod
+	}
+	slog.Info("Shutting down, waiting for requests in flight", "gracePeriod", gracePeriod)
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+	if err := c.mcpServer.Drain(drainCtx); err != nil {
+		slog.Warn("Grace period is over, canceling requests in flight", "error", err)
+	}
+	cancel()
+
+	checkpointCtx, cancel := context.WithTimeout(context.Background(), shutdownStepTimeout)
+	if err := c.sessions.Checkpoint(checkpointCtx); err != nil {
+		slog.Error("Failed to checkpoint sessions", "error", err)
+	}
+	cancel()
+
+	c.cancelRequests()
+	c.mcpServer.CloseStreams()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownStepTimeout)
+	if err := c.server.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("Failed to close connections", "error", err)
+	}
+	cancel()
+
+	c.sessions.Close()
+	slog.Info("Shutdown complete")
+}
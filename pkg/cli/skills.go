@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/obot-platform/nanobot/pkg/servers/skills"
+	"github.com/obot-platform/nanobot/pkg/skillformat"
+	"github.com/spf13/cobra"
+)
+
+type Skills struct {
+	n *Nanobot
+}
+
+func NewSkills(n *Nanobot) *Skills {
+	return &Skills{
+		n: n,
+	}
+}
+
+func (s *Skills) Customize(cmd *cobra.Command) {
+	cmd.Hidden = true
+	cmd.Use = "skills"
+	cmd.Short = "Install and remove skills shared from git repositories or HTTPS URLs"
+	cmd.Aliases = []string{"skill"}
+	cmd.Args = cobra.NoArgs
+}
+
+func (s *Skills) Run(cmd *cobra.Command, _ []string) error {
+	return cmd.Help()
+}
+
+type SkillsInstall struct {
+	Ref      string `usage:"Git branch, tag, or commit to install"`
+	Path     string `usage:"Directory of the skill within the git repository"`
+	Checksum string `usage:"Pin the install to this SHA-256 of the archive, or commit SHA of the git repository"`
+	Force    bool   `usage:"Overwrite an installed skill with the same name" short:"f"`
+	n        *Nanobot
+}
+
+func NewSkillsInstall(n *Nanobot) *SkillsInstall {
+	return &SkillsInstall{
+		n: n,
+	}
+}
+
+func (s *SkillsInstall) Customize(cmd *cobra.Command) {
+	cmd.Use = "install [flags] URL"
+	cmd.Short = "Install a skill from an HTTPS ZIP archive or a git repository"
+	cmd.Args = cobra.ExactArgs(1)
+	cmd.Example = `
+  # Install a skill from the root of a git repository.
+  nanobot skills install https://github.com/acme/skills.git --path postgres-helper
+
+  # Install a pinned version of a skill archive.
+  nanobot skills install https://example.com/skills/postgres-helper.zip --checksum 3f2a...
+
+  # Install a skill at a pinned commit.
+  nanobot skills install git+https://github.com/acme/skills --path postgres-helper --checksum 9c1e...
+`
+}
+
+func (s *SkillsInstall) Run(cmd *cobra.Command, args []string) error {
+	bundle, err := skills.FetchRemote(cmd.Context(), skills.RemoteSource{
+		URL:      args[0],
+		Ref:      s.Ref,
+		Path:     s.Path,
+		Checksum: s.Checksum,
+	})
+	if err != nil {
+		return err
+	}
+
+	configDir := s.n.RuntimeConfigDir()
+	targetDir := filepath.Join(configDir, skillformat.SkillsDir, bundle.Name)
+	if _, err := os.Stat(targetDir); err == nil && !s.Force {
+		return fmt.Errorf("skill %q is already installed in %s, use --force to overwrite it", bundle.Name, targetDir)
+	}
+
+	path, files, err := skills.InstallBundle(configDir, bundle)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Installed %s into %s (%d files)\n", bundle.Name, path, len(files))
+	if s.Checksum == "" {
+		fmt.Printf("Pin this version with --checksum %s\n", bundle.Checksum)
+	}
+	return nil
+}
+
+type SkillsRemove struct {
+	n *Nanobot
+}
+
+func NewSkillsRemove(n *Nanobot) *SkillsRemove {
+	return &SkillsRemove{
+		n: n,
+	}
+}
+
+func (s *SkillsRemove) Customize(cmd *cobra.Command) {
+	cmd.Use = "remove [flags] NAME..."
+	cmd.Short = "Remove installed skills"
+	cmd.Aliases = []string{"rm"}
+	cmd.Args = cobra.MinimumNArgs(1)
+}
+
+func (s *SkillsRemove) Run(_ *cobra.Command, args []string) error {
+	for _, name := range args {
+		path, err := skills.RemoveSkill(s.n.RuntimeConfigDir(), name)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removed %s from %s\n", name, path)
+	}
+	return nil
+}
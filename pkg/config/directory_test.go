@@ -35,8 +35,8 @@ func TestLoadFromDirectory_Simple(t *testing.T) {
 		t.Errorf("expected agent name 'Main Assistant', got '%s'", agent.Name)
 	}
 
-	if agent.Model != "gpt-4" {
-		t.Errorf("expected model 'gpt-4', got '%s'", agent.Model)
+	if agent.Model.Primary() != "gpt-4" {
+		t.Errorf("expected model 'gpt-4', got '%s'", agent.Model.Primary())
 	}
 
 	if agent.Instructions.Instructions == "" {
@@ -307,8 +307,8 @@ func TestParseMarkdownAgent_AllFields(t *testing.T) {
 	}
 
 	// Check various fields from front-matter
-	if agent.Model != "gpt-4" {
-		t.Errorf("expected model 'gpt-4', got '%s'", agent.Model)
+	if agent.Model.Primary() != "gpt-4" {
+		t.Errorf("expected model 'gpt-4', got '%s'", agent.Model.Primary())
 	}
 
 	if len(agent.MCPServers) != 1 || agent.MCPServers[0] != "myserver" {
@@ -1021,8 +1021,8 @@ func TestLoadFromDirectory_MarkdownOverridesYAML(t *testing.T) {
 	if agent.Name != "Markdown Main" {
 		t.Errorf("name: got %q, want %q (markdown should override YAML)", agent.Name, "Markdown Main")
 	}
-	if agent.Model != "gpt-4.1" {
-		t.Errorf("model: got %q, want %q", agent.Model, "gpt-4.1")
+	if agent.Model.Primary() != "gpt-4.1" {
+		t.Errorf("model: got %q, want %q", agent.Model.Primary(), "gpt-4.1")
 	}
 
 	// MCP server from YAML base should still be present
@@ -1064,8 +1064,8 @@ func TestLoadFromDirectory_LLMProvidersFromYAML(t *testing.T) {
 	if !ok {
 		t.Fatal("expected agent 'main'")
 	}
-	if agent.Model != "anthropic/claude-haiku-4-5" {
-		t.Errorf("model: got %q, want %q", agent.Model, "anthropic/claude-haiku-4-5")
+	if agent.Model.Primary() != "anthropic/claude-haiku-4-5" {
+		t.Errorf("model: got %q, want %q", agent.Model.Primary(), "anthropic/claude-haiku-4-5")
 	}
 }
 
@@ -1093,8 +1093,8 @@ func TestLoadFromDirectory_Merge(t *testing.T) {
 	if main.Name != "Markdown Main" {
 		t.Errorf("main name: got %q, want %q (markdown should override YAML)", main.Name, "Markdown Main")
 	}
-	if main.Model != "gpt-4" {
-		t.Errorf("main model: got %q, want %q (markdown should override YAML)", main.Model, "gpt-4")
+	if main.Model.Primary() != "gpt-4" {
+		t.Errorf("main model: got %q, want %q (markdown should override YAML)", main.Model.Primary(), "gpt-4")
 	}
 
 	// Markdown-only helper is present
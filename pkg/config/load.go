@@ -90,6 +90,8 @@ func loadSingle(ctx context.Context, path string, includeDefaultAgents bool, pro
 }
 
 func loadResource(ctx context.Context, configResource *resource, profiles ...string) (*types.Config, string, error) {
+	ctx = withTemplateProfiles(ctx, profiles)
+
 	targetCwd, err := configResource.Cwd()
 	if err != nil {
 		return nil, "", fmt.Errorf("error determining working directory: %w", err)
@@ -132,13 +134,15 @@ func loadResource(ctx context.Context, configResource *resource, profiles ...str
 
 	for _, profile := range profiles {
 		profileName, _, optional := strings.Cut(profile, "?")
-		profileConfig, found := last.Profiles[profileName]
+		profileConfig, found, err := resolveProfile(last.Profiles, profileName, nil)
+		if err != nil {
+			return nil, "", err
+		}
 		if !found && !optional {
 			return nil, "", fmt.Errorf("profile %s not found", profileName)
 		} else if !found {
 			continue
 		}
-		var err error
 		last, err = Merge(last, profileConfig)
 		if err != nil {
 			return nil, "", fmt.Errorf("error merging profile %s: %w", profileName, err)
@@ -192,12 +196,64 @@ func toMap(cfg types.Config) (map[string]any, error) {
 	return result, json.Unmarshal(data, &result)
 }
 
-func mergeObject(base, overlay any) any {
+// resolveProfile returns the profile merged over the profiles it extends.
+func resolveProfile(profiles map[string]types.Config, name string, chain []string) (types.Config, bool, error) {
+	profile, found := profiles[name]
+	if !found {
+		return types.Config{}, false, nil
+	}
+	if slices.Contains(chain, name) {
+		return types.Config{}, false, fmt.Errorf("profile %s extends itself through %s", name, strings.Join(append(chain, name), " -> "))
+	}
+
+	var (
+		parents    *types.Config
+		strategies = map[string]types.MergeStrategy{}
+	)
+	for _, parentName := range profile.Extends {
+		parent, found, err := resolveProfile(profiles, parentName, append(chain, name))
+		if err != nil {
+			return types.Config{}, false, err
+		} else if !found {
+			return types.Config{}, false, fmt.Errorf("profile %s extends profile %s that is not defined", name, parentName)
+		}
+		maps.Copy(strategies, parent.MergeStrategies)
+		if parents == nil {
+			parents = &parent
+			continue
+		}
+		merged, err := Merge(*parents, parent)
+		if err != nil {
+			return types.Config{}, false, fmt.Errorf("error merging profile %s: %w", parentName, err)
+		}
+		parents = &merged
+	}
+
+	profile.Extends = nil
+	if parents == nil {
+		return profile, true, nil
+	}
+
+	// The strategies of the profiles still apply when the result is merged
+	// over the config.
+	maps.Copy(strategies, profile.MergeStrategies)
+	merged, err := Merge(*parents, profile)
+	if err != nil {
+		return types.Config{}, false, fmt.Errorf("error merging profile %s: %w", name, err)
+	}
+	merged.MergeStrategies = strategies
+	return merged, true, nil
+}
+
+func mergeObject(base, overlay any, strategies map[string]types.MergeStrategy, path []string) any {
+	if mergeStrategy(strategies, path) == types.MergeStrategyReplace {
+		return overlay
+	}
 	if baseMap, ok := base.(map[string]any); ok {
 		if overlayMap, ok := overlay.(map[string]any); ok {
 			newMap := maps.Clone(baseMap)
 			for k, v := range overlayMap {
-				newMap[k] = mergeObject(baseMap[k], v)
+				newMap[k] = mergeObject(baseMap[k], v, strategies, append(path, k))
 			}
 			return newMap
 		}
@@ -210,7 +266,41 @@ func mergeObject(base, overlay any) any {
 	return overlay
 }
 
+// mergeStrategy returns the strategy of the first pattern that matches the
+// path, patterns are JSON pointers in which * matches any key.
+func mergeStrategy(strategies map[string]types.MergeStrategy, path []string) types.MergeStrategy {
+	for _, pattern := range slices.Sorted(maps.Keys(strategies)) {
+		segments := strings.Split(strings.TrimPrefix(pattern, "/"), "/")
+		if len(segments) != len(path) {
+			continue
+		}
+		matched := true
+		for i, segment := range segments {
+			segment = strings.NewReplacer("~1", "/", "~0", "~").Replace(segment)
+			if segment != "*" && segment != path[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return strategies[pattern]
+		}
+	}
+	return ""
+}
+
+// Merge merges overlay over base. Maps are merged and lists are appended to,
+// unless the merge strategies of overlay say otherwise.
 func Merge(base, overlay types.Config) (types.Config, error) {
+	for pattern, strategy := range overlay.MergeStrategies {
+		switch strategy {
+		case types.MergeStrategyMerge, types.MergeStrategyAppend, types.MergeStrategyReplace:
+		default:
+			return types.Config{}, fmt.Errorf("merge strategy of %s must be %s, %s, or %s, got %q", pattern,
+				types.MergeStrategyMerge, types.MergeStrategyAppend, types.MergeStrategyReplace, strategy)
+		}
+	}
+
 	baseMap, err := toMap(base)
 	if err != nil {
 		return types.Config{}, err
@@ -220,7 +310,9 @@ func Merge(base, overlay types.Config) (types.Config, error) {
 		return types.Config{}, err
 	}
 
-	merged := mergeObject(baseMap, overlayMap)
+	merged := mergeObject(baseMap, overlayMap, overlay.MergeStrategies, nil).(map[string]any)
+	// The strategies are only for merging over base.
+	delete(merged, "mergeStrategies")
 	mergedData, err := json.Marshal(merged)
 	if err != nil {
 		return types.Config{}, err
@@ -3,8 +3,13 @@ package config
 import (
 	"encoding/json"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
 	"github.com/santhosh-tekuri/jsonschema/v6"
 	"sigs.k8s.io/yaml"
 )
@@ -188,3 +193,103 @@ func TestSchema(t *testing.T) {
 		t.Fatalf("Failed to validate schema: %v", err)
 	}
 }
+
+func TestProfileInheritance(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "nanobot.yaml"), []byte(`agents:
+  main:
+    model: gpt-4.1
+    tools: [search]
+    mcpServers: [search]
+mcpServers:
+  search:
+    url: https://search.example.com/mcp
+profiles:
+  base:
+    agents:
+      main:
+        maxTurns: 5
+        tools: [files]
+  dev:
+    extends: base
+    mergeStrategies:
+      /agents/*/mcpServers: replace
+    agents:
+      main:
+        mcpServers: [files]
+    mcpServers:
+      files:
+        command: npx
+  loop:
+    extends: cycle
+  cycle:
+    extends: loop
+  broken:
+    extends: missing
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, _, err := Load(t.Context(), dir, false, "dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	main := cfg.Agents["main"]
+	if main.MaxTurns != 5 {
+		t.Errorf("expected maxTurns from the base profile, got %d", main.MaxTurns)
+	}
+	if !slices.Equal(main.Tools, types.StringList{"search", "files"}) {
+		t.Errorf("expected the tools to be appended to, got %v", main.Tools)
+	}
+	if !slices.Equal(main.MCPServers, types.StringList{"files"}) {
+		t.Errorf("expected the MCP servers to be replaced, got %v", main.MCPServers)
+	}
+
+	if _, _, err := Load(t.Context(), dir, false, "loop"); err == nil || !strings.Contains(err.Error(), "extends itself") {
+		t.Errorf("expected a cycle error, got %v", err)
+	}
+	if _, _, err := Load(t.Context(), dir, false, "broken"); err == nil || !strings.Contains(err.Error(), "profile missing that is not defined") {
+		t.Errorf("expected an unknown profile error, got %v", err)
+	}
+}
+
+func TestMergeStrategies(t *testing.T) {
+	base := types.Config{
+		Agents: map[string]types.Agent{
+			"main": {HookAgent: types.HookAgent{Tools: []string{"a"}}},
+		},
+		MCPServers: map[string]mcp.Server{
+			"one": {BaseURL: "https://one.example.com"},
+		},
+	}
+	overlay := types.Config{
+		Agents: map[string]types.Agent{
+			"main": {HookAgent: types.HookAgent{Tools: []string{"b"}}},
+		},
+		MCPServers: map[string]mcp.Server{
+			"two": {BaseURL: "https://two.example.com"},
+		},
+		MergeStrategies: map[string]types.MergeStrategy{
+			"/mcpServers": types.MergeStrategyReplace,
+		},
+	}
+
+	merged, err := Merge(base, overlay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(merged.Agents["main"].Tools, types.StringList{"a", "b"}) {
+		t.Errorf("expected the tools to be appended to, got %v", merged.Agents["main"].Tools)
+	}
+	if _, ok := merged.MCPServers["one"]; ok || len(merged.MCPServers) != 1 {
+		t.Errorf("expected the MCP servers to be replaced, got %v", merged.MCPServers)
+	}
+	if merged.MergeStrategies != nil {
+		t.Errorf("expected the merge strategies to be dropped, got %v", merged.MergeStrategies)
+	}
+
+	overlay.MergeStrategies = map[string]types.MergeStrategy{"/agents": "prepend"}
+	if _, err := Merge(base, overlay); err == nil {
+		t.Error("expected an unknown merge strategy to be refused")
+	}
+}
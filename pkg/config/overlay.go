@@ -0,0 +1,114 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/obot-platform/nanobot/pkg/types"
+	"sigs.k8s.io/yaml"
+)
+
+// OverlayFileName is the file in the config directory that holds the agents
+// defined at runtime. It is merged over the config whenever it is loaded.
+const OverlayFileName = "overlay.yaml"
+
+var overlayLock sync.Mutex
+
+func OverlayPath(configDir string) string {
+	return filepath.Join(configDir, OverlayFileName)
+}
+
+// ReadOverlay returns the overlay in configDir, which is empty if there is
+// none.
+func ReadOverlay(configDir string) (types.Config, error) {
+	var overlay types.Config
+	data, err := os.ReadFile(OverlayPath(configDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return overlay, nil
+	} else if err != nil {
+		return overlay, fmt.Errorf("failed to read config overlay: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &overlay); err != nil {
+		return overlay, fmt.Errorf("failed to parse config overlay %s: %w", OverlayPath(configDir), err)
+	}
+	return overlay, nil
+}
+
+// UpdateOverlay changes the overlay in configDir with update and writes it
+// back, unless update fails.
+func UpdateOverlay(configDir string, update func(overlay *types.Config) error) error {
+	overlayLock.Lock()
+	defer overlayLock.Unlock()
+
+	overlay, err := ReadOverlay(configDir)
+	if err != nil {
+		return err
+	}
+	if err := update(&overlay); err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(overlay)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(configDir, 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	// Write to a temporary file first so a config that is loaded meanwhile
+	// never sees half of the overlay.
+	tmp, err := os.CreateTemp(configDir, OverlayFileName+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write config overlay: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write config overlay: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write config overlay: %w", err)
+	}
+	return os.Rename(tmp.Name(), OverlayPath(configDir))
+}
+
+// ApplyOverlay merges the overlay in configDir over cfg and validates the
+// result.
+func ApplyOverlay(cfg types.Config, configDir string) (types.Config, error) {
+	overlay, err := ReadOverlay(configDir)
+	if err != nil {
+		return cfg, err
+	}
+	if len(overlay.Agents) == 0 && len(overlay.Publish.Entrypoint) == 0 {
+		return cfg, nil
+	}
+
+	merged, err := Merge(cfg, overlay)
+	if err != nil {
+		return cfg, fmt.Errorf("error merging config overlay %s: %w", OverlayPath(configDir), err)
+	}
+	merged.Publish.Entrypoint = uniqueEntrypoints(merged.Publish.Entrypoint)
+
+	if err := merged.Validate(true); err != nil {
+		return cfg, fmt.Errorf("invalid config overlay %s: %w", OverlayPath(configDir), err)
+	}
+	return merged, nil
+}
+
+// uniqueEntrypoints drops the entrypoints of the overlay that the config
+// already has, keeping the order they were added in.
+func uniqueEntrypoints(entrypoints types.StringList) types.StringList {
+	var result types.StringList
+	for _, entrypoint := range entrypoints {
+		if !slices.Contains(result, entrypoint) {
+			result = append(result, entrypoint)
+		}
+	}
+	return result
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+func TestOverlay(t *testing.T) {
+	dir := t.TempDir()
+	base := types.Config{
+		Publish: types.Publish{
+			Entrypoint: []string{"main", "helper"},
+		},
+		Agents: map[string]types.Agent{
+			"main":   {HookAgent: types.HookAgent{Model: types.AgentModel{"gpt-4.1"}, Tools: types.StringList{"files"}}},
+			"helper": {HookAgent: types.HookAgent{Model: types.AgentModel{"gpt-4.1"}}},
+		},
+		MCPServers: map[string]mcp.Server{
+			"files": {Command: "files"},
+		},
+	}
+
+	cfg, err := ApplyOverlay(base, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Agents) != 2 {
+		t.Fatalf("expected the config as it is without an overlay, got %v", cfg.Agents)
+	}
+
+	err = UpdateOverlay(dir, func(overlay *types.Config) error {
+		overlay.Agents = map[string]types.Agent{
+			"main":     {HookAgent: types.HookAgent{Model: types.AgentModel{"claude-sonnet-4"}}},
+			"reviewer": {HookAgent: types.HookAgent{Tools: types.StringList{"files"}}},
+		}
+		overlay.MergeStrategies = map[string]types.MergeStrategy{
+			"/agents/main":     types.MergeStrategyReplace,
+			"/agents/reviewer": types.MergeStrategyReplace,
+		}
+		overlay.Publish.Entrypoint = []string{"main", "reviewer"}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err = ApplyOverlay(base, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if main := cfg.Agents["main"]; main.Model.Primary() != "claude-sonnet-4" || len(main.Tools) != 0 {
+		t.Fatalf("expected the overlay to replace the main agent, got %+v", main)
+	}
+	if _, ok := cfg.Agents["reviewer"]; !ok {
+		t.Fatalf("expected the reviewer agent from the overlay, got %v", cfg.Agents)
+	}
+	if !slices.Equal(cfg.Publish.Entrypoint, []string{"main", "helper", "reviewer"}) {
+		t.Fatalf("unexpected entrypoints %v", cfg.Publish.Entrypoint)
+	}
+	if len(cfg.MergeStrategies) != 0 {
+		t.Fatalf("expected no merge strategies in the merged config, got %v", cfg.MergeStrategies)
+	}
+
+	err = UpdateOverlay(dir, func(overlay *types.Config) error {
+		overlay.Agents["reviewer"] = types.Agent{HookAgent: types.HookAgent{Tools: types.StringList{"missing"}}}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ApplyOverlay(base, dir); err == nil || !strings.Contains(err.Error(), OverlayFileName) {
+		t.Fatalf("expected the invalid overlay to be reported, got %v", err)
+	}
+}
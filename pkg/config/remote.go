@@ -0,0 +1,241 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// gitPrefix marks a config in a git repository, as in
+// git::https://github.com/acme/agents.git//catalog@v1.2.0
+const gitPrefix = "git::"
+
+var (
+	commitPattern = regexp.MustCompile(`^[0-9a-fA-F]{40}$`)
+	gitLock       sync.Mutex
+)
+
+func isRemote(name string) bool {
+	return strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://") || strings.HasPrefix(name, gitPrefix)
+}
+
+// remoteCacheDir is where remote configs are kept so that nanobot still starts
+// when their source is unreachable.
+func remoteCacheDir(kind string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user cache directory: %w", err)
+	}
+	return filepath.Join(dir, "nanobot", "config", kind), nil
+}
+
+func cacheKey(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// parseHTTPRef splits the sha256 pin from a URL like
+// https://example.com/nanobot.yaml#sha256=<hex>.
+func parseHTTPRef(name string) (url, checksum string, err error) {
+	url, fragment, ok := strings.Cut(name, "#")
+	if !ok {
+		return name, "", nil
+	}
+	checksum, ok = strings.CutPrefix(fragment, "sha256=")
+	if !ok || len(checksum) != sha256.Size*2 {
+		return "", "", fmt.Errorf("invalid pin %q in %s, must be #sha256=<hex digest>", fragment, url)
+	}
+	return url, strings.ToLower(checksum), nil
+}
+
+// parseGitRef parses git::<repo>[//<path>][@<ref>]. A ref that is a full
+// commit SHA pins the config to that commit.
+func parseGitRef(name string) (repo, subPath, ref string, err error) {
+	rest := strings.TrimPrefix(name, gitPrefix)
+
+	// The path separator is the first // after the scheme of the repository URL.
+	start := 0
+	if i := strings.Index(rest, "://"); i >= 0 {
+		start = i + len("://")
+	}
+	repo = rest
+	if i := strings.Index(rest[start:], "//"); i >= 0 {
+		repo, subPath = rest[:start+i], rest[start+i+len("//"):]
+	}
+
+	// The ref follows the last @, unless that @ is the user of an SSH URL
+	// like git@github.com:acme/agents.git.
+	if subPath != "" {
+		if i := strings.LastIndex(subPath, "@"); i >= 0 {
+			subPath, ref = subPath[:i], subPath[i+1:]
+		}
+	} else if i := strings.LastIndex(repo, "@"); i > strings.LastIndex(repo, ":") {
+		repo, ref = repo[:i], repo[i+1:]
+	}
+
+	if repo == "" {
+		return "", "", "", fmt.Errorf("invalid git config %s, must be git::<repo>[//<path>][@<ref>]", name)
+	}
+	if strings.HasPrefix(repo, "-") || strings.HasPrefix(ref, "-") {
+		return "", "", "", fmt.Errorf("invalid git config %s, the repository and ref must not start with -", name)
+	}
+	subPath = strings.Trim(subPath, "/")
+	if subPath != "" && !filepath.IsLocal(filepath.FromSlash(subPath)) {
+		return "", "", "", fmt.Errorf("path %q in %s must be relative to the repository root", subPath, name)
+	}
+	return repo, subPath, ref, nil
+}
+
+// fetchRemote reads a remote config, keeping the last copy that was read on
+// disk. A pinned config is only used if its sha256 digest matches.
+func fetchRemote(ctx context.Context, url, checksum string) ([]byte, error) {
+	var cacheFile string
+	if dir, err := remoteCacheDir("http"); err == nil {
+		cacheFile = filepath.Join(dir, cacheKey(url))
+	}
+
+	if checksum != "" && cacheFile != "" {
+		if data, err := os.ReadFile(cacheFile); err == nil && digest(data) == checksum {
+			return data, nil
+		}
+	}
+
+	data, err := httpGet(ctx, url)
+	if err != nil {
+		cached, cacheErr := readCache(cacheFile)
+		if cacheErr != nil {
+			return nil, err
+		}
+		slog.Warn("failed to fetch remote config, using cached copy", "url", url, "error", err)
+		data = cached
+	}
+
+	if checksum != "" && digest(data) != checksum {
+		return nil, fmt.Errorf("checksum mismatch for %s: expected sha256 %s, got %s", url, checksum, digest(data))
+	}
+
+	if cacheFile != "" {
+		if err := writeCache(cacheFile, data); err != nil {
+			slog.Debug("failed to cache remote config", "url", url, "error", err)
+		}
+	}
+	return data, nil
+}
+
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func readCache(file string) ([]byte, error) {
+	if file == "" {
+		return nil, os.ErrNotExist
+	}
+	return os.ReadFile(file)
+}
+
+func writeCache(file string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0600)
+}
+
+// gitCheckout returns a local checkout of the ref of a repository. A checkout
+// of a pinned commit is reused without fetching, other refs are fetched every
+// time and the previous checkout is used if that fails.
+func gitCheckout(ctx context.Context, repo, ref string) (string, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return "", fmt.Errorf("git is required to load config from %s: %w", repo, err)
+	}
+
+	cacheDir, err := remoteCacheDir("git")
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(cacheDir, cacheKey(repo+"@"+ref))
+	pinned := commitPattern.MatchString(ref)
+
+	gitLock.Lock()
+	defer gitLock.Unlock()
+
+	if pinned {
+		if head, err := runGit(ctx, dir, "rev-parse", "HEAD"); err == nil && strings.EqualFold(head, ref) {
+			return dir, nil
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create git cache directory: %w", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		if _, err := runGit(ctx, dir, "init", "--quiet"); err != nil {
+			return "", err
+		}
+	}
+
+	fetchRef := ref
+	if fetchRef == "" {
+		fetchRef = "HEAD"
+	}
+	_, err = runGit(ctx, dir, "fetch", "--quiet", "--depth", "1", "--", repo, fetchRef)
+	if err == nil {
+		_, err = runGit(ctx, dir, "checkout", "--quiet", "--force", "FETCH_HEAD")
+	}
+	if err != nil {
+		if _, headErr := runGit(ctx, dir, "rev-parse", "HEAD"); headErr == nil && !pinned {
+			slog.Warn("failed to fetch remote config, using cached checkout", "repo", repo, "ref", ref, "error", err)
+			return dir, nil
+		}
+		return "", err
+	}
+
+	if pinned {
+		head, err := runGit(ctx, dir, "rev-parse", "HEAD")
+		if err != nil {
+			return "", err
+		}
+		if !strings.EqualFold(head, ref) {
+			return "", fmt.Errorf("commit mismatch for %s: expected %s, got %s", repo, ref, head)
+		}
+	}
+
+	return dir, nil
+}
+
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %w: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// repoRel resolves a path relative to a config in a repository, which can't
+// leave the repository.
+func repoRel(subPath, rel string) (string, error) {
+	joined := rel
+	if subPath != "" {
+		joined = joinPath(subPath, rel)
+	}
+	joined = strings.Trim(path.Clean(joined), "/")
+	if joined == "." {
+		return "", nil
+	}
+	if !filepath.IsLocal(filepath.FromSlash(joined)) {
+		return "", fmt.Errorf("path %q must be within the repository", rel)
+	}
+	return joined, nil
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParseGitRef(t *testing.T) {
+	tests := []struct {
+		name, repo, subPath, ref string
+	}{
+		{name: "git::https://github.com/acme/agents.git", repo: "https://github.com/acme/agents.git"},
+		{name: "git::https://github.com/acme/agents.git@v1.2.0", repo: "https://github.com/acme/agents.git", ref: "v1.2.0"},
+		{name: "git::https://github.com/acme/agents.git//catalog/nanobot.yaml@feature/x", repo: "https://github.com/acme/agents.git", subPath: "catalog/nanobot.yaml", ref: "feature/x"},
+		{name: "git::git@github.com:acme/agents.git", repo: "git@github.com:acme/agents.git"},
+		{name: "git::git@github.com:acme/agents.git//catalog@main", repo: "git@github.com:acme/agents.git", subPath: "catalog", ref: "main"},
+	}
+	for _, tt := range tests {
+		repo, subPath, ref, err := parseGitRef(tt.name)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if repo != tt.repo || subPath != tt.subPath || ref != tt.ref {
+			t.Errorf("%s: got repo %q, path %q, ref %q", tt.name, repo, subPath, ref)
+		}
+	}
+
+	if _, _, _, err := parseGitRef("git::https://github.com/acme/agents.git//../secrets"); err == nil {
+		t.Error("expected a path outside of the repository to be refused")
+	}
+	for _, name := range []string{
+		"git::https://github.com/acme/agents.git@--upload-pack=touch /tmp/x",
+		"git::--upload-pack=touch /tmp/x",
+	} {
+		if _, _, _, err := parseGitRef(name); err == nil {
+			t.Errorf("%s: expected an option-like repository or ref to be refused", name)
+		}
+	}
+}
+
+func TestFetchRemotePinned(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	content := "agents:\n  main:\n    model: gpt-4.1\n"
+	var down atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		if down.Load() {
+			http.Error(rw, "down", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = rw.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	pinned, err := resolve(server.URL + "/pinned.yaml#sha256=" + digest([]byte(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := pinned.read(t.Context()); err != nil || string(data) != content {
+		t.Fatalf("expected the pinned config, got %q, %v", data, err)
+	}
+
+	wrong, err := resolve(server.URL + "/wrong.yaml#sha256=" + digest([]byte("other")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wrong.read(t.Context()); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected a checksum mismatch, got %v", err)
+	}
+
+	// A config that was read before is used from the cache when the server is down.
+	cached, err := resolve(server.URL + "/cached.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cached.read(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	down.Store(true)
+	httpCacheLock.Lock()
+	delete(httpCache, server.URL+"/cached.yaml")
+	httpCacheLock.Unlock()
+	if data, err := cached.read(t.Context()); err != nil || string(data) != content {
+		t.Fatalf("expected the cached config, got %q, %v", data, err)
+	}
+}
+
+func TestGitConfig(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	repo := t.TempDir()
+	git := func(args ...string) string {
+		t.Helper()
+		out, err := runGit(t.Context(), repo, append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	if err := os.MkdirAll(filepath.Join(repo, "catalog"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"catalog/nanobot.yaml": "extends: base.yaml\nagents:\n  main:\n    model: gpt-4.1\n",
+		"catalog/base.yaml":    "agents:\n  helper:\n    model: gpt-4.1-mini\npublish:\n  entrypoint: [main]\n",
+	} {
+		if err := os.WriteFile(filepath.Join(repo, filepath.FromSlash(name)), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("init", "--quiet")
+	git("add", "-A")
+	git("commit", "--quiet", "-m", "catalog")
+	commit := git("rev-parse", "HEAD")
+	git("config", "uploadpack.allowAnySHA1InWant", "true")
+
+	cfg, _, err := Load(t.Context(), "git::file://"+repo+"//catalog/nanobot.yaml@"+commit, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cfg.Agents["main"]; !ok {
+		t.Errorf("expected the main agent, got %v", cfg.Agents)
+	}
+	if _, ok := cfg.Agents["helper"]; !ok {
+		t.Errorf("expected the helper agent from the extended config, got %v", cfg.Agents)
+	}
+
+	// The pinned commit is read from the cache once it was checked out.
+	if err := os.RemoveAll(repo); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Load(t.Context(), "git::file://"+repo+"//catalog/nanobot.yaml@"+commit, false); err != nil {
+		t.Fatalf("expected the cached checkout to be used, got %v", err)
+	}
+}
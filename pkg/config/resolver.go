@@ -21,6 +21,9 @@ type resource struct {
 	url          string
 	parts        []string
 	ref          string
+	checksum     string
+	repo         string
+	subPath      string
 	static       *types.Config
 }
 
@@ -184,6 +187,17 @@ func (r *resource) SourceRel(source mcp.ServerSource) (mcp.ServerSource, error)
 		}
 		source.SubPath = strings.Join(append(r.parts[3:], source.SubPath), "/")
 		return source, nil
+	case "repo":
+		subPath, err := repoRel(r.subPath, source.SubPath)
+		if err != nil {
+			return mcp.ServerSource{}, fmt.Errorf("error resolving source %s in %s: %w", source.SubPath, r.url, err)
+		}
+		source.Repo = r.repo
+		if source.Reference == "" && source.Tag == "" && source.Branch == "" && source.Commit == "" {
+			source.Reference = r.ref
+		}
+		source.SubPath = subPath
+		return source, nil
 	}
 
 	return mcp.ServerSource{}, fmt.Errorf("unknown resource type: %s", r.resourceType)
@@ -198,7 +212,23 @@ func (r *resource) String() string {
 
 func (r *resource) read(ctx context.Context) ([]byte, error) {
 	if r.resourceType == "http" {
-		return httpGet(ctx, r.url)
+		data, err := fetchRemote(ctx, r.url, r.checksum)
+		if err != nil {
+			return nil, err
+		}
+		return render(ctx, r.url, data)
+	}
+
+	if r.resourceType == "repo" {
+		dir, err := gitCheckout(ctx, r.repo, r.ref)
+		if err != nil {
+			return nil, err
+		}
+		local := &resource{
+			resourceType: "path",
+			url:          filepath.Join(dir, filepath.FromSlash(r.subPath)),
+		}
+		return local.read(ctx)
 	}
 
 	if r.resourceType == "path" {
@@ -216,7 +246,10 @@ func (r *resource) read(ctx context.Context) ([]byte, error) {
 		// Try to read nanobot.yaml (may or may not exist)
 		var yamlData []byte
 		if data, err := os.ReadFile(f); err == nil {
-			yamlData = data
+			yamlData, err = render(ctx, f, data)
+			if err != nil {
+				return nil, fmt.Errorf("error reading file %s: %w", f, err)
+			}
 		} else if !os.IsNotExist(err) {
 			return nil, fmt.Errorf("error reading file %s: %w", f, err)
 		}
@@ -241,7 +274,11 @@ func (r *resource) read(ctx context.Context) ([]byte, error) {
 	}
 
 	if r.resourceType == "git" {
-		return gitRead(ctx, r.parts, r.ref)
+		data, err := gitRead(ctx, r.parts, r.ref)
+		if err != nil {
+			return nil, err
+		}
+		return render(ctx, r.String(), data)
 	}
 
 	return nil, fmt.Errorf("unknown resource type: %s", r.resourceType)
@@ -297,6 +334,10 @@ func (r *resource) Rel(path string) (*resource, error) {
 		return staticCfg, nil
 	}
 
+	if isRemote(path) {
+		return resolve(path)
+	}
+
 	switch r.resourceType {
 	case "http":
 		return &resource{
@@ -314,6 +355,18 @@ func (r *resource) Rel(path string) (*resource, error) {
 			parts:        append(r.parts, path),
 			ref:          r.ref,
 		}, nil
+	case "repo":
+		subPath, err := repoRel(r.subPath, path)
+		if err != nil {
+			return nil, fmt.Errorf("error resolving %s in %s: %w", path, r.url, err)
+		}
+		return &resource{
+			resourceType: "repo",
+			url:          r.url,
+			repo:         r.repo,
+			subPath:      subPath,
+			ref:          r.ref,
+		}, nil
 	}
 	return nil, fmt.Errorf("unknown resource type: %s", r.resourceType)
 }
@@ -335,7 +388,7 @@ func gitRead(ctx context.Context, parts []string, ref string) ([]byte, error) {
 	}
 
 	url := fmt.Sprintf("https://raw.githubusercontent.com/%s/%s/%s/%s", owner, repo, ref, path)
-	return httpGet(ctx, url)
+	return fetchRemote(ctx, url, "")
 }
 
 func statics(name string) *resource {
@@ -353,11 +406,31 @@ func resolve(name string) (*resource, error) {
 		return staticCfg, nil
 	}
 
+	if strings.HasPrefix(name, gitPrefix) {
+		// Handle configs in any git repository
+		repo, subPath, ref, err := parseGitRef(name)
+		if err != nil {
+			return nil, err
+		}
+		return &resource{
+			resourceType: "repo",
+			url:          name,
+			repo:         repo,
+			subPath:      subPath,
+			ref:          ref,
+		}, nil
+	}
+
 	if strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://") {
 		// Handle HTTP resources
+		url, checksum, err := parseHTTPRef(name)
+		if err != nil {
+			return nil, err
+		}
 		return &resource{
 			resourceType: "http",
-			url:          name,
+			url:          url,
+			checksum:     checksum,
 		}, nil
 	}
 
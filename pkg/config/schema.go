@@ -1,7 +1,10 @@
 package config
 
+//go:generate go run gen_schema.go
+
 import (
 	_ "embed"
+	"encoding/json"
 	"fmt"
 	"os"
 	"sync"
@@ -12,6 +15,10 @@ import (
 	"sigs.k8s.io/yaml"
 )
 
+// SchemaID identifies the published JSON schema of nanobot.yaml, for editors
+// and CI tools that validate configs.
+const SchemaID = "https://nanobot.dev/schemas/nanobot.schema.json"
+
 var (
 	schemaOnce sync.Once
 	schema     *jsonschema.Schema
@@ -49,3 +56,19 @@ func initSchema() (*jsonschema.Schema, error) {
 
 	return s, nil
 }
+
+// SchemaJSON returns the schema that configs are validated with as indented
+// JSON. The published copy under docs/schemas is kept in sync by go generate.
+func SchemaJSON() ([]byte, error) {
+	schemaObj := map[string]any{}
+	if err := yaml.Unmarshal(schemaByte, &schemaObj); err != nil {
+		return nil, err
+	}
+	schemaObj["$id"] = SchemaID
+
+	data, err := json.MarshalIndent(schemaObj, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
@@ -10,7 +10,7 @@ var UI = types.Config{
 		"nanobot.summary": {
 			HookAgent: types.HookAgent{
 				Name:  "nanobot.summary",
-				Model: "mini",
+				Model: types.AgentModel{"mini"},
 				Chat:  new(bool),
 				Instructions: types.DynamicInstructions{
 					Instructions: `- you will generate a short title based on the first message a user begins a conversation with
@@ -27,6 +27,7 @@ var UI = types.Config{
 		MCPServers: []string{"nanobot.meta", "nanobot.workflows", "nanobot.tasks"},
 		Tools: []string{
 			"nanobot.workflow-tools/deleteWorkflow",
+			"nanobot.workflow-tools/runWorkflow",
 			"nanobot.skills/installSkill",
 			"nanobot.skills/deleteSkill",
 			"nanobot.system/uploadFile",
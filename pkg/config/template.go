@@ -0,0 +1,107 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// TemplateData is what config templates can refer to, as in
+// model: {{ env "MODEL" | default "gpt-4.1" }} or a block between
+// {{ if hasProfile "production" }} and {{ end }}.
+type TemplateData struct {
+	// Env is the environment of nanobot, including its env file.
+	Env map[string]string
+	// Profiles are the names of the profiles the config is loaded with.
+	Profiles []string
+}
+
+type templateDataKey struct{}
+
+// WithTemplateEnv sets the environment config templates are rendered with.
+// Without it they see the environment of the process.
+func WithTemplateEnv(ctx context.Context, env map[string]string) context.Context {
+	return context.WithValue(ctx, templateDataKey{}, TemplateData{Env: env})
+}
+
+func withTemplateProfiles(ctx context.Context, profiles []string) context.Context {
+	data := templateDataFromContext(ctx)
+	data.Profiles = nil
+	for _, profile := range profiles {
+		name, _, _ := strings.Cut(profile, "?")
+		data.Profiles = append(data.Profiles, name)
+	}
+	return context.WithValue(ctx, templateDataKey{}, data)
+}
+
+func templateDataFromContext(ctx context.Context) TemplateData {
+	data, ok := ctx.Value(templateDataKey{}).(TemplateData)
+	if !ok || data.Env == nil {
+		data.Env = map[string]string{}
+		for _, kv := range os.Environ() {
+			k, v, _ := strings.Cut(kv, "=")
+			data.Env[k] = v
+		}
+	}
+	return data
+}
+
+// templateDirective is the first line of the config files that are
+// templates. Other files are used as they are, so literal {{ }} in their
+// prompts are kept.
+const templateDirective = "# nanobot:template"
+
+// isTemplate indicates if a config file starts with templateDirective.
+func isTemplate(content []byte) bool {
+	line, _, _ := bytes.Cut(content, []byte("\n"))
+	return string(bytes.TrimSpace(line)) == templateDirective
+}
+
+// render evaluates the Go template in a config file that is a template. Other
+// files are returned as they are.
+func render(ctx context.Context, name string, content []byte) ([]byte, error) {
+	if !isTemplate(content) {
+		return content, nil
+	}
+
+	data := templateDataFromContext(ctx)
+	tmpl, err := template.New(name).Option("missingkey=zero").Funcs(template.FuncMap{
+		"env": func(name string) string {
+			return data.Env[name]
+		},
+		"hasProfile": func(name string) bool {
+			return slices.Contains(data.Profiles, name)
+		},
+		"default": func(def string, value any) any {
+			if value == nil || value == "" {
+				return def
+			}
+			return value
+		},
+		"required": func(msg string, value any) (any, error) {
+			if value == nil || value == "" {
+				return nil, errors.New(msg)
+			}
+			return value, nil
+		},
+		"quote": func(value any) (string, error) {
+			data, err := json.Marshal(fmt.Sprint(value))
+			return string(data), err
+		},
+	}).Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("error rendering template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
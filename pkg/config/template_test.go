@@ -0,0 +1,81 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTemplate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "nanobot.yaml"), []byte(`# nanobot:template
+agents:
+  main:
+    model: {{ env "MODEL" | default "gpt-4.1" | quote }}
+    instructions: Answer as ${USER_NAME}.
+{{- if hasProfile "production" }}
+    mcpServers: [search]
+mcpServers:
+  search:
+    url: {{ required "SEARCH_URL is required in production" .Env.SEARCH_URL }}
+{{- end }}
+profiles:
+  production:
+    agents:
+      main:
+        maxTurns: 10
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := WithTemplateEnv(t.Context(), map[string]string{})
+	cfg, _, err := Load(ctx, dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if main := cfg.Agents["main"]; main.Model.Primary() != "gpt-4.1" || len(main.MCPServers) != 0 || main.Instructions.Instructions != "Answer as ${USER_NAME}." {
+		t.Fatalf("unexpected agent %+v", main)
+	}
+	if len(cfg.MCPServers) != 0 {
+		t.Fatalf("expected no MCP servers without the production profile, got %v", cfg.MCPServers)
+	}
+
+	if _, _, err := Load(ctx, dir, false, "production"); err == nil || !strings.Contains(err.Error(), "SEARCH_URL is required in production") {
+		t.Fatalf("expected the required value to be missing, got %v", err)
+	}
+
+	ctx = WithTemplateEnv(t.Context(), map[string]string{
+		"MODEL":      "claude-sonnet-4",
+		"SEARCH_URL": "https://search.example.com/mcp",
+	})
+	cfg, _, err = Load(ctx, dir, false, "production")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if main := cfg.Agents["main"]; main.Model.Primary() != "claude-sonnet-4" || len(main.MCPServers) != 1 || main.MaxTurns != 10 {
+		t.Fatalf("unexpected agent %+v", main)
+	}
+	if cfg.MCPServers["search"].BaseURL != "https://search.example.com/mcp" {
+		t.Fatalf("unexpected MCP servers %v", cfg.MCPServers)
+	}
+}
+
+func TestTemplateOptIn(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "nanobot.yaml"), []byte(`agents:
+  main:
+    model: gpt-4.1
+    instructions: Reply with a Handlebars greeting like {{ name }}.
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, _, err := Load(t.Context(), dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Agents["main"].Instructions.Instructions; got != "Reply with a Handlebars greeting like {{ name }}." {
+		t.Errorf("expected a file without the template directive to be kept as is, got %q", got)
+	}
+}
@@ -0,0 +1,292 @@
+package config
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	yamlv3 "gopkg.in/yaml.v3"
+	"sigs.k8s.io/yaml"
+)
+
+// Problem is an error in a config, with the line and column of the value it
+// is about when that is known.
+type Problem struct {
+	File    string `json:"file"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	var sb strings.Builder
+	sb.WriteString(p.File)
+	if p.Line > 0 {
+		fmt.Fprintf(&sb, ":%d:%d", p.Line, p.Column)
+	}
+	sb.WriteString(": ")
+	if p.Path != "" {
+		sb.WriteString(p.Path)
+		sb.WriteString(": ")
+	}
+	sb.WriteString(p.Message)
+	return sb.String()
+}
+
+// Check validates the config at path against the schema, reporting where in
+// the file each problem is, and then loads it with the profiles to check the
+// references between agents, servers, and providers. The error is only set
+// if the config couldn't be read at all.
+func Check(ctx context.Context, path string, profiles ...string) ([]Problem, error) {
+	ctx = withTemplateProfiles(ctx, profiles)
+
+	r, err := resolve(path)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving config path %s: %w", path, err)
+	}
+
+	file, data, positions, err := readForCheck(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := map[string]any{}
+	if err := yaml.Unmarshal(data, &obj); err != nil {
+		return []Problem{yamlProblem(file, err)}, nil
+	}
+
+	if err := getSchema().Validate(obj); err != nil {
+		var validationErr *jsonschema.ValidationError
+		if !errors.As(err, &validationErr) {
+			return nil, err
+		}
+		var root *yamlv3.Node
+		if positions {
+			var doc yamlv3.Node
+			if yamlv3.Unmarshal(data, &doc) == nil && len(doc.Content) > 0 {
+				root = doc.Content[0]
+			}
+		}
+		return schemaProblems(file, root, validationErr), nil
+	}
+
+	if _, _, err := Load(ctx, path, false, profiles...); err != nil {
+		var problems []Problem
+		for _, err := range splitErrors(err) {
+			problems = append(problems, Problem{File: file, Message: err.Error()})
+		}
+		return problems, nil
+	}
+	return nil, nil
+}
+
+// readForCheck reads the config of r. Positions are only meaningful when the
+// data is the file itself, not a directory of markdown agents merged into it.
+func readForCheck(ctx context.Context, r *resource) (file string, data []byte, positions bool, err error) {
+	data, err = r.read(ctx)
+	if err != nil {
+		return "", nil, false, fmt.Errorf("error reading %s: %w", r, err)
+	}
+	if r.resourceType != "path" {
+		return r.url, data, true, nil
+	}
+
+	file, err = r.fileToRead()
+	if err != nil {
+		return "", nil, false, err
+	}
+	if content, err := os.ReadFile(file); err == nil && string(content) == string(data) {
+		return file, data, true, nil
+	}
+	return r.url, data, false, nil
+}
+
+var yamlLinePattern = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+func yamlProblem(file string, err error) Problem {
+	msg := strings.TrimPrefix(err.Error(), "error converting YAML to JSON: ")
+	problem := Problem{File: file, Message: msg}
+	if match := yamlLinePattern.FindStringSubmatch(msg); match != nil {
+		problem.Line, _ = strconv.Atoi(match[1])
+		problem.Column = 1
+		problem.Message = match[2]
+	}
+	return problem
+}
+
+var printer = message.NewPrinter(language.English)
+
+// schemaProblems flattens a validation error to the errors of the values
+// that are wrong, rather than the objects that contain them.
+func schemaProblems(file string, root *yamlv3.Node, err *jsonschema.ValidationError) []Problem {
+	var problems []Problem
+	add := func(location []string, msg string) {
+		problem := Problem{
+			File:    file,
+			Path:    "/" + strings.Join(location, "/"),
+			Message: msg,
+		}
+		if node := findNode(root, location); node != nil {
+			problem.Line, problem.Column = node.Line, node.Column
+		}
+		if !slices.Contains(problems, problem) {
+			problems = append(problems, problem)
+		}
+	}
+
+	var walk func(e *jsonschema.ValidationError)
+	walk = func(e *jsonschema.ValidationError) {
+		if len(e.Causes) == 0 {
+			location := e.InstanceLocation
+			if additional, ok := e.ErrorKind.(*kind.AdditionalProperties); ok {
+				for _, property := range additional.Properties {
+					add(append(slices.Clone(location), property), printer.Sprintf("property %q is not allowed", property))
+				}
+				return
+			}
+			add(location, e.ErrorKind.LocalizedString(printer))
+			return
+		}
+
+		switch e.ErrorKind.(type) {
+		case *kind.OneOf, *kind.AnyOf:
+			// When no alternative got past the value itself, report what
+			// was expected. Otherwise the alternatives that got further into
+			// the value are the ones that were meant.
+			var deeper []*jsonschema.ValidationError
+			for _, cause := range e.Causes {
+				if deepest(cause) > len(e.InstanceLocation) {
+					deeper = append(deeper, cause)
+				}
+			}
+			if len(deeper) == 0 {
+				add(e.InstanceLocation, alternativesMessage(e.Causes))
+				return
+			}
+			for _, cause := range deeper {
+				walk(cause)
+			}
+		default:
+			for _, cause := range e.Causes {
+				walk(cause)
+			}
+		}
+	}
+	walk(err)
+
+	slices.SortStableFunc(problems, func(a, b Problem) int {
+		return cmp.Or(cmp.Compare(a.Line, b.Line), cmp.Compare(a.Column, b.Column), cmp.Compare(a.Path, b.Path))
+	})
+	return problems
+}
+
+func deepest(e *jsonschema.ValidationError) int {
+	depth := len(e.InstanceLocation)
+	if _, ok := e.ErrorKind.(*kind.AdditionalProperties); ok {
+		depth++
+	}
+	for _, cause := range e.Causes {
+		depth = max(depth, deepest(cause))
+	}
+	return depth
+}
+
+// alternativesMessage describes why a value matched none of the alternatives,
+// as one type error when they all failed on the type.
+func alternativesMessage(causes []*jsonschema.ValidationError) string {
+	var (
+		leaves   []*jsonschema.ValidationError
+		got      string
+		want     []string
+		messages []string
+	)
+	for _, cause := range causes {
+		leaves = append(leaves, leafErrors(cause)...)
+	}
+	for _, leaf := range leaves {
+		if typeErr, ok := leaf.ErrorKind.(*kind.Type); ok && (got == "" || got == typeErr.Got) {
+			got = typeErr.Got
+			want = append(want, typeErr.Want...)
+			continue
+		}
+		messages = append(messages, leaf.ErrorKind.LocalizedString(printer))
+	}
+	if len(messages) == 0 && got != "" {
+		return printer.Sprintf("got %s, want %s", got, strings.Join(slices.Compact(want), " or "))
+	}
+	for _, leaf := range leaves {
+		if _, ok := leaf.ErrorKind.(*kind.Type); ok {
+			messages = append(messages, leaf.ErrorKind.LocalizedString(printer))
+		}
+	}
+	return strings.Join(slices.Compact(messages), " or ")
+}
+
+func leafErrors(e *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(e.Causes) == 0 {
+		return []*jsonschema.ValidationError{e}
+	}
+	var leaves []*jsonschema.ValidationError
+	for _, cause := range e.Causes {
+		leaves = append(leaves, leafErrors(cause)...)
+	}
+	return leaves
+}
+
+// findNode returns the node at the JSON pointer location. A property is
+// located by its key, so that errors point at the name that was written.
+func findNode(node *yamlv3.Node, location []string) *yamlv3.Node {
+	for i, token := range location {
+		if node == nil {
+			return nil
+		}
+		for node.Kind == yamlv3.AliasNode {
+			node = node.Alias
+		}
+		switch node.Kind {
+		case yamlv3.MappingNode:
+			var next *yamlv3.Node
+			for j := 0; j+1 < len(node.Content); j += 2 {
+				if node.Content[j].Value == token {
+					if i == len(location)-1 {
+						return node.Content[j]
+					}
+					next = node.Content[j+1]
+					break
+				}
+			}
+			node = next
+		case yamlv3.SequenceNode:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(node.Content) {
+				return nil
+			}
+			node = node.Content[index]
+		default:
+			return nil
+		}
+	}
+	return node
+}
+
+func splitErrors(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var errs []error
+		for _, err := range joined.Unwrap() {
+			errs = append(errs, splitErrors(err)...)
+		}
+		return errs
+	}
+	return []error{err}
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+	"sigs.k8s.io/yaml"
+)
+
+func TestSchemaUpToDate(t *testing.T) {
+	generated, err := SchemaJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	published, err := os.ReadFile(filepath.Join("..", "..", "docs", "schemas", "nanobot.schema.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(generated, published) {
+		t.Error("docs/schemas/nanobot.schema.json is out of date, run go generate ./pkg/config")
+	}
+}
+
+// TestSchemaCoversConfig fails when a field is added to the config types
+// without adding it to schema.yaml, which would reject configs that set it.
+func TestSchemaCoversConfig(t *testing.T) {
+	obj := map[string]any{}
+	if err := yaml.Unmarshal(schemaByte, &obj); err != nil {
+		t.Fatal(err)
+	}
+	properties := func(obj map[string]any) map[string]any {
+		return obj["properties"].(map[string]any)
+	}
+	definitions := obj["definitions"].(map[string]any)
+
+	for name, tt := range map[string]struct {
+		properties map[string]any
+		typ        reflect.Type
+	}{
+		"config":    {properties(obj), reflect.TypeFor[types.Config]()},
+		"agent":     {properties(definitions["Agent"].(map[string]any)), reflect.TypeFor[types.Agent]()},
+		"mcpServer": {properties(definitions["MCPServer"].(map[string]any)), reflect.TypeFor[mcp.Server]()},
+	} {
+		for _, field := range jsonFields(tt.typ) {
+			if _, ok := tt.properties[field]; !ok {
+				t.Errorf("%s field %q is missing from schema.yaml", name, field)
+			}
+		}
+	}
+}
+
+func jsonFields(typ reflect.Type) (fields []string) {
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		switch {
+		case field.Anonymous && name == "":
+			fields = append(fields, jsonFields(field.Type)...)
+		case name != "" && name != "-" && field.IsExported():
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		t.Helper()
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+
+	valid := write("valid.yaml", `agents:
+  main:
+    model: gpt-4.1
+profiles:
+  fast:
+    agents:
+      main:
+        model: gpt-4.1-mini
+`)
+	if problems, err := Check(t.Context(), valid, "fast"); err != nil || len(problems) != 0 {
+		t.Fatalf("expected no problems, got %v, %v", problems, err)
+	}
+	if problems, err := Check(t.Context(), valid, "missing"); err != nil || len(problems) != 1 || !strings.Contains(problems[0].Message, "profile missing not found") {
+		t.Fatalf("expected the missing profile to be reported, got %v, %v", problems, err)
+	}
+
+	schema := write("schema.yaml", `agents:
+  main:
+    model: gpt-4.1
+    mdoel: typo
+    maxTurns: many
+`)
+	problems, err := Check(t.Context(), schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]Problem{
+		"/agents/main/mdoel":    {Line: 4, Column: 5},
+		"/agents/main/maxTurns": {Line: 5, Column: 5},
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("expected %d problems, got %v", len(want), problems)
+	}
+	for _, problem := range problems {
+		expected, ok := want[problem.Path]
+		if !ok || problem.Line != expected.Line || problem.Column != expected.Column || problem.File != schema {
+			t.Errorf("unexpected problem %s", problem)
+		}
+	}
+
+	references := write("references.yaml", `agents:
+  main:
+    model: gpt-4.1
+    mcpServers: [missing]
+`)
+	problems, err = Check(t.Context(), references)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 1 || !strings.Contains(problems[0].Message, `MCP server "missing" that is not defined`) {
+		t.Fatalf("expected the missing MCP server to be reported, got %v", problems)
+	}
+
+	syntax := write("syntax.yaml", "agents:\n  main:\n    model: [gpt-4.1\n")
+	problems, err = Check(t.Context(), syntax)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 1 || problems[0].Line == 0 {
+		t.Fatalf("expected a syntax error with its line, got %v", problems)
+	}
+}
@@ -29,6 +29,10 @@ func (*Service) HandleAuthURL(ctx context.Context, mcpServerName, url string) (b
 		session = session.Parent
 	}
 
+	if session.ClientCapabilities().Elicitation == nil {
+		return false, fmt.Errorf("MCP server %s requires authorization, visit %s to authorize it and try again", mcpServerName, url)
+	}
+
 	meta := map[string]any{
 		types.MetaPrefix + "oauth-url":   url,
 		types.MetaPrefix + "server-name": mcpServerName,
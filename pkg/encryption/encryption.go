@@ -0,0 +1,233 @@
+// Package encryption encrypts session data at rest with envelope encryption.
+// Data is encrypted with a data key, which is stored next to it wrapped by a
+// key encryption key that never leaves its keeper, a local secret or a KMS.
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+)
+
+// SessionKey is the session attribute holding the *Envelope that encrypts the
+// data of the session.
+const SessionKey = "encryptionEnvelope"
+
+// sealedPrefix starts every sealed value, it is a JSON object so that sealed
+// values can be stored in JSON columns.
+var sealedPrefix = []byte(`{"nanobotEncrypted":`)
+
+// ErrNoKey is returned when reading encrypted data without an encryption key.
+var ErrNoKey = errors.New("data is encrypted, set the session encryption key to read it")
+
+// KeyWrapper encrypts and decrypts data keys with a key encryption key.
+type KeyWrapper interface {
+	WrapKey(ctx context.Context, key []byte) ([]byte, error)
+	UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// Envelope seals data with a data key that is generated once per process and
+// wrapped by a KeyWrapper. The data keys of sealed data are unwrapped once and
+// cached.
+type Envelope struct {
+	wrapper KeyWrapper
+
+	lock    sync.Mutex
+	key     []byte
+	wrapped []byte
+	keys    map[string][]byte
+}
+
+func New(wrapper KeyWrapper) *Envelope {
+	return &Envelope{
+		wrapper: wrapper,
+		keys:    map[string][]byte{},
+	}
+}
+
+type sealed struct {
+	Version int    `json:"v"`
+	Key     []byte `json:"key"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+type sealedValue struct {
+	Sealed sealed `json:"nanobotEncrypted"`
+}
+
+// IsSealed returns whether data was sealed by an Envelope.
+func IsSealed(data []byte) bool {
+	return bytes.HasPrefix(data, sealedPrefix)
+}
+
+func (e *Envelope) dataKey(ctx context.Context) ([]byte, []byte, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.key != nil {
+		return e.key, e.wrapped, nil
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, nil, err
+	}
+	wrapped, err := e.wrapper.WrapKey(ctx, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+
+	e.key, e.wrapped = key, wrapped
+	e.keys[string(wrapped)] = key
+	return key, wrapped, nil
+}
+
+func (e *Envelope) unwrap(ctx context.Context, wrapped []byte) ([]byte, error) {
+	e.lock.Lock()
+	key, ok := e.keys[string(wrapped)]
+	e.lock.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	key, err := e.wrapper.UnwrapKey(ctx, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+
+	e.lock.Lock()
+	e.keys[string(wrapped)] = key
+	e.lock.Unlock()
+	return key, nil
+}
+
+// Seal encrypts data, the result carries the wrapped data key.
+func (e *Envelope) Seal(ctx context.Context, data []byte) ([]byte, error) {
+	key, wrapped, err := e.dataKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, ciphertext, err := seal(key, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(sealedValue{
+		Sealed: sealed{
+			Version: 1,
+			Key:     wrapped,
+			Nonce:   nonce,
+			Data:    ciphertext,
+		},
+	})
+}
+
+// Open decrypts data sealed by Seal. Data that isn't sealed is returned as
+// is, so that data written before encryption was enabled can be read.
+func (e *Envelope) Open(ctx context.Context, data []byte) ([]byte, error) {
+	if !IsSealed(data) {
+		return data, nil
+	}
+
+	var value sealedValue
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("failed to read encrypted data: %w", err)
+	}
+	if value.Sealed.Version != 1 {
+		return nil, fmt.Errorf("unsupported encrypted data version %d", value.Sealed.Version)
+	}
+
+	key, err := e.unwrap(ctx, value.Sealed.Key)
+	if err != nil {
+		return nil, err
+	}
+	return open(key, value.Sealed.Nonce, value.Sealed.Data)
+}
+
+// Open decrypts data with the envelope of the context, failing with ErrNoKey
+// if the data is sealed and there is none.
+func Open(ctx context.Context, data []byte) ([]byte, error) {
+	if !IsSealed(data) {
+		return data, nil
+	}
+	e := FromContext(ctx)
+	if e == nil {
+		return nil, ErrNoKey
+	}
+	return e.Open(ctx, data)
+}
+
+// Seal encrypts data with the envelope of the context, if there is one.
+func Seal(ctx context.Context, data []byte) ([]byte, error) {
+	if e := FromContext(ctx); e != nil {
+		return e.Seal(ctx, data)
+	}
+	return data, nil
+}
+
+type envelopeKey struct{}
+
+func WithEnvelope(ctx context.Context, e *Envelope) context.Context {
+	if e == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, envelopeKey{}, e)
+}
+
+// FromContext returns the envelope of the context, or of its MCP session.
+// It returns nil if session data isn't encrypted.
+func FromContext(ctx context.Context) *Envelope {
+	if e, ok := ctx.Value(envelopeKey{}).(*Envelope); ok {
+		return e
+	}
+	var e *Envelope
+	if session := mcp.SessionFromContext(ctx); session != nil && session.Get(SessionKey, &e) {
+		return e
+	}
+	return nil
+}
+
+func seal(key, data []byte) ([]byte, []byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+	return nonce, gcm.Seal(nil, nonce, data, nil), nil
+}
+
+func open(key, nonce, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce size %d", len(nonce))
+	}
+	plaintext, err := gcm.Open(nil, nonce, data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data: %w", err)
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,113 @@
+package encryption
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSealOpen(t *testing.T) {
+	ctx := context.Background()
+	key, err := NewLocalKey("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := New(key)
+
+	sealed, err := e.Seal(ctx, []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsSealed(sealed) || strings.Contains(string(sealed), "hello") {
+		t.Fatalf("expected sealed data, got %s", sealed)
+	}
+	if !json.Valid(sealed) {
+		t.Fatalf("expected sealed data to be JSON, got %s", sealed)
+	}
+
+	// A new envelope with the same secret, like after a restart.
+	key, _ = NewLocalKey("secret")
+	plaintext, err := Open(WithEnvelope(ctx, New(key)), sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(plaintext) != "hello" {
+		t.Fatalf("expected hello, got %q", plaintext)
+	}
+
+	other, _ := NewLocalKey("other")
+	if _, err := New(other).Open(ctx, sealed); err == nil {
+		t.Fatal("expected opening with another key to fail")
+	}
+
+	if _, err := Open(ctx, sealed); !errors.Is(err, ErrNoKey) {
+		t.Fatalf("expected ErrNoKey, got %v", err)
+	}
+
+	plaintext, err = Open(ctx, []byte(`{"plain":true}`))
+	if err != nil || string(plaintext) != `{"plain":true}` {
+		t.Fatalf("expected plaintext to be returned as is, got %q, %v", plaintext, err)
+	}
+}
+
+func TestVaultTransit(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		switch r.URL.Path {
+		case "/v1/transit/encrypt/nanobot":
+			_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]string{"ciphertext": "vault:v1:" + body["plaintext"]}})
+		case "/v1/transit/decrypt/nanobot":
+			_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]string{"plaintext": strings.TrimPrefix(body["ciphertext"], "vault:v1:")}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	if _, err := NewVaultTransit(srv.URL+"/v1/transit/nanobot", "token"); err == nil {
+		t.Fatal("expected an invalid key URL to fail")
+	}
+
+	ctx := context.Background()
+	kms, err := NewVaultTransit(srv.URL+"/v1/transit/keys/nanobot", "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := New(kms)
+	for range 2 {
+		if _, err := e.Seal(ctx, []byte("hello")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sealed, _ := e.Seal(ctx, []byte("hello"))
+	if calls != 1 {
+		t.Fatalf("expected the data key to be wrapped once, got %d calls", calls)
+	}
+
+	plaintext, err := New(kms).Open(ctx, sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(plaintext) != "hello" || calls != 2 {
+		t.Fatalf("expected hello after unwrapping once, got %q after %d calls", plaintext, calls)
+	}
+
+	var value sealedValue
+	_ = json.Unmarshal(sealed, &value)
+	if _, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(string(value.Sealed.Key), "vault:v1:")); err != nil {
+		t.Fatalf("expected the wrapped key to come from vault, got %q", value.Sealed.Key)
+	}
+}
@@ -0,0 +1,138 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// LocalKey wraps data keys with a key derived from a secret, such as the
+// encryption key of the auth settings.
+type LocalKey struct {
+	key []byte
+}
+
+func NewLocalKey(secret string) (*LocalKey, error) {
+	secret = strings.TrimSpace(secret)
+	if secret == "" {
+		return nil, errors.New("encryption key is empty")
+	}
+	hash := sha256.Sum256([]byte(secret))
+	return &LocalKey{key: hash[:]}, nil
+}
+
+func (l *LocalKey) WrapKey(_ context.Context, key []byte) ([]byte, error) {
+	nonce, ciphertext, err := seal(l.key, key)
+	if err != nil {
+		return nil, err
+	}
+	return append(nonce, ciphertext...), nil
+}
+
+func (l *LocalKey) UnwrapKey(_ context.Context, wrapped []byte) ([]byte, error) {
+	gcm, err := newGCM(l.key)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < gcm.NonceSize() {
+		return nil, errors.New("wrapped key is too short")
+	}
+	return open(l.key, wrapped[:gcm.NonceSize()], wrapped[gcm.NonceSize():])
+}
+
+// VaultTransit wraps data keys with a key of the transit secrets engine of
+// HashiCorp Vault, or OpenBao, so that the key encryption key never leaves
+// the KMS.
+type VaultTransit struct {
+	encryptURL string
+	decryptURL string
+	token      string
+	client     *http.Client
+}
+
+// NewVaultTransit returns a wrapper for the transit key at keyURL, such as
+// https://vault:8200/v1/transit/keys/nanobot. The token defaults to
+// $VAULT_TOKEN.
+func NewVaultTransit(keyURL, token string) (*VaultTransit, error) {
+	base, name, ok := strings.Cut(keyURL, "/keys/")
+	if !ok || name == "" || strings.Contains(name, "/") {
+		return nil, fmt.Errorf("invalid transit key URL %q, expected <vault>/v1/<mount>/keys/<name>", keyURL)
+	}
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+	if token == "" {
+		return nil, errors.New("a Vault token is required to use a transit key, set VAULT_TOKEN")
+	}
+	return &VaultTransit{
+		encryptURL: base + "/encrypt/" + name,
+		decryptURL: base + "/decrypt/" + name,
+		token:      token,
+		client:     http.DefaultClient,
+	}, nil
+}
+
+func (v *VaultTransit) WrapKey(ctx context.Context, key []byte) ([]byte, error) {
+	var resp struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	if err := v.post(ctx, v.encryptURL, map[string]string{
+		"plaintext": base64.StdEncoding.EncodeToString(key),
+	}, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Data.Ciphertext == "" {
+		return nil, errors.New("vault returned no ciphertext")
+	}
+	return []byte(resp.Data.Ciphertext), nil
+}
+
+func (v *VaultTransit) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	var resp struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+	if err := v.post(ctx, v.decryptURL, map[string]string{
+		"ciphertext": string(wrapped),
+	}, &resp); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(resp.Data.Plaintext)
+}
+
+func (v *VaultTransit) post(ctx context.Context, url string, body, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Vault-Token", v.token)
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("vault returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -79,6 +79,7 @@ func translate(ctx context.Context, env map[string]string, data map[string]any,
 			}
 			result[key] = res
 		}
+		return result, nil
 	case string:
 		return evalString(ctx, env, data, expr)
 	}
@@ -0,0 +1,27 @@
+package expr
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEvalObjectMap(t *testing.T) {
+	data := map[string]any{"name": "nanobot", "count": 2}
+	got, err := EvalObject(t.Context(), nil, data, map[string]any{
+		"greeting": "hello ${name}",
+		"count":    "${count}",
+		"nested":   map[string]any{"items": []any{"${name}", 3}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]any{
+		"greeting": "hello nanobot",
+		"count":    int64(2),
+		"nested":   map[string]any{"items": []any{"nanobot", 3}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("EvalObject() = %#v, want %#v", got, want)
+	}
+}
@@ -1,8 +1,10 @@
 package fswatch
 
 import (
+	"context"
 	"os"
 	"path/filepath"
+	"slices"
 	"sync"
 	"testing"
 	"time"
@@ -199,3 +201,134 @@ func TestSubscriptionManager(t *testing.T) {
 
 	// Test passes if no panics occur
 }
+
+func TestWatcherNewNestedDirectories(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	var (
+		mu     sync.Mutex
+		events []Event
+	)
+	watcher := NewWatcher(tmpDir, UnlimitedDepth, nil, func(evts []Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, evts...)
+	})
+	if err := watcher.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Close()
+
+	// Files written right after their directories are created may land
+	// before the directories are watched.
+	nested := filepath.Join(tmpDir, "a", "b", "c", "d")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(nested, "early.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if err := os.WriteFile(filepath.Join(nested, "late.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mu.Lock()
+		var early, late bool
+		for _, evt := range events {
+			early = early || evt.Path == filepath.Join("a", "b", "c", "d", "early.txt")
+			late = late || evt.Path == filepath.Join("a", "b", "c", "d", "late.txt")
+		}
+		got := slices.Clone(events)
+		mu.Unlock()
+		if early && late {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected events for both nested files, got %+v", got)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestWatcherCoalescesBursts(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	batches := make(chan []Event, 10)
+	watcher := NewWatcher(tmpDir, 0, nil, func(evts []Event) {
+		batches <- evts
+	})
+	if err := watcher.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Close()
+
+	path := filepath.Join(tmpDir, "burst.txt")
+	for i := range 20 {
+		if err := os.WriteFile(path, []byte{byte(i)}, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A file that comes and goes within the burst isn't reported.
+	if err := os.WriteFile(filepath.Join(tmpDir, "tmp.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(tmpDir, "tmp.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case batch := <-batches:
+		if len(batch) != 1 || batch[0] != (Event{Path: "burst.txt", Type: EventCreate}) {
+			t.Errorf("expected one create event for the burst, got %+v", batch)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the burst")
+	}
+}
+
+func TestWatcherPolling(t *testing.T) {
+	oldInterval := pollInterval
+	pollInterval = 50 * time.Millisecond
+	defer func() { pollInterval = oldInterval }()
+
+	tmpDir := t.TempDir()
+	existing := filepath.Join(tmpDir, "sub", "existing.txt")
+	if err := os.MkdirAll(filepath.Dir(existing), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(existing, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	batches := make(chan []Event, 10)
+	watcher := NewWatcher(tmpDir, UnlimitedDepth, nil, func(evts []Event) {
+		batches <- evts
+	})
+	watcher.ctx, watcher.cancel = context.WithCancel(t.Context())
+	defer watcher.Close()
+	go watcher.pollLoop()
+	time.Sleep(20 * time.Millisecond)
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "sub", "new.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(existing); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case batch := <-batches:
+		want := []Event{
+			{Path: filepath.Join("sub", "existing.txt"), Type: EventDelete},
+			{Path: filepath.Join("sub", "new.txt"), Type: EventCreate},
+		}
+		if !slices.Equal(batch, want) {
+			t.Errorf("expected %+v, got %+v", want, batch)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for polled events")
+	}
+}
@@ -0,0 +1,252 @@
+package fswatch
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultReconcileInterval is how long an Index trusts watcher events before
+// rescanning the directory to pick up anything the events missed.
+const DefaultReconcileInterval = time.Minute
+
+// FileEntry describes a file found under a walked or indexed directory.
+type FileEntry struct {
+	// Path is relative to the root directory.
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// Walk lists the files under rootDir, descending into directories up to
+// maxDepth levels below the root (so files at depth maxDepth+1 are included),
+// or every level for UnlimitedDepth.
+// Directories are read concurrently by at most workers goroutines; workers <= 0
+// uses GOMAXPROCS. Entries rejected by filter are skipped, and rejected
+// directories are not descended into. Unreadable entries are ignored. The
+// result is sorted by path.
+func Walk(rootDir string, maxDepth int, filter FilterFunc, workers int) ([]FileEntry, error) {
+	if _, err := os.Stat(rootDir); err != nil {
+		return nil, err
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		sem     = make(chan struct{}, workers)
+		entries []FileEntry
+		walkDir func(rel string, depth int)
+	)
+
+	walkDir = func(rel string, depth int) {
+		defer wg.Done()
+
+		sem <- struct{}{}
+		dirEntries, err := os.ReadDir(filepath.Join(rootDir, rel))
+		if err != nil {
+			<-sem
+			return
+		}
+
+		var (
+			files   []FileEntry
+			subDirs []string
+		)
+		for _, d := range dirEntries {
+			relPath := filepath.Join(rel, d.Name())
+			info, err := d.Info()
+			if err != nil {
+				continue
+			}
+			if filter != nil && !filter(relPath, info) {
+				continue
+			}
+			if d.IsDir() {
+				if withinDepth(depth+1, maxDepth) {
+					subDirs = append(subDirs, relPath)
+				}
+				continue
+			}
+			files = append(files, FileEntry{
+				Path:    relPath,
+				Size:    info.Size(),
+				ModTime: info.ModTime(),
+			})
+		}
+		<-sem
+
+		mu.Lock()
+		entries = append(entries, files...)
+		mu.Unlock()
+
+		// Children acquire the semaphore themselves, so a directory never holds
+		// a worker slot while waiting on its subdirectories.
+		for _, subDir := range subDirs {
+			wg.Add(1)
+			go walkDir(subDir, depth+1)
+		}
+	}
+
+	wg.Add(1)
+	walkDir("", 0)
+	wg.Wait()
+
+	slices.SortFunc(entries, func(a, b FileEntry) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return entries, nil
+}
+
+// Index is an in-memory listing of the files under a directory. It is kept
+// current by feeding it the events of a Watcher on the same directory via
+// Apply, and is rebuilt with Walk when it is first used, after a new
+// directory appears, and every reconcile interval.
+type Index struct {
+	rootDir           string
+	maxDepth          int
+	filter            FilterFunc
+	reconcileInterval time.Duration
+
+	mu       sync.Mutex
+	files    map[string]FileEntry
+	scanned  time.Time
+	stale    bool
+	scanning chan struct{}
+	// pending holds events received during a scan, replayed once it completes
+	// since the scan may have read the directory before they happened.
+	pending []Event
+}
+
+// NewIndex creates an Index. The rootDir, maxDepth, and filter should match
+// those of the Watcher whose events are applied to it.
+func NewIndex(rootDir string, maxDepth int, filter FilterFunc) *Index {
+	return &Index{
+		rootDir:           rootDir,
+		maxDepth:          maxDepth,
+		filter:            filter,
+		reconcileInterval: DefaultReconcileInterval,
+	}
+}
+
+// Apply updates the index from watcher events.
+func (i *Index) Apply(events []Event) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.scanning != nil {
+		i.pending = append(i.pending, events...)
+		return
+	}
+	i.applyLocked(events)
+}
+
+func (i *Index) applyLocked(events []Event) {
+	if i.files == nil {
+		// Cold; the first Files call does a full scan anyway.
+		return
+	}
+
+	for _, event := range events {
+		switch event.Type {
+		case EventDelete:
+			delete(i.files, event.Path)
+			// The path may have been a directory, drop everything beneath it.
+			prefix := event.Path + string(filepath.Separator)
+			for path := range i.files {
+				if strings.HasPrefix(path, prefix) {
+					delete(i.files, path)
+				}
+			}
+		case EventCreate, EventWrite:
+			info, err := os.Stat(filepath.Join(i.rootDir, event.Path))
+			if err != nil {
+				delete(i.files, event.Path)
+				continue
+			}
+			if info.IsDir() {
+				// Files may have been written into the directory before it was
+				// watched, so rescan rather than trust the events.
+				i.stale = true
+				continue
+			}
+			i.files[event.Path] = FileEntry{
+				Path:    event.Path,
+				Size:    info.Size(),
+				ModTime: info.ModTime(),
+			}
+		}
+	}
+}
+
+// Invalidate forces the next Files call to rescan the directory.
+func (i *Index) Invalidate() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.stale = true
+}
+
+// Files returns the indexed files sorted by path, scanning the directory
+// first if the index is cold, stale, or due for reconciliation.
+func (i *Index) Files() ([]FileEntry, error) {
+	for {
+		i.mu.Lock()
+		if i.files != nil && !i.stale && time.Since(i.scanned) < i.reconcileInterval {
+			entries := make([]FileEntry, 0, len(i.files))
+			for _, entry := range i.files {
+				entries = append(entries, entry)
+			}
+			i.mu.Unlock()
+			slices.SortFunc(entries, func(a, b FileEntry) int {
+				return strings.Compare(a.Path, b.Path)
+			})
+			return entries, nil
+		}
+
+		if i.scanning != nil {
+			// Another caller is already scanning, wait for it and re-check.
+			done := i.scanning
+			i.mu.Unlock()
+			<-done
+			continue
+		}
+
+		done := make(chan struct{})
+		i.scanning = done
+		i.stale = false
+		i.mu.Unlock()
+
+		started := time.Now()
+		entries, err := Walk(i.rootDir, i.maxDepth, i.filter, 0)
+
+		i.mu.Lock()
+		i.scanning = nil
+		pending := i.pending
+		i.pending = nil
+		close(done)
+		if err != nil {
+			i.mu.Unlock()
+			if os.IsNotExist(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		i.files = make(map[string]FileEntry, len(entries))
+		for _, entry := range entries {
+			i.files[entry.Path] = entry
+		}
+		i.scanned = started
+		if len(pending) == 0 {
+			i.mu.Unlock()
+			return entries, nil
+		}
+		i.applyLocked(pending)
+		i.mu.Unlock()
+	}
+}
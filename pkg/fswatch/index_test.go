@@ -0,0 +1,101 @@
+package fswatch
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func writeFiles(t *testing.T, root string, files ...string) {
+	t.Helper()
+	for _, f := range files {
+		path := filepath.Join(root, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(f), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func entryPaths(entries []FileEntry) []string {
+	var paths []string
+	for _, e := range entries {
+		paths = append(paths, filepath.ToSlash(e.Path))
+	}
+	return paths
+}
+
+func TestWalk(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, "a.txt", "sub/b.txt", "sub/deep/c.txt", "sub/deep/deeper/d.txt", "skip/e.txt")
+
+	filter := func(relPath string, info os.FileInfo) bool {
+		return filepath.Base(relPath) != "skip"
+	}
+
+	for _, workers := range []int{1, 4} {
+		entries, err := Walk(root, 2, filter, workers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"a.txt", "sub/b.txt", "sub/deep/c.txt"}
+		if got := entryPaths(entries); !slices.Equal(got, want) {
+			t.Errorf("workers=%d: expected %v, got %v", workers, want, got)
+		}
+	}
+
+	if _, err := Walk(filepath.Join(root, "missing"), 2, nil, 0); !os.IsNotExist(err) {
+		t.Errorf("expected not exist error, got %v", err)
+	}
+}
+
+func TestIndex(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, "a.txt", "sub/b.txt")
+
+	index := NewIndex(root, 2, nil)
+	entries, err := index.Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := entryPaths(entries); !slices.Equal(got, []string{"a.txt", "sub/b.txt"}) {
+		t.Fatalf("unexpected initial scan: %v", got)
+	}
+
+	// Changes are picked up from events without rescanning.
+	writeFiles(t, root, "c.txt")
+	if err := os.Remove(filepath.Join(root, "a.txt")); err != nil {
+		t.Fatal(err)
+	}
+	index.Apply([]Event{{Path: "c.txt", Type: EventCreate}, {Path: "a.txt", Type: EventDelete}})
+	entries, _ = index.Files()
+	if got := entryPaths(entries); !slices.Equal(got, []string{"c.txt", "sub/b.txt"}) {
+		t.Errorf("unexpected entries after events: %v", got)
+	}
+
+	// Deleting a directory drops everything beneath it.
+	index.Apply([]Event{{Path: "sub", Type: EventDelete}})
+	entries, _ = index.Files()
+	if got := entryPaths(entries); !slices.Equal(got, []string{"c.txt"}) {
+		t.Errorf("unexpected entries after directory delete: %v", got)
+	}
+
+	// A new directory marks the index stale so its contents are rescanned.
+	writeFiles(t, root, "new/f.txt")
+	index.Apply([]Event{{Path: "new", Type: EventCreate}})
+	entries, _ = index.Files()
+	if got := entryPaths(entries); !slices.Equal(got, []string{"c.txt", "new/f.txt", "sub/b.txt"}) {
+		t.Errorf("unexpected entries after rescan: %v", got)
+	}
+
+	// Reconciliation catches changes that were never reported.
+	writeFiles(t, root, "unreported.txt")
+	index.reconcileInterval = 0
+	entries, _ = index.Files()
+	if !slices.Contains(entryPaths(entries), "unreported.txt") {
+		t.Errorf("expected reconciliation to find unreported.txt, got %v", entryPaths(entries))
+	}
+}
@@ -96,8 +96,27 @@ func (sm *SubscriptionManager) Unsubscribe(sessionID string, uri string) {
 	}
 }
 
+// IsSubscribed returns true if any session is subscribed to the given URI.
+func (sm *SubscriptionManager) IsSubscribed(uri string) bool {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	for _, sub := range sm.subscriptions {
+		if _, ok := sub.uris[uri]; ok {
+			return true
+		}
+	}
+	return false
+}
+
 // SendResourceUpdatedNotification sends a notifications/resources/updated message to sessions subscribed to the given URI.
 func (sm *SubscriptionManager) SendResourceUpdatedNotification(uri string) {
+	sm.SendResourceUpdatedNotificationWithMeta(uri, nil)
+}
+
+// SendResourceUpdatedNotificationWithMeta sends a notifications/resources/updated message with the given _meta,
+// such as a description of the change, to sessions subscribed to the given URI.
+func (sm *SubscriptionManager) SendResourceUpdatedNotificationWithMeta(uri string, meta map[string]any) {
 	sm.mu.RLock()
 	var sessionsToNotify []*mcp.Session
 	for _, sub := range sm.subscriptions {
@@ -112,10 +131,9 @@ func (sm *SubscriptionManager) SendResourceUpdatedNotification(uri string) {
 		Method:  "notifications/resources/updated",
 	}
 
-	params := struct {
-		URI string `json:"uri"`
-	}{
-		URI: uri,
+	params := mcp.ResourceUpdatedNotification{
+		Meta: meta,
+		URI:  uri,
 	}
 
 	paramsBytes, err := json.Marshal(params)
@@ -2,10 +2,13 @@ package fswatch
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"log/slog"
@@ -13,7 +16,21 @@ import (
 	"github.com/fsnotify/fsnotify"
 )
 
-const debounceTimeout = 100 * time.Millisecond
+const (
+	// debounceTimeout is how long the events of a watcher must be quiet before
+	// they are reported, so a burst of changes is reported as one batch.
+	debounceTimeout = 100 * time.Millisecond
+	// maxDebounceDelay bounds how long a steady stream of events delays them.
+	maxDebounceDelay = time.Second
+)
+
+// pollInterval is how often a watcher that fell back to polling rescans its
+// directory tree.
+var pollInterval = 2 * time.Second
+
+// UnlimitedDepth is the maximum depth of a watcher that watches every level of
+// subdirectories.
+const UnlimitedDepth = -1
 
 // mockFileInfo is a simple implementation of os.FileInfo for testing/filtering
 type mockFileInfo struct {
@@ -54,7 +71,10 @@ type FilterFunc func(relPath string, info os.FileInfo) bool
 // EventHandler is called when filesystem events occur after debouncing.
 type EventHandler func(events []Event)
 
-// Watcher watches a directory tree for file changes with configurable depth and filtering.
+// Watcher watches a directory tree for file changes with configurable depth and
+// filtering. Directories created under the tree are watched as they appear.
+// Where the platform runs out of watches, as with the inotify limits of Linux,
+// the watcher falls back to polling the tree.
 type Watcher struct {
 	rootDir   string
 	maxDepth  int
@@ -67,13 +87,20 @@ type Watcher struct {
 	mu        sync.Mutex
 	initErr   error
 	watchDirs map[string]struct{} // currently watched directories
+
+	// pending holds the events not reported yet, by path, coalesced until
+	// they are quiet for debounceTimeout.
+	pendingMu    sync.Mutex
+	pending      map[string]EventType
+	firstPending time.Time
+	lastPending  time.Time
 }
 
 // NewWatcher creates a new Watcher.
 //
 // Parameters:
 //   - rootDir: the root directory to watch
-//   - maxDepth: maximum depth of subdirectories to watch (0 = rootDir only, 1 = one level of children, etc.)
+//   - maxDepth: maximum depth of subdirectories to watch (0 = rootDir only, 1 = one level of children, etc., UnlimitedDepth = all)
 //   - filter: a function to decide which files/directories to include (nil means include all)
 //   - handler: callback for filesystem events
 func NewWatcher(rootDir string, maxDepth int, filter FilterFunc, handler EventHandler) *Watcher {
@@ -83,6 +110,7 @@ func NewWatcher(rootDir string, maxDepth int, filter FilterFunc, handler EventHa
 		filter:    filter,
 		handler:   handler,
 		watchDirs: make(map[string]struct{}),
+		pending:   make(map[string]EventType),
 	}
 }
 
@@ -90,23 +118,26 @@ func NewWatcher(rootDir string, maxDepth int, filter FilterFunc, handler EventHa
 // only the first call will start watching.
 func (w *Watcher) Start() error {
 	w.once.Do(func() {
-		watcher, err := fsnotify.NewWatcher()
-		if err != nil {
-			w.initErr = err
-			return
-		}
-
-		w.watcher = watcher
 		w.ctx, w.cancel = context.WithCancel(context.Background())
 
-		// Walk directory tree and add watches
-		if err := w.addWatchRecursive(w.rootDir, 0); err != nil {
+		watcher, err := fsnotify.NewWatcher()
+		if err == nil {
+			w.watcher = watcher
+			// Walk directory tree and add watches
+			if err = w.addWatchRecursive(w.rootDir, 0, false); err == nil {
+				go w.watchLoop()
+				return
+			}
 			watcher.Close()
+			w.watcher = nil
+		}
+
+		if !isWatchLimit(err) {
 			w.initErr = err
 			return
 		}
-
-		go w.watchLoop()
+		slog.Warn("file watch limit reached, polling for changes", "path", w.rootDir, "error", err)
+		go w.pollLoop()
 	})
 
 	return w.initErr
@@ -126,9 +157,23 @@ func (w *Watcher) Close() error {
 	return nil
 }
 
+// isWatchLimit indicates if an error is the platform running out of watches
+// or of watcher instances.
+func isWatchLimit(err error) bool {
+	return errors.Is(err, syscall.ENOSPC) || errors.Is(err, syscall.EMFILE)
+}
+
+// withinDepth indicates if a directory at depth is shallow enough to be
+// watched or walked.
+func withinDepth(depth, maxDepth int) bool {
+	return maxDepth < 0 || depth <= maxDepth
+}
+
 // addWatchRecursive adds watches for the given directory and its subdirectories up to maxDepth.
-func (w *Watcher) addWatchRecursive(dir string, currentDepth int) error {
-	if currentDepth > w.maxDepth {
+// When report is set, the directory is new and the files already in it are
+// reported as created, since they may have been written before it was watched.
+func (w *Watcher) addWatchRecursive(dir string, currentDepth int, report bool) error {
+	if !withinDepth(currentDepth, w.maxDepth) {
 		return nil
 	}
 
@@ -162,26 +207,53 @@ func (w *Watcher) addWatchRecursive(dir string, currentDepth int) error {
 	w.watchDirs[dir] = struct{}{}
 	w.mu.Unlock()
 
-	// Walk subdirectories
-	if currentDepth < w.maxDepth {
-		entries, err := os.ReadDir(dir)
-		if err != nil {
-			return nil // Ignore errors reading directory contents
-		}
-		for _, entry := range entries {
-			if entry.IsDir() {
-				subDir := filepath.Join(dir, entry.Name())
-				if err := w.addWatchRecursive(subDir, currentDepth+1); err != nil {
-					// Log but continue - don't fail the whole watch setup for one subdir
-					slog.Error("failed to watch subdirectory", "path", subDir, "error", err)
-				}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil // Ignore errors reading directory contents
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if !entry.IsDir() {
+			if report {
+				w.queueFile(path, EventCreate)
 			}
+			continue
+		}
+		if !withinDepth(currentDepth+1, w.maxDepth) {
+			continue
+		}
+		if err := w.addWatchRecursive(path, currentDepth+1, report); isWatchLimit(err) {
+			return err
+		} else if err != nil {
+			// Log but continue - don't fail the whole watch setup for one subdir
+			slog.Error("failed to watch subdirectory", "path", path, "error", err)
 		}
 	}
 
 	return nil
 }
 
+// unwatchDir stops watching a directory that was removed or renamed, and the
+// directories under it, and indicates if it was watched. A renamed directory
+// keeps its watch on some platforms, which would report events under its old
+// path.
+func (w *Watcher) unwatchDir(dir string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, ok := w.watchDirs[dir]; !ok {
+		return false
+	}
+	prefix := dir + string(filepath.Separator)
+	for watched := range w.watchDirs {
+		if watched == dir || strings.HasPrefix(watched, prefix) {
+			_ = w.watcher.Remove(watched)
+			delete(w.watchDirs, watched)
+		}
+	}
+	return true
+}
+
 // depthOf returns the depth of a path relative to the root directory.
 func (w *Watcher) depthOf(path string) int {
 	relPath, err := filepath.Rel(w.rootDir, path)
@@ -194,18 +266,80 @@ func (w *Watcher) depthOf(path string) int {
 	return len(strings.Split(relPath, string(filepath.Separator)))
 }
 
-// watchLoop processes file system events and sends notifications.
-func (w *Watcher) watchLoop() {
-	// Debounce map: filename -> event
-	type pendingEvent struct {
-		path      string
-		eventType EventType
-		time      time.Time
+// queueFile queues an event of a file if the filter accepts it.
+func (w *Watcher) queueFile(path string, eventType EventType) {
+	relPath, err := filepath.Rel(w.rootDir, path)
+	if err != nil {
+		return
+	}
+	if w.filter != nil {
+		info, err := os.Stat(path)
+		if err != nil {
+			// For deleted files, create a mock fileinfo that assumes it's a file
+			// We check if any parent directory component should be excluded
+			info = &mockFileInfo{isDir: false}
+		}
+		if !w.filter(relPath, info) {
+			return
+		}
+	}
+	w.queue(relPath, eventType)
+}
+
+// queue adds an event to the pending events, coalesced with an event of the
+// same path that is still pending.
+func (w *Watcher) queue(relPath string, eventType EventType) {
+	w.pendingMu.Lock()
+	defer w.pendingMu.Unlock()
+
+	now := time.Now()
+	if len(w.pending) == 0 {
+		w.firstPending = now
+	}
+	w.lastPending = now
+
+	prev, ok := w.pending[relPath]
+	switch {
+	case !ok:
+		w.pending[relPath] = eventType
+	case prev == EventCreate && eventType == EventDelete:
+		// The file came and went before anyone heard of it.
+		delete(w.pending, relPath)
+	case prev == EventCreate:
+		// Writes to a new file are part of its creation.
+	case prev == EventDelete && eventType == EventCreate:
+		// The file was replaced.
+		w.pending[relPath] = EventWrite
+	default:
+		w.pending[relPath] = eventType
 	}
-	pending := make(map[string]*pendingEvent)
-	pendingMu := sync.Mutex{}
+}
+
+// flush reports the pending events once they are quiet, or right away if
+// force is set.
+func (w *Watcher) flush(force bool) {
+	w.pendingMu.Lock()
+	now := time.Now()
+	if len(w.pending) == 0 || !force && now.Sub(w.lastPending) < debounceTimeout && now.Sub(w.firstPending) < maxDebounceDelay {
+		w.pendingMu.Unlock()
+		return
+	}
+	events := make([]Event, 0, len(w.pending))
+	for path, eventType := range w.pending {
+		events = append(events, Event{Path: path, Type: eventType})
+	}
+	clear(w.pending)
+	w.pendingMu.Unlock()
+
+	slices.SortFunc(events, func(a, b Event) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	w.handler(events)
+}
 
-	ticker := time.NewTicker(debounceTimeout)
+// watchLoop processes file system events and sends notifications.
+func (w *Watcher) watchLoop() {
+	ticker := time.NewTicker(debounceTimeout / 4)
 	defer ticker.Stop()
 
 	for {
@@ -217,108 +351,131 @@ func (w *Watcher) watchLoop() {
 			if !ok {
 				return
 			}
-
-			// Get relative path
-			relPath, err := filepath.Rel(w.rootDir, event.Name)
-			if err != nil {
-				continue
+			if err := w.handleEvent(event); err != nil {
+				slog.Warn("file watch limit reached, polling for changes", "path", w.rootDir, "error", err)
+				w.mu.Lock()
+				w.watcher.Close()
+				w.watcher = nil
+				w.mu.Unlock()
+				w.flush(true)
+				w.pollLoop()
+				return
 			}
 
-			// Check if this is a directory event (for managing watches)
-			info, statErr := os.Stat(event.Name)
-
-			// Handle new directories: add them to the watcher if within depth
-			if statErr == nil && info.IsDir() && event.Op.Has(fsnotify.Create) {
-				depth := w.depthOf(event.Name)
-				if depth >= 0 && depth <= w.maxDepth {
-					if w.filter == nil || w.filter(relPath, info) {
-						if err := w.addWatchRecursive(event.Name, depth); err != nil {
-							slog.Error("failed to watch new directory", "path", event.Name, "error", err)
-						}
-						// Send list_changed since new directory means new potential files
-						w.handler([]Event{{Path: relPath, Type: EventCreate}})
-					}
-				}
-				continue
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
 			}
+			slog.Error("file watcher error", "error", err)
 
-			// Skip if directory (we only report file events)
-			if statErr == nil && info.IsDir() {
-				continue
-			}
+		case <-ticker.C:
+			w.flush(false)
+		}
+	}
+}
 
-			// Apply filter for files
-			if w.filter != nil {
-				// For deleted files, we can't stat them, so we need to infer if it's a file
-				if statErr != nil {
-					if event.Op.Has(fsnotify.Remove | fsnotify.Rename) {
-						// For deleted files, create a mock fileinfo that assumes it's a file
-						// We check if any parent directory component should be excluded
-						mockInfo := &mockFileInfo{isDir: false}
-						if !w.filter(relPath, mockInfo) {
-							continue
-						}
-					} else {
-						continue
-					}
-				} else if !w.filter(relPath, info) {
-					continue
-				}
-			}
+// handleEvent queues the events of a filesystem change, and watches the
+// directories it creates. It fails only when the platform runs out of
+// watches.
+func (w *Watcher) handleEvent(event fsnotify.Event) error {
+	relPath, err := filepath.Rel(w.rootDir, event.Name)
+	if err != nil {
+		return nil
+	}
 
-			if !event.Op.Has(fsnotify.Write | fsnotify.Create | fsnotify.Remove | fsnotify.Rename) {
-				continue
-			}
+	// Check if this is a directory event (for managing watches)
+	info, statErr := os.Stat(event.Name)
 
-			// Determine event type
-			var evType EventType
-			if event.Op.Has(fsnotify.Remove | fsnotify.Rename) {
-				evType = EventDelete
-			} else if event.Op.Has(fsnotify.Create) {
-				evType = EventCreate
-			} else {
-				evType = EventWrite
-			}
+	if event.Has(fsnotify.Remove | fsnotify.Rename) {
+		// A removed or renamed directory is reported as deleted, with its
+		// files. Its new name, if any, is reported as a created directory.
+		if w.unwatchDir(event.Name) {
+			w.queue(relPath, EventDelete)
+		} else if statErr != nil || !info.IsDir() {
+			w.queueFile(event.Name, EventDelete)
+		}
+		return nil
+	}
 
-			// Remove and create events are handled immediately
-			if evType == EventDelete || evType == EventCreate {
-				w.handler([]Event{{Path: relPath, Type: evType}})
-				pendingMu.Lock()
-				delete(pending, event.Name)
-				pendingMu.Unlock()
-			} else {
-				// Debounce write events
-				pendingMu.Lock()
-				pending[event.Name] = &pendingEvent{
-					path:      relPath,
-					eventType: evType,
-					time:      time.Now(),
-				}
-				pendingMu.Unlock()
-			}
+	if statErr != nil {
+		if event.Has(fsnotify.Create) {
+			// The file is already gone, its create cancels out with the
+			// delete that follows.
+			w.queueFile(event.Name, EventCreate)
+		}
+		return nil
+	}
 
-		case err, ok := <-w.watcher.Errors:
-			if !ok {
-				return
-			}
-			slog.Error("file watcher error", "error", err)
+	// Handle new directories: add them to the watcher if within depth
+	if info.IsDir() {
+		if !event.Has(fsnotify.Create) {
+			return nil
+		}
+		depth := w.depthOf(event.Name)
+		if depth < 0 || !withinDepth(depth, w.maxDepth) || w.filter != nil && !w.filter(relPath, info) {
+			return nil
+		}
+		if err := w.addWatchRecursive(event.Name, depth, true); isWatchLimit(err) {
+			return err
+		} else if err != nil {
+			slog.Error("failed to watch new directory", "path", event.Name, "error", err)
+		}
+		// Report the directory since it means new potential files
+		w.queue(relPath, EventCreate)
+		return nil
+	}
+
+	switch {
+	case event.Has(fsnotify.Create):
+		w.queueFile(event.Name, EventCreate)
+	case event.Has(fsnotify.Write | fsnotify.Chmod):
+		// A change of permissions can change what reading the file gives, so
+		// it is reported as a write.
+		w.queueFile(event.Name, EventWrite)
+	}
+	return nil
+}
+
+// pollLoop reports the changes of the directory tree by rescanning it every
+// pollInterval, for when the platform can't watch it.
+func (w *Watcher) pollLoop() {
+	files := w.scan()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
 
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
 		case <-ticker.C:
-			// Process debounced events
-			now := time.Now()
-			var events []Event
-			pendingMu.Lock()
-			for name, pe := range pending {
-				if now.Sub(pe.time) >= debounceTimeout {
-					events = append(events, Event{Path: pe.path, Type: pe.eventType})
-					delete(pending, name)
+			next := w.scan()
+			for path, entry := range next {
+				if prev, ok := files[path]; !ok {
+					w.queue(path, EventCreate)
+				} else if prev.Size != entry.Size || !prev.ModTime.Equal(entry.ModTime) {
+					w.queue(path, EventWrite)
 				}
 			}
-			pendingMu.Unlock()
-
-			if len(events) > 0 {
-				w.handler(events)
+			for path := range files {
+				if _, ok := next[path]; !ok {
+					w.queue(path, EventDelete)
+				}
 			}
+			files = next
+			w.flush(true)
 		}
 	}
 }
+
+// scan lists the files of the directory tree, by path.
+func (w *Watcher) scan() map[string]FileEntry {
+	entries, err := Walk(w.rootDir, w.maxDepth, w.filter, 0)
+	if err != nil && !os.IsNotExist(err) {
+		slog.Error("failed to scan watched directory", "path", w.rootDir, "error", err)
+	}
+	files := make(map[string]FileEntry, len(entries))
+	for _, entry := range entries {
+		files[entry.Path] = entry
+	}
+	return files
+}
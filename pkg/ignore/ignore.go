@@ -0,0 +1,262 @@
+// Package ignore decides which files under a directory are ignored, by
+// gitignore-style patterns: the defaults of nanobot, and the .gitignore and
+// .nanobotignore files of the directory and its subdirectories.
+package ignore
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NanobotFile is the name of the ignore file specific to nanobot. Its
+// patterns take precedence over those of .gitignore, so it can also include
+// files git ignores.
+const NanobotFile = ".nanobotignore"
+
+// Files are the names of the ignore files read in each directory, in order of
+// precedence.
+var Files = []string{".gitignore", NanobotFile}
+
+// Defaults are the patterns of directories and files that aren't content,
+// ignored before any ignore file is applied.
+var Defaults = []string{
+	"node_modules/",
+	"vendor/",
+	"__pycache__/",
+	"dist/",
+	"build/",
+	"bin/",
+	".git/",
+	".svn/",
+	".jj/",
+	".vscode/",
+	".idea/",
+	".nanobot/",
+	"sessions/",
+	"nanobot.db",
+	"nanobot.db-journal",
+	".DS_Store",
+}
+
+// Matcher decides which paths under a root directory are ignored. Ignore files
+// are read as they are needed, and read again when they change.
+type Matcher struct {
+	root     string
+	defaults []rule
+
+	mu   sync.Mutex
+	dirs map[string]*dirRules
+}
+
+// dirRules are the rules of the ignore files of a directory.
+type dirRules struct {
+	rules    []rule
+	modTimes []time.Time
+}
+
+type rule struct {
+	pattern  []string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// New creates a Matcher of the root directory, that ignores the patterns
+// before those of the ignore files.
+func New(root string, patterns ...string) *Matcher {
+	return &Matcher{
+		root:     root,
+		defaults: parse(strings.Join(patterns, "\n")),
+		dirs:     map[string]*dirRules{},
+	}
+}
+
+// Filter indicates if a path relative to the root should be included, so the
+// Matcher can filter the walks and watches of fswatch.
+func (m *Matcher) Filter(relPath string, info os.FileInfo) bool {
+	return relPath == "." || !m.Ignored(relPath, info.IsDir())
+}
+
+// Ignored indicates if a path relative to the root is ignored. As in git, the
+// paths under an ignored directory are ignored too.
+func (m *Matcher) Ignored(relPath string, isDir bool) bool {
+	parts := strings.Split(filepath.ToSlash(filepath.Clean(relPath)), "/")
+	if len(parts) == 0 || parts[0] == "." || parts[0] == ".." {
+		return false
+	}
+
+	m.mu.Lock()
+	dirs := make([]*dirRules, len(parts))
+	for i := range parts {
+		dirs[i] = m.dirRules(parts[:i])
+	}
+	m.mu.Unlock()
+
+	for i := range parts {
+		if m.ignored(dirs[:i+1], parts[:i+1], isDir || i < len(parts)-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// ignored applies the defaults, then the ignore files of the directories
+// above the path, the last matching rule deciding.
+func (m *Matcher) ignored(dirs []*dirRules, parts []string, isDir bool) bool {
+	ignored := false
+	apply := func(rules []rule, rel []string) {
+		for _, r := range rules {
+			if r.matches(rel, isDir) {
+				ignored = !r.negate
+			}
+		}
+	}
+
+	apply(m.defaults, parts)
+	for i, dir := range dirs {
+		apply(dir.rules, parts[i:])
+	}
+	return ignored
+}
+
+// RipgrepIgnoreFile writes the patterns and the .nanobotignore of the root to
+// a temporary file, for the --ignore-file flag of ripgrep run in the root.
+// Ripgrep reads .gitignore files itself, but not those of nanobot. The caller
+// removes the file.
+func RipgrepIgnoreFile(root string, patterns ...string) (string, error) {
+	f, err := os.CreateTemp("", "nanobot-*.ignore")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	content := strings.Join(patterns, "\n") + "\n"
+	if data, err := os.ReadFile(filepath.Join(root, NanobotFile)); err == nil {
+		content += string(data)
+	}
+	if _, err := f.WriteString(content); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// dirRules returns the rules of the ignore files of a directory, reading them
+// again if they changed.
+func (m *Matcher) dirRules(dir []string) *dirRules {
+	key := strings.Join(dir, "/")
+	modTimes := make([]time.Time, len(Files))
+	for i, name := range Files {
+		if info, err := os.Stat(filepath.Join(m.root, filepath.FromSlash(key), name)); err == nil {
+			modTimes[i] = info.ModTime()
+		}
+	}
+
+	if cached, ok := m.dirs[key]; ok && equalTimes(cached.modTimes, modTimes) {
+		return cached
+	}
+
+	loaded := &dirRules{modTimes: modTimes}
+	for i, name := range Files {
+		if modTimes[i].IsZero() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(m.root, filepath.FromSlash(key), name))
+		if err == nil {
+			loaded.rules = append(loaded.rules, parse(string(data))...)
+		}
+	}
+	m.dirs[key] = loaded
+	return loaded
+}
+
+func equalTimes(a, b []time.Time) bool {
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return len(a) == len(b)
+}
+
+// parse parses gitignore-style patterns.
+func parse(data string) (rules []rule) {
+	scanner := bufio.NewScanner(bytes.NewBufferString(data))
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if !strings.HasSuffix(line, `\ `) {
+			line = strings.TrimRight(line, " \t")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var r rule
+		if strings.HasPrefix(line, "!") {
+			r.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			r.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		// A pattern with a slash, other than a trailing one, is relative to
+		// the directory of its ignore file. Others match at any level.
+		r.anchored = strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		if line == "" {
+			continue
+		}
+		r.pattern = strings.Split(line, "/")
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// matches indicates if the rule matches a path relative to the directory of
+// its ignore file.
+func (r rule) matches(rel []string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if !r.anchored {
+		return matchSegments(r.pattern, rel[len(rel)-1:])
+	}
+	return matchSegments(r.pattern, rel)
+}
+
+// matchSegments matches the segments of a path against those of a pattern,
+// where "**" matches any number of segments.
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			if len(pattern) == 1 {
+				// A trailing "**" matches everything inside, but not the
+				// directory itself.
+				return len(name) > 0
+			}
+			for i := range len(name) + 1 {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
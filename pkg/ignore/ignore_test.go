@@ -0,0 +1,111 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIgnored(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".gitignore"), strings.Join([]string{
+		"# comments and blank lines are skipped",
+		"",
+		"*.log",
+		"!keep.log",
+		"/out",
+		"docs/*.tmp",
+		"**/cache/",
+		"secrets/**",
+		`\#hash`,
+	}, "\n"))
+	writeFile(t, filepath.Join(root, NanobotFile), "!build/\nprivate.txt\n")
+	writeFile(t, filepath.Join(root, "src", ".gitignore"), "/generated\n*.bak\n")
+
+	m := New(root, Defaults...)
+	for _, test := range []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{path: "main.go"},
+		{path: "debug.log", ignored: true},
+		{path: "src/debug.log", ignored: true},
+		{path: "keep.log"},
+		{path: "out", isDir: true, ignored: true},
+		{path: "out/result.txt", ignored: true},
+		{path: "src/out", isDir: true},
+		{path: "docs/a.tmp", ignored: true},
+		{path: "docs/nested/a.tmp"},
+		{path: "a/b/cache", isDir: true, ignored: true},
+		{path: "a/b/cache", isDir: false},
+		{path: "secrets", isDir: true},
+		{path: "secrets/key", ignored: true},
+		{path: "#hash", ignored: true},
+		{path: "private.txt", ignored: true},
+		{path: "src/generated", isDir: true, ignored: true},
+		{path: "generated", isDir: true},
+		{path: "src/old.bak", ignored: true},
+		{path: "old.bak"},
+		{path: "node_modules/pkg/index.js", ignored: true},
+		{path: "project/.git", isDir: true, ignored: true},
+		{path: "nanobot.db", ignored: true},
+		// .nanobotignore takes precedence over the defaults.
+		{path: "build/output.txt"},
+	} {
+		if got := m.Ignored(test.path, test.isDir); got != test.ignored {
+			t.Errorf("Ignored(%q, isDir=%v) = %v, expected %v", test.path, test.isDir, got, test.ignored)
+		}
+	}
+}
+
+func TestIgnoredReloadsChangedFiles(t *testing.T) {
+	root := t.TempDir()
+	ignoreFile := filepath.Join(root, ".gitignore")
+	writeFile(t, ignoreFile, "*.txt\n")
+
+	m := New(root)
+	if !m.Ignored("a.txt", false) {
+		t.Fatal("expected a.txt to be ignored")
+	}
+
+	writeFile(t, ignoreFile, "*.md\n")
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(ignoreFile, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if m.Ignored("a.txt", false) || !m.Ignored("a.md", false) {
+		t.Error("expected the changed ignore file to be read again")
+	}
+}
+
+func TestRipgrepIgnoreFile(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, NanobotFile), "private.txt\n")
+
+	file, err := RipgrepIgnoreFile(root, "node_modules/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file)
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "node_modules/\nprivate.txt\n" {
+		t.Errorf("unexpected ignore file: %q", data)
+	}
+}
@@ -0,0 +1,257 @@
+// Package journal records the state of files before an agent turn changes
+// them, so that the whole turn can be undone later.
+package journal
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// mu serializes journal updates, tool calls in a turn run concurrently.
+var mu sync.Mutex
+
+// maxTurns is how many turns of a session are kept in its journal, so only
+// the last turns can be undone. Older turns are removed when a new turn
+// records its first file.
+var maxTurns = 20
+
+type entry struct {
+	Path    string      `json:"path"`
+	Existed bool        `json:"existed"`
+	Backup  string      `json:"backup,omitempty"`
+	Mode    fs.FileMode `json:"mode,omitempty"`
+}
+
+func turnDir(sessionID, turnID string) (string, error) {
+	if sessionID == "" || turnID == "" || strings.ContainsAny(turnID, `/\`) || turnID == "." || turnID == ".." {
+		return "", fmt.Errorf("invalid journal turn %q", turnID)
+	}
+	return filepath.Join(".nanobot", sessionID, "journal", turnID), nil
+}
+
+// Record saves the current state of path, a file that is about to be written
+// or deleted in the turn, unless the turn already recorded it. Directories
+// are recorded file by file. Nothing is recorded without a turn.
+func Record(sessionID, turnID, path string) error {
+	if sessionID == "" || turnID == "" {
+		return nil
+	}
+
+	dir, err := turnDir(sessionID, turnID)
+	if err != nil {
+		return err
+	}
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	entries, err := readEntries(dir)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		if err := prune(filepath.Dir(dir), filepath.Base(dir)); err != nil {
+			return err
+		}
+	}
+
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return appendEntry(dir, entries, entry{Path: path})
+	} else if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return recordFile(dir, entries, path, info)
+	}
+
+	return filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !d.Type().IsRegular() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if err := recordFile(dir, entries, file, info); err != nil {
+			return err
+		}
+		entries = append(entries, entry{Path: file})
+		return nil
+	})
+}
+
+// prune removes the oldest turns of a journal so that, with the turn about to
+// be recorded, it keeps maxTurns.
+func prune(journalDir, turnID string) error {
+	dirs, err := os.ReadDir(journalDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	type turn struct {
+		name    string
+		modTime int64
+	}
+	var turns []turn
+	for _, d := range dirs {
+		if !d.IsDir() || d.Name() == turnID {
+			continue
+		}
+		info, err := d.Info()
+		if err != nil {
+			continue
+		}
+		turns = append(turns, turn{name: d.Name(), modTime: info.ModTime().UnixNano()})
+	}
+	if len(turns) < maxTurns {
+		return nil
+	}
+
+	slices.SortFunc(turns, func(a, b turn) int { return cmp.Compare(a.modTime, b.modTime) })
+	for _, t := range turns[:len(turns)-maxTurns+1] {
+		if err := os.RemoveAll(filepath.Join(journalDir, t.name)); err != nil {
+			return fmt.Errorf("failed to remove old journal turn %s: %w", t.name, err)
+		}
+	}
+	return nil
+}
+
+func recordFile(dir string, entries []entry, path string, info fs.FileInfo) error {
+	if slices.ContainsFunc(entries, func(e entry) bool { return e.Path == path }) {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	backup := strconv.Itoa(len(entries))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create journal directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, backup), data, 0600); err != nil {
+		return fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+
+	return appendEntry(dir, entries, entry{
+		Path:    path,
+		Existed: true,
+		Backup:  backup,
+		Mode:    info.Mode().Perm(),
+	})
+}
+
+func appendEntry(dir string, entries []entry, e entry) error {
+	if slices.ContainsFunc(entries, func(existing entry) bool { return existing.Path == e.Path }) {
+		return nil
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create journal directory: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(dir, "entries.jsonl"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	return nil
+}
+
+func readEntries(dir string) ([]entry, error) {
+	f, err := os.Open(filepath.Join(dir, "entries.jsonl"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("failed to read journal: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// Revert restores every file the turn recorded to its state before the turn
+// and removes the turn's journal. Files the turn created are deleted. It
+// returns the reverted paths in the order they were first changed.
+func Revert(sessionID, turnID string) ([]string, error) {
+	dir, err := turnDir(sessionID, turnID)
+	if err != nil {
+		return nil, err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	entries, err := readEntries(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		paths = make([]string, 0, len(entries))
+		errs  []error
+	)
+	for _, e := range entries {
+		paths = append(paths, e.Path)
+		if !e.Existed {
+			if err := os.RemoveAll(e.Path); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, e.Backup))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(e.Path), 0755); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := os.WriteFile(e.Path, data, e.Mode); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return paths, fmt.Errorf("failed to revert turn %s: %w", turnID, err)
+	}
+
+	return paths, os.RemoveAll(dir)
+}
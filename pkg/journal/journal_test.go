@@ -0,0 +1,108 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRecordAndRevert(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	if err := os.WriteFile("edited.txt", []byte("before"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll("dir", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join("dir", "deleted.txt"), []byte("keep me"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a turn that edits a file twice, creates one, and deletes a
+	// directory.
+	for _, path := range []string{"edited.txt", "created.txt", "dir"} {
+		if err := Record("session", "turn-1", path); err != nil {
+			t.Fatalf("Record(%s) failed: %v", path, err)
+		}
+	}
+	if err := os.WriteFile("edited.txt", []byte("after"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Record("session", "turn-1", "edited.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("edited.txt", []byte("after again"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("created.txt", []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll("dir"); err != nil {
+		t.Fatal(err)
+	}
+
+	paths, err := Revert("session", "turn-1")
+	if err != nil {
+		t.Fatalf("Revert() failed: %v", err)
+	}
+	if len(paths) != 3 || !slices.ContainsFunc(paths, func(p string) bool { return filepath.Base(p) == "deleted.txt" }) {
+		t.Errorf("unexpected reverted paths %v", paths)
+	}
+
+	if data, err := os.ReadFile("edited.txt"); err != nil || string(data) != "before" {
+		t.Errorf("edited.txt = %q, %v, want the content from before the turn", data, err)
+	}
+	if info, err := os.Stat("edited.txt"); err != nil || info.Mode().Perm() != 0640 {
+		t.Errorf("expected edited.txt to keep its mode, got %v, %v", info, err)
+	}
+	if _, err := os.Stat("created.txt"); !os.IsNotExist(err) {
+		t.Errorf("expected created.txt to be removed, got %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join("dir", "deleted.txt")); err != nil || string(data) != "keep me" {
+		t.Errorf("dir/deleted.txt = %q, %v, want it restored", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(".nanobot", "session", "journal", "turn-1")); !os.IsNotExist(err) {
+		t.Errorf("expected the turn's journal to be removed, got %v", err)
+	}
+}
+
+func TestRecordWithoutTurn(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	if err := Record("session", "", "file.txt"); err != nil {
+		t.Fatalf("Record() failed: %v", err)
+	}
+	if _, err := os.Stat(".nanobot"); !os.IsNotExist(err) {
+		t.Errorf("expected nothing to be journaled without a turn, got %v", err)
+	}
+	if _, err := Revert("session", "../escape"); err == nil {
+		t.Error("expected an invalid turn ID to be rejected")
+	}
+}
+
+func TestRecordPrunesOldTurns(t *testing.T) {
+	t.Chdir(t.TempDir())
+	defer func(turns int) { maxTurns = turns }(maxTurns)
+	maxTurns = 2
+
+	for i, turnID := range []string{"turn-1", "turn-2", "turn-3"} {
+		if err := Record("session", turnID, "file.txt"); err != nil {
+			t.Fatal(err)
+		}
+		// Turns are pruned oldest first.
+		modTime := time.Now().Add(time.Duration(i-10) * time.Minute)
+		if err := os.Chtimes(filepath.Join(".nanobot", "session", "journal", turnID), modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for turnID, kept := range map[string]bool{"turn-1": false, "turn-2": true, "turn-3": true} {
+		_, err := os.Stat(filepath.Join(".nanobot", "session", "journal", turnID))
+		if kept != (err == nil) {
+			t.Errorf("%s: expected kept=%v, got %v", turnID, kept, err)
+		}
+	}
+}
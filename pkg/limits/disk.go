@@ -0,0 +1,115 @@
+package limits
+
+import (
+	"context"
+	"time"
+)
+
+// diskRefreshInterval is how often the size of the files of a session is
+// measured again. In between, the bytes written by the file tools are added
+// to the last measure, and what commands wrote is only seen once measured,
+// or when MeasureDisk is called while they run.
+const diskRefreshInterval = 30 * time.Second
+
+// diskMeasure is the last measure of the size of some files.
+type diskMeasure struct {
+	bytes    int64
+	measured time.Time
+}
+
+// diskUsage is the size of the files of the sessions of an account, and of
+// the sessions of the account that checked their quotas.
+type diskUsage struct {
+	account  diskMeasure
+	sessions map[string]diskMeasure
+}
+
+// diskKey returns the key of the disk usage of an account. Sessions without
+// an account are only measured on their own.
+func diskKey(accountID, sessionID string) string {
+	if accountID == "" {
+		return "session/" + sessionID
+	}
+	return "account/" + accountID
+}
+
+func (l *Limiter) CheckDisk(ctx context.Context, accountID, sessionID string, adding int64) error {
+	return l.checkDisk(ctx, accountID, sessionID, adding, false)
+}
+
+// MeasureDisk measures the files of the session and of its account again,
+// to see what a running command wrote, and fails when they take more than the
+// disk quotas.
+func (l *Limiter) MeasureDisk(ctx context.Context, accountID, sessionID string) error {
+	return l.checkDisk(ctx, accountID, sessionID, 0, true)
+}
+
+func (l *Limiter) checkDisk(ctx context.Context, accountID, sessionID string, adding int64, measure bool) error {
+	if (l.settings.SessionDiskMB <= 0 && l.settings.AccountDiskMB <= 0) || l.sessions == nil {
+		return nil
+	}
+
+	key := diskKey(accountID, sessionID)
+	now := l.now()
+
+	l.lock.Lock()
+	l.evictDiskLocked(now)
+	usage := l.disk[key]
+	account, session := usage.account, usage.sessions[sessionID]
+	l.lock.Unlock()
+
+	if measure || now.Sub(account.measured) >= diskRefreshInterval || now.Sub(session.measured) >= diskRefreshInterval {
+		accountBytes, sessionBytes, err := l.sessions.DiskUsage(ctx, accountID, sessionID)
+		if err != nil {
+			return err
+		}
+		account = diskMeasure{bytes: accountBytes, measured: now}
+		session = diskMeasure{bytes: sessionBytes, measured: now}
+	}
+
+	var err error
+	if quota := l.settings.SessionDiskMB; quota > 0 && session.bytes+adding > int64(quota)<<20 {
+		err = &Error{
+			Limit: SessionDiskMB,
+			Max:   quota,
+		}
+	} else if quota := l.settings.AccountDiskMB; quota > 0 && account.bytes+adding > int64(quota)<<20 {
+		err = &Error{
+			Limit: AccountDiskMB,
+			Max:   quota,
+		}
+	} else {
+		account.bytes += adding
+		session.bytes += adding
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	usage = l.disk[key]
+	if usage.sessions == nil {
+		usage.sessions = map[string]diskMeasure{}
+	}
+	usage.account = account
+	usage.sessions[sessionID] = session
+	l.disk[key] = usage
+	return err
+}
+
+// evictDiskLocked forgets the measures that are too old to be used, at most
+// once per diskRefreshInterval.
+func (l *Limiter) evictDiskLocked(now time.Time) {
+	if now.Sub(l.diskSweep) < diskRefreshInterval {
+		return
+	}
+	l.diskSweep = now
+	for key, usage := range l.disk {
+		for sessionID, session := range usage.sessions {
+			if now.Sub(session.measured) >= diskRefreshInterval {
+				delete(usage.sessions, sessionID)
+			}
+		}
+		if len(usage.sessions) == 0 && now.Sub(usage.account.measured) >= diskRefreshInterval {
+			delete(l.disk, key)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package limits
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// Wrap rejects the MCP requests of accounts over their limits with a 429 and
+// a JSON-RPC error saying which limit was reached. New sessions are checked
+// against the sessions limit, tool calls against the tool calls and tokens
+// limits. Runs and the calls their agents make are limited as they happen.
+func (l *Limiter) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost || req.Body == nil {
+			next.ServeHTTP(rw, req)
+			return
+		}
+
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			http.Error(rw, `{"http_error": "Failed to read request body"}`, http.StatusBadRequest)
+			return
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+
+		var msg mcp.Message
+		if err := json.Unmarshal(body, &msg); err != nil {
+			// Let the server answer malformed messages.
+			next.ServeHTTP(rw, req)
+			return
+		}
+
+		accountID := types.NanobotContext(req.Context()).User.ID
+		switch msg.Method {
+		case "initialize":
+			if l.sessions == nil || l.sessions.ExtractID(req) == "" {
+				err = l.CheckSession(accountID)
+			}
+		case "tools/call":
+			err = l.checkToolCalls(accountID, false)
+			if err == nil {
+				err = l.CheckTokens(accountID)
+			}
+		}
+
+		var limitErr *Error
+		if errors.As(err, &limitErr) {
+			slog.InfoContext(req.Context(), "rejected request over the limits of the account",
+				"method", msg.Method, "account", accountID, "limit", limitErr.Limit)
+			writeError(rw, msg, limitErr)
+			return
+		}
+
+		next.ServeHTTP(rw, req)
+	})
+}
+
+func writeError(rw http.ResponseWriter, msg mcp.Message, limitErr *Error) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Header().Set("Retry-After", strconv.Itoa(limitErr.retryAfterSeconds()))
+	rw.WriteHeader(http.StatusTooManyRequests)
+	_ = json.NewEncoder(rw).Encode(mcp.Message{
+		JSONRPC: "2.0",
+		ID:      msg.ID,
+		Error:   limitErr.RPCError(),
+	})
+}
@@ -0,0 +1,227 @@
+package limits
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// busyRetryAfter is when to retry after reaching a limit on what is in use at
+// once, sessions that aren't used anymore are released within 10 seconds.
+const busyRetryAfter = 10 * time.Second
+
+// Names of the limits, as in the limits of the config.
+const (
+	MaxSessions        = "maxSessions"
+	MaxConcurrentRuns  = "maxConcurrentRuns"
+	ToolCallsPerMinute = "toolCallsPerMinute"
+	TokensPerDay       = "tokensPerDay"
+	SessionDiskMB      = "sessionDiskMB"
+	AccountDiskMB      = "accountDiskMB"
+)
+
+// Error is returned when an account reached one of its limits.
+type Error struct {
+	Limit      string
+	Max        int
+	RetryAfter time.Duration
+}
+
+type errorData struct {
+	Limit             string `json:"limit"`
+	Max               int    `json:"max"`
+	RetryAfterSeconds int    `json:"retryAfterSeconds"`
+}
+
+func (e *Error) Error() string {
+	if e.RetryAfter <= 0 {
+		// Nothing frees up on its own, as with disk space.
+		return fmt.Sprintf("the %s limit of %d was reached", e.Limit, e.Max)
+	}
+	return fmt.Sprintf("the %s limit of %d was reached, retry in %s", e.Limit, e.Max, e.RetryAfter.Round(time.Second))
+}
+
+func (e *Error) RPCError() *mcp.RPCError {
+	rpcErr := mcp.ErrRPCLimitExceeded.WithMessage("%s", e.Error())
+	rpcErr.DataObject = errorData{
+		Limit:             e.Limit,
+		Max:               e.Max,
+		RetryAfterSeconds: e.retryAfterSeconds(),
+	}
+	return rpcErr.RPCError()
+}
+
+func (e *Error) retryAfterSeconds() int {
+	return int((e.RetryAfter + time.Second - 1) / time.Second)
+}
+
+// Sessions are the sessions of the server, the limiter counts the ones in use
+// by each account and the size of their files.
+type Sessions interface {
+	ExtractID(req *http.Request) string
+	LiveSessions(accountID string) int
+	// DiskUsage returns the size of the files of all the sessions of the
+	// account, and of the session. Without an account, both are the size of
+	// the files of the session.
+	DiskUsage(ctx context.Context, accountID, sessionID string) (account, session int64, _ error)
+}
+
+var _ types.AccountLimiter = (*Limiter)(nil)
+
+// Limiter enforces the limits of each account. What it counts is kept in
+// memory, so each server process enforces the limits on its own.
+type Limiter struct {
+	settings types.LimitSettings
+	sessions Sessions
+	now      func() time.Time
+
+	lock      sync.Mutex
+	runs      map[string]int
+	toolCalls map[string][]time.Time
+	tokens    map[string]dailyTokens
+	disk      map[string]diskUsage
+	diskSweep time.Time
+}
+
+type dailyTokens struct {
+	day  string
+	used int
+}
+
+func NewLimiter(settings types.LimitSettings, sessions Sessions) *Limiter {
+	return &Limiter{
+		settings:  settings,
+		sessions:  sessions,
+		now:       time.Now,
+		runs:      map[string]int{},
+		toolCalls: map[string][]time.Time{},
+		tokens:    map[string]dailyTokens{},
+		disk:      map[string]diskUsage{},
+	}
+}
+
+// CheckSession fails when the account can't start another session.
+func (l *Limiter) CheckSession(accountID string) error {
+	if l.settings.MaxSessions <= 0 || l.sessions == nil {
+		return nil
+	}
+	if l.sessions.LiveSessions(accountID) >= l.settings.MaxSessions {
+		return &Error{
+			Limit:      MaxSessions,
+			Max:        l.settings.MaxSessions,
+			RetryAfter: busyRetryAfter,
+		}
+	}
+	return nil
+}
+
+func (l *Limiter) StartRun(accountID string) (func(), error) {
+	if l.settings.MaxConcurrentRuns <= 0 {
+		return func() {}, nil
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.runs[accountID] >= l.settings.MaxConcurrentRuns {
+		return nil, &Error{
+			Limit:      MaxConcurrentRuns,
+			Max:        l.settings.MaxConcurrentRuns,
+			RetryAfter: busyRetryAfter,
+		}
+	}
+	l.runs[accountID]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.lock.Lock()
+			defer l.lock.Unlock()
+			if l.runs[accountID]--; l.runs[accountID] <= 0 {
+				delete(l.runs, accountID)
+			}
+		})
+	}, nil
+}
+
+func (l *Limiter) AllowToolCall(accountID string) error {
+	return l.checkToolCalls(accountID, true)
+}
+
+// checkToolCalls fails when the account made its tool calls of the last
+// minute. Otherwise, count counts a call.
+func (l *Limiter) checkToolCalls(accountID string, count bool) error {
+	if l.settings.ToolCallsPerMinute <= 0 {
+		return nil
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now()
+	calls := l.toolCalls[accountID]
+	for len(calls) > 0 && now.Sub(calls[0]) >= time.Minute {
+		calls = calls[1:]
+	}
+
+	if len(calls) >= l.settings.ToolCallsPerMinute {
+		l.toolCalls[accountID] = calls
+		return &Error{
+			Limit:      ToolCallsPerMinute,
+			Max:        l.settings.ToolCallsPerMinute,
+			RetryAfter: calls[0].Add(time.Minute).Sub(now),
+		}
+	}
+
+	if count {
+		calls = append(calls, now)
+	}
+	if len(calls) == 0 {
+		delete(l.toolCalls, accountID)
+	} else {
+		l.toolCalls[accountID] = calls
+	}
+	return nil
+}
+
+func (l *Limiter) CheckTokens(accountID string) error {
+	if l.settings.TokensPerDay <= 0 {
+		return nil
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now().UTC()
+	if used := l.tokens[accountID]; used.day == now.Format(time.DateOnly) && used.used >= l.settings.TokensPerDay {
+		tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+		return &Error{
+			Limit:      TokensPerDay,
+			Max:        l.settings.TokensPerDay,
+			RetryAfter: tomorrow.Sub(now),
+		}
+	}
+	return nil
+}
+
+func (l *Limiter) AddTokens(accountID string, tokens int) {
+	if l.settings.TokensPerDay <= 0 || tokens <= 0 {
+		return
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	day := l.now().UTC().Format(time.DateOnly)
+	used := l.tokens[accountID]
+	if used.day != day {
+		used = dailyTokens{day: day}
+	}
+	used.used += tokens
+	l.tokens[accountID] = used
+}
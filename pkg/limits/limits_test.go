@@ -0,0 +1,229 @@
+package limits
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+type testSessions map[string]int
+
+func (t testSessions) ExtractID(req *http.Request) string {
+	return req.Header.Get("Mcp-Session-Id")
+}
+
+func (t testSessions) LiveSessions(accountID string) int {
+	return t[accountID]
+}
+
+func (t testSessions) DiskUsage(context.Context, string, string) (int64, int64, error) {
+	return 0, 0, nil
+}
+
+// diskSessions are sessions whose files take the given sizes, by session.
+type diskSessions struct {
+	testSessions
+	sizes    map[string]int64
+	measures int
+}
+
+func (d *diskSessions) DiskUsage(_ context.Context, _, sessionID string) (account, session int64, _ error) {
+	d.measures++
+	for _, size := range d.sizes {
+		account += size
+	}
+	return account, d.sizes[sessionID], nil
+}
+
+func expectLimit(t *testing.T, err error, limit string) *Error {
+	t.Helper()
+	var limitErr *Error
+	if !errors.As(err, &limitErr) || limitErr.Limit != limit {
+		t.Fatalf("expected the %s limit to be reached, got %v", limit, err)
+	}
+	return limitErr
+}
+
+func TestToolCallsPerMinute(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := NewLimiter(types.LimitSettings{ToolCallsPerMinute: 2}, nil)
+	l.now = func() time.Time { return now }
+
+	for range 2 {
+		if err := l.AllowToolCall("alice"); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(10 * time.Second)
+	}
+	limitErr := expectLimit(t, l.AllowToolCall("alice"), ToolCallsPerMinute)
+	if limitErr.RetryAfter != 40*time.Second {
+		t.Fatalf("expected to retry after 40s, got %s", limitErr.RetryAfter)
+	}
+	if err := l.AllowToolCall("bob"); err != nil {
+		t.Fatalf("expected other accounts to be counted apart, got %v", err)
+	}
+
+	now = now.Add(40 * time.Second)
+	if err := l.AllowToolCall("alice"); err != nil {
+		t.Fatalf("expected the oldest call to have left the window, got %v", err)
+	}
+}
+
+func TestTokensPerDay(t *testing.T) {
+	now := time.Date(2026, 1, 1, 18, 0, 0, 0, time.UTC)
+	l := NewLimiter(types.LimitSettings{TokensPerDay: 1000}, nil)
+	l.now = func() time.Time { return now }
+
+	l.AddTokens("alice", 600)
+	if err := l.CheckTokens("alice"); err != nil {
+		t.Fatal(err)
+	}
+	l.AddTokens("alice", 600)
+	limitErr := expectLimit(t, l.CheckTokens("alice"), TokensPerDay)
+	if limitErr.RetryAfter != 6*time.Hour {
+		t.Fatalf("expected to retry at midnight UTC, got %s", limitErr.RetryAfter)
+	}
+
+	now = now.Add(6 * time.Hour)
+	if err := l.CheckTokens("alice"); err != nil {
+		t.Fatalf("expected the tokens to reset the next day, got %v", err)
+	}
+}
+
+func TestConcurrentRuns(t *testing.T) {
+	l := NewLimiter(types.LimitSettings{MaxConcurrentRuns: 1}, nil)
+
+	done, err := l.StartRun("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = l.StartRun("alice")
+	expectLimit(t, err, MaxConcurrentRuns)
+
+	done()
+	done()
+	if l.runs["alice"] != 0 {
+		t.Fatalf("expected calling done twice to free one slot, got %d runs", l.runs["alice"])
+	}
+	if _, err := l.StartRun("alice"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	l := NewLimiter(types.LimitSettings{MaxSessions: 1}, testSessions{"": 1})
+	handler := l.Wrap(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}))
+
+	initialize := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(initialize)))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "10" {
+		t.Fatalf("expected Retry-After 10, got %q", rec.Header().Get("Retry-After"))
+	}
+
+	var msg mcp.Message
+	if err := json.Unmarshal(rec.Body.Bytes(), &msg); err != nil {
+		t.Fatal(err)
+	}
+	var data errorData
+	if err := json.Unmarshal(msg.Error.Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Error.Code != mcp.ErrRPCLimitExceeded.Code || data.Limit != MaxSessions || data.Max != 1 {
+		t.Fatalf("unexpected error %+v with data %+v", msg.Error, data)
+	}
+
+	// Initializing a session that exists doesn't start another one.
+	req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(initialize))
+	req.Header.Set("Mcp-Session-Id", "existing")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+}
+
+func TestDiskQuotas(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	sessions := &diskSessions{sizes: map[string]int64{
+		"s1": 3 << 20,
+		"s2": 4 << 20,
+	}}
+	l := NewLimiter(types.LimitSettings{SessionDiskMB: 4, AccountDiskMB: 8}, sessions)
+	l.now = func() time.Time { return now }
+
+	if err := l.CheckDisk(t.Context(), "a1", "s1", 512<<10); err != nil {
+		t.Fatalf("expected the write to fit, got %v", err)
+	}
+	// The written bytes are added to the last measure, so the next write
+	// doesn't fit in the session.
+	err := l.CheckDisk(t.Context(), "a1", "s1", 600<<10)
+	if limitErr := expectLimit(t, err, SessionDiskMB); limitErr.Max != 4 {
+		t.Errorf("expected a max of 4, got %d", limitErr.Max)
+	}
+	if err.Error() != "the sessionDiskMB limit of 4 was reached" {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+	if sessions.measures != 1 {
+		t.Errorf("expected the files to be measured once, got %d", sessions.measures)
+	}
+
+	// Another session of the account is limited by the files of both.
+	sessions.sizes["s2"] = 5 << 20
+	expectLimit(t, l.CheckDisk(t.Context(), "a1", "s3", 1), AccountDiskMB)
+
+	// Files removed by commands are seen once measured again.
+	sessions.sizes["s1"] = 0
+	now = now.Add(diskRefreshInterval)
+	if err := l.CheckDisk(t.Context(), "a1", "s1", 1<<20); err != nil {
+		t.Errorf("expected the write to fit after measuring again, got %v", err)
+	}
+}
+
+func TestMeasureDisk(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	sessions := &diskSessions{sizes: map[string]int64{"s1": 1 << 20}}
+	l := NewLimiter(types.LimitSettings{SessionDiskMB: 4}, sessions)
+	l.now = func() time.Time { return now }
+
+	if err := l.CheckDisk(t.Context(), "a1", "s1", 0); err != nil {
+		t.Fatal(err)
+	}
+	// A command wrote past the quota, which is seen before the next refresh.
+	sessions.sizes["s1"] = 5 << 20
+	expectLimit(t, l.MeasureDisk(t.Context(), "a1", "s1"), SessionDiskMB)
+	if sessions.measures != 2 {
+		t.Errorf("expected the files to be measured twice, got %d", sessions.measures)
+	}
+
+	// Sessions without an account are measured on their own.
+	if err := l.CheckDisk(t.Context(), "", "s2", 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := l.disk[diskKey("", "s2")]; !ok || len(l.disk) != 2 {
+		t.Errorf("expected the session without an account to be measured on its own, got %v", l.disk)
+	}
+
+	// Old measures are forgotten.
+	now = now.Add(diskRefreshInterval)
+	if err := l.CheckDisk(t.Context(), "", "s2", 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(l.disk) != 1 {
+		t.Errorf("expected the old measures to be evicted, got %v", l.disk)
+	}
+}
@@ -0,0 +1,264 @@
+package anthropic
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+const bedrockVersion = "bedrock-2023-05-31"
+
+// maxEventStreamMessage bounds the size of one message of the AWS event
+// stream, so a corrupt length doesn't allocate without limit.
+const maxEventStreamMessage = 16 << 20
+
+func (c *Client) bedrockURL(model string) (string, error) {
+	if id, ok := c.Bedrock.Models[model]; ok {
+		model = id
+	}
+
+	baseURL := c.BaseURL
+	if baseURL == "" {
+		if c.Bedrock.Region == "" {
+			return "", errors.New("bedrock region is required")
+		}
+		baseURL = "https://bedrock-runtime." + c.Bedrock.Region + ".amazonaws.com"
+	}
+	return strings.TrimSuffix(baseURL, "/") + "/model/" + awsEscape(model) + "/invoke-with-response-stream", nil
+}
+
+// signBedrock authorizes a Bedrock request with a Bedrock API key, or else
+// signs it with AWS Signature Version 4.
+func (c *Client) signBedrock(req *http.Request, body []byte, now time.Time) error {
+	if c.Bedrock.AccessKeyID == "" && c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+		return nil
+	}
+	if c.Bedrock.AccessKeyID == "" || c.Bedrock.SecretAccessKey == "" {
+		return errors.New("bedrock requires an API key or AWS access keys")
+	}
+	if c.Bedrock.Region == "" {
+		return errors.New("bedrock region is required")
+	}
+
+	signV4(req, body, c.Bedrock, "bedrock", now)
+	return nil
+}
+
+// signV4 signs a request with AWS Signature Version 4.
+func signV4(req *http.Request, body []byte, creds *types.BedrockProvider, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	headers := map[string]string{
+		"host": req.URL.Host,
+	}
+	for _, name := range []string{"Content-Type", "X-Amz-Date", "X-Amz-Security-Token"} {
+		if value := req.Header.Get(name); value != "" {
+			headers[strings.ToLower(name)] = strings.TrimSpace(value)
+		}
+	}
+	names := slices.Sorted(maps.Keys(headers))
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	// Outside of S3 every segment of the already escaped path is escaped
+	// again.
+	segments := strings.Split(req.URL.EscapedPath(), "/")
+	for i, segment := range segments {
+		segments[i] = awsEscape(segment)
+	}
+
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		strings.Join(segments, "/"),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	date := amzDate[:8]
+	scope := date + "/" + creds.Region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	key = hmacSHA256(key, creds.Region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// awsEscape escapes everything but the unreserved characters, the way AWS
+// expects path segments, so model ARNs keep their colons and slashes in one
+// segment.
+func awsEscape(s string) string {
+	var sb strings.Builder
+	for _, b := range []byte(s) {
+		if b >= 'A' && b <= 'Z' || b >= 'a' && b <= 'z' || b >= '0' && b <= '9' || b == '-' || b == '_' || b == '.' || b == '~' {
+			sb.WriteByte(b)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", b)
+		}
+	}
+	return sb.String()
+}
+
+// eventStreamReader converts the AWS event stream of a Bedrock response to the
+// server-sent events the Anthropic API streams, each chunk of the event stream
+// carries one Anthropic event.
+type eventStreamReader struct {
+	r   io.Reader
+	buf bytes.Buffer
+}
+
+func newEventStreamReader(r io.Reader) *eventStreamReader {
+	return &eventStreamReader{r: r}
+}
+
+func (e *eventStreamReader) Read(p []byte) (int, error) {
+	for e.buf.Len() == 0 {
+		if err := e.next(); err != nil {
+			return 0, err
+		}
+	}
+	return e.buf.Read(p)
+}
+
+func (e *eventStreamReader) next() error {
+	var prelude [12]byte
+	if _, err := io.ReadFull(e.r, prelude[:]); err != nil {
+		return err
+	}
+
+	totalLength := binary.BigEndian.Uint32(prelude[0:4])
+	headersLength := binary.BigEndian.Uint32(prelude[4:8])
+	if crc32.ChecksumIEEE(prelude[:8]) != binary.BigEndian.Uint32(prelude[8:12]) {
+		return errors.New("invalid event stream prelude checksum")
+	}
+	if totalLength < uint32(len(prelude))+headersLength+4 || totalLength > maxEventStreamMessage {
+		return fmt.Errorf("invalid event stream message length %d", totalLength)
+	}
+
+	message := make([]byte, totalLength-uint32(len(prelude)))
+	if _, err := io.ReadFull(e.r, message); err != nil {
+		return fmt.Errorf("failed to read event stream message: %w", err)
+	}
+	checksum := crc32.Update(crc32.ChecksumIEEE(prelude[:]), crc32.IEEETable, message[:len(message)-4])
+	if checksum != binary.BigEndian.Uint32(message[len(message)-4:]) {
+		return errors.New("invalid event stream message checksum")
+	}
+
+	headers, err := eventStreamHeaders(message[:headersLength])
+	if err != nil {
+		return err
+	}
+	payload := message[headersLength : len(message)-4]
+
+	switch headers[":message-type"] {
+	case "event":
+		if headers[":event-type"] != "chunk" {
+			return nil
+		}
+		var chunk struct {
+			Bytes []byte `json:"bytes"`
+		}
+		if err := json.Unmarshal(payload, &chunk); err != nil {
+			return fmt.Errorf("failed to decode event stream chunk: %w", err)
+		}
+		e.buf.WriteString("data: ")
+		e.buf.Write(chunk.Bytes)
+		e.buf.WriteString("\n\n")
+	case "exception":
+		return fmt.Errorf("bedrock %s: %s", headers[":exception-type"], payload)
+	case "error":
+		return fmt.Errorf("bedrock %s: %s", headers[":error-code"], headers[":error-message"])
+	}
+	return nil
+}
+
+// eventStreamHeaders decodes the headers of an event stream message, keeping
+// only the string values.
+func eventStreamHeaders(data []byte) (map[string]string, error) {
+	var (
+		headers    = map[string]string{}
+		errInvalid = errors.New("invalid event stream headers")
+	)
+	for len(data) > 0 {
+		nameLength := int(data[0])
+		if len(data) < 2+nameLength {
+			return nil, errInvalid
+		}
+		name := string(data[1 : 1+nameLength])
+		valueType := data[1+nameLength]
+		data = data[2+nameLength:]
+
+		var size int
+		switch valueType {
+		case 0, 1:
+			// bool true and false have no value
+		case 2:
+			size = 1
+		case 3:
+			size = 2
+		case 4:
+			size = 4
+		case 5, 8:
+			size = 8
+		case 9:
+			size = 16
+		case 6, 7:
+			if len(data) < 2 {
+				return nil, errInvalid
+			}
+			length := int(binary.BigEndian.Uint16(data))
+			data = data[2:]
+			if len(data) < length {
+				return nil, errInvalid
+			}
+			if valueType == 7 {
+				headers[name] = string(data[:length])
+			}
+			size = length
+		default:
+			return nil, errInvalid
+		}
+		if len(data) < size {
+			return nil, errInvalid
+		}
+		data = data[size:]
+	}
+	return headers, nil
+}
@@ -0,0 +1,154 @@
+package anthropic
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// TestSignV4 checks the signature against the example in the AWS Signature
+// Version 4 documentation.
+func TestSignV4(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	signV4(req, nil, &types.BedrockProvider{
+		Region:          "us-east-1",
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}, "iam", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, " +
+		"SignedHeaders=content-type;host;x-amz-date, " +
+		"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization = %s, want %s", got, want)
+	}
+}
+
+func TestBedrockRequest(t *testing.T) {
+	c := NewClient(Config{
+		APIKey: "anthropic-key",
+		Bedrock: &types.BedrockProvider{
+			Region:          "us-west-2",
+			AccessKeyID:     "AKIDEXAMPLE",
+			SecretAccessKey: "secret",
+			Models: map[string]string{
+				"claude-sonnet-4-5": "arn:aws:bedrock:us-west-2:123456789012:inference-profile/us.anthropic.claude-sonnet-4-5-20250929-v1:0",
+			},
+		},
+	})
+
+	data, _ := json.Marshal(Request{Model: "claude-sonnet-4-5", MaxTokens: 100, Stream: true})
+	req, err := c.newRequest(t.Context(), "claude-sonnet-4-5", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantPath := "/model/arn%3Aaws%3Abedrock%3Aus-west-2%3A123456789012%3Ainference-profile%2Fus.anthropic.claude-sonnet-4-5-20250929-v1%3A0/invoke-with-response-stream"
+	if req.URL.Host != "bedrock-runtime.us-west-2.amazonaws.com" || req.URL.EscapedPath() != wantPath {
+		t.Errorf("URL = %s, want the inference profile on the us-west-2 runtime", req.URL)
+	}
+	if req.Header.Get("x-api-key") != "" {
+		t.Error("the Anthropic API key must not be sent to Bedrock")
+	}
+	if auth := req.Header.Get("Authorization"); !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") {
+		t.Errorf("Authorization = %s, want a SigV4 signature", auth)
+	}
+
+	var body map[string]any
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := body["model"]; ok {
+		t.Error("body should not have the model")
+	}
+	if _, ok := body["stream"]; ok {
+		t.Error("body should not have stream")
+	}
+	if body["anthropic_version"] != bedrockVersion {
+		t.Errorf("anthropic_version = %v, want %s", body["anthropic_version"], bedrockVersion)
+	}
+}
+
+func TestVertexRequest(t *testing.T) {
+	c := NewClient(Config{
+		Vertex: &types.VertexProvider{
+			Region:      "us-east5",
+			ProjectID:   "my-project",
+			AccessToken: "token",
+		},
+	})
+
+	data, _ := json.Marshal(Request{Model: "claude-sonnet-4-5@20250929", MaxTokens: 100, Stream: true})
+	req, err := c.newRequest(t.Context(), "claude-sonnet-4-5@20250929", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "https://us-east5-aiplatform.googleapis.com/v1/projects/my-project/locations/us-east5/publishers/anthropic/models/claude-sonnet-4-5@20250929:streamRawPredict"
+	if req.URL.String() != want {
+		t.Errorf("URL = %s, want %s", req.URL, want)
+	}
+	if auth := req.Header.Get("Authorization"); auth != "Bearer token" {
+		t.Errorf("Authorization = %s, want the access token", auth)
+	}
+
+	var body map[string]any
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body["anthropic_version"] != vertexVersion || body["stream"] != true {
+		t.Errorf("body = %v, want the vertex version and streaming", body)
+	}
+}
+
+// eventStreamMessage encodes one message of the AWS event stream.
+func eventStreamMessage(headers map[string]string, payload []byte) []byte {
+	var encodedHeaders bytes.Buffer
+	for name, value := range headers {
+		encodedHeaders.WriteByte(byte(len(name)))
+		encodedHeaders.WriteString(name)
+		encodedHeaders.WriteByte(7)
+		_ = binary.Write(&encodedHeaders, binary.BigEndian, uint16(len(value)))
+		encodedHeaders.WriteString(value)
+	}
+
+	var msg bytes.Buffer
+	_ = binary.Write(&msg, binary.BigEndian, uint32(16+encodedHeaders.Len()+len(payload)))
+	_ = binary.Write(&msg, binary.BigEndian, uint32(encodedHeaders.Len()))
+	_ = binary.Write(&msg, binary.BigEndian, crc32.ChecksumIEEE(msg.Bytes()))
+	msg.Write(encodedHeaders.Bytes())
+	msg.Write(payload)
+	_ = binary.Write(&msg, binary.BigEndian, crc32.ChecksumIEEE(msg.Bytes()))
+	return msg.Bytes()
+}
+
+func TestEventStreamReader(t *testing.T) {
+	event := `{"type":"message_stop"}`
+	chunk, _ := json.Marshal(map[string]string{"bytes": base64.StdEncoding.EncodeToString([]byte(event))})
+
+	var stream bytes.Buffer
+	stream.Write(eventStreamMessage(map[string]string{":message-type": "event", ":event-type": "chunk"}, chunk))
+	stream.Write(eventStreamMessage(map[string]string{":message-type": "exception", ":exception-type": "throttlingException"}, []byte(`{"message":"slow down"}`)))
+
+	data, err := io.ReadAll(newEventStreamReader(&stream))
+	if string(data) != "data: "+event+"\n\n" {
+		t.Errorf("events = %q, want the chunk as a server-sent event", data)
+	}
+	if err == nil || !strings.Contains(err.Error(), "throttlingException") {
+		t.Errorf("err = %v, want the exception of the stream", err)
+	}
+}
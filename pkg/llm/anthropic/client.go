@@ -29,21 +29,29 @@ type Config struct {
 	APIKey  string
 	BaseURL string
 	Headers map[string]string
+	// Bedrock or Vertex send the requests to the Anthropic models of a cloud
+	// platform instead of the Anthropic API.
+	Bedrock *types.BedrockProvider
+	Vertex  *types.VertexProvider
 }
 
 // NewClient creates a new OpenAI client with the provided API key and base URL.
 func NewClient(cfg Config) *Client {
-	if cfg.BaseURL == "" {
-		cfg.BaseURL = "https://api.anthropic.com/v1"
-	}
 	if cfg.Headers == nil {
 		cfg.Headers = map[string]string{}
 	}
-	if _, ok := cfg.Headers["x-api-key"]; !ok && cfg.APIKey != "" {
-		cfg.Headers["x-api-key"] = cfg.APIKey
-	}
-	if _, ok := cfg.Headers["anthropic-version"]; !ok {
-		cfg.Headers["anthropic-version"] = "2023-06-01"
+	if cfg.Bedrock == nil && cfg.Vertex == nil {
+		// The cloud platforms take the API version in the body and have their
+		// own authorization.
+		if cfg.BaseURL == "" {
+			cfg.BaseURL = "https://api.anthropic.com/v1"
+		}
+		if _, ok := cfg.Headers["x-api-key"]; !ok && cfg.APIKey != "" {
+			cfg.Headers["x-api-key"] = cfg.APIKey
+		}
+		if _, ok := cfg.Headers["anthropic-version"]; !ok {
+			cfg.Headers["anthropic-version"] = "2023-06-01"
+		}
 	}
 	if _, ok := cfg.Headers["Content-Type"]; !ok {
 		cfg.Headers["Content-Type"] = "application/json"
@@ -75,6 +83,56 @@ func (c *Client) Complete(ctx context.Context, completionRequest types.Completio
 	return cr, nil
 }
 
+// newRequest creates the request for the messages endpoint of the Anthropic
+// API or for its equivalent on a cloud platform.
+func (c *Client) newRequest(ctx context.Context, model string, data []byte) (*http.Request, error) {
+	url := c.BaseURL + "/messages"
+	if c.Bedrock != nil || c.Vertex != nil {
+		var (
+			err  error
+			body map[string]json.RawMessage
+		)
+		if err := json.Unmarshal(data, &body); err != nil {
+			return nil, err
+		}
+		// The model is in the URL and Bedrock streams by the endpoint.
+		delete(body, "model")
+		if c.Bedrock != nil {
+			delete(body, "stream")
+			body["anthropic_version"], _ = json.Marshal(bedrockVersion)
+			url, err = c.bedrockURL(model)
+		} else {
+			body["anthropic_version"], _ = json.Marshal(vertexVersion)
+			url, err = c.vertexURL(model)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if data, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+
+	httpReq, err := http.NewRequestWithContext(mcp.UserContext(ctx), http.MethodPost, url, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range c.Headers {
+		httpReq.Header.Set(key, value)
+	}
+	if requestType := types.InternalLLMRequestType(ctx); requestType != "" {
+		httpReq.Header.Set(types.InternalLLMRequestTypeHeader, requestType)
+	}
+
+	switch {
+	case c.Bedrock != nil:
+		err = c.signBedrock(httpReq, data, time.Now())
+	case c.Vertex != nil:
+		err = c.authorizeVertex(httpReq)
+	}
+	return httpReq, err
+}
+
 func (c *Client) complete(ctx context.Context, agentName string, req Request, opts ...types.CompletionOptions) (*Response, string, string, error) {
 	opt := complete.Complete(opts...)
 
@@ -83,16 +141,10 @@ func (c *Client) complete(ctx context.Context, agentName string, req Request, op
 	data, _ := json.Marshal(req)
 	log.Messages(ctx, "anthropic-api", true, data)
 
-	httpReq, err := http.NewRequestWithContext(mcp.UserContext(ctx), http.MethodPost, c.BaseURL+"/messages", bytes.NewBuffer(data))
+	httpReq, err := c.newRequest(ctx, req.Model, data)
 	if err != nil {
 		return nil, "", "", err
 	}
-	for key, value := range c.Headers {
-		httpReq.Header.Set(key, value)
-	}
-	if requestType := types.InternalLLMRequestType(ctx); requestType != "" {
-		httpReq.Header.Set(types.InternalLLMRequestTypeHeader, requestType)
-	}
 
 	httpResp, err := http.DefaultClient.Do(httpReq)
 	if err != nil {
@@ -107,8 +159,13 @@ func (c *Client) complete(ctx context.Context, agentName string, req Request, op
 		return nil, "", "", fmt.Errorf("failed to get response from Anthropic API: %s %q", httpResp.Status, string(body))
 	}
 
+	var stream io.Reader = httpResp.Body
+	if c.Bedrock != nil {
+		stream = newEventStreamReader(httpResp.Body)
+	}
+
 	var (
-		lines                   = bufio.NewScanner(httpResp.Body)
+		lines                   = bufio.NewScanner(stream)
 		resp                    Response
 		toolCallPolicyViolation string
 		partialJSON             strings.Builder
@@ -167,6 +224,34 @@ func (c *Client) complete(ctx context.Context, agentName string, req Request, op
 						},
 					}, opt.ProgressToken)
 				}
+			case "thinking_delta", "signature_delta":
+				if contentIndex >= 0 {
+					block := &resp.Content[contentIndex]
+					if block.Thinking == nil {
+						block.Thinking = new(string)
+					}
+					*block.Thinking += delta.Delta.Thinking
+					block.Signature += delta.Delta.Signature
+					if delta.Delta.Thinking != "" {
+						progress.Send(ctx, &types.CompletionProgress{
+							Model:     resp.Model,
+							Agent:     agentName,
+							MessageID: resp.ID,
+							Item: types.CompletionItem{
+								ID:      fmt.Sprintf("%s-%d", resp.ID, contentIndex),
+								Partial: true,
+								HasMore: true,
+								Reasoning: &types.Reasoning{
+									Summary: []types.SummaryText{
+										{
+											Text: delta.Delta.Thinking,
+										},
+									},
+								},
+							},
+						}, opt.ProgressToken)
+					}
+				}
 			case "input_json_delta":
 				partialJSON.WriteString(delta.Delta.PartialJSON)
 				if contentIndex >= 0 {
@@ -215,6 +300,7 @@ func (c *Client) complete(ctx context.Context, agentName string, req Request, op
 			if err != nil {
 				return nil, "", "", fmt.Errorf("failed to unmarshal message delta: %w", err)
 			}
+			resp.Usage = mergeUsage(resp.Usage, delta.Usage)
 		case "message_stop":
 			// nothing to do, but here for completeness
 		}
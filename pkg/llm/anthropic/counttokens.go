@@ -0,0 +1,76 @@
+package anthropic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// countTokensRequest is the body of the count tokens endpoint, which only
+// takes the fields of a request that make up its input.
+type countTokensRequest struct {
+	Messages   []Message    `json:"messages"`
+	Model      string       `json:"model"`
+	System     []Content    `json:"system,omitempty"`
+	ToolChoice *ToolChoice  `json:"tool_choice,omitempty"`
+	Tools      []CustomTool `json:"tools,omitempty"`
+	Thinking   *Thinking    `json:"thinking,omitempty"`
+}
+
+// CountTokens asks the count tokens endpoint of the Anthropic API how many
+// input tokens the request has. The cloud platforms aren't supported.
+func (c *Client) CountTokens(ctx context.Context, completionRequest types.CompletionRequest) (int, error) {
+	if c.Bedrock != nil || c.Vertex != nil {
+		return 0, types.ErrTokenCountingUnsupported
+	}
+
+	req, err := toRequest(&completionRequest)
+	if err != nil {
+		return 0, err
+	}
+
+	data, err := json.Marshal(countTokensRequest{
+		Messages:   req.Messages,
+		Model:      req.Model,
+		System:     req.System,
+		ToolChoice: req.ToolChoice,
+		Tools:      req.Tools,
+		Thinking:   req.Thinking,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(mcp.UserContext(ctx), http.MethodPost, c.BaseURL+"/messages/count_tokens", bytes.NewBuffer(data))
+	if err != nil {
+		return 0, err
+	}
+	for key, value := range c.Headers {
+		httpReq.Header.Set(key, value)
+	}
+
+	httpResp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return 0, err
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(httpResp.Body)
+		return 0, fmt.Errorf("failed to count tokens with Anthropic API: %s %q", httpResp.Status, string(body))
+	}
+
+	var resp struct {
+		InputTokens int `json:"input_tokens"`
+	}
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return 0, fmt.Errorf("failed to decode token count: %w", err)
+	}
+	return resp.InputTokens, nil
+}
@@ -50,28 +50,102 @@ func toResponse(resp *Response, created time.Time) (*types.CompletionResponse, e
 					Data:     content.Source.Data,
 				},
 			})
+		} else if content.Type == "thinking" && content.Thinking != nil {
+			result.Output.Items = append(result.Output.Items, types.CompletionItem{
+				ID: fmt.Sprintf("%s-%d", resp.ID, contentIndex),
+				Reasoning: &types.Reasoning{
+					Summary: []types.SummaryText{
+						{
+							Text: *content.Thinking,
+						},
+					},
+					Signature: content.Signature,
+				},
+			})
+		} else if content.Type == "redacted_thinking" {
+			result.Output.Items = append(result.Output.Items, types.CompletionItem{
+				ID: fmt.Sprintf("%s-%d", resp.ID, contentIndex),
+				Reasoning: &types.Reasoning{
+					RedactedContent: content.Data,
+				},
+			})
 		}
 	}
 
+	result.Usage = toUsage(resp.Usage)
+
 	return result, nil
 }
 
+func toUsage(usage *Usage) *types.Usage {
+	if usage == nil {
+		return nil
+	}
+	deref := func(i *int) int {
+		if i == nil {
+			return 0
+		}
+		return *i
+	}
+	return &types.Usage{
+		InputTokens:      deref(usage.InputTokens),
+		OutputTokens:     deref(usage.OutputTokens),
+		CacheReadTokens:  deref(usage.CacheReadInputTokens),
+		CacheWriteTokens: deref(usage.CacheCreationInputTokens),
+		Completions:      1,
+	}
+}
+
+// mergeUsage updates the usage from the start of a stream with the counts in
+// a message delta, which are cumulative.
+func mergeUsage(usage, delta *Usage) *Usage {
+	if delta == nil {
+		return usage
+	}
+	if usage == nil {
+		return delta
+	}
+	merged := *usage
+	if delta.InputTokens != nil {
+		merged.InputTokens = delta.InputTokens
+	}
+	if delta.OutputTokens != nil {
+		merged.OutputTokens = delta.OutputTokens
+	}
+	if delta.CacheReadInputTokens != nil {
+		merged.CacheReadInputTokens = delta.CacheReadInputTokens
+	}
+	if delta.CacheCreationInputTokens != nil {
+		merged.CacheCreationInputTokens = delta.CacheCreationInputTokens
+	}
+	return &merged
+}
+
 func toRequest(req *types.CompletionRequest) (Request, error) {
 	// TODO: handle output schema
 
 	if req.MaxTokens == 0 {
+		// The model's max output tokens are set by the caller when known.
 		req.MaxTokens = 64_000
 	}
 
 	result := Request{
 		Model:       req.Model,
-		System:      strings.TrimSpace(req.SystemPrompt),
 		MaxTokens:   req.MaxTokens,
 		Temperature: req.Temperature,
 		TopP:        req.TopP,
 		Metadata:    req.Metadata,
 	}
 
+	if system := strings.TrimSpace(req.SystemPrompt); system != "" {
+		result.System = []Content{
+			{
+				Type: "text",
+				Text: &system,
+			},
+		}
+	}
+
 	for _, tool := range req.Tools {
 		result.Tools = append(result.Tools, CustomTool{
 			Name:        tool.Name,
@@ -106,8 +180,27 @@ func toRequest(req *types.CompletionRequest) (Request, error) {
 		}
 	}
 
+	if req.ParallelToolCalls != nil && !*req.ParallelToolCalls && len(req.Tools) > 0 {
+		if result.ToolChoice == nil {
+			result.ToolChoice = &ToolChoice{
+				Type: "auto",
+			}
+		}
+		if result.ToolChoice.Type != "none" {
+			result.ToolChoice.DisableParallelToolUse = true
+		}
+	}
+
 	for _, msg := range req.Input {
 		for _, input := range msg.Items {
+			if input.Reasoning != nil && msg.Role == "assistant" {
+				if thinking, ok := reasoningToContent(input.Reasoning); ok {
+					result.Messages = append(result.Messages, Message{
+						Content: []Content{thinking},
+						Role:    "assistant",
+					})
+				}
+			}
 			if input.Content != nil {
 				content := contentToContent([]mcp.Content{*input.Content})
 				if len(content) == 0 {
@@ -151,9 +244,165 @@ func toRequest(req *types.CompletionRequest) (Request, error) {
 		}
 	}
 
+	result.Messages = mergeToolMessages(result.Messages)
+
+	if budget := thinkingBudget(req.Reasoning); budget > 0 && !inToolLoopWithoutThinking(result.Messages) {
+		enableThinking(&result, budget)
+	}
+
+	if req.PromptCaching == nil || *req.PromptCaching {
+		addCacheBreakpoints(&result)
+	}
+
 	return result, nil
 }
 
+// maxCacheBreakpoints is the most cache_control markers a request may have.
+const maxCacheBreakpoints = 4
+
+// addCacheBreakpoints marks the prefixes of the request that stay the same
+// from one turn to the next so the API caches them: the tool definitions, the
+// system prompt, and the history up to the last two messages. Marking the
+// message before the last lets the next turn read the cache even when the
+// last message was replaced.
+func addCacheBreakpoints(req *Request) {
+	var (
+		ephemeral = &CacheControl{Type: "ephemeral"}
+		count     int
+	)
+	if len(req.Tools) > 0 {
+		req.Tools[len(req.Tools)-1].CacheControl = ephemeral
+		count++
+	}
+	if len(req.System) > 0 {
+		req.System[len(req.System)-1].CacheControl = ephemeral
+		count++
+	}
+	for i := len(req.Messages) - 1; i >= 0 && i >= len(req.Messages)-2 && count < maxCacheBreakpoints; i-- {
+		// Thinking blocks can't be marked.
+		content := req.Messages[i].Content
+		if j := len(content) - 1; j >= 0 && !isThinkingBlock(content[j].Type) {
+			content[j].CacheControl = ephemeral
+			count++
+		}
+	}
+}
+
+// mergeToolMessages merges consecutive tool_use messages, and consecutive
+// tool_result messages, so that the blocks of parallel tool calls share one
+// assistant message and their results share the user message that follows.
+// Thinking is merged into the assistant message that follows it, which it
+// must start.
+func mergeToolMessages(messages []Message) []Message {
+	var result []Message
+	for _, msg := range messages {
+		if last := len(result) - 1; last >= 0 && result[last].Role == msg.Role {
+			lastType := result[last].Content[len(result[last].Content)-1].Type
+			if isThinkingBlock(lastType) || isToolBlock(msg.Content[0].Type) && lastType == msg.Content[0].Type {
+				result[last].Content = append(result[last].Content, msg.Content...)
+				continue
+			}
+		}
+		msg.Content = slices.Clone(msg.Content)
+		result = append(result, msg)
+	}
+	return result
+}
+
+func isToolBlock(blockType string) bool {
+	return blockType == "tool_use" || blockType == "tool_result"
+}
+
+func isThinkingBlock(blockType string) bool {
+	return blockType == "thinking" || blockType == "redacted_thinking"
+}
+
+// reasoningToContent converts reasoning back into the thinking block it came
+// from. Reasoning from other providers can't be sent to Anthropic.
+func reasoningToContent(reasoning *types.Reasoning) (Content, bool) {
+	if reasoning.RedactedContent != "" {
+		return Content{
+			Type: "redacted_thinking",
+			Data: reasoning.RedactedContent,
+		}, true
+	}
+	if reasoning.Signature == "" {
+		return Content{}, false
+	}
+
+	var text strings.Builder
+	for _, summary := range reasoning.Summary {
+		text.WriteString(summary.Text)
+	}
+	return Content{
+		Type:      "thinking",
+		Thinking:  new(text.String()),
+		Signature: reasoning.Signature,
+	}, true
+}
+
+// thinkingBudget returns how many tokens the model may think for, or zero if
+// extended thinking is off.
+func thinkingBudget(reasoning *types.AgentReasoning) int {
+	if reasoning == nil {
+		return 0
+	}
+	if reasoning.BudgetTokens > 0 {
+		return reasoning.BudgetTokens
+	}
+	switch reasoning.Effort {
+	case "low":
+		return 4_000
+	case "medium":
+		return 16_000
+	case "high":
+		return 32_000
+	}
+	return 0
+}
+
+// inToolLoopWithoutThinking checks whether the request continues a tool call
+// made without thinking. The API requires the assistant message of the tool
+// call to start with thinking when thinking is on, so it has to stay off until
+// the loop ends, like when thinking was turned on in the middle of it.
+func inToolLoopWithoutThinking(messages []Message) bool {
+	last := len(messages) - 1
+	if last < 1 || messages[last].Role != "user" || messages[last].Content[0].Type != "tool_result" {
+		return false
+	}
+	// The API combines consecutive assistant messages, so the first of them
+	// is the one that has to start with thinking.
+	first := -1
+	for i := last - 1; i >= 0 && messages[i].Role == "assistant"; i-- {
+		first = i
+	}
+	return first >= 0 && !isThinkingBlock(messages[first].Content[0].Type)
+}
+
+// enableThinking turns on extended thinking and relaxes the parameters that
+// the API doesn't allow with it.
+func enableThinking(req *Request, budget int) {
+	req.Thinking = &Thinking{
+		Type:         "enabled",
+		BudgetTokens: budget,
+	}
+	if req.MaxTokens <= budget {
+		// The budget is part of the max tokens, keep room for the answer.
+		req.MaxTokens += budget
+	}
+	req.Temperature = nil
+	if req.TopP != nil {
+		if topP, err := req.TopP.Float64(); err != nil || topP < 0.95 {
+			req.TopP = nil
+		}
+	}
+	if req.ToolChoice != nil && (req.ToolChoice.Type == "tool" || req.ToolChoice.Type == "any") {
+		// Forcing a tool isn't allowed with thinking.
+		req.ToolChoice.Type = "auto"
+		req.ToolChoice.Name = ""
+	}
+}
+
 func contentToContent(content []mcp.Content) (result []Content) {
 	for _, item := range content {
 		if item.Type == "text" || item.Type == "" {
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/obot-platform/nanobot/pkg/mcp"
 	"github.com/obot-platform/nanobot/pkg/types"
@@ -73,3 +74,197 @@ func TestToRequestDropsResourceLinkContent(t *testing.T) {
 		t.Fatalf("request still contains null content: %s", data)
 	}
 }
+
+func TestToRequestParallelToolCalls(t *testing.T) {
+	req := types.CompletionRequest{
+		Model:             "claude-opus-4-6",
+		ParallelToolCalls: new(false),
+		Tools:             []types.ToolUseDefinition{{Name: "read"}},
+		Input: []types.Message{
+			{
+				Role: "assistant",
+				Items: []types.CompletionItem{
+					{ToolCall: &types.ToolCall{CallID: "a", Name: "read", Arguments: "{}"}},
+					{ToolCall: &types.ToolCall{CallID: "b", Name: "read", Arguments: "{}"}},
+				},
+			},
+			{
+				Role: "user",
+				Items: []types.CompletionItem{
+					{ToolCallResult: &types.ToolCallResult{CallID: "a"}},
+					{ToolCallResult: &types.ToolCallResult{CallID: "b"}},
+				},
+			},
+		},
+	}
+
+	anthropicReq, err := toRequest(&req)
+	if err != nil {
+		t.Fatalf("toRequest failed: %v", err)
+	}
+
+	if len(anthropicReq.Messages) != 2 || len(anthropicReq.Messages[0].Content) != 2 || len(anthropicReq.Messages[1].Content) != 2 {
+		t.Fatalf("expected the parallel tool_use and tool_result blocks to share a message each, got %+v", anthropicReq.Messages)
+	}
+	if anthropicReq.ToolChoice == nil || anthropicReq.ToolChoice.Type != "auto" || !anthropicReq.ToolChoice.DisableParallelToolUse {
+		t.Fatalf("expected parallel tool use to be disabled, got %+v", anthropicReq.ToolChoice)
+	}
+
+	data, err := json.Marshal(anthropicReq.ToolChoice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"type":"auto","disable_parallel_tool_use":true}` {
+		t.Errorf("unexpected tool_choice %s", data)
+	}
+}
+
+func TestToResponseStreamedUsage(t *testing.T) {
+	var start, delta DeltaEvent
+	if err := json.Unmarshal([]byte(`{"type":"message_start","message":{"id":"msg_1","usage":{"input_tokens":25,"cache_read_input_tokens":1000,"cache_creation_input_tokens":200,"output_tokens":1}}}`), &start); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":15}}`), &delta); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := start.Message
+	resp.Usage = mergeUsage(resp.Usage, delta.Usage)
+
+	result, err := toResponse(&resp, time.Now())
+	if err != nil {
+		t.Fatalf("toResponse failed: %v", err)
+	}
+
+	want := types.Usage{InputTokens: 25, OutputTokens: 15, CacheReadTokens: 1000, CacheWriteTokens: 200, Completions: 1}
+	if result.Usage == nil || *result.Usage != want {
+		t.Errorf("usage = %+v, want %+v", result.Usage, want)
+	}
+}
+
+func TestToRequestPromptCaching(t *testing.T) {
+	req := types.CompletionRequest{
+		Model:        "claude-opus-4-6",
+		SystemPrompt: "You are a helpful assistant.",
+		Tools:        []types.ToolUseDefinition{{Name: "read"}, {Name: "write"}},
+		Input: []types.Message{
+			{Role: "user", Items: []types.CompletionItem{{Content: &mcp.Content{Type: "text", Text: "first"}}}},
+			{Role: "assistant", Items: []types.CompletionItem{{Content: &mcp.Content{Type: "text", Text: "second"}}}},
+			{Role: "user", Items: []types.CompletionItem{{Content: &mcp.Content{Type: "text", Text: "third"}}}},
+		},
+	}
+
+	anthropicReq, err := toRequest(&req)
+	if err != nil {
+		t.Fatalf("toRequest failed: %v", err)
+	}
+
+	if anthropicReq.Tools[0].CacheControl != nil || anthropicReq.Tools[1].CacheControl == nil {
+		t.Errorf("expected only the last tool to be marked, got %+v", anthropicReq.Tools)
+	}
+	if len(anthropicReq.System) != 1 || anthropicReq.System[0].CacheControl == nil {
+		t.Errorf("expected the system prompt to be marked, got %+v", anthropicReq.System)
+	}
+	for i, want := range []bool{false, true, true} {
+		if got := anthropicReq.Messages[i].Content[0].CacheControl != nil; got != want {
+			t.Errorf("message %d marked = %v, want %v", i, got, want)
+		}
+	}
+
+	data, err := json.Marshal(anthropicReq.Tools[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"cache_control":{"type":"ephemeral"}`) {
+		t.Errorf("expected cache_control in the tool definition, got %s", data)
+	}
+
+	req.PromptCaching = new(false)
+	anthropicReq, err = toRequest(&req)
+	if err != nil {
+		t.Fatalf("toRequest failed: %v", err)
+	}
+	if anthropicReq.Tools[1].CacheControl != nil || anthropicReq.System[0].CacheControl != nil || anthropicReq.Messages[2].Content[0].CacheControl != nil {
+		t.Errorf("expected no cache breakpoints with prompt caching off")
+	}
+}
+
+func TestThinkingRoundTrip(t *testing.T) {
+	thinking := "The user wants the file, read it first."
+	result, err := toResponse(&Response{
+		ID: "msg_1",
+		Content: []Content{
+			{Type: "thinking", Thinking: &thinking, Signature: "sig"},
+			{Type: "redacted_thinking", Data: "encrypted"},
+			{Type: "tool_use", ID: "call-1", Name: "read", Input: map[string]any{}},
+		},
+	}, time.Now())
+	if err != nil {
+		t.Fatalf("toResponse failed: %v", err)
+	}
+	if reasoning := result.Output.Items[0].Reasoning; reasoning == nil || reasoning.Summary[0].Text != thinking || reasoning.Signature != "sig" {
+		t.Fatalf("expected thinking as reasoning, got %+v", result.Output.Items[0])
+	}
+	if reasoning := result.Output.Items[1].Reasoning; reasoning == nil || reasoning.RedactedContent != "encrypted" {
+		t.Fatalf("expected redacted thinking as reasoning, got %+v", result.Output.Items[1])
+	}
+
+	req := types.CompletionRequest{
+		Model:       "claude-opus-4-6",
+		Temperature: new(json.Number("0.2")),
+		Reasoning:   &types.AgentReasoning{Effort: "medium"},
+		Tools:       []types.ToolUseDefinition{{Name: "read"}},
+		Input: []types.Message{
+			{Role: "user", Items: []types.CompletionItem{{Content: &mcp.Content{Type: "text", Text: "show me main.go"}}}},
+			result.Output,
+			{Role: "user", Items: []types.CompletionItem{{ToolCallResult: &types.ToolCallResult{CallID: "call-1"}}}},
+		},
+	}
+
+	anthropicReq, err := toRequest(&req)
+	if err != nil {
+		t.Fatalf("toRequest failed: %v", err)
+	}
+
+	if anthropicReq.Thinking == nil || anthropicReq.Thinking.BudgetTokens != 16_000 || anthropicReq.Temperature != nil {
+		t.Errorf("expected thinking to be enabled without a temperature, got %+v, temperature %v", anthropicReq.Thinking, anthropicReq.Temperature)
+	}
+
+	var blocks []string
+	for _, content := range anthropicReq.Messages[1].Content {
+		blocks = append(blocks, content.Type)
+	}
+	if strings.Join(blocks, ",") != "thinking,redacted_thinking,tool_use" {
+		t.Errorf("expected the assistant message to start with its thinking, got %v", blocks)
+	}
+	if block := anthropicReq.Messages[1].Content[0]; block.Signature != "sig" || block.Thinking == nil || *block.Thinking != thinking {
+		t.Errorf("thinking was not sent back as it was received: %+v", block)
+	}
+}
+
+func TestThinkingOffInToolLoopWithoutThinking(t *testing.T) {
+	req := types.CompletionRequest{
+		Model:     "claude-opus-4-6",
+		Reasoning: &types.AgentReasoning{BudgetTokens: 2048},
+		Input: []types.Message{
+			{Role: "user", Items: []types.CompletionItem{{Content: &mcp.Content{Type: "text", Text: "show me main.go"}}}},
+			{Role: "assistant", Items: []types.CompletionItem{
+				// Reasoning from another provider can't be sent to Anthropic.
+				{Reasoning: &types.Reasoning{EncryptedContent: "openai"}},
+				{ToolCall: &types.ToolCall{CallID: "call-1", Name: "read", Arguments: "{}"}},
+			}},
+			{Role: "user", Items: []types.CompletionItem{{ToolCallResult: &types.ToolCallResult{CallID: "call-1"}}}},
+		},
+	}
+
+	anthropicReq, err := toRequest(&req)
+	if err != nil {
+		t.Fatalf("toRequest failed: %v", err)
+	}
+	if anthropicReq.Thinking != nil {
+		t.Errorf("expected thinking to stay off until the tool loop ends, got %+v", anthropicReq.Thinking)
+	}
+	if len(anthropicReq.Messages[1].Content) != 1 || anthropicReq.Messages[1].Content[0].Type != "tool_use" {
+		t.Errorf("expected only the tool call in the assistant message, got %+v", anthropicReq.Messages[1].Content)
+	}
+}
@@ -10,12 +10,19 @@ type Request struct {
 	Model         string         `json:"model"`
 	StopSequences []string       `json:"stop_sequences,omitempty"`
 	Stream        bool           `json:"stream,omitempty"`
-	System        string         `json:"system,omitempty"`
+	System        []Content      `json:"system,omitempty"`
 	Temperature   *json.Number   `json:"temperature,omitempty"`
 	ToolChoice    *ToolChoice    `json:"tool_choice,omitempty"`
 	Tools         []CustomTool   `json:"tools,omitempty"`
 	TopP          *json.Number   `json:"top_p,omitempty"`
 	Metadata      map[string]any `json:"metadata,omitempty"`
+	Thinking      *Thinking      `json:"thinking,omitempty"`
+}
+
+type Thinking struct {
+	// Type is either "enabled" or "disabled"
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens,omitempty"`
 }
 
 type Response struct {
@@ -41,12 +48,17 @@ type ServerToolUse struct {
 }
 
 type ToolChoice struct {
-	// Type is either "auto", "tool", or "none"
-	Type                   string
+	// Type is either "auto", "any", "tool", or "none"
+	Type                   string `json:"type"`
 	DisableParallelToolUse bool   `json:"disable_parallel_tool_use,omitempty"`
 	Name                   string `json:"name,omitempty"`
 }
 
+// CacheControl marks the end of a prompt prefix that the API caches.
+type CacheControl struct {
+	Type string `json:"type"`
+}
+
 type Message struct {
 	Content []Content `json:"content"`
 	Role    string    `json:"role"`
@@ -61,6 +73,13 @@ type Content struct {
 	// Type = image
 	Source ContentSource `json:"source,omitzero"`
 
+	// Type = thinking
+	Thinking  *string `json:"thinking,omitempty"`
+	Signature string  `json:"signature,omitempty"`
+
+	// Type = redacted_thinking
+	Data string `json:"data,omitempty"`
+
 	// Type = tool_use
 	ID    string         `json:"id,omitempty"`
 	Input map[string]any `json:"input,omitzero"`
@@ -70,6 +89,8 @@ type Content struct {
 	ToolUseID string    `json:"tool_use_id,omitempty"`
 	Content   []Content `json:"content,omitempty"`
 	IsError   bool      `json:"is_error,omitempty"`
+
+	CacheControl *CacheControl `json:"cache_control,omitempty"`
 }
 
 type ContentSource struct {
@@ -84,11 +105,12 @@ type ContentSource struct {
 }
 
 type CustomTool struct {
-	Type        string          `json:"type,omitempty"`
-	Name        string          `json:"name,omitempty"`
-	InputSchema json.RawMessage `json:"input_schema,omitzero"`
-	Description string          `json:"description,omitempty"`
-	Attributes  map[string]any  `json:"-"`
+	Type         string          `json:"type,omitempty"`
+	Name         string          `json:"name,omitempty"`
+	InputSchema  json.RawMessage `json:"input_schema,omitzero"`
+	Description  string          `json:"description,omitempty"`
+	CacheControl *CacheControl   `json:"cache_control,omitempty"`
+	Attributes   map[string]any  `json:"-"`
 }
 
 func (c *CustomTool) UnmarshalJSON(data []byte) error {
@@ -106,6 +128,7 @@ func (c *CustomTool) UnmarshalJSON(data []byte) error {
 	delete(c.Attributes, "input_schema")
 	delete(c.Attributes, "strict")
 	delete(c.Attributes, "description")
+	delete(c.Attributes, "cache_control")
 	c.Type = ""
 
 	return nil
@@ -159,10 +182,13 @@ type DeltaEvent struct {
 	Message      Response `json:"message"`
 	ContentBlock Content  `json:"content_block"`
 	Delta        Delta    `json:"delta"`
+	Usage        *Usage   `json:"usage"`
 }
 
 type Delta struct {
 	Type        string `json:"type"`
 	Text        string `json:"text,omitempty"`
 	PartialJSON string `json:"partial_json,omitempty"`
+	Thinking    string `json:"thinking,omitempty"`
+	Signature   string `json:"signature,omitempty"`
 }
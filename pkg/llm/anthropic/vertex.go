@@ -0,0 +1,133 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/jwt"
+)
+
+const (
+	vertexVersion  = "vertex-2023-10-16"
+	vertexScope    = "https://www.googleapis.com/auth/cloud-platform"
+	googleTokenURL = "https://oauth2.googleapis.com/token"
+)
+
+var (
+	// vertexTokenSources caches the token source of each credentials file, the
+	// client is created for every completion and tokens last an hour.
+	vertexTokenSources     = map[string]oauth2.TokenSource{}
+	vertexTokenSourcesLock sync.Mutex
+)
+
+func (c *Client) vertexURL(model string) (string, error) {
+	if id, ok := c.Vertex.Models[model]; ok {
+		model = id
+	}
+	if c.Vertex.ProjectID == "" {
+		return "", errors.New("vertex project ID is required")
+	}
+
+	region := c.Vertex.Region
+	if region == "" {
+		region = "global"
+	}
+
+	baseURL := c.BaseURL
+	if baseURL == "" {
+		if region == "global" {
+			baseURL = "https://aiplatform.googleapis.com/v1"
+		} else {
+			baseURL = "https://" + region + "-aiplatform.googleapis.com/v1"
+		}
+	}
+	return fmt.Sprintf("%s/projects/%s/locations/%s/publishers/anthropic/models/%s:streamRawPredict",
+		strings.TrimSuffix(baseURL, "/"), c.Vertex.ProjectID, region, model), nil
+}
+
+func (c *Client) authorizeVertex(req *http.Request) error {
+	token := c.Vertex.AccessToken
+	if token == "" {
+		if c.Vertex.CredentialsFile == "" {
+			return errors.New("vertex requires an access token or a credentials file")
+		}
+		ts, err := vertexTokenSource(c.Vertex.CredentialsFile)
+		if err != nil {
+			return err
+		}
+		t, err := ts.Token()
+		if err != nil {
+			return fmt.Errorf("failed to get a Google Cloud access token: %w", err)
+		}
+		token = t.AccessToken
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// vertexTokenSource returns the token source of a service account key file,
+// or of the authorized user credentials written by "gcloud auth
+// application-default login".
+func vertexTokenSource(credentialsFile string) (oauth2.TokenSource, error) {
+	vertexTokenSourcesLock.Lock()
+	defer vertexTokenSourcesLock.Unlock()
+
+	if ts, ok := vertexTokenSources[credentialsFile]; ok {
+		return ts, nil
+	}
+
+	data, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Google Cloud credentials: %w", err)
+	}
+
+	var creds struct {
+		Type         string `json:"type"`
+		ClientEmail  string `json:"client_email"`
+		PrivateKey   string `json:"private_key"`
+		PrivateKeyID string `json:"private_key_id"`
+		TokenURI     string `json:"token_uri"`
+		ClientID     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, fmt.Errorf("failed to parse Google Cloud credentials %s: %w", credentialsFile, err)
+	}
+
+	var ts oauth2.TokenSource
+	switch creds.Type {
+	case "service_account":
+		cfg := &jwt.Config{
+			Email:        creds.ClientEmail,
+			PrivateKey:   []byte(creds.PrivateKey),
+			PrivateKeyID: creds.PrivateKeyID,
+			Scopes:       []string{vertexScope},
+			TokenURL:     creds.TokenURI,
+		}
+		if cfg.TokenURL == "" {
+			cfg.TokenURL = googleTokenURL
+		}
+		ts = cfg.TokenSource(context.Background())
+	case "authorized_user":
+		cfg := &oauth2.Config{
+			ClientID:     creds.ClientID,
+			ClientSecret: creds.ClientSecret,
+			Endpoint:     oauth2.Endpoint{TokenURL: googleTokenURL},
+			Scopes:       []string{vertexScope},
+		}
+		ts = cfg.TokenSource(context.Background(), &oauth2.Token{RefreshToken: creds.RefreshToken})
+	default:
+		return nil, fmt.Errorf("unsupported Google Cloud credentials type %q in %s", creds.Type, credentialsFile)
+	}
+
+	vertexTokenSources[credentialsFile] = ts
+	return ts, nil
+}
@@ -248,6 +248,7 @@ func (c *Client) parseStream(ctx context.Context, agentName string, body io.Read
 						result.Output.ID = *event.Response.ID
 					}
 				}
+				result.Usage = toUsage(event.Response.Usage)
 			}
 
 		case schemas.ResponsesStreamResponseTypeFailed, schemas.ResponsesStreamResponseTypeIncomplete:
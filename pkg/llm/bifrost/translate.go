@@ -45,6 +45,10 @@ func toRequest(provider string, req *types.CompletionRequest) (*schemas.BifrostR
 		params.Tools = append(params.Tools, t)
 	}
 
+	if req.ParallelToolCalls != nil && len(req.Tools) > 0 {
+		params.ParallelToolCalls = req.ParallelToolCalls
+	}
+
 	if req.ToolChoice != "" {
 		switch req.ToolChoice {
 		case "none", "auto", "required":
@@ -228,3 +232,25 @@ func toContentBlock(content mcp.Content) (schemas.ResponsesMessageContentBlock,
 	}
 	return schemas.ResponsesMessageContentBlock{}, false
 }
+
+// toUsage converts the usage of a response. Input tokens include the cached
+// tokens, which are counted separately.
+func toUsage(usage *schemas.ResponsesResponseUsage) *types.Usage {
+	if usage == nil {
+		return nil
+	}
+	result := &types.Usage{
+		InputTokens:  usage.InputTokens,
+		OutputTokens: usage.OutputTokens,
+		Completions:  1,
+	}
+	if details := usage.InputTokensDetails; details != nil {
+		result.CacheReadTokens = details.CachedReadTokens
+		result.CacheWriteTokens = details.CachedWriteTokens
+		result.InputTokens -= details.CachedReadTokens + details.CachedWriteTokens
+	}
+	if details := usage.OutputTokensDetails; details != nil {
+		result.ReasoningTokens = details.ReasoningTokens
+	}
+	return result
+}
@@ -1,9 +1,11 @@
 package llm
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"maps"
 	"strings"
 
@@ -27,17 +29,31 @@ type LLMProviderConfig struct {
 	APIKey  string // supports ${VAR} syntax
 	BaseURL string // supports ${VAR} syntax
 	Headers map[string]string
+	Bedrock *types.BedrockProvider // supports ${VAR} syntax
+	Vertex  *types.VertexProvider  // supports ${VAR} syntax
+	Local   bool
+	Models  map[string]types.LLMModel // BaseURL supports ${VAR} syntax
+	Retry   *types.LLMRetry
+	// CountTokens enables the count tokens endpoint of the provider.
+	CountTokens bool
 }
 
+// defaultLocalBaseURL is the OpenAI compatible endpoint of a local Ollama.
+const defaultLocalBaseURL = "http://localhost:11434/v1"
+
 type Config struct {
 	DefaultModel, DefaultMiniModel string
-	LLMProviders                   map[string]LLMProviderConfig
+	// EmbeddingModel is used for embeddings, in the same "{provider}/{model}"
+	// format as DefaultModel. Embeddings are disabled when it is empty.
+	EmbeddingModel string
+	LLMProviders   map[string]LLMProviderConfig
 }
 
 func NewClient(cfg Config) *Client {
 	return &Client{
 		defaultModel:     cfg.DefaultModel,
 		defaultMiniModel: cfg.DefaultMiniModel,
+		embeddingModel:   cfg.EmbeddingModel,
 		cfg:              cfg,
 	}
 }
@@ -45,6 +61,7 @@ func NewClient(cfg Config) *Client {
 type Client struct {
 	defaultModel     string
 	defaultMiniModel string
+	embeddingModel   string
 	cfg              Config
 }
 
@@ -101,20 +118,22 @@ func (c Client) Complete(ctx context.Context, req types.CompletionRequest, opts
 		if ret != nil && ret.Agent == "" {
 			ret.Agent = req.Agent
 		}
+		assignToolCallIDs(ret)
 	}()
 
 	dynamic := c.dynamicConfig(ctx)
 
-	var provider string
-	req.Model, provider = resolveProvider(req.Model, dynamic)
+	models := append([]string{req.Model}, req.FallbackModels...)
+	req.FallbackModels = nil
 
 	opt := complete.Complete(opts...)
 	if opt.ProgressToken != nil && len(req.Input) > 0 {
+		model, _ := resolveProvider(req.Model, dynamic)
 		lastMsg := req.Input[len(req.Input)-1]
 		if lastMsg.ID != "" && lastMsg.Role == "user" {
 			for _, item := range lastMsg.Items {
 				progress.Send(ctx, &types.CompletionProgress{
-					Model:     req.Model,
+					Model:     model,
 					MessageID: lastMsg.ID,
 					Role:      lastMsg.Role,
 					Item:      item,
@@ -123,16 +142,125 @@ func (c Client) Complete(ctx context.Context, req types.CompletionRequest, opts
 		}
 	}
 
+	req.Input = normalizeToolCalls(req.Input)
+
+	for i, model := range models {
+		var provider string
+		req.Model, provider = resolveProvider(model, dynamic)
+		ret, err = completeStructured(ctx, dynamic, provider, req, opts...)
+		if err == nil || i == len(models)-1 || ctx.Err() != nil || !isRetryable(err) {
+			return ret, err
+		}
+		slog.Warn("LLM completion failed, failing over to the next model", "model", model, "fallback", models[i+1], "error", err)
+	}
+	return ret, err
+}
+
+// completeModel completes the request with the model of the request, retrying
+// failures that are worth retrying.
+func completeModel(ctx context.Context, dynamic Config, provider string, req types.CompletionRequest, opts ...types.CompletionOptions) (*types.CompletionResponse, error) {
 	providerCfg, ok := dynamic.LLMProviders[provider]
 	if !ok {
 		return nil, fmt.Errorf("unknown LLM provider %q: not defined in llmProviders config", provider)
 	}
+
+	model := providerCfg.Models[req.Model]
+	if model.BaseURL != "" {
+		providerCfg.BaseURL = model.BaseURL
+	}
+	if providerCfg.Dialect == types.DialectAnthropicMessages && req.MaxTokens == 0 {
+		// Anthropic requires the most tokens to generate, ask for all the model can.
+		req.MaxTokens = limitsOf(req.Model, model).MaxOutputTokens
+	}
+	if providerCfg.Local && providerCfg.BaseURL == "" {
+		providerCfg.BaseURL = defaultLocalBaseURL
+	}
+
+	return withRetries(ctx, provider+"/"+req.Model, providerCfg.Retry, func() (*types.CompletionResponse, error) {
+		if len(req.Tools) == 0 {
+			return completeWith(ctx, provider, providerCfg, req, opts...)
+		}
+		if (model.ToolCalling != nil && !*model.ToolCalling) || lacksToolCalling(providerCfg.BaseURL, req.Model) {
+			return completeEmulatingTools(ctx, provider, providerCfg, req, opts...)
+		}
+
+		resp, err := completeWith(ctx, provider, providerCfg, req, opts...)
+		if err != nil && model.ToolCalling == nil && isToolCallingUnsupported(err) {
+			slog.Info("model does not support tool calling, emulating tool calls in the prompt", "provider", provider, "model", req.Model)
+			markLacksToolCalling(providerCfg.BaseURL, req.Model)
+			return completeEmulatingTools(ctx, provider, providerCfg, req, opts...)
+		}
+		return resp, err
+	})
+}
+
+// ModelInfo returns the limits of a model and whether its provider is local.
+// Local servers run models with their own limits, so only the configured ones
+// are used for local models.
+func (c Client) ModelInfo(ctx context.Context, model string) types.ModelInfo {
+	dynamic := c.dynamicConfig(ctx)
+	model, provider := resolveProvider(model, dynamic)
+	providerCfg := dynamic.LLMProviders[provider]
+
+	limits := modelLimits{
+		ContextWindow:   providerCfg.Models[model].ContextWindow,
+		MaxOutputTokens: providerCfg.Models[model].MaxOutputTokens,
+	}
+	if !providerCfg.Local {
+		limits = limitsOf(model, providerCfg.Models[model])
+	}
+	return types.ModelInfo{
+		ContextWindow:   limits.ContextWindow,
+		MaxOutputTokens: limits.MaxOutputTokens,
+		Local:           providerCfg.Local,
+	}
+}
+
+// CountTokens asks the provider of the model how many input tokens the request
+// has. Only providers with countTokens enabled are asked, and of those only
+// the dialects with a count tokens endpoint.
+func (c Client) CountTokens(ctx context.Context, req types.CompletionRequest) (int, error) {
+	dynamic := c.dynamicConfig(ctx)
+
+	var provider string
+	req.Model, provider = resolveProvider(req.Model, dynamic)
+	providerCfg, ok := dynamic.LLMProviders[provider]
+	if !ok || !providerCfg.CountTokens {
+		return 0, types.ErrTokenCountingUnsupported
+	}
+	if model := providerCfg.Models[req.Model]; model.BaseURL != "" {
+		providerCfg.BaseURL = model.BaseURL
+	}
+	req.Input = normalizeToolCalls(req.Input)
+
+	switch providerCfg.Dialect {
+	case types.DialectAnthropicMessages:
+		return anthropic.NewClient(anthropic.Config{
+			APIKey:  providerCfg.APIKey,
+			BaseURL: providerCfg.BaseURL,
+			Headers: providerCfg.Headers,
+			Bedrock: providerCfg.Bedrock,
+			Vertex:  providerCfg.Vertex,
+		}).CountTokens(ctx, req)
+	case types.DialectOpenAIResponses, "":
+		return responses.NewClient(responses.Config{
+			APIKey:  providerCfg.APIKey,
+			BaseURL: providerCfg.BaseURL,
+			Headers: providerCfg.Headers,
+		}).CountTokens(ctx, req)
+	}
+	return 0, types.ErrTokenCountingUnsupported
+}
+
+func completeWith(ctx context.Context, provider string, providerCfg LLMProviderConfig, req types.CompletionRequest, opts ...types.CompletionOptions) (*types.CompletionResponse, error) {
 	switch providerCfg.Dialect {
 	case types.DialectAnthropicMessages:
 		return anthropic.NewClient(anthropic.Config{
 			APIKey:  providerCfg.APIKey,
 			BaseURL: providerCfg.BaseURL,
 			Headers: providerCfg.Headers,
+			Bedrock: providerCfg.Bedrock,
+			Vertex:  providerCfg.Vertex,
 		}).Complete(ctx, req, opts...)
 	case types.DialectOpenAIChatCompletions:
 		return completions.NewClient(completions.Config{
@@ -166,16 +294,23 @@ func (c Client) dynamicConfig(ctx context.Context) Config {
 	cfg := Config{
 		DefaultModel:     c.defaultModel,
 		DefaultMiniModel: c.defaultMiniModel,
+		EmbeddingModel:   c.embeddingModel,
 		LLMProviders:     map[string]LLMProviderConfig{},
 	}
 
 	// Start with built-in/static provider refs (env var names)
 	for name, p := range c.cfg.LLMProviders {
 		cfg.LLMProviders[name] = LLMProviderConfig{
-			Dialect: p.Dialect,
-			APIKey:  p.APIKey,
-			BaseURL: p.BaseURL,
-			Headers: maps.Clone(p.Headers),
+			Dialect:     p.Dialect,
+			APIKey:      p.APIKey,
+			BaseURL:     p.BaseURL,
+			Headers:     maps.Clone(p.Headers),
+			Bedrock:     p.Bedrock,
+			Vertex:      p.Vertex,
+			Local:       p.Local,
+			Models:      p.Models,
+			Retry:       p.Retry,
+			CountTokens: p.CountTokens,
 		}
 	}
 
@@ -190,10 +325,16 @@ func (c Client) dynamicConfig(ctx context.Context) Config {
 	typesConfig := types.ConfigFromContext(ctx)
 	for name, p := range typesConfig.LLMProviders {
 		cfg.LLMProviders[name] = LLMProviderConfig{
-			Dialect: p.Dialect,
-			APIKey:  p.APIKey,
-			BaseURL: p.BaseURL,
-			Headers: maps.Clone(p.Headers),
+			Dialect:     p.Dialect,
+			APIKey:      p.APIKey,
+			BaseURL:     p.BaseURL,
+			Headers:     maps.Clone(p.Headers),
+			Bedrock:     p.Bedrock,
+			Vertex:      p.Vertex,
+			Local:       p.Local,
+			Models:      p.Models,
+			Retry:       p.Retry,
+			CountTokens: p.CountTokens,
 		}
 	}
 
@@ -204,16 +345,69 @@ func (c Client) dynamicConfig(ctx context.Context) Config {
 	if v := strings.TrimSpace(env["NANOBOT_DEFAULT_MINI_MODEL"]); v != "" {
 		cfg.DefaultMiniModel = v
 	}
+	if v := strings.TrimSpace(env["NANOBOT_EMBEDDING_MODEL"]); v != "" {
+		cfg.EmbeddingModel = v
+	}
 
 	// Resolve ${VAR} references in provider config using the session env
 	for name, p := range cfg.LLMProviders {
 		cfg.LLMProviders[name] = LLMProviderConfig{
-			Dialect: p.Dialect,
-			APIKey:  envvar.ReplaceString(env, p.APIKey),
-			BaseURL: envvar.ReplaceString(env, p.BaseURL),
-			Headers: envvar.ReplaceMap(env, p.Headers),
+			Dialect:     p.Dialect,
+			APIKey:      envvar.ReplaceString(env, p.APIKey),
+			BaseURL:     envvar.ReplaceString(env, p.BaseURL),
+			Headers:     envvar.ReplaceMap(env, p.Headers),
+			Bedrock:     replaceBedrock(env, p.Bedrock),
+			Vertex:      replaceVertex(env, p.Vertex),
+			Local:       p.Local,
+			Models:      replaceModels(env, p.Models),
+			Retry:       p.Retry,
+			CountTokens: p.CountTokens,
 		}
 	}
 
 	return cfg
 }
+
+// replaceBedrock resolves ${VAR} references in the Bedrock settings. Unset
+// settings default to the standard AWS environment variables.
+func replaceBedrock(env map[string]string, p *types.BedrockProvider) *types.BedrockProvider {
+	if p == nil {
+		return nil
+	}
+	return &types.BedrockProvider{
+		Region:          envvar.ReplaceString(env, cmp.Or(p.Region, "${AWS_REGION}")),
+		AccessKeyID:     envvar.ReplaceString(env, cmp.Or(p.AccessKeyID, "${AWS_ACCESS_KEY_ID}")),
+		SecretAccessKey: envvar.ReplaceString(env, cmp.Or(p.SecretAccessKey, "${AWS_SECRET_ACCESS_KEY}")),
+		SessionToken:    envvar.ReplaceString(env, cmp.Or(p.SessionToken, "${AWS_SESSION_TOKEN}")),
+		Models:          envvar.ReplaceMap(env, p.Models),
+	}
+}
+
+// replaceVertex resolves ${VAR} references in the Vertex AI settings. Unset
+// settings default to the standard Google Cloud environment variables.
+func replaceVertex(env map[string]string, p *types.VertexProvider) *types.VertexProvider {
+	if p == nil {
+		return nil
+	}
+	return &types.VertexProvider{
+		Region:          envvar.ReplaceString(env, cmp.Or(p.Region, "${CLOUD_ML_REGION}")),
+		ProjectID:       envvar.ReplaceString(env, cmp.Or(p.ProjectID, "${GOOGLE_CLOUD_PROJECT}")),
+		CredentialsFile: envvar.ReplaceString(env, cmp.Or(p.CredentialsFile, "${GOOGLE_APPLICATION_CREDENTIALS}")),
+		AccessToken:     envvar.ReplaceString(env, p.AccessToken),
+		Models:          envvar.ReplaceMap(env, p.Models),
+	}
+}
+
+// replaceModels resolves ${VAR} references in the base URLs of the model
+// settings.
+func replaceModels(env map[string]string, models map[string]types.LLMModel) map[string]types.LLMModel {
+	if models == nil {
+		return nil
+	}
+	result := make(map[string]types.LLMModel, len(models))
+	for name, m := range models {
+		m.BaseURL = envvar.ReplaceString(env, m.BaseURL)
+		result[name] = m
+	}
+	return result
+}
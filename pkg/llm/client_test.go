@@ -2,6 +2,11 @@ package llm
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/obot-platform/nanobot/pkg/mcp"
@@ -153,3 +158,95 @@ func TestDynamicConfigProviderResolution(t *testing.T) {
 		t.Errorf("from-env Headers[Authorization]: got %q, want %q", fromEnv.Headers["Authorization"], "Bearer bearer-token")
 	}
 }
+
+func TestCountTokens(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/messages/count_tokens" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		fmt.Fprint(w, `{"input_tokens": 42}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(Config{
+		LLMProviders: map[string]LLMProviderConfig{
+			"anthropic": {Dialect: types.DialectAnthropicMessages, BaseURL: server.URL, CountTokens: true},
+			"estimated": {Dialect: types.DialectAnthropicMessages, BaseURL: server.URL},
+		},
+	})
+	req := types.CompletionRequest{
+		Model:     "anthropic/claude-sonnet-4-5",
+		MaxTokens: 1000,
+		Input:     []types.Message{{Role: "user", Items: []types.CompletionItem{{Content: &mcp.Content{Type: "text", Text: "hi"}}}}},
+	}
+
+	tokens, err := client.CountTokens(t.Context(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokens != 42 {
+		t.Errorf("expected 42 tokens, got %d", tokens)
+	}
+	if _, ok := body["max_tokens"]; ok || body["model"] != "claude-sonnet-4-5" {
+		t.Errorf("expected only the input fields with the model name, got %v", body)
+	}
+
+	req.Model = "estimated/claude-sonnet-4-5"
+	if _, err := client.CountTokens(t.Context(), req); !errors.Is(err, types.ErrTokenCountingUnsupported) {
+		t.Errorf("expected ErrTokenCountingUnsupported for a provider without countTokens, got %v", err)
+	}
+}
+
+func TestModelInfo(t *testing.T) {
+	client := NewClient(Config{
+		LLMProviders: map[string]LLMProviderConfig{
+			"openai": {Dialect: types.DialectOpenAIResponses, Models: map[string]types.LLMModel{
+				"gpt-4o": {ContextWindow: 64_000},
+			}},
+			"anthropic": {Dialect: types.DialectAnthropicMessages},
+			"ollama":    {Dialect: types.DialectOpenAIChatCompletions, Local: true},
+		},
+	})
+
+	tests := []struct {
+		model string
+		want  types.ModelInfo
+	}{
+		{"anthropic/claude-sonnet-4-5-20250929", types.ModelInfo{ContextWindow: 200_000, MaxOutputTokens: 64_000}},
+		{"openai/gpt-4.1-mini", types.ModelInfo{ContextWindow: 1_047_576, MaxOutputTokens: 32_768}},
+		{"openai/gpt-4o", types.ModelInfo{ContextWindow: 64_000, MaxOutputTokens: 16_384}},
+		{"openai/unknown-model", types.ModelInfo{}},
+		{"ollama/gpt-4o", types.ModelInfo{Local: true}},
+	}
+	for _, tt := range tests {
+		if got := client.ModelInfo(t.Context(), tt.model); got != tt.want {
+			t.Errorf("ModelInfo(%q) = %+v, want %+v", tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestPing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		http.Error(rw, "unauthorized", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	client := NewClient(Config{
+		DefaultModel: "local/llama3",
+		LLMProviders: map[string]LLMProviderConfig{
+			"local": {Dialect: types.DialectOpenAIChatCompletions, BaseURL: "${LOCAL_BASE_URL}"},
+		},
+	})
+
+	if err := client.Ping(t.Context(), map[string]string{"LOCAL_BASE_URL": server.URL}); err != nil {
+		t.Errorf("expected any response to count as reachable, got %v", err)
+	}
+
+	server.Close()
+	if err := client.Ping(t.Context(), map[string]string{"LOCAL_BASE_URL": server.URL}); err == nil {
+		t.Error("expected an unreachable provider to fail")
+	}
+}
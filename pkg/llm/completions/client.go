@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
@@ -114,8 +115,32 @@ func (c *Client) complete(ctx context.Context, agentName string, req Request, op
 		initialized             = false
 		toolCalls               = make(map[int]*ToolCall)
 		toolCallPolicyViolation string
+		// openItems are the IDs of the items streamed with more to come, in
+		// the order they were started. They are closed when the choice
+		// finishes.
+		openItems []string
 	)
 
+	markOpen := func(id string, hasMore bool) {
+		if hasMore && !slices.Contains(openItems, id) {
+			openItems = append(openItems, id)
+		}
+	}
+	closeItems := func() {
+		for _, id := range openItems {
+			progress.Send(ctx, &types.CompletionProgress{
+				Model:     resp.Model,
+				Agent:     agentName,
+				MessageID: resp.ID,
+				Item: types.CompletionItem{
+					ID:      id,
+					Partial: true,
+				},
+			}, opt.ProgressToken)
+		}
+		openItems = nil
+	}
+
 	for lines.Scan() {
 		line := lines.Text()
 
@@ -192,12 +217,13 @@ func (c *Client) complete(ctx context.Context, agentName string, req Request, op
 				}
 				*resp.Choices[choice.Index].Message.Content.Text += *delta.Content
 
+				markOpen(contentItemID(resp.ID, choice.Index), !isFinished)
 				progress.Send(ctx, &types.CompletionProgress{
 					Model:     resp.Model,
 					Agent:     agentName,
 					MessageID: resp.ID,
 					Item: types.CompletionItem{
-						ID:      fmt.Sprintf("%s-%d", resp.ID, choice.Index),
+						ID:      contentItemID(resp.ID, choice.Index),
 						Partial: true,
 						HasMore: !isFinished,
 						Content: &mcp.Content{
@@ -215,12 +241,13 @@ func (c *Client) complete(ctx context.Context, agentName string, req Request, op
 				}
 				*resp.Choices[choice.Index].Message.Reasoning += *delta.Reasoning
 
+				markOpen(reasoningItemID(resp.ID, choice.Index), !isFinished)
 				progress.Send(ctx, &types.CompletionProgress{
 					Model:     resp.Model,
 					Agent:     agentName,
 					MessageID: resp.ID,
 					Item: types.CompletionItem{
-						ID:      fmt.Sprintf("%s-reasoning-%d", resp.ID, choice.Index),
+						ID:      reasoningItemID(resp.ID, choice.Index),
 						Partial: true,
 						HasMore: !isFinished,
 						Reasoning: &types.Reasoning{
@@ -255,12 +282,13 @@ func (c *Client) complete(ctx context.Context, agentName string, req Request, op
 						toolCalls[index].Function.Arguments += toolCall.Function.Arguments
 					}
 
+					markOpen(toolCallItemID(resp.ID, index), !isFinished)
 					progress.Send(ctx, &types.CompletionProgress{
 						Model:     resp.Model,
 						Agent:     agentName,
 						MessageID: resp.ID,
 						Item: types.CompletionItem{
-							ID:      fmt.Sprintf("%s-t-%d", resp.ID, index),
+							ID:      toolCallItemID(resp.ID, index),
 							Partial: true,
 							HasMore: !isFinished,
 							ToolCall: &types.ToolCall{
@@ -276,6 +304,7 @@ func (c *Client) complete(ctx context.Context, agentName string, req Request, op
 			// Handle finish reason
 			if choice.FinishReason != nil {
 				resp.Choices[choice.Index].FinishReason = choice.FinishReason
+				closeItems()
 			}
 
 			// Handle refusal
@@ -310,7 +339,7 @@ func (c *Client) complete(ctx context.Context, agentName string, req Request, op
 				Agent:     agentName,
 				MessageID: resp.ID,
 				Item: types.CompletionItem{
-					ID:      fmt.Sprintf("%s-%d", resp.ID, contentIndex),
+					ID:      contentItemID(resp.ID, contentIndex),
 					Partial: true,
 					Content: &mcp.Content{
 						Type: "text",
@@ -338,6 +367,9 @@ func (c *Client) complete(ctx context.Context, agentName string, req Request, op
 		return nil, "", "", fmt.Errorf("failed to read streaming response: %w", err)
 	}
 
+	// Some servers end the stream without a finish reason.
+	closeItems()
+
 	// Convert tool calls map to slice
 	if len(toolCalls) > 0 {
 		resp.Choices[0].Message.ToolCalls = make([]ToolCall, len(toolCalls))
@@ -353,3 +385,18 @@ func (c *Client) complete(ctx context.Context, agentName string, req Request, op
 
 	return &resp, inputReplacement, toolCallPolicyViolation, nil
 }
+
+// contentItemID, reasoningItemID, and toolCallItemID return the IDs of the
+// streamed items. The response uses the same IDs so clients can match its
+// items to the progress they rendered.
+func contentItemID(responseID string, choice int) string {
+	return fmt.Sprintf("%s-%d", responseID, choice)
+}
+
+func reasoningItemID(responseID string, choice int) string {
+	return fmt.Sprintf("%s-reasoning-%d", responseID, choice)
+}
+
+func toolCallItemID(responseID string, index int) string {
+	return fmt.Sprintf("%s-t-%d", responseID, index)
+}
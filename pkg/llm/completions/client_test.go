@@ -0,0 +1,80 @@
+package completions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+func TestCompleteProgressMatchesResponse(t *testing.T) {
+	chunks := []string{
+		`{"id":"chatcmpl-1","model":"m","choices":[{"index":0,"delta":{"role":"assistant","content":"Hel"}}]}`,
+		`{"id":"chatcmpl-1","model":"m","choices":[{"index":0,"delta":{"content":"lo"}}]}`,
+		`{"id":"chatcmpl-1","model":"m","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call-1","type":"function","function":{"name":"read","arguments":"{}"}}]}}]}`,
+		`{"id":"chatcmpl-1","model":"m","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range chunks {
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	serverSession, err := mcp.NewServerSession(t.Context(), mcp.MessageHandlerFunc(func(context.Context, mcp.Message) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer serverSession.Close(false)
+	ctx := mcp.WithSession(t.Context(), serverSession.GetSession())
+
+	// A first message to replay the progress after.
+	if err := serverSession.Send(ctx, mcp.Message{Method: "notifications/message"}); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := NewClient(Config{BaseURL: server.URL}).Complete(ctx, types.CompletionRequest{Model: "m"}, types.CompletionOptions{
+		ProgressToken: "token",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		replay, _, _ = serverSession.Subscribe(t.Context(), 1)
+		hasMore      = map[string]bool{}
+	)
+	for _, event := range replay {
+		var notification mcp.NotificationProgressRequest
+		if err := json.Unmarshal(event.Message.Params, &notification); err != nil {
+			t.Fatal(err)
+		}
+		var progress types.CompletionProgress
+		if err := mcp.JSONCoerce(notification.Meta[types.CompletionProgressMetaKey], &progress); err != nil {
+			t.Fatal(err)
+		}
+		if progress.MessageID != resp.Output.ID {
+			t.Errorf("progress message ID %q, want %q", progress.MessageID, resp.Output.ID)
+		}
+		hasMore[progress.Item.ID] = progress.Item.HasMore
+	}
+
+	if len(resp.Output.Items) != 2 {
+		t.Fatalf("expected text and a tool call, got %+v", resp.Output.Items)
+	}
+	for _, item := range resp.Output.Items {
+		more, streamed := hasMore[item.ID]
+		if !streamed {
+			t.Errorf("item %q was not streamed, streamed items %v", item.ID, hasMore)
+		} else if more {
+			t.Errorf("item %q was never closed", item.ID)
+		}
+	}
+}
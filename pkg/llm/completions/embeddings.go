@@ -0,0 +1,78 @@
+package completions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+)
+
+type embeddingsRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type embeddingsResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// Embed returns one embedding per input, in input order, from the OpenAI
+// compatible embeddings API.
+func (c *Client) Embed(ctx context.Context, model string, inputs []string) ([][]float32, error) {
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(embeddingsRequest{
+		Model: model,
+		Input: inputs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(mcp.UserContext(ctx), http.MethodPost, c.BaseURL+"/embeddings", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range c.Headers {
+		httpReq.Header.Set(key, value)
+	}
+
+	httpResp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(httpResp.Body)
+		return nil, fmt.Errorf("failed to get response from OpenAI Embeddings API: %s %q", httpResp.Status, string(body))
+	}
+
+	var resp embeddingsResponse
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to decode embeddings response: %w", err)
+	}
+
+	result := make([][]float32, len(inputs))
+	for _, item := range resp.Data {
+		if item.Index < 0 || item.Index >= len(result) {
+			return nil, fmt.Errorf("embeddings response has out of range index %d", item.Index)
+		}
+		result[item.Index] = item.Embedding
+	}
+	for i, embedding := range result {
+		if embedding == nil {
+			return nil, fmt.Errorf("embeddings response is missing input %d", i)
+		}
+	}
+	return result, nil
+}
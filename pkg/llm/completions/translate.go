@@ -26,7 +26,7 @@ func toResponse(resp *Response, created time.Time) (*types.CompletionResponse, e
 			// Handle reasoning (for reasoning models)
 			if choice.Message.Reasoning != nil && *choice.Message.Reasoning != "" {
 				result.Output.Items = append(result.Output.Items, types.CompletionItem{
-					ID: fmt.Sprintf("%s-reasoning", resp.ID),
+					ID: reasoningItemID(resp.ID, choice.Index),
 					Reasoning: &types.Reasoning{
 						Summary: []types.SummaryText{
 							{
@@ -40,7 +40,7 @@ func toResponse(resp *Response, created time.Time) (*types.CompletionResponse, e
 			// Handle content
 			if choice.Message.Content.Text != nil {
 				result.Output.Items = append(result.Output.Items, types.CompletionItem{
-					ID: fmt.Sprintf("%s-content", resp.ID),
+					ID: contentItemID(resp.ID, choice.Index),
 					Content: &mcp.Content{
 						Type: "text",
 						Text: *choice.Message.Content.Text,
@@ -51,7 +51,7 @@ func toResponse(resp *Response, created time.Time) (*types.CompletionResponse, e
 			// Handle tool calls
 			for i, toolCall := range choice.Message.ToolCalls {
 				result.Output.Items = append(result.Output.Items, types.CompletionItem{
-					ID: fmt.Sprintf("%s-%d", resp.ID, i),
+					ID: toolCallItemID(resp.ID, i),
 					ToolCall: &types.ToolCall{
 						CallID:    toolCall.ID,
 						Name:      toolCall.Function.Name,
@@ -73,9 +73,32 @@ func toResponse(resp *Response, created time.Time) (*types.CompletionResponse, e
 		}
 	}
 
+	result.Usage = toUsage(resp.Usage)
+
 	return result, nil
 }
 
+// toUsage converts the usage of a response. Prompt tokens include the cached
+// tokens, which are counted separately.
+func toUsage(usage *Usage) *types.Usage {
+	if usage == nil {
+		return nil
+	}
+	result := &types.Usage{
+		InputTokens:  usage.PromptTokens,
+		OutputTokens: usage.CompletionTokens,
+		Completions:  1,
+	}
+	if usage.PromptTokensDetails != nil {
+		result.CacheReadTokens = usage.PromptTokensDetails.CachedTokens
+		result.InputTokens -= result.CacheReadTokens
+	}
+	if usage.CompletionTokensDetails != nil {
+		result.ReasoningTokens = usage.CompletionTokensDetails.ReasoningTokens
+	}
+	return result
+}
+
 func toRequest(req *types.CompletionRequest) (Request, error) {
 	if req.MaxTokens == 0 {
 		req.MaxTokens = 4096
@@ -103,6 +126,10 @@ func toRequest(req *types.CompletionRequest) (Request, error) {
 		})
 	}
 
+	if req.ParallelToolCalls != nil && len(req.Tools) > 0 {
+		result.ParallelToolCalls = req.ParallelToolCalls
+	}
+
 	// Handle tool choice
 	if req.ToolChoice != "" {
 		switch req.ToolChoice {
@@ -144,6 +171,7 @@ func toRequest(req *types.CompletionRequest) (Request, error) {
 		openAIMsg := Message{
 			Role: msg.Role,
 		}
+		var toolMessages []Message
 
 		// Handle single text content case
 		if len(msg.Items) == 1 && msg.Items[0].Content != nil && msg.Items[0].Content.Type == "text" && msg.Items[0].Content.Text != "" {
@@ -216,9 +244,8 @@ func toRequest(req *types.CompletionRequest) (Request, error) {
 						},
 					})
 				} else if item.ToolCallResult != nil {
-					// Handle tool call results (tool message)
-					openAIMsg.Role = "tool"
-					openAIMsg.ToolCallID = item.ToolCallResult.CallID
+					// Handle tool call results, each one is its own tool message
+					// so that parallel calls are all answered
 
 					// Combine all content into text
 					var resultText string
@@ -263,7 +290,13 @@ func toRequest(req *types.CompletionRequest) (Request, error) {
 						resultText = "Tool execution completed"
 					}
 
-					openAIMsg.Content.Text = &resultText
+					toolMessages = append(toolMessages, Message{
+						Role:       "tool",
+						ToolCallID: item.ToolCallResult.CallID,
+						Content: MessageContent{
+							Text: &resultText,
+						},
+					})
 				}
 			}
 
@@ -272,7 +305,10 @@ func toRequest(req *types.CompletionRequest) (Request, error) {
 			}
 		}
 
-		result.Messages = append(result.Messages, openAIMsg)
+		if len(toolMessages) == 0 || openAIMsg.Content.Text != nil || len(openAIMsg.Content.ContentParts) > 0 || len(openAIMsg.ToolCalls) > 0 {
+			result.Messages = append(result.Messages, openAIMsg)
+		}
+		result.Messages = append(result.Messages, toolMessages...)
 	}
 
 	// Add system message if present
@@ -16,6 +16,7 @@ type Request struct {
 	Stop                []string        `json:"stop,omitempty"`
 	ToolChoice          *ToolChoice     `json:"tool_choice,omitempty"`
 	Tools               []Tool          `json:"tools,omitempty"`
+	ParallelToolCalls   *bool           `json:"parallel_tool_calls,omitempty"`
 	User                string          `json:"user,omitempty"`
 	Metadata            map[string]any  `json:"metadata,omitempty"`
 	ResponseFormat      *ResponseFormat `json:"response_format,omitempty"`
@@ -0,0 +1,35 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/obot-platform/nanobot/pkg/llm/completions"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// Embed returns an embedding for each input using the configured embedding
+// model. Only providers that speak an OpenAI dialect support embeddings.
+func (c Client) Embed(ctx context.Context, inputs []string) ([][]float32, error) {
+	dynamic := c.dynamicConfig(ctx)
+	if dynamic.EmbeddingModel == "" {
+		return nil, fmt.Errorf("no embedding model is configured")
+	}
+
+	model, provider := resolveProvider(dynamic.EmbeddingModel, dynamic)
+	providerCfg, ok := dynamic.LLMProviders[provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown LLM provider %q: not defined in llmProviders config", provider)
+	}
+
+	switch providerCfg.Dialect {
+	case types.DialectAnthropicMessages, types.DialectBifrostRequest:
+		return nil, fmt.Errorf("LLM provider %q does not support embeddings", provider)
+	default:
+		return completions.NewClient(completions.Config{
+			APIKey:  providerCfg.APIKey,
+			BaseURL: providerCfg.BaseURL,
+			Headers: providerCfg.Headers,
+		}).Embed(ctx, model, inputs)
+	}
+}
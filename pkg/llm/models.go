@@ -0,0 +1,64 @@
+package llm
+
+import (
+	"strings"
+
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// modelLimits are the context window and most output tokens of a model.
+type modelLimits struct {
+	ContextWindow   int
+	MaxOutputTokens int
+}
+
+// knownLimits are the limits of well known models, by model name prefix. The
+// models of a provider's models config override them.
+var knownLimits = map[string]modelLimits{
+	"gpt-5":             {ContextWindow: 400_000, MaxOutputTokens: 128_000},
+	"gpt-4.1":           {ContextWindow: 1_047_576, MaxOutputTokens: 32_768},
+	"gpt-4o":            {ContextWindow: 128_000, MaxOutputTokens: 16_384},
+	"o3":                {ContextWindow: 200_000, MaxOutputTokens: 100_000},
+	"o4-mini":           {ContextWindow: 200_000, MaxOutputTokens: 100_000},
+	"claude-opus-4":     {ContextWindow: 200_000, MaxOutputTokens: 32_000},
+	"claude-opus-4-5":   {ContextWindow: 200_000, MaxOutputTokens: 64_000},
+	"claude-opus-4-6":   {ContextWindow: 200_000, MaxOutputTokens: 128_000},
+	"claude-sonnet-4":   {ContextWindow: 200_000, MaxOutputTokens: 64_000},
+	"claude-3-7-sonnet": {ContextWindow: 200_000, MaxOutputTokens: 64_000},
+	"claude-3-5-sonnet": {ContextWindow: 200_000, MaxOutputTokens: 8_192},
+	"claude-haiku-4-5":  {ContextWindow: 200_000, MaxOutputTokens: 64_000},
+	"claude-3-5-haiku":  {ContextWindow: 200_000, MaxOutputTokens: 8_192},
+	"gemini-2.5-pro":    {ContextWindow: 1_048_576, MaxOutputTokens: 65_536},
+	"gemini-2.5-flash":  {ContextWindow: 1_048_576, MaxOutputTokens: 65_536},
+}
+
+// byPrefix looks up a model in a table keyed by model name prefix, matching
+// the longest known prefix so that dated versions get the entry of their
+// family. A provider in front of the name, like "anthropic/", is ignored.
+func byPrefix[V any](table map[string]V, model string) (V, bool) {
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+
+	var match string
+	for prefix := range table {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(match) {
+			match = prefix
+		}
+	}
+	value, ok := table[match]
+	return value, ok && match != ""
+}
+
+// limitsOf returns the limits of a model, from the models config of its
+// provider and then the built-in table. Unknown limits are zero.
+func limitsOf(model string, configured types.LLMModel) modelLimits {
+	result, _ := byPrefix(knownLimits, model)
+	if configured.ContextWindow > 0 {
+		result.ContextWindow = configured.ContextWindow
+	}
+	if configured.MaxOutputTokens > 0 {
+		result.MaxOutputTokens = configured.MaxOutputTokens
+	}
+	return result
+}
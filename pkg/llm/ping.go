@@ -0,0 +1,52 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// Ping checks that the provider of the default model is reachable with the
+// environment. Any HTTP response counts, only failing to connect is an error,
+// so checking spends no tokens. Bedrock and Vertex AI providers are skipped.
+func (c Client) Ping(ctx context.Context, env map[string]string) error {
+	session := mcp.NewEmptySession(ctx)
+	session.SetEnv(env)
+	dynamic := c.dynamicConfig(mcp.WithSession(ctx, session))
+
+	model, provider := resolveProvider("default", dynamic)
+	providerCfg, ok := dynamic.LLMProviders[provider]
+	if !ok {
+		return fmt.Errorf("unknown LLM provider %q of the default model", provider)
+	}
+	if m := providerCfg.Models[model]; m.BaseURL != "" {
+		providerCfg.BaseURL = m.BaseURL
+	}
+	if providerCfg.Local && providerCfg.BaseURL == "" {
+		providerCfg.BaseURL = defaultLocalBaseURL
+	}
+	if providerCfg.BaseURL == "" {
+		switch {
+		case providerCfg.Bedrock != nil || providerCfg.Vertex != nil:
+			return nil
+		case providerCfg.Dialect == types.DialectAnthropicMessages:
+			providerCfg.BaseURL = "https://api.anthropic.com/v1"
+		default:
+			providerCfg.BaseURL = "https://api.openai.com/v1"
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, providerCfg.BaseURL, nil)
+	if err != nil {
+		return fmt.Errorf("invalid base URL of LLM provider %q: %w", provider, err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("LLM provider %q is unreachable: %w", provider, err)
+	}
+	_ = resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,71 @@
+package llm
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// Price is what a model charges, in US dollars per million tokens.
+type Price struct {
+	Input      float64
+	Output     float64
+	CacheRead  float64
+	CacheWrite float64
+}
+
+func (p Price) Cost(usage types.Usage) float64 {
+	return (float64(usage.InputTokens)*p.Input +
+		float64(usage.OutputTokens)*p.Output +
+		float64(usage.CacheReadTokens)*p.CacheRead +
+		float64(usage.CacheWriteTokens)*p.CacheWrite) / 1_000_000
+}
+
+// prices are the list prices of well known models, by model name prefix.
+var prices = map[string]Price{
+	"gpt-5":             {Input: 1.25, Output: 10, CacheRead: 0.125},
+	"gpt-5-mini":        {Input: 0.25, Output: 2, CacheRead: 0.025},
+	"gpt-5-nano":        {Input: 0.05, Output: 0.4, CacheRead: 0.005},
+	"gpt-4.1":           {Input: 2, Output: 8, CacheRead: 0.5},
+	"gpt-4.1-mini":      {Input: 0.4, Output: 1.6, CacheRead: 0.1},
+	"gpt-4.1-nano":      {Input: 0.1, Output: 0.4, CacheRead: 0.025},
+	"gpt-4o":            {Input: 2.5, Output: 10, CacheRead: 1.25},
+	"gpt-4o-mini":       {Input: 0.15, Output: 0.6, CacheRead: 0.075},
+	"o3":                {Input: 2, Output: 8, CacheRead: 0.5},
+	"o4-mini":           {Input: 1.1, Output: 4.4, CacheRead: 0.275},
+	"claude-opus-4":     {Input: 15, Output: 75, CacheRead: 1.5, CacheWrite: 18.75},
+	"claude-opus-4-5":   {Input: 5, Output: 25, CacheRead: 0.5, CacheWrite: 6.25},
+	"claude-opus-4-6":   {Input: 5, Output: 25, CacheRead: 0.5, CacheWrite: 6.25},
+	"claude-sonnet-4":   {Input: 3, Output: 15, CacheRead: 0.3, CacheWrite: 3.75},
+	"claude-3-7-sonnet": {Input: 3, Output: 15, CacheRead: 0.3, CacheWrite: 3.75},
+	"claude-haiku-4-5":  {Input: 1, Output: 5, CacheRead: 0.1, CacheWrite: 1.25},
+	"claude-3-5-haiku":  {Input: 0.8, Output: 4, CacheRead: 0.08, CacheWrite: 1},
+}
+
+// PriceOf returns the price of the model, matched by the longest known prefix
+// of its name so that dated versions get the price of their family. A
+// provider in front of the name, like "anthropic/", is ignored.
+func PriceOf(model string) (Price, bool) {
+	return byPrefix(prices, model)
+}
+
+// UsageReport prices the usage of a session. Models with unknown prices are
+// reported without a cost.
+func UsageReport(usage types.SessionUsage) types.UsageReport {
+	var report types.UsageReport
+	for _, model := range slices.Sorted(maps.Keys(usage.Models)) {
+		modelUsage := types.ModelUsage{
+			Model: model,
+			Usage: usage.Models[model],
+		}
+		if price, ok := PriceOf(model); ok {
+			cost := price.Cost(modelUsage.Usage)
+			modelUsage.Cost = &cost
+			report.Cost += cost
+		}
+		report.Models = append(report.Models, modelUsage)
+		report.Total.Add(modelUsage.Usage)
+	}
+	return report
+}
@@ -0,0 +1,62 @@
+package llm
+
+import (
+	"math"
+	"testing"
+
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+func TestPriceOf(t *testing.T) {
+	tests := []struct {
+		model string
+		want  Price
+		found bool
+	}{
+		{"gpt-4.1", prices["gpt-4.1"], true},
+		{"gpt-4.1-mini-2025-04-14", prices["gpt-4.1-mini"], true},
+		{"openai/gpt-4.1-nano", prices["gpt-4.1-nano"], true},
+		{"claude-opus-4-6", prices["claude-opus-4-6"], true},
+		{"claude-opus-4-20250514", prices["claude-opus-4"], true},
+		{"anthropic/claude-sonnet-4-5-20250929", prices["claude-sonnet-4"], true},
+		{"llama3.1", Price{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			got, found := PriceOf(tt.model)
+			if got != tt.want || found != tt.found {
+				t.Errorf("PriceOf(%q) = %+v, %v, want %+v, %v", tt.model, got, found, tt.want, tt.found)
+			}
+		})
+	}
+}
+
+func TestUsageReport(t *testing.T) {
+	var usage types.SessionUsage
+	usage.Add("claude-sonnet-4-5", types.Usage{InputTokens: 1_000_000, OutputTokens: 100_000, CacheReadTokens: 2_000_000, Completions: 1})
+	usage.Add("claude-sonnet-4-5", types.Usage{CacheWriteTokens: 1_000_000, Completions: 1})
+	usage.Add("local-model", types.Usage{InputTokens: 500, OutputTokens: 50, Completions: 1})
+
+	report := UsageReport(usage)
+	if len(report.Models) != 2 {
+		t.Fatalf("expected 2 models, got %+v", report.Models)
+	}
+
+	sonnet := report.Models[0]
+	if sonnet.Model != "claude-sonnet-4-5" || sonnet.Completions != 2 {
+		t.Errorf("unexpected usage for %s: %+v", sonnet.Model, sonnet.Usage)
+	}
+	// 3 input + 1.5 output + 0.6 cache read + 3.75 cache write
+	if sonnet.Cost == nil || math.Abs(*sonnet.Cost-8.85) > 1e-9 {
+		t.Errorf("expected a cost of 8.85, got %v", sonnet.Cost)
+	}
+
+	if local := report.Models[1]; local.Model != "local-model" || local.Cost != nil {
+		t.Errorf("expected no cost for a model with unknown prices, got %+v", local)
+	}
+
+	if report.Total.InputTokens != 1_000_500 || report.Total.Completions != 3 || math.Abs(report.Cost-8.85) > 1e-9 {
+		t.Errorf("unexpected total %+v, cost %v", report.Total, report.Cost)
+	}
+}
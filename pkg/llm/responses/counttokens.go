@@ -0,0 +1,63 @@
+package responses
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// CountTokens asks the input tokens endpoint of the Responses API how many
+// input tokens the request has.
+func (c *Client) CountTokens(ctx context.Context, completionRequest types.CompletionRequest) (int, error) {
+	req, err := toRequest(&completionRequest)
+	if err != nil {
+		return 0, err
+	}
+
+	// The endpoint only takes the fields that make up the input.
+	req.Include = nil
+	req.MaxOutputTokens = nil
+	req.Metadata = nil
+	req.ServiceTier = nil
+	req.Store = nil
+	req.Stream = nil
+	req.User = ""
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		return 0, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(mcp.UserContext(ctx), http.MethodPost, c.BaseURL+"/responses/input_tokens", bytes.NewBuffer(data))
+	if err != nil {
+		return 0, err
+	}
+	for key, value := range c.Headers {
+		httpReq.Header.Set(key, value)
+	}
+
+	httpResp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return 0, err
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(httpResp.Body)
+		return 0, fmt.Errorf("failed to count tokens with OpenAI Responses API: %s %q", httpResp.Status, string(body))
+	}
+
+	var resp struct {
+		InputTokens int `json:"input_tokens"`
+	}
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return 0, fmt.Errorf("failed to decode token count: %w", err)
+	}
+	return resp.InputTokens, nil
+}
@@ -65,9 +65,26 @@ func toResponse(req *types.CompletionRequest, resp *Response) (*types.Completion
 		}
 	}
 
+	result.Usage = toUsage(resp.Usage)
+
 	return result, nil
 }
 
+// toUsage converts the usage of a response. Input tokens include the cached
+// tokens, which are counted separately.
+func toUsage(usage Usage) *types.Usage {
+	if usage == (Usage{}) {
+		return nil
+	}
+	return &types.Usage{
+		InputTokens:     usage.InputTokens - usage.InputTokensDetails.CachedTokens,
+		OutputTokens:    usage.OutputTokens,
+		CacheReadTokens: usage.InputTokensDetails.CachedTokens,
+		ReasoningTokens: usage.OutputTokensDetails.ReasoningTokens,
+		Completions:     1,
+	}
+}
+
 func toSamplingMessageFromOutputMessage(output *Message) (result []types.CompletionItem) {
 	for _, content := range output.Content {
 		if content.OutputText != nil {
@@ -129,6 +146,10 @@ func toRequest(completion *types.CompletionRequest) (req Request, _ error) {
 		req.MaxOutputTokens = &completion.MaxTokens
 	}
 
+	if completion.ParallelToolCalls != nil && len(completion.Tools) > 0 {
+		req.ParallelToolCalls = completion.ParallelToolCalls
+	}
+
 	if completion.ToolChoice != "" {
 		switch completion.ToolChoice {
 		case "none", "auto", "required":
@@ -0,0 +1,189 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// errCircuitOpen is returned without calling the provider while the circuit
+// breaker of a model is open.
+var errCircuitOpen = errors.New("circuit breaker is open")
+
+// retryableStatus matches the HTTP status that the provider clients put in
+// their errors, for rate limits, timeouts and server errors. 529 is the
+// status of an overloaded Anthropic API.
+var retryableStatus = regexp.MustCompile(`: (408|429|5\d\d) [A-Za-z]`)
+
+// isRetryable checks whether an error of a completion is worth retrying, the
+// same request may succeed later.
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, errCircuitOpen) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	if _, ok := errors.AsType[net.Error](err); ok {
+		return true
+	}
+	msg := err.Error()
+	if retryableStatus.MatchString(msg) {
+		return true
+	}
+	msg = strings.ToLower(msg)
+	return strings.Contains(msg, "overloaded") || strings.Contains(msg, "rate limit") || strings.Contains(msg, "rate_limit")
+}
+
+type retryPolicy struct {
+	maxRetries       int
+	initialDelay     time.Duration
+	maxDelay         time.Duration
+	failureThreshold int
+	cooldown         time.Duration
+}
+
+func newRetryPolicy(cfg *types.LLMRetry) retryPolicy {
+	p := retryPolicy{
+		maxRetries:       3,
+		initialDelay:     time.Second,
+		maxDelay:         30 * time.Second,
+		failureThreshold: 5,
+		cooldown:         30 * time.Second,
+	}
+	if cfg == nil {
+		return p
+	}
+	if cfg.MaxRetries != nil {
+		p.maxRetries = *cfg.MaxRetries
+	}
+	if cfg.InitialDelayMS > 0 {
+		p.initialDelay = time.Duration(cfg.InitialDelayMS) * time.Millisecond
+	}
+	if cfg.MaxDelayMS > 0 {
+		p.maxDelay = time.Duration(cfg.MaxDelayMS) * time.Millisecond
+	}
+	if cfg.FailureThreshold > 0 {
+		p.failureThreshold = cfg.FailureThreshold
+	}
+	if cfg.CooldownMS > 0 {
+		p.cooldown = time.Duration(cfg.CooldownMS) * time.Millisecond
+	}
+	return p
+}
+
+// delay returns the backoff before a retry, doubled for each attempt and
+// jittered so concurrent requests don't retry in lockstep.
+func (p retryPolicy) delay(attempt int) time.Duration {
+	d := p.initialDelay << attempt
+	if d <= 0 || d > p.maxDelay {
+		d = p.maxDelay
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// breakers holds the circuit breaker of each provider and model.
+var breakers sync.Map
+
+type circuitBreaker struct {
+	lock      sync.Mutex
+	name      string
+	failures  int
+	openUntil time.Time
+}
+
+func breakerFor(name string) *circuitBreaker {
+	b, _ := breakers.LoadOrStore(name, &circuitBreaker{name: name})
+	return b.(*circuitBreaker)
+}
+
+// allow checks whether a request may be sent. Once the cooldown of an open
+// breaker has passed a single trial request is let through, the next one
+// waits for another cooldown unless the trial closes the breaker.
+func (b *circuitBreaker) allow(policy retryPolicy, now time.Time) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.openUntil.IsZero() {
+		return true
+	}
+	if now.Before(b.openUntil) {
+		return false
+	}
+	b.openUntil = now.Add(policy.cooldown)
+	slog.Info("LLM circuit breaker half-open, sending a trial request", "model", b.name)
+	return true
+}
+
+func (b *circuitBreaker) success() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if !b.openUntil.IsZero() {
+		slog.Info("LLM circuit breaker closed", "model", b.name)
+	}
+	b.failures = 0
+	b.openUntil = time.Time{}
+}
+
+func (b *circuitBreaker) failure(policy retryPolicy, now time.Time) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.failures++
+	if !b.openUntil.IsZero() {
+		b.openUntil = now.Add(policy.cooldown)
+		slog.Warn("LLM circuit breaker reopened, the trial request failed", "model", b.name, "cooldown", policy.cooldown)
+	} else if b.failures >= policy.failureThreshold {
+		b.openUntil = now.Add(policy.cooldown)
+		slog.Warn("LLM circuit breaker opened", "model", b.name, "failures", b.failures, "cooldown", policy.cooldown)
+	}
+}
+
+// withRetries calls complete, retrying errors worth retrying with exponential
+// backoff. Every failure counts against the circuit breaker of the model, an
+// open breaker fails right away so the caller can fail over.
+func withRetries(ctx context.Context, name string, cfg *types.LLMRetry, complete func() (*types.CompletionResponse, error)) (*types.CompletionResponse, error) {
+	var (
+		policy  = newRetryPolicy(cfg)
+		breaker = breakerFor(name)
+	)
+	for attempt := 0; ; attempt++ {
+		if !breaker.allow(policy, time.Now()) {
+			return nil, fmt.Errorf("%w for %s", errCircuitOpen, name)
+		}
+
+		resp, err := complete()
+		if ctx.Err() != nil {
+			return resp, err
+		}
+		if err == nil || !isRetryable(err) {
+			// The provider answered, even if it rejected the request.
+			breaker.success()
+			return resp, err
+		}
+
+		breaker.failure(policy, time.Now())
+		if attempt >= policy.maxRetries {
+			return nil, err
+		}
+
+		delay := policy.delay(attempt)
+		slog.Warn("retrying failed LLM completion", "model", name, "attempt", attempt+1, "delay", delay, "error", err)
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(delay):
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// completionsServer answers chat completions with text, after failing the
+// first failures requests with status.
+func completionsServer(t *testing.T, status, failures int, text string) (*httptest.Server, *int) {
+	t.Helper()
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= failures {
+			http.Error(w, `{"error":{"message":"try again later"}}`, status)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\",\"content\":%q},\"finish_reason\":\"stop\"}]}\n\n", text)
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func responseText(resp *types.CompletionResponse) string {
+	for _, item := range resp.Output.Items {
+		if item.Content != nil {
+			return item.Content.Text
+		}
+	}
+	return ""
+}
+
+func TestCompleteRetries(t *testing.T) {
+	server, requests := completionsServer(t, http.StatusServiceUnavailable, 2, "hello")
+
+	client := NewClient(Config{
+		LLMProviders: map[string]LLMProviderConfig{
+			"flaky": {
+				Dialect: types.DialectOpenAIChatCompletions,
+				BaseURL: server.URL,
+				Retry:   &types.LLMRetry{InitialDelayMS: 1, MaxDelayMS: 2},
+			},
+		},
+	})
+
+	resp, err := client.Complete(t.Context(), types.CompletionRequest{
+		Model: "flaky/retry-model",
+		Input: []types.Message{{Role: "user", Items: []types.CompletionItem{{Content: &mcp.Content{Type: "text", Text: "hi"}}}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := responseText(resp); got != "hello" {
+		t.Errorf("expected hello, got %q", got)
+	}
+	if *requests != 3 {
+		t.Errorf("expected 3 requests, got %d", *requests)
+	}
+}
+
+func TestCompleteFailsOver(t *testing.T) {
+	down, downRequests := completionsServer(t, http.StatusTooManyRequests, 100, "")
+	up, _ := completionsServer(t, 0, 0, "from the fallback")
+	rejecting, rejectingRequests := completionsServer(t, http.StatusBadRequest, 100, "")
+
+	client := NewClient(Config{
+		LLMProviders: map[string]LLMProviderConfig{
+			"down": {
+				Dialect: types.DialectOpenAIChatCompletions,
+				BaseURL: down.URL,
+				Retry:   &types.LLMRetry{MaxRetries: new(1), InitialDelayMS: 1},
+			},
+			"up": {
+				Dialect: types.DialectOpenAIChatCompletions,
+				BaseURL: up.URL,
+			},
+			"rejecting": {
+				Dialect: types.DialectOpenAIChatCompletions,
+				BaseURL: rejecting.URL,
+			},
+		},
+	})
+	input := []types.Message{{Role: "user", Items: []types.CompletionItem{{Content: &mcp.Content{Type: "text", Text: "hi"}}}}}
+
+	resp, err := client.Complete(t.Context(), types.CompletionRequest{
+		Model:          "down/primary",
+		FallbackModels: []string{"up/secondary"},
+		Input:          input,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := responseText(resp); got != "from the fallback" {
+		t.Errorf("expected the fallback's reply, got %q", got)
+	}
+	if *downRequests != 2 {
+		t.Errorf("expected the primary to be tried twice, got %d", *downRequests)
+	}
+
+	// Requests the provider rejects fail without retries or failover.
+	_, err = client.Complete(t.Context(), types.CompletionRequest{
+		Model:          "rejecting/primary",
+		FallbackModels: []string{"up/secondary"},
+		Input:          input,
+	})
+	if err == nil {
+		t.Fatal("expected the rejected request to fail")
+	}
+	if *rejectingRequests != 1 {
+		t.Errorf("expected a single request, got %d", *rejectingRequests)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var (
+		policy  = newRetryPolicy(&types.LLMRetry{FailureThreshold: 2, CooldownMS: 1000})
+		breaker = &circuitBreaker{name: "test/breaker"}
+		now     = time.Now()
+	)
+
+	breaker.failure(policy, now)
+	if !breaker.allow(policy, now) {
+		t.Fatal("breaker opened before reaching the threshold")
+	}
+	breaker.failure(policy, now)
+	if breaker.allow(policy, now) {
+		t.Fatal("breaker did not open at the threshold")
+	}
+
+	// After the cooldown a single trial request is let through.
+	later := now.Add(time.Second)
+	if !breaker.allow(policy, later) {
+		t.Fatal("breaker did not let a trial request through after the cooldown")
+	}
+	if breaker.allow(policy, later) {
+		t.Fatal("breaker let a second request through while half-open")
+	}
+
+	breaker.success()
+	if !breaker.allow(policy, later) {
+		t.Fatal("breaker did not close after a successful trial")
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{errors.New(`failed to get response from Anthropic API: 529 status code 529 "{\"type\":\"overloaded_error\"}"`), true},
+		{errors.New(`failed to get response from OpenAI Responses API: 429 Too Many Requests "slow down"`), true},
+		{errors.New(`failed to get response from OpenAI Chat Completions API: 502 Bad Gateway ""`), true},
+		{errors.New(`failed to get response from OpenAI Chat Completions API: 400 Bad Request "max_tokens 500 is too large"`), false},
+		{fmt.Errorf("%w for openai/gpt-4o", errCircuitOpen), true},
+	}
+	for _, tt := range tests {
+		if got := isRetryable(tt.err); got != tt.want {
+			t.Errorf("isRetryable(%q) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/schema"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// maxRepairTurns bounds how many times a model is asked to fix output that
+// doesn't match the output schema.
+const maxRepairTurns = 2
+
+// nativeStructuredOutput checks whether the dialect sends the output schema
+// to the provider. The others are told about it in the system prompt.
+func nativeStructuredOutput(dialect types.Dialect) bool {
+	switch dialect {
+	case types.DialectOpenAIChatCompletions, types.DialectOpenAIResponses, types.DialectOpenResponses, "":
+		return true
+	}
+	return false
+}
+
+func outputSchemaPrompt(output *types.OutputSchema) string {
+	prompt := "\n\n# Output format\n\nWhen you give your final answer, reply with only a JSON value, without code fences or other text, that matches this JSON schema:\n\n" +
+		string(output.ToSchema())
+	if output.Description != "" {
+		prompt += "\n\nThe output is " + output.Description
+	}
+	return prompt
+}
+
+// completeStructured completes a request with an output schema, validating
+// the final answer of the model against the schema. Invalid answers are sent
+// back to the model with the validation error, a bounded number of times.
+// Responses with tool calls aren't final answers and are returned as they are.
+func completeStructured(ctx context.Context, dynamic Config, provider string, req types.CompletionRequest, opts ...types.CompletionOptions) (*types.CompletionResponse, error) {
+	if req.OutputSchema == nil || len(req.OutputSchema.ToSchema()) == 0 {
+		return completeModel(ctx, dynamic, provider, req, opts...)
+	}
+	if providerCfg := dynamic.LLMProviders[provider]; !nativeStructuredOutput(providerCfg.Dialect) {
+		req.SystemPrompt += outputSchemaPrompt(req.OutputSchema)
+	}
+
+	var usage types.Usage
+	for turn := 0; ; turn++ {
+		resp, err := completeModel(ctx, dynamic, provider, req, opts...)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Usage != nil {
+			usage.Add(*resp.Usage)
+			resp.Usage = &usage
+		}
+		if hasToolCalls(resp.Output) {
+			return resp, nil
+		}
+
+		validationErr := validateStructuredOutput(resp, req.OutputSchema)
+		if validationErr == nil {
+			return resp, nil
+		}
+		if turn >= maxRepairTurns {
+			return nil, fmt.Errorf("model output failed validation against output schema %s after %d repair attempts: %w",
+				req.OutputSchema.Name, maxRepairTurns, validationErr)
+		}
+
+		slog.Warn("model output failed validation, asking the model to repair it", "model", req.Model, "attempt", turn+1, "error", validationErr)
+		req.Input = append(req.Input, resp.Output, types.Message{
+			Role: "user",
+			Items: []types.CompletionItem{{
+				Content: &mcp.Content{
+					Type: "text",
+					Text: fmt.Sprintf("Your output failed validation: %v\n\nReply again with only the corrected JSON that matches the output schema.", validationErr),
+				},
+			}},
+		})
+	}
+}
+
+func hasToolCalls(msg types.Message) bool {
+	for _, item := range msg.Items {
+		if item.ToolCall != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// validateStructuredOutput validates the text of the response against the
+// schema. Valid output is rewritten to the bare JSON, without the code fences
+// or surrounding text some models add.
+func validateStructuredOutput(resp *types.CompletionResponse, output *types.OutputSchema) error {
+	var (
+		texts []string
+		first = -1
+	)
+	for i, item := range resp.Output.Items {
+		if item.Content != nil && item.Content.Type == "text" {
+			texts = append(texts, item.Content.Text)
+			if first < 0 {
+				first = i
+			}
+		}
+	}
+	if first < 0 {
+		return errors.New("the output has no text")
+	}
+
+	data, value, err := extractJSON(strings.Join(texts, ""))
+	if err != nil {
+		return err
+	}
+	if err := schema.ValidateOutput(output.ToSchema(), value); err != nil {
+		return err
+	}
+
+	content := *resp.Output.Items[first].Content
+	content.Text = data
+	items := make([]types.CompletionItem, 0, len(resp.Output.Items))
+	for i, item := range resp.Output.Items {
+		switch {
+		case i == first:
+			item.Content = &content
+		case item.Content != nil && item.Content.Type == "text":
+			continue
+		}
+		items = append(items, item)
+	}
+	resp.Output.Items = items
+	return nil
+}
+
+// extractJSON parses the JSON value of a reply, either the whole reply, the
+// contents of a code fence, or the outermost object in the text.
+func extractJSON(text string) (string, any, error) {
+	text = strings.TrimSpace(text)
+	candidates := []string{text}
+	if start := strings.Index(text, "```"); start >= 0 {
+		fenced := text[start+3:]
+		if newline := strings.Index(fenced, "\n"); newline >= 0 {
+			fenced = fenced[newline+1:]
+		}
+		if end := strings.Index(fenced, "```"); end >= 0 {
+			candidates = append(candidates, strings.TrimSpace(fenced[:end]))
+		}
+	}
+	if start, end := strings.Index(text, "{"), strings.LastIndex(text, "}"); start >= 0 && end > start {
+		candidates = append(candidates, text[start:end+1])
+	}
+
+	var err error
+	for _, candidate := range candidates {
+		var value any
+		if err = json.Unmarshal([]byte(candidate), &value); err == nil {
+			return candidate, value, nil
+		}
+	}
+	return "", nil, fmt.Errorf("the output is not valid JSON: %w", err)
+}
@@ -0,0 +1,68 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+func TestCompleteRepairsStructuredOutput(t *testing.T) {
+	var (
+		replies = []string{
+			`{"city": "Paris"}`,
+			"Here you go:\n```json\n{\"city\": \"Paris\", \"temperature\": 21}\n```",
+		}
+		bodies []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		reply, _ := json.Marshal(replies[min(len(bodies), len(replies))-1])
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: {\"id\":\"%d\",\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\",\"content\":%s},\"finish_reason\":\"stop\"}]}\n\n", len(bodies), reply)
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	client := NewClient(Config{
+		LLMProviders: map[string]LLMProviderConfig{
+			"local": {Dialect: types.DialectOpenAIChatCompletions, BaseURL: server.URL},
+		},
+	})
+	req := types.CompletionRequest{
+		Model: "local/structured",
+		Input: []types.Message{{Role: "user", Items: []types.CompletionItem{{Content: &mcp.Content{Type: "text", Text: "Weather in Paris?"}}}}},
+		OutputSchema: &types.OutputSchema{
+			Name:   "weather",
+			Schema: json.RawMessage(`{"type":"object","properties":{"city":{"type":"string"},"temperature":{"type":"number"}},"required":["city","temperature"]}`),
+		},
+	}
+
+	resp, err := client.Complete(t.Context(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := responseText(resp); got != `{"city": "Paris", "temperature": 21}` {
+		t.Errorf("expected the bare JSON of the repaired output, got %q", got)
+	}
+	if len(bodies) != 2 || !strings.Contains(bodies[1], "Your output failed validation") {
+		t.Errorf("expected a repair turn with the validation error, got requests %v", bodies)
+	}
+
+	// A model that never gets it right fails after the repair turns.
+	replies = []string{"not json"}
+	bodies = nil
+	if _, err := client.Complete(t.Context(), req); err == nil || !strings.Contains(err.Error(), "failed validation") {
+		t.Errorf("expected a validation error, got %v", err)
+	}
+	if len(bodies) != maxRepairTurns+1 {
+		t.Errorf("expected %d requests, got %d", maxRepairTurns+1, len(bodies))
+	}
+}
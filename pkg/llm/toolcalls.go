@@ -0,0 +1,115 @@
+package llm
+
+import (
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+	"github.com/obot-platform/nanobot/pkg/uuid"
+)
+
+// missingToolResult is the result sent for a tool call that never got one,
+// like a call that was interrupted.
+const missingToolResult = "The tool call did not complete and has no result."
+
+// normalizeToolCalls rewrites the tool calls in the input into the shape that
+// every provider accepts. Each assistant message with tool calls is followed
+// directly by a single message with the results of all of its calls, in the
+// order the calls were made. Calls without a result get an error result and
+// results without a call are dropped. Items that carry both a call and its
+// result are split in two.
+func normalizeToolCalls(input []types.Message) []types.Message {
+	var (
+		results = map[string]*types.ToolCallResult{}
+		calls   = map[string]bool{}
+	)
+	for _, msg := range input {
+		for _, item := range msg.Items {
+			if item.ToolCall != nil {
+				calls[item.ToolCall.CallID] = true
+			}
+			if item.ToolCallResult != nil {
+				if _, ok := results[item.ToolCallResult.CallID]; !ok {
+					results[item.ToolCallResult.CallID] = item.ToolCallResult
+				}
+			}
+		}
+	}
+	if len(calls) == 0 && len(results) == 0 {
+		return input
+	}
+
+	var (
+		output = make([]types.Message, 0, len(input))
+		placed = map[string]bool{}
+	)
+	for _, msg := range input {
+		var (
+			items       = make([]types.CompletionItem, 0, len(msg.Items))
+			resultItems []types.CompletionItem
+		)
+		for _, item := range msg.Items {
+			if item.ToolCall != nil {
+				if placed[item.ToolCall.CallID] {
+					// A call ID must be unique in the conversation.
+					continue
+				}
+				placed[item.ToolCall.CallID] = true
+
+				result := results[item.ToolCall.CallID]
+				if result == nil {
+					result = &types.ToolCallResult{
+						CallID: item.ToolCall.CallID,
+						Output: types.CallResult{
+							Content: []mcp.Content{{Type: "text", Text: missingToolResult}},
+							IsError: true,
+						},
+					}
+				}
+				resultItems = append(resultItems, types.CompletionItem{
+					ID:             item.ID,
+					ToolCallResult: result,
+				})
+				item.ToolCallResult = nil
+			} else if item.ToolCallResult != nil {
+				// Results are moved next to their calls, or dropped if there
+				// is no call.
+				continue
+			}
+			items = append(items, item)
+		}
+
+		if len(items) > 0 {
+			msg.Items = items
+			output = append(output, msg)
+		}
+		if len(resultItems) > 0 {
+			output = append(output, types.Message{
+				Role:  "user",
+				Items: resultItems,
+			})
+		}
+	}
+
+	return output
+}
+
+// assignToolCallIDs gives the tool calls of a response that have no ID, or
+// repeat the ID of an earlier call in the response, a new one. Some providers
+// leave IDs out, but the results can't be matched to the calls without them.
+func assignToolCallIDs(resp *types.CompletionResponse) {
+	if resp == nil {
+		return
+	}
+
+	seen := map[string]bool{}
+	for i, item := range resp.Output.Items {
+		if item.ToolCall == nil {
+			continue
+		}
+		if item.ToolCall.CallID == "" || seen[item.ToolCall.CallID] {
+			toolCall := *item.ToolCall
+			toolCall.CallID = "call_" + uuid.String()
+			resp.Output.Items[i].ToolCall = &toolCall
+		}
+		seen[resp.Output.Items[i].ToolCall.CallID] = true
+	}
+}
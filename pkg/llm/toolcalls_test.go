@@ -0,0 +1,96 @@
+package llm
+
+import (
+	"testing"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+func TestNormalizeToolCalls(t *testing.T) {
+	call := func(id string) types.CompletionItem {
+		return types.CompletionItem{ToolCall: &types.ToolCall{CallID: id, Name: "read"}}
+	}
+	result := func(id string) types.Message {
+		return types.Message{
+			Role:  "user",
+			Items: []types.CompletionItem{{ToolCallResult: &types.ToolCallResult{CallID: id}}},
+		}
+	}
+
+	input := []types.Message{
+		{Role: "user", Items: []types.CompletionItem{{Content: &mcp.Content{Type: "text", Text: "read the files"}}}},
+		{Role: "assistant", Items: []types.CompletionItem{call("b"), call("a"), call("interrupted")}},
+		// Results arrive as separate messages in any order.
+		result("a"),
+		result("b"),
+		result("orphan"),
+		{Role: "user", Items: []types.CompletionItem{{Content: &mcp.Content{Type: "text", Text: "thanks"}}}},
+	}
+
+	output := normalizeToolCalls(input)
+	if len(output) != 4 {
+		t.Fatalf("expected 4 messages, got %d: %+v", len(output), output)
+	}
+
+	results := output[2]
+	if results.Role != "user" || len(results.Items) != 3 {
+		t.Fatalf("expected one user message with all three results, got %+v", results)
+	}
+	for i, id := range []string{"b", "a", "interrupted"} {
+		if got := results.Items[i].ToolCallResult.CallID; got != id {
+			t.Errorf("result %d is for call %s, want %s", i, got, id)
+		}
+	}
+	if !results.Items[2].ToolCallResult.Output.IsError {
+		t.Error("expected the call without a result to get an error result")
+	}
+	if output[3].Items[0].Content.Text != "thanks" {
+		t.Errorf("expected the last user message to be kept, got %+v", output[3])
+	}
+}
+
+func TestNormalizeToolCallsSplitsConsolidatedItems(t *testing.T) {
+	input := []types.Message{
+		{
+			Role: "assistant",
+			Items: []types.CompletionItem{{
+				ToolCall:       &types.ToolCall{CallID: "a", Name: "read"},
+				ToolCallResult: &types.ToolCallResult{CallID: "a"},
+			}},
+		},
+	}
+
+	output := normalizeToolCalls(input)
+	if len(output) != 2 || output[0].Items[0].ToolCallResult != nil || output[1].Items[0].ToolCallResult == nil {
+		t.Fatalf("expected the call and its result in separate messages, got %+v", output)
+	}
+	if input[0].Items[0].ToolCallResult == nil {
+		t.Error("expected the input to be left unchanged")
+	}
+}
+
+func TestAssignToolCallIDs(t *testing.T) {
+	resp := &types.CompletionResponse{
+		Output: types.Message{
+			Items: []types.CompletionItem{
+				{ToolCall: &types.ToolCall{Name: "read"}},
+				{ToolCall: &types.ToolCall{CallID: "call_1", Name: "grep"}},
+				{ToolCall: &types.ToolCall{CallID: "call_1", Name: "glob"}},
+			},
+		},
+	}
+
+	assignToolCallIDs(resp)
+
+	seen := map[string]bool{}
+	for _, item := range resp.Output.Items {
+		if item.ToolCall.CallID == "" || seen[item.ToolCall.CallID] {
+			t.Fatalf("expected unique call IDs, got %+v", resp.Output.Items)
+		}
+		seen[item.ToolCall.CallID] = true
+	}
+	if resp.Output.Items[1].ToolCall.CallID != "call_1" {
+		t.Errorf("expected the first use of an ID to be kept, got %s", resp.Output.Items[1].ToolCall.CallID)
+	}
+}
@@ -0,0 +1,232 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// noToolCalling remembers the models, by base URL and model name, that
+// rejected a request with tools, so later requests go straight to emulation.
+var noToolCalling sync.Map
+
+func lacksToolCalling(baseURL, model string) bool {
+	_, ok := noToolCalling.Load(baseURL + "\x00" + model)
+	return ok
+}
+
+func markLacksToolCalling(baseURL, model string) {
+	noToolCalling.Store(baseURL+"\x00"+model, true)
+}
+
+// isToolCallingUnsupported checks whether a provider rejected a request
+// because the model has no native tool calling, like Ollama does for models
+// without tool support.
+func isToolCallingUnsupported(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{
+		"does not support tools",
+		"does not support tool",
+		"tool calling is not supported",
+		"tools are not supported",
+		"tool use is not supported",
+	} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+type emulatedToolCalls struct {
+	ToolCalls []emulatedToolCall `json:"tool_calls"`
+}
+
+type emulatedToolCall struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+// completeEmulatingTools completes a request for a model without native tool
+// calling. The tools are described in the system prompt, previous calls and
+// results are sent as text, and calls are parsed back out of the reply.
+func completeEmulatingTools(ctx context.Context, provider string, providerCfg LLMProviderConfig, req types.CompletionRequest, opts ...types.CompletionOptions) (*types.CompletionResponse, error) {
+	tools := req.Tools
+	resp, err := completeWith(ctx, provider, providerCfg, emulateToolsRequest(req), opts...)
+	if err != nil {
+		return nil, err
+	}
+	parseEmulatedToolCalls(resp, tools)
+	return resp, nil
+}
+
+func emulateToolsRequest(req types.CompletionRequest) types.CompletionRequest {
+	var prompt strings.Builder
+	if req.SystemPrompt != "" {
+		prompt.WriteString(req.SystemPrompt)
+		prompt.WriteString("\n\n")
+	}
+	prompt.WriteString(`# Tools
+
+You can call the tools below. To call tools, reply with only a JSON object in this form and nothing else:
+
+{"tool_calls": [{"name": "<tool name>", "arguments": {<arguments matching the parameters of the tool>}}]}
+
+The results of the calls are sent back to you in the next message. When you don't need a tool, reply normally without the JSON object.
+`)
+	for _, tool := range req.Tools {
+		prompt.WriteString("\n## " + tool.Name + "\n")
+		if tool.Description != "" {
+			prompt.WriteString(tool.Description + "\n")
+		}
+		if len(tool.Parameters) > 0 {
+			prompt.WriteString("Parameters: " + string(tool.Parameters) + "\n")
+		}
+	}
+	if req.ToolChoice != "" && req.ToolChoice != "auto" && req.ToolChoice != "none" {
+		if req.ToolChoice == "required" {
+			prompt.WriteString("\nYou must call at least one tool.\n")
+		} else {
+			prompt.WriteString("\nYou must call the " + req.ToolChoice + " tool.\n")
+		}
+	}
+
+	req.SystemPrompt = prompt.String()
+	req.Tools = nil
+	req.ToolChoice = ""
+	req.ParallelToolCalls = nil
+	req.Input = emulateToolsInput(req.Input)
+	return req
+}
+
+// emulateToolsInput rewrites the tool calls and results of the input as text.
+func emulateToolsInput(input []types.Message) []types.Message {
+	names := map[string]string{}
+	for _, msg := range input {
+		for _, item := range msg.Items {
+			if item.ToolCall != nil {
+				names[item.ToolCall.CallID] = item.ToolCall.Name
+			}
+		}
+	}
+
+	result := make([]types.Message, 0, len(input))
+	for _, msg := range input {
+		var (
+			items []types.CompletionItem
+			calls emulatedToolCalls
+		)
+		for _, item := range msg.Items {
+			switch {
+			case item.ToolCall != nil:
+				args := json.RawMessage(item.ToolCall.Arguments)
+				if !json.Valid(args) {
+					args, _ = json.Marshal(item.ToolCall.Arguments)
+				}
+				calls.ToolCalls = append(calls.ToolCalls, emulatedToolCall{
+					Name:      item.ToolCall.Name,
+					Arguments: args,
+				})
+			case item.ToolCallResult != nil:
+				text := "Result of the " + names[item.ToolCallResult.CallID] + " tool call:"
+				if item.ToolCallResult.Output.IsError {
+					text = "The " + names[item.ToolCallResult.CallID] + " tool call failed:"
+				}
+				for _, content := range item.ToolCallResult.Output.Content {
+					if content.Type == "text" {
+						text += "\n" + content.Text
+					} else {
+						items = append(items, types.CompletionItem{Content: &content})
+					}
+				}
+				items = append(items, types.CompletionItem{Content: &mcp.Content{Type: "text", Text: text}})
+			default:
+				items = append(items, item)
+			}
+		}
+		if len(calls.ToolCalls) > 0 {
+			data, _ := json.Marshal(calls)
+			items = append(items, types.CompletionItem{Content: &mcp.Content{Type: "text", Text: string(data)}})
+		}
+		msg.Items = items
+		result = append(result, msg)
+	}
+	return result
+}
+
+// parseEmulatedToolCalls replaces a JSON object of tool calls in the text of
+// the response with tool calls. Text before the object is kept.
+func parseEmulatedToolCalls(resp *types.CompletionResponse, tools []types.ToolUseDefinition) {
+	known := map[string]bool{}
+	for _, tool := range tools {
+		known[tool.Name] = true
+	}
+
+	var items []types.CompletionItem
+	for _, item := range resp.Output.Items {
+		if item.Content == nil || item.Content.Type != "text" {
+			items = append(items, item)
+			continue
+		}
+		prefix, calls, ok := findEmulatedToolCalls(item.Content.Text, known)
+		if !ok {
+			items = append(items, item)
+			continue
+		}
+		if prefix != "" {
+			content := *item.Content
+			content.Text = prefix
+			item.Content = &content
+			items = append(items, item)
+		}
+		for _, call := range calls {
+			items = append(items, types.CompletionItem{
+				ToolCall: &types.ToolCall{
+					Name:      call.Name,
+					Arguments: emulatedArguments(call.Arguments),
+				},
+			})
+		}
+	}
+	resp.Output.Items = items
+}
+
+func findEmulatedToolCalls(text string, known map[string]bool) (string, []emulatedToolCall, bool) {
+	for i := strings.Index(text, "{"); i >= 0; {
+		var calls emulatedToolCalls
+		if err := json.NewDecoder(strings.NewReader(text[i:])).Decode(&calls); err == nil && len(calls.ToolCalls) > 0 {
+			valid := true
+			for _, call := range calls.ToolCalls {
+				valid = valid && known[call.Name]
+			}
+			if valid {
+				prefix := strings.TrimSpace(text[:i])
+				prefix = strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(prefix, "json"), "```"))
+				return prefix, calls.ToolCalls, true
+			}
+		}
+		next := strings.Index(text[i+1:], "{")
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return "", nil, false
+}
+
+// emulatedArguments returns the arguments of a call as a JSON object, models
+// sometimes send them as a string of JSON.
+func emulatedArguments(args json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(args, &s); err == nil {
+		args = json.RawMessage(s)
+	}
+	if len(args) == 0 || string(args) == "null" || !json.Valid(args) {
+		return "{}"
+	}
+	return string(args)
+}
@@ -0,0 +1,119 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+func TestCompleteEmulatesToolsForLocalModels(t *testing.T) {
+	var (
+		requests     int
+		systemPrompt string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		body, _ := io.ReadAll(r.Body)
+		var req struct {
+			Tools    []any `json:"tools"`
+			Messages []struct {
+				Role    string `json:"role"`
+				Content any    `json:"content"`
+			} `json:"messages"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Errorf("invalid request: %v", err)
+		}
+		if len(req.Tools) > 0 {
+			http.Error(w, `{"error":{"message":"registry.ollama.ai/library/gemma2:latest does not support tools"}}`, http.StatusBadRequest)
+			return
+		}
+		for _, msg := range req.Messages {
+			if msg.Role == "system" {
+				systemPrompt = fmt.Sprint(msg.Content)
+			}
+		}
+
+		reply, _ := json.Marshal("Let me check.\n```json\n" + `{"tool_calls": [{"name": "get_weather", "arguments": {"city": "Paris"}}]}` + "\n```")
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: {\"id\":\"1\",\"model\":\"gemma2\",\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\",\"content\":%s},\"finish_reason\":\"stop\"}]}\n\n", reply)
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	client := NewClient(Config{
+		LLMProviders: map[string]LLMProviderConfig{
+			"ollama": {
+				Dialect: types.DialectOpenAIChatCompletions,
+				BaseURL: server.URL,
+				Local:   true,
+			},
+		},
+	})
+
+	req := types.CompletionRequest{
+		Model: "ollama/gemma2",
+		Input: []types.Message{{
+			Role:  "user",
+			Items: []types.CompletionItem{{Content: &mcp.Content{Type: "text", Text: "What's the weather in Paris?"}}},
+		}},
+		Tools: []types.ToolUseDefinition{{
+			Name:        "get_weather",
+			Description: "Get the current weather of a city",
+			Parameters:  json.RawMessage(`{"type":"object","properties":{"city":{"type":"string"}}}`),
+		}},
+	}
+
+	for attempt := range 2 {
+		resp, err := client.Complete(t.Context(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		items := resp.Output.Items
+		if len(items) != 2 || items[0].Content == nil || items[0].Content.Text != "Let me check." {
+			t.Fatalf("attempt %d: expected the text before the tool call and the tool call, got %+v", attempt, items)
+		}
+		call := items[1].ToolCall
+		if call == nil || call.Name != "get_weather" || call.Arguments != `{"city": "Paris"}` || call.CallID == "" {
+			t.Fatalf("attempt %d: unexpected tool call %+v", attempt, call)
+		}
+	}
+
+	// The first completion is rejected for its tools, after that the model is
+	// known to lack tool calling.
+	if requests != 3 {
+		t.Errorf("expected 3 requests, got %d", requests)
+	}
+	if !strings.Contains(systemPrompt, "## get_weather") || !strings.Contains(systemPrompt, `"tool_calls"`) {
+		t.Errorf("expected the tools in the system prompt, got %q", systemPrompt)
+	}
+}
+
+func TestEmulateToolsInput(t *testing.T) {
+	input := emulateToolsInput([]types.Message{
+		{Role: "assistant", Items: []types.CompletionItem{
+			{ToolCall: &types.ToolCall{CallID: "call-1", Name: "get_weather", Arguments: `{"city":"Paris"}`}},
+		}},
+		{Role: "user", Items: []types.CompletionItem{
+			{ToolCallResult: &types.ToolCallResult{
+				CallID: "call-1",
+				Output: types.CallResult{Content: []mcp.Content{{Type: "text", Text: "sunny"}}},
+			}},
+		}},
+	})
+
+	if got, want := input[0].Items[0].Content.Text, `{"tool_calls":[{"name":"get_weather","arguments":{"city":"Paris"}}]}`; got != want {
+		t.Errorf("call: got %q, want %q", got, want)
+	}
+	if got, want := input[1].Items[0].Content.Text, "Result of the get_weather tool call:\nsunny"; got != want {
+		t.Errorf("result: got %q, want %q", got, want)
+	}
+}
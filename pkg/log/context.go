@@ -0,0 +1,145 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Keys of the attributes that correlate the records logged while handling a
+// request.
+const (
+	SessionIDKey = "sessionID"
+	AccountIDKey = "accountID"
+	AgentKey     = "agent"
+	ToolNameKey  = "toolName"
+	RequestIDKey = "requestID"
+)
+
+type attrsKey struct{}
+
+// WithAttrs returns a context whose records are logged with the attributes,
+// in addition to the ones of the parent context. An attribute replaces the
+// parent's attribute with the same key.
+func WithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	parent := Attrs(ctx)
+	merged := make([]slog.Attr, 0, len(parent)+len(attrs))
+	for _, attr := range parent {
+		if !slices.ContainsFunc(attrs, func(a slog.Attr) bool { return a.Key == attr.Key }) {
+			merged = append(merged, attr)
+		}
+	}
+	for _, attr := range attrs {
+		if !attr.Equal(slog.Attr{}) && !(attr.Value.Kind() == slog.KindString && attr.Value.String() == "") {
+			merged = append(merged, attr)
+		}
+	}
+	return context.WithValue(ctx, attrsKey{}, merged)
+}
+
+// Attrs returns the correlation attributes of the context.
+func Attrs(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	attrs, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	return attrs
+}
+
+// contextHandler adds the correlation attributes of the context to records.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs := Attrs(ctx); len(attrs) > 0 {
+		r = r.Clone()
+		r.AddAttrs(attrs...)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+// minSensitiveLength is the length under which values aren't redacted, short
+// values like "true" would redact too much.
+const minSensitiveLength = 8
+
+var (
+	sensitiveLock   sync.RWMutex
+	sensitiveValues = map[string]string{}
+)
+
+// redactPrefixLength is how many characters of a sensitive value are kept by
+// Redact, and only for values at least redactPrefixMinLength long, so that
+// what is shown never gives away a meaningful part of the secret.
+const (
+	redactPrefixLength    = 3
+	redactPrefixMinLength = 16
+	redactMask            = "****"
+)
+
+// Redact returns the redacted form of a sensitive value: a fixed mask, after
+// the first few characters of long values to tell them apart.
+func Redact(val string) string {
+	if len(val) < redactPrefixMinLength {
+		return redactMask
+	}
+	return val[:redactPrefixLength] + redactMask
+}
+
+// AddSensitiveValues registers values, like the sensitive env values of the
+// config, to redact wherever they appear in logged messages and attributes.
+func AddSensitiveValues(values ...string) {
+	sensitiveLock.Lock()
+	defer sensitiveLock.Unlock()
+	for _, val := range values {
+		if len(val) >= minSensitiveLength {
+			sensitiveValues[val] = Redact(val)
+		}
+	}
+}
+
+// RedactSensitive redacts the registered sensitive values from s.
+func RedactSensitive(s string) string {
+	sensitiveLock.RLock()
+	defer sensitiveLock.RUnlock()
+	for val, redacted := range sensitiveValues {
+		s = strings.ReplaceAll(s, val, redacted)
+	}
+	return s
+}
+
+func redactAttr(_ []string, attr slog.Attr) slog.Attr {
+	sensitiveLock.RLock()
+	empty := len(sensitiveValues) == 0
+	sensitiveLock.RUnlock()
+	if empty {
+		return attr
+	}
+
+	var s string
+	switch v := attr.Value.Any().(type) {
+	case string:
+		s = v
+	case error:
+		s = v.Error()
+	case fmt.Stringer:
+		s = v.String()
+	default:
+		return attr
+	}
+	if redacted := RedactSensitive(s); redacted != s {
+		attr.Value = slog.StringValue(redacted)
+	}
+	return attr
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"regexp"
@@ -18,6 +19,8 @@ var (
 	EnableUI          = hasDebugToken("ui")
 	Base64Replace     = regexp.MustCompile(`((;base64,|")[a-zA-Z0-9+/=]{60})[a-zA-Z0-9+/=]+"`)
 	Base64Replacement = []byte(`$1..."`)
+
+	output io.Writer = os.Stderr
 )
 
 func parseDebugTokens(raw string) []string {
@@ -38,18 +41,49 @@ func hasDebugToken(token string) bool {
 }
 
 func init() {
-	ConfigureSlog(false, false)
+	_ = Configure(Options{})
+}
+
+type Options struct {
+	Debug bool
+	Trace bool
+	// Format is "text", the default, or "json".
+	Format string
+	// Level is the lowest level logged: debug, info, warn, or error. Debug,
+	// trace, and NANOBOT_DEBUG lower it to debug.
+	Level string
 }
 
-func ConfigureSlog(debug, trace bool) {
+// Configure sets the default logger. Records logged with a context carry the
+// correlation attributes of the context, and sensitive values are redacted.
+func Configure(opts Options) error {
 	level := slog.LevelInfo
-	if len(debugs) > 0 || debug || trace {
-		level = slog.LevelDebug
+	if opts.Level != "" {
+		if err := level.UnmarshalText([]byte(opts.Level)); err != nil {
+			return fmt.Errorf("invalid log level %q: %w", opts.Level, err)
+		}
+	}
+	if len(debugs) > 0 || opts.Debug || opts.Trace {
+		level = min(level, slog.LevelDebug)
+	}
+
+	handlerOpts := &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redactAttr,
+	}
+
+	var handler slog.Handler
+	switch opts.Format {
+	case "", "text":
+		handler = slog.NewTextHandler(output, handlerOpts)
+	case "json":
+		handler = slog.NewJSONHandler(output, handlerOpts)
+	default:
+		return fmt.Errorf("invalid log format %q, must be text or json", opts.Format)
 	}
 
-	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
-		Level: level,
-	})))
+	slog.SetDefault(slog.New(contextHandler{Handler: handler}))
+	return nil
 }
 
 func Messages(_ context.Context, server string, out bool, data []byte) {
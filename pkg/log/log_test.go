@@ -2,6 +2,10 @@ package log
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
 	"testing"
 )
 
@@ -24,3 +28,43 @@ func TestRegexp2(t *testing.T) {
 		t.Errorf("Expected data to be modified, but it was not. %s", expected)
 	}
 }
+
+func TestConfigureJSON(t *testing.T) {
+	var buf bytes.Buffer
+	output = &buf
+	t.Cleanup(func() {
+		output = os.Stderr
+		_ = Configure(Options{})
+	})
+
+	if err := Configure(Options{Format: "json", Level: "warn"}); err != nil {
+		t.Fatal(err)
+	}
+	AddSensitiveValues("sk-supersecretvalue", "short")
+
+	ctx := WithAttrs(t.Context(), slog.String(SessionIDKey, "s1"), slog.String(AgentKey, "planner"))
+	ctx = WithAttrs(ctx, slog.String(AgentKey, "coder"), slog.String(ToolNameKey, "bash"))
+	slog.InfoContext(ctx, "not logged below the level")
+	slog.WarnContext(ctx, "calling with sk-supersecretvalue", "error", errors.New("bad key sk-supersecretvalue"), "flag", "short")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("expected a single JSON record, got %q: %v", buf.String(), err)
+	}
+	for key, want := range map[string]string{
+		"msg":        "calling with sk-****",
+		"error":      "bad key sk-****",
+		"flag":       "short",
+		SessionIDKey: "s1",
+		AgentKey:     "coder",
+		ToolNameKey:  "bash",
+	} {
+		if record[key] != want {
+			t.Errorf("%s = %v, want %q", key, record[key], want)
+		}
+	}
+
+	if err := Configure(Options{Format: "xml"}); err == nil {
+		t.Error("expected an invalid format to fail")
+	}
+}
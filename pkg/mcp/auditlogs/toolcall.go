@@ -0,0 +1,85 @@
+package auditlogs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"regexp"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/log"
+)
+
+// ToolCall is the audit record of a tool call.
+type ToolCall struct {
+	CreatedAt time.Time `json:"createdAt"`
+	SessionID string    `json:"sessionId,omitempty"`
+	AccountID string    `json:"accountId,omitempty"`
+	Agent     string    `json:"agent,omitempty"`
+	Server    string    `json:"server"`
+	Tool      string    `json:"tool,omitempty"`
+	// ArgumentsHash is the hex encoded SHA-256 of the JSON arguments, so
+	// calls can be matched to their arguments without storing them.
+	ArgumentsHash string `json:"argumentsHash"`
+	// Arguments are the redacted arguments, only recorded when enabled.
+	Arguments  json.RawMessage `json:"arguments,omitempty"`
+	DurationMS int64           `json:"durationMs"`
+	IsError    bool            `json:"isError,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// ToolCallRecorder appends tool call records to an audit store.
+type ToolCallRecorder interface {
+	RecordToolCall(ctx context.Context, call ToolCall) error
+}
+
+// sensitiveArgument matches the names of arguments whose values are always
+// redacted.
+var sensitiveArgument = regexp.MustCompile(`(?i)(passw(or)?d|secret|token|api[-_]?key|auth|credential|private[-_]?key|cookie)`)
+
+const redactedValue = "[REDACTED]"
+
+// HashArguments returns the hex encoded SHA-256 of the JSON encoded
+// arguments.
+func HashArguments(args any) string {
+	data, _ := json.Marshal(args)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// RedactArguments returns the JSON encoded arguments with the values of
+// sensitive looking arguments, at any depth, replaced and the registered
+// sensitive values redacted from strings.
+func RedactArguments(args any) json.RawMessage {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return nil
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil
+	}
+	data, _ = json.Marshal(redactValue(value))
+	return data
+}
+
+func redactValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, val := range v {
+			if sensitiveArgument.MatchString(key) {
+				v[key] = redactedValue
+			} else {
+				v[key] = redactValue(val)
+			}
+		}
+	case []any:
+		for i, val := range v {
+			v[i] = redactValue(val)
+		}
+	case string:
+		return log.RedactSensitive(v)
+	}
+	return value
+}
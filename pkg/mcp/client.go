@@ -21,6 +21,7 @@ type Client struct {
 	Session       *Session
 	serverName    string
 	toolOverrides ToolOverrides
+	toolFilter    ToolFilter
 	toolPrefix    string
 }
 
@@ -55,6 +56,8 @@ type ClientOption struct {
 	Wire          Wire
 	HookRunner    HookRunner
 	ignoreEvents  bool
+	// pooled is the pooled stateless server the client connects to.
+	pooled *pooledServer
 }
 
 func (c ClientOption) Complete() ClientOption {
@@ -119,6 +122,8 @@ func (c ClientOption) Merge(other ClientOption) (result ClientOption) {
 	result.TokenExchangeEndpoint = complete.Last(c.TokenExchangeEndpoint, other.TokenExchangeEndpoint)
 	result.TokenExchangeClientID = complete.Last(c.TokenExchangeClientID, other.TokenExchangeClientID)
 	result.TokenExchangeClientSecret = complete.Last(c.TokenExchangeClientSecret, other.TokenExchangeClientSecret)
+	result.Pool = complete.Last(c.Pool, other.Pool)
+	result.pooled = complete.Last(c.pooled, other.pooled)
 	result.OAuthClientName = complete.Last(c.OAuthClientName, other.OAuthClientName)
 	result.Env = complete.MergeMap(c.Env, other.Env)
 	result.SessionState = complete.Last(c.SessionState, other.SessionState)
@@ -154,12 +159,76 @@ type Server struct {
 	// If providing no tool overrides, all tools will be enabled.
 	ToolOverrides ToolOverrides `json:"toolOverrides,omitzero"`
 
+	// Tools selects the tools of the server that are used and renames them,
+	// before ToolOverrides and ToolPrefix apply.
+	Tools ToolFilter `json:"tools,omitzero"`
+
 	// ToolPrefix is prepended to the name of every tool this server exposes
 	// (after any ToolOverrides rename). Incoming tool calls are stripped of the
 	// prefix before being dispatched upstream. Empty disables prefixing.
 	ToolPrefix string `json:"toolPrefix,omitempty"`
 
+	// ToolSettings tunes calls to individual tools, keyed by the tool's name
+	// on this server. The key "*" applies to every tool of the server.
+	ToolSettings map[string]ToolSettings `json:"toolSettings,omitempty"`
+
+	// ToolCurations change the descriptions and input schemas of tools as
+	// the model sees them, keyed like ToolSettings.
+	ToolCurations map[string]ToolCuration `json:"toolCurations,omitempty"`
+
+	// Sampling limits the sampling/createMessage requests of the server.
+	Sampling *SamplingPolicy `json:"sampling,omitempty"`
+
 	Hooks Hooks `json:"hooks,omitzero"`
+
+	// Startup is when the server is started, one of StartupLazy (the
+	// default), StartupEager or StartupDisabled.
+	Startup string `json:"startup,omitempty"`
+
+	// Process controls the process of a server started with a command that
+	// talks over stdio.
+	Process *ProcessSettings `json:"process,omitempty"`
+}
+
+// ProcessSettings controls the lifecycle and resources of the process of a
+// stdio MCP server.
+type ProcessSettings struct {
+	// IdleTimeoutMS stops the process once no request was sent to it for
+	// this long. It is started again the next time the server is used.
+	IdleTimeoutMS int `json:"idleTimeoutMs,omitempty"`
+	// MaxLifetimeMS restarts the process once it ran this long, as soon as
+	// no request is in flight.
+	MaxLifetimeMS int `json:"maxLifetimeMs,omitempty"`
+	// RestartOnCrash restarts the process when it exits on its own, waiting
+	// longer after each crash in a row.
+	RestartOnCrash bool `json:"restartOnCrash,omitempty"`
+	// MaxMemoryMB limits the memory the process can allocate.
+	MaxMemoryMB int `json:"maxMemoryMb,omitempty"`
+	// MaxCPUSeconds limits the CPU time the process can use, after which it
+	// is killed.
+	MaxCPUSeconds int `json:"maxCpuSeconds,omitempty"`
+}
+
+const (
+	// StartupLazy starts the server the first time a session uses it.
+	StartupLazy = "lazy"
+	// StartupEager starts the server when nanobot starts and prefetches its
+	// tools, so the first message of a session doesn't wait for it.
+	StartupEager = "eager"
+	// StartupDisabled never starts the server and hides its tools.
+	StartupDisabled = "disabled"
+)
+
+// SamplingPolicy controls how the sampling requests of a server are answered.
+type SamplingPolicy struct {
+	// Disabled doesn't offer sampling to the server.
+	Disabled bool `json:"disabled,omitempty"`
+	// Agents are the agents whose models the server can sample with, chosen
+	// by the model preferences of a request. By default the server samples
+	// with the agent that uses it.
+	Agents []string `json:"agents,omitempty"`
+	// MaxTokens caps the maxTokens of the requests of the server.
+	MaxTokens int `json:"maxTokens,omitempty"`
 }
 
 func (s Server) MarshalJSON() ([]byte, error) {
@@ -180,6 +249,43 @@ type ToolOverride struct {
 	InputSchema json.RawMessage `json:"inputSchema,omitempty"`
 }
 
+// AllToolsSettingsKey is the ToolSettings key that applies to every tool of a
+// server.
+const AllToolsSettingsKey = "*"
+
+type ToolSettings struct {
+	// TimeoutMS bounds each attempt to call the tool.
+	TimeoutMS int `json:"timeoutMs,omitempty"`
+	// MaxRetries is the number of times a call that fails with an error,
+	// rather than returning an error result, is retried.
+	MaxRetries int `json:"maxRetries,omitempty"`
+	// MaxResultTokens replaces the default size at which tool results are
+	// truncated before being sent to the model.
+	MaxResultTokens int `json:"maxResultTokens,omitempty"`
+	// TruncationTailPercent is the share of a truncated result kept from its
+	// end, with the rest kept from its start. Defaults to 30.
+	TruncationTailPercent *int `json:"truncationTailPercent,omitempty"`
+	// CacheTTLMS caches successful results for identical arguments within a
+	// session for this long.
+	CacheTTLMS int `json:"cacheTTLMs,omitempty"`
+}
+
+// Merge returns s with the non-zero settings of other applied on top.
+func (s ToolSettings) Merge(other ToolSettings) ToolSettings {
+	s.TimeoutMS = complete.Last(s.TimeoutMS, other.TimeoutMS)
+	s.MaxRetries = complete.Last(s.MaxRetries, other.MaxRetries)
+	s.MaxResultTokens = complete.Last(s.MaxResultTokens, other.MaxResultTokens)
+	s.TruncationTailPercent = complete.Last(s.TruncationTailPercent, other.TruncationTailPercent)
+	s.CacheTTLMS = complete.Last(s.CacheTTLMS, other.CacheTTLMS)
+	return s
+}
+
+// SettingsForTool returns the settings for the named tool, layered over the
+// settings for all tools of the server.
+func (s Server) SettingsForTool(tool string) ToolSettings {
+	return s.ToolSettings[AllToolsSettingsKey].Merge(s.ToolSettings[tool])
+}
+
 type ServerSource struct {
 	Repo      string `json:"repo,omitempty"`
 	Tag       string `json:"tag,omitempty"`
@@ -336,12 +442,23 @@ func NewSession(ctx context.Context, serverName string, config Server, opts ...C
 			}
 			headers["Mcp-Session-Id"] = opt.SessionState.ID
 		}
-		wire, err = newHTTPClient(serverName, config, opt.HTTPClientOptions, opt.SessionState, headers, !opt.ignoreEvents)
+		// A stateless server can't send messages outside of the responses to
+		// requests, so a pooled client doesn't listen for events.
+		stateless := opt.pooled != nil && opt.SessionState != nil
+		httpClient, err := newHTTPClient(serverName, config, opt.HTTPClientOptions, opt.SessionState, headers, !opt.ignoreEvents && !stateless)
 		if err != nil {
 			return nil, err
 		}
+		if opt.pooled != nil {
+			httpClient.httpClient = opt.pooled.httpClient
+		}
+		wire = httpClient
 	} else {
-		wire, err = newStdioClient(ctx, opt.Roots, opt.Env, serverName, config, opt.Runner)
+		var sessionID string
+		if opt.ParentSession != nil {
+			sessionID = opt.ParentSession.ID()
+		}
+		wire, err = newStdioClient(ctx, opt.Roots, opt.Env, serverName, config, opt.Runner, sessionID)
 		if err != nil {
 			return nil, err
 		}
@@ -357,6 +474,47 @@ func NewClient(ctx context.Context, serverName string, config Server, opts ...Cl
 		err     error
 	)
 
+	var (
+		sampling     *SamplingCapability
+		roots        *RootsCapability
+		elicitations *struct{}
+	)
+	if opt.OnSampling != nil {
+		sampling = &SamplingCapability{
+			// Since we are technically only support protocol version 2025-06-18,
+			// we shouldn't indicate support for features that require later protocol versions.
+			// Context: &struct{}{},
+			// Tools:   &struct{}{},
+		}
+	}
+	if opt.OnRoots != nil {
+		roots = &RootsCapability{ListChanged: true}
+	}
+	if opt.OnElicit != nil {
+		elicitations = &struct{}{}
+	}
+	initialize := InitializeRequest{
+		ProtocolVersion: "2025-06-18",
+		Capabilities: ClientCapabilities{
+			Sampling:    sampling,
+			Roots:       roots,
+			Elicitation: elicitations,
+		},
+		ClientInfo: ClientInfo{
+			Name:    opt.ClientName,
+			Version: opt.ClientVersion,
+		},
+	}
+
+	// Only new clients of remote servers are pooled, restored clients keep
+	// the session they had.
+	if opt.Pool != nil && opt.SessionState == nil && opt.Wire == nil && config.BaseURL != "" && config.Command == "" {
+		opt.pooled = opt.Pool.server(serverName, config, initialize)
+		if opt.pooled != nil {
+			opt.SessionState = opt.pooled.sessionState()
+		}
+	}
+
 	session, err = NewSession(ctx, serverName, config, opt)
 	if err != nil {
 		return nil, err
@@ -377,41 +535,17 @@ func NewClient(ctx context.Context, serverName string, config Server, opts ...Cl
 		Session:       session,
 		serverName:    serverName,
 		toolOverrides: config.ToolOverrides,
+		toolFilter:    config.Tools,
 		toolPrefix:    config.ToolPrefix,
 	}
 
-	var (
-		sampling     *SamplingCapability
-		roots        *RootsCapability
-		elicitations *struct{}
-	)
-	if opt.OnSampling != nil {
-		sampling = &SamplingCapability{
-			// Since we are technically only support protocol version 2025-06-18,
-			// we shouldn't indicate support for features that require later protocol versions.
-			// Context: &struct{}{},
-			// Tools:   &struct{}{},
-		}
-	}
-	if opt.OnRoots != nil {
-		roots = &RootsCapability{}
-	}
-	if opt.OnElicit != nil {
-		elicitations = &struct{}{}
-	}
 	if opt.SessionState == nil {
-		_, err = c.Initialize(ctx, InitializeRequest{
-			ProtocolVersion: "2025-06-18",
-			Capabilities: ClientCapabilities{
-				Sampling:    sampling,
-				Roots:       roots,
-				Elicitation: elicitations,
-			},
-			ClientInfo: ClientInfo{
-				Name:    opt.ClientName,
-				Version: opt.ClientVersion,
-			},
-		})
+		result, err := c.Initialize(ctx, initialize)
+		if err == nil && opt.pooled != nil {
+			if httpClient, ok := session.wire.(*HTTPClient); ok && !httpClient.sse {
+				opt.pooled.initialized(serverName, httpClient.SessionID(), initialize, result)
+			}
+		}
 		return c, err
 	}
 
@@ -448,6 +582,20 @@ func (c *Client) ReadResource(ctx context.Context, uri string) (*ReadResourceRes
 	return &result, err
 }
 
+func (c *Client) Complete(ctx context.Context, request CompleteRequest) (*CompleteResult, error) {
+	ctx, span := startOutboundSpan(ctx, "mcp.completion.complete",
+		attribute.String("mcp.server.name", c.serverName),
+	)
+	result := CompleteResult{Completion: Completion{Values: []string{}}}
+	if c.Session.InitializeResult.Capabilities.Completions == nil {
+		finishOutboundSpan(span, nil)
+		return &result, nil
+	}
+	err := c.Session.Exchange(ctx, "completion/complete", request, &result)
+	finishOutboundSpan(span, err)
+	return &result, err
+}
+
 func (c *Client) ListResourceTemplates(ctx context.Context) (*ListResourceTemplatesResult, error) {
 	ctx, span := startOutboundSpan(ctx, "mcp.resources.templates.list",
 		attribute.String("mcp.server.name", c.serverName),
@@ -457,7 +605,13 @@ func (c *Client) ListResourceTemplates(ctx context.Context) (*ListResourceTempla
 		finishOutboundSpan(span, nil)
 		return &result, nil
 	}
-	err := c.Session.Exchange(ctx, "resources/templates/list", struct{}{}, &result)
+	result, err := exchangeList(ctx, c.Session, "resources/templates/list", func(cursor string) any {
+		return ListResourceTemplatesRequest{Cursor: cursor}
+	}, func(r *ListResourceTemplatesResult) *string {
+		return &r.NextCursor
+	}, func(result, page *ListResourceTemplatesResult) {
+		result.ResourceTemplates = append(result.ResourceTemplates, page.ResourceTemplates...)
+	})
 	finishOutboundSpan(span, err)
 	return &result, err
 }
@@ -471,7 +625,13 @@ func (c *Client) ListResources(ctx context.Context) (*ListResourcesResult, error
 		finishOutboundSpan(span, nil)
 		return &result, nil
 	}
-	err := c.Session.Exchange(ctx, "resources/list", struct{}{}, &result)
+	result, err := exchangeList(ctx, c.Session, "resources/list", func(cursor string) any {
+		return ListResourcesRequest{Cursor: cursor}
+	}, func(r *ListResourcesResult) *string {
+		return &r.NextCursor
+	}, func(result, page *ListResourcesResult) {
+		result.Resources = append(result.Resources, page.Resources...)
+	})
 	finishOutboundSpan(span, err)
 	return &result, err
 }
@@ -511,7 +671,13 @@ func (c *Client) ListPrompts(ctx context.Context) (*ListPromptsResult, error) {
 		finishOutboundSpan(span, nil)
 		return &prompts, nil
 	}
-	err := c.Session.Exchange(ctx, "prompts/list", struct{}{}, &prompts)
+	prompts, err := exchangeList(ctx, c.Session, "prompts/list", func(cursor string) any {
+		return ListPromptsRequest{Cursor: cursor}
+	}, func(r *ListPromptsResult) *string {
+		return &r.NextCursor
+	}, func(result, page *ListPromptsResult) {
+		result.Prompts = append(result.Prompts, page.Prompts...)
+	})
 	finishOutboundSpan(span, err)
 	return &prompts, err
 }
@@ -539,21 +705,37 @@ func (c *Client) ListTools(ctx context.Context) (*ListToolsResult, error) {
 		return &ListToolsResult{}, nil
 	}
 
-	var tools ListToolsResult
-	err := c.Session.Exchange(ctx, "tools/list", struct{}{}, &tools)
-	if err == nil && len(c.toolOverrides) > 0 {
+	tools, err := exchangeList(ctx, c.Session, "tools/list", func(cursor string) any {
+		return ListToolsRequest{Cursor: cursor}
+	}, func(r *ListToolsResult) *string {
+		return &r.NextCursor
+	}, func(result, page *ListToolsResult) {
+		result.Tools = append(result.Tools, page.Tools...)
+	})
+	if err == nil && (len(c.toolOverrides) > 0 || !c.toolFilter.IsZero()) {
 		filtered := tools.Tools[:0] // reuse the backing array
 		for _, tool := range tools.Tools {
-			override, ok := c.toolOverrides[tool.Name]
-			if !ok {
-				// If there are tool overrides, but this tool is not there, then skip it.
+			if !c.toolFilter.Allowed(tool.Name) {
 				continue
 			}
 
-			tool.Name = complete.First(override.Name, tool.Name)
-			tool.Description = complete.First(override.Description, tool.Description)
-			if len(override.InputSchema) > 0 {
-				tool.InputSchema = override.InputSchema
+			if len(c.toolOverrides) > 0 {
+				override, ok := c.toolOverrides[tool.Name]
+				if !ok {
+					// If there are tool overrides, but this tool is not there, then skip it.
+					continue
+				}
+
+				if renamed, ok := c.toolFilter.Rename[tool.Name]; ok {
+					override.Name = renamed
+				}
+				tool.Name = complete.First(override.Name, tool.Name)
+				tool.Description = complete.First(override.Description, tool.Description)
+				if len(override.InputSchema) > 0 {
+					tool.InputSchema = override.InputSchema
+				}
+			} else if renamed, ok := c.toolFilter.Rename[tool.Name]; ok {
+				tool.Name = renamed
 			}
 
 			filtered = append(filtered, tool)
@@ -603,12 +785,19 @@ func (c *Client) Call(ctx context.Context, tool string, args any, opts ...CallOp
 	// rename — the upstream server only knows the original (or override) name.
 	tool = strings.TrimPrefix(tool, c.toolPrefix)
 
-	for name, o := range c.toolOverrides {
-		if o.Name != "" && tool == o.Name {
-			tool = name
-			break
+	if original := c.toolFilter.originalName(tool); original != tool {
+		tool = original
+	} else {
+		for name, o := range c.toolOverrides {
+			if o.Name != "" && tool == o.Name {
+				tool = name
+				break
+			}
 		}
 	}
+	if !c.toolFilter.Allowed(tool) {
+		return result, fmt.Errorf("tool %s of MCP server %s is not allowed by its tools config", tool, c.serverName)
+	}
 
 	ctx, span := startOutboundSpan(ctx, "mcp.tools.call",
 		attribute.String("mcp.server.name", c.serverName),
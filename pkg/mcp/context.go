@@ -83,6 +83,21 @@ func RequestIDFromContext(ctx context.Context) any {
 	return ctx.Value(requestIDKey{})
 }
 
+type progressTokenKey struct{}
+
+// WithProgressToken sets the progress token of the request being handled, so
+// that handlers can report progress for it.
+func WithProgressToken(ctx context.Context, token any) context.Context {
+	if token == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, progressTokenKey{}, token)
+}
+
+func ProgressTokenFromContext(ctx context.Context) any {
+	return ctx.Value(progressTokenKey{})
+}
+
 type userCtxKey struct{}
 
 func withUserCtx(ctx, userCtx context.Context) context.Context {
@@ -0,0 +1,144 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// readyCheckTimeout bounds how long the checks of a readiness probe take.
+const readyCheckTimeout = 5 * time.Second
+
+// HealthCheck is a dependency checked by the readiness probe.
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// HealthReport is the JSON body of the health and readiness probes.
+type HealthReport struct {
+	Status string                       `json:"status"`
+	Checks map[string]HealthCheckResult `json:"checks,omitempty"`
+}
+
+// HealthCheckResult is the result of one check of a HealthReport.
+type HealthCheckResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+func newHealthCheckResult(err error) HealthCheckResult {
+	if err != nil {
+		return HealthCheckResult{Status: "error", Error: err.Error()}
+	}
+	return HealthCheckResult{Status: "ok"}
+}
+
+// CachedHealthCheck returns a check that runs check at most once per ttl, for
+// dependencies that are slow or costly to check on every probe. Checks that
+// failed because their context was canceled or timed out are not cached, as
+// they say more about the probe than about the dependency.
+func CachedHealthCheck(ttl time.Duration, check func(ctx context.Context) error) func(ctx context.Context) error {
+	var (
+		lock    sync.Mutex
+		checked time.Time
+		lastErr error
+	)
+	return func(ctx context.Context) error {
+		lock.Lock()
+		defer lock.Unlock()
+		if !checked.IsZero() && time.Since(checked) < ttl {
+			return lastErr
+		}
+		err := check(ctx)
+		if ctx.Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+		lastErr, checked = err, time.Now()
+		return lastErr
+	}
+}
+
+// mcpServersCheck returns the result of the background check of the MCP
+// servers, and whether it is done starting up.
+func (h *HTTPServer) mcpServersCheck() (HealthCheckResult, bool) {
+	h.healthMu.RLock()
+	healthErr := h.healthErr
+	h.healthMu.RUnlock()
+
+	if healthErr == nil {
+		return HealthCheckResult{Status: "starting", Error: "waiting for startup"}, false
+	}
+	return newHealthCheckResult(*healthErr), true
+}
+
+func (h *HTTPServer) healthz(rw http.ResponseWriter, _ *http.Request) {
+	if h.draining.Load() {
+		writeHealthReport(rw, http.StatusServiceUnavailable, HealthReport{Status: "draining"})
+		return
+	}
+
+	result, started := h.mcpServersCheck()
+	report := HealthReport{
+		Status: result.Status,
+		Checks: map[string]HealthCheckResult{"mcpServers": result},
+	}
+	switch {
+	case !started:
+		writeHealthReport(rw, http.StatusTooEarly, report)
+	case result.Error != "":
+		writeHealthReport(rw, http.StatusInternalServerError, report)
+	default:
+		writeHealthReport(rw, http.StatusOK, report)
+	}
+}
+
+// readyz runs the readiness checks, along with the background check of the
+// MCP servers, and fails unless all of them pass.
+func (h *HTTPServer) readyz(rw http.ResponseWriter, req *http.Request) {
+	if h.draining.Load() {
+		writeHealthReport(rw, http.StatusServiceUnavailable, HealthReport{Status: "draining"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), readyCheckTimeout)
+	defer cancel()
+
+	var (
+		lock   sync.Mutex
+		wg     sync.WaitGroup
+		report = HealthReport{
+			Status: "ok",
+			Checks: map[string]HealthCheckResult{},
+		}
+	)
+	report.Checks["mcpServers"], _ = h.mcpServersCheck()
+	for _, check := range h.readyChecks {
+		wg.Go(func() {
+			result := newHealthCheckResult(check.Check(ctx))
+			lock.Lock()
+			defer lock.Unlock()
+			report.Checks[check.Name] = result
+		})
+	}
+	wg.Wait()
+
+	status := http.StatusOK
+	for _, result := range report.Checks {
+		if result.Status != "ok" {
+			report.Status = "error"
+			status = http.StatusServiceUnavailable
+		}
+	}
+	writeHealthReport(rw, status, report)
+}
+
+func writeHealthReport(rw http.ResponseWriter, status int, report HealthReport) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Header().Set("Cache-Control", "no-store")
+	rw.WriteHeader(status)
+	_ = json.NewEncoder(rw).Encode(report)
+}
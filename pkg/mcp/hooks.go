@@ -56,6 +56,9 @@ func (h Hooks) MarshalJSON() ([]byte, error) {
 
 type HookResponseCallback[T any] = func(hook HookMapping, target HookTarget, resp T, err error) T
 
+// InvokeHooks runs the hooks matching the name and params, in order, each
+// with the output of the previous one. Targets are tools as "server/tool" or
+// webhook URLs that the input is POSTed to.
 func InvokeHooks[T any](ctx context.Context, r HookRunner, hooks Hooks, in *T, name string, params map[string]string, callbacks ...HookResponseCallback[T]) (T, error) {
 	var (
 		out     T
@@ -67,7 +70,15 @@ func InvokeHooks[T any](ctx context.Context, r HookRunner, hooks Hooks, in *T, n
 		if mapping.Matches(name, params) {
 			for _, target := range mapping.Targets {
 				matched = true
-				hasOutput, err := r.RunHook(ctx, current, &out, target.Target)
+				var (
+					hasOutput bool
+					err       error
+				)
+				if IsWebhook(target.Target) {
+					hasOutput, err = sendWebhook(ctx, target.Target, name, params, current, &out)
+				} else {
+					hasOutput, err = r.RunHook(ctx, current, &out, target.Target)
+				}
 				if hasOutput || err != nil {
 					for _, cb := range callbacks {
 						out = cb(mapping, target, out, err)
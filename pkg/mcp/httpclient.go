@@ -77,6 +77,9 @@ type HTTPClientOptions struct {
 	TokenExchangeEndpoint     string
 	TokenExchangeClientID     string
 	TokenExchangeClientSecret string
+	// Pool shares the connections and initialization of stateless servers
+	// between clients. Nil doesn't share them.
+	Pool *HTTPClientPool
 }
 
 func newHTTPClient(serverName string, config Server, opts HTTPClientOptions, sessionState *SessionState, headers map[string]string, watchesEvents bool) (*HTTPClient, error) {
@@ -596,6 +599,19 @@ func (s *HTTPClient) Send(ctx context.Context, msg Message) error {
 			"method", msg.Method,
 			"request_id", MessageIDString(msg.ID))
 
+		// The server may have revoked the access token before it expired, so try
+		// refreshing it before asking the user to consent again.
+		if httpClient := s.oauthHandler.refreshFromStorage(s.ctx, s.baseURL); httpClient != nil {
+			s.clientLock.Lock()
+			s.httpClient = instrumentHTTPClient(httpClient)
+			s.clientLock.Unlock()
+
+			err = s.send(ctx, msg)
+			if _, ok := errors.AsType[AuthRequiredErr](err); !ok {
+				return err
+			}
+		}
+
 		// If there is an existing token, it doesn't work, so delete it.
 		if err := s.oauthHandler.tokenStorage.DeleteTokenConfig(ctx, s.baseURL); err != nil {
 			return fmt.Errorf("failed to delete token config: %w", err)
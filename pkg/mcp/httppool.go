@@ -0,0 +1,140 @@
+package mcp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// pooledMaxIdleConnsPerHost is how many idle connections to a stateless
+// server are kept for reuse, instead of the two of http.DefaultTransport.
+const pooledMaxIdleConnsPerHost = 64
+
+// pooledIdleTimeout is how long a pooled server is kept after its last
+// request.
+var pooledIdleTimeout = 10 * time.Minute
+
+// HTTPClientPool shares the connections and initialization of stateless HTTP
+// MCP servers, the ones that don't return a session ID when initialized,
+// between the clients of every session. Clients of the same server config,
+// before the env of the session is substituted, and initialize request reuse
+// the result of the first initialize request and the connections of one HTTP
+// client. The headers of each session, its passthrough headers, tokens and
+// OAuth credentials are still sent per request by the client of the session.
+// Servers without requests for pooledIdleTimeout are removed from the pool.
+type HTTPClientPool struct {
+	lock      sync.Mutex
+	servers   map[string]*pooledServer
+	lastSweep time.Time
+}
+
+type pooledServer struct {
+	httpClient *http.Client
+	transport  *http.Transport
+	lock       sync.RWMutex
+	state      *SessionState
+	lastUsed   time.Time
+}
+
+// RoundTrip records the use of the server and sends the request over the
+// shared connections.
+func (s *pooledServer) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.lock.Lock()
+	s.lastUsed = time.Now()
+	s.lock.Unlock()
+	return s.transport.RoundTrip(req)
+}
+
+func (s *pooledServer) idleSince() time.Time {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.lastUsed
+}
+
+func NewHTTPClientPool() *HTTPClientPool {
+	return &HTTPClientPool{
+		servers: map[string]*pooledServer{},
+	}
+}
+
+// server returns the pooled server of a config, before the env of the
+// session is substituted, and an initialize request.
+func (p *HTTPClientPool) server(serverName string, config Server, initialize InitializeRequest) *pooledServer {
+	data, err := json.Marshal(struct {
+		Name       string            `json:"name"`
+		Config     Server            `json:"config"`
+		Initialize InitializeRequest `json:"initialize"`
+	}{serverName, config, initialize})
+	if err != nil {
+		return nil
+	}
+	hash := sha256.Sum256(data)
+	key := hex.EncodeToString(hash[:])
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.evictIdleLocked()
+	if server, ok := p.servers[key]; ok {
+		return server
+	}
+
+	server := &pooledServer{
+		transport: http.DefaultTransport.(*http.Transport).Clone(),
+		lastUsed:  time.Now(),
+	}
+	server.transport.MaxIdleConnsPerHost = pooledMaxIdleConnsPerHost
+	server.httpClient = instrumentHTTPClient(&http.Client{Transport: server})
+	p.servers[key] = server
+	return server
+}
+
+// evictIdleLocked removes the servers without requests for
+// pooledIdleTimeout, at most once per pooledIdleTimeout. Clients still
+// holding an evicted server keep working, they just don't share its
+// connections with new clients.
+func (p *HTTPClientPool) evictIdleLocked() {
+	now := time.Now()
+	if now.Sub(p.lastSweep) < pooledIdleTimeout {
+		return
+	}
+	p.lastSweep = now
+	for key, server := range p.servers {
+		if now.Sub(server.idleSince()) >= pooledIdleTimeout {
+			delete(p.servers, key)
+			server.transport.CloseIdleConnections()
+		}
+	}
+}
+
+// sessionState returns the state to start a client of the server with, if
+// it is known to be stateless.
+func (s *pooledServer) sessionState() *SessionState {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if s.state == nil {
+		return nil
+	}
+	state := *s.state
+	return &state
+}
+
+// initialized records the result of initializing a client of the server,
+// remembering it if the server didn't start a session.
+func (s *pooledServer) initialized(serverName, sessionID string, request InitializeRequest, result InitializeResult) {
+	if sessionID != "" {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.state == nil {
+		slog.Info("mcp client pooling stateless server", "server", serverName)
+		s.state = &SessionState{
+			InitializeRequest: request,
+			InitializeResult:  result,
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHTTPClientPool(t *testing.T) {
+	for _, stateful := range []bool{false, true} {
+		var (
+			lock        sync.Mutex
+			initialized int
+			users       []string
+		)
+		upstream := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if req.Method != http.MethodPost {
+				rw.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			var msg Message
+			if err := json.NewDecoder(req.Body).Decode(&msg); err != nil || msg.ID == nil {
+				rw.WriteHeader(http.StatusAccepted)
+				return
+			}
+
+			var result any
+			lock.Lock()
+			switch msg.Method {
+			case "initialize":
+				initialized++
+				if stateful {
+					rw.Header().Set(SessionIDHeader, "s1")
+				}
+				result = InitializeResult{
+					ProtocolVersion: "2025-06-18",
+					Capabilities:    ServerCapabilities{Tools: &ToolsServerCapability{}},
+				}
+			case "tools/list":
+				users = append(users, req.Header.Get("X-User")+":"+req.Header.Get("X-Token"))
+				result = ListToolsResult{Tools: []Tool{{Name: "query"}}}
+			}
+			lock.Unlock()
+
+			data, _ := json.Marshal(result)
+			rw.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(rw).Encode(Message{JSONRPC: "2.0", ID: msg.ID, Result: data})
+		}))
+
+		pool := NewHTTPClientPool()
+		config := Server{
+			BaseURL:            upstream.URL,
+			Headers:            map[string]string{"X-Token": "${TOKEN}"},
+			PassthroughHeaders: []string{"X-User"},
+		}
+		for _, user := range []string{"alice", "bob"} {
+			incoming := httptest.NewRequest(http.MethodPost, "http://nanobot.example/mcp", nil)
+			incoming.Header.Set("X-User", user)
+			ctx := WithRequest(t.Context(), incoming)
+
+			c, err := NewClient(ctx, "search", config, ClientOption{
+				HTTPClientOptions: HTTPClientOptions{Pool: pool},
+				Env:               map[string]string{"TOKEN": user + "-token"},
+				OnNotify:          func(context.Context, Message) error { return nil },
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := c.ListTools(ctx); err != nil {
+				t.Fatal(err)
+			}
+			c.Close(false)
+		}
+		upstream.Close()
+
+		want := 1
+		if stateful {
+			want = 2
+		}
+		if initialized != want {
+			t.Errorf("stateful=%v: expected %d initialize requests, got %d", stateful, want, initialized)
+		}
+		if len(users) != 2 || users[0] != "alice:alice-token" || users[1] != "bob:bob-token" {
+			t.Errorf("stateful=%v: expected the headers of each session, got %v", stateful, users)
+		}
+	}
+}
+
+func TestHTTPClientPoolEvictsIdleServers(t *testing.T) {
+	defer func(timeout time.Duration) { pooledIdleTimeout = timeout }(pooledIdleTimeout)
+	pooledIdleTimeout = time.Hour
+
+	pool := NewHTTPClientPool()
+	first := pool.server("search", Server{BaseURL: "https://search.example"}, InitializeRequest{})
+	if got := pool.server("search", Server{BaseURL: "https://search.example"}, InitializeRequest{}); got != first {
+		t.Fatal("expected the same config to share a pooled server")
+	}
+
+	first.lock.Lock()
+	first.lastUsed = time.Now().Add(-2 * time.Hour)
+	first.lock.Unlock()
+	pool.lastSweep = time.Time{}
+
+	pool.server("other", Server{BaseURL: "https://other.example"}, InitializeRequest{})
+	if got := pool.server("search", Server{BaseURL: "https://search.example"}, InitializeRequest{}); got == first {
+		t.Error("expected the idle pooled server to be evicted")
+	}
+}
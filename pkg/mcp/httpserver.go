@@ -10,8 +10,10 @@ import (
 	"log/slog"
 	"maps"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/obot-platform/nanobot/pkg/complete"
@@ -106,6 +108,7 @@ type HTTPServer struct {
 	sessions                  SessionStore
 	ctx                       context.Context
 	healthzPath               string
+	readyChecks               []HealthCheck
 
 	// internal health check state
 	internalSession *ServerSession
@@ -113,12 +116,23 @@ type HTTPServer struct {
 	healthMu        sync.RWMutex
 
 	auditLogCollector *auditlogs.Collector
+
+	// draining is set once the server stops accepting new sessions, inFlight
+	// counts the messages being handled, and closeStreams is closed to end the
+	// event streams.
+	draining         atomic.Bool
+	inFlight         atomic.Int64
+	closeStreams     chan struct{}
+	closeStreamsOnce sync.Once
 }
 
 type HTTPServerOptions struct {
-	SessionStore      SessionStore
-	BaseContext       context.Context
-	HealthCheckPath   string
+	SessionStore    SessionStore
+	BaseContext     context.Context
+	HealthCheckPath string
+	// ReadyCheckPath serves the readiness probe, running ReadyChecks.
+	ReadyCheckPath    string
+	ReadyChecks       []HealthCheck
 	ResourceName      string
 	RunHealthChecker  bool
 	AuditLogCollector *auditlogs.Collector
@@ -143,6 +157,8 @@ func (h HTTPServerOptions) Merge(other HTTPServerOptions) (result HTTPServerOpti
 	h.BaseContext = complete.Last(h.BaseContext, other.BaseContext)
 	h.RunHealthChecker = complete.Last(h.RunHealthChecker, other.RunHealthChecker)
 	h.HealthCheckPath = complete.Last(h.HealthCheckPath, other.HealthCheckPath)
+	h.ReadyCheckPath = complete.Last(h.ReadyCheckPath, other.ReadyCheckPath)
+	h.ReadyChecks = append(h.ReadyChecks, other.ReadyChecks...)
 	h.ResourceName = complete.Last(h.ResourceName, other.ResourceName)
 	h.AuditLogCollector = complete.Last(h.AuditLogCollector, other.AuditLogCollector)
 	return h
@@ -157,11 +173,16 @@ func NewHTTPServer(ctx context.Context, envProvider func() (map[string]string, e
 		sessions:          o.SessionStore,
 		ctx:               o.BaseContext,
 		auditLogCollector: o.AuditLogCollector,
+		readyChecks:       o.ReadyChecks,
+		closeStreams:      make(chan struct{}),
 	}
 
 	if o.HealthCheckPath != "" {
 		h.mux.HandleFunc("GET /"+strings.TrimPrefix(o.HealthCheckPath, "/"), h.healthz)
 	}
+	if o.ReadyCheckPath != "" {
+		h.mux.HandleFunc("GET /"+strings.TrimPrefix(o.ReadyCheckPath, "/"), h.readyz)
+	}
 
 	if o.RunHealthChecker {
 		go h.runHealthTicker()
@@ -225,24 +246,74 @@ func (h *HTTPServer) streamEvents(rw http.ResponseWriter, req *http.Request, aud
 	// connections for the same session each get their own channel and every
 	// message is delivered to all of them. The done channel signals session
 	// closure so we don't hang after the session is torn down.
-	ch, done := session.Subscribe(req.Context())
+	//
+	// A reconnecting client sends the ID of the last event it saw, and the
+	// events it missed are replayed before any new ones.
+	lastEventID, _ := strconv.ParseUint(req.Header.Get("Last-Event-ID"), 10, 64)
+	replay, ch, done := session.Subscribe(req.Context(), lastEventID)
+	if len(replay) > 0 {
+		slog.Debug("mcp server replaying events", "session_id", id, "last_event_id", lastEventID, "count", len(replay))
+	}
+	for _, event := range replay {
+		if err := writeStreamEvent(rw, event); err != nil {
+			return
+		}
+	}
 
 	for {
 		select {
-		case msg := <-ch:
-			data, _ := json.Marshal(msg)
-			_, err := rw.Write([]byte("data: " + string(data) + "\n\n"))
-			if err != nil {
+		case event, ok := <-ch:
+			if !ok {
 				return
 			}
-			if f, ok := rw.(http.Flusher); ok {
-				f.Flush()
+			if err := writeStreamEvent(rw, event); err != nil {
+				return
 			}
 		case <-done:
 			slog.Debug("mcp server event stream closed", "session_id", id)
 			return
+		case <-h.closeStreams:
+			slog.Debug("mcp server event stream closed for shutdown", "session_id", id)
+			return
+		}
+	}
+}
+
+// Drain stops accepting new sessions and waits until the messages being
+// handled are done, or the context is. Existing sessions can still send
+// messages, in-flight tool calls may need them to finish.
+func (h *HTTPServer) Drain(ctx context.Context) error {
+	h.draining.Store(true)
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for h.inFlight.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%d requests still in flight: %w", h.inFlight.Load(), ctx.Err())
+		case <-ticker.C:
 		}
 	}
+	return nil
+}
+
+// CloseStreams ends the event streams of all sessions, so their clients
+// reconnect, to another replica once this one stopped listening.
+func (h *HTTPServer) CloseStreams() {
+	h.closeStreamsOnce.Do(func() {
+		close(h.closeStreams)
+	})
+}
+
+func writeStreamEvent(rw http.ResponseWriter, event StreamEvent) error {
+	data, _ := json.Marshal(event.Message)
+	if _, err := fmt.Fprintf(rw, "id: %d\ndata: %s\n\n", event.ID, data); err != nil {
+		return err
+	}
+	if f, ok := rw.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
 }
 
 type requestKey struct{}
@@ -256,20 +327,6 @@ func RequestFromContext(ctx context.Context) *http.Request {
 	return ret
 }
 
-func (h *HTTPServer) healthz(rw http.ResponseWriter, req *http.Request) {
-	h.healthMu.RLock()
-	healthErr := h.healthErr
-	h.healthMu.RUnlock()
-
-	if healthErr == nil {
-		http.Error(rw, "waiting for startup", http.StatusTooEarly)
-	} else if *healthErr != nil {
-		http.Error(rw, (*healthErr).Error(), http.StatusInternalServerError)
-	} else {
-		rw.WriteHeader(http.StatusOK)
-	}
-}
-
 func (h *HTTPServer) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	h.mux.ServeHTTP(rw, req)
 }
@@ -354,6 +411,9 @@ func (h *HTTPServer) serveHTTP(rw http.ResponseWriter, req *http.Request) {
 
 	ctx = WithAuditLog(ctx, &auditLog)
 
+	h.inFlight.Add(1)
+	defer h.inFlight.Add(-1)
+
 	var err error
 	auditLog.RequestBody, err = io.ReadAll(req.Body)
 	if err != nil {
@@ -468,6 +528,13 @@ func (h *HTTPServer) serveHTTP(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if h.draining.Load() {
+		slog.Info("mcp server rejected new session while shutting down", "request_id", MessageIDString(msg.ID))
+		rw.Header().Set("Retry-After", "1")
+		http.Error(rw, `{"http_error": "Server is shutting down"}`, http.StatusServiceUnavailable)
+		return
+	}
+
 	session, err := NewServerSession(h.ctx, h.MessageHandler, ServerSessionOptions{
 		DefaultAgent: req.Header.Get("X-Nanobot-Default-Agent"),
 	})
@@ -0,0 +1,164 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPServerDrain(t *testing.T) {
+	ctx := t.Context()
+	started, release := make(chan struct{}), make(chan struct{})
+	handler := MessageHandlerFunc(func(ctx context.Context, msg Message) {
+		close(started)
+		<-release
+		_ = msg.Reply(ctx, map[string]any{})
+	})
+
+	store := NewInMemorySessionStore()
+	server, err := NewHTTPServer(ctx, nil, handler, HTTPServerOptions{
+		SessionStore:    store,
+		HealthCheckPath: "/healthz",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := NewServerSession(ctx, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close(false)
+	_ = store.Store(ctx, session.ID(), session)
+
+	post := func(body, sessionID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body))
+		if sessionID != "" {
+			req.Header.Set("Mcp-Session-Id", sessionID)
+		}
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// A tool call is in flight when the server starts draining.
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- post(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"slow"}}`, session.ID())
+	}()
+	<-started
+
+	drained := make(chan error)
+	go func() {
+		drained <- server.Drain(ctx)
+	}()
+	for !server.draining.Load() {
+		time.Sleep(time.Millisecond)
+	}
+
+	if rec := post(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`, ""); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected new sessions to be refused while draining, got %d", rec.Code)
+	}
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected the health check to fail while draining, got %d", rec.Code)
+	}
+
+	select {
+	case err := <-drained:
+		t.Fatalf("expected draining to wait for the tool call, got %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	close(release)
+	if rec := <-done; rec.Code != http.StatusOK {
+		t.Fatalf("expected the tool call to finish, got %d", rec.Code)
+	}
+	if err := <-drained; err != nil {
+		t.Fatal(err)
+	}
+
+	deadline, cancel := context.WithTimeout(ctx, 0)
+	defer cancel()
+	if err := server.Drain(deadline); err != nil {
+		t.Fatalf("expected nothing left in flight, got %v", err)
+	}
+}
+
+func TestHTTPServerReadyz(t *testing.T) {
+	var dbErr error
+	server, err := NewHTTPServer(t.Context(), nil, MessageHandlerFunc(func(context.Context, Message) {}), HTTPServerOptions{
+		HealthCheckPath: "/healthz",
+		ReadyCheckPath:  "/readyz",
+		ReadyChecks: []HealthCheck{
+			{Name: "db", Check: func(context.Context) error { return dbErr }},
+			{Name: "llm", Check: func(context.Context) error { return nil }},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(path string) (int, HealthReport) {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		var report HealthReport
+		if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+			t.Fatalf("GET %s: invalid report %q: %v", path, rec.Body.String(), err)
+		}
+		return rec.Code, report
+	}
+
+	if code, report := get("/readyz"); code != http.StatusOK || report.Status != "ok" || len(report.Checks) != 3 {
+		t.Errorf("expected ready, got %d %+v", code, report)
+	}
+	if code, report := get("/healthz"); code != http.StatusOK || report.Checks["mcpServers"].Status != "ok" {
+		t.Errorf("expected healthy, got %d %+v", code, report)
+	}
+
+	dbErr = errors.New("connection refused")
+	code, report := get("/readyz")
+	if code != http.StatusServiceUnavailable || report.Status != "error" ||
+		report.Checks["db"].Error != "connection refused" || report.Checks["llm"].Status != "ok" {
+		t.Errorf("expected the failing check to be reported, got %d %+v", code, report)
+	}
+}
+
+func TestCachedHealthCheck(t *testing.T) {
+	calls := 0
+	check := CachedHealthCheck(time.Hour, func(context.Context) error {
+		calls++
+		return nil
+	})
+	for range 3 {
+		_ = check(t.Context())
+	}
+	if calls != 1 {
+		t.Errorf("expected the check to run once, got %d", calls)
+	}
+}
+
+func TestCachedHealthCheckSkipsContextErrors(t *testing.T) {
+	calls := 0
+	check := CachedHealthCheck(time.Hour, func(ctx context.Context) error {
+		calls++
+		return ctx.Err()
+	})
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if err := check(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the canceled probe to fail, got %v", err)
+	}
+	if err := check(t.Context()); err != nil {
+		t.Errorf("expected the next probe to run the check again, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected the check to run twice, got %d", calls)
+	}
+}
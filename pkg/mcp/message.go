@@ -176,6 +176,7 @@ var (
 	ErrRPCInvalidParams    = NewRPCError(-32602, "JSON RPC invalid params")
 	ErrRPCInternal         = NewRPCError(-32603, "JSON RPC internal error")
 	ErrRPCRequestCancelled = NewRPCError(-32800, "Request cancelled")
+	ErrRPCLimitExceeded    = NewRPCError(-32029, "Limit exceeded")
 )
 
 type RPCError struct {
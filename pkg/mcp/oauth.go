@@ -96,6 +96,33 @@ func (o *oauth) loadFromStorage(ctx context.Context, connectURL string) *http.Cl
 	return nil
 }
 
+// refreshFromStorage forces a refresh of the stored token, for when the server
+// rejects an access token that hasn't expired yet. It returns nil if there is
+// no refresh token or the refresh fails, and the user has to consent again.
+func (o *oauth) refreshFromStorage(ctx context.Context, connectURL string) *http.Client {
+	if o.tokenStorage == nil {
+		return nil
+	}
+
+	conf, tok, err := o.tokenStorage.GetTokenConfig(ctx, connectURL)
+	if err != nil || conf == nil || tok == nil || tok.RefreshToken == "" {
+		return nil
+	}
+
+	expired := *tok
+	expired.Expiry = time.Now().Add(-time.Minute)
+	ts := newTokenSource(ctx, o.tokenStorage, connectURL, conf, &expired)
+	tok, err = ts.Token()
+	if err != nil || !tok.Valid() {
+		slog.Info("failed to refresh stored oauth token", "connect_url", connectURL, "error", err)
+		return nil
+	}
+
+	o.currentToken = *tok
+	slog.Info("refreshed stored oauth token", "connect_url", connectURL)
+	return oauth2.NewClient(ctx, ts)
+}
+
 func discoverOAuthMetadata(ctx context.Context, client *http.Client, baseURL, authenticateHeader, clientName, redirectURL string, headers map[string]string) (oauthMetadataDiscovery, bool, error) {
 	resourceMetadataURL, scope, u, err := oauthResourceMetadataURL(baseURL, authenticateHeader)
 	if err != nil {
@@ -7,6 +7,9 @@ import (
 	"net/http/httptest"
 	"sync/atomic"
 	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
 )
 
 func TestGetOAuthMetadata(t *testing.T) {
@@ -180,3 +183,60 @@ func TestGetOAuthMetadataAuthorizationServerNoRegistration(t *testing.T) {
 		t.Fatalf("expected no dynamic client registration support")
 	}
 }
+
+type memoryTokenStorage struct {
+	conf *oauth2.Config
+	tok  *oauth2.Token
+}
+
+func (m *memoryTokenStorage) GetTokenConfig(context.Context, string) (*oauth2.Config, *oauth2.Token, error) {
+	return m.conf, m.tok, nil
+}
+
+func (m *memoryTokenStorage) SetTokenConfig(_ context.Context, _ string, conf *oauth2.Config, tok *oauth2.Token) error {
+	m.conf, m.tok = conf, tok
+	return nil
+}
+
+func (m *memoryTokenStorage) DeleteTokenConfig(context.Context, string) error {
+	m.conf, m.tok = nil, nil
+	return nil
+}
+
+func TestRefreshFromStorage(t *testing.T) {
+	var refreshes atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if err := req.ParseForm(); err != nil || req.Form.Get("grant_type") != "refresh_token" || req.Form.Get("refresh_token") != "refresh" {
+			http.Error(w, "invalid_grant", http.StatusBadRequest)
+			return
+		}
+		refreshes.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"new","refresh_token":"refresh2","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer ts.Close()
+
+	conf := &oauth2.Config{ClientID: "client", Endpoint: oauth2.Endpoint{TokenURL: ts.URL}}
+	storage := &memoryTokenStorage{
+		conf: conf,
+		tok:  &oauth2.Token{AccessToken: "revoked", RefreshToken: "refresh", Expiry: time.Now().Add(time.Hour)},
+	}
+	o := newOAuth(nil, nil, storage, "Test Client", "http://localhost/callback")
+
+	if client := o.refreshFromStorage(t.Context(), "http://example.com/mcp"); client == nil {
+		t.Fatal("expected a client with the refreshed token")
+	}
+	if refreshes.Load() != 1 || storage.tok.AccessToken != "new" || storage.tok.RefreshToken != "refresh2" {
+		t.Fatalf("expected the refreshed token to be stored, got %d refreshes and %+v", refreshes.Load(), storage.tok)
+	}
+
+	// A refresh the server rejects means the user has to consent again.
+	if client := o.refreshFromStorage(t.Context(), "http://example.com/mcp"); client != nil {
+		t.Fatal("expected no client when the refresh is rejected")
+	}
+
+	storage.tok = &oauth2.Token{AccessToken: "revoked"}
+	if client := o.refreshFromStorage(t.Context(), "http://example.com/mcp"); client != nil {
+		t.Fatal("expected no client without a refresh token")
+	}
+}
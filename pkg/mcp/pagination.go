@@ -0,0 +1,66 @@
+package mcp
+
+import (
+	"context"
+	"encoding/base64"
+	"strconv"
+)
+
+// PageSize is how many items a page of a list result holds.
+const PageSize = 100
+
+// maxListPages bounds how many pages a client fetches for a list, in case a
+// server keeps returning cursors.
+const maxListPages = 1000
+
+// Paginate returns the page of the items that starts at the cursor, and the
+// cursor of the next page, or "" for the last page. Cursors are opaque
+// offsets in the items, so items must be listed in a stable order.
+func Paginate[T any](items []T, cursor string) ([]T, string, error) {
+	start := 0
+	if cursor != "" {
+		data, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err == nil {
+			start, err = strconv.Atoi(string(data))
+		}
+		if err != nil || start < 0 {
+			return nil, "", ErrRPCInvalidParams.WithMessage("invalid cursor %q", cursor)
+		}
+	}
+	if start >= len(items) {
+		return items[len(items):], "", nil
+	}
+
+	end := start + PageSize
+	if end >= len(items) {
+		return items[start:], "", nil
+	}
+	return items[start:end], base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(end))), nil
+}
+
+// exchangeList exchanges a list request for each page of the result, until
+// the server returns no next cursor. The items of the other pages are
+// appended to the first page, which is returned.
+func exchangeList[R any](ctx context.Context, session *Session, method string, request func(cursor string) any,
+	nextCursor func(*R) *string, appendPage func(result, page *R)) (R, error) {
+	var result R
+	if err := session.Exchange(ctx, method, request(""), &result); err != nil {
+		return result, err
+	}
+
+	cursor := nextCursor(&result)
+	for pages := 1; *cursor != "" && pages < maxListPages; pages++ {
+		var page R
+		if err := session.Exchange(ctx, method, request(*cursor), &page); err != nil {
+			return result, err
+		}
+		appendPage(&result, &page)
+		if next := *nextCursor(&page); next != *cursor {
+			*cursor = next
+		} else {
+			break
+		}
+	}
+	*cursor = ""
+	return result, nil
+}
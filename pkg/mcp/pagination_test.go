@@ -0,0 +1,93 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestPaginate(t *testing.T) {
+	items := make([]int, 2*PageSize+1)
+	for i := range items {
+		items[i] = i
+	}
+
+	var (
+		cursor string
+		all    []int
+	)
+	for pages := 1; ; pages++ {
+		page, next, err := Paginate(items, cursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		all = append(all, page...)
+		if next == "" {
+			if pages != 3 {
+				t.Errorf("expected 3 pages, got %d", pages)
+			}
+			break
+		}
+		cursor = next
+	}
+	if len(all) != len(items) || all[len(all)-1] != len(items)-1 {
+		t.Errorf("expected all the items once, got %d", len(all))
+	}
+
+	if _, _, err := Paginate(items, "not a cursor"); err == nil {
+		t.Error("expected an invalid cursor to fail")
+	}
+}
+
+func TestClientListsAllPages(t *testing.T) {
+	tools := ServerTools{}
+	for i := range PageSize + 5 {
+		name := fmt.Sprintf("tool_%03d", i)
+		tools[name] = NewServerTool(name, "", func(context.Context, struct{}) (string, error) {
+			return "", nil
+		})
+	}
+
+	var requests int
+	handler := MessageHandlerFunc(func(ctx context.Context, msg Message) {
+		switch msg.Method {
+		case "initialize":
+			_ = msg.Reply(ctx, InitializeResult{
+				ProtocolVersion: "2025-06-18",
+				Capabilities:    ServerCapabilities{Tools: &ToolsServerCapability{}},
+			})
+		case "tools/list":
+			requests++
+			var req ListToolsRequest
+			_ = json.Unmarshal(msg.Params, &req)
+			result, err := tools.List(ctx, msg, req)
+			if err != nil {
+				msg.SendError(ctx, err)
+				return
+			}
+			_ = msg.Reply(ctx, result)
+		}
+	})
+
+	wire, err := NewExistingServerSession(t.Context(), SessionState{}, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewClient(t.Context(), "tools", Server{}, ClientOption{Wire: wire})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close(false) })
+
+	result, err := client.ListTools(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Tools) != PageSize+5 || result.NextCursor != "" {
+		t.Errorf("expected %d tools and no cursor, got %d tools and cursor %q", PageSize+5, len(result.Tools), result.NextCursor)
+	}
+	if requests != 2 {
+		t.Errorf("expected 2 list requests, got %d", requests)
+	}
+}
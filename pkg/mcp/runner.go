@@ -1,6 +1,7 @@
 package mcp
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"io"
@@ -23,6 +24,41 @@ import (
 type Runner struct {
 	lock    sync.Mutex
 	running map[string]Server
+
+	stdioLock sync.Mutex
+	stdio     map[*Stdio]struct{}
+}
+
+func (r *Runner) track(s *Stdio) {
+	r.stdioLock.Lock()
+	defer r.stdioLock.Unlock()
+	if r.stdio == nil {
+		r.stdio = map[*Stdio]struct{}{}
+	}
+	r.stdio[s] = struct{}{}
+}
+
+func (r *Runner) untrack(s *Stdio) {
+	r.stdioLock.Lock()
+	defer r.stdioLock.Unlock()
+	delete(r.stdio, s)
+}
+
+// Processes returns the state of the processes of the stdio servers started
+// by the runner, sorted by server and session.
+func (r *Runner) Processes() []ProcessStatus {
+	r.stdioLock.Lock()
+	wires := slices.Collect(maps.Keys(r.stdio))
+	r.stdioLock.Unlock()
+
+	result := make([]ProcessStatus, 0, len(wires))
+	for _, s := range wires {
+		result = append(result, s.status())
+	}
+	slices.SortFunc(result, func(a, b ProcessStatus) int {
+		return cmp.Or(strings.Compare(a.Server, b.Server), strings.Compare(a.SessionID, b.SessionID))
+	})
+	return result
 }
 
 type streamResult struct {
@@ -74,6 +110,9 @@ func (r *Runner) newCommand(ctx context.Context, currentEnv map[string]string, r
 		cmd := supervise.Cmd(internalCtx, command, args...)
 		cmd.Dir = envvar.ReplaceString(currentEnv, config.Cwd)
 		cmd.Env = append(cleanOSEnv(), env...)
+		if limits := processLimits(config.Process); !limits.IsZero() {
+			cmd.Env = append(cmd.Env, limits.Env())
+		}
 		return config, sandbox.WrapCmd(ctx, cmd, forceCancel, nil), nil
 	}
 
@@ -119,6 +158,17 @@ func (r *Runner) newCommand(ctx context.Context, currentEnv map[string]string, r
 	return config, cmd, nil
 }
 
+// processLimits returns the resource limits of the process of a server.
+func processLimits(settings *ProcessSettings) supervise.Limits {
+	if settings == nil {
+		return supervise.Limits{}
+	}
+	return supervise.Limits{
+		MemoryBytes: uint64(settings.MaxMemoryMB) << 20,
+		CPUSeconds:  uint64(settings.MaxCPUSeconds),
+	}
+}
+
 var allowedEnv = map[string]bool{
 	"PATH": true,
 	"HOME": true,
@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
 	"sync"
 
 	"github.com/obot-platform/nanobot/pkg/complete"
@@ -65,13 +68,66 @@ type ServerSession struct {
 	wire    *serverWire
 }
 
-// Subscribe returns a message channel and a done channel. The message channel
-// receives a copy of every message sent through the server wire. Multiple
-// subscribers can exist concurrently and each receives every message (broadcast
-// semantics). The done channel is closed when the session is closed; callers
-// must select on it to detect session shutdown.
-func (s *ServerSession) Subscribe(ctx context.Context) (<-chan Message, <-chan struct{}) {
-	return s.wire.subscribe(ctx)
+// maxBufferedEvents bounds the number of sent messages each session keeps for
+// replay to event streams that reconnect.
+const maxBufferedEvents = 256
+
+// StreamEvent is a message sent through a server session, numbered in the
+// order it was sent.
+type StreamEvent struct {
+	ID      uint64
+	Message Message
+}
+
+// EventStore persists the events sent through server sessions.
+type EventStore interface {
+	// AppendEvent stores a message and returns its event ID, which is greater
+	// than the IDs of the session's earlier events.
+	AppendEvent(ctx context.Context, sessionID string, msg Message) (uint64, error)
+	// EventsSince returns the session's stored events after lastEventID,
+	// oldest first.
+	EventsSince(ctx context.Context, sessionID string, lastEventID uint64) ([]StreamEvent, error)
+}
+
+// Subscribe returns the buffered events sent after lastEventID, an event
+// channel, and a done channel. The event channel receives every message sent
+// through the server wire after the returned replay, with no gap or overlap.
+// Multiple subscribers can exist concurrently and each receives every message
+// (broadcast semantics). Use a lastEventID of 0 to skip the replay. The done
+// channel is closed when the session is closed; callers must select on it to
+// detect session shutdown.
+func (s *ServerSession) Subscribe(ctx context.Context, lastEventID uint64) ([]StreamEvent, <-chan StreamEvent, <-chan struct{}) {
+	return s.wire.subscribe(ctx, lastEventID)
+}
+
+// SetRelay sets a function that receives every notification sent through the
+// session, such as resources/updated, in addition to its event streams. It is
+// used to forward them to event streams of the session on other replicas.
+func (s *ServerSession) SetRelay(relay func(context.Context, StreamEvent)) {
+	s.wire.subscriberLock.Lock()
+	defer s.wire.subscriberLock.Unlock()
+	s.wire.relay = relay
+}
+
+// SetEventStore persists the events of the session in an event store, so
+// that an event stream can resume with Last-Event-ID on any replica. Events
+// are then numbered by the store.
+func (s *ServerSession) SetEventStore(store EventStore) {
+	s.wire.sendLock.Lock()
+	defer s.wire.sendLock.Unlock()
+	s.wire.eventStore = store
+}
+
+// Deliver sends an event relayed from another replica to the event streams of
+// the session without relaying it again. It keeps its ID if the session has
+// an event store, the event is already stored.
+func (s *ServerSession) Deliver(ctx context.Context, event StreamEvent) error {
+	s.wire.sendLock.Lock()
+	if s.wire.eventStore == nil {
+		event.ID = 0
+	}
+	_, err := s.wire.publish(ctx, s.wire.record(ctx, event))
+	return err
 }
 
 func (s *ServerSession) Wait() {
@@ -189,7 +245,18 @@ type serverWire struct {
 	sessionID  string
 
 	subscriberLock sync.RWMutex
-	subscribers    []chan Message
+	subscribers    []chan StreamEvent
+	// relay receives the notifications sent through the wire, so they can
+	// reach event streams of the session that are connected elsewhere.
+	relay func(context.Context, StreamEvent)
+
+	// sendLock orders sends so that event IDs match delivery order.
+	sendLock    sync.Mutex
+	lastEventID uint64
+	events      []StreamEvent
+	// eventStore persists the events instead of the events buffer, so that
+	// they can be replayed by any replica.
+	eventStore EventStore
 }
 
 func (s *serverWire) SessionID() string {
@@ -223,19 +290,49 @@ func (s *serverWire) exchange(ctx context.Context, msg Message) (Message, error)
 	}
 }
 
-func (s *serverWire) subscribe(ctx context.Context) (<-chan Message, <-chan struct{}) {
-	ch := make(chan Message, 32)
+func (s *serverWire) subscribe(ctx context.Context, lastEventID uint64) ([]StreamEvent, <-chan StreamEvent, <-chan struct{}) {
+	ch := make(chan StreamEvent, 32)
+
+	// Hold the send lock so that nothing is sent between taking the replay and
+	// registering the subscriber.
+	s.sendLock.Lock()
+	var replay []StreamEvent
+	if lastEventID > 0 && s.eventStore != nil {
+		var err error
+		replay, err = s.eventStore.EventsSince(ctx, s.sessionID, lastEventID)
+		if err != nil {
+			slog.Error("mcp server failed to load session events for replay",
+				"session_id", s.sessionID,
+				"last_event_id", lastEventID,
+				"error", err)
+		}
+	} else if lastEventID > 0 {
+		for i, event := range s.events {
+			if event.ID > lastEventID {
+				replay = slices.Clone(s.events[i:])
+				break
+			}
+		}
+		if len(s.events) > 0 && s.events[0].ID > lastEventID+1 {
+			slog.Warn("mcp server session event buffer overflowed, some events cannot be replayed",
+				"session_id", s.sessionID,
+				"last_event_id", lastEventID,
+				"oldest_event_id", s.events[0].ID)
+		}
+	}
 	s.subscriberLock.Lock()
 	s.subscribers = append(s.subscribers, ch)
 	s.subscriberLock.Unlock()
+	s.sendLock.Unlock()
+
 	context.AfterFunc(ctx, func() {
 		s.removeSubscriber(ch)
 		close(ch)
 	})
-	return ch, s.ctx.Done()
+	return replay, ch, s.ctx.Done()
 }
 
-func (s *serverWire) removeSubscriber(ch chan Message) {
+func (s *serverWire) removeSubscriber(ch chan StreamEvent) {
 	s.subscriberLock.Lock()
 	defer s.subscriberLock.Unlock()
 	for i, sub := range s.subscribers {
@@ -269,6 +366,64 @@ func (s *serverWire) Send(ctx context.Context, req Message) error {
 		return nil
 	}
 
+	s.sendLock.Lock()
+	event := s.record(ctx, StreamEvent{Message: req})
+	sent, err := s.publish(ctx, event)
+
+	if relay := s.getRelay(); relay != nil && req.ID == nil && strings.HasPrefix(req.Method, "notifications/") {
+		relay(ctx, event)
+	}
+
+	if sent || err != nil {
+		return err
+	}
+
+	// No subscribers — fall back to single-reader channel. This path is
+	// used by in-process connections (ServerSession.Start) where there is
+	// exactly one reader on s.read.
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	case <-s.noReader:
+		return ErrNoReader
+	case s.read <- req:
+		return nil
+	}
+}
+
+// record numbers an event and keeps it for replay, in the event store if
+// there is one. Events that already have an ID from the event store keep it.
+// It must be called with sendLock held.
+func (s *serverWire) record(ctx context.Context, event StreamEvent) StreamEvent {
+	if s.eventStore != nil {
+		if event.ID == 0 {
+			id, err := s.eventStore.AppendEvent(ctx, s.sessionID, event.Message)
+			if err != nil {
+				slog.Error("mcp server failed to persist session event, it cannot be replayed",
+					"session_id", s.sessionID,
+					"error", err)
+			}
+			event.ID = id
+		}
+		return event
+	}
+
+	// Buffer the message so an event stream that reconnects can replay it,
+	// even if nothing is listening right now.
+	s.lastEventID++
+	event.ID = s.lastEventID
+	if len(s.events) >= maxBufferedEvents {
+		s.events = slices.Delete(s.events, 0, len(s.events)-maxBufferedEvents+1)
+	}
+	s.events = append(s.events, event)
+	return event
+}
+
+// publish sends an event to all subscribers, returning whether there were
+// any. It must be called with sendLock held, and releases it.
+func (s *serverWire) publish(ctx context.Context, event StreamEvent) (bool, error) {
 	// If there are subscribers, broadcast to all of them instead of sending
 	// to the single read channel. This ensures that every SSE connection
 	// sees every server-to-client message (e.g. elicitation/create).
@@ -280,33 +435,28 @@ func (s *serverWire) Send(ctx context.Context, req Message) error {
 	// so there is no send-on-closed-channel panic.
 	s.subscriberLock.RLock()
 	if len(s.subscribers) > 0 {
+		defer s.sendLock.Unlock()
 		defer s.subscriberLock.RUnlock()
 		for _, ch := range s.subscribers {
 			select {
-			case ch <- req:
+			case ch <- event:
 			case <-ctx.Done():
-				return ctx.Err()
+				return true, ctx.Err()
 			case <-s.ctx.Done():
-				return s.ctx.Err()
+				return true, s.ctx.Err()
 			}
 		}
-		return nil
+		return true, nil
 	}
 	s.subscriberLock.RUnlock()
+	s.sendLock.Unlock()
+	return false, nil
+}
 
-	// No subscribers — fall back to single-reader channel. This path is
-	// used by in-process connections (ServerSession.Start) where there is
-	// exactly one reader on s.read.
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case <-s.ctx.Done():
-		return s.ctx.Err()
-	case <-s.noReader:
-		return ErrNoReader
-	case s.read <- req:
-		return nil
-	}
+func (s *serverWire) getRelay() func(context.Context, StreamEvent) {
+	s.subscriberLock.RLock()
+	defer s.subscriberLock.RUnlock()
+	return s.relay
 }
 
 func (s *serverWire) startReading() {
@@ -0,0 +1,59 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+)
+
+func TestServerSessionReplaysEventsAfterReconnect(t *testing.T) {
+	ctx := t.Context()
+	session, err := NewServerSession(ctx, MessageHandlerFunc(func(context.Context, Message) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close(false)
+
+	// Nothing is listening, the messages are only buffered.
+	for range 3 {
+		_ = session.wire.Send(ctx, Message{Method: "notifications/progress"})
+	}
+
+	subCtx, cancel := context.WithCancel(ctx)
+	replay, ch, _ := session.Subscribe(subCtx, 1)
+	if len(replay) != 2 || replay[0].ID != 2 || replay[1].ID != 3 {
+		t.Fatalf("expected events 2 and 3 to be replayed, got %+v", replay)
+	}
+
+	if err := session.wire.Send(ctx, Message{Method: "notifications/message"}); err != nil {
+		t.Fatal(err)
+	}
+	if event := <-ch; event.ID != 4 || event.Message.Method != "notifications/message" {
+		t.Fatalf("unexpected live event %+v", event)
+	}
+	cancel()
+
+	if replay, _, _ := session.Subscribe(ctx, 0); len(replay) != 0 {
+		t.Errorf("expected no replay without a last event ID, got %d events", len(replay))
+	}
+}
+
+func TestServerSessionEventBufferIsBounded(t *testing.T) {
+	ctx := t.Context()
+	session, err := NewServerSession(ctx, MessageHandlerFunc(func(context.Context, Message) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close(false)
+
+	for range maxBufferedEvents + 10 {
+		_ = session.wire.Send(ctx, Message{Method: "notifications/progress"})
+	}
+
+	replay, _, _ := session.Subscribe(ctx, 1)
+	if len(replay) != maxBufferedEvents {
+		t.Fatalf("expected %d replayed events, got %d", maxBufferedEvents, len(replay))
+	}
+	if replay[0].ID != 11 || replay[len(replay)-1].ID != maxBufferedEvents+10 {
+		t.Errorf("unexpected replay range %d-%d", replay[0].ID, replay[len(replay)-1].ID)
+	}
+}
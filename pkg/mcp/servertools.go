@@ -78,7 +78,9 @@ func (s *serverTool[In, Out]) Definition() Tool {
 	return s.tool
 }
 
-func (s *serverTool[In, Out]) Invoke(ctx context.Context, _ Message, call CallToolRequest) (*CallToolResult, error) {
+func (s *serverTool[In, Out]) Invoke(ctx context.Context, msg Message, call CallToolRequest) (*CallToolResult, error) {
+	ctx = WithProgressToken(ctx, msg.ProgressToken())
+
 	var in In
 	if len(call.Arguments) > 0 {
 		if err := JSONCoerce(call.Arguments, &in); err != nil {
@@ -126,6 +128,28 @@ func NewServerTool[In, Out any](name, description string, handler func(ctx conte
 	}
 }
 
+// ReadOnly marks a tool as not changing its environment with the readOnlyHint
+// annotation, so that agents can run its calls at the same time as other
+// read-only calls.
+func ReadOnly(tool ServerTool) ServerTool {
+	return readOnlyTool{tool}
+}
+
+type readOnlyTool struct {
+	ServerTool
+}
+
+func (r readOnlyTool) Definition() Tool {
+	tool := r.ServerTool.Definition()
+	var annotations ToolAnnotations
+	if tool.Annotations != nil {
+		annotations = *tool.Annotations
+	}
+	annotations.ReadOnlyHint = true
+	tool.Annotations = &annotations
+	return tool
+}
+
 func callResult(object any, err error) (*CallToolResult, error) {
 	if err != nil {
 		return nil, err
@@ -215,16 +239,25 @@ func callResult(object any, err error) (*CallToolResult, error) {
 	}, nil
 }
 
-func (s ServerTools) List(_ context.Context, _ Message, _ ListToolsRequest) (*ListToolsResult, error) {
+func (s ServerTools) List(_ context.Context, _ Message, req ListToolsRequest) (*ListToolsResult, error) {
+	tools, next, err := Paginate(s.Definitions(), req.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	return &ListToolsResult{
+		Tools:      tools,
+		NextCursor: next,
+	}, nil
+}
+
+// Definitions returns the definitions of the tools, sorted by name.
+func (s ServerTools) Definitions() []Tool {
 	// purposefully not set to nil, so that we can return an empty list
 	tools := []Tool{}
 	for _, key := range slices.Sorted(maps.Keys(s)) {
 		tools = append(tools, s[key].Definition())
 	}
-
-	return &ListToolsResult{
-		Tools: tools,
-	}, nil
+	return tools
 }
 
 func JSONCoerce[T any](in any, out *T) error {
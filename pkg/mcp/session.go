@@ -217,6 +217,16 @@ func (s *Session) removeProgressTokenMapping(ctx context.Context, id any) {
 	}
 }
 
+// ClientCapabilities returns the capabilities the client declared when it
+// initialized the root session.
+func (s *Session) ClientCapabilities() ClientCapabilities {
+	root := s.Root()
+	if root == nil {
+		return ClientCapabilities{}
+	}
+	return root.InitializeRequest.Capabilities
+}
+
 func (s *Session) ID() string {
 	if s == nil || s.wire == nil {
 		return ""
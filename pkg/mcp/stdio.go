@@ -6,7 +6,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	log2 "log"
 	"log/slog"
 	"os/exec"
 	"strings"
@@ -41,26 +40,47 @@ func (w *waiter) Close() {
 }
 
 type Stdio struct {
-	stdout         io.Reader
-	stdin          io.Writer
-	cmd            *exec.Cmd
-	closer         func()
 	server         string
+	sessionID      string
 	pendingRequest PendingRequests
 	waiter         *waiter
 	writeLock      sync.Mutex
+
+	ctx     context.Context
+	handler WireHandler
+
+	// lifecycle restarts the process of the server, nil if the wire closes
+	// with its process.
+	lifecycle *processLifecycle
+
+	lock    sync.Mutex
+	process *stdioProcess
+	// starting is closed once the process being started is running or
+	// failed to start, nil when no process is starting.
+	starting chan struct{}
+	closed   bool
+	onClose  func()
 }
 
 func (s *Stdio) Send(ctx context.Context, req Message) error {
-	s.writeLock.Lock()
-	defer s.writeLock.Unlock()
-
 	data, err := json.Marshal(req)
 	if err != nil {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
-	if s.cmd != nil && (s.cmd.Process == nil || s.cmd.ProcessState != nil) {
+	if req.Method == "ping" && s.answerIdlePing(req) {
+		return nil
+	}
+
+	p, err := s.ensureProcess(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+
+	if p.cmd != nil && (p.cmd.Process == nil || p.cmd.ProcessState != nil) {
 		return fmt.Errorf("stdin is closed")
 	}
 
@@ -72,11 +92,33 @@ func (s *Stdio) Send(ctx context.Context, req Message) error {
 			"call_identifier", getMessageName(&req))
 	}
 
+	p.sent(req)
 	log.Messages(ctx, s.server, true, data)
-	_, err = s.stdin.Write(append(data, '\n'))
+	_, err = p.stdin.Write(append(data, '\n'))
 	return err
 }
 
+// ensureProcess returns the running process, starting one if the previous
+// one was stopped.
+func (s *Stdio) ensureProcess(ctx context.Context, req Message) (*stdioProcess, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.lifecycle != nil {
+		s.lifecycle.record(req)
+	}
+	if err := s.waitStartingLocked(ctx); err != nil {
+		return nil, err
+	}
+	if s.process != nil {
+		return s.process, nil
+	}
+	if s.closed || s.lifecycle == nil {
+		return nil, fmt.Errorf("stdin is closed")
+	}
+	return s.startLocked(ctx, req.Method != "initialize")
+}
+
 func (s *Stdio) SessionID() string {
 	// Stdio does not have a session ID, return an empty string
 	return ""
@@ -87,65 +129,99 @@ func (s *Stdio) Wait() {
 }
 
 func (s *Stdio) Close(bool) {
-	s.closer()
+	s.lock.Lock()
+	p := s.process
+	s.process = nil
+	alreadyClosed := s.closed
+	s.closed = true
+	s.lock.Unlock()
+
+	if p != nil {
+		p.close()
+	}
 	s.waiter.Close()
+	if !alreadyClosed && s.onClose != nil {
+		s.onClose()
+	}
 }
 
 func (s *Stdio) Start(ctx context.Context, handler WireHandler) error {
+	s.ctx = ctx
+	s.handler = handler
 	context.AfterFunc(ctx, func() {
 		s.Close(false)
 	})
-	go func() {
-		err := s.start(ctx, handler)
-		if err != nil {
-			log2.Fatal(err)
-		}
-	}()
+
+	s.lock.Lock()
+	p := s.process
+	s.lock.Unlock()
+	if p != nil {
+		go s.read(p)
+	}
+	if s.lifecycle != nil {
+		go s.supervise()
+	}
 	return nil
 }
 
-func (s *Stdio) start(ctx context.Context, handler WireHandler) error {
-	defer s.Close(false)
+// read passes the messages of the process to the handler until it exits.
+func (s *Stdio) read(p *stdioProcess) {
+	err := s.readMessages(p)
+	close(p.done)
+	s.exited(p, err)
+}
 
-	buf := bufio.NewScanner(s.stdout)
+func (s *Stdio) readMessages(p *stdioProcess) error {
+	buf := bufio.NewScanner(p.stdout)
 	buf.Buffer(make([]byte, 0, 1024), 10*1024*1024)
 	for buf.Scan() {
 		text := strings.TrimSpace(buf.Text())
-		log.Messages(ctx, s.server, false, []byte(text))
+		log.Messages(s.ctx, s.server, false, []byte(text))
 		var msg Message
 		if err := json.Unmarshal([]byte(text), &msg); err != nil {
 			slog.Error("failed to unmarshal message", "error", err)
 			continue
 		}
-		if slog.Default().Enabled(ctx, slog.LevelDebug) {
+		if slog.Default().Enabled(s.ctx, slog.LevelDebug) {
 			slog.Debug("mcp stdio receive",
 				"server", s.server,
 				"method", msg.Method,
 				"request_id", MessageIDString(msg.ID),
 				"call_identifier", getMessageName(&msg))
 		}
-		go handler(ctx, msg)
+		if p.received(msg) {
+			continue
+		}
+		go s.handler(s.ctx, msg)
 	}
 	return buf.Err()
 }
 
-func newStdioClient(ctx context.Context, roots func(context.Context) ([]Root, error), env map[string]string, serverName string, config Server, r *Runner) (*Stdio, error) {
-	result, err := r.Stream(ctx, roots, env, serverName, config)
+func newStdioClient(ctx context.Context, roots func(context.Context) ([]Root, error), env map[string]string, serverName string, config Server, r *Runner, sessionID string) (*Stdio, error) {
+	launch := func() (*streamResult, error) {
+		return r.Stream(ctx, roots, env, serverName, config)
+	}
+	result, err := launch()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create stream: %w", err)
 	}
 
 	s := NewStdio(serverName, result.cmd, result.Stdout, result.Stdin, result.Close)
+	s.sessionID = sessionID
+	if config.Process != nil {
+		s.lifecycle = newProcessLifecycle(*config.Process, launch)
+	}
+	r.track(s)
+	s.onClose = func() {
+		r.untrack(s)
+	}
 	return s, nil
 }
 
 func NewStdio(server string, cmd *exec.Cmd, in io.Reader, out io.Writer, close func()) *Stdio {
 	return &Stdio{
-		server: server,
-		cmd:    cmd,
-		stdout: in,
-		stdin:  out,
-		closer: close,
-		waiter: newWaiter(),
+		server:  server,
+		process: newStdioProcess(cmd, in, out, close),
+		waiter:  newWaiter(),
 	}
 }
@@ -0,0 +1,481 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	log2 "log"
+	"log/slog"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// The processes of stdio servers with ProcessSettings are checked every
+// processCheckInterval. A crashed process is restarted after restartDelay,
+// doubled after each crash in a row up to maxRestartDelay. A process that
+// ran for crashResetAfter before crashing starts over from restartDelay.
+var (
+	processCheckInterval = time.Second
+	processInitTimeout   = 30 * time.Second
+	restartDelay         = time.Second
+	maxRestartDelay      = 5 * time.Minute
+	crashResetAfter      = time.Minute
+)
+
+// The states of the process of a stdio server.
+const (
+	ProcessRunning = "running"
+	// ProcessStopped is a process stopped after being idle, started again
+	// when the server is used.
+	ProcessStopped = "stopped"
+	// ProcessRestarting is a process that crashed and is waiting to restart.
+	ProcessRestarting = "restarting"
+)
+
+// ProcessStatus is the state of the process of a stdio MCP server.
+type ProcessStatus struct {
+	Server     string    `json:"server"`
+	SessionID  string    `json:"sessionId,omitempty"`
+	PID        int       `json:"pid,omitempty"`
+	State      string    `json:"state"`
+	StartedAt  time.Time `json:"startedAt,omitzero"`
+	LastUsedAt time.Time `json:"lastUsedAt,omitzero"`
+	// Restarts counts the processes started after a crash or after their
+	// maximum lifetime.
+	Restarts  int    `json:"restarts,omitempty"`
+	LastError string `json:"lastError,omitempty"`
+}
+
+// stdioProcess is a process of a stdio server.
+type stdioProcess struct {
+	cmd       *exec.Cmd
+	stdin     io.Writer
+	stdout    io.Reader
+	close     func()
+	startedAt time.Time
+	// done is closed once the output of the process ended.
+	done chan struct{}
+
+	lock     sync.Mutex
+	lastUsed time.Time
+	// inflight are the IDs of the requests waiting for a response.
+	inflight map[string]any
+	// replayID is the ID of the initialize request replayed to the process,
+	// its response is sent to replayed instead of the handler.
+	replayID string
+	replayed chan Message
+}
+
+func newStdioProcess(cmd *exec.Cmd, stdout io.Reader, stdin io.Writer, close func()) *stdioProcess {
+	now := time.Now()
+	return &stdioProcess{
+		cmd:       cmd,
+		stdin:     stdin,
+		stdout:    stdout,
+		close:     close,
+		startedAt: now,
+		lastUsed:  now,
+		done:      make(chan struct{}),
+		inflight:  map[string]any{},
+	}
+}
+
+func (p *stdioProcess) pid() int {
+	if p.cmd == nil || p.cmd.Process == nil {
+		return 0
+	}
+	return p.cmd.Process.Pid
+}
+
+// sent records a message sent to the process. Pings don't count as a use,
+// so health checks don't keep an idle process running.
+func (p *stdioProcess) sent(msg Message) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if msg.Method != "ping" {
+		p.lastUsed = time.Now()
+	}
+	if msg.ID != nil && msg.Method != "" {
+		p.inflight[MessageIDString(msg.ID)] = msg.ID
+	}
+}
+
+// received records a message from the process, returning true if it is the
+// response to a replayed initialize request.
+func (p *stdioProcess) received(msg Message) bool {
+	if msg.ID == nil || msg.Method != "" {
+		return false
+	}
+	id := MessageIDString(msg.ID)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.replayID != "" && id == p.replayID {
+		// Only the first response is waited for, a duplicate is dropped
+		// rather than blocking the reader.
+		p.replayID = ""
+		select {
+		case p.replayed <- msg:
+		default:
+		}
+		return true
+	}
+	delete(p.inflight, id)
+	return false
+}
+
+// usage returns when the process was last sent a message and whether it has
+// requests in flight.
+func (p *stdioProcess) usage() (time.Time, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.lastUsed, len(p.inflight) > 0
+}
+
+// takeInflight returns the requests in flight, which won't get a response.
+func (p *stdioProcess) takeInflight() []any {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var ids []any
+	for _, id := range p.inflight {
+		ids = append(ids, id)
+	}
+	clear(p.inflight)
+	return ids
+}
+
+// processLifecycle is the lifecycle of the processes of a stdio server with
+// ProcessSettings.
+type processLifecycle struct {
+	settings ProcessSettings
+	launch   func() (*streamResult, error)
+
+	// initialize is the initialize request of the client, replayed to each
+	// new process, initialized whether it was followed by
+	// notifications/initialized.
+	initialize  *Message
+	initialized bool
+
+	// idle is set while the process is stopped after being idle.
+	idle     bool
+	lastUsed time.Time
+	restarts int
+	crashes  int
+	retryAt  time.Time
+	lastErr  error
+}
+
+func newProcessLifecycle(settings ProcessSettings, launch func() (*streamResult, error)) *processLifecycle {
+	return &processLifecycle{
+		settings: settings,
+		launch:   launch,
+	}
+}
+
+// record records the initialization of the client.
+func (l *processLifecycle) record(msg Message) {
+	switch msg.Method {
+	case "initialize":
+		l.initialize = &msg
+	case "notifications/initialized":
+		l.initialized = true
+	}
+}
+
+// answerIdlePing answers a ping to a process stopped after being idle, which
+// is healthy, without starting it again.
+func (s *Stdio) answerIdlePing(req Message) bool {
+	s.lock.Lock()
+	idle := s.lifecycle != nil && s.lifecycle.idle && s.process == nil && s.starting == nil && !s.closed
+	s.lock.Unlock()
+	if !idle || req.ID == nil {
+		return idle
+	}
+	go s.handler(s.ctx, Message{JSONRPC: "2.0", ID: req.ID, Result: json.RawMessage(`{}`)})
+	return true
+}
+
+// waitStartingLocked waits for the process being started, if any. It is
+// called with s.lock held, which it releases while waiting.
+func (s *Stdio) waitStartingLocked(ctx context.Context) error {
+	for s.starting != nil {
+		starting := s.starting
+		s.lock.Unlock()
+		select {
+		case <-starting:
+		case <-ctx.Done():
+			s.lock.Lock()
+			return ctx.Err()
+		}
+		s.lock.Lock()
+	}
+	return nil
+}
+
+// startLocked starts a new process, replaying the initialization of the
+// client to it if replay is true. It is called with s.lock held, which it
+// releases while the process starts, so the status of the server can be read
+// meanwhile. Other messages wait for the process in ensureProcess.
+func (s *Stdio) startLocked(ctx context.Context, replay bool) (*stdioProcess, error) {
+	l := s.lifecycle
+	if wait := time.Until(l.retryAt); wait > 0 {
+		return nil, fmt.Errorf("MCP server %s crashed, restarting in %s: %w", s.server, wait.Round(time.Second), l.lastErr)
+	}
+
+	var initialize *Message
+	if replay && l.initialize != nil {
+		msg := *l.initialize
+		initialize = &msg
+	}
+	initialized := l.initialized
+
+	starting := make(chan struct{})
+	s.starting = starting
+	s.lock.Unlock()
+	p, startedAt, err := s.launch(ctx, initialize, initialized)
+	s.lock.Lock()
+	s.starting = nil
+	close(starting)
+
+	if err != nil {
+		return nil, s.crashedLocked(err, startedAt)
+	}
+	if s.closed {
+		p.close()
+		return nil, fmt.Errorf("stdin is closed")
+	}
+
+	// Starting a process stopped for being idle again isn't a restart.
+	if !l.idle {
+		l.restarts++
+	}
+	l.idle = false
+	s.process = p
+
+	slog.Info("mcp stdio process started", "server", s.server, "pid", p.pid(), "restarts", l.restarts)
+	return p, nil
+}
+
+// launch starts a process and replays the initialize request to it, if any.
+// It returns when the process started, to schedule a restart if it failed.
+func (s *Stdio) launch(ctx context.Context, initialize *Message, initialized bool) (*stdioProcess, time.Time, error) {
+	result, err := s.lifecycle.launch()
+	if err != nil {
+		return nil, time.Now(), fmt.Errorf("failed to start MCP server %s: %w", s.server, err)
+	}
+
+	p := newStdioProcess(result.cmd, result.Stdout, result.Stdin, result.Close)
+	go s.read(p)
+
+	if initialize != nil {
+		if err := s.replayInitialize(ctx, p, *initialize, initialized); err != nil {
+			p.close()
+			return nil, p.startedAt, fmt.Errorf("failed to initialize MCP server %s: %w", s.server, err)
+		}
+	}
+	return p, p.startedAt, nil
+}
+
+// replayInitialize initializes a new process as the client initialized the
+// first one.
+func (s *Stdio) replayInitialize(ctx context.Context, p *stdioProcess, msg Message, initialized bool) error {
+	msg.ID = nextMessageID()
+
+	p.lock.Lock()
+	p.replayID = MessageIDString(msg.ID)
+	p.replayed = make(chan Message, 1)
+	p.lock.Unlock()
+
+	if err := s.write(p, msg); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, processInitTimeout)
+	defer cancel()
+	select {
+	case resp := <-p.replayed:
+		if resp.Error != nil {
+			return resp.Error
+		}
+	case <-p.done:
+		return errors.New("process exited")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if initialized {
+		return s.write(p, Message{JSONRPC: "2.0", Method: "notifications/initialized"})
+	}
+	return nil
+}
+
+func (s *Stdio) write(p *stdioProcess, msg Message) error {
+	msg.JSONRPC = "2.0"
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+	_, err = p.stdin.Write(append(data, '\n'))
+	return err
+}
+
+// crashedLocked records a crash of a process started at startedAt,
+// scheduling a restart if the server restarts on crash.
+func (s *Stdio) crashedLocked(err error, startedAt time.Time) error {
+	l := s.lifecycle
+	l.lastErr = err
+	if !l.settings.RestartOnCrash {
+		return err
+	}
+
+	if time.Since(startedAt) >= crashResetAfter {
+		l.crashes = 0
+	}
+	l.crashes++
+	delay := min(restartDelay<<(l.crashes-1), maxRestartDelay)
+	if l.crashes > 20 {
+		delay = maxRestartDelay
+	}
+	l.retryAt = time.Now().Add(delay)
+
+	slog.Warn("mcp stdio process crashed", "server", s.server, "restart_in", delay, "crashes", l.crashes, "error", err)
+	time.AfterFunc(delay, s.restart)
+	return err
+}
+
+// exited handles the end of the output of a process.
+func (s *Stdio) exited(p *stdioProcess, err error) {
+	if err != nil && s.lifecycle == nil {
+		log2.Fatal(err)
+	}
+
+	s.lock.Lock()
+	if s.process != p || s.closed {
+		// The process was stopped or replaced.
+		s.lock.Unlock()
+		return
+	}
+	s.process = nil
+
+	// The requests in flight won't get a response from this process.
+	for _, id := range p.takeInflight() {
+		go s.handler(s.ctx, Message{
+			JSONRPC: "2.0",
+			ID:      id,
+			Error: &RPCError{
+				Code:    -32603,
+				Message: fmt.Sprintf("MCP server %s exited", s.server),
+			},
+		})
+	}
+
+	if s.lifecycle == nil || !s.lifecycle.settings.RestartOnCrash {
+		s.lock.Unlock()
+		s.Close(false)
+		return
+	}
+
+	if err == nil {
+		err = errors.New("process exited")
+	}
+	if p.cmd != nil && p.cmd.ProcessState != nil {
+		err = fmt.Errorf("process exited: %s", p.cmd.ProcessState)
+	}
+	_ = s.crashedLocked(err, p.startedAt)
+	s.lock.Unlock()
+}
+
+// restart starts a process if there is none.
+func (s *Stdio) restart() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed || s.process != nil || s.starting != nil || time.Now().Before(s.lifecycle.retryAt) {
+		return
+	}
+	_, _ = s.startLocked(s.ctx, true)
+}
+
+// supervise stops the process once it is idle and restarts it once it ran
+// for its maximum lifetime, until the wire is closed.
+func (s *Stdio) supervise() {
+	settings := s.lifecycle.settings
+	if settings.IdleTimeoutMS <= 0 && settings.MaxLifetimeMS <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(processCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.waiter.running:
+			return
+		case <-ticker.C:
+		}
+
+		s.lock.Lock()
+		p := s.process
+		if p == nil || s.closed {
+			s.lock.Unlock()
+			continue
+		}
+
+		var reason string
+		lastUsed, busy := p.usage()
+		switch {
+		case busy:
+		case settings.IdleTimeoutMS > 0 && time.Since(lastUsed) >= time.Duration(settings.IdleTimeoutMS)*time.Millisecond:
+			reason = "idle"
+		case settings.MaxLifetimeMS > 0 && time.Since(p.startedAt) >= time.Duration(settings.MaxLifetimeMS)*time.Millisecond:
+			reason = "max lifetime"
+		}
+		if reason == "" {
+			s.lock.Unlock()
+			continue
+		}
+
+		s.process = nil
+		s.lifecycle.lastUsed = lastUsed
+		s.lifecycle.idle = reason == "idle"
+		s.lock.Unlock()
+
+		slog.Info("mcp stdio process stopping", "server", s.server, "pid", p.pid(), "reason", reason)
+		p.close()
+		if reason == "max lifetime" {
+			s.restart()
+		}
+	}
+}
+
+// status returns the state of the process of the server.
+func (s *Stdio) status() ProcessStatus {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	status := ProcessStatus{
+		Server:    s.server,
+		SessionID: s.sessionID,
+		State:     ProcessStopped,
+	}
+	if l := s.lifecycle; l != nil {
+		status.Restarts = l.restarts
+		status.LastUsedAt = l.lastUsed
+		if l.lastErr != nil {
+			status.LastError = l.lastErr.Error()
+		}
+		if time.Now().Before(l.retryAt) {
+			status.State = ProcessRestarting
+		}
+	}
+	if p := s.process; p != nil {
+		status.State = ProcessRunning
+		status.PID = p.pid()
+		status.StartedAt = p.startedAt
+		status.LastUsedAt, _ = p.usage()
+	}
+	return status
+}
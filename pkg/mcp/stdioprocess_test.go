@@ -0,0 +1,212 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeStdioServers launches in-memory stdio servers that answer every
+// request except "hang", recording the methods each one received.
+type fakeStdioServers struct {
+	lock    sync.Mutex
+	methods [][]string
+	crash   []func()
+}
+
+func (f *fakeStdioServers) launch() (*streamResult, error) {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+
+	f.lock.Lock()
+	n := len(f.methods)
+	f.methods = append(f.methods, nil)
+	f.crash = append(f.crash, func() { _ = outW.Close() })
+	f.lock.Unlock()
+
+	go func() {
+		scanner := bufio.NewScanner(inR)
+		for scanner.Scan() {
+			var msg Message
+			if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+				continue
+			}
+			f.lock.Lock()
+			f.methods[n] = append(f.methods[n], msg.Method)
+			f.lock.Unlock()
+			if msg.ID == nil || msg.Method == "hang" {
+				continue
+			}
+			data, _ := json.Marshal(Message{JSONRPC: "2.0", ID: msg.ID, Result: json.RawMessage(`{}`)})
+			if _, err := outW.Write(append(data, '\n')); err != nil {
+				return
+			}
+		}
+	}()
+
+	return &streamResult{
+		Stdout: outR,
+		Stdin:  inW,
+		Close: func() {
+			_ = inW.Close()
+			_ = outW.Close()
+		},
+	}, nil
+}
+
+func (f *fakeStdioServers) received(n int) []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if n >= len(f.methods) {
+		return nil
+	}
+	return f.methods[n]
+}
+
+func startFakeStdio(t *testing.T, settings ProcessSettings) (*Stdio, *fakeStdioServers, chan Message) {
+	t.Helper()
+	servers := &fakeStdioServers{}
+	result, _ := servers.launch()
+	s := NewStdio("fake", nil, result.Stdout, result.Stdin, result.Close)
+	s.lifecycle = newProcessLifecycle(settings, servers.launch)
+
+	received := make(chan Message, 10)
+	if err := s.Start(t.Context(), func(_ context.Context, msg Message) {
+		received <- msg
+	}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close(false) })
+
+	if err := s.Send(t.Context(), Message{JSONRPC: "2.0", ID: 1, Method: "initialize"}); err != nil {
+		t.Fatal(err)
+	}
+	expectResponse(t, received, "1")
+	if err := s.Send(t.Context(), Message{JSONRPC: "2.0", Method: "notifications/initialized"}); err != nil {
+		t.Fatal(err)
+	}
+	return s, servers, received
+}
+
+func expectResponse(t *testing.T, received chan Message, id string) Message {
+	t.Helper()
+	select {
+	case msg := <-received:
+		if MessageIDString(msg.ID) != id {
+			t.Fatalf("expected the response to %s, got %+v", id, msg)
+		}
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for the response to %s", id)
+	}
+	return Message{}
+}
+
+func waitForState(t *testing.T, s *Stdio, state string) {
+	t.Helper()
+	for range 500 {
+		if s.status().State == state {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("expected the process to be %s, got %+v", state, s.status())
+}
+
+func TestStdioRestartOnCrash(t *testing.T) {
+	defer func(delay time.Duration) { restartDelay = delay }(restartDelay)
+	restartDelay = 10 * time.Millisecond
+
+	s, servers, received := startFakeStdio(t, ProcessSettings{RestartOnCrash: true})
+
+	if err := s.Send(t.Context(), Message{JSONRPC: "2.0", ID: 2, Method: "hang"}); err != nil {
+		t.Fatal(err)
+	}
+	servers.crash[0]()
+
+	// The request in flight fails instead of waiting forever.
+	if msg := expectResponse(t, received, "2"); msg.Error == nil {
+		t.Errorf("expected an error for the request in flight, got %+v", msg)
+	}
+
+	waitForState(t, s, ProcessRunning)
+	if err := s.Send(t.Context(), Message{JSONRPC: "2.0", ID: 3, Method: "tools/list"}); err != nil {
+		t.Fatal(err)
+	}
+	expectResponse(t, received, "3")
+
+	got := servers.received(1)
+	if len(got) != 3 || got[0] != "initialize" || got[1] != "notifications/initialized" || got[2] != "tools/list" {
+		t.Errorf("expected the restarted process to be initialized again, got %v", got)
+	}
+	if status := s.status(); status.Restarts != 1 || status.LastError == "" {
+		t.Errorf("expected a restart after an error, got %+v", status)
+	}
+}
+
+func TestStdioIdleTimeout(t *testing.T) {
+	defer func(interval time.Duration) { processCheckInterval = interval }(processCheckInterval)
+	processCheckInterval = 5 * time.Millisecond
+
+	s, servers, received := startFakeStdio(t, ProcessSettings{IdleTimeoutMS: 20})
+	waitForState(t, s, ProcessStopped)
+
+	if err := s.Send(t.Context(), Message{JSONRPC: "2.0", ID: 2, Method: "tools/list"}); err != nil {
+		t.Fatal(err)
+	}
+	expectResponse(t, received, "2")
+	if got := servers.received(1); len(got) != 3 || got[0] != "initialize" {
+		t.Errorf("expected the idle process to be started again, got %v", got)
+	}
+	if restarts := s.status().Restarts; restarts != 0 {
+		t.Errorf("expected starting an idle process again not to count as a restart, got %d", restarts)
+	}
+}
+
+func TestStdioProcessDuplicateReplayResponse(t *testing.T) {
+	p := newStdioProcess(nil, nil, nil, func() {})
+	p.replayID = "7"
+	p.replayed = make(chan Message, 1)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 2 {
+			p.received(Message{JSONRPC: "2.0", ID: 7, Result: json.RawMessage(`{}`)})
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a duplicate response to the replayed initialize blocked the reader")
+	}
+	if msg := <-p.replayed; MessageIDString(msg.ID) != "7" {
+		t.Errorf("expected the response to the replayed initialize, got %+v", msg)
+	}
+}
+
+func TestStdioPingIdleProcess(t *testing.T) {
+	defer func(interval time.Duration) { processCheckInterval = interval }(processCheckInterval)
+	processCheckInterval = 5 * time.Millisecond
+
+	s, servers, received := startFakeStdio(t, ProcessSettings{IdleTimeoutMS: 20})
+	waitForState(t, s, ProcessStopped)
+
+	// A health check doesn't start the idle process again.
+	if err := s.Send(t.Context(), Message{JSONRPC: "2.0", ID: 2, Method: "ping"}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := expectResponse(t, received, "2"); msg.Error != nil {
+		t.Errorf("expected the ping to succeed, got %+v", msg)
+	}
+	if got := servers.received(1); got != nil {
+		t.Errorf("expected the idle process to stay stopped, got %v", got)
+	}
+	if state := s.status().State; state != ProcessStopped {
+		t.Errorf("expected the process to stay stopped, got %s", state)
+	}
+}
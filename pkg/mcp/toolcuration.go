@@ -0,0 +1,164 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ToolCuration changes how a tool of a server is described to the model,
+// without changing the tool on the server.
+type ToolCuration struct {
+	// Description replaces the description of the tool.
+	Description string `json:"description,omitempty"`
+	// AppendDescription is added to the end of the description of the tool,
+	// as in usage guidance for the tool.
+	AppendDescription string `json:"appendDescription,omitempty"`
+	// Parameters change the properties of the input schema of the tool, by
+	// their name.
+	Parameters map[string]ParameterCuration `json:"parameters,omitempty"`
+}
+
+// ParameterCuration changes how a parameter of a tool is described to the
+// model.
+type ParameterCuration struct {
+	// Description replaces the description of the parameter.
+	Description string `json:"description,omitempty"`
+	// AppendDescription is added to the end of the description of the
+	// parameter.
+	AppendDescription string `json:"appendDescription,omitempty"`
+	// Fixed is the value of the parameter on every call to the tool. The
+	// parameter is removed from the input schema the model sees.
+	Fixed any `json:"fixed,omitempty"`
+}
+
+// Merge returns c with other applied on top. Descriptions of other replace
+// the ones of c, and appended descriptions are both appended.
+func (c ToolCuration) Merge(other ToolCuration) ToolCuration {
+	c.Description = mergeDescription(c.Description, other.Description)
+	c.AppendDescription = appendDescription(c.AppendDescription, other.AppendDescription)
+	if len(other.Parameters) > 0 {
+		parameters := maps.Clone(c.Parameters)
+		if parameters == nil {
+			parameters = map[string]ParameterCuration{}
+		}
+		for name, param := range other.Parameters {
+			existing := parameters[name]
+			existing.Description = mergeDescription(existing.Description, param.Description)
+			existing.AppendDescription = appendDescription(existing.AppendDescription, param.AppendDescription)
+			if param.Fixed != nil {
+				existing.Fixed = param.Fixed
+			}
+			parameters[name] = existing
+		}
+		c.Parameters = parameters
+	}
+	return c
+}
+
+// IsZero indicates if the curation leaves the tool as it is.
+func (c ToolCuration) IsZero() bool {
+	return c.Description == "" && c.AppendDescription == "" && len(c.Parameters) == 0
+}
+
+// FixedArguments returns the values of the fixed parameters.
+func (c ToolCuration) FixedArguments() map[string]any {
+	var args map[string]any
+	for name, param := range c.Parameters {
+		if param.Fixed == nil {
+			continue
+		}
+		if args == nil {
+			args = map[string]any{}
+		}
+		args[name] = param.Fixed
+	}
+	return args
+}
+
+// Apply returns the tool as the model sees it.
+func (c ToolCuration) Apply(tool Tool) (Tool, error) {
+	if c.IsZero() {
+		return tool, nil
+	}
+
+	if c.Description != "" {
+		tool.Description = c.Description
+	}
+	tool.Description = appendDescription(tool.Description, c.AppendDescription)
+
+	if len(c.Parameters) == 0 {
+		return tool, nil
+	}
+
+	var schema map[string]any
+	if len(tool.InputSchema) > 0 {
+		if err := json.Unmarshal(tool.InputSchema, &schema); err != nil {
+			return tool, fmt.Errorf("invalid input schema of tool %s: %w", tool.Name, err)
+		}
+	}
+	if schema == nil {
+		schema = map[string]any{"type": "object"}
+	}
+	properties, _ := schema["properties"].(map[string]any)
+
+	for _, name := range slices.Sorted(maps.Keys(c.Parameters)) {
+		param := c.Parameters[name]
+		if param.Fixed != nil {
+			delete(properties, name)
+			if required, ok := schema["required"].([]any); ok {
+				schema["required"] = slices.DeleteFunc(required, func(v any) bool {
+					return v == name
+				})
+			}
+			continue
+		}
+
+		property, ok := properties[name].(map[string]any)
+		if !ok {
+			continue
+		}
+		description, _ := property["description"].(string)
+		if param.Description != "" {
+			description = param.Description
+		}
+		description = appendDescription(description, param.AppendDescription)
+		if description != "" {
+			property["description"] = description
+		}
+	}
+
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return tool, fmt.Errorf("failed to marshal input schema of tool %s: %w", tool.Name, err)
+	}
+	tool.InputSchema = data
+	return tool, nil
+}
+
+// CurationForTool returns the curation of the named tool, layered over the
+// curation of all tools of the server.
+func (s Server) CurationForTool(tool string) ToolCuration {
+	return s.ToolCurations[AllToolsSettingsKey].Merge(s.ToolCurations[tool])
+}
+
+func mergeDescription(base, override string) string {
+	if override != "" {
+		return override
+	}
+	return base
+}
+
+func appendDescription(description, extra string) string {
+	extra = strings.TrimSpace(extra)
+	switch {
+	case extra == "":
+		return description
+	case strings.TrimSpace(description) == "":
+		return extra
+	default:
+		return strings.TrimRight(description, " \n") + "\n\n" + extra
+	}
+}
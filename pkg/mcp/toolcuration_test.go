@@ -0,0 +1,63 @@
+package mcp
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestToolCuration(t *testing.T) {
+	server := Server{
+		ToolCurations: map[string]ToolCuration{
+			AllToolsSettingsKey: {
+				AppendDescription: "Only use for the acme org.",
+				Parameters: map[string]ParameterCuration{
+					"org": {Fixed: "acme"},
+				},
+			},
+			"search": {
+				Description: "Search issues.",
+				Parameters: map[string]ParameterCuration{
+					"query": {AppendDescription: "Use GitHub search syntax."},
+				},
+			},
+		},
+	}
+
+	curation := server.CurationForTool("search")
+	tool, err := curation.Apply(Tool{
+		Name:        "search",
+		Description: "Search.",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{"org":{"type":"string"},"query":{"type":"string","description":"The query."}},"required":["org","query"]}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tool.Description != "Search issues.\n\nOnly use for the acme org." {
+		t.Errorf("unexpected description %q", tool.Description)
+	}
+
+	var schema map[string]any
+	if err := json.Unmarshal(tool.InputSchema, &schema); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"query": map[string]any{"type": "string", "description": "The query.\n\nUse GitHub search syntax."},
+		},
+		"required": []any{"query"},
+	}
+	if !reflect.DeepEqual(schema, want) {
+		t.Errorf("unexpected input schema %s", tool.InputSchema)
+	}
+
+	if args := curation.FixedArguments(); !reflect.DeepEqual(args, map[string]any{"org": "acme"}) {
+		t.Errorf("unexpected fixed arguments %v", args)
+	}
+
+	if curation := server.CurationForTool("other"); curation.Description != "" || len(curation.Parameters) != 1 {
+		t.Errorf("unexpected curation of another tool %+v", curation)
+	}
+}
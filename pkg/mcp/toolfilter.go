@@ -0,0 +1,73 @@
+package mcp
+
+import (
+	"fmt"
+	"path"
+	"slices"
+)
+
+// ToolFilter selects and renames the tools of a server. Include, Exclude,
+// and the keys of Rename are the names the server gives its tools.
+type ToolFilter struct {
+	// Include lists the only tools of the server that are used. Names can
+	// use the wildcards of path.Match, as in "read_*". Empty includes all
+	// tools.
+	Include []string `json:"include,omitempty"`
+	// Exclude lists tools of the server that are not used, even when they
+	// are included.
+	Exclude []string `json:"exclude,omitempty"`
+	// Rename gives tools of the server other names, by their name.
+	Rename map[string]string `json:"rename,omitempty"`
+}
+
+func (f ToolFilter) IsZero() bool {
+	return len(f.Include) == 0 && len(f.Exclude) == 0 && len(f.Rename) == 0
+}
+
+// Allowed indicates if the tool of the server is used.
+func (f ToolFilter) Allowed(tool string) bool {
+	if len(f.Include) > 0 && !slices.ContainsFunc(f.Include, matchTool(tool)) {
+		return false
+	}
+	return !slices.ContainsFunc(f.Exclude, matchTool(tool))
+}
+
+// Validate checks the patterns of the filter, and that no two tools are
+// renamed to the same name.
+func (f ToolFilter) Validate() error {
+	for _, pattern := range slices.Concat(f.Include, f.Exclude) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid tool pattern %q: %w", pattern, err)
+		}
+	}
+
+	renamed := map[string]string{}
+	for tool, name := range f.Rename {
+		if name == "" {
+			return fmt.Errorf("tool %q must not be renamed to an empty name", tool)
+		}
+		if other, ok := renamed[name]; ok {
+			return fmt.Errorf("tools %q and %q are both renamed to %q", min(tool, other), max(tool, other), name)
+		}
+		renamed[name] = tool
+	}
+	return nil
+}
+
+// originalName returns the name the server gives the tool that is used as
+// name.
+func (f ToolFilter) originalName(name string) string {
+	for tool, renamed := range f.Rename {
+		if renamed == name {
+			return tool
+		}
+	}
+	return name
+}
+
+func matchTool(tool string) func(string) bool {
+	return func(pattern string) bool {
+		ok, _ := path.Match(pattern, tool)
+		return ok
+	}
+}
@@ -0,0 +1,79 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestToolFilter(t *testing.T) {
+	var called []string
+	handler := MessageHandlerFunc(func(ctx context.Context, msg Message) {
+		switch msg.Method {
+		case "initialize":
+			_ = msg.Reply(ctx, InitializeResult{
+				ProtocolVersion: "2025-06-18",
+				Capabilities:    ServerCapabilities{Tools: &ToolsServerCapability{}},
+			})
+		case "tools/list":
+			var result ListToolsResult
+			for _, name := range []string{"read_file", "read_dir", "write_file", "delete_file"} {
+				result.Tools = append(result.Tools, Tool{Name: name})
+			}
+			_ = msg.Reply(ctx, result)
+		case "tools/call":
+			var call CallToolRequest
+			_ = json.Unmarshal(msg.Params, &call)
+			called = append(called, call.Name)
+			_ = msg.Reply(ctx, CallToolResult{})
+		}
+	})
+
+	wire, err := NewExistingServerSession(t.Context(), SessionState{}, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewClient(t.Context(), "files", Server{
+		Tools: ToolFilter{
+			Include: []string{"read_*", "write_file"},
+			Exclude: []string{"read_dir"},
+			Rename:  map[string]string{"read_file": "read"},
+		},
+		ToolPrefix: "files_",
+	}, ClientOption{Wire: wire})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close(false) })
+
+	tools, err := client.ListTools(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, tool := range tools.Tools {
+		names = append(names, tool.Name)
+	}
+	if !slices.Equal(names, []string{"files_read", "files_write_file"}) {
+		t.Errorf("unexpected tools %v", names)
+	}
+
+	if _, err := client.Call(t.Context(), "files_read", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Call(t.Context(), "files_delete_file", nil); err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Errorf("expected the excluded tool to be rejected, got %v", err)
+	}
+	if !slices.Equal(called, []string{"read_file"}) {
+		t.Errorf("unexpected calls %v", called)
+	}
+
+	if err := (ToolFilter{Rename: map[string]string{"a": "x", "b": "x"}}).Validate(); err == nil {
+		t.Error("expected two tools renamed to the same name to be rejected")
+	}
+	if err := (ToolFilter{Include: []string{"[a"}}).Validate(); err == nil {
+		t.Error("expected an invalid pattern to be rejected")
+	}
+}
@@ -32,6 +32,7 @@ type ClientInfo struct {
 
 type ServerCapabilities struct {
 	Experimental map[string]any             `json:"experimental,omitempty"`
+	Completions  *struct{}                  `json:"completions,omitempty"`
 	Logging      *struct{}                  `json:"logging,omitempty"`
 	Prompts      *PromptsServerCapability   `json:"prompts,omitempty"`
 	Resources    *ResourcesServerCapability `json:"resources,omitempty"`
@@ -97,7 +98,8 @@ type PrimitiveSchema struct {
 }
 
 type PrimitiveProperty struct {
-	// Type must be one of "string", "number", "boolean", "enum", "integer"
+	// Type must be one of "string", "number", "boolean", "enum", "integer",
+	// or "array" for a multi-select of the enum of its items
 	Type        string       `json:"type"`
 	Title       string       `json:"title,omitempty"`
 	Description string       `json:"description,omitempty"`
@@ -109,7 +111,19 @@ type PrimitiveProperty struct {
 	Enum        []string     `json:"enum,omitempty"`
 	EnumNames   []string     `json:"enumNames,omitempty"`
 	// Format must be one of "date-time", "email", "uri", "date"
-	Format string `json:"format,omitempty"`
+	Format  string `json:"format,omitempty"`
+	Pattern string `json:"pattern,omitempty"`
+	// Items, MinItems, and MaxItems describe the choices of an "array"
+	Items    *PrimitiveItems `json:"items,omitempty"`
+	MinItems *int            `json:"minItems,omitempty"`
+	MaxItems *int            `json:"maxItems,omitempty"`
+}
+
+// PrimitiveItems are the choices of a multi-select property.
+type PrimitiveItems struct {
+	Type      string   `json:"type"`
+	Enum      []string `json:"enum,omitempty"`
+	EnumNames []string `json:"enumNames,omitempty"`
 }
 
 type ModelPreferences struct {
@@ -350,11 +364,14 @@ type CallToolRequest struct {
 
 var EmptyObjectSchema = json.RawMessage(`{"type": "object", "properties": {}, "additionalProperties": false, "required": []}`)
 
-type ListToolsRequest struct{}
+type ListToolsRequest struct {
+	Cursor string `json:"cursor,omitempty"`
+}
 
 type ListToolsResult struct {
-	Meta  map[string]any `json:"_meta,omitzero"`
-	Tools []Tool         `json:"tools"`
+	Meta       map[string]any `json:"_meta,omitzero"`
+	Tools      []Tool         `json:"tools"`
+	NextCursor string         `json:"nextCursor,omitempty"`
 }
 
 type GetPromptRequest struct {
@@ -409,11 +426,14 @@ func (r ResourceContent) ToDataURI() string {
 	return "data:" + r.MIMEType + ";base64,"
 }
 
-type ListResourceTemplatesRequest struct{}
+type ListResourceTemplatesRequest struct {
+	Cursor string `json:"cursor,omitempty"`
+}
 
 type ListResourceTemplatesResult struct {
 	Meta              map[string]any     `json:"_meta,omitzero"`
 	ResourceTemplates []ResourceTemplate `json:"resourceTemplates"`
+	NextCursor        string             `json:"nextCursor,omitempty"`
 }
 
 type SubscribeRequest struct {
@@ -428,6 +448,11 @@ type UnsubscribeRequest struct {
 
 type UnsubscribeResult struct{}
 
+type ResourceUpdatedNotification struct {
+	Meta map[string]any `json:"_meta,omitzero"`
+	URI  string         `json:"uri"`
+}
+
 type ResourceTemplate struct {
 	URITemplate string       `json:"uriTemplate"`
 	Name        string       `json:"name"`
@@ -436,11 +461,56 @@ type ResourceTemplate struct {
 	Annotations *Annotations `json:"annotations,omitempty"`
 }
 
-type ListResourcesRequest struct{}
+// CompleteRequest asks the server for the values an argument of a prompt or
+// of a resource template can take, starting with the value typed so far.
+type CompleteRequest struct {
+	Ref      CompleteReference `json:"ref"`
+	Argument CompleteArgument  `json:"argument"`
+	Context  *CompleteContext  `json:"context,omitempty"`
+}
+
+const (
+	CompleteRefPrompt   = "ref/prompt"
+	CompleteRefResource = "ref/resource"
+)
+
+// CompleteReference is the prompt, by name, or the resource template, by URI
+// template, that a completion is for.
+type CompleteReference struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+	URI  string `json:"uri,omitempty"`
+}
+
+type CompleteArgument struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// CompleteContext holds the arguments that are already resolved.
+type CompleteContext struct {
+	Arguments map[string]string `json:"arguments,omitempty"`
+}
+
+type CompleteResult struct {
+	Completion Completion `json:"completion"`
+}
+
+type Completion struct {
+	Values  []string `json:"values"`
+	Total   int      `json:"total,omitempty"`
+	HasMore bool     `json:"hasMore,omitempty"`
+}
+
+type ListResourcesRequest struct {
+	Meta   map[string]any `json:"_meta,omitzero"`
+	Cursor string         `json:"cursor,omitempty"`
+}
 
 type ListResourcesResult struct {
-	Meta      map[string]any `json:"_meta,omitzero"`
-	Resources []Resource     `json:"resources"`
+	Meta       map[string]any `json:"_meta,omitzero"`
+	Resources  []Resource     `json:"resources"`
+	NextCursor string         `json:"nextCursor,omitempty"`
 }
 
 type Resource struct {
@@ -461,11 +531,14 @@ type Annotations struct {
 	LastModified time.Time   `json:"lastModified,omitzero"`
 }
 
-type ListPromptsRequest struct{}
+type ListPromptsRequest struct {
+	Cursor string `json:"cursor,omitempty"`
+}
 
 type ListPromptsResult struct {
-	Meta    map[string]any `json:"_meta,omitzero"`
-	Prompts []Prompt       `json:"prompts"`
+	Meta       map[string]any `json:"_meta,omitzero"`
+	Prompts    []Prompt       `json:"prompts"`
+	NextCursor string         `json:"nextCursor,omitempty"`
 }
 
 type Prompt struct {
@@ -503,6 +576,18 @@ type SessionMessageHook struct {
 	Reason  string   `json:"reason"`
 }
 
+// ToolResultHook is sent after a call to a tool of an MCP server, before the
+// result reaches the model. The hook can change Result, as in redacting
+// secrets from command output. The hook can be limited to a server or a tool
+// with the "server" and "tool" parameters, as in "toolResult?server=shell".
+// Hook Name = "toolResult"
+type ToolResultHook struct {
+	Server    string          `json:"server"`
+	Tool      string          `json:"tool"`
+	Arguments any             `json:"arguments,omitempty"`
+	Result    *CallToolResult `json:"result"`
+}
+
 type CancelledNotification struct {
 	RequestID any    `json:"requestId"`
 	Reason    string `json:"reason,omitempty"`
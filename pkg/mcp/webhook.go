@@ -0,0 +1,175 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/envvar"
+	"github.com/obot-platform/nanobot/pkg/uuid"
+)
+
+// WebhookSecretEnv is the session environment variable with the key webhook
+// payloads are signed with. Payloads are sent unsigned when it isn't set.
+const WebhookSecretEnv = "NANOBOT_WEBHOOK_SECRET"
+
+const (
+	WebhookEventHeader     = "X-Nanobot-Event"
+	WebhookDeliveryHeader  = "X-Nanobot-Delivery"
+	WebhookTimestampHeader = "X-Nanobot-Timestamp"
+	WebhookSignatureHeader = "X-Nanobot-Signature"
+)
+
+var (
+	webhookClient     = &http.Client{Timeout: 30 * time.Second}
+	webhookAttempts   = 4
+	webhookRetryDelay = time.Second
+)
+
+// WebhookPayload is the body POSTed to a webhook hook target.
+type WebhookPayload struct {
+	Event     string            `json:"event"`
+	Params    map[string]string `json:"params,omitempty"`
+	Timestamp int64             `json:"timestamp"`
+	Data      any               `json:"data"`
+}
+
+// IsWebhook indicates if a hook target is a URL that the hook is POSTed to
+// instead of a tool.
+func IsWebhook(target string) bool {
+	return strings.HasPrefix(target, "https://") || strings.HasPrefix(target, "http://")
+}
+
+// SignWebhook returns the signature of a webhook body sent at the timestamp,
+// the hex encoded HMAC-SHA256 of "<timestamp>.<body>". Receivers should
+// recompute it and reject old timestamps to guard against replays.
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// SendWebhook POSTs data to the URL as the event, signed with the
+// WebhookSecretEnv of env and retried like the webhooks of hooks. The
+// response is ignored. The URL is used as is, env variables in it aren't
+// expanded since it may not come from the config.
+func SendWebhook(ctx context.Context, env map[string]string, target, event string, data any) error {
+	_, err := deliverWebhook(ctx, env, target, event, nil, data, nil)
+	return err
+}
+
+// WebhookMaxAge is how old the timestamp of a webhook VerifyWebhook accepts
+// can be.
+const WebhookMaxAge = 5 * time.Minute
+
+// VerifyWebhook checks the signature of a webhook body received with the
+// timestamp header, and that the timestamp is at most WebhookMaxAge away from
+// now.
+func VerifyWebhook(secret, timestamp, signature string, body []byte, now time.Time) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s header", WebhookTimestampHeader)
+	}
+	if age := now.Sub(time.Unix(ts, 0)); age > WebhookMaxAge || age < -WebhookMaxAge {
+		return fmt.Errorf("webhook timestamp is too old")
+	}
+	if !hmac.Equal([]byte(signature), []byte(SignWebhook(secret, ts, body))) {
+		return fmt.Errorf("invalid webhook signature")
+	}
+	return nil
+}
+
+// sendWebhook POSTs the hook input to the URL, retrying network errors, 429s
+// and 5xx responses with a backoff. A JSON object in the response is the hook
+// output, like the structured content of a tool.
+func sendWebhook(ctx context.Context, target, event string, params map[string]string, in, out any) (bool, error) {
+	env := SessionFromContext(ctx).GetEnvMap()
+	// The targets of hooks come from the config, so they can use the env.
+	return deliverWebhook(ctx, env, envvar.ReplaceString(env, target), event, params, in, out)
+}
+
+func deliverWebhook(ctx context.Context, env map[string]string, target, event string, params map[string]string, in, out any) (bool, error) {
+	timestamp := time.Now().Unix()
+	body, err := json.Marshal(WebhookPayload{
+		Event:     event,
+		Params:    params,
+		Timestamp: timestamp,
+		Data:      in,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	var (
+		deliveryID = uuid.String()
+		delay      = webhookRetryDelay
+		respBody   []byte
+	)
+	for attempt := 1; ; attempt++ {
+		var retry bool
+		respBody, retry, err = postWebhook(ctx, target, event, deliveryID, env[WebhookSecretEnv], timestamp, body)
+		if err == nil || !retry || attempt >= webhookAttempts {
+			break
+		}
+
+		slog.Debug("retrying webhook", "event", event, "delivery", deliveryID, "attempt", attempt, "error", err)
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if out == nil || len(bytes.TrimSpace(respBody)) == 0 || bytes.TrimSpace(respBody)[0] != '{' {
+		return false, nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return false, fmt.Errorf("failed to unmarshal webhook response: %w", err)
+	}
+	return true, nil
+}
+
+func postWebhook(ctx context.Context, target, event, deliveryID, secret string, timestamp int64, body []byte) (_ []byte, retry bool, _ error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, event)
+	req.Header.Set(WebhookDeliveryHeader, deliveryID)
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	if secret != "" {
+		req.Header.Set(WebhookSignatureHeader, SignWebhook(secret, timestamp, body))
+	}
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return nil, ctx.Err() == nil, fmt.Errorf("failed to send webhook %s: %w", event, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to read webhook response: %w", err)
+	}
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+		return nil, retry, fmt.Errorf("webhook %s failed with status %d: %s", event, resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return respBody, false, nil
+}
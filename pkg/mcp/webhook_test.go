@@ -0,0 +1,110 @@
+package mcp
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestInvokeHooksWebhook(t *testing.T) {
+	webhookRetryDelay = time.Millisecond
+	t.Cleanup(func() { webhookRetryDelay = time.Second })
+
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		if r.URL.Path != "/hooks" {
+			t.Errorf("path = %q, want the env var in the URL to be replaced", r.URL.Path)
+		}
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(WebhookTimestampHeader), 10, 64)
+		if got, want := r.Header.Get(WebhookSignatureHeader), SignWebhook("s3cret", timestamp, body); got != want {
+			t.Errorf("signature = %q, want %q", got, want)
+		}
+		if event := r.Header.Get(WebhookEventHeader); event != "toolCall" {
+			t.Errorf("event header = %q, want toolCall", event)
+		}
+
+		var payload WebhookPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Error(err)
+		}
+		if payload.Event != "toolCall" || payload.Params["tool"] != "deploy" || payload.Data.(map[string]any)["name"] != "deploy" {
+			t.Errorf("unexpected payload %s", body)
+		}
+		_, _ = w.Write([]byte(`{"name":"deployed"}`))
+	}))
+	defer server.Close()
+
+	session := NewEmptySession(t.Context())
+	session.SetEnv(map[string]string{
+		WebhookSecretEnv: "s3cret",
+		"HOOK_PATH":      "hooks",
+	})
+	ctx := WithSession(t.Context(), session)
+
+	type hookData struct {
+		Name string `json:"name"`
+	}
+	hooks := Hooks{
+		{Name: "toolCall", Params: map[string]string{"tool": "deploy"}, Targets: []HookTarget{{Target: server.URL + "/${HOOK_PATH}"}}},
+	}
+
+	out, err := InvokeHooks(ctx, nil, hooks, &hookData{Name: "deploy"}, "toolCall", map[string]string{"tool": "deploy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Name != "deployed" {
+		t.Errorf("expected the webhook response as the hook output, got %+v", out)
+	}
+	if n := attempts.Load(); n != 2 {
+		t.Errorf("expected the failed delivery to be retried once, got %d attempts", n)
+	}
+
+	// Client errors aren't retried.
+	attempts.Store(0)
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	if _, err := InvokeHooks(ctx, nil, hooks, &hookData{Name: "deploy"}, "toolCall", map[string]string{"tool": "deploy"}); err == nil {
+		t.Fatal("expected the rejected webhook to fail the hook")
+	}
+	if n := attempts.Load(); n != 1 {
+		t.Errorf("expected a rejected delivery not to be retried, got %d attempts", n)
+	}
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	var (
+		now       = time.Unix(1700000000, 0)
+		body      = []byte(`{"event":"push"}`)
+		signature = SignWebhook("s3cret", now.Unix(), body)
+		timestamp = strconv.FormatInt(now.Unix(), 10)
+	)
+
+	if err := VerifyWebhook("s3cret", timestamp, signature, body, now.Add(time.Minute)); err != nil {
+		t.Errorf("expected the signature to verify, got %v", err)
+	}
+	if err := VerifyWebhook("other", timestamp, signature, body, now); err == nil {
+		t.Error("expected a signature with another secret to fail")
+	}
+	if err := VerifyWebhook("s3cret", timestamp, signature, []byte(`{}`), now); err == nil {
+		t.Error("expected a signature of another body to fail")
+	}
+	if err := VerifyWebhook("s3cret", timestamp, signature, body, now.Add(WebhookMaxAge+time.Second)); err == nil {
+		t.Error("expected an old timestamp to fail")
+	}
+	if err := VerifyWebhook("s3cret", "", signature, body, now); err == nil {
+		t.Error("expected a missing timestamp to fail")
+	}
+}
@@ -5,9 +5,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"strings"
 
 	"github.com/obot-platform/nanobot/pkg/agents"
+	"github.com/obot-platform/nanobot/pkg/channels"
 	"github.com/obot-platform/nanobot/pkg/complete"
 	"github.com/obot-platform/nanobot/pkg/llm"
 	"github.com/obot-platform/nanobot/pkg/mcp"
@@ -15,9 +17,12 @@ import (
 	"github.com/obot-platform/nanobot/pkg/sampling"
 	"github.com/obot-platform/nanobot/pkg/servers/agent"
 	"github.com/obot-platform/nanobot/pkg/servers/artifacts"
+	"github.com/obot-platform/nanobot/pkg/servers/knowledge"
+	"github.com/obot-platform/nanobot/pkg/servers/memory"
 	"github.com/obot-platform/nanobot/pkg/servers/meta"
 	"github.com/obot-platform/nanobot/pkg/servers/obotmcp"
 	"github.com/obot-platform/nanobot/pkg/servers/skills"
+	"github.com/obot-platform/nanobot/pkg/servers/subagent"
 	"github.com/obot-platform/nanobot/pkg/servers/system"
 	"github.com/obot-platform/nanobot/pkg/servers/tasks"
 	"github.com/obot-platform/nanobot/pkg/servers/workflows"
@@ -25,6 +30,7 @@ import (
 	"github.com/obot-platform/nanobot/pkg/sessiondata"
 	"github.com/obot-platform/nanobot/pkg/tools"
 	"github.com/obot-platform/nanobot/pkg/types"
+	"github.com/obot-platform/nanobot/pkg/workflow"
 )
 
 type Runtime struct {
@@ -32,6 +38,7 @@ type Runtime struct {
 	llmConfig  llm.Config
 	opt        Options
 	taskServer *tasks.Server
+	channels   *channels.Server
 }
 
 type Options struct {
@@ -47,9 +54,21 @@ type Options struct {
 	TokenExchangeClientID     string
 	TokenExchangeClientSecret string
 	AuditLogCollector         *auditlogs.Collector
+	ToolCallRecorder          auditlogs.ToolCallRecorder
+	AuditToolArguments        bool
 	DefaultModel              string
 	ConfigDir                 string
 	LoopbackURL               string
+	// Schedules are the schedules of the config that the task server runs.
+	Schedules map[string]types.Schedule
+	// Triggers are the triggers of the config that the task server runs.
+	Triggers map[string]types.Trigger
+	// Channels are the channels of the config, answered over the loopback
+	// URL.
+	Channels map[string]types.Channel
+	// Env is the environment of the webhooks of the schedules, triggers,
+	// and channels.
+	Env map[string]string
 }
 
 func (o Options) Merge(other Options) (result Options) {
@@ -65,9 +84,15 @@ func (o Options) Merge(other Options) (result Options) {
 	result.TokenExchangeClientID = complete.Last(o.TokenExchangeClientID, other.TokenExchangeClientID)
 	result.TokenExchangeClientSecret = complete.Last(o.TokenExchangeClientSecret, other.TokenExchangeClientSecret)
 	result.AuditLogCollector = complete.Last(o.AuditLogCollector, other.AuditLogCollector)
+	result.ToolCallRecorder = complete.Last(o.ToolCallRecorder, other.ToolCallRecorder)
+	result.AuditToolArguments = complete.Last(o.AuditToolArguments, other.AuditToolArguments)
 	result.DefaultModel = complete.Last(o.DefaultModel, other.DefaultModel)
 	result.ConfigDir = complete.Last(o.ConfigDir, other.ConfigDir)
 	result.LoopbackURL = complete.Last(o.LoopbackURL, other.LoopbackURL)
+	result.Schedules = complete.MergeMap(o.Schedules, other.Schedules)
+	result.Triggers = complete.MergeMap(o.Triggers, other.Triggers)
+	result.Channels = complete.MergeMap(o.Channels, other.Channels)
+	result.Env = complete.MergeMap(o.Env, other.Env)
 	return
 }
 
@@ -96,6 +121,8 @@ func NewRuntime(ctx context.Context, cfg llm.Config, opts ...Options) (*Runtime,
 		TokenExchangeClientID:     opt.TokenExchangeClientID,
 		TokenExchangeClientSecret: opt.TokenExchangeClientSecret,
 		AuditLogCollector:         opt.AuditLogCollector,
+		ToolCallRecorder:          opt.ToolCallRecorder,
+		AuditToolArguments:        opt.AuditToolArguments,
 	})
 	agentsService := agents.New(completer, registry)
 	sampler := sampling.NewSampler(agentsService)
@@ -121,12 +148,23 @@ func NewRuntime(ctx context.Context, cfg llm.Config, opts ...Options) (*Runtime,
 		return system.NewServer(opt.DefaultModel, opt.ConfigDir)
 	})
 
+	for _, module := range system.Modules() {
+		if module == system.ModuleDynamicMCP {
+			// dynamic-mcp has no tools of its own to publish.
+			continue
+		}
+		registry.AddServer(system.ModuleServerName(module), func(string) mcp.MessageHandler {
+			return system.NewModuleServer(system.NewServer(opt.DefaultModel, opt.ConfigDir), module)
+		})
+	}
+
+	workflowEngine := workflow.NewEngine(registry, agentsService)
 	registry.AddServer("nanobot.workflows", func(string) mcp.MessageHandler {
-		return workflows.NewServer()
+		return workflows.NewServer(workflowEngine)
 	})
 
 	registry.AddServer("nanobot.workflow-tools", func(string) mcp.MessageHandler {
-		return workflows.NewToolsServer()
+		return workflows.NewToolsServer(workflowEngine)
 	})
 
 	registry.AddServer("nanobot.artifacts", func(string) mcp.MessageHandler {
@@ -141,8 +179,31 @@ func NewRuntime(ctx context.Context, cfg llm.Config, opts ...Options) (*Runtime,
 		return obotmcp.NewServer(opt.ConfigDir)
 	})
 
+	subagentServer := subagent.NewServer(agentsService)
+	registry.AddServer(subagent.ServerName, func(string) mcp.MessageHandler {
+		return subagentServer
+	})
+
+	if opt.Store != nil {
+		memoryServer := memory.NewServer(opt.Store)
+		registry.AddServer(memory.ServerName, func(string) mcp.MessageHandler {
+			return memoryServer
+		})
+	}
+
+	if cfg.EmbeddingModel != "" {
+		knowledgeServer := knowledge.NewServer(completer.Embed)
+		registry.AddServer(knowledge.ServerName, func(string) mcp.MessageHandler {
+			return knowledgeServer
+		})
+	}
+
 	if opt.LoopbackURL != "" && opt.Store != nil {
-		taskServer, err := tasks.NewServer(ctx, opt.Store, opt.LoopbackURL)
+		taskServer, err := tasks.NewServer(ctx, opt.Store, opt.LoopbackURL, tasks.Options{
+			Schedules: opt.Schedules,
+			Triggers:  opt.Triggers,
+			Env:       opt.Env,
+		})
 		if err != nil {
 			return nil, fmt.Errorf("failed to start task server: %w", err)
 		}
@@ -152,9 +213,40 @@ func NewRuntime(ctx context.Context, cfg llm.Config, opts ...Options) (*Runtime,
 		})
 	}
 
+	if opt.LoopbackURL != "" && opt.Store != nil && len(opt.Channels) > 0 {
+		channelServer, err := channels.NewServer(ctx, opt.Store, opt.LoopbackURL, opt.Channels, opt.Env)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start channels: %w", err)
+		}
+		r.channels = channelServer
+	}
+
 	return r, nil
 }
 
+// TriggerHandler serves the webhooks of the triggers of the config. It is
+// nil when the runtime has no task server.
+// PingLLM checks that the LLM provider of the default model is reachable.
+func (r *Runtime) PingLLM(ctx context.Context, env map[string]string) error {
+	return llm.NewClient(r.llmConfig).Ping(ctx, env)
+}
+
+func (r *Runtime) TriggerHandler() http.Handler {
+	if r.taskServer == nil {
+		return nil
+	}
+	return r.taskServer.TriggerHandler()
+}
+
+// ChannelHandler serves the channels of the config. It is nil when the
+// runtime has no channels.
+func (r *Runtime) ChannelHandler() http.Handler {
+	if r.channels == nil {
+		return nil
+	}
+	return r.channels
+}
+
 func (r *Runtime) WithTempSession(ctx context.Context, config *types.Config) context.Context {
 	session := mcp.NewEmptySession(ctx)
 	session.Set(types.ConfigSessionKey, config)
@@ -168,8 +260,9 @@ func (r *Runtime) getToolFromRef(ctx context.Context, config types.Config, serve
 
 	toolRef := strings.Split(serverRef, "/")
 	if len(toolRef) == 1 {
-		_, ok := config.Agents[toolRef[0]]
-		if ok {
+		_, isAgent := config.Agents[toolRef[0]]
+		_, isComposite := config.CompositeTools[toolRef[0]]
+		if isAgent || isComposite {
 			server, tool = toolRef[0], toolRef[0]
 		} else {
 			server, tool = "", toolRef[0]
@@ -245,7 +338,24 @@ func (r *Runtime) CallFromCLI(ctx context.Context, serverRef string, args ...str
 		argValue = map[string]any{}
 	}
 
-	callResult, err := r.Call(ctx, tools.Server, tools.Tools[0].Name, argValue)
+	return r.callFromCLI(ctx, tools, argValue)
+}
+
+// ChatFromCLI sends a prompt with attachments to an agent from the command
+// line.
+func (r *Runtime) ChatFromCLI(ctx context.Context, agent string, request types.SampleCallRequest) (*mcp.CallToolResult, error) {
+	tools, err := r.getToolFromRef(ctx, types.ConfigFromContext(ctx), agent)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(tools.Tools[0].InputSchema, types.ChatInputSchema) {
+		return nil, fmt.Errorf("%s is not an agent", agent)
+	}
+	return r.callFromCLI(ctx, tools, request)
+}
+
+func (r *Runtime) callFromCLI(ctx context.Context, tools *tools.ListToolsResult, args any) (*mcp.CallToolResult, error) {
+	callResult, err := r.Call(ctx, tools.Server, tools.Tools[0].Name, args)
 	if err != nil {
 		return nil, err
 	}
@@ -34,10 +34,10 @@ type scored struct {
 	model string
 }
 
-func (s *Sampler) sortModels(config types.Config, preferences mcp.ModelPreferences) []string {
+func (s *Sampler) sortModels(config types.Config, candidates []string, preferences mcp.ModelPreferences) []string {
 	var scoredModels []scored
 
-	for _, modelKey := range slices.Sorted(maps.Keys(config.Agents)) {
+	for _, modelKey := range candidates {
 		model := config.Agents[modelKey]
 		cost := model.Cost
 		if preferences.CostPriority != nil {
@@ -68,24 +68,44 @@ func (s *Sampler) sortModels(config types.Config, preferences mcp.ModelPreferenc
 	return models
 }
 
-func (s *Sampler) getMatchingModel(config types.Config, req *mcp.CreateMessageRequest) (string, bool) {
+// candidates returns the agents a request can sample with: the allowed
+// agents of the options, or the agent of the options, or else all agents.
+func candidates(config types.Config, opt SamplerOptions) []string {
+	allowed := opt.Agents
+	if len(allowed) == 0 && opt.Agent != "" {
+		allowed = []string{opt.Agent}
+	}
+	if len(allowed) == 0 {
+		return slices.Sorted(maps.Keys(config.Agents))
+	}
+
+	var result []string
+	for _, agent := range allowed {
+		if _, ok := config.Agents[agent]; ok && !slices.Contains(result, agent) {
+			result = append(result, agent)
+		}
+	}
+	return result
+}
+
+func (s *Sampler) getMatchingModel(config types.Config, req *mcp.CreateMessageRequest, candidates []string) (string, bool) {
 	// Agent by name
 	for _, model := range req.ModelPreferences.Hints {
-		if _, ok := config.Agents[model.Name]; ok {
+		if slices.Contains(candidates, model.Name) {
 			return model.Name, true
 		}
 	}
 
 	// Model by alias
 	for _, model := range req.ModelPreferences.Hints {
-		for _, modelKey := range slices.Sorted(maps.Keys(config.Agents)) {
+		for _, modelKey := range candidates {
 			if slices.Contains(config.Agents[modelKey].Aliases, model.Name) {
 				return modelKey, true
 			}
 		}
 	}
 
-	models := s.sortModels(config, req.ModelPreferences)
+	models := s.sortModels(config, candidates, req.ModelPreferences)
 	if len(models) == 0 {
 		return "", false
 	}
@@ -102,6 +122,13 @@ type SamplerOptions struct {
 	Tools              []mcp.Tool
 	ToolIncludeContext string
 	ToolSource         string
+	// Agent is the agent the request is sampled for, which is the only
+	// agent it can sample with unless Agents are set.
+	Agent string
+	// Agents are the agents the request can sample with.
+	Agents []string
+	// MaxTokens caps the maxTokens of the request.
+	MaxTokens int
 }
 
 func (s SamplerOptions) Merge(other SamplerOptions) (result SamplerOptions) {
@@ -112,6 +139,9 @@ func (s SamplerOptions) Merge(other SamplerOptions) (result SamplerOptions) {
 	result.Tools = append(s.Tools, other.Tools...)
 	result.ToolIncludeContext = complete.Last(s.ToolIncludeContext, other.ToolIncludeContext)
 	result.ToolSource = complete.Last(s.ToolSource, other.ToolSource)
+	result.Agent = complete.Last(s.Agent, other.Agent)
+	result.Agents = append(s.Agents, other.Agents...)
+	result.MaxTokens = complete.Last(s.MaxTokens, other.MaxTokens)
 	return
 }
 
@@ -119,7 +149,7 @@ func (s *Sampler) Sample(ctx context.Context, req mcp.CreateMessageRequest, opts
 	opt := complete.Complete(opts...)
 	config := types.ConfigFromContext(ctx)
 
-	model, ok := s.getMatchingModel(config, &req)
+	model, ok := s.getMatchingModel(config, &req, candidates(config, opt))
 	if !ok {
 		return nil, ErrNoMatchingModel
 	}
@@ -131,6 +161,9 @@ func (s *Sampler) Sample(ctx context.Context, req mcp.CreateMessageRequest, opts
 	if req.MaxTokens != 0 {
 		request.MaxTokens = req.MaxTokens
 	}
+	if opt.MaxTokens > 0 && (request.MaxTokens == 0 || request.MaxTokens > opt.MaxTokens) {
+		request.MaxTokens = opt.MaxTokens
+	}
 	if req.SystemPrompt != "" {
 		request.SystemPrompt = req.SystemPrompt
 	}
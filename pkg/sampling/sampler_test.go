@@ -2,6 +2,7 @@ package sampling
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/obot-platform/nanobot/pkg/mcp"
@@ -39,7 +40,7 @@ func TestSamplePreservesAttachmentHiddenMessageBoundary(t *testing.T) {
 		Agents: map[string]types.Agent{
 			"test-agent": {
 				HookAgent: types.HookAgent{
-					Model: "test-model",
+					Model: types.AgentModel{"test-model"},
 				},
 			},
 		},
@@ -84,3 +85,48 @@ func TestSamplePreservesAttachmentHiddenMessageBoundary(t *testing.T) {
 		t.Fatalf("unexpected second message text: %q", complete.lastReq.Input[1].Items[0].Content.Text)
 	}
 }
+
+func TestSampleAllowedAgents(t *testing.T) {
+	complete := &fakeCompleter{}
+	s := NewSampler(complete)
+
+	ctx := types.WithConfig(context.Background(), types.Config{
+		Agents: map[string]types.Agent{
+			"main":  {HookAgent: types.HookAgent{Intelligence: 0.1}},
+			"smart": {HookAgent: types.HookAgent{Intelligence: 1, Aliases: []string{"claude"}}},
+			"cheap": {HookAgent: types.HookAgent{Cost: 1}},
+		},
+	})
+	hint := func(name string) mcp.CreateMessageRequest {
+		return mcp.CreateMessageRequest{
+			MaxTokens:        500,
+			ModelPreferences: mcp.ModelPreferences{Hints: []mcp.ModelHint{{Name: name}}},
+			Messages:         []mcp.SamplingMessage{{Role: "user", Content: []mcp.Content{{Type: "text", Text: "hi"}}}},
+		}
+	}
+
+	for _, tt := range []struct {
+		name, hint, model string
+		opt               SamplerOptions
+		maxTokens         int
+	}{
+		{"any agent without options", "smart", "smart", SamplerOptions{}, 500},
+		{"only the agent of the request", "smart", "main", SamplerOptions{Agent: "main"}, 500},
+		{"alias of an allowed agent", "claude", "smart", SamplerOptions{Agent: "main", Agents: []string{"cheap", "smart"}}, 500},
+		{"preferences of allowed agents", "unknown", "cheap", SamplerOptions{Agents: []string{"cheap", "main"}}, 500},
+		{"capped max tokens", "main", "main", SamplerOptions{Agent: "main", MaxTokens: 100}, 100},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.Sample(ctx, hint(tt.hint), tt.opt); err != nil {
+				t.Fatal(err)
+			}
+			if complete.lastReq.Model != tt.model || complete.lastReq.MaxTokens != tt.maxTokens {
+				t.Errorf("expected model %s with %d max tokens, got %s with %d", tt.model, tt.maxTokens, complete.lastReq.Model, complete.lastReq.MaxTokens)
+			}
+		})
+	}
+
+	if _, err := s.Sample(ctx, hint("main"), SamplerOptions{Agents: []string{"missing"}}); !errors.Is(err, ErrNoMatchingModel) {
+		t.Errorf("expected no matching model, got %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/obot-platform/nanobot/pkg/types"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// maxExampleDescriptionLen bounds the example value used as a generated
+// field description.
+const maxExampleDescriptionLen = 60
+
+const (
+	kindString = "string"
+	kindInt    = "int"
+	kindNumber = "number"
+	kindBool   = "bool"
+	kindObject = "object"
+	kindArray  = "array"
+)
+
+// FieldsFromExamples infers the simple-schema Fields of an agent output from
+// example JSON objects. Fields present in every example are required, arrays
+// of objects become "name[]" with nested fields, arrays of numbers or booleans
+// become "name(int)[]", "name(number)[]" or "name(bool)[]", and values whose
+// type differs between examples fall back to strings (int and number widen to
+// number).
+// Every example must be a JSON object.
+func FieldsFromExamples(examples []any) (map[string]types.Field, error) {
+	if len(examples) == 0 {
+		return nil, fmt.Errorf("at least one example is required")
+	}
+
+	objects := make([]map[string]any, 0, len(examples))
+	for i, example := range examples {
+		obj, ok := example.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("example %d is not a JSON object", i+1)
+		}
+		objects = append(objects, obj)
+	}
+
+	return fieldsFromObjects(objects), nil
+}
+
+func fieldsFromObjects(objects []map[string]any) map[string]types.Field {
+	names := map[string]struct{}{}
+	for _, obj := range objects {
+		for name := range obj {
+			names[name] = struct{}{}
+		}
+	}
+
+	result := make(map[string]types.Field, len(names))
+	for _, name := range slices.Sorted(maps.Keys(names)) {
+		var (
+			values  []any
+			missing bool
+		)
+		for _, obj := range objects {
+			if v, ok := obj[name]; ok && v != nil {
+				values = append(values, v)
+			} else {
+				missing = true
+			}
+		}
+
+		var field types.Field
+		if missing {
+			field.Required = new(false)
+		}
+
+		switch kind := kindOf(values); kind {
+		case kindObject:
+			var children []map[string]any
+			for _, v := range values {
+				children = append(children, v.(map[string]any))
+			}
+			field.Fields = fieldsFromObjects(children)
+			result[name] = field
+		case kindArray:
+			var items []any
+			for _, v := range values {
+				items = append(items, v.([]any)...)
+			}
+			switch itemKind := kindOf(items); itemKind {
+			case kindObject:
+				var children []map[string]any
+				for _, item := range items {
+					children = append(children, item.(map[string]any))
+				}
+				field.Fields = fieldsFromObjects(children)
+				result[name+"[]"] = field
+			case kindInt, kindNumber, kindBool:
+				field.Description = exampleDescription(items)
+				result[name+"("+itemKind+")[]"] = field
+			default:
+				// Arrays of arrays, or of mixed kinds, hold strings.
+				field.Description = exampleDescription(items)
+				result[name+"[]"] = field
+			}
+		case kindString:
+			field.Description = exampleDescription(values)
+			result[name] = field
+		default:
+			field.Description = exampleDescription(values)
+			result[name+"("+kind+")"] = field
+		}
+	}
+
+	return result
+}
+
+// kindOf returns the common kind of values, or kindString when they differ.
+func kindOf(values []any) string {
+	kind := ""
+	for _, v := range values {
+		var k string
+		switch v := v.(type) {
+		case map[string]any:
+			k = kindObject
+		case []any:
+			k = kindArray
+		case bool:
+			k = kindBool
+		case float64:
+			k = kindNumber
+			if v == math.Trunc(v) {
+				k = kindInt
+			}
+		case json.Number:
+			k = kindNumber
+			if _, err := v.Int64(); err == nil {
+				k = kindInt
+			}
+		default:
+			k = kindString
+		}
+
+		switch {
+		case kind == "" || kind == k:
+			kind = k
+		case (kind == kindInt && k == kindNumber) || (kind == kindNumber && k == kindInt):
+			kind = kindNumber
+		default:
+			return kindString
+		}
+	}
+	if kind == "" {
+		return kindString
+	}
+	return kind
+}
+
+// exampleDescription describes a field by its first example value so the
+// generated block is useful before the descriptions are written by hand.
+func exampleDescription(values []any) string {
+	if len(values) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(values[0])
+	example := string(data)
+	if len(example) > maxExampleDescriptionLen {
+		example = strings.ToValidUTF8(example[:maxExampleDescriptionLen], "") + "..."
+	}
+	return "e.g. " + example
+}
+
+// ValidateOutput validates an agent output against a JSON schema, such as the
+// one produced by types.OutputSchema.ToSchema.
+func ValidateOutput(schema json.RawMessage, output any) error {
+	schemaObj, err := jsonschema.UnmarshalJSON(strings.NewReader(string(schema)))
+	if err != nil {
+		return fmt.Errorf("invalid output schema: %w", err)
+	}
+
+	c := jsonschema.NewCompiler()
+	if err := c.AddResource("output.json", schemaObj); err != nil {
+		return fmt.Errorf("invalid output schema: %w", err)
+	}
+	s, err := c.Compile("output.json")
+	if err != nil {
+		return fmt.Errorf("invalid output schema: %w", err)
+	}
+
+	// Round trip through JSON so Go values match the validator's expectations.
+	data, err := json.Marshal(output)
+	if err != nil {
+		return err
+	}
+	value, err := jsonschema.UnmarshalJSON(strings.NewReader(string(data)))
+	if err != nil {
+		return err
+	}
+	return s.Validate(value)
+}
@@ -0,0 +1,70 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+func TestFieldsFromExamples(t *testing.T) {
+	var examples []any
+	if err := json.Unmarshal([]byte(`[
+		{"city": "Paris", "temp": 21, "sunny": true, "tags": ["warm"], "scores": [1, 2], "flags": [true], "forecast": [{"day": "Mon", "high": 22}]},
+		{"city": "Oslo", "temp": 4.5, "sunny": false, "tags": [], "scores": [3.5], "flags": [], "forecast": [{"day": "Tue", "high": 5, "low": 1}], "note": "cold"}
+	]`), &examples); err != nil {
+		t.Fatal(err)
+	}
+
+	fields, err := FieldsFromExamples(examples)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]types.Field{
+		"city":             {Description: `e.g. "Paris"`},
+		"temp(number)":     {Description: "e.g. 21"},
+		"sunny(bool)":      {Description: "e.g. true"},
+		"tags[]":           {Description: `e.g. "warm"`},
+		"scores(number)[]": {Description: "e.g. 1"},
+		"flags(bool)[]":    {Description: "e.g. true"},
+		"note":             {Description: `e.g. "cold"`, Required: new(false)},
+		"forecast[]": {Fields: map[string]types.Field{
+			"day":       {Description: `e.g. "Mon"`},
+			"high(int)": {Description: "e.g. 22"},
+			"low(int)":  {Description: "e.g. 1", Required: new(false)},
+		}},
+	}
+	if !reflect.DeepEqual(fields, expected) {
+		got, _ := json.MarshalIndent(fields, "", "  ")
+		t.Errorf("unexpected fields:\n%s", got)
+	}
+
+	schema := types.OutputSchema{Fields: fields}.ToSchema()
+	for i, example := range examples {
+		if err := ValidateOutput(schema, example); err != nil {
+			t.Errorf("example %d does not validate: %v", i+1, err)
+		}
+	}
+	if err := ValidateOutput(schema, map[string]any{"city": "Rome"}); err == nil {
+		t.Error("expected output missing required fields to fail validation")
+	}
+}
+
+func TestFieldsFromExamples_MixedTypes(t *testing.T) {
+	fields, err := FieldsFromExamples([]any{
+		map[string]any{"id": 1.0},
+		map[string]any{"id": "abc"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fields["id"]; !ok {
+		t.Errorf("expected mixed types to fall back to a string field, got %v", fields)
+	}
+
+	if _, err := FieldsFromExamples([]any{"not an object"}); err == nil {
+		t.Error("expected an error for a non-object example")
+	}
+}
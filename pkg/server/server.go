@@ -2,7 +2,9 @@ package server
 
 import (
 	"bytes"
+	"cmp"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,10 +12,13 @@ import (
 	"maps"
 	"net/url"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/obot-platform/nanobot/pkg/complete"
+	"github.com/obot-platform/nanobot/pkg/encryption"
 	"github.com/obot-platform/nanobot/pkg/expr"
+	"github.com/obot-platform/nanobot/pkg/log"
 	"github.com/obot-platform/nanobot/pkg/mcp"
 	"github.com/obot-platform/nanobot/pkg/runtime"
 	"github.com/obot-platform/nanobot/pkg/session"
@@ -22,6 +27,10 @@ import (
 	"github.com/obot-platform/nanobot/pkg/types"
 )
 
+// maxPromptResourceSize bounds the size of a resource embedded in a prompt for
+// a resource-typed argument.
+const maxPromptResourceSize = 256 * 1024 // 256 KiB
+
 type Server struct {
 	handlers           []handler
 	runtime            *runtime.Runtime
@@ -29,15 +38,20 @@ type Server struct {
 	config             types.ConfigFactory
 	manager            *session.Manager
 	forceFetchToolList bool
+	limiter            types.AccountLimiter
 }
 
 type Options struct {
 	ForceFetchToolList bool
+	// Limiter enforces the limits of each account on its runs, tool calls,
+	// and completions.
+	Limiter types.AccountLimiter
 }
 
 func (o Options) Merge(other Options) Options {
 	return Options{
 		ForceFetchToolList: o.ForceFetchToolList || other.ForceFetchToolList,
+		Limiter:            complete.Last(o.Limiter, other.Limiter),
 	}
 }
 
@@ -49,6 +63,7 @@ func NewServer(runtime *runtime.Runtime, config types.ConfigFactory, manager *se
 		config:             config,
 		manager:            manager,
 		forceFetchToolList: opt.ForceFetchToolList,
+		limiter:            opt.Limiter,
 	}
 	s.init()
 	return s
@@ -86,7 +101,9 @@ func (s *Server) init() {
 		handle("resources/read", s.handleReadResource),
 		handle("resources/subscribe", s.handleResourcesSubscribe),
 		handle("resources/unsubscribe", s.handleResourcesUnsubscribe),
+		handle("completion/complete", s.handleComplete),
 		handle("notifications/cancelled", s.handleCancelled),
+		handle("notifications/roots/list_changed", s.handleRootsListChanged),
 	}
 }
 
@@ -106,7 +123,7 @@ func (s *Server) handleResourcesSubscribe(ctx context.Context, msg mcp.Message,
 	return msg.Reply(ctx, mcp.SubscribeResult{})
 }
 
-func (s *Server) handleListResourceTemplates(ctx context.Context, msg mcp.Message, _ mcp.ListResourceTemplatesRequest) error {
+func (s *Server) handleListResourceTemplates(ctx context.Context, msg mcp.Message, payload mcp.ListResourceTemplatesRequest) error {
 	resourceTemplateMappings, err := s.data.PublishedResourceTemplateMappings(ctx)
 	if err != nil {
 		return err
@@ -121,6 +138,39 @@ func (s *Server) handleListResourceTemplates(ctx context.Context, msg mcp.Messag
 		result.ResourceTemplates = append(result.ResourceTemplates, match.ResourceTemplate)
 	}
 
+	result.ResourceTemplates, result.NextCursor, err = mcp.Paginate(result.ResourceTemplates, payload.Cursor)
+	if err != nil {
+		return err
+	}
+	return msg.Reply(ctx, result)
+}
+
+func (s *Server) handleComplete(ctx context.Context, msg mcp.Message, payload mcp.CompleteRequest) error {
+	promptMappings, err := s.data.PublishedPromptMappings(ctx)
+	if err != nil {
+		return err
+	}
+	resourceTemplateMappings, err := s.data.PublishedResourceTemplateMappings(ctx)
+	if err != nil {
+		return err
+	}
+
+	target, ref, ok := s.data.CompletionTarget(ctx, payload.Ref, promptMappings, resourceTemplateMappings)
+	if !ok {
+		return msg.Reply(ctx, mcp.CompleteResult{Completion: mcp.Completion{Values: []string{}}})
+	}
+
+	c, err := s.runtime.GetClient(ctx, target)
+	if err != nil {
+		return fmt.Errorf("failed to get client for server %s: %w", target, err)
+	}
+
+	payload.Ref = ref
+	result, err := c.Complete(ctx, payload)
+	if err != nil {
+		return err
+	}
+
 	return msg.Reply(ctx, result)
 }
 
@@ -159,10 +209,91 @@ func (s *Server) handleGetPrompt(ctx context.Context, msg mcp.Message, payload m
 		return err
 	}
 
+	if inline, ok := types.ConfigFromContext(ctx).Prompts[promptMapping.MCPServer]; ok && promptMapping.MCPServer == promptMapping.TargetName {
+		var resourceMessages []mcp.PromptMessage
+		for _, name := range inline.ResourceInputs() {
+			uri := payload.Arguments[name]
+			if uri == "" {
+				continue
+			}
+			messages, err := s.readPromptResource(ctx, uri)
+			if err != nil {
+				return fmt.Errorf("failed to read resource %s for prompt argument %s: %w", uri, name, err)
+			}
+			resourceMessages = append(resourceMessages, messages...)
+		}
+		// Put the resources ahead of the rendered template so the instructions
+		// follow the content they refer to.
+		result.Messages = append(resourceMessages, result.Messages...)
+	}
+
 	return msg.Reply(ctx, result)
 }
 
-func (s *Server) handleListResources(ctx context.Context, msg mcp.Message, _ mcp.ListResourcesRequest) error {
+// readPromptResource reads a published resource and converts its contents to
+// prompt messages with embedded resources. Text larger than
+// maxPromptResourceSize is truncated and larger binary contents are replaced
+// with a resource link.
+func (s *Server) readPromptResource(ctx context.Context, uri string) ([]mcp.PromptMessage, error) {
+	target, resourceName, err := s.data.MatchPublishedResource(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := s.runtime.GetClient(ctx, target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client for server %s: %w", target, err)
+	}
+
+	resource, err := c.ReadResource(ctx, resourceName)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]mcp.PromptMessage, 0, len(resource.Contents))
+	for _, content := range resource.Contents {
+		embedded := &mcp.EmbeddedResource{
+			URI:      cmp.Or(content.URI, uri),
+			Name:     content.Name,
+			MIMEType: content.MIMEType,
+		}
+		switch {
+		case content.Text != nil:
+			embedded.Text = *content.Text
+			if len(embedded.Text) > maxPromptResourceSize {
+				embedded.Text = strings.ToValidUTF8(embedded.Text[:maxPromptResourceSize], "") +
+					fmt.Sprintf("\n\n[Truncated: showing the first %d of %d bytes]", maxPromptResourceSize, len(*content.Text))
+			}
+		case content.Blob != nil:
+			if base64.StdEncoding.DecodedLen(len(*content.Blob)) > maxPromptResourceSize {
+				messages = append(messages, mcp.PromptMessage{
+					Role: "user",
+					Content: mcp.Content{
+						Type:     "resource_link",
+						URI:      embedded.URI,
+						Name:     embedded.Name,
+						MIMEType: embedded.MIMEType,
+					},
+				})
+				continue
+			}
+			embedded.Blob = *content.Blob
+		}
+		messages = append(messages, mcp.PromptMessage{
+			Role: "user",
+			Content: mcp.Content{
+				Type:     "resource",
+				Resource: embedded,
+			},
+		})
+	}
+
+	return messages, nil
+}
+
+func (s *Server) handleListResources(ctx context.Context, msg mcp.Message, payload mcp.ListResourcesRequest) error {
+	mcp.SessionFromContext(ctx).Set(types.ResourcesListedSessionKey, true)
+
 	resourceMappings, err := s.data.PublishedResourceMappings(ctx)
 	if err != nil {
 		return err
@@ -176,10 +307,14 @@ func (s *Server) handleListResources(ctx context.Context, msg mcp.Message, _ mcp
 		result.Resources = append(result.Resources, resourceMappings[k].Target)
 	}
 
+	result.Resources, result.NextCursor, err = mcp.Paginate(result.Resources, payload.Cursor)
+	if err != nil {
+		return err
+	}
 	return msg.Reply(ctx, result)
 }
 
-func (s *Server) handleListPrompts(ctx context.Context, msg mcp.Message, _ mcp.ListPromptsRequest) error {
+func (s *Server) handleListPrompts(ctx context.Context, msg mcp.Message, payload mcp.ListPromptsRequest) error {
 	s.data.Refresh(ctx, false)
 	promptMappings, err := s.data.PublishedPromptMappings(ctx)
 	if err != nil {
@@ -194,6 +329,10 @@ func (s *Server) handleListPrompts(ctx context.Context, msg mcp.Message, _ mcp.L
 		result.Prompts = append(result.Prompts, promptMappings[k].Target)
 	}
 
+	result.Prompts, result.NextCursor, err = mcp.Paginate(result.Prompts, payload.Cursor)
+	if err != nil {
+		return err
+	}
 	return msg.Reply(ctx, result)
 }
 
@@ -218,7 +357,7 @@ func (s *Server) handleCallTool(ctx context.Context, msg mcp.Message, payload mc
 		}
 	}
 
-	slog.Debug("mcp server dispatching tool call",
+	slog.DebugContext(ctx, "mcp server dispatching tool call",
 		"mcp_tool_name", payload.Name,
 		"target_server", toolMapping.MCPServer,
 		"target_tool_name", toolMapping.TargetName,
@@ -234,7 +373,7 @@ func (s *Server) handleCallTool(ctx context.Context, msg mcp.Message, payload mc
 	if err != nil {
 		return err
 	}
-	slog.Debug("mcp server completed tool call",
+	slog.DebugContext(ctx, "mcp server completed tool call",
 		"mcp_tool_name", payload.Name,
 		"target_server", toolMapping.MCPServer,
 		"target_tool_name", toolMapping.TargetName,
@@ -248,10 +387,17 @@ func (s *Server) handleCallTool(ctx context.Context, msg mcp.Message, payload mc
 		Content:           result.Content,
 	}
 
+	if types.IsRawResponseFormat(msg.Meta()) {
+		mcpResult, err = types.RawCallToolResult(mcpResult)
+		if err != nil {
+			return err
+		}
+	}
+
 	return msg.Reply(ctx, mcpResult)
 }
 
-func (s *Server) handleListTools(ctx context.Context, msg mcp.Message, _ mcp.ListToolsRequest) error {
+func (s *Server) handleListTools(ctx context.Context, msg mcp.Message, payload mcp.ListToolsRequest) error {
 	result := mcp.ListToolsResult{
 		Tools: []mcp.Tool{},
 	}
@@ -265,6 +411,10 @@ func (s *Server) handleListTools(ctx context.Context, msg mcp.Message, _ mcp.Lis
 		result.Tools = append(result.Tools, toolMappings[k].Target.Tool)
 	}
 
+	result.Tools, result.NextCursor, err = mcp.Paginate(result.Tools, payload.Cursor)
+	if err != nil {
+		return err
+	}
 	return msg.Reply(ctx, result)
 }
 
@@ -302,6 +452,9 @@ func reconcileEnv(session *mcp.Session, c types.Config) error {
 			continue
 		}
 		envMap[envKey] = envVal
+		if envDef.IsSensitive() {
+			log.AddSensitiveValues(envVal)
+		}
 	}
 
 	if len(missing) == 0 {
@@ -365,6 +518,7 @@ func (s *Server) runInitHook(ctx context.Context) (types.SessionInitHook, error)
 
 	session.Get(types.SessionInitSessionKey, &sessionInit)
 	sessionInit.SessionID = session.ID()
+	sessionInit.ClientCapabilities = session.ClientCapabilities()
 	if req != nil {
 		sessionInit.URL = sessiondata.GetHostURL(req)
 	}
@@ -426,13 +580,18 @@ func (s *Server) handleInitialize(ctx context.Context, msg mcp.Message, payload
 		ProtocolVersion: payload.ProtocolVersion,
 		Capabilities: mcp.ServerCapabilities{
 			Experimental: experimental,
+			Completions:  &struct{}{},
 			Logging:      &struct{}{},
 			Prompts:      &mcp.PromptsServerCapability{},
 			Resources: &mcp.ResourcesServerCapability{
 				Subscribe:   true,
 				ListChanged: true,
 			},
-			Tools: &mcp.ToolsServerCapability{},
+			// The tools of unavailable MCP servers are hidden until they
+			// reconnect.
+			Tools: &mcp.ToolsServerCapability{
+				ListChanged: true,
+			},
 		},
 		ServerInfo: mcp.ServerInfo{
 			Name:    c.Publish.Name,
@@ -467,10 +626,41 @@ func (s *Server) handleCancelled(ctx context.Context, msg mcp.Message, payload m
 	return nil
 }
 
+// handleRootsListChanged passes the change of the roots of the client on to
+// the MCP servers of the session.
+func (s *Server) handleRootsListChanged(ctx context.Context, _ mcp.Message, _ struct{}) error {
+	s.runtime.NotifyRootsChanged(ctx)
+
+	// No response for notifications
+	return nil
+}
+
+// applyAccount rejects the messages of disabled accounts and sets what admins
+// allow the account of the session to use.
+func (s *Server) applyAccount(ctx context.Context) error {
+	_, accountID := types.GetSessionAndAccountID(ctx)
+	account, err := s.manager.DB.GetAccount(ctx, accountID)
+	if err != nil {
+		return fmt.Errorf("failed to get account: %w", err)
+	}
+	if account.Disabled {
+		return mcp.ErrRPCInvalidRequest.WithMessage("account %q is disabled", accountID)
+	}
+	mcp.SessionFromContext(ctx).Set(types.AccountSettingsSessionKey, account.Settings)
+	return nil
+}
+
 func (s *Server) OnMessage(ctx context.Context, msg mcp.Message) {
+	attrs := []slog.Attr{slog.String(log.SessionIDKey, msg.Session.ID())}
 	if msg.ID != nil {
 		ctx = mcp.WithRequestID(ctx, msg.ID)
+		attrs = append(attrs, slog.Any(log.RequestIDKey, msg.ID))
+	}
+	var accountID string
+	if msg.Session.Get(types.AccountIDSessionKey, &accountID) {
+		attrs = append(attrs, slog.String(log.AccountIDKey, accountID))
 	}
+	ctx = log.WithAttrs(ctx, attrs...)
 
 	msg.Session.Run(ctx, msg, func(ctx context.Context, m mcp.Message) {
 		s.onMessage(ctx, m)
@@ -483,7 +673,19 @@ func (s *Server) onMessage(ctx context.Context, msg mcp.Message) {
 		return
 	}
 
+	if err := s.applyAccount(ctx); err != nil {
+		msg.SendError(ctx, err)
+		return
+	}
+
 	mcp.SessionFromContext(ctx).Set(session.ManagerSessionKey, s.manager)
+	mcp.SessionFromContext(ctx).Set(types.CheckpointerSessionKey, s.manager)
+	if s.limiter != nil {
+		mcp.SessionFromContext(ctx).Set(types.LimiterSessionKey, s.limiter)
+	}
+	if envelope := s.manager.DB.Encryption(); envelope != nil {
+		mcp.SessionFromContext(ctx).Set(encryption.SessionKey, envelope)
+	}
 
 	for _, h := range s.handlers {
 		ok, err := h(ctx, msg)
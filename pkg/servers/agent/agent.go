@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"log/slog"
 	"slices"
+	"strings"
 
 	"github.com/obot-platform/nanobot/pkg/agents"
+	"github.com/obot-platform/nanobot/pkg/llm"
 	"github.com/obot-platform/nanobot/pkg/mcp"
 	"github.com/obot-platform/nanobot/pkg/sampling"
 	"github.com/obot-platform/nanobot/pkg/sessiondata"
@@ -42,6 +44,9 @@ func NewServer(d *sessiondata.Data, r Caller, agents *agents.Agents, name string
 
 	s.tools = mcp.NewServerTools(
 		chatCall{s: s},
+		mcp.NewServerTool(undoToolName, "Undoes the most recent turn of the chat. The files changed by its tools are reverted and its messages are removed from the conversation. Changes made by bash commands are not reverted.", s.undoLastTurn),
+		mcp.NewServerTool(compactToolName, "Compacts the chat now, replacing its older messages with a summary. The replaced messages are archived and can be read from the "+types.ArchiveURI+" resource.", s.compactConversation),
+		mcp.NewServerTool(restoreToolName, "Restores the messages archived by compaction to the chat in place of their summaries.", s.restoreCompactedMessages),
 	)
 
 	return s
@@ -98,6 +103,8 @@ func (s *Server) OnMessage(ctx context.Context, msg mcp.Message) {
 		mcp.Invoke(ctx, msg, s.promptsList)
 	case "prompts/get":
 		mcp.Invoke(ctx, msg, s.promptGet)
+	case "completion/complete":
+		mcp.Invoke(ctx, msg, s.complete)
 	default:
 		msg.SendError(ctx, mcp.ErrRPCMethodNotFound.WithMessage("%v", msg.Method))
 	}
@@ -197,6 +204,24 @@ func (s *Server) readPendingElicitation(ctx context.Context) ([]mcp.ResourceCont
 	}, nil
 }
 
+func (s *Server) readUsage(ctx context.Context) ([]mcp.ResourceContent, error) {
+	var usage types.SessionUsage
+	mcp.SessionFromContext(ctx).Root().Get(types.UsageSessionKey, &usage)
+
+	data, err := json.Marshal(llm.UsageReport(usage))
+	if err != nil {
+		return nil, err
+	}
+
+	return []mcp.ResourceContent{
+		{
+			URI:      types.UsageURI,
+			MIMEType: types.UsageMimeType,
+			Text:     new(string(data)),
+		},
+	}, nil
+}
+
 func (s *Server) promptGet(ctx context.Context, _ mcp.Message, payload mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
 	c := types.ConfigFromContext(ctx)
 	agent := c.Agents[s.agentName]
@@ -246,6 +271,14 @@ func (s *Server) resourcesRead(ctx context.Context, _ mcp.Message, request mcp.R
 		return &mcp.ReadResourceResult{
 			Contents: contents,
 		}, nil
+	case types.ArchiveURI:
+		contents, err = s.readArchive(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &mcp.ReadResourceResult{
+			Contents: contents,
+		}, nil
 	case types.ProgressURI:
 		contents, err = s.readProgress(ctx)
 		if err != nil {
@@ -262,6 +295,18 @@ func (s *Server) resourcesRead(ctx context.Context, _ mcp.Message, request mcp.R
 		return &mcp.ReadResourceResult{
 			Contents: contents,
 		}, nil
+	case types.UsageURI:
+		contents, err = s.readUsage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &mcp.ReadResourceResult{
+			Contents: contents,
+		}, nil
+	}
+
+	if isTranscriptURI(request.URI) {
+		return readTranscript(ctx, request.URI)
 	}
 
 	ctx, err = s.withConfig(ctx)
@@ -269,6 +314,10 @@ func (s *Server) resourcesRead(ctx context.Context, _ mcp.Message, request mcp.R
 		return nil, err
 	}
 
+	if name, ok := strings.CutPrefix(request.URI, fmt.Sprintf(types.TruncatedOutputURI, "")); ok {
+		return readTruncatedOutput(ctx, request.URI, name)
+	}
+
 	c := types.ConfigFromContext(ctx)
 	agent := c.Agents[s.agentName]
 
@@ -302,6 +351,35 @@ func (s *Server) resourcesTemplatesList(ctx context.Context, _ mcp.Message, _ mc
 	return result, nil
 }
 
+// complete forwards a completion to the server of the prompt or resource
+// template of the agent it is for.
+func (s *Server) complete(ctx context.Context, _ mcp.Message, request mcp.CompleteRequest) (*mcp.CompleteResult, error) {
+	c := types.ConfigFromContext(ctx)
+	agent := c.Agents[s.agentName]
+
+	prompts, err := s.data.BuildPromptMappings(ctx, slices.Concat(agent.MCPServers, agent.Prompts))
+	if err != nil {
+		return nil, err
+	}
+	templates, err := s.data.BuildResourceTemplateMappings(ctx, slices.Concat(agent.MCPServers, agent.Resources))
+	if err != nil {
+		return nil, err
+	}
+
+	server, ref, ok := s.data.CompletionTarget(ctx, request.Ref, prompts, templates)
+	if !ok {
+		return &mcp.CompleteResult{Completion: mcp.Completion{Values: []string{}}}, nil
+	}
+
+	client, err := s.runtime.GetClient(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Ref = ref
+	return client.Complete(ctx, request)
+}
+
 func (s *Server) resourcesList(ctx context.Context, _ mcp.Message, _ mcp.ListResourcesRequest) (*mcp.ListResourcesResult, error) {
 	c := types.ConfigFromContext(ctx)
 	agent := c.Agents[s.agentName]
@@ -322,6 +400,12 @@ func (s *Server) resourcesList(ctx context.Context, _ mcp.Message, _ mcp.ListRes
 		Title:       "Chat History",
 		Description: "The chat history for the current agent.",
 		MimeType:    types.HistoryMimeType,
+	}, mcp.Resource{
+		URI:         types.ArchiveURI,
+		Name:        "chat-archive",
+		Title:       "Compacted Chat History",
+		Description: "The messages of the current agent's chat that were replaced by compaction summaries.",
+		MimeType:    types.HistoryMimeType,
 	}, mcp.Resource{
 		URI:         types.ProgressURI,
 		Name:        "chat-progress",
@@ -334,7 +418,21 @@ func (s *Server) resourcesList(ctx context.Context, _ mcp.Message, _ mcp.ListRes
 		Title:       "Pending Elicitation",
 		Description: "The pending elicitation for the current session, if any.",
 		MimeType:    types.ElicitationMimeType,
+	}, mcp.Resource{
+		URI:         types.UsageURI,
+		Name:        "session-usage",
+		Title:       "Session Usage",
+		Description: "The tokens used by the current session and their estimated cost, by model.",
+		MimeType:    types.UsageMimeType,
 	})
+
+	result.Resources = append(result.Resources, transcriptResources()...)
+
+	truncated, err := truncatedOutputResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result.Resources = append(result.Resources, truncated...)
 	return result, nil
 }
 
@@ -359,9 +457,10 @@ func (s *Server) initialize(ctx context.Context, _ mcp.Message, params mcp.Initi
 	return &mcp.InitializeResult{
 		ProtocolVersion: params.ProtocolVersion,
 		Capabilities: mcp.ServerCapabilities{
-			Tools:     &mcp.ToolsServerCapability{},
-			Prompts:   &mcp.PromptsServerCapability{},
-			Resources: &mcp.ResourcesServerCapability{},
+			Completions: &struct{}{},
+			Tools:       &mcp.ToolsServerCapability{},
+			Prompts:     &mcp.PromptsServerCapability{},
+			Resources:   &mcp.ResourcesServerCapability{},
 		},
 		ServerInfo: mcp.ServerInfo{
 			Name:    version.Name,
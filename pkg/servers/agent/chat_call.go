@@ -187,6 +187,13 @@ func appendProgress(ctx context.Context, session *mcp.Session, progressMessage *
 }
 
 func (c chatCall) Invoke(ctx context.Context, msg mcp.Message, payload mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if isUndoCommand(payload.Arguments) {
+		return c.s.undoCommandResult(ctx)
+	}
+	if isCompactCommand(payload.Arguments) {
+		return c.s.compactCommandResult(ctx)
+	}
+
 	c.s.describeSession(ctx, payload.Arguments)
 
 	if attachments, _ := payload.Arguments["attachments"].([]any); len(attachments) > 0 {
@@ -272,28 +279,9 @@ func (c chatCall) chatInvoke(ctx context.Context, msg mcp.Message, payload mcp.C
 }
 
 func GetMessages(ctx context.Context) ([]types.Message, error) {
-	var (
-		run         types.Execution
-		allMessages []types.Message
-	)
-
-	session := mcp.SessionFromContext(ctx)
-	session.Get(types.PreviousExecutionKey, &run)
-
-	// Prepend archived pre-compaction messages
-	if len(run.CompactedMessages) > 0 {
-		allMessages = append(allMessages, run.CompactedMessages...)
-	}
-
-	// Add current input (includes compaction summary in its natural position)
-	if run.PopulatedRequest != nil {
-		allMessages = append(allMessages, run.PopulatedRequest.Input...)
-	}
-	if run.Response != nil {
-		allMessages = append(allMessages, run.Response.Output)
-	}
-
-	return types.ConsolidateTools(allMessages), nil
+	var run types.Execution
+	mcp.SessionFromContext(ctx).Get(types.PreviousExecutionKey, &run)
+	return types.ConsolidateTools(run.Messages()), nil
 }
 
 type progressPayload struct {
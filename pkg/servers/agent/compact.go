@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/obot-platform/nanobot/pkg/agents"
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+const (
+	compactToolName = "compactConversation"
+	restoreToolName = "restoreCompactedMessages"
+	// compactCommand is the chat prompt that compacts the chat instead of
+	// being sent to the agent.
+	compactCommand = "/compact"
+)
+
+func (s *Server) compactConversation(ctx context.Context, _ struct{}) (*agents.CompactResult, error) {
+	ctx, err := s.withConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.agents.CompactConversation(ctx)
+	if errors.Is(err, agents.ErrNothingToCompact) {
+		return nil, mcp.ErrRPCInvalidRequest.WithMessage("%v", err)
+	} else if err != nil {
+		return nil, err
+	}
+
+	s.sendHistoryUpdated(ctx)
+	return result, nil
+}
+
+func (s *Server) restoreCompactedMessages(ctx context.Context, _ struct{}) (*agents.RestoreResult, error) {
+	result, err := s.agents.RestoreCompactedMessages(ctx)
+	if errors.Is(err, agents.ErrNothingToRestore) {
+		return nil, mcp.ErrRPCInvalidRequest.WithMessage("%v", err)
+	} else if err != nil {
+		return nil, err
+	}
+
+	s.sendHistoryUpdated(ctx)
+	return result, nil
+}
+
+func (s *Server) sendHistoryUpdated(ctx context.Context) {
+	session := mcp.SessionFromContext(ctx).Root()
+	for _, uri := range []string{types.HistoryURI, types.ArchiveURI} {
+		_ = session.SendPayload(ctx, "notifications/resources/updated", map[string]any{
+			"uri": uri,
+		})
+	}
+}
+
+func (s *Server) readArchive(ctx context.Context) ([]mcp.ResourceContent, error) {
+	var run types.Execution
+	mcp.SessionFromContext(ctx).Get(types.PreviousExecutionKey, &run)
+	return messagesToResourceContents(types.ConsolidateTools(run.CompactedMessages))
+}
+
+// isCompactCommand reports whether the chat arguments are the /compact
+// command.
+func isCompactCommand(args map[string]any) bool {
+	prompt, _ := args["prompt"].(string)
+	attachments, _ := args["attachments"].([]any)
+	return strings.TrimSpace(prompt) == compactCommand && len(attachments) == 0
+}
+
+// compactCommandResult runs the /compact command and describes the result in
+// the chat.
+func (s *Server) compactCommandResult(ctx context.Context) (*mcp.CallToolResult, error) {
+	result, err := s.compactConversation(ctx, struct{}{})
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{{Type: "text", Text: fmt.Sprintf("Nothing was compacted: %v", err)}},
+		}, nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{{Type: "text", Text: fmt.Sprintf("Compacted the conversation, archiving %d messages and keeping %d. The archived messages can be read from %s.",
+			result.Archived, result.Kept, types.ArchiveURI)}},
+	}, nil
+}
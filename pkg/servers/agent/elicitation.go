@@ -40,11 +40,18 @@ func (p PendingElicitation) Deserialize(v any) (any, error) {
 
 const pendingElicitationKey = "pending-elicitation"
 
+// ErrElicitationUnsupported is returned by ExchangeElicitation when the client
+// didn't declare the elicitation capability, it would never answer.
+var ErrElicitationUnsupported = errors.New("the client does not support elicitation")
+
 func ExchangeElicitation(ctx context.Context, session *mcp.Session, elicit any, result any) error {
 	root := session.Root()
 	if root == nil {
 		return fmt.Errorf("no root session found")
 	}
+	if root.ClientCapabilities().Elicitation == nil {
+		return ErrElicitationUnsupported
+	}
 
 	msg, err := mcp.NewMessageWithID("elicitation/create", elicit)
 	if err != nil {
@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/transcript"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+var transcriptFormats = []string{transcript.FormatMarkdown, transcript.FormatHTML}
+
+func transcriptResources() (resources []mcp.Resource) {
+	for _, format := range transcriptFormats {
+		resources = append(resources, mcp.Resource{
+			URI:         fmt.Sprintf(types.TranscriptURI, format),
+			Name:        "chat-transcript-" + format,
+			Title:       "Chat Transcript",
+			Description: "A readable transcript of the current agent's chat, add ?reasoning=true to include the model's reasoning.",
+			MimeType:    transcript.MimeType(format),
+		})
+	}
+	return resources
+}
+
+// isTranscriptURI reports whether the URI is of a transcript resource.
+func isTranscriptURI(uri string) bool {
+	return strings.HasPrefix(uri, fmt.Sprintf(types.TranscriptURI, ""))
+}
+
+func readTranscript(ctx context.Context, uri string) (*mcp.ReadResourceResult, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("invalid transcript URI %q: %v", uri, err)
+	}
+
+	format := strings.TrimPrefix(u.Path, "/")
+	if format != transcript.FormatMarkdown && format != transcript.FormatHTML {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("unknown transcript format %q, must be one of %s", format, strings.Join(transcriptFormats, ", "))
+	}
+
+	var opts transcript.Options
+	if reasoning := u.Query().Get("reasoning"); reasoning != "" {
+		if opts.Reasoning, err = strconv.ParseBool(reasoning); err != nil {
+			return nil, mcp.ErrRPCInvalidParams.WithMessage("invalid reasoning parameter %q", reasoning)
+		}
+	}
+
+	session := mcp.SessionFromContext(ctx)
+	session.Get(types.DescriptionSessionKey, &opts.Title)
+
+	messages, err := GetMessages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := transcript.Render(&buf, format, messages, opts); err != nil {
+		return nil, err
+	}
+
+	return &mcp.ReadResourceResult{
+		Contents: []mcp.ResourceContent{{
+			URI:      uri,
+			MIMEType: transcript.MimeType(format),
+			Text:     new(buf.String()),
+		}},
+	}, nil
+}
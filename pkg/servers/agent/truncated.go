@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/obot-platform/nanobot/pkg/agents"
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// truncatedOutputResources lists the full outputs of the session's truncated
+// tool results as resources.
+func truncatedOutputResources(ctx context.Context) ([]mcp.Resource, error) {
+	outputs, err := agents.ListTruncatedOutputs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []mcp.Resource
+	for _, output := range outputs {
+		resources = append(resources, mcp.Resource{
+			URI:         fmt.Sprintf(types.TruncatedOutputURI, output.Name),
+			Name:        output.Name,
+			Title:       "Truncated Tool Result",
+			Description: "The full output of a tool result that was truncated before it was sent to the model.",
+			MimeType:    output.MimeType,
+			Size:        output.Size,
+			Annotations: &mcp.Annotations{
+				LastModified: output.Modified,
+			},
+		})
+	}
+	return resources, nil
+}
+
+func readTruncatedOutput(ctx context.Context, uri, name string) (*mcp.ReadResourceResult, error) {
+	data, mimeType, err := agents.ReadTruncatedOutput(ctx, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("truncated output %q not found", name)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &mcp.ReadResourceResult{
+		Contents: []mcp.ResourceContent{{
+			URI:      uri,
+			MIMEType: mimeType,
+			Text:     new(string(data)),
+		}},
+	}, nil
+}
@@ -0,0 +1,60 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/obot-platform/nanobot/pkg/agents"
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+const (
+	undoToolName = "undoLastTurn"
+	// undoCommand is the chat prompt that undoes the last turn instead of
+	// being sent to the agent.
+	undoCommand = "/undo"
+)
+
+func (s *Server) undoLastTurn(ctx context.Context, _ struct{}) (*agents.UndoResult, error) {
+	result, err := s.agents.UndoLastTurn(ctx)
+	if errors.Is(err, agents.ErrNothingToUndo) {
+		return nil, mcp.ErrRPCInvalidRequest.WithMessage("%v", err)
+	} else if err != nil {
+		return nil, err
+	}
+
+	_ = mcp.SessionFromContext(ctx).Root().SendPayload(ctx, "notifications/resources/updated", map[string]any{
+		"uri": types.HistoryURI,
+	})
+	return result, nil
+}
+
+// isUndoCommand reports whether the chat arguments are the /undo command.
+func isUndoCommand(args map[string]any) bool {
+	prompt, _ := args["prompt"].(string)
+	attachments, _ := args["attachments"].([]any)
+	return strings.TrimSpace(prompt) == undoCommand && len(attachments) == 0
+}
+
+// undoCommandResult runs the /undo command and describes the result in the
+// chat.
+func (s *Server) undoCommandResult(ctx context.Context) (*mcp.CallToolResult, error) {
+	result, err := s.undoLastTurn(ctx, struct{}{})
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{{Type: "text", Text: fmt.Sprintf("Nothing was undone: %v", err)}},
+		}, nil
+	}
+
+	text := fmt.Sprintf("Undid the last turn and removed %d messages from the conversation.", result.Messages)
+	if len(result.RevertedFiles) > 0 {
+		text += "\nReverted files:\n- " + strings.Join(result.RevertedFiles, "\n- ")
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{{Type: "text", Text: text}},
+	}, nil
+}
@@ -2,6 +2,7 @@ package artifacts
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -93,7 +94,10 @@ func (s *Server) installArtifact(ctx context.Context, params installArtifactPara
 		}
 
 		var result mcp.ElicitResult
-		if err := agent.ExchangeElicitation(ctx, session, elicit, &result); err != nil {
+		if err := agent.ExchangeElicitation(ctx, session, elicit, &result); errors.Is(err, agent.ErrElicitationUnsupported) {
+			// The user can't be asked, so leave the existing workflow alone.
+			result.Action = "decline"
+		} else if err != nil {
 			return nil, fmt.Errorf("failed to send overwrite confirmation: %w", err)
 		}
 
@@ -0,0 +1,327 @@
+package knowledge
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/obot-platform/nanobot/pkg/fswatch"
+)
+
+const (
+	// maxIndexDepth bounds how far below the workspace root files are indexed.
+	maxIndexDepth = 8
+	// maxIndexFileSize skips files too large to be worth embedding.
+	maxIndexFileSize = 1024 * 1024
+	// maxChunkChars is the target size of a chunk. Chunks break on lines, so
+	// a chunk is only larger than this when a single line is.
+	maxChunkChars = 1500
+	// chunkOverlapLines is how many lines each chunk repeats from the end of
+	// the previous one, so text spanning a boundary is still found.
+	chunkOverlapLines = 2
+	// embedBatchSize is the number of chunks embedded per request.
+	embedBatchSize = 64
+)
+
+// EmbedFunc returns one embedding per input, in input order.
+type EmbedFunc func(ctx context.Context, inputs []string) ([][]float32, error)
+
+type chunk struct {
+	StartLine int
+	EndLine   int
+	Text      string
+	vector    []float32
+}
+
+type indexedFile struct {
+	size    int64
+	modTime time.Time
+	chunks  []chunk
+}
+
+// Match is a chunk of a file that matched a search.
+type Match struct {
+	Path      string  `json:"path"`
+	StartLine int     `json:"startLine"`
+	EndLine   int     `json:"endLine"`
+	Score     float64 `json:"score"`
+	Text      string  `json:"text"`
+}
+
+// Index is an embeddings index of the text files under a directory. The
+// directory is walked and embedded on first use, after which a watcher marks
+// changed files so only those are embedded again on the next search.
+type Index struct {
+	rootDir string
+	embed   EmbedFunc
+
+	mu      sync.Mutex
+	loaded  bool
+	files   map[string]*indexedFile
+	dirty   map[string]struct{}
+	watcher *fswatch.Watcher
+}
+
+func NewIndex(rootDir string, embed EmbedFunc) *Index {
+	return &Index{
+		rootDir: rootDir,
+		embed:   embed,
+		files:   map[string]*indexedFile{},
+		dirty:   map[string]struct{}{},
+	}
+}
+
+// indexFilter skips hidden files and directories, which covers .git and
+// .nanobot, and dependency directories that are rarely worth searching.
+func indexFilter(relPath string, info os.FileInfo) bool {
+	if relPath == "." {
+		return true
+	}
+	base := filepath.Base(relPath)
+	if strings.HasPrefix(base, ".") {
+		return false
+	}
+	if info.IsDir() {
+		return base != "node_modules" && base != "vendor"
+	}
+	return info.Size() <= maxIndexFileSize
+}
+
+func (i *Index) handleEvents(events []fswatch.Event) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for _, event := range events {
+		i.dirty[event.Path] = struct{}{}
+		if event.Type == fswatch.EventDelete {
+			// A deleted directory only reports itself, so recheck its files.
+			prefix := event.Path + string(filepath.Separator)
+			for path := range i.files {
+				if strings.HasPrefix(path, prefix) {
+					i.dirty[path] = struct{}{}
+				}
+			}
+		}
+	}
+}
+
+// Close stops watching the directory.
+func (i *Index) Close() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.watcher != nil {
+		return i.watcher.Close()
+	}
+	return nil
+}
+
+// sync brings the index up to date. The lock is held throughout so concurrent
+// searches don't embed the same files twice.
+func (i *Index) sync(ctx context.Context) error {
+	if !i.loaded {
+		files, err := fswatch.Walk(i.rootDir, maxIndexDepth, indexFilter, 0)
+		if err != nil {
+			return fmt.Errorf("failed to list files: %w", err)
+		}
+		for _, file := range files {
+			i.dirty[file.Path] = struct{}{}
+		}
+
+		i.watcher = fswatch.NewWatcher(i.rootDir, maxIndexDepth, indexFilter, i.handleEvents)
+		if err := i.watcher.Start(); err != nil {
+			// Without a watcher the index still works, it just goes stale.
+			slog.Error("failed to watch files for the knowledge index", "dir", i.rootDir, "error", err)
+		}
+		i.loaded = true
+	}
+
+	var (
+		pending []string
+		chunks  []*chunk
+	)
+	for path := range i.dirty {
+		file, err := i.readFile(path)
+		if err != nil {
+			slog.Debug("skipping file for knowledge index", "path", path, "error", err)
+			delete(i.files, path)
+			delete(i.dirty, path)
+			continue
+		}
+		if file == nil {
+			delete(i.files, path)
+			delete(i.dirty, path)
+			continue
+		}
+		if old, ok := i.files[path]; ok && old.size == file.size && old.modTime.Equal(file.modTime) {
+			delete(i.dirty, path)
+			continue
+		}
+		i.files[path] = file
+		pending = append(pending, path)
+		for j := range file.chunks {
+			chunks = append(chunks, &file.chunks[j])
+		}
+	}
+
+	for start := 0; start < len(chunks); start += embedBatchSize {
+		batch := chunks[start:min(start+embedBatchSize, len(chunks))]
+		inputs := make([]string, len(batch))
+		for j, c := range batch {
+			inputs[j] = c.Text
+		}
+		vectors, err := i.embed(ctx, inputs)
+		if err == nil && len(vectors) != len(batch) {
+			err = fmt.Errorf("expected %d embeddings, got %d", len(batch), len(vectors))
+		}
+		if err != nil {
+			// Leave the files dirty so the next search retries them.
+			for _, path := range pending {
+				delete(i.files, path)
+			}
+			return fmt.Errorf("failed to embed files: %w", err)
+		}
+		for j, c := range batch {
+			c.vector = vectors[j]
+		}
+	}
+
+	for _, path := range pending {
+		delete(i.dirty, path)
+	}
+	return nil
+}
+
+// readFile reads and chunks a file. It returns nil when the file no longer
+// exists or isn't text.
+func (i *Index) readFile(path string) (*indexedFile, error) {
+	info, err := os.Stat(filepath.Join(i.rootDir, path))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if info.IsDir() || !indexFilter(path, info) {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(i.rootDir, path))
+	if err != nil {
+		return nil, err
+	}
+	if bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data) {
+		return nil, nil
+	}
+
+	return &indexedFile{
+		size:    info.Size(),
+		modTime: info.ModTime(),
+		chunks:  chunkText(string(data)),
+	}, nil
+}
+
+// chunkText splits text into chunks of whole lines of up to maxChunkChars,
+// each overlapping the previous by chunkOverlapLines. Line numbers are 1-based.
+func chunkText(text string) []chunk {
+	lines := strings.SplitAfter(text, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	var chunks []chunk
+	for start := 0; start < len(lines); {
+		end, size := start, 0
+		for end < len(lines) && (end == start || size+len(lines[end]) <= maxChunkChars) {
+			size += len(lines[end])
+			end++
+		}
+
+		chunkText := strings.Join(lines[start:end], "")
+		if strings.TrimSpace(chunkText) != "" {
+			chunks = append(chunks, chunk{
+				StartLine: start + 1,
+				EndLine:   end,
+				Text:      chunkText,
+			})
+		}
+
+		if end == len(lines) {
+			break
+		}
+		start = max(end-chunkOverlapLines, start+1)
+	}
+	return chunks
+}
+
+// Search returns the chunks most similar to the query, best first. Files
+// rejected by include are skipped.
+func (i *Index) Search(ctx context.Context, query string, limit int, include func(path string) bool) ([]Match, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if err := i.sync(ctx); err != nil {
+		return nil, err
+	}
+
+	vectors, err := i.embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("expected 1 embedding, got %d", len(vectors))
+	}
+	queryVector := vectors[0]
+
+	var matches []Match
+	for path, file := range i.files {
+		if include != nil && !include(path) {
+			continue
+		}
+		for _, c := range file.chunks {
+			matches = append(matches, Match{
+				Path:      filepath.ToSlash(path),
+				StartLine: c.StartLine,
+				EndLine:   c.EndLine,
+				Score:     cosineSimilarity(queryVector, c.vector),
+				Text:      c.Text,
+			})
+		}
+	}
+
+	slices.SortFunc(matches, func(a, b Match) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.Path, b.Path); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.StartLine, b.StartLine)
+	})
+	return matches[:min(limit, len(matches))], nil
+}
+
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,174 @@
+package knowledge
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+	"github.com/obot-platform/nanobot/pkg/version"
+)
+
+const (
+	// ServerName is the name the knowledge server is registered under.
+	ServerName = "nanobot.knowledge"
+	// permission is the agent permission that enables semantic search.
+	permission = "knowledge"
+
+	// sessionsDir holds the files of each session under the workspace. Only
+	// the current session's files are searched.
+	sessionsDir        = "sessions"
+	defaultSearchLimit = 5
+	maxSearchLimit     = 50
+)
+
+// Server answers conceptual queries over the workspace files using
+// embeddings, which grep can't do for large documents.
+type Server struct {
+	tools mcp.ServerTools
+	embed EmbedFunc
+
+	indexLock sync.Mutex
+	indexes   map[string]*Index
+}
+
+func NewServer(embed EmbedFunc) *Server {
+	s := &Server{
+		embed:   embed,
+		indexes: map[string]*Index{},
+	}
+
+	s.tools = mcp.NewServerTools(
+		mcp.NewServerTool("semanticSearch", "Search the text files in the workspace by meaning rather than exact words. Returns the passages most related to the query with their file paths and line ranges. Use grep for exact strings or identifiers.", s.semanticSearch),
+		mcp.NewServerTool("config", "Adds the semantic search tool to the agent config", s.config),
+	)
+
+	return s
+}
+
+func (s *Server) OnMessage(ctx context.Context, msg mcp.Message) {
+	switch msg.Method {
+	case "initialize":
+		mcp.Invoke(ctx, msg, s.initialize)
+	case "notifications/initialized":
+	case "notifications/cancelled":
+		mcp.HandleCancelled(ctx, msg)
+	case "tools/list":
+		mcp.Invoke(ctx, msg, s.tools.List)
+	case "tools/call":
+		mcp.Invoke(ctx, msg, s.tools.Call)
+	default:
+		msg.SendError(ctx, mcp.ErrRPCMethodNotFound.WithMessage("%v", msg.Method))
+	}
+}
+
+func (s *Server) initialize(_ context.Context, _ mcp.Message, params mcp.InitializeRequest) (*mcp.InitializeResult, error) {
+	return &mcp.InitializeResult{
+		ProtocolVersion: params.ProtocolVersion,
+		Capabilities:    mcp.ServerCapabilities{Tools: &mcp.ToolsServerCapability{}},
+		ServerInfo:      mcp.ServerInfo{Name: version.Name, Version: version.Get().String()},
+	}, nil
+}
+
+// index returns the index of the working directory, creating it on first use.
+func (s *Server) index() (*Index, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	s.indexLock.Lock()
+	defer s.indexLock.Unlock()
+
+	index, ok := s.indexes[cwd]
+	if !ok {
+		index = NewIndex(cwd, s.embed)
+		s.indexes[cwd] = index
+	}
+	return index, nil
+}
+
+type semanticSearchParams struct {
+	Query string `json:"query" jsonschema:"A description of what to find, in natural language"`
+	Path  string `json:"path,omitempty" jsonschema:"Only search files under this directory, relative to the workspace"`
+	Limit *int   `json:"limit,omitempty" jsonschema:"Maximum number of passages to return, defaults to 5"`
+}
+
+type semanticSearchResult struct {
+	Matches []Match `json:"matches"`
+}
+
+func (s *Server) semanticSearch(ctx context.Context, params semanticSearchParams) (*semanticSearchResult, error) {
+	query := strings.TrimSpace(params.Query)
+	if query == "" {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("query is required")
+	}
+
+	limit := defaultSearchLimit
+	if params.Limit != nil {
+		if *params.Limit <= 0 || *params.Limit > maxSearchLimit {
+			return nil, mcp.ErrRPCInvalidParams.WithMessage("limit must be between 1 and %d", maxSearchLimit)
+		}
+		limit = *params.Limit
+	}
+
+	var prefix string
+	if params.Path != "" {
+		prefix = filepath.Clean(params.Path)
+		if filepath.IsAbs(prefix) || prefix == ".." || strings.HasPrefix(prefix, ".."+string(filepath.Separator)) {
+			return nil, mcp.ErrRPCInvalidParams.WithMessage("path must be relative to the workspace")
+		}
+		if prefix == "." {
+			prefix = ""
+		}
+	}
+
+	index, err := s.index()
+	if err != nil {
+		return nil, err
+	}
+
+	sessionID, _ := types.GetSessionAndAccountID(ctx)
+	matches, err := index.Search(ctx, query, limit, searchFilter(sessionID, prefix))
+	if err != nil {
+		return nil, err
+	}
+
+	return &semanticSearchResult{
+		Matches: matches,
+	}, nil
+}
+
+// searchFilter includes the files under prefix, skipping the files of
+// sessions other than sessionID, which are private to them.
+func searchFilter(sessionID, prefix string) func(path string) bool {
+	return func(path string) bool {
+		if prefix != "" && path != prefix && !strings.HasPrefix(path, prefix+string(filepath.Separator)) {
+			return false
+		}
+		if rest, ok := strings.CutPrefix(path, sessionsDir+string(filepath.Separator)); ok {
+			return sessionID != "" && strings.HasPrefix(rest, sessionID+string(filepath.Separator))
+		}
+		return true
+	}
+}
+
+// config is an agent config hook that gives agents with the knowledge
+// permission the semantic search tool.
+func (s *Server) config(_ context.Context, params types.AgentConfigHook) (types.AgentConfigHook, error) {
+	agent := params.Agent
+	if agent == nil || agent.Name == "nanobot.summary" || (agent.Permissions != nil && !agent.Permissions.IsAllowed(permission)) {
+		return params, nil
+	}
+
+	agent.Tools = append(agent.Tools, ServerName+"/semanticSearch")
+	if params.MCPServers == nil {
+		params.MCPServers = make(map[string]types.AgentConfigHookMCPServer, 1)
+	}
+	params.MCPServers[ServerName] = types.AgentConfigHookMCPServer{}
+
+	return params, nil
+}
@@ -0,0 +1,167 @@
+package knowledge
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+)
+
+// vocabulary gives the fake embedder one dimension per concept, with a few
+// synonyms each, so that related words land close together.
+var vocabulary = [][]string{
+	{"invoice", "billing", "payment", "refund"},
+	{"deploy", "kubernetes", "helm", "release"},
+	{"cat", "kitten", "feline"},
+}
+
+// fakeEmbedder embeds text as counts of vocabulary concepts and counts the
+// chunks it embeds.
+type fakeEmbedder struct {
+	embedded atomic.Int64
+}
+
+func (f *fakeEmbedder) embed(_ context.Context, inputs []string) ([][]float32, error) {
+	f.embedded.Add(int64(len(inputs)))
+	result := make([][]float32, len(inputs))
+	for i, input := range inputs {
+		vector := make([]float32, len(vocabulary))
+		for _, word := range strings.Fields(strings.ToLower(input)) {
+			for dim, synonyms := range vocabulary {
+				for _, synonym := range synonyms {
+					if strings.Trim(word, ".,") == synonym {
+						vector[dim]++
+					}
+				}
+			}
+		}
+		result[i] = vector
+	}
+	return result, nil
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIndexSearchAndIncrementalUpdate(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "docs", "billing.md"), "How a refund is issued for an invoice.\n")
+	writeFile(t, filepath.Join(dir, "docs", "ops.md"), "We deploy with helm to kubernetes.\n")
+	writeFile(t, filepath.Join(dir, ".git", "config"), "billing billing billing\n")
+
+	embedder := &fakeEmbedder{}
+	index := NewIndex(dir, embedder.embed)
+	t.Cleanup(func() { _ = index.Close() })
+
+	matches, err := index.Search(t.Context(), "payment", 1, nil)
+	if err != nil {
+		t.Fatalf("Search() failed: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Path != "docs/billing.md" || matches[0].StartLine != 1 {
+		t.Fatalf("matches = %+v", matches)
+	}
+	// Two files and the query, the hidden file is not indexed.
+	if n := embedder.embedded.Load(); n != 3 {
+		t.Fatalf("embedded %d inputs, want 3", n)
+	}
+
+	// Unchanged files are not embedded again.
+	if _, err := index.Search(t.Context(), "payment", 1, nil); err != nil {
+		t.Fatal(err)
+	}
+	if n := embedder.embedded.Load(); n != 4 {
+		t.Fatalf("embedded %d inputs, want 4", n)
+	}
+
+	writeFile(t, filepath.Join(dir, "docs", "pets.md"), "The kitten sleeps.\n")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		matches, err = index.Search(t.Context(), "feline", 1, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(matches) == 1 && matches[0].Path == "docs/pets.md" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("new file was not indexed, matches = %+v", matches)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestChunkText(t *testing.T) {
+	line := strings.Repeat("x", 99) + "\n"
+	chunks := chunkText(strings.Repeat(line, 40))
+	if len(chunks) != 3 {
+		t.Fatalf("got %d chunks, want 3", len(chunks))
+	}
+	if chunks[0].StartLine != 1 || chunks[0].EndLine != 15 || chunks[1].StartLine != 14 || chunks[2].EndLine != 40 {
+		t.Fatalf("chunks = %d-%d, %d-%d, %d-%d", chunks[0].StartLine, chunks[0].EndLine,
+			chunks[1].StartLine, chunks[1].EndLine, chunks[2].StartLine, chunks[2].EndLine)
+	}
+
+	// A single line longer than a chunk is kept whole.
+	if chunks := chunkText(strings.Repeat("y", maxChunkChars*2)); len(chunks) != 1 {
+		t.Fatalf("got %d chunks, want 1", len(chunks))
+	}
+}
+
+func TestSearchFilter(t *testing.T) {
+	sep := string(filepath.Separator)
+	for _, tt := range []struct {
+		sessionID, prefix, path string
+		want                    bool
+	}{
+		{"abc", "", "docs" + sep + "a.md", true},
+		{"abc", "", sessionsDir + sep + "abc" + sep + "a.md", true},
+		{"abc", "", sessionsDir + sep + "xyz" + sep + "a.md", false},
+		{"", "", sessionsDir + sep + "xyz" + sep + "a.md", false},
+		{"abc", "docs", "docs" + sep + "a.md", true},
+		{"abc", "docs", "docsets" + sep + "a.md", false},
+	} {
+		if got := searchFilter(tt.sessionID, tt.prefix)(tt.path); got != tt.want {
+			t.Errorf("searchFilter(%q, %q)(%q) = %v, want %v", tt.sessionID, tt.prefix, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestSemanticSearch(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	writeFile(t, filepath.Join(dir, "docs", "billing.md"), "Refund the invoice.\n")
+	writeFile(t, filepath.Join(dir, sessionsDir, "other-session", "notes.md"), "Refund the invoice today.\n")
+
+	embedder := &fakeEmbedder{}
+	s := NewServer(embedder.embed)
+	t.Cleanup(func() {
+		for _, index := range s.indexes {
+			_ = index.Close()
+		}
+	})
+
+	ctx := mcp.WithSession(context.Background(), mcp.NewEmptySession(context.Background()))
+	result, err := s.semanticSearch(ctx, semanticSearchParams{Query: "billing"})
+	if err != nil {
+		t.Fatalf("semanticSearch() failed: %v", err)
+	}
+	if len(result.Matches) != 1 || result.Matches[0].Path != "docs/billing.md" {
+		t.Fatalf("matches = %+v", result.Matches)
+	}
+
+	if _, err := s.semanticSearch(ctx, semanticSearchParams{Query: "billing", Path: "../etc"}); err == nil {
+		t.Fatal("expected a path outside the workspace to be rejected")
+	}
+}
@@ -0,0 +1,260 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/session"
+	"github.com/obot-platform/nanobot/pkg/types"
+	"github.com/obot-platform/nanobot/pkg/version"
+	"gorm.io/gorm"
+)
+
+const (
+	// ServerName is the name the memory server is registered under.
+	ServerName = "nanobot.memory"
+	// permission is the agent permission that enables memory.
+	permission = "memory"
+
+	// maxPromptMemories bounds the memories injected into agent instructions.
+	maxPromptMemories  = 20
+	defaultRecallLimit = 10
+)
+
+var agentTools = []string{"rememberFact", "recallFacts", "forgetFact"}
+
+// Server stores facts that agents remember across sessions, scoped to the
+// account of the session.
+type Server struct {
+	tools mcp.ServerTools
+	db    *session.Store
+}
+
+func NewServer(db *session.Store) *Server {
+	s := &Server{
+		db: db,
+	}
+
+	s.tools = mcp.NewServerTools(
+		mcp.NewServerTool("rememberFact", "Remember a fact about the user or their work so it is available in future conversations. Store one self-contained fact per call.", s.rememberFact),
+		mcp.NewServerTool("recallFacts", "Recall remembered facts, most relevant to the query first. Use an empty query to list the most recent facts.", s.recallFacts),
+		mcp.NewServerTool("forgetFact", "Forget a remembered fact by its ID", s.forgetFact),
+		mcp.NewServerTool("config", "Adds memory tools and remembered facts to the agent config", s.config),
+	)
+
+	return s
+}
+
+func (s *Server) OnMessage(ctx context.Context, msg mcp.Message) {
+	switch msg.Method {
+	case "initialize":
+		mcp.Invoke(ctx, msg, s.initialize)
+	case "notifications/initialized":
+	case "notifications/cancelled":
+		mcp.HandleCancelled(ctx, msg)
+	case "tools/list":
+		mcp.Invoke(ctx, msg, s.tools.List)
+	case "tools/call":
+		mcp.Invoke(ctx, msg, s.tools.Call)
+	default:
+		msg.SendError(ctx, mcp.ErrRPCMethodNotFound.WithMessage("%v", msg.Method))
+	}
+}
+
+func (s *Server) initialize(_ context.Context, _ mcp.Message, params mcp.InitializeRequest) (*mcp.InitializeResult, error) {
+	return &mcp.InitializeResult{
+		ProtocolVersion: params.ProtocolVersion,
+		Capabilities:    mcp.ServerCapabilities{Tools: &mcp.ToolsServerCapability{}},
+		ServerInfo:      mcp.ServerInfo{Name: version.Name, Version: version.Get().String()},
+	}, nil
+}
+
+// fact is the agent-facing JSON shape for a memory.
+type fact struct {
+	ID        uint      `json:"id"`
+	Fact      string    `json:"fact"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func toFact(memory session.Memory) fact {
+	return fact{
+		ID:        memory.ID,
+		Fact:      memory.Fact,
+		CreatedAt: memory.CreatedAt,
+	}
+}
+
+type rememberFactParams struct {
+	Fact string `json:"fact" jsonschema:"The fact to remember"`
+}
+
+func (s *Server) rememberFact(ctx context.Context, params rememberFactParams) (*fact, error) {
+	text := strings.TrimSpace(params.Fact)
+	if text == "" {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("fact is required")
+	}
+
+	_, accountID := types.GetSessionAndAccountID(ctx)
+	memories, err := s.db.ListMemories(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	for _, memory := range memories {
+		if strings.EqualFold(memory.Fact, text) {
+			result := toFact(memory)
+			return &result, nil
+		}
+	}
+
+	memory := session.Memory{
+		AccountID: accountID,
+		Fact:      text,
+	}
+	if err := s.db.CreateMemory(ctx, &memory); err != nil {
+		return nil, fmt.Errorf("failed to remember fact: %w", err)
+	}
+
+	result := toFact(memory)
+	return &result, nil
+}
+
+type recallFactsParams struct {
+	Query string `json:"query,omitempty" jsonschema:"Keywords to match against remembered facts"`
+	Limit *int   `json:"limit,omitempty" jsonschema:"Maximum number of facts to return, defaults to 10"`
+}
+
+type recallFactsResult struct {
+	Facts []fact `json:"facts"`
+}
+
+func (s *Server) recallFacts(ctx context.Context, params recallFactsParams) (*recallFactsResult, error) {
+	limit := defaultRecallLimit
+	if params.Limit != nil {
+		if *params.Limit <= 0 {
+			return nil, mcp.ErrRPCInvalidParams.WithMessage("limit must be greater than zero")
+		}
+		limit = *params.Limit
+	}
+
+	_, accountID := types.GetSessionAndAccountID(ctx)
+	memories, err := s.db.ListMemories(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	memories = rank(memories, params.Query)
+	result := &recallFactsResult{
+		Facts: make([]fact, 0, min(limit, len(memories))),
+	}
+	for _, memory := range memories[:min(limit, len(memories))] {
+		result.Facts = append(result.Facts, toFact(memory))
+	}
+	return result, nil
+}
+
+// rank orders memories by the number of query keywords they contain,
+// dropping those that match none. Memories are newest-first within a score,
+// and all memories are kept, newest-first, for an empty query.
+func rank(memories []session.Memory, query string) []session.Memory {
+	keywords := strings.Fields(strings.ToLower(query))
+	if len(keywords) == 0 {
+		return memories
+	}
+
+	type scored struct {
+		memory session.Memory
+		score  int
+	}
+	var matches []scored
+	for _, memory := range memories {
+		text := strings.ToLower(memory.Fact)
+		var score int
+		for _, keyword := range keywords {
+			if strings.Contains(text, keyword) {
+				score++
+			}
+		}
+		if score > 0 {
+			matches = append(matches, scored{memory: memory, score: score})
+		}
+	}
+
+	// Stable so that ties keep the newest-first order from the store.
+	slices.SortStableFunc(matches, func(a, b scored) int {
+		return cmp.Compare(b.score, a.score)
+	})
+
+	result := make([]session.Memory, 0, len(matches))
+	for _, match := range matches {
+		result = append(result, match.memory)
+	}
+	return result
+}
+
+type forgetFactParams struct {
+	ID uint `json:"id" jsonschema:"The ID of the fact to forget"`
+}
+
+func (s *Server) forgetFact(ctx context.Context, params forgetFactParams) (string, error) {
+	if params.ID == 0 {
+		return "", mcp.ErrRPCInvalidParams.WithMessage("id is required")
+	}
+
+	_, accountID := types.GetSessionAndAccountID(ctx)
+	if err := s.db.DeleteMemory(ctx, accountID, params.ID); errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", mcp.ErrRPCInvalidParams.WithMessage("fact %d not found", params.ID)
+	} else if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("fact %d forgotten", params.ID), nil
+}
+
+// config is an agent config hook that gives agents with the memory permission
+// the memory tools and the account's most recent memories.
+func (s *Server) config(ctx context.Context, params types.AgentConfigHook) (types.AgentConfigHook, error) {
+	agent := params.Agent
+	if agent == nil || agent.Name == "nanobot.summary" || (agent.Permissions != nil && !agent.Permissions.IsAllowed(permission)) {
+		return params, nil
+	}
+
+	for _, tool := range agentTools {
+		agent.Tools = append(agent.Tools, ServerName+"/"+tool)
+	}
+	if params.MCPServers == nil {
+		params.MCPServers = make(map[string]types.AgentConfigHookMCPServer, 1)
+	}
+	params.MCPServers[ServerName] = types.AgentConfigHookMCPServer{}
+
+	_, accountID := types.GetSessionAndAccountID(ctx)
+	memories, err := s.db.ListMemories(ctx, accountID)
+	if err != nil {
+		// Memories are a convenience, so don't fail the hook.
+		slog.Error("failed to list memories", "error", err)
+		return params, nil
+	}
+
+	var prompt strings.Builder
+	prompt.WriteString("\n\n## Memory\n\n")
+	prompt.WriteString("You can remember facts across conversations. ")
+	prompt.WriteString("Call rememberFact when you learn something durable about the user, their preferences, or their work, ")
+	prompt.WriteString("recallFacts to look up older facts, and forgetFact when a fact is wrong or outdated.\n")
+	if len(memories) > 0 {
+		prompt.WriteString("\nThings you remember, most recent first:\n\n")
+		for _, memory := range memories[:min(maxPromptMemories, len(memories))] {
+			fmt.Fprintf(&prompt, "- [%d] %s\n", memory.ID, memory.Fact)
+		}
+		if len(memories) > maxPromptMemories {
+			fmt.Fprintf(&prompt, "\n%d older facts are not shown, use recallFacts to search them.\n", len(memories)-maxPromptMemories)
+		}
+	}
+	agent.Instructions.Instructions += prompt.String()
+
+	return params, nil
+}
@@ -0,0 +1,117 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/session"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+func testServer(t *testing.T) *Server {
+	t.Helper()
+
+	store, err := session.NewStoreFromDSN(fmt.Sprintf("sqlite:file:%s?mode=memory&cache=shared",
+		strings.NewReplacer("/", "-", " ", "-").Replace(t.Name())))
+	if err != nil {
+		t.Fatalf("failed to create session store: %v", err)
+	}
+	return NewServer(store)
+}
+
+func accountContext(accountID string) context.Context {
+	ctx := context.Background()
+	session := mcp.NewEmptySession(ctx)
+	session.Set(types.AccountIDSessionKey, accountID)
+	return mcp.WithSession(ctx, session)
+}
+
+func TestRememberRecallForget(t *testing.T) {
+	srv := testServer(t)
+	alice := accountContext("alice")
+
+	for _, text := range []string{"Prefers Go over Python", "Works on the billing service", "Deploys with Helm"} {
+		if _, err := srv.rememberFact(alice, rememberFactParams{Fact: text}); err != nil {
+			t.Fatalf("rememberFact(%q): %v", text, err)
+		}
+	}
+
+	// Remembering the same fact again returns the existing one.
+	dup, err := srv.rememberFact(alice, rememberFactParams{Fact: "prefers go over python"})
+	if err != nil {
+		t.Fatalf("rememberFact duplicate: %v", err)
+	}
+
+	all, err := srv.recallFacts(alice, recallFactsParams{})
+	if err != nil {
+		t.Fatalf("recallFacts: %v", err)
+	}
+	if len(all.Facts) != 3 {
+		t.Fatalf("recalled %d facts, want 3", len(all.Facts))
+	}
+
+	matched, err := srv.recallFacts(alice, recallFactsParams{Query: "billing go"})
+	if err != nil {
+		t.Fatalf("recallFacts query: %v", err)
+	}
+	if len(matched.Facts) != 2 {
+		t.Fatalf("recalled %v, want the two matching facts", matched.Facts)
+	}
+
+	// Facts are scoped to the account.
+	bob, err := srv.recallFacts(accountContext("bob"), recallFactsParams{})
+	if err != nil {
+		t.Fatalf("recallFacts bob: %v", err)
+	}
+	if len(bob.Facts) != 0 {
+		t.Fatalf("bob recalled %v", bob.Facts)
+	}
+	if _, err := srv.forgetFact(accountContext("bob"), forgetFactParams{ID: dup.ID}); err == nil {
+		t.Fatal("expected bob to be unable to forget alice's fact")
+	}
+
+	if _, err := srv.forgetFact(alice, forgetFactParams{ID: dup.ID}); err != nil {
+		t.Fatalf("forgetFact: %v", err)
+	}
+	all, err = srv.recallFacts(alice, recallFactsParams{})
+	if err != nil {
+		t.Fatalf("recallFacts: %v", err)
+	}
+	if len(all.Facts) != 2 {
+		t.Fatalf("recalled %d facts after forgetting, want 2", len(all.Facts))
+	}
+}
+
+func TestConfigInjectsMemories(t *testing.T) {
+	srv := testServer(t)
+	ctx := accountContext("alice")
+
+	if _, err := srv.rememberFact(ctx, rememberFactParams{Fact: "Prefers concise answers"}); err != nil {
+		t.Fatalf("rememberFact: %v", err)
+	}
+
+	result, err := srv.config(ctx, types.AgentConfigHook{Agent: &types.HookAgent{Name: "agent"}})
+	if err != nil {
+		t.Fatalf("config: %v", err)
+	}
+	if !strings.Contains(result.Agent.Instructions.Instructions, "Prefers concise answers") {
+		t.Fatalf("instructions = %q", result.Agent.Instructions.Instructions)
+	}
+	if _, ok := result.MCPServers[ServerName]; !ok {
+		t.Fatal("expected memory server to be added")
+	}
+	if len(result.Agent.Tools) != len(agentTools) {
+		t.Fatalf("tools = %v", result.Agent.Tools)
+	}
+
+	denied, err := srv.config(ctx, types.AgentConfigHook{Agent: &types.HookAgent{Name: "agent", Permissions: types.DenyAllPermissions()}})
+	if err != nil {
+		t.Fatalf("config: %v", err)
+	}
+	if len(denied.Agent.Tools) != 0 || denied.Agent.Instructions.Instructions != "" {
+		t.Fatalf("expected no memory for an agent without the memory permission, got %+v", denied.Agent)
+	}
+}
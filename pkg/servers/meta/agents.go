@@ -0,0 +1,200 @@
+package meta
+
+import (
+	"context"
+	"maps"
+	"regexp"
+	"slices"
+
+	"github.com/obot-platform/nanobot/pkg/config"
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+var agentNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+type createAgentParams struct {
+	Name         string   `json:"name" jsonschema:"ID of the agent, used to select it and to refer to it from other agents. Letters, digits, '.', '_' and '-'."`
+	DisplayName  string   `json:"displayName,omitempty" jsonschema:"Name of the agent shown to users, the ID if not set"`
+	Description  string   `json:"description,omitempty" jsonschema:"What the agent is for"`
+	Instructions string   `json:"instructions" jsonschema:"System prompt of the agent"`
+	Model        string   `json:"model,omitempty" jsonschema:"Model of the agent, the default model if not set"`
+	Tools        []string `json:"tools,omitempty" jsonschema:"MCP servers, or server/tool names, the agent can use"`
+}
+
+type updateAgentParams struct {
+	Name         string    `json:"name" jsonschema:"ID of the agent to update"`
+	DisplayName  *string   `json:"displayName,omitempty" jsonschema:"Name of the agent shown to users"`
+	Description  *string   `json:"description,omitempty" jsonschema:"What the agent is for"`
+	Instructions *string   `json:"instructions,omitempty" jsonschema:"System prompt of the agent"`
+	Model        *string   `json:"model,omitempty" jsonschema:"Model of the agent, empty for the default model"`
+	Tools        *[]string `json:"tools,omitempty" jsonschema:"MCP servers, or server/tool names, the agent can use. Replaces the tools the agent had."`
+}
+
+func (s *Server) createAgent(ctx context.Context, data createAgentParams) (*types.AgentDisplay, error) {
+	if !agentNamePattern.MatchString(data.Name) {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("invalid agent name %q, must be letters, digits, '.', '_' and '-'", data.Name)
+	}
+	if data.Instructions == "" {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("instructions are required")
+	}
+
+	c := types.ConfigFromContext(ctx)
+	if _, ok := c.Agents[data.Name]; ok {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("agent %s already exists", data.Name)
+	}
+
+	agent := types.Agent{}
+	agent.Name = data.DisplayName
+	agent.Description = data.Description
+	agent.Instructions.Instructions = data.Instructions
+	if data.Model != "" {
+		agent.Model = types.AgentModel{data.Model}
+	}
+	agent.Tools = data.Tools
+
+	return s.saveAgent(ctx, c, data.Name, agent)
+}
+
+func (s *Server) updateAgent(ctx context.Context, data updateAgentParams) (*types.AgentDisplay, error) {
+	c := types.ConfigFromContext(ctx)
+	agent, ok := c.Agents[data.Name]
+	if !ok {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("agent %s not found", data.Name)
+	}
+
+	if data.DisplayName != nil {
+		agent.Name = *data.DisplayName
+	}
+	if data.Description != nil {
+		agent.Description = *data.Description
+	}
+	if data.Instructions != nil {
+		if *data.Instructions == "" {
+			return nil, mcp.ErrRPCInvalidParams.WithMessage("instructions can't be empty")
+		}
+		agent.Instructions = types.DynamicInstructions{Instructions: *data.Instructions}
+	}
+	if data.Model != nil {
+		agent.Model = nil
+		if *data.Model != "" {
+			agent.Model = types.AgentModel{*data.Model}
+		}
+	}
+	if data.Tools != nil {
+		agent.Tools = *data.Tools
+	}
+
+	return s.saveAgent(ctx, c, data.Name, agent)
+}
+
+// requireAdmin checks that the account of the session is an admin. The
+// overlay agents are kept in is shared by all accounts, so only admins can
+// change it.
+func (s *Server) requireAdmin(ctx context.Context) error {
+	manager, accountID, err := s.getManagerAndAccountID(mcp.SessionFromContext(ctx))
+	if err != nil {
+		return err
+	}
+	if !manager.IsAdmin(accountID) {
+		return mcp.ErrRPCInvalidRequest.WithMessage("only admins can change agents")
+	}
+	return nil
+}
+
+// saveAgent validates the config with the agent and keeps the agent in the
+// overlay, replacing how it is defined in the config, if it is.
+func (s *Server) saveAgent(ctx context.Context, c types.Config, name string, agent types.Agent) (*types.AgentDisplay, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if s.configDir == "" {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("there is no config directory to keep agents in")
+	}
+
+	c.Agents = maps.Clone(c.Agents)
+	if c.Agents == nil {
+		c.Agents = map[string]types.Agent{}
+	}
+	c.Agents[name] = agent
+	if !slices.Contains(c.Publish.Entrypoint, name) {
+		c.Publish.Entrypoint = append(slices.Clone(c.Publish.Entrypoint), name)
+	}
+	if err := c.Validate(true); err != nil {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("invalid agent %s: %v", name, err)
+	}
+
+	err := config.UpdateOverlay(s.configDir, func(overlay *types.Config) error {
+		if overlay.Agents == nil {
+			overlay.Agents = map[string]types.Agent{}
+		}
+		overlay.Agents[name] = agent
+		if overlay.MergeStrategies == nil {
+			overlay.MergeStrategies = map[string]types.MergeStrategy{}
+		}
+		overlay.MergeStrategies[agentPointer(name)] = types.MergeStrategyReplace
+		if !slices.Contains(overlay.Publish.Entrypoint, name) {
+			overlay.Publish.Entrypoint = append(overlay.Publish.Entrypoint, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.data.SetConfig(ctx, c)
+
+	display := agent.ToDisplay(name)
+	return &display, nil
+}
+
+func (s *Server) deleteAgent(ctx context.Context, data struct {
+	Name string `json:"name" jsonschema:"ID of the agent to delete"`
+}) (*types.AgentList, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if s.configDir == "" {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("there is no config directory to keep agents in")
+	}
+
+	overlay, err := config.ReadOverlay(s.configDir)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := overlay.Agents[data.Name]; !ok {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("agent %s was not created or updated at runtime and can only be removed from the config", data.Name)
+	}
+
+	c := types.ConfigFromContext(ctx)
+	c.Agents = maps.Clone(c.Agents)
+	delete(c.Agents, data.Name)
+	c.Publish.Entrypoint = slices.DeleteFunc(slices.Clone(c.Publish.Entrypoint), func(entrypoint string) bool {
+		return entrypoint == data.Name
+	})
+	if err := c.Validate(true); err != nil {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("can't delete agent %s: %v", data.Name, err)
+	}
+
+	err = config.UpdateOverlay(s.configDir, func(overlay *types.Config) error {
+		delete(overlay.Agents, data.Name)
+		delete(overlay.MergeStrategies, agentPointer(data.Name))
+		overlay.Publish.Entrypoint = slices.DeleteFunc(overlay.Publish.Entrypoint, func(entrypoint string) bool {
+			return entrypoint == data.Name
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// An agent that was only updated at runtime goes back to how the config
+	// defines it when the config is next loaded.
+	s.data.SetConfig(ctx, c)
+
+	return s.listAgents(ctx, struct{}{})
+}
+
+func agentPointer(name string) string {
+	return "/agents/" + name
+}
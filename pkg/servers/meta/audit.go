@@ -0,0 +1,78 @@
+package meta
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/session"
+)
+
+// auditToolCallsURI is the resource with the tool call audit log, filtered by
+// the session, tool, since, errors, and limit query parameters, as in
+// audit:///tool-calls?tool=bash&since=24h. Audit admins read the records of
+// all accounts, other accounts only their own.
+const auditToolCallsURI = "audit:///tool-calls"
+
+func (s *Server) readAuditResource(ctx context.Context, uri string) (*mcp.ReadResourceResult, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("invalid audit URI: %v", err)
+	}
+	query, err := parseAuditQuery(u.Query())
+	if err != nil {
+		return nil, err
+	}
+
+	manager, accountID, err := s.getManagerAndAccountID(mcp.SessionFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := manager.ToolCallAudits(ctx, accountID, query)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(records)
+	if err != nil {
+		return nil, err
+	}
+
+	text := string(data)
+	return &mcp.ReadResourceResult{
+		Contents: []mcp.ResourceContent{
+			{
+				URI:      uri,
+				Name:     "tool-calls",
+				MIMEType: "application/json",
+				Text:     &text,
+			},
+		},
+	}, nil
+}
+
+func parseAuditQuery(values url.Values) (session.ToolCallAuditQuery, error) {
+	query := session.ToolCallAuditQuery{
+		SessionID:  values.Get("session"),
+		Tool:       values.Get("tool"),
+		ErrorsOnly: values.Get("errors") == "true",
+	}
+	if since := values.Get("since"); since != "" {
+		d, err := time.ParseDuration(since)
+		if err != nil {
+			return query, mcp.ErrRPCInvalidParams.WithMessage("invalid since duration %q: %v", since, err)
+		}
+		query.Since = time.Now().Add(-d)
+	}
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return query, mcp.ErrRPCInvalidParams.WithMessage("invalid limit %q: %v", limit, err)
+		}
+		query.Limit = n
+	}
+	return query, nil
+}
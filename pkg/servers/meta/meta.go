@@ -3,6 +3,7 @@ package meta
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 
 	"github.com/obot-platform/nanobot/pkg/fswatch"
 	"github.com/obot-platform/nanobot/pkg/mcp"
@@ -18,8 +19,9 @@ type Server struct {
 	configDir       string
 	subscriptions   *fswatch.SubscriptionManager
 	workflowWatcher *fswatch.Watcher
-	skillWatcher    *fswatch.Watcher
+	skillWatchers   []*fswatch.Watcher
 	sessionsWatcher *fswatch.Watcher
+	sessionsIndex   atomic.Pointer[fswatch.Index]
 	watcherOnce     sync.Once
 	watcherInitErr  error
 }
@@ -34,7 +36,14 @@ func NewServer(data *sessiondata.Data, configDir string) *Server {
 	s.tools = mcp.NewServerTools(
 		mcp.NewServerTool("list_chats", "Returns all previous chat threads", s.listChats),
 		mcp.NewServerTool("update_chat", "Update fields of a give chat thread", s.updateChat),
+		mcp.NewServerTool("fork_chat", "Forks a chat thread, the current one unless chatId is set, into a new thread with a copy of its messages and environment, and optionally its files", s.forkChat),
+		mcp.NewServerTool("export_chat", "Exports a chat thread, the current one unless chatId is set, with its messages, tool results, and files as a gzipped tar bundle that import_chat recreates it from", s.exportChat),
+		mcp.NewServerTool("import_chat", "Imports a chat thread from a base64 encoded bundle made by export_chat or nanobot session export", s.importChat),
+		mcp.NewServerTool("searchSessions", "Searches the messages and tool results of all chat threads for all the words of the query, returning the matching threads with when the messages were sent and snippets of the matches", s.searchSessions),
 		mcp.NewServerTool("list_agents", "List available agents and their meta data", s.listAgents),
+		mcp.NewServerTool("createAgent", "Creates an agent with instructions, a model, and tools. The agent is kept in the config overlay and can be selected right away. Only admins can create agents", s.createAgent),
+		mcp.NewServerTool("updateAgent", "Updates the fields that are set of an agent, which is kept in the config overlay from then on. Only admins can update agents", s.updateAgent),
+		mcp.NewServerTool("deleteAgent", "Deletes an agent that was created at runtime, or undoes the updates made at runtime to an agent of the config. Only admins can delete agents", s.deleteAgent),
 	)
 
 	return s
@@ -48,8 +57,8 @@ func (s *Server) Close() error {
 			errs = append(errs, err)
 		}
 	}
-	if s.skillWatcher != nil {
-		if err := s.skillWatcher.Close(); err != nil {
+	for _, w := range s.skillWatchers {
+		if err := w.Close(); err != nil {
 			errs = append(errs, err)
 		}
 	}
@@ -8,12 +8,14 @@ import (
 	"mime"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"log/slog"
 
 	"github.com/obot-platform/nanobot/pkg/fileuri"
 	"github.com/obot-platform/nanobot/pkg/fswatch"
+	"github.com/obot-platform/nanobot/pkg/ignore"
 	"github.com/obot-platform/nanobot/pkg/mcp"
 	"github.com/obot-platform/nanobot/pkg/skillformat"
 	"github.com/obot-platform/nanobot/pkg/types"
@@ -22,11 +24,12 @@ import (
 const sessionsDir = "sessions"
 
 // resourcesList returns all resources (workflows + cross-session files).
-func (s *Server) resourcesList(ctx context.Context, _ mcp.Message, _ mcp.ListResourcesRequest) (*mcp.ListResourcesResult, error) {
+func (s *Server) resourcesList(ctx context.Context, _ mcp.Message, request mcp.ListResourcesRequest) (*mcp.ListResourcesResult, error) {
 	var resources []mcp.Resource
 
 	// Add workflow resources
-	workflowResources, err := s.listWorkflowResources(ctx)
+	category, _ := request.Meta[types.WorkflowCategoryMetaKey].(string)
+	workflowResources, err := s.listWorkflowResources(ctx, category)
 	if err != nil {
 		slog.Error("failed to list workflow resources", "error", err)
 	} else {
@@ -46,10 +49,18 @@ func (s *Server) resourcesList(ctx context.Context, _ mcp.Message, _ mcp.ListRes
 	if err != nil {
 		slog.Error("failed to list cross-session file resources", "error", err)
 	} else {
+		// Files are walked in parallel, sort them so pages are stable.
+		slices.SortFunc(fileResources, func(a, b mcp.Resource) int {
+			return strings.Compare(a.URI, b.URI)
+		})
 		resources = append(resources, fileResources...)
 	}
 
-	return &mcp.ListResourcesResult{Resources: resources}, nil
+	resources, next, err := mcp.Paginate(resources, request.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	return &mcp.ListResourcesResult{Resources: resources, NextCursor: next}, nil
 }
 
 // resourcesRead reads a resource by URI.
@@ -60,6 +71,8 @@ func (s *Server) resourcesRead(ctx context.Context, _ mcp.Message, request mcp.R
 		return s.readSkillResource(ctx, request.URI)
 	} else if strings.HasPrefix(request.URI, "file:///") {
 		return s.readFileResource(ctx, request.URI)
+	} else if strings.HasPrefix(request.URI, auditToolCallsURI) {
+		return s.readAuditResource(ctx, request.URI)
 	}
 	return nil, mcp.ErrRPCInvalidParams.WithMessage("unsupported resource URI: %s", request.URI)
 }
@@ -70,12 +83,11 @@ func (s *Server) resourcesSubscribe(ctx context.Context, msg mcp.Message, reques
 
 	// Validate the URI
 	if strings.HasPrefix(request.URI, "workflow:///") {
-		workflowName := strings.TrimPrefix(request.URI, "workflow:///")
-		workflowName = strings.TrimSuffix(workflowName, ".md")
-		if workflowName == "" {
-			return nil, mcp.ErrRPCInvalidParams.WithMessage("workflow name is required")
+		workflowName, err := parseWorkflowURI(request.URI)
+		if err != nil {
+			return nil, err
 		}
-		workflowPath := filepath.Join(".", skillformat.WorkflowsDir, workflowName, skillformat.SkillMainFile)
+		workflowPath := filepath.Join(".", skillformat.WorkflowsDir, filepath.FromSlash(workflowName), skillformat.SkillMainFile)
 		if _, err := os.Stat(workflowPath); os.IsNotExist(err) {
 			return nil, mcp.ErrRPCInvalidParams.WithMessage("workflow not found: %s", request.URI)
 		}
@@ -85,11 +97,11 @@ func (s *Server) resourcesSubscribe(ctx context.Context, msg mcp.Message, reques
 		if skillName == "" {
 			return nil, mcp.ErrRPCInvalidParams.WithMessage("skill name is required")
 		}
-		if s.configDir == "" {
-			return nil, mcp.ErrRPCInvalidParams.WithMessage("skills not available: no config directory")
+		skillDir, ok := skillformat.FindSkillDir(skillformat.SearchPaths(s.configDir), skillName)
+		if !ok {
+			return nil, mcp.ErrRPCInvalidParams.WithMessage("skill not found: %s", request.URI)
 		}
-		skillPath := filepath.Join(s.configDir, "skills", skillName, skillformat.SkillMainFile)
-		if _, err := os.Stat(skillPath); os.IsNotExist(err) {
+		if _, err := os.Stat(filepath.Join(skillDir, skillformat.SkillMainFile)); os.IsNotExist(err) {
 			return nil, mcp.ErrRPCInvalidParams.WithMessage("skill not found: %s", request.URI)
 		}
 	} else if strings.HasPrefix(request.URI, "file:///") {
@@ -103,7 +115,7 @@ func (s *Server) resourcesSubscribe(ctx context.Context, msg mcp.Message, reques
 			if _, statErr := os.Stat(filepath.Join(".", cleanPath)); os.IsNotExist(statErr) {
 				return nil, mcp.ErrRPCInvalidParams.WithMessage("file not found: %s", request.URI)
 			}
-		} else if s.configDir != "" && strings.HasPrefix(cleanPath, filepath.Clean(s.configDir)+string(filepath.Separator)) {
+		} else if (s.configDir != "" && strings.HasPrefix(cleanPath, filepath.Clean(s.configDir)+string(filepath.Separator))) || s.isSkillFile(cleanPath) {
 			// Skill supporting file — verify it exists
 			if _, statErr := os.Stat(cleanPath); os.IsNotExist(statErr) {
 				return nil, mcp.ErrRPCInvalidParams.WithMessage("file not found: %s", request.URI)
@@ -129,93 +141,115 @@ func (s *Server) resourcesUnsubscribe(ctx context.Context, _ mcp.Message, reques
 	return &mcp.UnsubscribeResult{}, nil
 }
 
-// listWorkflowResources reads the workflows/ directory and returns workflow resources.
-func (s *Server) listWorkflowResources(ctx context.Context) ([]mcp.Resource, error) {
+// listWorkflowResources walks the workflows/ directory and returns workflow
+// resources, only those in category and its subcategories if it is set.
+func (s *Server) listWorkflowResources(ctx context.Context, category string) ([]mcp.Resource, error) {
 	workflowsPath := filepath.Join(".", skillformat.WorkflowsDir)
 
-	entries, err := os.ReadDir(workflowsPath)
-	if err != nil {
+	if _, err := os.Stat(workflowsPath); err != nil {
 		// Directory doesn't exist - return empty list
 		return nil, nil
 	}
 
 	var resources []mcp.Resource
-	for _, entry := range entries {
-		if !entry.IsDir() {
+	for _, workflow := range skillformat.FindWorkflows(workflowsPath) {
+		if !skillformat.InCategory(workflow.Category, category) {
 			continue
 		}
 
-		name := entry.Name()
-		workflowDir := filepath.Join(workflowsPath, name)
-
 		// Read the main workflow file from the subdirectory
-		contentBytes, err := os.ReadFile(filepath.Join(workflowDir, skillformat.SkillMainFile))
-		if err == nil {
-			fm, _, err := skillformat.ParseFrontmatter(string(contentBytes))
-			if err != nil {
-				slog.Debug("failed to parse frontmatter for workflow", "workflow", entry.Name(), "error", err)
-			}
+		contentBytes, err := os.ReadFile(filepath.Join(workflow.Dir, skillformat.SkillMainFile))
+		if err != nil {
+			continue
+		}
 
-			resourceMeta := skillformat.FrontmatterToMeta(fm)
+		fm, _, err := skillformat.ParseFrontmatter(string(contentBytes))
+		if err != nil {
+			slog.Debug("failed to parse frontmatter for workflow", "workflow", workflow.Name, "error", err)
+		}
 
-			res := mcp.Resource{
-				URI:         fmt.Sprintf("workflow:///%s", name),
-				Name:        name,
-				Description: fm.Description,
-				MimeType:    "text/markdown",
-			}
-			if len(resourceMeta) > 0 {
-				res.Meta = resourceMeta
-			}
+		resourceMeta := skillformat.FrontmatterToMeta(fm)
+		if workflow.Category != "" {
+			resourceMeta["category"] = workflow.Category
+		}
 
-			resources = append(resources, res)
+		res := mcp.Resource{
+			URI:         fmt.Sprintf("workflow:///%s", workflow.Name),
+			Name:        workflow.Name,
+			Description: fm.Description,
+			MimeType:    "text/markdown",
+		}
+		if len(resourceMeta) > 0 {
+			res.Meta = resourceMeta
 		}
 
-		// List supporting files in the workflow directory (even if SKILL.md doesn't exist yet)
-		_ = filepath.WalkDir(workflowDir, func(path string, d os.DirEntry, walkErr error) error {
-			if walkErr != nil || d.IsDir() {
-				return nil
-			}
-			if filepath.Base(path) == skillformat.SkillMainFile {
-				return nil
-			}
-			relPath, err := filepath.Rel(".", path)
-			if err != nil {
-				return nil
-			}
-			info, err := d.Info()
-			if err != nil {
-				return nil
-			}
-			mimeType := mime.TypeByExtension(filepath.Ext(relPath))
-			if mimeType == "" {
-				mimeType = "application/octet-stream"
-			}
-			resources = append(resources, mcp.Resource{
-				URI:      fileuri.Encode(relPath),
-				Name:     filepath.Base(relPath),
-				MimeType: mimeType,
-				Size:     info.Size(),
-				Annotations: &mcp.Annotations{
-					LastModified: info.ModTime(),
-				},
-			})
+		resources = append(resources, res)
+	}
+
+	// List supporting files in the workflow directories (even if SKILL.md doesn't exist yet)
+	_ = filepath.WalkDir(workflowsPath, func(path string, d os.DirEntry, walkErr error) error {
+		if walkErr != nil || d.IsDir() {
 			return nil
+		}
+		if filepath.Base(path) == skillformat.SkillMainFile {
+			return nil
+		}
+		dir, err := filepath.Rel(workflowsPath, filepath.Dir(path))
+		if err != nil || dir == "." || !skillformat.InCategory(filepath.ToSlash(dir), category) {
+			return nil
+		}
+		// File URIs are relative to the working directory, so supporting
+		// files of skills outside of it can't be served.
+		relPath, ok := relToWorkingDir(path)
+		if !ok {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		mimeType := mime.TypeByExtension(filepath.Ext(relPath))
+		if mimeType == "" {
+			mimeType = "application/octet-stream"
+		}
+		resources = append(resources, mcp.Resource{
+			URI:      fileuri.Encode(relPath),
+			Name:     filepath.Base(relPath),
+			MimeType: mimeType,
+			Size:     info.Size(),
+			Annotations: &mcp.Annotations{
+				LastModified: info.ModTime(),
+			},
 		})
-	}
+		return nil
+	})
 
 	return resources, nil
 }
 
-// readWorkflowResource reads a specific workflow by URI.
-func (s *Server) readWorkflowResource(ctx context.Context, uri string) (*mcp.ReadResourceResult, error) {
+// parseWorkflowURI extracts the workflow name from a workflow:///name URI. Names
+// of workflows in categories have the category in front, e.g.
+// workflow:///ops/deploy/rollback.
+func parseWorkflowURI(uri string) (string, error) {
 	workflowName := strings.TrimPrefix(uri, "workflow:///")
 	workflowName = strings.TrimSuffix(workflowName, ".md")
 	if workflowName == "" {
-		return nil, mcp.ErrRPCInvalidParams.WithMessage("workflow name is required")
+		return "", mcp.ErrRPCInvalidParams.WithMessage("workflow name is required")
+	}
+	if !skillformat.ValidWorkflowName(workflowName) {
+		return "", mcp.ErrRPCInvalidParams.WithMessage("invalid workflow name: %s", workflowName)
+	}
+	return workflowName, nil
+}
+
+// readWorkflowResource reads a specific workflow by URI.
+func (s *Server) readWorkflowResource(ctx context.Context, uri string) (*mcp.ReadResourceResult, error) {
+	workflowName, err := parseWorkflowURI(uri)
+	if err != nil {
+		return nil, err
 	}
 
-	workflowPath := filepath.Join(".", skillformat.WorkflowsDir, workflowName, skillformat.SkillMainFile)
+	workflowPath := filepath.Join(".", skillformat.WorkflowsDir, filepath.FromSlash(workflowName), skillformat.SkillMainFile)
 	contentBytes, err := os.ReadFile(workflowPath)
 	if err != nil {
 		return nil, mcp.ErrRPCInvalidParams.WithMessage("workflow not found: %s", uri)
@@ -228,6 +262,9 @@ func (s *Server) readWorkflowResource(ctx context.Context, uri string) (*mcp.Rea
 	}
 
 	resourceMeta := skillformat.FrontmatterToMeta(fm)
+	if category := skillformat.WorkflowCategory(workflowName); category != "" {
+		resourceMeta["category"] = category
+	}
 
 	rc := mcp.ResourceContent{
 		URI:      uri,
@@ -247,35 +284,11 @@ func (s *Server) readWorkflowResource(ctx context.Context, uri string) (*mcp.Rea
 // maxSessionFileDepth is the maximum depth for walking session directories.
 const maxSessionFileDepth = 2
 
-// sessionFileFilter determines if a file should be included in session file listing.
-func sessionFileFilter(relPath string, info os.FileInfo) bool {
-	if relPath == "." {
-		return true
-	}
-
-	basename := filepath.Base(relPath)
-
-	// Exclude common non-content directories/files
-	excludedDirs := map[string]struct{}{
-		"node_modules": {},
-		".git":         {},
-		".nanobot":     {},
-	}
-	excludedFiles := map[string]struct{}{
-		".DS_Store": {},
-	}
-
-	if info.IsDir() {
-		if _, excluded := excludedDirs[basename]; excluded {
-			return false
-		}
-	} else {
-		if _, excluded := excludedFiles[basename]; excluded {
-			return false
-		}
-	}
-
-	return true
+// sessionFileFilter determines if a file under the sessions directory should
+// be included in session file listing, by the defaults and the ignore files of
+// each session.
+func sessionFileFilter(sessionsPath string) fswatch.FilterFunc {
+	return ignore.New(sessionsPath, ignore.Defaults...).Filter
 }
 
 // listFileResourcesAllSessions lists files across all sessions belonging to the current account.
@@ -291,78 +304,68 @@ func (s *Server) listFileResourcesAllSessions(ctx context.Context) ([]mcp.Resour
 		return nil, fmt.Errorf("failed to list sessions: %w", err)
 	}
 
-	cwd, err := os.Getwd()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get working directory: %w", err)
-	}
-
-	var resources []mcp.Resource
+	accountSessions := make(map[string]struct{}, len(sessions))
 	for _, sess := range sessions {
-		sessDir := filepath.Join(cwd, sessionsDir, sess.SessionID)
+		accountSessions[sess.SessionID] = struct{}{}
+	}
 
-		// Skip if directory doesn't exist
-		if _, err := os.Stat(sessDir); os.IsNotExist(err) {
-			continue
+	var files []fswatch.FileEntry
+	if index := s.sessionsIndex.Load(); index != nil {
+		files, err = index.Files()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list session files: %w", err)
+		}
+	} else {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get working directory: %w", err)
 		}
 
-		err := filepath.WalkDir(sessDir, func(path string, d os.DirEntry, err error) error {
-			if err != nil {
-				return nil
-			}
-
-			relPath, err := filepath.Rel(sessDir, path)
-			if err != nil {
-				return nil
-			}
-
-			if relPath == "." {
-				return nil
-			}
-
-			depth := len(strings.Split(relPath, string(filepath.Separator)))
-			if d.IsDir() && depth > maxSessionFileDepth {
-				return filepath.SkipDir
-			}
-
-			info, err := d.Info()
-			if err != nil {
-				return nil
-			}
-			if !sessionFileFilter(relPath, info) {
-				if d.IsDir() {
-					return filepath.SkipDir
-				}
-				return nil
-			}
-
-			if d.IsDir() {
-				return nil
-			}
-
-			mimeType := mime.TypeByExtension(filepath.Ext(relPath))
-			if mimeType == "" {
-				mimeType = "application/octet-stream"
-			}
+		// Walk only this account's session directories, in parallel.
+		sessionsPath := filepath.Join(cwd, sessionsDir)
+		filter := sessionFileFilter(sessionsPath)
+		files, err = fswatch.Walk(sessionsPath, maxSessionFileDepth+1, func(relPath string, info os.FileInfo) bool {
+			if !strings.ContainsRune(relPath, filepath.Separator) {
+				_, ok := accountSessions[relPath]
+				return ok && info.IsDir()
+			}
+			return filter(relPath, info)
+		}, 0)
+		if os.IsNotExist(err) {
+			return nil, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to list session files: %w", err)
+		}
+	}
 
-			// URI format: file:///sessions/{sessionID}/{path}
-			uri := fileuri.Encode(filepath.Join(sessionsDir, sess.SessionID, relPath))
-			name := fmt.Sprintf("%s/%s", sess.SessionID, relPath)
+	var resources []mcp.Resource
+	for _, file := range files {
+		sessionID, relPath, ok := strings.Cut(file.Path, string(filepath.Separator))
+		if !ok {
+			continue
+		}
+		if _, ok := accountSessions[sessionID]; !ok {
+			continue
+		}
 
-			resources = append(resources, mcp.Resource{
-				URI:      uri,
-				Name:     name,
-				MimeType: mimeType,
-				Size:     info.Size(),
-				Annotations: &mcp.Annotations{
-					LastModified: info.ModTime(),
-				},
-			})
+		mimeType := mime.TypeByExtension(filepath.Ext(relPath))
+		if mimeType == "" {
+			mimeType = "application/octet-stream"
+		}
 
-			return nil
+		// URI format: file:///sessions/{sessionID}/{path}
+		uri := fileuri.Encode(filepath.Join(sessionsDir, file.Path))
+		name := fmt.Sprintf("%s/%s", sessionID, relPath)
+
+		resources = append(resources, mcp.Resource{
+			URI:      uri,
+			Name:     name,
+			MimeType: mimeType,
+			Size:     file.Size,
+			Annotations: &mcp.Annotations{
+				LastModified: file.ModTime,
+			},
 		})
-		if err != nil {
-			slog.Error("failed to walk session directory", "path", sessDir, "error", err)
-		}
 	}
 
 	return resources, nil
@@ -418,7 +421,7 @@ func (s *Server) readFileResource(ctx context.Context, uri string) (*mcp.ReadRes
 
 	// Workflow and skill supporting files don't need session access verification
 	isWorkflowFile := strings.HasPrefix(cleanPath, skillformat.WorkflowsDir+string(filepath.Separator))
-	isSkillFile := s.configDir != "" && strings.HasPrefix(cleanPath, filepath.Clean(s.configDir)+string(filepath.Separator)+"skills"+string(filepath.Separator))
+	isSkillFile := s.isSkillFile(cleanPath)
 	if !isWorkflowFile && !isSkillFile {
 		if err := s.verifyFileResourceAccess(ctx, relPath); err != nil {
 			return nil, err
@@ -481,8 +484,9 @@ func (s *Server) ensureWatchers() error {
 			return
 		}
 
-		// Depth 3 to watch nested files like <workflow>/scripts/analyze.py
-		s.workflowWatcher = fswatch.NewWatcher(workflowsPath, 3, nil, s.handleWorkflowEvents)
+		// Watch the whole tree, workflows can be nested in category folders
+		// and have nested files like <workflow>/scripts/analyze.py
+		s.workflowWatcher = fswatch.NewWatcher(workflowsPath, skillformat.MaxWorkflowDepth, nil, s.handleWorkflowEvents)
 		if err := s.workflowWatcher.Start(); err != nil {
 			s.watcherInitErr = fmt.Errorf("failed to start workflow watcher: %w", err)
 			return
@@ -490,19 +494,27 @@ func (s *Server) ensureWatchers() error {
 
 		slog.Debug("started meta workflow watcher", "path", workflowsPath)
 
-		// Watch skills directory
-		if s.configDir != "" {
-			skillsPath := filepath.Join(s.configDir, "skills")
-			if err := os.MkdirAll(skillsPath, 0755); err != nil {
-				slog.Error("failed to create skills directory", "path", skillsPath, "error", err)
-			} else {
-				s.skillWatcher = fswatch.NewWatcher(skillsPath, 3, nil, s.handleSkillEvents)
-				if err := s.skillWatcher.Start(); err != nil {
-					slog.Error("failed to start skill watcher", "error", err)
-				} else {
-					slog.Debug("started meta skill watcher", "path", skillsPath)
+		// Watch the skills search paths. Only the config dir's skills directory
+		// is created, additional search paths are watched if they exist.
+		for _, skillsPath := range skillformat.SearchPaths(s.configDir) {
+			if s.configDir != "" && skillsPath == filepath.Join(s.configDir, skillformat.SkillsDir) {
+				if err := os.MkdirAll(skillsPath, 0755); err != nil {
+					slog.Error("failed to create skills directory", "path", skillsPath, "error", err)
+					continue
 				}
+			} else if info, err := os.Stat(skillsPath); err != nil || !info.IsDir() {
+				continue
 			}
+
+			watcher := fswatch.NewWatcher(skillsPath, 3, nil, func(events []fswatch.Event) {
+				s.handleSkillEvents(skillsPath, events)
+			})
+			if err := watcher.Start(); err != nil {
+				slog.Error("failed to start skill watcher", "path", skillsPath, "error", err)
+				continue
+			}
+			s.skillWatchers = append(s.skillWatchers, watcher)
+			slog.Debug("started meta skill watcher", "path", skillsPath)
 		}
 
 		// Watch sessions directory
@@ -518,11 +530,17 @@ func (s *Server) ensureWatchers() error {
 			return
 		}
 
-		s.sessionsWatcher = fswatch.NewWatcher(sessionsPath, 3, sessionFileFilter, s.handleSessionFileEvents)
+		filter := sessionFileFilter(sessionsPath)
+		index := fswatch.NewIndex(sessionsPath, maxSessionFileDepth+1, filter)
+		s.sessionsWatcher = fswatch.NewWatcher(sessionsPath, maxSessionFileDepth+1, filter, func(events []fswatch.Event) {
+			index.Apply(events)
+			s.handleSessionFileEvents(events)
+		})
 		if err := s.sessionsWatcher.Start(); err != nil {
 			slog.Error("failed to start sessions watcher", "error", err)
 			return
 		}
+		s.sessionsIndex.Store(index)
 
 		slog.Debug("started meta sessions watcher", "path", sessionsPath)
 	})
@@ -533,20 +551,20 @@ func (s *Server) ensureWatchers() error {
 // handleWorkflowEvents processes filesystem events from the workflow watcher.
 func (s *Server) handleWorkflowEvents(events []fswatch.Event) {
 	for _, event := range events {
-		// Event paths are relative to the workflows dir, e.g. "code-review/SKILL.md"
-		// or "code-review/scripts/analyze.py"
-		parts := strings.SplitN(event.Path, string(filepath.Separator), 2)
-		workflowName := parts[0]
-		workflowURI := fmt.Sprintf("workflow:///%s", workflowName)
+		// Event paths are relative to the workflows dir, e.g. "code-review/SKILL.md",
+		// "ops/deploy/SKILL.md" or "code-review/scripts/analyze.py"
+		dir := filepath.Dir(event.Path)
+		isNested := dir != "."
+		workflowURI := fmt.Sprintf("workflow:///%s", filepath.ToSlash(dir))
 
-		isMainFile := len(parts) == 2 && parts[1] == skillformat.SkillMainFile
+		isMainFile := isNested && filepath.Base(event.Path) == skillformat.SkillMainFile
 
 		switch event.Type {
 		case fswatch.EventDelete:
 			if isMainFile {
 				s.subscriptions.SendResourceUpdatedNotification(workflowURI)
 				s.subscriptions.AutoUnsubscribe(workflowURI)
-			} else if len(parts) == 2 {
+			} else if isNested {
 				fileURI := fileuri.Encode(filepath.Join(skillformat.WorkflowsDir, event.Path))
 				s.subscriptions.SendResourceUpdatedNotification(fileURI)
 				s.subscriptions.AutoUnsubscribe(fileURI)
@@ -557,7 +575,7 @@ func (s *Server) handleWorkflowEvents(events []fswatch.Event) {
 		case fswatch.EventWrite:
 			if isMainFile {
 				s.subscriptions.SendResourceUpdatedNotification(workflowURI)
-			} else if len(parts) == 2 {
+			} else if isNested {
 				fileURI := fileuri.Encode(filepath.Join(skillformat.WorkflowsDir, event.Path))
 				s.subscriptions.SendResourceUpdatedNotification(fileURI)
 			}
@@ -584,8 +602,9 @@ func (s *Server) handleSessionFileEvents(events []fswatch.Event) {
 	}
 }
 
-// handleSkillEvents processes filesystem events from the skill watcher.
-func (s *Server) handleSkillEvents(events []fswatch.Event) {
+// handleSkillEvents processes filesystem events from the watcher of a skills
+// search path.
+func (s *Server) handleSkillEvents(skillsPath string, events []fswatch.Event) {
 	for _, event := range events {
 		// Event paths are relative to the skills dir, e.g. "my-skill/SKILL.md"
 		// or "my-skill/scripts/helper.py"
@@ -601,9 +620,11 @@ func (s *Server) handleSkillEvents(events []fswatch.Event) {
 				s.subscriptions.SendResourceUpdatedNotification(skillURI)
 				s.subscriptions.AutoUnsubscribe(skillURI)
 			} else if len(parts) == 2 {
-				fileURI := fileuri.Encode(filepath.Join(s.configDir, "skills", event.Path))
-				s.subscriptions.SendResourceUpdatedNotification(fileURI)
-				s.subscriptions.AutoUnsubscribe(fileURI)
+				if relPath, ok := relToWorkingDir(filepath.Join(skillsPath, event.Path)); ok {
+					fileURI := fileuri.Encode(relPath)
+					s.subscriptions.SendResourceUpdatedNotification(fileURI)
+					s.subscriptions.AutoUnsubscribe(fileURI)
+				}
 			}
 			s.subscriptions.SendListChangedNotification()
 		case fswatch.EventCreate:
@@ -612,24 +633,32 @@ func (s *Server) handleSkillEvents(events []fswatch.Event) {
 			if isMainFile {
 				s.subscriptions.SendResourceUpdatedNotification(skillURI)
 			} else if len(parts) == 2 {
-				fileURI := fileuri.Encode(filepath.Join(s.configDir, "skills", event.Path))
-				s.subscriptions.SendResourceUpdatedNotification(fileURI)
+				if relPath, ok := relToWorkingDir(filepath.Join(skillsPath, event.Path)); ok {
+					s.subscriptions.SendResourceUpdatedNotification(fileuri.Encode(relPath))
+				}
 			}
 		}
 	}
 }
 
-// listSkillResources reads the configDir/skills/ directory and returns skill resources.
+// listSkillResources reads the skills search paths and returns skill resources.
+// A skill in an earlier search path hides skills of the same name in later ones.
 func (s *Server) listSkillResources() ([]mcp.Resource, error) {
-	if s.configDir == "" {
-		return nil, nil
+	var (
+		resources []mcp.Resource
+		seen      = map[string]struct{}{}
+	)
+	for _, skillsPath := range skillformat.SearchPaths(s.configDir) {
+		resources = append(resources, s.listSkillResourcesInPath(skillsPath, seen)...)
 	}
+	return resources, nil
+}
 
-	skillsPath := filepath.Join(s.configDir, "skills")
+func (s *Server) listSkillResourcesInPath(skillsPath string, seen map[string]struct{}) []mcp.Resource {
 	entries, err := os.ReadDir(skillsPath)
 	if err != nil {
 		// Directory doesn't exist - return empty list
-		return nil, nil
+		return nil
 	}
 
 	var resources []mcp.Resource
@@ -639,6 +668,10 @@ func (s *Server) listSkillResources() ([]mcp.Resource, error) {
 		}
 
 		name := entry.Name()
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
 		skillDir := filepath.Join(skillsPath, name)
 
 		// Read the main skill file from the subdirectory
@@ -679,8 +712,10 @@ func (s *Server) listSkillResources() ([]mcp.Resource, error) {
 			if filepath.Base(path) == skillformat.SkillMainFile {
 				return nil
 			}
-			relPath, err := filepath.Rel(".", path)
-			if err != nil {
+			// File URIs are relative to the working directory, so supporting
+			// files of skills outside of it can't be served.
+			relPath, ok := relToWorkingDir(path)
+			if !ok {
 				return nil
 			}
 			info, err := d.Info()
@@ -701,7 +736,7 @@ func (s *Server) listSkillResources() ([]mcp.Resource, error) {
 		})
 	}
 
-	return resources, nil
+	return resources
 }
 
 // readSkillResource reads a specific skill by URI.
@@ -712,12 +747,12 @@ func (s *Server) readSkillResource(_ context.Context, uri string) (*mcp.ReadReso
 		return nil, mcp.ErrRPCInvalidParams.WithMessage("skill name is required")
 	}
 
-	if s.configDir == "" {
-		return nil, mcp.ErrRPCInvalidParams.WithMessage("skills not available: no config directory")
+	skillDir, ok := skillformat.FindSkillDir(skillformat.SearchPaths(s.configDir), skillName)
+	if !ok {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("skill not found: %s", uri)
 	}
 
-	skillPath := filepath.Join(s.configDir, "skills", skillName, skillformat.SkillMainFile)
-	contentBytes, err := os.ReadFile(skillPath)
+	contentBytes, err := os.ReadFile(filepath.Join(skillDir, skillformat.SkillMainFile))
 	if err != nil {
 		return nil, mcp.ErrRPCInvalidParams.WithMessage("skill not found: %s", uri)
 	}
@@ -751,3 +786,31 @@ func (s *Server) readSkillResource(_ context.Context, uri string) (*mcp.ReadReso
 		Contents: []mcp.ResourceContent{rc},
 	}, nil
 }
+
+// relToWorkingDir returns path relative to the working directory, or false if
+// it is outside of it.
+func relToWorkingDir(path string) (string, bool) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", false
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(cwd, path)
+	}
+	rel, err := filepath.Rel(cwd, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+// isSkillFile reports whether relPath, relative to the working directory, is
+// inside one of the skills search paths.
+func (s *Server) isSkillFile(relPath string) bool {
+	for _, skillsPath := range skillformat.SearchPaths(s.configDir) {
+		if rel, ok := relToWorkingDir(skillsPath); ok && strings.HasPrefix(relPath, rel+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,11 +1,16 @@
 package meta
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
 
 	"github.com/obot-platform/nanobot/pkg/mcp"
 	"github.com/obot-platform/nanobot/pkg/session"
 	"github.com/obot-platform/nanobot/pkg/types"
+	"gorm.io/gorm"
 )
 
 func (s *Server) updateChat(ctx context.Context, data struct {
@@ -45,6 +50,126 @@ func (s *Server) updateChat(ctx context.Context, data struct {
 	return &chat, nil
 }
 
+func (s *Server) forkChat(ctx context.Context, data struct {
+	ID        string `json:"chatId,omitempty"`
+	Title     string `json:"title,omitempty"`
+	CopyFiles bool   `json:"copyFiles,omitempty"`
+}) (*types.Chat, error) {
+	mcpSession := mcp.SessionFromContext(ctx)
+	manager, accountID, err := s.getManagerAndAccountID(mcpSession)
+	if err != nil {
+		return nil, err
+	}
+
+	if data.ID == "" {
+		data.ID, _ = types.GetSessionAndAccountID(ctx)
+	}
+
+	fork, err := manager.Fork(ctx, data.ID, session.ForkOptions{
+		AccountID:   accountID,
+		Description: data.Title,
+		CopyFiles:   data.CopyFiles,
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("chat %s not found", data.ID)
+	} else if err != nil {
+		return nil, err
+	}
+
+	chat := chatFromSession(fork, accountID, nil)
+	return &chat, nil
+}
+
+func (s *Server) exportChat(ctx context.Context, data struct {
+	ID string `json:"chatId,omitempty"`
+}) (*mcp.CallToolResult, error) {
+	mcpSession := mcp.SessionFromContext(ctx)
+	manager, accountID, err := s.getManagerAndAccountID(mcpSession)
+	if err != nil {
+		return nil, err
+	}
+
+	if data.ID == "" {
+		data.ID, _ = types.GetSessionAndAccountID(ctx)
+	}
+
+	var buf bytes.Buffer
+	err = manager.Export(ctx, data.ID, &buf, session.ExportOptions{
+		AccountID: accountID,
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("chat %s not found", data.ID)
+	} else if err != nil {
+		return nil, err
+	}
+
+	name := data.ID + ".tar.gz"
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{{
+			Type: "resource",
+			Resource: &mcp.EmbeddedResource{
+				URI:      "chat://exports/" + name,
+				Name:     name,
+				MIMEType: "application/gzip",
+				Blob:     base64.StdEncoding.EncodeToString(buf.Bytes()),
+			},
+		}},
+	}, nil
+}
+
+func (s *Server) importChat(ctx context.Context, data struct {
+	Bundle string `json:"bundle"`
+}) (*types.Chat, error) {
+	mcpSession := mcp.SessionFromContext(ctx)
+	manager, accountID, err := s.getManagerAndAccountID(mcpSession)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle, err := base64.StdEncoding.DecodeString(data.Bundle)
+	if err != nil {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("bundle must be base64 encoded: %v", err)
+	}
+
+	imported, err := manager.Import(ctx, bytes.NewReader(bundle), session.ImportOptions{
+		AccountID: accountID,
+	})
+	if err != nil {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("failed to import chat: %v", err)
+	}
+
+	chat := chatFromSession(imported, accountID, nil)
+	return &chat, nil
+}
+
+func (s *Server) searchSessions(ctx context.Context, data struct {
+	Query string `json:"query"`
+	Limit int    `json:"limit,omitempty"`
+}) (*searchSessionsResult, error) {
+	mcpSession := mcp.SessionFromContext(ctx)
+	manager, accountID, err := s.getManagerAndAccountID(mcpSession)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(data.Query) == "" {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("query is required")
+	}
+
+	results, err := manager.DB.SearchMessages(ctx, accountID, data.Query, data.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &searchSessionsResult{
+		Results: results,
+	}, nil
+}
+
+type searchSessionsResult struct {
+	Results []session.SearchResult `json:"results"`
+}
+
 func (s *Server) getManagerAndAccountID(mcpSession *mcp.Session) (*session.Manager, string, error) {
 	var (
 		manager   session.Manager
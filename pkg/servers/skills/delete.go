@@ -41,3 +41,18 @@ func (s *Server) deleteSkill(_ context.Context, data struct {
 
 	return fmt.Sprintf("%s deleted", data.URI), nil
 }
+
+type removeSkillParams struct {
+	Name string `json:"name" jsonschema:"Name of the installed skill to remove"`
+}
+
+func (s *Server) removeSkill(_ context.Context, params removeSkillParams) (string, error) {
+	if params.Name == "" {
+		return "", mcp.ErrRPCInvalidParams.WithMessage("name is required")
+	}
+
+	if _, err := RemoveSkill(s.configDir, params.Name); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s removed", params.Name), nil
+}
@@ -2,6 +2,7 @@ package skills
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -13,7 +14,11 @@ import (
 )
 
 type installSkillParams struct {
-	SkillID string `json:"skillID"`
+	SkillID  string `json:"skillID,omitempty" jsonschema:"ID of a skill from the Obot catalog. Mutually exclusive with url."`
+	URL      string `json:"url,omitempty" jsonschema:"HTTPS URL of a skill ZIP archive, or an HTTPS git repository URL (ending in .git or prefixed with git+) to install from instead of Obot. The user confirms the install."`
+	Ref      string `json:"ref,omitempty" jsonschema:"Git branch, tag, or commit to install. Only used with git repository URLs."`
+	Path     string `json:"path,omitempty" jsonschema:"Directory of the skill within the git repository. Defaults to the repository root."`
+	Checksum string `json:"checksum,omitempty" jsonschema:"Pins the install: the SHA-256 of a ZIP archive, or the full commit SHA of a git repository. The install fails if the fetched bundle does not match."`
 }
 
 type installSkillResult struct {
@@ -22,14 +27,22 @@ type installSkillResult struct {
 	DisplayName    string   `json:"displayName,omitempty"`
 	RepoID         string   `json:"repoID,omitempty"`
 	CommitSHA      string   `json:"commitSHA,omitempty"`
+	Source         string   `json:"source,omitempty"`
+	Checksum       string   `json:"checksum,omitempty"`
 	Path           string   `json:"path"`
 	InstalledFiles []string `json:"installedFiles,omitempty"`
 	Message        string   `json:"message"`
 }
 
 func (s *Server) installSkill(ctx context.Context, params installSkillParams) (*installSkillResult, error) {
+	if params.SkillID != "" && params.URL != "" {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("only one of skillID or url may be set")
+	}
+	if params.URL != "" {
+		return s.installRemoteSkill(ctx, params)
+	}
 	if params.SkillID == "" {
-		return nil, fmt.Errorf("skillID is required")
+		return nil, fmt.Errorf("skillID or url is required")
 	}
 
 	client, err := s.newClient(ctx)
@@ -99,6 +112,68 @@ func (s *Server) installSkill(ctx context.Context, params installSkillParams) (*
 	}, nil
 }
 
+// installRemoteSkill installs a skill from an HTTPS archive or git repository,
+// which does not require an Obot connection.
+func (s *Server) installRemoteSkill(ctx context.Context, params installSkillParams) (*installSkillResult, error) {
+	bundle, err := FetchRemote(ctx, RemoteSource{
+		URL:      params.URL,
+		Ref:      params.Ref,
+		Path:     params.Path,
+		Checksum: params.Checksum,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	targetDir := filepath.Join(s.configDir, skillformat.SkillsDir, bundle.Name)
+	if _, err := os.Stat(targetDir); err == nil {
+		overwrite, err := s.confirmOverwrite(ctx, bundle.Name)
+		if err != nil {
+			return nil, err
+		}
+		if !overwrite {
+			return &installSkillResult{
+				Name:     bundle.Name,
+				Source:   params.URL,
+				Checksum: bundle.Checksum,
+				Path:     targetDir,
+				Message:  fmt.Sprintf("Installation of %q was canceled by the user. The existing skill was not modified.", bundle.Name),
+			}, nil
+		}
+	} else if os.IsNotExist(err) {
+		// The URL may come from a model, so the user confirms every install.
+		install, err := s.confirmInstall(ctx, bundle.Name, params.URL, bundle.Checksum)
+		if err != nil {
+			return nil, err
+		}
+		if !install {
+			return &installSkillResult{
+				Name:     bundle.Name,
+				Source:   params.URL,
+				Checksum: bundle.Checksum,
+				Path:     targetDir,
+				Message:  fmt.Sprintf("Installation of %q was canceled by the user.", bundle.Name),
+			}, nil
+		}
+	} else {
+		return nil, fmt.Errorf("failed to inspect existing skill directory: %w", err)
+	}
+
+	path, installedFiles, err := InstallBundle(s.configDir, bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	return &installSkillResult{
+		Name:           bundle.Name,
+		Source:         params.URL,
+		Checksum:       bundle.Checksum,
+		Path:           path,
+		InstalledFiles: installedFiles,
+		Message:        fmt.Sprintf("Installed %s from %s into %s (%d files, checksum %s)", bundle.Name, params.URL, path, len(installedFiles), bundle.Checksum),
+	}, nil
+}
+
 func installIntoDirectory(zipData []byte, skillName, targetDir string) ([]string, error) {
 	parentDir := filepath.Dir(targetDir)
 	if err := os.MkdirAll(parentDir, 0o755); err != nil {
@@ -165,9 +240,37 @@ func defaultConfirmOverwrite(ctx context.Context, name string) (bool, error) {
 	}
 
 	var result mcp.ElicitResult
-	if err := agent.ExchangeElicitation(ctx, session, elicit, &result); err != nil {
+	if err := agent.ExchangeElicitation(ctx, session, elicit, &result); errors.Is(err, agent.ErrElicitationUnsupported) {
+		// The user can't be asked, so leave the existing skill alone.
+		return false, nil
+	} else if err != nil {
 		return false, fmt.Errorf("failed to send overwrite confirmation: %w", err)
 	}
 
 	return result.Action == "accept", nil
 }
+
+func defaultConfirmInstall(ctx context.Context, name, source, checksum string) (bool, error) {
+	session := mcp.SessionFromContext(ctx)
+	if session == nil {
+		return false, fmt.Errorf("no session found in context")
+	}
+
+	elicit := mcp.ElicitRequest{
+		Message: fmt.Sprintf("Do you want to install the skill %q from %s (checksum %s)?", name, source, checksum),
+		RequestedSchema: mcp.PrimitiveSchema{
+			Type:       "object",
+			Properties: map[string]mcp.PrimitiveProperty{},
+		},
+	}
+
+	var result mcp.ElicitResult
+	if err := agent.ExchangeElicitation(ctx, session, elicit, &result); errors.Is(err, agent.ErrElicitationUnsupported) {
+		// The user can't be asked, so nothing is installed.
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to send install confirmation: %w", err)
+	}
+
+	return result.Action == "accept", nil
+}
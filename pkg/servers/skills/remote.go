@@ -0,0 +1,298 @@
+package skills
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/obot-platform/nanobot/pkg/servers/installzip"
+	"github.com/obot-platform/nanobot/pkg/skillformat"
+)
+
+// remoteHTTPClient downloads skill bundles; tests replace it to trust their
+// TLS server.
+var remoteHTTPClient = http.DefaultClient
+
+// RemoteSource identifies a skill bundle outside of Obot. URL is either an
+// HTTPS link to a ZIP archive with SKILL.md at its root, or an HTTPS git
+// repository (ending in .git, or prefixed with git+). Other transports are
+// refused, the URL may come from a model.
+type RemoteSource struct {
+	URL string
+	// Ref is the git branch, tag, or commit to fetch. Defaults to the pinned
+	// commit, or the default branch.
+	Ref string
+	// Path is the skill directory within a git repository. Defaults to the
+	// repository root.
+	Path string
+	// Checksum pins the bundle: the SHA-256 of a ZIP archive, or the full
+	// commit SHA of a git repository.
+	Checksum string
+}
+
+// RemoteBundle is a fetched skill bundle, repackaged as a ZIP archive.
+type RemoteBundle struct {
+	Name string
+	// Checksum is the SHA-256 of the archive or the commit SHA that was
+	// fetched, suitable for pinning later installs.
+	Checksum string
+	Data     []byte
+}
+
+// FetchRemote downloads the skill bundle described by src and verifies it
+// against the pinned checksum, if any.
+func FetchRemote(ctx context.Context, src RemoteSource) (*RemoteBundle, error) {
+	if src.URL == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+
+	repo, isGit := gitRepoURL(src.URL)
+	if u, err := url.Parse(repo); err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("skills must be installed from https:// URLs, got %q", src.URL)
+	}
+
+	var (
+		bundle *RemoteBundle
+		err    error
+	)
+	if isGit {
+		bundle, err = fetchGit(ctx, repo, src)
+	} else {
+		bundle, err = fetchArchive(ctx, src)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fm, err := installzip.ReadFrontmatter(bundle.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate skill bundle: %w", err)
+	}
+	bundle.Name = fm.Name
+	return bundle, nil
+}
+
+// InstallBundle installs a fetched bundle into the skills directory under
+// configDir, replacing any existing skill of the same name.
+func InstallBundle(configDir string, bundle *RemoteBundle) (string, []string, error) {
+	targetDir := filepath.Join(configDir, skillformat.SkillsDir, bundle.Name)
+	installedFiles, err := installIntoDirectory(bundle.Data, bundle.Name, targetDir)
+	if err != nil {
+		return "", nil, err
+	}
+	return targetDir, installedFiles, nil
+}
+
+// RemoveSkill deletes an installed skill by name from the skills directory
+// under configDir and returns the removed path.
+func RemoveSkill(configDir, name string) (string, error) {
+	if err := skillformat.ValidateName(name); err != nil {
+		return "", fmt.Errorf("invalid skill name: %w", err)
+	}
+
+	skillPath := filepath.Join(configDir, skillformat.SkillsDir, name)
+	if _, err := os.Stat(skillPath); os.IsNotExist(err) {
+		return "", fmt.Errorf("skill %q is not installed in %s", name, filepath.Dir(skillPath))
+	} else if err != nil {
+		return "", fmt.Errorf("failed to inspect skill directory: %w", err)
+	}
+
+	if err := os.RemoveAll(skillPath); err != nil {
+		return "", fmt.Errorf("failed to remove skill: %w", err)
+	}
+	return skillPath, nil
+}
+
+// gitRepoURL reports whether rawURL names a git repository and returns the
+// URL to pass to git.
+func gitRepoURL(rawURL string) (string, bool) {
+	if repo, ok := strings.CutPrefix(rawURL, "git+"); ok {
+		return repo, true
+	}
+	return rawURL, strings.HasSuffix(strings.TrimSuffix(rawURL, "/"), ".git")
+}
+
+func fetchArchive(ctx context.Context, src RemoteSource) (*RemoteBundle, error) {
+	if src.Path != "" {
+		return nil, fmt.Errorf("path is only supported for git repositories")
+	}
+	if src.Ref != "" {
+		return nil, fmt.Errorf("ref is only supported for git repositories")
+	}
+
+	u, err := url.Parse(src.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+	if u.Scheme != "https" {
+		return nil, fmt.Errorf("skill bundles must be downloaded over https, got %q", src.URL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := remoteHTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download skill bundle: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download skill bundle: %s", resp.Status)
+	}
+
+	data, err := installzip.ReadAll(resp.Body, "skill bundle")
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+	if src.Checksum != "" {
+		want := strings.ToLower(strings.TrimPrefix(src.Checksum, "sha256:"))
+		if want != checksum {
+			return nil, fmt.Errorf("checksum mismatch for %s: expected sha256 %s, got %s", src.URL, want, checksum)
+		}
+	}
+
+	return &RemoteBundle{
+		Checksum: checksum,
+		Data:     data,
+	}, nil
+}
+
+func fetchGit(ctx context.Context, repo string, src RemoteSource) (*RemoteBundle, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("git is required to install skills from a repository: %w", err)
+	}
+
+	subPath := filepath.Clean(filepath.FromSlash(src.Path))
+	if !filepath.IsLocal(subPath) {
+		return nil, fmt.Errorf("path %q must be relative to the repository root", src.Path)
+	}
+
+	ref := src.Ref
+	if ref == "" {
+		ref = src.Checksum
+	}
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	dir, err := os.MkdirTemp("", "nanobot-skill-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary clone directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"fetch", "--quiet", "--depth", "1", "--", repo, ref},
+		{"checkout", "--quiet", "FETCH_HEAD"},
+	} {
+		if _, err := runGit(ctx, dir, args...); err != nil {
+			return nil, err
+		}
+	}
+
+	commit, err := runGit(ctx, dir, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	if src.Checksum != "" && !strings.EqualFold(src.Checksum, commit) {
+		return nil, fmt.Errorf("checksum mismatch for %s: expected commit %s, got %s", repo, src.Checksum, commit)
+	}
+
+	data, err := zipDirectory(filepath.Join(dir, subPath))
+	if err != nil {
+		return nil, err
+	}
+
+	return &RemoteBundle{
+		Checksum: commit,
+		Data:     data,
+	}, nil
+}
+
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %w: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// zipDirectory packages a skill directory so it goes through the same
+// validation and extraction as downloaded archives.
+func zipDirectory(root string) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("symbolic links are not allowed in skill bundles: %s", path)
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		header.Method = zip.Deflate
+
+		w, err := writer.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(w, f)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to package skill directory: %w", err)
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to package skill directory: %w", err)
+	}
+	if buf.Len() > installzip.MaxArchiveBytes {
+		return nil, fmt.Errorf("skill bundle exceeds maximum size of %d bytes", installzip.MaxArchiveBytes)
+	}
+	return buf.Bytes(), nil
+}
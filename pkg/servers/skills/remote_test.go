@@ -0,0 +1,198 @@
+package skills
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/skillformat"
+)
+
+func serveRemoteBundle(t *testing.T, data []byte) string {
+	t.Helper()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "application/zip")
+		_, _ = rw.Write(data)
+	}))
+	t.Cleanup(server.Close)
+
+	oldClient := remoteHTTPClient
+	remoteHTTPClient = server.Client()
+	t.Cleanup(func() { remoteHTTPClient = oldClient })
+
+	return server.URL + "/postgres-helper.zip"
+}
+
+func TestInstallSkillFromURL(t *testing.T) {
+	zipData := createSkillZIP(t, skillformat.Frontmatter{
+		Name:        "postgres-helper",
+		Description: "Postgres utilities",
+	}, "\n# Postgres\n", map[string]string{
+		"scripts/run.sh": "echo hi\n",
+	})
+	sum := sha256.Sum256(zipData)
+	checksum := hex.EncodeToString(sum[:])
+	bundleURL := serveRemoteBundle(t, zipData)
+
+	configDir := t.TempDir()
+	s := NewServer(configDir)
+	ctx := testContext(t, map[string]string{})
+
+	s.confirmInstall = func(context.Context, string, string, string) (bool, error) {
+		return false, nil
+	}
+	result, err := s.installSkill(ctx, installSkillParams{URL: bundleURL, Checksum: "sha256:" + checksum})
+	if err != nil {
+		t.Fatalf("installSkill() failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(configDir, "skills", "postgres-helper")); !os.IsNotExist(err) {
+		t.Fatalf("expected nothing to be installed without the user's confirmation, stat err = %v", err)
+	}
+
+	var confirmed string
+	s.confirmInstall = func(_ context.Context, name, source, _ string) (bool, error) {
+		confirmed = name + " " + source
+		return true, nil
+	}
+	result, err = s.installSkill(ctx, installSkillParams{URL: bundleURL, Checksum: "sha256:" + checksum})
+	if err != nil {
+		t.Fatalf("installSkill() failed: %v", err)
+	}
+	if confirmed != "postgres-helper "+bundleURL {
+		t.Fatalf("expected the install to be confirmed, got %q", confirmed)
+	}
+	if result.Name != "postgres-helper" || result.Checksum != checksum {
+		t.Fatalf("result = %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(configDir, "skills", "postgres-helper", "scripts", "run.sh")); err != nil {
+		t.Fatalf("expected installed asset: %v", err)
+	}
+
+	if _, err := s.removeSkill(ctx, removeSkillParams{Name: "postgres-helper"}); err != nil {
+		t.Fatalf("removeSkill() failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(configDir, "skills", "postgres-helper")); !os.IsNotExist(err) {
+		t.Fatalf("expected skill to be removed, stat err = %v", err)
+	}
+	if _, err := s.removeSkill(ctx, removeSkillParams{Name: "postgres-helper"}); err == nil {
+		t.Fatal("expected removing a missing skill to fail")
+	}
+}
+
+func TestInstallSkillFromURLChecksumMismatch(t *testing.T) {
+	zipData := createSkillZIP(t, skillformat.Frontmatter{
+		Name:        "postgres-helper",
+		Description: "Postgres utilities",
+	}, "\n# Postgres\n", nil)
+	bundleURL := serveRemoteBundle(t, zipData)
+
+	configDir := t.TempDir()
+	s := NewServer(configDir)
+	_, err := s.installSkill(testContext(t, map[string]string{}), installSkillParams{
+		URL:      bundleURL,
+		Checksum: strings.Repeat("0", 64),
+	})
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(configDir, "skills", "postgres-helper")); !os.IsNotExist(err) {
+		t.Fatalf("expected nothing to be installed, stat err = %v", err)
+	}
+}
+
+func TestFetchRemoteRejectsPlainHTTP(t *testing.T) {
+	_, err := FetchRemote(context.Background(), RemoteSource{URL: "http://example.com/skill.zip"})
+	if err == nil || !strings.Contains(err.Error(), "https") {
+		t.Fatalf("expected https error, got %v", err)
+	}
+}
+
+func TestFetchRemoteRejectsOtherTransports(t *testing.T) {
+	for _, rawURL := range []string{
+		"git+file:///tmp/skills",
+		"git://example.com/skills.git",
+		"ssh://git@example.com/skills.git",
+		"git@example.com:acme/skills.git",
+		"/tmp/skills.git",
+	} {
+		if _, err := FetchRemote(t.Context(), RemoteSource{URL: rawURL}); err == nil || !strings.Contains(err.Error(), "https") {
+			t.Errorf("expected %s to be refused, got %v", rawURL, err)
+		}
+	}
+}
+
+func TestFetchRemoteGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	repoDir := filepath.Join(t.TempDir(), "skills.git")
+	content, err := skillformat.FormatSkillMD(skillformat.Frontmatter{
+		Name:        "postgres-helper",
+		Description: "Postgres utilities",
+	}, "\n# Postgres\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(repoDir, "postgres-helper"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repoDir, "postgres-helper", skillformat.SkillMainFile), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = repoDir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v failed: %v: %s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	git("init", "--quiet")
+	git("add", ".")
+	git("commit", "--quiet", "-m", "add skill")
+	commit := git("rev-parse", "HEAD")
+
+	// FetchRemote only fetches https:// repositories, so fetch the local one
+	// directly.
+	bundle, err := fetchGit(t.Context(), repoDir, RemoteSource{URL: repoDir, Path: "postgres-helper", Checksum: commit})
+	if err != nil {
+		t.Fatalf("fetchGit() failed: %v", err)
+	}
+	if bundle.Checksum != commit {
+		t.Fatalf("bundle checksum = %s", bundle.Checksum)
+	}
+
+	_, err = fetchGit(t.Context(), repoDir, RemoteSource{URL: repoDir, Ref: "HEAD", Path: "postgres-helper", Checksum: strings.Repeat("0", 40)})
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+}
+
+func TestListToolsWithoutObot(t *testing.T) {
+	s := NewServer(t.TempDir())
+	result, err := s.listTools(testContext(t, map[string]string{}), mcp.Message{}, mcp.ListToolsRequest{})
+	if err != nil {
+		t.Fatalf("listTools() failed: %v", err)
+	}
+
+	var names []string
+	for _, tool := range result.Tools {
+		names = append(names, tool.Name)
+	}
+	if strings.Join(names, ",") != "installSkill,removeSkill" {
+		t.Fatalf("tools = %v", names)
+	}
+}
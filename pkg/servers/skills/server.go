@@ -11,8 +11,11 @@ import (
 type Server struct {
 	configDir        string
 	tools            mcp.ServerTools
+	localTools       mcp.ServerTools
 	newClient        func(context.Context) (*obotClient, error)
 	confirmOverwrite func(context.Context, string) (bool, error)
+	// confirmInstall asks the user to install a skill from a URL.
+	confirmInstall func(ctx context.Context, name, source, checksum string) (bool, error)
 }
 
 func NewServer(configDir string) *Server {
@@ -20,19 +23,28 @@ func NewServer(configDir string) *Server {
 		configDir:        configDir,
 		newClient:        newClient,
 		confirmOverwrite: defaultConfirmOverwrite,
+		confirmInstall:   defaultConfirmInstall,
 	}
 
+	installSkill := mcp.NewServerTool("installSkill",
+		"Download and install a skill into the local skills workspace, either from the Obot catalog by skillID or from an HTTPS ZIP archive or git repository by url. Pass checksum to pin the exact bundle.",
+		s.installSkill)
+	removeSkill := mcp.NewServerTool("removeSkill",
+		"Remove an installed skill from the local skills workspace by name.",
+		s.removeSkill)
+
 	s.tools = mcp.NewServerTools(
 		mcp.NewServerTool("searchSkills",
 			"Search the Obot skill catalog for skills available to the current agent.",
 			s.searchSkills),
-		mcp.NewServerTool("installSkill",
-			"Download and install a skill from Obot into the local skills workspace.",
-			s.installSkill),
+		installSkill,
+		removeSkill,
 		mcp.NewServerTool("deleteSkill",
 			"Delete an installed skill by its URI.",
 			s.deleteSkill),
 	)
+	// Installing from a URL and removing skills work without Obot.
+	s.localTools = mcp.NewServerTools(installSkill, removeSkill)
 
 	return s
 }
@@ -53,18 +65,7 @@ func (s *Server) OnMessage(ctx context.Context, msg mcp.Message) {
 	}
 }
 
-func (s *Server) initialize(ctx context.Context, _ mcp.Message, params mcp.InitializeRequest) (*mcp.InitializeResult, error) {
-	if !s.enabled(ctx) {
-		return &mcp.InitializeResult{
-			ProtocolVersion: params.ProtocolVersion,
-			Capabilities:    mcp.ServerCapabilities{},
-			ServerInfo: mcp.ServerInfo{
-				Name:    version.Name,
-				Version: version.Get().String(),
-			},
-		}, nil
-	}
-
+func (s *Server) initialize(_ context.Context, _ mcp.Message, params mcp.InitializeRequest) (*mcp.InitializeResult, error) {
 	return &mcp.InitializeResult{
 		ProtocolVersion: params.ProtocolVersion,
 		Capabilities: mcp.ServerCapabilities{
@@ -128,15 +129,18 @@ func (s *Server) searchSkills(ctx context.Context, params searchSkillsParams) (*
 	return result, nil
 }
 
-func (s *Server) listTools(ctx context.Context, msg mcp.Message, _ mcp.ListToolsRequest) (*mcp.ListToolsResult, error) {
+func (s *Server) listTools(ctx context.Context, msg mcp.Message, req mcp.ListToolsRequest) (*mcp.ListToolsResult, error) {
 	if !s.enabled(ctx) {
-		return &mcp.ListToolsResult{Tools: []mcp.Tool{}}, nil
+		return s.localTools.List(ctx, msg, req)
 	}
-	return s.tools.List(ctx, msg, mcp.ListToolsRequest{})
+	return s.tools.List(ctx, msg, req)
 }
 
 func (s *Server) callTool(ctx context.Context, msg mcp.Message, payload mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	if !s.enabled(ctx) {
+		if _, ok := s.localTools[payload.Name]; ok {
+			return s.localTools.Call(ctx, msg, payload)
+		}
 		return nil, fmt.Errorf("OBOT_URL is not configured — nanobot skill tools require an Obot platform connection")
 	}
 	return s.tools.Call(ctx, msg, payload)
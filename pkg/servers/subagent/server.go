@@ -0,0 +1,199 @@
+package subagent
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/agents"
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+	"github.com/obot-platform/nanobot/pkg/version"
+)
+
+const (
+	// ServerName is the name the sub-agent server is registered under.
+	ServerName = "nanobot.subagent"
+	// permission is the agent permission that enables the task tool.
+	permission = "task"
+
+	defaultMaxTurns = 25
+	maxMaxTurns     = 100
+)
+
+// Server lets agents delegate open-ended work, like searches that take many
+// rounds of tool calls, to sub-agents that report back a summary.
+type Server struct {
+	tools  mcp.ServerTools
+	agents *agents.Agents
+}
+
+func NewServer(agentsService *agents.Agents) *Server {
+	s := &Server{
+		agents: agentsService,
+	}
+
+	s.tools = mcp.NewServerTools(
+		mcp.NewServerTool(agents.TaskToolName, `Launches a sub-agent to work on a task autonomously and returns its final report.
+
+The sub-agent starts with an empty conversation and has the same tools as you, except this one. Only its report is added to your conversation, so use it for open-ended searches and investigations that take many rounds of tool calls, to keep your own context small.
+
+Usage notes:
+- Give the sub-agent a complete, self-contained prompt. It can't see your conversation or ask questions, so include all of the context it needs and say exactly what it should report back.
+- Launch multiple sub-agents in a single response to explore independent questions in parallel.
+- Limit the sub-agent to specific tools with the tools parameter, for example only read, glob, and grep for a read-only search.
+- The sub-agent stops and reports what it has when it reaches maxTurns or maxTokens.`, s.task),
+		mcp.NewServerTool(agents.FanOutToolName, `Runs a task in several sub-agents at once and returns all of their reports when the last one is done.
+
+Each branch is a sub-agent that starts with an empty conversation and runs as you or one of your agents, with that agent's instructions and tools. Use it to get independent answers, reviews, or searches from several agents, then compare or merge their reports.
+
+Usage notes:
+- Give every branch a complete, self-contained prompt, or set prompt once for all branches. The sub-agents can't see your conversation or ask questions.
+- A branch that fails or doesn't finish within its timeout doesn't stop the others. Its result has the status failed or timedOut and the error.
+- At most 10 branches run in one fan-out.`, s.fanOut),
+		mcp.NewServerTool("config", "Adds the task and fanout tools to the agent config", s.config),
+	)
+
+	return s
+}
+
+func (s *Server) OnMessage(ctx context.Context, msg mcp.Message) {
+	switch msg.Method {
+	case "initialize":
+		mcp.Invoke(ctx, msg, s.initialize)
+	case "notifications/initialized":
+	case "notifications/cancelled":
+		mcp.HandleCancelled(ctx, msg)
+	case "tools/list":
+		mcp.Invoke(ctx, msg, s.tools.List)
+	case "tools/call":
+		mcp.Invoke(ctx, msg, s.tools.Call)
+	default:
+		msg.SendError(ctx, mcp.ErrRPCMethodNotFound.WithMessage("%v", msg.Method))
+	}
+}
+
+func (s *Server) initialize(_ context.Context, _ mcp.Message, params mcp.InitializeRequest) (*mcp.InitializeResult, error) {
+	return &mcp.InitializeResult{
+		ProtocolVersion: params.ProtocolVersion,
+		Capabilities:    mcp.ServerCapabilities{Tools: &mcp.ToolsServerCapability{}},
+		ServerInfo:      mcp.ServerInfo{Name: version.Name, Version: version.Get().String()},
+	}, nil
+}
+
+type taskParams struct {
+	Description string   `json:"description" jsonschema:"A short (3-5 word) description of the task"`
+	Prompt      string   `json:"prompt" jsonschema:"The complete task for the sub-agent to perform, including what to report back"`
+	Tools       []string `json:"tools,omitempty" jsonschema:"Names of the tools the sub-agent may use, defaults to all of your tools except this one"`
+	MaxTurns    *int     `json:"maxTurns,omitempty" jsonschema:"Maximum number of turns the sub-agent may take, defaults to 25"`
+	MaxTokens   *int     `json:"maxTokens,omitempty" jsonschema:"Approximate maximum number of tokens the sub-agent may use across its turns, unbounded by default"`
+}
+
+func (s *Server) task(ctx context.Context, params taskParams) (*agents.TaskResult, error) {
+	if strings.TrimSpace(params.Prompt) == "" {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("prompt is required")
+	}
+
+	maxTurns := defaultMaxTurns
+	if params.MaxTurns != nil {
+		if *params.MaxTurns <= 0 || *params.MaxTurns > maxMaxTurns {
+			return nil, mcp.ErrRPCInvalidParams.WithMessage("maxTurns must be between 1 and %d", maxMaxTurns)
+		}
+		maxTurns = *params.MaxTurns
+	}
+
+	var maxTokens int
+	if params.MaxTokens != nil {
+		if *params.MaxTokens <= 0 {
+			return nil, mcp.ErrRPCInvalidParams.WithMessage("maxTokens must be greater than zero")
+		}
+		maxTokens = *params.MaxTokens
+	}
+
+	agentName := types.CurrentAgent(ctx)
+	if agentName == "" {
+		return nil, mcp.ErrRPCInvalidRequest.WithMessage("no current agent to run the task as")
+	}
+
+	return s.agents.RunTask(ctx, agents.Task{
+		Agent:     agentName,
+		Prompt:    params.Prompt,
+		Tools:     params.Tools,
+		MaxTurns:  maxTurns,
+		MaxTokens: maxTokens,
+	})
+}
+
+type fanOutParams struct {
+	Prompt   string         `json:"prompt,omitempty" jsonschema:"The task of the branches that don't have a prompt of their own"`
+	Branches []branchParams `json:"branches" jsonschema:"The sub-agents to run, at most 10"`
+}
+
+type branchParams struct {
+	Agent          string   `json:"agent,omitempty" jsonschema:"The agent the branch runs as, you or one of your agents. Defaults to you"`
+	Prompt         string   `json:"prompt,omitempty" jsonschema:"The complete task for this branch, including what to report back"`
+	Tools          []string `json:"tools,omitempty" jsonschema:"Names of the tools the branch may use, defaults to all of the tools of its agent"`
+	TimeoutSeconds int      `json:"timeoutSeconds,omitempty" jsonschema:"Seconds the branch may run, defaults to 300"`
+	MaxTurns       *int     `json:"maxTurns,omitempty" jsonschema:"Maximum number of turns the branch may take, defaults to 25"`
+}
+
+func (s *Server) fanOut(ctx context.Context, params fanOutParams) (*agents.FanOutResult, error) {
+	currentAgent := types.CurrentAgent(ctx)
+	if currentAgent == "" {
+		return nil, mcp.ErrRPCInvalidRequest.WithMessage("no current agent to run the fan-out from")
+	}
+	allowed := append([]string{currentAgent}, types.ConfigFromContext(ctx).Agents[currentAgent].Agents...)
+
+	fanOut := agents.FanOut{
+		Prompt: params.Prompt,
+	}
+	for i, branch := range params.Branches {
+		if branch.Agent == "" {
+			branch.Agent = currentAgent
+		}
+		if !slices.Contains(allowed, branch.Agent) {
+			return nil, mcp.ErrRPCInvalidParams.WithMessage("branch %d can't run as %s, it must be one of %s", i, branch.Agent, strings.Join(allowed, ", "))
+		}
+		if branch.TimeoutSeconds < 0 {
+			return nil, mcp.ErrRPCInvalidParams.WithMessage("timeoutSeconds of branch %d must not be negative", i)
+		}
+		maxTurns := defaultMaxTurns
+		if branch.MaxTurns != nil {
+			if *branch.MaxTurns <= 0 || *branch.MaxTurns > maxMaxTurns {
+				return nil, mcp.ErrRPCInvalidParams.WithMessage("maxTurns of branch %d must be between 1 and %d", i, maxMaxTurns)
+			}
+			maxTurns = *branch.MaxTurns
+		}
+		fanOut.Branches = append(fanOut.Branches, agents.Branch{
+			Agent:    branch.Agent,
+			Prompt:   branch.Prompt,
+			Tools:    branch.Tools,
+			Timeout:  time.Duration(branch.TimeoutSeconds) * time.Second,
+			MaxTurns: maxTurns,
+		})
+	}
+
+	result, err := s.agents.RunFanOut(ctx, fanOut)
+	if err != nil {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("%v", err)
+	}
+	return result, nil
+}
+
+// config is an agent config hook that gives agents with the task permission
+// the task and fanout tools.
+func (s *Server) config(_ context.Context, params types.AgentConfigHook) (types.AgentConfigHook, error) {
+	agent := params.Agent
+	if agent == nil || agent.Name == "nanobot.summary" || (agent.Permissions != nil && !agent.Permissions.IsAllowed(permission)) {
+		return params, nil
+	}
+
+	agent.Tools = append(agent.Tools, ServerName+"/"+agents.TaskToolName, ServerName+"/"+agents.FanOutToolName)
+	if params.MCPServers == nil {
+		params.MCPServers = make(map[string]types.AgentConfigHookMCPServer, 1)
+	}
+	params.MCPServers[ServerName] = types.AgentConfigHookMCPServer{}
+
+	return params, nil
+}
@@ -0,0 +1,560 @@
+package system
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/obot-platform/nanobot/pkg/fileuri"
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+const (
+	archiveFormatZip   = "zip"
+	archiveFormatTar   = "tar"
+	archiveFormatTarGz = "tar.gz"
+
+	maxArchiveEntries          = 10_000
+	maxArchiveUncompressedSize = 500 * 1024 * 1024 // 500 MiB
+)
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// ExtractArchiveParams are the parameters for the extractArchive tool.
+type ExtractArchiveParams struct {
+	// Path is the archive to extract, relative to the session directory.
+	Path string `json:"path"`
+	// Destination is the directory to extract into, relative to the session
+	// directory. Defaults to the archive name without its extension.
+	Destination *string `json:"destination,omitempty"`
+}
+
+// CreateArchiveParams are the parameters for the createArchive tool.
+type CreateArchiveParams struct {
+	// Paths are the files and directories to include, relative to the session directory.
+	Paths []string `json:"paths"`
+	// Output is the archive to create, relative to the session directory.
+	Output string `json:"output"`
+	// Format is one of "zip", "tar", or "tar.gz". Inferred from the output extension when omitted.
+	Format *string `json:"format,omitempty"`
+}
+
+// resolveSessionPath resolves a path relative to the session directory, that
+// the tool writes to if write is set, and rejects anything that would escape
+// it. Paths in mounts resolve to their host directories, as with
+// workspacePath.
+func resolveSessionPath(ctx context.Context, name string, write bool) (string, string, error) {
+	relPath := filepath.Clean(name)
+	if filepath.IsAbs(relPath) || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return "", "", mcp.ErrRPCInvalidParams.WithMessage("invalid path %q: must be a relative path inside the session directory", name)
+	}
+	absPath, err := workspacePath(ctx, relPath, write)
+	if err != nil {
+		return "", "", err
+	}
+	return absPath, relPath, nil
+}
+
+// archiveFormat returns the archive format implied by a file name, or "" if unknown.
+func archiveFormat(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return archiveFormatZip
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return archiveFormatTarGz
+	case strings.HasSuffix(lower, ".tar"):
+		return archiveFormatTar
+	}
+	return ""
+}
+
+// trimArchiveExt strips a known archive extension from a file name.
+func trimArchiveExt(name string) string {
+	lower := strings.ToLower(name)
+	for _, ext := range []string{".tar.gz", ".tgz", ".tar", ".zip"} {
+		if strings.HasSuffix(lower, ext) {
+			return name[:len(name)-len(ext)]
+		}
+	}
+	return name + "-extracted"
+}
+
+func (s *Server) extractArchive(ctx context.Context, params ExtractArchiveParams) (string, error) {
+	if params.Path == "" {
+		return "", mcp.ErrRPCInvalidParams.WithMessage("path is required")
+	}
+
+	sessionID, _ := types.GetSessionAndAccountID(ctx)
+	if sessionID == "" {
+		return "", mcp.ErrRPCInvalidParams.WithMessage("session not found")
+	}
+	archivePath, relArchive, err := resolveSessionPath(ctx, params.Path, false)
+	if err != nil {
+		return "", err
+	}
+
+	destination := trimArchiveExt(relArchive)
+	if params.Destination != nil && *params.Destination != "" {
+		destination = *params.Destination
+	}
+	destPath, relDest, err := resolveSessionPath(ctx, destination, true)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return "", fmt.Errorf("error opening archive: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", fmt.Errorf("error reading archive: %w", err)
+	}
+
+	header := make([]byte, 4)
+	n, _ := io.ReadFull(f, header)
+	header = header[:n]
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("error reading archive: %w", err)
+	}
+
+	x := &archiveExtractor{
+		baseDir: destPath,
+		beforeWrite: func(path string) {
+			journalFile(ctx, path)
+		},
+	}
+	switch {
+	case bytes.HasPrefix(header, []byte("PK")):
+		err = x.extractZip(f, info.Size())
+	case bytes.HasPrefix(header, gzipMagic):
+		gz, gzErr := gzip.NewReader(bufio.NewReader(f))
+		if gzErr != nil {
+			return "", fmt.Errorf("invalid gzip archive: %w", gzErr)
+		}
+		defer gz.Close()
+		err = x.extractTar(gz)
+	case archiveFormat(relArchive) == archiveFormatTar:
+		err = x.extractTar(f)
+	default:
+		return "", mcp.ErrRPCInvalidParams.WithMessage("unsupported archive format for %s: expected .zip, .tar, .tar.gz, or .tgz", params.Path)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var result strings.Builder
+	fmt.Fprintf(&result, "Extracted %d files from %s to %s", len(x.extracted), relArchive, relDest)
+	if x.skipped > 0 {
+		fmt.Fprintf(&result, " (skipped %d links)", x.skipped)
+	}
+	result.WriteString("\n")
+	for _, name := range x.extracted {
+		result.WriteString(filepath.ToSlash(filepath.Join(relDest, filepath.FromSlash(name))))
+		result.WriteString("\n")
+	}
+	return result.String(), nil
+}
+
+// archiveExtractor writes archive entries under baseDir, enforcing entry count
+// and total size limits and refusing paths that escape baseDir.
+type archiveExtractor struct {
+	baseDir   string
+	entries   int
+	written   int64
+	extracted []string
+	skipped   int
+	// beforeWrite, when set, is called with each file before it is written.
+	beforeWrite func(path string)
+}
+
+func (x *archiveExtractor) destination(name string) (string, string, error) {
+	cleaned := path.Clean(strings.TrimPrefix(strings.ReplaceAll(name, "\\", "/"), "./"))
+	switch {
+	case cleaned == "." || cleaned == "":
+		return "", "", nil
+	case strings.HasPrefix(cleaned, "/"), filepath.VolumeName(filepath.FromSlash(cleaned)) != "":
+		return "", "", fmt.Errorf("absolute paths are not allowed in archive contents: %s", name)
+	case cleaned == ".." || strings.HasPrefix(cleaned, "../"):
+		return "", "", fmt.Errorf("path traversal is not allowed in archive contents: %s", name)
+	}
+	return filepath.Join(x.baseDir, filepath.FromSlash(cleaned)), cleaned, nil
+}
+
+func (x *archiveExtractor) count() error {
+	x.entries++
+	if x.entries > maxArchiveEntries {
+		return fmt.Errorf("archive contains more than %d entries", maxArchiveEntries)
+	}
+	return nil
+}
+
+func (x *archiveExtractor) writeFile(destPath, name string, mode fs.FileMode, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return fmt.Errorf("failed to create parent directory for %s: %w", name, err)
+	}
+
+	if x.beforeWrite != nil {
+		x.beforeWrite(destPath)
+	}
+
+	perm := mode.Perm() | 0600
+	out, err := os.OpenFile(destPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", name, err)
+	}
+
+	remaining := maxArchiveUncompressedSize - x.written
+	n, copyErr := io.Copy(out, io.LimitReader(r, remaining+1))
+	x.written += n
+	closeErr := out.Close()
+	if copyErr != nil {
+		return fmt.Errorf("failed to extract %s: %w", name, copyErr)
+	}
+	if x.written > maxArchiveUncompressedSize {
+		return fmt.Errorf("archive exceeds maximum uncompressed size of %d bytes", maxArchiveUncompressedSize)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("failed to close %s: %w", name, closeErr)
+	}
+
+	x.extracted = append(x.extracted, name)
+	return nil
+}
+
+func (x *archiveExtractor) extractZip(r io.ReaderAt, size int64) error {
+	reader, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("invalid zip archive: %w", err)
+	}
+
+	for _, file := range reader.File {
+		if err := x.count(); err != nil {
+			return err
+		}
+		destPath, name, err := x.destination(file.Name)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			continue
+		}
+
+		switch {
+		case file.Mode()&os.ModeSymlink != 0:
+			x.skipped++
+		case strings.HasSuffix(file.Name, "/") || file.FileInfo().IsDir():
+			if err := os.MkdirAll(destPath, 0755); err != nil {
+				return fmt.Errorf("failed to create directory %s: %w", name, err)
+			}
+		default:
+			rc, err := file.Open()
+			if err != nil {
+				return fmt.Errorf("failed to open zip entry %s: %w", name, err)
+			}
+			err = x.writeFile(destPath, name, file.Mode(), rc)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (x *archiveExtractor) extractTar(r io.Reader) error {
+	reader := tar.NewReader(r)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid tar archive: %w", err)
+		}
+
+		if err := x.count(); err != nil {
+			return err
+		}
+		destPath, name, err := x.destination(header.Name)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			continue
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(destPath, 0755); err != nil {
+				return fmt.Errorf("failed to create directory %s: %w", name, err)
+			}
+		case tar.TypeReg:
+			if err := x.writeFile(destPath, name, header.FileInfo().Mode(), reader); err != nil {
+				return err
+			}
+		case tar.TypeSymlink, tar.TypeLink:
+			x.skipped++
+		}
+	}
+}
+
+func (s *Server) createArchive(ctx context.Context, params CreateArchiveParams) (*mcp.Resource, error) {
+	if len(params.Paths) == 0 {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("paths is required")
+	}
+	if params.Output == "" {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("output is required")
+	}
+
+	format := archiveFormat(params.Output)
+	if params.Format != nil && *params.Format != "" {
+		format = *params.Format
+	}
+	if !slices.Contains([]string{archiveFormatZip, archiveFormatTar, archiveFormatTarGz}, format) {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("unsupported archive format %q, must be %q, %q, or %q",
+			format, archiveFormatZip, archiveFormatTar, archiveFormatTarGz)
+	}
+
+	sessionID, _ := types.GetSessionAndAccountID(ctx)
+	if sessionID == "" {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("session not found")
+	}
+	outputPath, relOutput, err := resolveSessionPath(ctx, params.Output, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []archiveSource
+	for _, p := range params.Paths {
+		source, relSource, err := resolveSessionPath(ctx, p, false)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Lstat(source); err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", p, err)
+		}
+		sources = append(sources, archiveSource{path: source, name: relSource})
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directories: %w", err)
+	}
+
+	// Write to a temporary file first so a failed run never leaves a partial
+	// archive behind, and so the output is not picked up while walking sources.
+	tmp, err := os.CreateTemp(filepath.Dir(outputPath), ".archive-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	err = writeArchive(tmp, format, sources, tmp.Name(), outputPath)
+	if closeErr := tmp.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write archive: %w", closeErr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	journalFile(ctx, outputPath)
+	if err := os.Rename(tmp.Name(), outputPath); err != nil {
+		return nil, fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	info, err := os.Stat(outputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	mimeType := "application/zip"
+	switch format {
+	case archiveFormatTar:
+		mimeType = "application/x-tar"
+	case archiveFormatTarGz:
+		mimeType = "application/gzip"
+	}
+
+	return &mcp.Resource{
+		URI:      fileuri.Encode(relOutput),
+		Name:     relOutput,
+		MimeType: mimeType,
+		Size:     info.Size(),
+		Annotations: &mcp.Annotations{
+			LastModified: info.ModTime(),
+		},
+	}, nil
+}
+
+// archiveWriter abstracts over zip and tar output so the source walk is shared.
+type archiveWriter interface {
+	addDir(name string, info fs.FileInfo) error
+	addFile(name string, info fs.FileInfo, r io.Reader) error
+	Close() error
+}
+
+// archiveSource is a file or directory to archive, with its path relative to
+// the session directory.
+type archiveSource struct {
+	path string
+	name string
+}
+
+// writeArchive adds every source under its path relative to the session
+// directory. Symbolic links and the excluded paths are skipped.
+func writeArchive(w io.Writer, format string, sources []archiveSource, exclude ...string) error {
+	var aw archiveWriter
+	switch format {
+	case archiveFormatZip:
+		aw = zipArchiveWriter{zip.NewWriter(w)}
+	case archiveFormatTar:
+		aw = tarArchiveWriter{tw: tar.NewWriter(w)}
+	case archiveFormatTarGz:
+		gz := gzip.NewWriter(w)
+		aw = tarArchiveWriter{tw: tar.NewWriter(gz), gz: gz}
+	}
+
+	var (
+		entries int
+		seen    = map[string]struct{}{}
+	)
+	for _, source := range sources {
+		err := filepath.WalkDir(source.path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if slices.Contains(exclude, p) || d.Type()&fs.ModeSymlink != 0 {
+				return nil
+			}
+
+			rel, err := filepath.Rel(source.path, p)
+			if err != nil {
+				return err
+			}
+			name := filepath.ToSlash(filepath.Join(source.name, rel))
+			if name == "." {
+				return nil
+			}
+			if _, ok := seen[name]; ok {
+				return nil
+			}
+			seen[name] = struct{}{}
+
+			entries++
+			if entries > maxArchiveEntries {
+				return fmt.Errorf("archive would contain more than %d entries", maxArchiveEntries)
+			}
+
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return aw.addDir(name, info)
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+
+			f, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			return aw.addFile(name, info, f)
+		})
+		if err != nil {
+			aw.Close()
+			return fmt.Errorf("failed to write archive: %w", err)
+		}
+	}
+
+	if err := aw.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return nil
+}
+
+type zipArchiveWriter struct {
+	zw *zip.Writer
+}
+
+func (z zipArchiveWriter) addDir(name string, info fs.FileInfo) error {
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name + "/"
+	_, err = z.zw.CreateHeader(header)
+	return err
+}
+
+func (z zipArchiveWriter) addFile(name string, info fs.FileInfo, r io.Reader) error {
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	header.Method = zip.Deflate
+	w, err := z.zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
+
+func (z zipArchiveWriter) Close() error {
+	return z.zw.Close()
+}
+
+type tarArchiveWriter struct {
+	tw *tar.Writer
+	gz *gzip.Writer
+}
+
+func (t tarArchiveWriter) addDir(name string, info fs.FileInfo) error {
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = name + "/"
+	return t.tw.WriteHeader(header)
+}
+
+func (t tarArchiveWriter) addFile(name string, info fs.FileInfo, r io.Reader) error {
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if err := t.tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(t.tw, r)
+	return err
+}
+
+func (t tarArchiveWriter) Close() error {
+	err := t.tw.Close()
+	if t.gz != nil {
+		if gzErr := t.gz.Close(); err == nil {
+			err = gzErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,91 @@
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestArchiveRoundTrip(t *testing.T) {
+	tmpDir := t.TempDir()
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(oldWd)
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := testContext(t)
+	sessDir := filepath.Join(tmpDir, sessionsDir, testSessionID)
+	for name, content := range map[string]string{
+		"report/summary.md":    "# Summary",
+		"report/data/rows.csv": "a,b\n1,2\n",
+	} {
+		path := filepath.Join(sessDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := &Server{}
+	for _, output := range []string{"out/report.zip", "out/report.tar", "out/report.tgz"} {
+		t.Run(output, func(t *testing.T) {
+			resource, err := s.createArchive(ctx, CreateArchiveParams{
+				Paths:  []string{"report"},
+				Output: output,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resource.Name != output || resource.Size == 0 {
+				t.Errorf("unexpected resource: %+v", resource)
+			}
+
+			result, err := s.extractArchive(ctx, ExtractArchiveParams{Path: output})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(result, "Extracted 2 files") {
+				t.Errorf("unexpected result: %s", result)
+			}
+
+			data, err := os.ReadFile(filepath.Join(sessDir, trimArchiveExt(output), "report", "data", "rows.csv"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != "a,b\n1,2\n" {
+				t.Errorf("unexpected extracted content: %q", data)
+			}
+		})
+	}
+
+	t.Run("rejects paths outside session directory", func(t *testing.T) {
+		if _, err := s.createArchive(ctx, CreateArchiveParams{Paths: []string{"../other"}, Output: "x.zip"}); err == nil {
+			t.Error("expected error for traversal in paths")
+		}
+		dest := "../escape"
+		if _, err := s.extractArchive(ctx, ExtractArchiveParams{Path: "out/report.zip", Destination: &dest}); err == nil {
+			t.Error("expected error for traversal in destination")
+		}
+	})
+
+	t.Run("rejects traversal inside archive", func(t *testing.T) {
+		writeZip(t, filepath.Join(sessDir, "evil.zip"), map[string]string{"../../evil.txt": "x"})
+		if _, err := s.extractArchive(ctx, ExtractArchiveParams{Path: "evil.zip"}); err == nil || !strings.Contains(err.Error(), "path traversal") {
+			t.Errorf("expected traversal error, got %v", err)
+		}
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		format := "rar"
+		if _, err := s.createArchive(ctx, CreateArchiveParams{Paths: []string{"report"}, Output: "x.rar", Format: &format}); err == nil {
+			t.Error("expected error for unsupported format")
+		}
+	})
+}
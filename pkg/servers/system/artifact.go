@@ -0,0 +1,245 @@
+package system
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/fileuri"
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// maxArtifacts is how many artifacts the server remembers, across sessions.
+// The oldest are forgotten first, and their files are then listed with the
+// MIME type of their extension.
+var maxArtifacts = 1000
+
+// artifact is a file in the session directory that the agent declared it is
+// producing, and that it writes in chunks.
+type artifact struct {
+	mimeType string
+	open     bool
+	started  time.Time
+}
+
+// StartArtifactParams are the parameters for the startArtifact tool.
+type StartArtifactParams struct {
+	Name     string `json:"name"`
+	MimeType string `json:"mimeType,omitempty"`
+}
+
+// AppendArtifactParams are the parameters for the appendArtifact tool.
+type AppendArtifactParams struct {
+	URI      string `json:"uri"`
+	Content  string `json:"content"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// FinishArtifactParams are the parameters for the finishArtifact tool.
+type FinishArtifactParams struct {
+	URI string `json:"uri"`
+}
+
+func artifactKey(sessionID, relPath string) string {
+	return sessionID + "/" + filepath.ToSlash(relPath)
+}
+
+// artifactMimeType returns the MIME type declared for an artifact of the
+// session, or "" if the file isn't an artifact.
+func (s *Server) artifactMimeType(sessionID, relPath string) string {
+	s.artifactsMu.Lock()
+	defer s.artifactsMu.Unlock()
+	if a, ok := s.artifacts[artifactKey(sessionID, relPath)]; ok {
+		return a.mimeType
+	}
+	return ""
+}
+
+// forgetArtifacts forgets the artifacts of the session at relPath or under it,
+// once their files are deleted.
+func (s *Server) forgetArtifacts(sessionID, relPath string) {
+	key := artifactKey(sessionID, relPath)
+	s.artifactsMu.Lock()
+	defer s.artifactsMu.Unlock()
+	for k := range s.artifacts {
+		if k == key || strings.HasPrefix(k, key+"/") {
+			delete(s.artifacts, k)
+		}
+	}
+}
+
+// evictArtifactsLocked forgets the oldest artifacts to make room for a new
+// one.
+func (s *Server) evictArtifactsLocked() {
+	for len(s.artifacts) >= maxArtifacts {
+		var oldest string
+		for key, a := range s.artifacts {
+			if oldest == "" || a.started.Before(s.artifacts[oldest].started) {
+				oldest = key
+			}
+		}
+		delete(s.artifacts, oldest)
+	}
+}
+
+func (s *Server) startArtifact(ctx context.Context, params StartArtifactParams) (*mcp.Resource, error) {
+	if params.Name == "" {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("name is required")
+	}
+
+	sessionID, _ := types.GetSessionAndAccountID(ctx)
+	if sessionID == "" {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("session not found")
+	}
+	absPath, relPath, err := resolveSessionPath(ctx, fileuri.SafeFilename(params.Name), true)
+	if err != nil {
+		return nil, err
+	}
+
+	mimeType := params.MimeType
+	if mimeType == "" {
+		mimeType = mime.TypeByExtension(filepath.Ext(relPath))
+	}
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+
+	if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directories: %w", err)
+	}
+	journalFile(ctx, absPath)
+	if err := os.WriteFile(absPath, nil, 0644); err != nil {
+		return nil, fmt.Errorf("failed to create artifact: %w", err)
+	}
+
+	s.artifactsMu.Lock()
+	if s.artifacts == nil {
+		s.artifacts = map[string]*artifact{}
+	}
+	key := artifactKey(sessionID, relPath)
+	if _, ok := s.artifacts[key]; !ok {
+		s.evictArtifactsLocked()
+	}
+	s.artifacts[key] = &artifact{
+		mimeType: mimeType,
+		open:     true,
+		started:  time.Now(),
+	}
+	s.artifactsMu.Unlock()
+
+	if s.subscriptions != nil {
+		s.subscriptions.SendListChangedNotification()
+	}
+
+	return artifactResource(absPath, relPath, mimeType)
+}
+
+func (s *Server) appendArtifact(ctx context.Context, params AppendArtifactParams) (*mcp.Resource, error) {
+	data := []byte(params.Content)
+	switch params.Encoding {
+	case "", "text":
+	case "base64":
+		var err error
+		if data, err = base64.StdEncoding.DecodeString(params.Content); err != nil {
+			return nil, mcp.ErrRPCInvalidParams.WithMessage("invalid base64 content: %v", err)
+		}
+	default:
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("unsupported encoding %q, must be \"text\" or \"base64\"", params.Encoding)
+	}
+
+	absPath, relPath, a, err := s.openArtifact(ctx, params.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkDiskQuota(ctx, int64(len(data))); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(absPath, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open artifact: %w", err)
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write artifact: %w", err)
+	}
+
+	if s.subscriptions != nil {
+		s.subscriptions.SendResourceUpdatedNotification(fileuri.Encode(relPath))
+	}
+
+	return artifactResource(absPath, relPath, a.mimeType)
+}
+
+func (s *Server) finishArtifact(ctx context.Context, params FinishArtifactParams) (*mcp.Resource, error) {
+	absPath, relPath, a, err := s.openArtifact(ctx, params.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	s.artifactsMu.Lock()
+	a.open = false
+	s.artifactsMu.Unlock()
+
+	if s.subscriptions != nil {
+		s.subscriptions.SendResourceUpdatedNotification(fileuri.Encode(relPath))
+	}
+
+	return artifactResource(absPath, relPath, a.mimeType)
+}
+
+// openArtifact resolves the URI of an artifact of the session that is still
+// being written.
+func (s *Server) openArtifact(ctx context.Context, uri string) (string, string, *artifact, error) {
+	if uri == "" {
+		return "", "", nil, mcp.ErrRPCInvalidParams.WithMessage("uri is required")
+	}
+	name, err := fileuri.Decode(uri)
+	if err != nil {
+		return "", "", nil, mcp.ErrRPCInvalidParams.WithMessage("%v", err)
+	}
+
+	sessionID, _ := types.GetSessionAndAccountID(ctx)
+	if sessionID == "" {
+		return "", "", nil, mcp.ErrRPCInvalidParams.WithMessage("session not found")
+	}
+	absPath, relPath, err := resolveSessionPath(ctx, name, true)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	s.artifactsMu.Lock()
+	a, ok := s.artifacts[artifactKey(sessionID, relPath)]
+	open := ok && a.open
+	s.artifactsMu.Unlock()
+	if !open {
+		return "", "", nil, mcp.ErrRPCInvalidParams.WithMessage("no artifact is being written to %s, call startArtifact first", uri)
+	}
+	return absPath, relPath, a, nil
+}
+
+func artifactResource(absPath, relPath, mimeType string) (*mcp.Resource, error) {
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat artifact: %w", err)
+	}
+	return &mcp.Resource{
+		URI:      fileuri.Encode(relPath),
+		Name:     relPath,
+		MimeType: mimeType,
+		Size:     info.Size(),
+		Annotations: &mcp.Annotations{
+			LastModified: info.ModTime(),
+		},
+	}, nil
+}
@@ -0,0 +1,103 @@
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestArtifact(t *testing.T) {
+	tmpDir := t.TempDir()
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(oldWd)
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := testContext(t)
+	s := &Server{}
+
+	resource, err := s.startArtifact(ctx, StartArtifactParams{Name: "reports/summary.txt", MimeType: "text/markdown"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resource.URI != "file:///reports/summary.txt" || resource.MimeType != "text/markdown" {
+		t.Errorf("unexpected resource: %+v", resource)
+	}
+
+	for _, params := range []AppendArtifactParams{
+		{URI: resource.URI, Content: "# Summary\n"},
+		{URI: resource.URI, Content: "QWxsIGdvb2QuCg==", Encoding: "base64"},
+	} {
+		if _, err := s.appendArtifact(ctx, params); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resource, err = s.finishArtifact(ctx, FinishArtifactParams{URI: resource.URI})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resource.Size != int64(len("# Summary\nAll good.\n")) {
+		t.Errorf("unexpected size %d", resource.Size)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, sessionsDir, testSessionID, "reports", "summary.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "# Summary\nAll good.\n" {
+		t.Errorf("unexpected content: %q", data)
+	}
+
+	result, err := s.readFileResource(ctx, resource.URI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Contents[0].MIMEType != "text/markdown" {
+		t.Errorf("expected the declared MIME type, got %q", result.Contents[0].MIMEType)
+	}
+
+	if _, err := s.appendArtifact(ctx, AppendArtifactParams{URI: resource.URI, Content: "more"}); err == nil {
+		t.Error("expected appending to a finished artifact to fail")
+	}
+	if _, err := s.appendArtifact(ctx, AppendArtifactParams{URI: "file:///other.txt", Content: "more"}); err == nil {
+		t.Error("expected appending to a file that isn't an artifact to fail")
+	}
+	if _, err := s.startArtifact(ctx, StartArtifactParams{Name: "../escape.txt"}); err == nil {
+		t.Error("expected an artifact outside the session directory to fail")
+	}
+
+	if _, err := s.deleteFile(ctx, DeleteFileParams{URI: "file:///reports"}); err != nil {
+		t.Fatal(err)
+	}
+	if mimeType := s.artifactMimeType(testSessionID, "reports/summary.txt"); mimeType != "" {
+		t.Errorf("expected a deleted artifact to be forgotten, got %q", mimeType)
+	}
+}
+
+func TestArtifactEviction(t *testing.T) {
+	t.Chdir(t.TempDir())
+	defer func(v int) { maxArtifacts = v }(maxArtifacts)
+	maxArtifacts = 2
+
+	ctx := testContext(t)
+	s := &Server{}
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		if _, err := s.startArtifact(ctx, StartArtifactParams{Name: name, MimeType: "text/markdown"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(s.artifacts) != 2 {
+		t.Errorf("expected 2 artifacts, got %d", len(s.artifacts))
+	}
+	if mimeType := s.artifactMimeType(testSessionID, "a.txt"); mimeType != "" {
+		t.Errorf("expected the oldest artifact to be forgotten, got %q", mimeType)
+	}
+	if mimeType := s.artifactMimeType(testSessionID, "c.txt"); mimeType != "text/markdown" {
+		t.Errorf("expected the newest artifact to be kept, got %q", mimeType)
+	}
+}
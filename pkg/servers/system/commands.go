@@ -0,0 +1,177 @@
+package system
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+const (
+	commandsURI = "commands:///list"
+
+	// maxCommandOutputPreview bounds the output kept with each recorded
+	// command. The hash always covers the full output.
+	maxCommandOutputPreview = 2 * 1024
+)
+
+// CommandRecord is the provenance of a command run by the bash tool.
+type CommandRecord struct {
+	Command     string    `json:"command"`
+	Description string    `json:"description,omitempty"`
+	Workdir     string    `json:"workdir"`
+	StartedAt   time.Time `json:"startedAt"`
+	DurationMS  int64     `json:"durationMs"`
+	// ExitCode is -1 when the command timed out or could not be started.
+	ExitCode        int    `json:"exitCode"`
+	TimedOut        bool   `json:"timedOut,omitempty"`
+	Error           string `json:"error,omitempty"`
+	OutputBytes     int    `json:"outputBytes"`
+	OutputSHA256    string `json:"outputSHA256"`
+	Output          string `json:"output,omitempty"`
+	OutputTruncated bool   `json:"outputTruncated,omitempty"`
+}
+
+func newCommandRecord(params BashParams, workdir string, start time.Time, output []byte) CommandRecord {
+	sum := sha256.Sum256(output)
+	record := CommandRecord{
+		Command:      params.Command,
+		Workdir:      workdir,
+		StartedAt:    start.UTC(),
+		DurationMS:   time.Since(start).Milliseconds(),
+		OutputBytes:  len(output),
+		OutputSHA256: hex.EncodeToString(sum[:]),
+		Output:       string(output),
+	}
+	if params.Description != nil {
+		record.Description = *params.Description
+	}
+	if len(output) > maxCommandOutputPreview {
+		record.Output = strings.ToValidUTF8(string(output[:maxCommandOutputPreview]), "")
+		record.OutputTruncated = true
+	} else if !utf8.Valid(output) {
+		record.Output = strings.ToValidUTF8(record.Output, "")
+	}
+	return record
+}
+
+func commandsPath(sessionID string) string {
+	return filepath.Join(".nanobot", sessionID, "status", "commands.jsonl")
+}
+
+// recordCommand appends the record to the session's command log. Failing to
+// record is logged rather than failing the command that already ran.
+func (s *Server) recordCommand(ctx context.Context, record CommandRecord) error {
+	sessionID, _ := types.GetSessionAndAccountID(ctx)
+	path := commandsPath(sessionID)
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	s.commandsMu.Lock()
+	defer s.commandsMu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create command log directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open command log: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write command log: %w", err)
+	}
+
+	s.subscriptions.SendResourceUpdatedNotification(commandsURI)
+	return nil
+}
+
+// listCommandResources returns the command log resource.
+func (s *Server) listCommandResources() []mcp.Resource {
+	return []mcp.Resource{
+		{
+			URI:         commandsURI,
+			Name:        "Commands",
+			Description: "Every command run by the bash tool in this session, with its working directory, exit code, duration, and output hash",
+			MimeType:    "application/json",
+		},
+	}
+}
+
+// readCommandResource reads the command log as a JSON array.
+func (s *Server) readCommandResource(ctx context.Context, uri string) (*mcp.ReadResourceResult, error) {
+	if uri != commandsURI {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("invalid commands URI, expected %s", commandsURI)
+	}
+
+	sessionID, _ := types.GetSessionAndAccountID(ctx)
+	records, err := s.readCommandRecords(commandsPath(sessionID))
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	content := string(data)
+
+	return &mcp.ReadResourceResult{
+		Contents: []mcp.ResourceContent{
+			{
+				URI:      uri,
+				Name:     "Commands",
+				MIMEType: "application/json",
+				Text:     &content,
+			},
+		},
+	}, nil
+}
+
+func (s *Server) readCommandRecords(path string) ([]CommandRecord, error) {
+	s.commandsMu.Lock()
+	defer s.commandsMu.Unlock()
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return []CommandRecord{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read command log: %w", err)
+	}
+
+	records := []CommandRecord{}
+	for line := range bytes.Lines(data) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var record CommandRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return nil, fmt.Errorf("failed to parse command log: %w", err)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// subscribeCommandResource subscribes to the command log resource.
+func (s *Server) subscribeCommandResource(uri string) error {
+	if uri != commandsURI {
+		return mcp.ErrRPCInvalidParams.WithMessage("invalid commands URI, expected %s", commandsURI)
+	}
+	// Subscription is handled by the shared subscription manager
+	return nil
+}
@@ -0,0 +1,77 @@
+package system
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBashRecordsCommands(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	server := NewServer("", ".nanobot")
+	ctx := testContext(t)
+	root, err := workspaceRoot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	workdir := filepath.Join(root, "work")
+	if err := os.Mkdir(workdir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := server.bash(ctx, BashParams{Command: "echo hello", Workdir: &workdir}); err != nil {
+		t.Fatalf("bash failed: %v", err)
+	}
+	if _, err := server.bash(ctx, BashParams{Command: "printf '%03000d' 0; exit 3", Workdir: &workdir}); err != nil {
+		t.Fatalf("bash failed: %v", err)
+	}
+
+	result, err := server.readCommandResource(ctx, commandsURI)
+	if err != nil {
+		t.Fatalf("readCommandResource failed: %v", err)
+	}
+
+	var records []CommandRecord
+	if err := json.Unmarshal([]byte(*result.Contents[0].Text), &records); err != nil {
+		t.Fatalf("failed to parse commands: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("recorded %d commands, want 2", len(records))
+	}
+
+	first := records[0]
+	sum := sha256.Sum256([]byte("hello\n"))
+	if first.Command != "echo hello" || first.Workdir != workdir || first.ExitCode != 0 ||
+		first.OutputSHA256 != hex.EncodeToString(sum[:]) || first.Output != "hello\n" {
+		t.Errorf("unexpected first record: %+v", first)
+	}
+
+	second := records[1]
+	if second.ExitCode != 3 {
+		t.Errorf("exit code = %d, want 3", second.ExitCode)
+	}
+	if second.OutputBytes != 3000 || !second.OutputTruncated || len(second.Output) != maxCommandOutputPreview {
+		t.Errorf("expected truncated output preview, got %d bytes, truncated %v", len(second.Output), second.OutputTruncated)
+	}
+	if !strings.HasPrefix(second.Output, "000") {
+		t.Errorf("output preview = %q", second.Output[:10])
+	}
+}
+
+func TestReadCommandResourceEmpty(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	server := NewServer("", ".nanobot")
+	result, err := server.readCommandResource(testContext(t), commandsURI)
+	if err != nil {
+		t.Fatalf("readCommandResource failed: %v", err)
+	}
+	if *result.Contents[0].Text != "[]" {
+		t.Fatalf("commands = %s, want []", *result.Contents[0].Text)
+	}
+}
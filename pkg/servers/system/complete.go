@@ -0,0 +1,97 @@
+package system
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// fileTemplateURI is the resource template of the files of the session
+// directory, so clients can build the URI of any file, listed or not.
+const fileTemplateURI = "file:///{path*}"
+
+// maxCompletions is how many values a completion holds at most, as the MCP
+// spec allows.
+const maxCompletions = 100
+
+func (s *Server) resourceTemplatesList(context.Context, mcp.Message, mcp.ListResourceTemplatesRequest) (*mcp.ListResourceTemplatesResult, error) {
+	return &mcp.ListResourceTemplatesResult{
+		ResourceTemplates: []mcp.ResourceTemplate{{
+			URITemplate: fileTemplateURI,
+			Name:        "file",
+			Description: "A file of the session directory, by its path relative to the directory.",
+		}},
+	}, nil
+}
+
+// complete completes the path argument of the file template with the files
+// and directories of the workspace.
+func (s *Server) complete(ctx context.Context, _ mcp.Message, request mcp.CompleteRequest) (*mcp.CompleteResult, error) {
+	result := &mcp.CompleteResult{Completion: mcp.Completion{Values: []string{}}}
+	sessionID, _ := types.GetSessionAndAccountID(ctx)
+	if sessionID == "" || request.Ref.Type != mcp.CompleteRefResource ||
+		request.Ref.URI != fileTemplateURI || request.Argument.Name != "path" {
+		return result, nil
+	}
+
+	values := completePath(ctx, request.Argument.Value)
+	result.Completion.Total = len(values)
+	if len(values) > maxCompletions {
+		values = values[:maxCompletions]
+		result.Completion.HasMore = true
+	}
+	result.Completion.Values = append(result.Completion.Values, values...)
+	return result, nil
+}
+
+// completePath returns the paths of the workspace that start with the value,
+// with a trailing slash for directories, so a UI completes one directory at a
+// time.
+func completePath(ctx context.Context, value string) []string {
+	dir, prefix := "", value
+	if i := strings.LastIndex(value, "/"); i >= 0 {
+		dir, prefix = value[:i+1], value[i+1:]
+	}
+
+	var (
+		config = types.ConfigFromContext(ctx)
+		names  []string
+	)
+	if dir == types.MountsDir+"/" {
+		for name := range config.Mounts {
+			names = append(names, name+"/")
+		}
+	} else if absDir, err := workspacePath(ctx, dir, false); err == nil {
+		// Directories that can't be read, or are outside of the workspace,
+		// have nothing to complete.
+		entries, _ := os.ReadDir(absDir)
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() {
+				name += "/"
+			}
+			names = append(names, name)
+		}
+		if dir == "" && len(config.Mounts) > 0 && !slices.Contains(names, types.MountsDir+"/") {
+			names = append(names, types.MountsDir+"/")
+		}
+	}
+
+	var values []string
+	for _, name := range names {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		// Hidden files are only completed once their dot is typed.
+		if strings.HasPrefix(name, ".") && !strings.HasPrefix(prefix, ".") {
+			continue
+		}
+		values = append(values, dir+name)
+	}
+	slices.Sort(values)
+	return values
+}
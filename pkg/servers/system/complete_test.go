@@ -0,0 +1,85 @@
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+func TestCompleteFilePath(t *testing.T) {
+	tmpDir := t.TempDir()
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(oldWd)
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+
+	root := filepath.Join(tmpDir, sessionsDir, testSessionID)
+	for _, file := range []string{"notes/todo.md", "notes/ideas.md", "news.txt", ".env", "report.pdf"} {
+		path := filepath.Join(root, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := types.WithConfig(testContext(t), types.Config{Mounts: map[string]types.Mount{
+		"docs": {Path: tmpDir},
+	}})
+	s := &Server{}
+
+	templates, err := s.resourceTemplatesList(ctx, mcp.Message{}, mcp.ListResourceTemplatesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates.ResourceTemplates) != 1 || templates.ResourceTemplates[0].URITemplate != fileTemplateURI {
+		t.Fatalf("unexpected templates: %+v", templates.ResourceTemplates)
+	}
+
+	for _, test := range []struct {
+		value string
+		want  []string
+	}{
+		{value: "n", want: []string{"news.txt", "notes/"}},
+		{value: "notes/", want: []string{"notes/ideas.md", "notes/todo.md"}},
+		{value: "notes/t", want: []string{"notes/todo.md"}},
+		{value: ".", want: []string{".env"}},
+		{value: "m", want: []string{"mounts/"}},
+		{value: "mounts/", want: []string{"mounts/docs/"}},
+		{value: "../", want: nil},
+	} {
+		result, err := s.complete(ctx, mcp.Message{}, mcp.CompleteRequest{
+			Ref:      mcp.CompleteReference{Type: mcp.CompleteRefResource, URI: fileTemplateURI},
+			Argument: mcp.CompleteArgument{Name: "path", Value: test.value},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(result.Completion.Values, test.want) {
+			t.Errorf("completing %q: expected %v, got %v", test.value, test.want, result.Completion.Values)
+		}
+		if result.Completion.Total != len(test.want) {
+			t.Errorf("completing %q: expected a total of %d, got %d", test.value, len(test.want), result.Completion.Total)
+		}
+	}
+
+	result, err := s.complete(ctx, mcp.Message{}, mcp.CompleteRequest{
+		Ref:      mcp.CompleteReference{Type: mcp.CompleteRefPrompt, Name: "review"},
+		Argument: mcp.CompleteArgument{Name: "path", Value: "n"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Completion.Values) != 0 {
+		t.Errorf("expected no completions for a prompt, got %v", result.Completion.Values)
+	}
+}
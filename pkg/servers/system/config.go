@@ -1,6 +1,7 @@
 package system
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"maps"
@@ -17,14 +18,18 @@ import (
 var allowedPermsToTools = map[string][]string{
 	"bash":            {"bash"},
 	"read":            {"read"},
-	"write":           {"write", "edit"},
+	"write":           {"write", "edit", "cleanupFiles"},
 	"edit":            {"edit"},
 	"glob":            {"glob"},
 	"grep":            {"grep"},
 	"todoWrite":       {"todoWrite"},
 	"webFetch":        {"webFetch"},
-	"skills":          {"getSkill"},
+	"skills":          {"getSkill", "searchSkills"},
 	"askUserQuestion": {"askUserQuestion"},
+	"archive":         {"extractArchive", "createArchive"},
+	"imageTransform":  {"imageTransform"},
+	"ocr":             {"ocr"},
+	"artifact":        {"startArtifact", "appendArtifact", "finishArtifact"},
 }
 
 func (s *Server) config(ctx context.Context, params types.AgentConfigHook) (types.AgentConfigHook, error) {
@@ -34,20 +39,19 @@ func (s *Server) config(ctx context.Context, params types.AgentConfigHook) (type
 		envMap = session.GetEnvMap()
 	}
 	if agent := params.Agent; agent != nil && agent.Name != "nanobot.summary" {
-		if agent.Model == "" {
+		if agent.Model.Primary() == "" {
 			// Ensure the model is set so that we count tokens accordingly for compaction.
-			agent.Model = envMap["NANOBOT_DEFAULT_MODEL"]
-			if agent.Model == "" {
-				agent.Model = s.defaultModel
-			}
+			agent.Model = types.AgentModel{cmp.Or(envMap["NANOBOT_DEFAULT_MODEL"], s.defaultModel)}
 		}
 
 		for _, perm := range agent.Permissions.Allowed(maps.Keys(allowedPermsToTools)) {
 			for _, tool := range allowedPermsToTools[perm] {
-				agent.Tools = append(agent.Tools, "nanobot.system/"+tool)
+				if toolEnabled(ctx, tool) {
+					agent.Tools = append(agent.Tools, "nanobot.system/"+tool)
+				}
 			}
 
-			if perm == "skills" {
+			if perm == "skills" && moduleEnabled(ctx, ModuleSkills) {
 				// Get all available skills (built-in + user-defined)
 				skillsList, err := s.listSkills(ctx, struct{}{})
 				if err != nil {
@@ -81,10 +85,7 @@ func (s *Server) config(ctx context.Context, params types.AgentConfigHook) (type
 				agent.Tools = append(agent.Tools, "nanobot.workflow-tools")
 				agent.Tools = append(agent.Tools, "nanobot.artifacts")
 				agent.Tools = append(agent.Tools, "nanobot.tasks")
-
-				if envMap["OBOT_URL"] != "" {
-					agent.Tools = append(agent.Tools, "nanobot.skills")
-				}
+				agent.Tools = append(agent.Tools, "nanobot.skills")
 			}
 		}
 
@@ -123,11 +124,13 @@ Do NOT put skill files in the session directory or workflow directory.
 		params.MCPServers["nanobot.workflow-tools"] = types.AgentConfigHookMCPServer{}
 		params.MCPServers["nanobot.artifacts"] = types.AgentConfigHookMCPServer{}
 		params.MCPServers["nanobot.tasks"] = types.AgentConfigHookMCPServer{}
-		if envMap["OBOT_URL"] != "" && agent.Permissions != nil && agent.Permissions.IsAllowed("skills") {
+		if agent.Permissions != nil && agent.Permissions.IsAllowed("skills") && moduleEnabled(ctx, ModuleSkills) {
 			params.MCPServers["nanobot.skills"] = types.AgentConfigHookMCPServer{}
 		}
 
-		obotmcp.ConfigureIntegration(ctx, agent, &params)
+		if moduleEnabled(ctx, ModuleDynamicMCP) {
+			obotmcp.ConfigureIntegration(ctx, agent, &params)
+		}
 
 		if envMap["OBOT_URL"] != "" {
 			agent.Instructions.Instructions += messagePolicyPrompt
@@ -489,7 +489,7 @@ func TestConfigSkillsPermissionAddsNanobotSkillsWithObotURL(t *testing.T) {
 	}
 }
 
-func TestConfigSkillsPermissionSkipsNanobotSkillsWithoutObotURL(t *testing.T) {
+func TestConfigSkillsPermissionAddsNanobotSkillsWithoutObotURL(t *testing.T) {
 	server := NewServer("", "")
 	ctx := context.Background()
 	session := mcp.NewEmptySession(ctx)
@@ -509,13 +509,12 @@ func TestConfigSkillsPermissionSkipsNanobotSkillsWithoutObotURL(t *testing.T) {
 		t.Fatalf("config() failed: %v", err)
 	}
 
-	if _, ok := result.MCPServers["nanobot.skills"]; ok {
-		t.Fatal("did not expect nanobot.skills MCP server without OBOT_URL")
+	// Installing skills from a URL and removing them does not need Obot.
+	if _, ok := result.MCPServers["nanobot.skills"]; !ok {
+		t.Fatal("expected nanobot.skills MCP server to be injected without OBOT_URL")
 	}
 
-	for _, tool := range result.Agent.Tools {
-		if tool == "nanobot.skills" {
-			t.Fatal("did not expect nanobot.skills tool without OBOT_URL")
-		}
+	if !slices.Contains(result.Agent.Tools, "nanobot.skills") {
+		t.Fatal("expected nanobot.skills to be added to agent tools without OBOT_URL")
 	}
 }
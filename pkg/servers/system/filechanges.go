@@ -0,0 +1,98 @@
+package system
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"unicode/utf8"
+
+	"github.com/hexops/gotextdiff"
+	"github.com/hexops/gotextdiff/myers"
+	"github.com/hexops/gotextdiff/span"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+const (
+	// maxDiffFileSize is the size of the largest text file whose changes are
+	// diffed in update notifications. Larger files only report their size
+	// and modification time.
+	maxDiffFileSize = 64 * 1024
+	// maxDiffSize is the size of the largest diff sent in an update
+	// notification.
+	maxDiffSize = 16 * 1024
+)
+
+// snapshotFile records the content of a subscribed file, to diff its next
+// change against.
+func (s *Server) snapshotFile(path string) {
+	content, ok := readDiffable(path)
+
+	s.snapshotsMu.Lock()
+	defer s.snapshotsMu.Unlock()
+	if ok {
+		s.snapshots[path] = content
+	} else {
+		delete(s.snapshots, path)
+	}
+}
+
+// forgetFile drops the snapshot of a file that is deleted or no longer
+// subscribed.
+func (s *Server) forgetFile(path string) {
+	s.snapshotsMu.Lock()
+	defer s.snapshotsMu.Unlock()
+	delete(s.snapshots, path)
+}
+
+// fileChange returns the _meta of the update notification of a changed file,
+// describing the change since its last snapshot, and snapshots it again.
+func (s *Server) fileChange(path, name string) map[string]any {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+	change := types.ResourceChange{
+		Size:     info.Size(),
+		Modified: info.ModTime(),
+	}
+
+	content, ok := readDiffable(path)
+
+	s.snapshotsMu.Lock()
+	before, hadBefore := s.snapshots[path]
+	if ok {
+		s.snapshots[path] = content
+	} else {
+		delete(s.snapshots, path)
+	}
+	s.snapshotsMu.Unlock()
+
+	if ok && hadBefore && before != content {
+		change.Diff = unifiedDiff(name, before, content)
+	}
+	return map[string]any{types.ResourceChangeMetaKey: change}
+}
+
+// readDiffable reads a file if it is text small enough to diff.
+func readDiffable(path string) (string, bool) {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() || info.Size() > maxDiffFileSize {
+		return "", false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || len(data) > maxDiffFileSize || bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data) {
+		return "", false
+	}
+	return string(data), true
+}
+
+// unifiedDiff returns the unified diff of two versions of a file, or "" if it
+// is too large to send.
+func unifiedDiff(name, before, after string) string {
+	edits := myers.ComputeEdits(span.URIFromPath(name), before, after)
+	diff := fmt.Sprint(gotextdiff.ToUnified("a/"+name, "b/"+name, before, edits))
+	if len(diff) > maxDiffSize {
+		return ""
+	}
+	return diff
+}
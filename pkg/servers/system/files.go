@@ -5,14 +5,18 @@ import (
 	"encoding/base64"
 	"fmt"
 	"io"
+	"maps"
 	"mime"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 
 	"github.com/obot-platform/nanobot/pkg/fileuri"
 	"github.com/obot-platform/nanobot/pkg/fswatch"
+	"github.com/obot-platform/nanobot/pkg/ignore"
+	"github.com/obot-platform/nanobot/pkg/journal"
 	"github.com/obot-platform/nanobot/pkg/mcp"
 	"github.com/obot-platform/nanobot/pkg/types"
 	"log/slog"
@@ -29,68 +33,15 @@ func init() {
 	}
 }
 
-var (
-	// Common directories/patterns to exclude from watching
-	excludedDirs = map[string]struct{}{
-		"node_modules": {},
-		"vendor":       {},
-		"__pycache__":  {},
-		"dist":         {},
-		"build":        {},
-		"bin":          {},
-		".git":         {},
-		".svn":         {},
-		".jj":          {},
-		".vscode":      {},
-		".idea":        {},
-		".nanobot":     {},
-		"sessions":     {},
-	}
-
-	excludedFiles = map[string]struct{}{
-		"nanobot.db":         {},
-		"nanobot.db-journal": {},
-		".DS_Store":          {},
-	}
-)
-
-// fileFilter determines if a file or directory should be included in file watching.
-func fileFilter(relPath string, info os.FileInfo) bool {
-	if relPath == "." {
-		return true
-	}
-
-	basename := filepath.Base(relPath)
-
-	// Check if basename is an excluded directory or file
-	if info.IsDir() {
-		if _, excluded := excludedDirs[basename]; excluded {
-			return false
-		}
-	} else {
-		if _, excluded := excludedFiles[basename]; excluded {
-			return false
-		}
-	}
-
-	// Check parent path components for directory exclusions
-	parts := strings.Split(relPath, string(filepath.Separator))
-
-	// Check all parts except the last (which we already checked above)
-	for i := 0; i < len(parts)-1; i++ {
-		part := parts[i]
-
-		// Exclude specific directories
-		if _, excluded := excludedDirs[part]; excluded {
-			return false
-		}
-	}
-
-	return true
+// fileFilter determines if a file or directory under root should be included
+// in file listing and watching, by the defaults and the .gitignore and
+// .nanobotignore files of root.
+func fileFilter(root string) fswatch.FilterFunc {
+	return ignore.New(root, ignore.Defaults...).Filter
 }
 
-// handleFileEvents processes filesystem events from the watcher.
-func (s *Server) handleFileEvents(events []fswatch.Event) {
+// handleFileEvents processes filesystem events from the watcher of a directory.
+func (s *Server) handleFileEvents(dir string, events []fswatch.Event) {
 	for _, event := range events {
 		uri := fileuri.Encode(event.Path)
 
@@ -100,98 +51,112 @@ func (s *Server) handleFileEvents(events []fswatch.Event) {
 			s.subscriptions.SendResourceUpdatedNotification(uri)
 			s.subscriptions.AutoUnsubscribe(uri)
 			s.subscriptions.SendListChangedNotification()
+			s.forgetFile(filepath.Join(dir, event.Path))
 
 		case fswatch.EventCreate:
 			// New file created - send list changed
 			s.subscriptions.SendListChangedNotification()
 
 		case fswatch.EventWrite:
-			// File modified - send updated notification
-			s.subscriptions.SendResourceUpdatedNotification(uri)
+			// File modified - send updated notification, describing the
+			// change if anyone is subscribed
+			var meta map[string]any
+			if s.subscriptions.IsSubscribed(uri) {
+				meta = s.fileChange(filepath.Join(dir, event.Path), filepath.ToSlash(event.Path))
+			}
+			s.subscriptions.SendResourceUpdatedNotificationWithMeta(uri, meta)
 		}
 	}
 }
 
 // listFileResources returns all file resources in the session directory up to maxWatchDepth.
+// Sessions with a running file watcher are served from its index; otherwise the
+// directory is walked in parallel.
 func (s *Server) listFileResources(ctx context.Context) ([]mcp.Resource, error) {
-	var resources []mcp.Resource
-
 	sessionID, _ := types.GetSessionAndAccountID(ctx)
 	if sessionID == "" {
 		return nil, nil
 	}
 
-	dir := sessionDir(sessionID)
-
-	// If session directory doesn't exist yet, return empty list
-	if _, err := os.Stat(dir); os.IsNotExist(err) {
-		return nil, nil
-	}
-
-	// Walk directory tree
-	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
-		if err != nil {
-			return nil // Skip errors
-		}
-
-		// Get relative path
-		relPath, err := filepath.Rel(dir, path)
-		if err != nil {
-			return nil
-		}
-
-		// Skip the root
-		if relPath == "." {
-			return nil
-		}
-
-		// Get depth
-		depth := len(strings.Split(relPath, string(filepath.Separator)))
-		if d.IsDir() && depth > maxWatchDepth {
-			return filepath.SkipDir
-		}
-
-		// Apply filter
-		info, err := d.Info()
-		if err != nil {
-			return nil
-		}
-		if !fileFilter(relPath, info) {
-			if d.IsDir() {
-				return filepath.SkipDir
-			}
-			return nil
-		}
-
-		// Skip directories (we only list files as resources)
-		if d.IsDir() {
-			return nil
+	s.fileWatchersMu.Lock()
+	index := s.fileIndexes[sessionID]
+	s.fileWatchersMu.Unlock()
+
+	var (
+		files []fswatch.FileEntry
+		err   error
+	)
+	if index != nil {
+		files, err = index.Files()
+	} else {
+		files, err = fswatch.Walk(sessionDir(sessionID), maxWatchDepth, fileFilter(sessionDir(sessionID)), 0)
+		if os.IsNotExist(err) {
+			// Session directory doesn't exist yet
+			files, err = nil, nil
 		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, listMountFiles(ctx)...)
 
+	resources := make([]mcp.Resource, 0, len(files))
+	for _, file := range files {
 		// Determine MIME type
-		mimeType := mime.TypeByExtension(filepath.Ext(relPath))
+		mimeType := s.artifactMimeType(sessionID, file.Path)
+		if mimeType == "" {
+			mimeType = mime.TypeByExtension(filepath.Ext(file.Path))
+		}
 		if mimeType == "" {
 			mimeType = "application/octet-stream"
 		}
 
 		resources = append(resources, mcp.Resource{
-			URI:      fileuri.Encode(relPath),
-			Name:     filepath.Base(relPath),
+			URI:      fileuri.Encode(file.Path),
+			Name:     filepath.Base(file.Path),
 			MimeType: mimeType,
-			Size:     info.Size(),
+			Size:     file.Size,
 			Annotations: &mcp.Annotations{
-				LastModified: info.ModTime(),
+				LastModified: file.ModTime,
 			},
 		})
+	}
 
-		return nil
-	})
+	return resources, nil
+}
 
-	if err != nil {
-		return nil, err
+// listMountFiles returns the files of the mounts of the config, with their
+// paths in the session directory.
+func listMountFiles(ctx context.Context) []fswatch.FileEntry {
+	config := types.ConfigFromContext(ctx)
+	var files []fswatch.FileEntry
+	for _, name := range slices.Sorted(maps.Keys(config.Mounts)) {
+		root := mountRoot(config.Mounts[name])
+		mountFiles, err := fswatch.Walk(root, maxWatchDepth, fileFilter(root), 0)
+		if err != nil {
+			slog.Error("failed to list mount files", "mount", name, "error", err)
+			continue
+		}
+		for _, file := range mountFiles {
+			file.Path = filepath.Join(types.MountsDir, name, file.Path)
+			files = append(files, file)
+		}
 	}
+	return files
+}
 
-	return resources, nil
+// resolveFileResource returns the path of a file resource of the session,
+// resolving the paths of mounts to their host directories.
+func resolveFileResource(ctx context.Context, sessionID, relPath string) (string, error) {
+	root := sessionDir(sessionID)
+	absPath := filepath.Join(root, relPath)
+	if name, hostPath, ok := mountPath(types.ConfigFromContext(ctx), root, absPath); ok {
+		if !linksInside(mountRoot(types.ConfigFromContext(ctx).Mounts[name]), hostPath) {
+			return "", mcp.ErrRPCInvalidParams.WithMessage("invalid file path: cannot access files outside mount %s", name)
+		}
+		return hostPath, nil
+	}
+	return absPath, nil
 }
 
 // readFileResource reads a file resource by URI, resolved against the session directory.
@@ -212,7 +177,10 @@ func (s *Server) readFileResource(ctx context.Context, uri string) (*mcp.ReadRes
 	if sessionID == "" {
 		return nil, mcp.ErrRPCInvalidParams.WithMessage("session not found")
 	}
-	absPath := filepath.Join(sessionDir(sessionID), relPath)
+	absPath, err := resolveFileResource(ctx, sessionID, relPath)
+	if err != nil {
+		return nil, err
+	}
 
 	// Open file once to get both content and metadata
 	f, err := os.Open(absPath)
@@ -230,7 +198,10 @@ func (s *Server) readFileResource(ctx context.Context, uri string) (*mcp.ReadRes
 	}
 
 	// Determine MIME type
-	mimeType := mime.TypeByExtension(filepath.Ext(relPath))
+	mimeType := s.artifactMimeType(sessionID, relPath)
+	if mimeType == "" {
+		mimeType = mime.TypeByExtension(filepath.Ext(relPath))
+	}
 	if mimeType == "" {
 		mimeType = "application/octet-stream"
 	}
@@ -276,16 +247,34 @@ func (s *Server) subscribeFileResource(ctx context.Context, uri string) error {
 	if sessionID == "" {
 		return mcp.ErrRPCInvalidParams.WithMessage("session not found")
 	}
-	absPath := filepath.Join(sessionDir(sessionID), relPath)
+	absPath, err := resolveFileResource(ctx, sessionID, relPath)
+	if err != nil {
+		return err
+	}
 
 	// Verify file exists
 	if _, err := os.Stat(absPath); os.IsNotExist(err) {
 		return mcp.ErrRPCInvalidParams.WithMessage("file not found: %s", uri)
 	}
 
+	// Only files of the session directory are watched, so only their changes
+	// are diffed.
+	if absPath == filepath.Join(sessionDir(sessionID), relPath) {
+		s.snapshotFile(absPath)
+	}
 	return nil
 }
 
+// unsubscribeFileResource drops the snapshot of a file no longer subscribed.
+func (s *Server) unsubscribeFileResource(ctx context.Context, uri string) {
+	sessionID, _ := types.GetSessionAndAccountID(ctx)
+	relPath, err := fileuri.Decode(uri)
+	if err != nil || sessionID == "" {
+		return
+	}
+	s.forgetFile(filepath.Join(sessionDir(sessionID), relPath))
+}
+
 // ensureFileWatcher starts the file watcher for a session's directory if not already started.
 func (s *Server) ensureFileWatcher(sessionID string) error {
 	if sessionID == "" {
@@ -304,7 +293,12 @@ func (s *Server) ensureFileWatcher(sessionID string) error {
 		return fmt.Errorf("failed to create session directory: %w", err)
 	}
 
-	watcher := fswatch.NewWatcher(dir, maxWatchDepth, fileFilter, s.handleFileEvents)
+	filter := fileFilter(dir)
+	index := fswatch.NewIndex(dir, maxWatchDepth, filter)
+	watcher := fswatch.NewWatcher(dir, maxWatchDepth, filter, func(events []fswatch.Event) {
+		index.Apply(events)
+		s.handleFileEvents(dir, events)
+	})
 	if err := watcher.Start(); err != nil {
 		return err
 	}
@@ -312,6 +306,7 @@ func (s *Server) ensureFileWatcher(sessionID string) error {
 	slog.Debug("started file watcher", "session_id", sessionID, "path", dir, "max_watch_depth", maxWatchDepth)
 
 	s.fileWatchers[sessionID] = watcher
+	s.fileIndexes[sessionID] = index
 	return nil
 }
 
@@ -343,11 +338,10 @@ func (s *Server) uploadFile(ctx context.Context, params UploadFileParams) (*mcp.
 	if sessionID == "" {
 		return nil, mcp.ErrRPCInvalidParams.WithMessage("session not found")
 	}
-	sessionDirPath, err := ensureSessionDir(sessionID)
+	absPath, relPath, err := resolveSessionPath(ctx, relPath, true)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create session directory: %w", err)
+		return nil, err
 	}
-	absPath := filepath.Join(sessionDirPath, relPath)
 
 	// Decode base64 content
 	data, err := base64.StdEncoding.DecodeString(params.Blob)
@@ -355,12 +349,18 @@ func (s *Server) uploadFile(ctx context.Context, params UploadFileParams) (*mcp.
 		return nil, mcp.ErrRPCInvalidParams.WithMessage("invalid base64 blob: %v", err)
 	}
 
+	if err := checkDiskQuota(ctx, fileGrowth(absPath, int64(len(data)))); err != nil {
+		return nil, err
+	}
+
 	// Create parent directories if needed
 	dir := filepath.Dir(absPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create directories: %w", err)
 	}
 
+	journalFile(ctx, absPath)
+
 	// Write file
 	if err := os.WriteFile(absPath, data, 0644); err != nil {
 		return nil, fmt.Errorf("failed to write file: %w", err)
@@ -420,16 +420,32 @@ func (s *Server) deleteFile(ctx context.Context, params DeleteFileParams) (strin
 		return "", fmt.Errorf("failed to stat file: %w", err)
 	}
 
+	journalFile(ctx, absPath)
+
 	if info.IsDir() {
 		if err := os.RemoveAll(absPath); err != nil {
 			return "", fmt.Errorf("failed to remove directory: %w", err)
 		}
+		s.forgetArtifacts(sessionID, cleanPath)
 		return fmt.Sprintf("Deleted directory: %s", params.URI), nil
 	}
 
 	if err := os.Remove(absPath); err != nil {
 		return "", fmt.Errorf("failed to remove file: %w", err)
 	}
+	s.forgetArtifacts(sessionID, cleanPath)
 
 	return fmt.Sprintf("Deleted file: %s", params.URI), nil
 }
+
+// journalFile records the state of a file before a tool changes it, so that
+// the chat turn can be undone. Failing to record is logged rather than
+// failing the change.
+func journalFile(ctx context.Context, path string) {
+	var turnID string
+	mcp.SessionFromContext(ctx).Get(types.TurnSessionKey, &turnID)
+	sessionID, _ := types.GetSessionAndAccountID(ctx)
+	if err := journal.Record(sessionID, turnID, path); err != nil {
+		slog.Error("failed to journal file change", "path", path, "error", err)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
 )
 
 const testSessionID = "test-session-123"
@@ -389,11 +390,12 @@ func TestFileFilter(t *testing.T) {
 		{name: "excluded file nested", path: "some/path/nanobot.db-journal", isDir: false, expected: false},
 	}
 
+	filter := fileFilter(t.TempDir())
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Create mock FileInfo
 			info := &mockFileInfo{isDir: tt.isDir}
-			result := fileFilter(tt.path, info)
+			result := filter(tt.path, info)
 
 			if result != tt.expected {
 				t.Errorf("fileFilter(%q, isDir=%v) = %v, expected %v",
@@ -476,6 +478,57 @@ func TestSubscribeFileResource(t *testing.T) {
 	}
 }
 
+func TestFileChange(t *testing.T) {
+	tmpDir := t.TempDir()
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(oldWd)
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+
+	sessDir := filepath.Join(tmpDir, sessionsDir, testSessionID)
+	if err := os.MkdirAll(sessDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(sessDir, "notes.txt")
+	if err := os.WriteFile(path, []byte("one\ntwo\nthree\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewServer("", "")
+	if err := server.subscribeFileResource(testContext(t), "file:///notes.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte("one\n2\nthree\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	change, ok := server.fileChange(path, "notes.txt")[types.ResourceChangeMetaKey].(types.ResourceChange)
+	if !ok {
+		t.Fatal("expected a resource change")
+	}
+	if change.Size != int64(len("one\n2\nthree\n")) || change.Modified.IsZero() {
+		t.Errorf("unexpected size or modification time: %+v", change)
+	}
+	for _, line := range []string{"--- a/notes.txt", "+++ b/notes.txt", "-two", "+2"} {
+		if !strings.Contains(change.Diff, line+"\n") {
+			t.Errorf("expected the diff to contain %q, got:\n%s", line, change.Diff)
+		}
+	}
+
+	// Binary content is described, but not diffed.
+	if err := os.WriteFile(path, []byte("one\x00two"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	change = server.fileChange(path, "notes.txt")[types.ResourceChangeMetaKey].(types.ResourceChange)
+	if change.Diff != "" || change.Size != 7 {
+		t.Errorf("expected no diff of binary content, got %+v", change)
+	}
+}
+
 func TestResourcesListCombined(t *testing.T) {
 	// Create temp directory
 	tmpDir := t.TempDir()
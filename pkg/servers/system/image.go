@@ -0,0 +1,344 @@
+package system
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/obot-platform/nanobot/pkg/fileuri"
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/webp"
+)
+
+const (
+	imageFitContain = "contain"
+	imageFitFill    = "fill"
+
+	defaultJPEGQuality = 85
+	// maxImagePixels bounds the decoded size of a source image so a small but
+	// highly compressed file cannot exhaust memory, and the size of the output
+	// image so a resize cannot either.
+	maxImagePixels = 50_000_000
+	// maxImageDimension bounds the requested width and height, so computing
+	// the output size cannot overflow.
+	maxImageDimension = 100_000
+)
+
+var imageFormatMimeTypes = map[string]string{
+	"png":  "image/png",
+	"jpeg": "image/jpeg",
+	"gif":  "image/gif",
+}
+
+// ImageTransformParams are the parameters for the imageTransform tool.
+type ImageTransformParams struct {
+	// Path is the source image, relative to the session directory.
+	Path string `json:"path"`
+	// Output is where to write the result, relative to the session directory.
+	// When omitted, the transformed image is returned inline.
+	Output *string `json:"output,omitempty"`
+	// Crop is applied before any resizing.
+	Crop *ImageCrop `json:"crop,omitempty"`
+	// Width and Height resize the image. When only one is set the other is
+	// derived from the aspect ratio.
+	Width  *int `json:"width,omitempty"`
+	Height *int `json:"height,omitempty"`
+	// Fit is "contain" (default) to fit within width x height preserving the
+	// aspect ratio, or "fill" to stretch to exactly width x height.
+	Fit *string `json:"fit,omitempty"`
+	// Thumbnail fits the image within a square of this size, never enlarging it.
+	Thumbnail *int `json:"thumbnail,omitempty"`
+	// Format is "png", "jpeg", or "gif". Defaults to the output extension, or
+	// the source format when neither is known.
+	Format *string `json:"format,omitempty"`
+	// Quality is the JPEG quality from 1 to 100.
+	Quality *int `json:"quality,omitempty"`
+}
+
+// ImageCrop is a rectangle in source image pixel coordinates.
+type ImageCrop struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+func (s *Server) imageTransform(ctx context.Context, params ImageTransformParams) (*mcp.CallToolResult, error) {
+	if params.Path == "" {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("path is required")
+	}
+
+	sessionID, _ := types.GetSessionAndAccountID(ctx)
+	if sessionID == "" {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("session not found")
+	}
+	sourcePath, relSource, err := resolveSessionPath(ctx, params.Path, false)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(sourcePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+	if info.Size() > int64(maxImageBytes) {
+		return nil, fmt.Errorf("file size %d B exceeds maximum allowed size %d B", info.Size(), maxImageBytes)
+	}
+
+	data, err := os.ReadFile(sourcePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+
+	img, sourceFormat, err := decodeImage(data)
+	if err != nil {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("failed to decode image %s: %v", relSource, err)
+	}
+	sourceBounds := img.Bounds()
+
+	img, err = transformImage(img, params)
+	if err != nil {
+		return nil, err
+	}
+
+	format := sourceFormat
+	if params.Output != nil && *params.Output != "" {
+		if ext := imageFormatFromExt(*params.Output); ext != "" {
+			format = ext
+		}
+	}
+	if params.Format != nil && *params.Format != "" {
+		format = strings.ToLower(*params.Format)
+		if format == "jpg" {
+			format = "jpeg"
+		}
+	}
+	mimeType, ok := imageFormatMimeTypes[format]
+	if !ok {
+		if params.Format == nil || *params.Format == "" {
+			// Sources without an encoder (e.g. WebP) are converted to PNG.
+			format, mimeType = "png", "image/png"
+		} else {
+			return nil, mcp.ErrRPCInvalidParams.WithMessage("unsupported format %q, must be \"png\", \"jpeg\", or \"gif\"", format)
+		}
+	}
+
+	encoded, err := encodeImage(img, format, params.Quality)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	summary := fmt.Sprintf("Transformed %s from %dx%d to %dx%d %s (%d bytes)",
+		relSource, sourceBounds.Dx(), sourceBounds.Dy(), bounds.Dx(), bounds.Dy(), format, len(encoded))
+
+	if params.Output == nil || *params.Output == "" {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				{Type: "text", Text: summary},
+				{
+					Type:     "image",
+					Data:     base64.StdEncoding.EncodeToString(encoded),
+					MIMEType: mimeType,
+					Meta:     map[string]any{types.SkipTruncationMetaKey: true},
+				},
+			},
+		}, nil
+	}
+
+	outputPath, relOutput, err := resolveSessionPath(ctx, *params.Output, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directories: %w", err)
+	}
+	journalFile(ctx, outputPath)
+	if err := os.WriteFile(outputPath, encoded, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			{Type: "text", Text: summary + " and wrote " + relOutput},
+			{
+				Type:     "resource_link",
+				URI:      fileuri.Encode(relOutput),
+				Name:     relOutput,
+				MIMEType: mimeType,
+			},
+		},
+	}, nil
+}
+
+// decodeImage decodes PNG, JPEG, GIF, and WebP images, refusing images whose
+// dimensions exceed maxImagePixels before decoding the pixel data.
+func decodeImage(data []byte) (image.Image, string, error) {
+	if len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP" {
+		cfg, err := webp.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, "", err
+		}
+		if cfg.Width*cfg.Height > maxImagePixels {
+			return nil, "", fmt.Errorf("image is %dx%d, exceeding the maximum of %d pixels", cfg.Width, cfg.Height, maxImagePixels)
+		}
+		img, err := webp.Decode(bytes.NewReader(data))
+		return img, "webp", err
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	if cfg.Width*cfg.Height > maxImagePixels {
+		return nil, "", fmt.Errorf("image is %dx%d, exceeding the maximum of %d pixels", cfg.Width, cfg.Height, maxImagePixels)
+	}
+	return image.Decode(bytes.NewReader(data))
+}
+
+func imageFormatFromExt(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".png":
+		return "png"
+	case ".jpg", ".jpeg":
+		return "jpeg"
+	case ".gif":
+		return "gif"
+	}
+	return ""
+}
+
+// transformImage applies the crop and then the resize described by params.
+func transformImage(img image.Image, params ImageTransformParams) (image.Image, error) {
+	if params.Crop != nil {
+		c := params.Crop
+		rect := image.Rect(c.X, c.Y, c.X+c.Width, c.Y+c.Height).Add(img.Bounds().Min)
+		if c.Width <= 0 || c.Height <= 0 || c.X < 0 || c.Y < 0 || !rect.In(img.Bounds()) {
+			return nil, mcp.ErrRPCInvalidParams.WithMessage("crop %dx%d+%d+%d is outside the %dx%d image",
+				c.Width, c.Height, c.X, c.Y, img.Bounds().Dx(), img.Bounds().Dy())
+		}
+		cropped := image.NewRGBA(image.Rect(0, 0, c.Width, c.Height))
+		draw.Copy(cropped, image.Point{}, img, rect, draw.Src, nil)
+		img = cropped
+	}
+
+	width, height, err := targetSize(img.Bounds().Dx(), img.Bounds().Dy(), params)
+	if err != nil {
+		return nil, err
+	}
+	if width == img.Bounds().Dx() && height == img.Bounds().Dy() {
+		return img, nil
+	}
+
+	resized := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(resized, resized.Bounds(), img, img.Bounds(), draw.Src, nil)
+	return resized, nil
+}
+
+// targetSize computes the output dimensions for an image of size w x h, and
+// fails if the output would have more than maxImagePixels.
+func targetSize(w, h int, params ImageTransformParams) (int, int, error) {
+	for _, dim := range []struct {
+		name  string
+		value *int
+	}{{"width", params.Width}, {"height", params.Height}, {"thumbnail", params.Thumbnail}} {
+		if dim.value != nil && *dim.value <= 0 {
+			return 0, 0, mcp.ErrRPCInvalidParams.WithMessage("%s must be > 0", dim.name)
+		}
+		if dim.value != nil && *dim.value > maxImageDimension {
+			return 0, 0, mcp.ErrRPCInvalidParams.WithMessage("%s must be at most %d", dim.name, maxImageDimension)
+		}
+	}
+
+	width, height, err := requestedSize(w, h, params)
+	if err != nil {
+		return 0, 0, err
+	}
+	if width*height > maxImagePixels {
+		return 0, 0, mcp.ErrRPCInvalidParams.WithMessage("output image would be %dx%d, exceeding the maximum of %d pixels", width, height, maxImagePixels)
+	}
+	return width, height, nil
+}
+
+// requestedSize computes the output dimensions the params ask for.
+func requestedSize(w, h int, params ImageTransformParams) (int, int, error) {
+
+	if params.Thumbnail != nil {
+		if params.Width != nil || params.Height != nil {
+			return 0, 0, mcp.ErrRPCInvalidParams.WithMessage("thumbnail cannot be combined with width or height")
+		}
+		size := *params.Thumbnail
+		if w <= size && h <= size {
+			return w, h, nil
+		}
+		width, height := fitWithin(w, h, size, size)
+		return width, height, nil
+	}
+
+	fit := imageFitContain
+	if params.Fit != nil && *params.Fit != "" {
+		fit = *params.Fit
+	}
+	if fit != imageFitContain && fit != imageFitFill {
+		return 0, 0, mcp.ErrRPCInvalidParams.WithMessage("invalid fit %q, must be %q or %q", fit, imageFitContain, imageFitFill)
+	}
+
+	switch {
+	case params.Width == nil && params.Height == nil:
+		return w, h, nil
+	case params.Width != nil && params.Height != nil && fit == imageFitFill:
+		return *params.Width, *params.Height, nil
+	case params.Width != nil && params.Height != nil:
+		width, height := fitWithin(w, h, *params.Width, *params.Height)
+		return width, height, nil
+	case params.Width != nil:
+		return *params.Width, max(1, h**params.Width/w), nil
+	default:
+		return max(1, w**params.Height/h), *params.Height, nil
+	}
+}
+
+// fitWithin scales w x h to the largest size within maxW x maxH that keeps the
+// aspect ratio.
+func fitWithin(w, h, maxW, maxH int) (int, int) {
+	if w*maxH > h*maxW {
+		return maxW, max(1, h*maxW/w)
+	}
+	return max(1, w*maxH/h), maxH
+}
+
+func encodeImage(img image.Image, format string, quality *int) ([]byte, error) {
+	var buf bytes.Buffer
+	switch format {
+	case "png":
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, fmt.Errorf("failed to encode png: %w", err)
+		}
+	case "jpeg":
+		q := defaultJPEGQuality
+		if quality != nil {
+			q = *quality
+		}
+		if q < 1 || q > 100 {
+			return nil, mcp.ErrRPCInvalidParams.WithMessage("quality must be between 1 and 100")
+		}
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: q}); err != nil {
+			return nil, fmt.Errorf("failed to encode jpeg: %w", err)
+		}
+	case "gif":
+		if err := gif.Encode(&buf, img, nil); err != nil {
+			return nil, fmt.Errorf("failed to encode gif: %w", err)
+		}
+	}
+	return buf.Bytes(), nil
+}
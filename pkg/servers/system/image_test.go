@@ -0,0 +1,134 @@
+package system
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImageTransform(t *testing.T) {
+	tmpDir := t.TempDir()
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(oldWd)
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := testContext(t)
+	sessDir := filepath.Join(tmpDir, sessionsDir, testSessionID)
+	if err := os.MkdirAll(sessDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	src := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	for x := 200; x < 400; x++ {
+		for y := 0; y < 200; y++ {
+			src.Set(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sessDir, "shot.png"), buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{}
+	decode := func(t *testing.T, data []byte) image.Image {
+		t.Helper()
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return img
+	}
+
+	t.Run("thumbnail inline", func(t *testing.T) {
+		result, err := s.imageTransform(ctx, ImageTransformParams{Path: "shot.png", Thumbnail: new(100)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Content) != 2 || result.Content[1].Type != "image" {
+			t.Fatalf("unexpected content: %+v", result.Content)
+		}
+		data, err := base64.StdEncoding.DecodeString(result.Content[1].Data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b := decode(t, data).Bounds(); b.Dx() != 100 || b.Dy() != 50 {
+			t.Errorf("expected 100x50, got %dx%d", b.Dx(), b.Dy())
+		}
+	})
+
+	t.Run("crop and convert to file", func(t *testing.T) {
+		out := "out/red.jpg"
+		result, err := s.imageTransform(ctx, ImageTransformParams{
+			Path:   "shot.png",
+			Output: &out,
+			Crop:   &ImageCrop{X: 200, Y: 0, Width: 200, Height: 200},
+			Width:  new(50),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Content[1].Type != "resource_link" || result.Content[1].MIMEType != "image/jpeg" {
+			t.Errorf("unexpected content: %+v", result.Content)
+		}
+		data, err := os.ReadFile(filepath.Join(sessDir, "out", "red.jpg"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		img := decode(t, data)
+		if b := img.Bounds(); b.Dx() != 50 || b.Dy() != 50 {
+			t.Errorf("expected 50x50, got %dx%d", b.Dx(), b.Dy())
+		}
+		if r, g, _, _ := img.At(25, 25).RGBA(); r < 0xf000 || g > 0x1000 {
+			t.Errorf("expected cropped region to be red, got r=%x g=%x", r, g)
+		}
+	})
+
+	t.Run("fill stretches", func(t *testing.T) {
+		fill := imageFitFill
+		result, err := s.imageTransform(ctx, ImageTransformParams{Path: "shot.png", Width: new(30), Height: new(30), Fit: &fill})
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := base64.StdEncoding.DecodeString(result.Content[1].Data)
+		if b := decode(t, data).Bounds(); b.Dx() != 30 || b.Dy() != 30 {
+			t.Errorf("expected 30x30, got %dx%d", b.Dx(), b.Dy())
+		}
+	})
+
+	t.Run("invalid parameters", func(t *testing.T) {
+		for name, params := range map[string]ImageTransformParams{
+			"crop outside image":   {Path: "shot.png", Crop: &ImageCrop{X: 300, Width: 200, Height: 10}},
+			"thumbnail with width": {Path: "shot.png", Thumbnail: new(10), Width: new(10)},
+			"unsupported format":   {Path: "shot.png", Format: new("bmp")},
+			"path traversal":       {Path: "../shot.png"},
+			"too many pixels":      {Path: "shot.png", Width: new(20000)},
+			"too wide":             {Path: "shot.png", Width: new(1 << 40), Height: new(1), Fit: new(imageFitFill)},
+		} {
+			if _, err := s.imageTransform(ctx, params); err == nil {
+				t.Errorf("%s: expected error", name)
+			}
+		}
+	})
+
+	t.Run("not an image", func(t *testing.T) {
+		os.WriteFile(filepath.Join(sessDir, "notes.txt"), []byte("hello"), 0644)
+		_, err := s.imageTransform(ctx, ImageTransformParams{Path: "notes.txt"})
+		if err == nil || !strings.Contains(err.Error(), "failed to decode image") {
+			t.Errorf("expected decode error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,147 @@
+package system
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+	"github.com/obot-platform/nanobot/pkg/version"
+)
+
+// System modules group the system tools by capability so that deployments
+// can turn off the ones they don't trust with the systemModules config.
+const (
+	ModuleFS         = "fs"
+	ModuleShell      = "shell"
+	ModuleWeb        = "web"
+	ModuleTodo       = "todo"
+	ModuleQuestion   = "question"
+	ModuleSkills     = "skills"
+	ModuleDynamicMCP = "dynamic-mcp"
+)
+
+// moduleTools maps each module to its tools. The config hook belongs to no
+// module, and dynamic-mcp has no system tools: it controls the connected
+// Obot MCP servers that the config hook adds to agents.
+var moduleTools = map[string][]string{
+	ModuleFS:         {"read", "write", "edit", "glob", "grep", "ocr", "uploadFile", "deleteFile", "cleanupFiles", "extractArchive", "createArchive", "imageTransform", "startArtifact", "appendArtifact", "finishArtifact"},
+	ModuleShell:      {"bash"},
+	ModuleWeb:        {"webFetch"},
+	ModuleTodo:       {"todoWrite"},
+	ModuleQuestion:   {"askUserQuestion"},
+	ModuleSkills:     {"listSkills", "getSkill", "searchSkills"},
+	ModuleDynamicMCP: nil,
+}
+
+// Modules returns the names of the system modules.
+func Modules() []string {
+	return slices.Sorted(maps.Keys(moduleTools))
+}
+
+// ModuleServerName returns the name a module's tools are published under on
+// their own.
+func ModuleServerName(module string) string {
+	return "nanobot.system." + module
+}
+
+// toolModule returns the module a tool belongs to, or "" for none.
+func toolModule(tool string) string {
+	for module, tools := range moduleTools {
+		if slices.Contains(tools, tool) {
+			return module
+		}
+	}
+	return ""
+}
+
+// moduleEnabled reports whether the config in the context enables a module.
+// Modules are enabled unless they are set to false.
+func moduleEnabled(ctx context.Context, module string) bool {
+	enabled, ok := types.ConfigFromContext(ctx).SystemModules[module]
+	return !ok || enabled
+}
+
+// toolEnabled reports whether a tool is enabled, tools outside of a module
+// always are.
+func toolEnabled(ctx context.Context, tool string) bool {
+	module := toolModule(tool)
+	return module == "" || moduleEnabled(ctx, module)
+}
+
+// listTools lists the tools of the enabled modules.
+func (s *Server) listTools(ctx context.Context, msg mcp.Message, req mcp.ListToolsRequest) (*mcp.ListToolsResult, error) {
+	tools := slices.DeleteFunc(s.tools.Definitions(), func(tool mcp.Tool) bool {
+		return !toolEnabled(ctx, tool.Name)
+	})
+	tools, next, err := mcp.Paginate(tools, req.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	return &mcp.ListToolsResult{Tools: tools, NextCursor: next}, nil
+}
+
+// callTool calls a tool if its module is enabled.
+func (s *Server) callTool(ctx context.Context, msg mcp.Message, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if !toolEnabled(ctx, req.Name) {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("tool %s is disabled by the %s system module", req.Name, toolModule(req.Name))
+	}
+	return s.tools.Call(ctx, msg, req)
+}
+
+// ModuleServer publishes the tools of a single system module, so they can be
+// referenced or published under their own namespace.
+type ModuleServer struct {
+	module string
+	tools  mcp.ServerTools
+}
+
+func NewModuleServer(system *Server, module string) *ModuleServer {
+	tools := make(mcp.ServerTools, len(moduleTools[module]))
+	for _, name := range moduleTools[module] {
+		tools[name] = system.tools[name]
+	}
+	return &ModuleServer{
+		module: module,
+		tools:  tools,
+	}
+}
+
+func (m *ModuleServer) OnMessage(ctx context.Context, msg mcp.Message) {
+	switch msg.Method {
+	case "initialize":
+		mcp.Invoke(ctx, msg, m.initialize)
+	case "notifications/initialized":
+	case "notifications/cancelled":
+		mcp.HandleCancelled(ctx, msg)
+	case "tools/list":
+		mcp.Invoke(ctx, msg, m.listTools)
+	case "tools/call":
+		mcp.Invoke(ctx, msg, m.callTool)
+	default:
+		msg.SendError(ctx, mcp.ErrRPCMethodNotFound.WithMessage("%v", msg.Method))
+	}
+}
+
+func (m *ModuleServer) initialize(_ context.Context, _ mcp.Message, params mcp.InitializeRequest) (*mcp.InitializeResult, error) {
+	return &mcp.InitializeResult{
+		ProtocolVersion: params.ProtocolVersion,
+		Capabilities:    mcp.ServerCapabilities{Tools: &mcp.ToolsServerCapability{}},
+		ServerInfo:      mcp.ServerInfo{Name: version.Name, Version: version.Get().String()},
+	}, nil
+}
+
+func (m *ModuleServer) listTools(ctx context.Context, msg mcp.Message, req mcp.ListToolsRequest) (*mcp.ListToolsResult, error) {
+	if !moduleEnabled(ctx, m.module) {
+		return &mcp.ListToolsResult{Tools: []mcp.Tool{}}, nil
+	}
+	return m.tools.List(ctx, msg, req)
+}
+
+func (m *ModuleServer) callTool(ctx context.Context, msg mcp.Message, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if !moduleEnabled(ctx, m.module) {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("the %s system module is disabled", m.module)
+	}
+	return m.tools.Call(ctx, msg, req)
+}
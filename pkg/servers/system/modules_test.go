@@ -0,0 +1,79 @@
+package system
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+func TestEveryToolHasAModule(t *testing.T) {
+	for name := range NewServer("", "").tools {
+		if name != "config" && toolModule(name) == "" {
+			t.Errorf("tool %s does not belong to a system module", name)
+		}
+	}
+}
+
+func TestDisabledModules(t *testing.T) {
+	server := NewServer("", "")
+	ctx := types.WithConfig(t.Context(), types.Config{
+		SystemModules: map[string]bool{
+			ModuleShell: false,
+			ModuleWeb:   true,
+		},
+	})
+
+	result, err := server.listTools(ctx, mcp.Message{}, mcp.ListToolsRequest{})
+	if err != nil {
+		t.Fatalf("listTools() failed: %v", err)
+	}
+	if slices.ContainsFunc(result.Tools, func(tool mcp.Tool) bool { return tool.Name == "bash" }) {
+		t.Error("expected bash to be hidden when the shell module is disabled")
+	}
+	if !slices.ContainsFunc(result.Tools, func(tool mcp.Tool) bool { return tool.Name == "webFetch" }) {
+		t.Error("expected webFetch to be listed")
+	}
+
+	if _, err := server.callTool(ctx, mcp.Message{}, mcp.CallToolRequest{Name: "bash"}); err == nil || !strings.Contains(err.Error(), "disabled") {
+		t.Errorf("expected calling bash to fail, got %v", err)
+	}
+
+	hook, err := server.config(ctx, types.AgentConfigHook{
+		Agent: &types.HookAgent{Name: "test-agent"},
+	})
+	if err != nil {
+		t.Fatalf("config() failed: %v", err)
+	}
+	if slices.Contains(hook.Agent.Tools, "nanobot.system/bash") {
+		t.Errorf("expected bash to be left out of the agent tools, got %v", hook.Agent.Tools)
+	}
+	if !slices.Contains(hook.Agent.Tools, "nanobot.system/read") {
+		t.Errorf("expected read in the agent tools, got %v", hook.Agent.Tools)
+	}
+}
+
+func TestModuleServer(t *testing.T) {
+	module := NewModuleServer(NewServer("", ""), ModuleTodo)
+
+	result, err := module.listTools(t.Context(), mcp.Message{}, mcp.ListToolsRequest{})
+	if err != nil {
+		t.Fatalf("listTools() failed: %v", err)
+	}
+	if len(result.Tools) != 1 || result.Tools[0].Name != "todoWrite" {
+		t.Fatalf("tools = %v", result.Tools)
+	}
+
+	ctx := types.WithConfig(t.Context(), types.Config{
+		SystemModules: map[string]bool{ModuleTodo: false},
+	})
+	result, err = module.listTools(ctx, mcp.Message{}, mcp.ListToolsRequest{})
+	if err != nil {
+		t.Fatalf("listTools() failed: %v", err)
+	}
+	if len(result.Tools) != 0 {
+		t.Fatalf("expected no tools for a disabled module, got %v", result.Tools)
+	}
+}
@@ -0,0 +1,155 @@
+package system
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+const defaultOCRLanguage = "eng"
+
+// ocrLanguageRe matches tesseract language specs such as "eng" or "eng+deu".
+var ocrLanguageRe = regexp.MustCompile(`^[A-Za-z_]+(\+[A-Za-z_]+)*$`)
+
+// OCRParams are the parameters for the ocr tool.
+type OCRParams struct {
+	// FilePath is the absolute path to the image or PDF to read.
+	FilePath string `json:"file_path"`
+	// Language is the tesseract language to recognize, e.g. "eng" or
+	// "eng+deu". Defaults to "eng".
+	Language *string `json:"language,omitempty"`
+	// Pages is the page range for PDF files (e.g., "1-5", "3"). Maximum 10
+	// pages per request.
+	Pages *string `json:"pages,omitempty"`
+}
+
+func (s *Server) ocr(ctx context.Context, params OCRParams) (*mcp.CallToolResult, error) {
+	if params.FilePath == "" {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("file_path is required")
+	}
+	filePath, err := workspacePath(ctx, params.FilePath, false)
+	if err != nil {
+		return nil, err
+	}
+	params.FilePath = filePath
+
+	language := defaultOCRLanguage
+	if params.Language != nil && *params.Language != "" {
+		language = *params.Language
+	}
+	if !ocrLanguageRe.MatchString(language) {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("invalid language %q, expected a tesseract language code such as \"eng\" or \"eng+deu\"", language)
+	}
+
+	mimeType := mime.TypeByExtension(filepath.Ext(params.FilePath))
+	_, isPDF := types.PDFMimeTypes[mimeType]
+	if !isPDF && !strings.HasPrefix(mimeType, "image/") {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("ocr only supports image and PDF files, use read for other files")
+	}
+	if !isPDF && params.Pages != nil {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("pages is only supported for PDF files")
+	}
+
+	if _, err := os.Stat(params.FilePath); err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+
+	// Degrade to an explanatory result instead of failing the turn so the
+	// model can fall back to read (and vision) or tell the user what to install.
+	if _, err := exec.LookPath("tesseract"); err != nil {
+		return ocrUnavailable("tesseract was not found on PATH. Install it to extract text from images " +
+			"(e.g., brew install tesseract, apt-get install tesseract-ocr)."), nil
+	}
+
+	if !isPDF {
+		text, err := tesseract(ctx, params.FilePath, nil, language)
+		if err != nil {
+			return nil, err
+		}
+		return ocrResult(text), nil
+	}
+
+	if _, err := exec.LookPath("pdftoppm"); err != nil {
+		return ocrUnavailable("pdftoppm was not found on PATH. Install poppler to OCR PDF files " +
+			"(e.g., brew install poppler, apt-get install poppler-utils)."), nil
+	}
+
+	totalPages, err := pdfPageCount(ctx, params.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("could not determine PDF page count (install poppler-utils for pdfinfo): %w", err)
+	}
+	first, last, err := parsePagesRange(params.Pages, totalPages)
+	if err != nil {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("%v", err)
+	}
+
+	var result strings.Builder
+	fmt.Fprintf(&result, "PDF: %s (pages %d-%d of %d)\n", filepath.Base(params.FilePath), first, last, totalPages)
+	for page := first; page <= last; page++ {
+		image, err := exec.CommandContext(ctx, "pdftoppm",
+			"-png", "-r", "300",
+			"-f", strconv.Itoa(page), "-l", strconv.Itoa(page),
+			"-singlefile",
+			params.FilePath,
+		).Output()
+		if err != nil {
+			return nil, fmt.Errorf("failed to render page %d: %w", page, err)
+		}
+
+		text, err := tesseract(ctx, "stdin", image, language)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", page, err)
+		}
+		fmt.Fprintf(&result, "\n--- Page %d ---\n%s\n", page, strings.TrimSpace(text))
+	}
+
+	return ocrResult(result.String()), nil
+}
+
+// tesseract runs OCR on the image at input, or on stdin when input is "stdin".
+func tesseract(ctx context.Context, input string, stdin []byte, language string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "tesseract", input, "stdout", "-l", language)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("tesseract failed: %s", msg)
+		}
+		return "", fmt.Errorf("tesseract failed: %w", err)
+	}
+	return string(out), nil
+}
+
+func ocrResult(text string) *mcp.CallToolResult {
+	if strings.TrimSpace(text) == "" {
+		text = "No text was recognized."
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{{Type: "text", Text: text}},
+	}
+}
+
+func ocrUnavailable(reason string) *mcp.CallToolResult {
+	return &mcp.CallToolResult{
+		IsError: true,
+		Content: []mcp.Content{{
+			Type: "text",
+			Text: "OCR is not available: " + reason + " If the model supports images, use the read tool to view the file instead.",
+		}},
+	}
+}
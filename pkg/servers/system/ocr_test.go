@@ -0,0 +1,56 @@
+package system
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOCR(t *testing.T) {
+	tmp := t.TempDir()
+	s := &Server{}
+
+	imagePath := filepath.Join(tmp, "scan.png")
+	if err := os.WriteFile(imagePath, []byte("not really a png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("rejects non-image files", func(t *testing.T) {
+		textPath := filepath.Join(tmp, "notes.txt")
+		os.WriteFile(textPath, []byte("hello"), 0644)
+		_, err := s.ocr(t.Context(), OCRParams{FilePath: textPath})
+		if err == nil || !strings.Contains(err.Error(), "only supports image and PDF files") {
+			t.Errorf("expected unsupported file error, got %v", err)
+		}
+	})
+
+	t.Run("rejects invalid language", func(t *testing.T) {
+		lang := "-c tessedit"
+		_, err := s.ocr(t.Context(), OCRParams{FilePath: imagePath, Language: &lang})
+		if err == nil || !strings.Contains(err.Error(), "invalid language") {
+			t.Errorf("expected invalid language error, got %v", err)
+		}
+	})
+
+	t.Run("rejects pages for images", func(t *testing.T) {
+		pages := "1"
+		if _, err := s.ocr(t.Context(), OCRParams{FilePath: imagePath, Pages: &pages}); err == nil {
+			t.Error("expected error for pages on an image")
+		}
+	})
+
+	t.Run("degrades without tesseract", func(t *testing.T) {
+		if _, err := exec.LookPath("tesseract"); err == nil {
+			t.Setenv("PATH", t.TempDir())
+		}
+		result, err := s.ocr(t.Context(), OCRParams{FilePath: imagePath})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.IsError || !strings.Contains(result.Content[0].Text, "tesseract was not found") {
+			t.Errorf("expected unavailable result, got %+v", result)
+		}
+	})
+}
@@ -0,0 +1,632 @@
+package system
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+)
+
+const (
+	officeKindDocument     = "docx"
+	officeKindSpreadsheet  = "xlsx"
+	officeKindPresentation = "pptx"
+
+	// maxOfficePartBytes caps how much of a single decompressed XML part is
+	// read, so a malicious archive can't exhaust memory.
+	maxOfficePartBytes = 50 * 1024 * 1024 // 50 MiB
+)
+
+// officeDocumentKind reports whether filePath is an OOXML document that the
+// read tool knows how to convert to markdown.
+func officeDocumentKind(filePath string) (string, bool) {
+	switch ext := strings.ToLower(filepath.Ext(filePath)); ext {
+	case ".docx", ".xlsx", ".pptx":
+		return strings.TrimPrefix(ext, "."), true
+	}
+	return "", false
+}
+
+func readOffice(p ReadParams, kind string) (*mcp.CallToolResult, error) {
+	if p.Pages != nil || p.Mode != nil {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("pages and mode are not supported for office documents")
+	}
+
+	archive, err := zip.OpenReader(p.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s file: %w", kind, err)
+	}
+	defer archive.Close()
+
+	var markdown string
+	switch kind {
+	case officeKindDocument:
+		markdown, err = docxToMarkdown(&archive.Reader)
+	case officeKindSpreadsheet:
+		markdown, err = xlsxToMarkdown(&archive.Reader)
+	case officeKindPresentation:
+		markdown, err = pptxToMarkdown(&archive.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported office document type %q", kind)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error converting %s file: %w", kind, err)
+	}
+
+	if strings.TrimSpace(markdown) == "" {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{{Type: "text", Text: fmt.Sprintf("%s contains no extractable text.", filepath.Base(p.FilePath))}},
+		}, nil
+	}
+
+	return readLines(p, strings.NewReader(markdown))
+}
+
+// openOfficePart returns a decoder for the named part of the package, or nil
+// if the part does not exist.
+func openOfficePart(archive *zip.Reader, name string) (*xml.Decoder, io.Closer, error) {
+	f, err := archive.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, nil
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	return xml.NewDecoder(io.LimitReader(f, maxOfficePartBytes)), f, nil
+}
+
+// readOfficeRels parses a relationships part into a map of ID to target path,
+// resolved relative to baseDir.
+func readOfficeRels(archive *zip.Reader, name, baseDir string) (map[string]string, error) {
+	dec, closer, err := openOfficePart(archive, name)
+	if err != nil || dec == nil {
+		return nil, err
+	}
+	defer closer.Close()
+
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := dec.Decode(&rels); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+
+	result := make(map[string]string, len(rels.Relationships))
+	for _, rel := range rels.Relationships {
+		target := rel.Target
+		if strings.HasPrefix(target, "/") {
+			target = strings.TrimPrefix(target, "/")
+		} else {
+			target = path.Join(baseDir, target)
+		}
+		result[rel.ID] = target
+	}
+	return result, nil
+}
+
+func attrValue(el xml.StartElement, local string) string {
+	for _, attr := range el.Attr {
+		if attr.Name.Local == local {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// docxToMarkdown converts word/document.xml to markdown. Headings are derived
+// from the Heading1-6 paragraph styles and tables are rendered as markdown tables.
+func docxToMarkdown(archive *zip.Reader) (string, error) {
+	dec, closer, err := openOfficePart(archive, "word/document.xml")
+	if err != nil {
+		return "", err
+	}
+	if dec == nil {
+		return "", fmt.Errorf("word/document.xml not found")
+	}
+	defer closer.Close()
+
+	var (
+		out       strings.Builder
+		para      strings.Builder
+		heading   int
+		listItem  bool
+		tableRows [][]string
+		row       []string
+		cell      strings.Builder
+		inTable   int
+		inCell    bool
+	)
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return "", fmt.Errorf("failed to parse word/document.xml: %w", err)
+		}
+
+		switch el := tok.(type) {
+		case xml.StartElement:
+			switch el.Name.Local {
+			case "tbl":
+				inTable++
+				if inTable == 1 {
+					tableRows = nil
+				}
+			case "tr":
+				if inTable == 1 {
+					row = nil
+				}
+			case "tc":
+				if inTable == 1 {
+					inCell = true
+					cell.Reset()
+				}
+			case "p":
+				para.Reset()
+				heading = 0
+				listItem = false
+			case "pStyle":
+				if level, ok := strings.CutPrefix(strings.ToLower(attrValue(el, "val")), "heading"); ok {
+					if n, err := strconv.Atoi(level); err == nil && n >= 1 && n <= 6 {
+						heading = n
+					}
+				} else if strings.EqualFold(attrValue(el, "val"), "title") {
+					heading = 1
+				}
+			case "numPr":
+				listItem = true
+			case "tab":
+				para.WriteString("\t")
+			case "br", "cr":
+				para.WriteString("\n")
+			case "t":
+				var text string
+				if err := dec.DecodeElement(&text, &el); err != nil {
+					return "", fmt.Errorf("failed to parse word/document.xml: %w", err)
+				}
+				para.WriteString(text)
+			}
+		case xml.EndElement:
+			switch el.Name.Local {
+			case "p":
+				text := strings.TrimSpace(para.String())
+				if inCell {
+					if cell.Len() > 0 && text != "" {
+						cell.WriteString(" ")
+					}
+					cell.WriteString(text)
+					continue
+				}
+				if text == "" {
+					continue
+				}
+				switch {
+				case heading > 0:
+					fmt.Fprintf(&out, "%s %s\n\n", strings.Repeat("#", heading), text)
+				case listItem:
+					fmt.Fprintf(&out, "- %s\n", text)
+				default:
+					fmt.Fprintf(&out, "%s\n\n", text)
+				}
+			case "tc":
+				if inTable == 1 {
+					row = append(row, cell.String())
+					inCell = false
+				}
+			case "tr":
+				if inTable == 1 {
+					tableRows = append(tableRows, row)
+				}
+			case "tbl":
+				inTable--
+				if inTable == 0 {
+					writeMarkdownTable(&out, tableRows)
+					out.WriteString("\n")
+				}
+			}
+		}
+	}
+
+	return out.String(), nil
+}
+
+// xlsxToMarkdown renders every worksheet of the workbook as a markdown table,
+// in workbook order.
+func xlsxToMarkdown(archive *zip.Reader) (string, error) {
+	sharedStrings, err := readSharedStrings(archive)
+	if err != nil {
+		return "", err
+	}
+
+	rels, err := readOfficeRels(archive, "xl/_rels/workbook.xml.rels", "xl")
+	if err != nil {
+		return "", err
+	}
+
+	dec, closer, err := openOfficePart(archive, "xl/workbook.xml")
+	if err != nil {
+		return "", err
+	}
+	if dec == nil {
+		return "", fmt.Errorf("xl/workbook.xml not found")
+	}
+	defer closer.Close()
+
+	var workbook struct {
+		Sheets []struct {
+			Name  string     `xml:"name,attr"`
+			Attrs []xml.Attr `xml:",any,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := dec.Decode(&workbook); err != nil {
+		return "", fmt.Errorf("failed to parse xl/workbook.xml: %w", err)
+	}
+
+	var out strings.Builder
+	for i, sheet := range workbook.Sheets {
+		var target string
+		for _, attr := range sheet.Attrs {
+			if attr.Name.Local == "id" {
+				target = rels[attr.Value]
+			}
+		}
+		if target == "" {
+			target = fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1)
+		}
+
+		rows, truncated, err := readSheetRows(archive, target, sharedStrings)
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(&out, "## Sheet: %s\n\n", sheet.Name)
+		if len(rows) == 0 {
+			out.WriteString("(empty)\n\n")
+			continue
+		}
+		writeMarkdownTable(&out, rows)
+		if truncated {
+			out.WriteString("\n(more rows not shown)\n")
+		}
+		out.WriteString("\n")
+	}
+
+	return out.String(), nil
+}
+
+func readSharedStrings(archive *zip.Reader) ([]string, error) {
+	dec, closer, err := openOfficePart(archive, "xl/sharedStrings.xml")
+	if err != nil || dec == nil {
+		return nil, err
+	}
+	defer closer.Close()
+
+	var (
+		result []string
+		si     strings.Builder
+		inSI   bool
+		inRPh  bool
+	)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to parse xl/sharedStrings.xml: %w", err)
+		}
+
+		switch el := tok.(type) {
+		case xml.StartElement:
+			switch el.Name.Local {
+			case "si":
+				inSI = true
+				si.Reset()
+			case "rPh":
+				// Phonetic hints duplicate the base text, skip them.
+				inRPh = true
+			case "t":
+				if inSI && !inRPh {
+					var text string
+					if err := dec.DecodeElement(&text, &el); err != nil {
+						return nil, fmt.Errorf("failed to parse xl/sharedStrings.xml: %w", err)
+					}
+					si.WriteString(text)
+				}
+			}
+		case xml.EndElement:
+			switch el.Name.Local {
+			case "si":
+				inSI = false
+				result = append(result, si.String())
+			case "rPh":
+				inRPh = false
+			}
+		}
+	}
+	return result, nil
+}
+
+// readSheetRows reads the rows of a sheet, and whether rows were left out
+// because the sheet has more than maxTableCells cells, counting the empty
+// cells before the last cell of each row.
+func readSheetRows(archive *zip.Reader, name string, sharedStrings []string) ([][]string, bool, error) {
+	dec, closer, err := openOfficePart(archive, name)
+	if err != nil {
+		return nil, false, err
+	}
+	if dec == nil {
+		return nil, false, fmt.Errorf("%s not found", name)
+	}
+	defer closer.Close()
+
+	var (
+		rows      [][]string
+		row       []string
+		cells     int
+		truncated bool
+	)
+	for !truncated {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, false, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+
+		el, ok := tok.(xml.StartElement)
+		if !ok {
+			if end, ok := tok.(xml.EndElement); ok && end.Name.Local == "row" {
+				if cells += len(row); cells > maxTableCells && len(rows) > 0 {
+					truncated = true
+				} else {
+					rows = append(rows, row)
+				}
+				row = nil
+			}
+			continue
+		}
+
+		switch el.Name.Local {
+		case "row":
+			row = nil
+		case "c":
+			var c struct {
+				Ref    string `xml:"r,attr"`
+				Type   string `xml:"t,attr"`
+				Value  string `xml:"v"`
+				Inline struct {
+					Text []string `xml:"t"`
+					Runs []string `xml:"r>t"`
+				} `xml:"is"`
+			}
+			if err := dec.DecodeElement(&c, &el); err != nil {
+				return nil, false, fmt.Errorf("failed to parse %s: %w", name, err)
+			}
+
+			value := c.Value
+			switch c.Type {
+			case "s":
+				if idx, err := strconv.Atoi(c.Value); err == nil && idx >= 0 && idx < len(sharedStrings) {
+					value = sharedStrings[idx]
+				}
+			case "inlineStr":
+				value = strings.Join(slices.Concat(c.Inline.Text, c.Inline.Runs), "")
+			case "b":
+				if value == "1" {
+					value = "TRUE"
+				} else {
+					value = "FALSE"
+				}
+			}
+
+			col := len(row)
+			if c.Ref != "" {
+				if idx, ok := cellColumn(c.Ref); ok {
+					col = idx
+				}
+			}
+			if col >= maxSheetColumns {
+				return nil, false, fmt.Errorf("failed to parse %s: cell %q is beyond the last column of a sheet", name, c.Ref)
+			}
+			for len(row) < col {
+				row = append(row, "")
+			}
+			row = append(row, value)
+		}
+	}
+
+	// Drop trailing empty rows so sparse sheets don't render as long blank tables.
+	for len(rows) > 0 && slices.IndexFunc(rows[len(rows)-1], func(s string) bool { return s != "" }) < 0 {
+		rows = rows[:len(rows)-1]
+	}
+
+	return rows, truncated, nil
+}
+
+// maxSheetColumns is the number of columns of a sheet, A to XFD.
+const maxSheetColumns = 16384
+
+// cellColumn returns the zero based column index of a cell reference like
+// "C12". Columns past maxSheetColumns are returned as maxSheetColumns.
+func cellColumn(ref string) (int, bool) {
+	col := 0
+	for i, r := range ref {
+		if r >= 'A' && r <= 'Z' {
+			col = col*26 + int(r-'A'+1)
+		} else if r >= 'a' && r <= 'z' {
+			col = col*26 + int(r-'a'+1)
+		} else {
+			if i == 0 {
+				return 0, false
+			}
+			break
+		}
+		if col > maxSheetColumns {
+			return maxSheetColumns, true
+		}
+	}
+	return col - 1, col > 0
+}
+
+// pptxToMarkdown extracts the text of each slide, in presentation order.
+func pptxToMarkdown(archive *zip.Reader) (string, error) {
+	rels, err := readOfficeRels(archive, "ppt/_rels/presentation.xml.rels", "ppt")
+	if err != nil {
+		return "", err
+	}
+
+	dec, closer, err := openOfficePart(archive, "ppt/presentation.xml")
+	if err != nil {
+		return "", err
+	}
+	if dec == nil {
+		return "", fmt.Errorf("ppt/presentation.xml not found")
+	}
+	defer closer.Close()
+
+	var presentation struct {
+		Slides []struct {
+			Attrs []xml.Attr `xml:",any,attr"`
+		} `xml:"sldIdLst>sldId"`
+	}
+	if err := dec.Decode(&presentation); err != nil {
+		return "", fmt.Errorf("failed to parse ppt/presentation.xml: %w", err)
+	}
+
+	var out strings.Builder
+	for i, slide := range presentation.Slides {
+		var target string
+		for _, attr := range slide.Attrs {
+			if attr.Name.Local == "id" && attr.Name.Space != "" {
+				target = rels[attr.Value]
+			}
+		}
+		if target == "" {
+			target = fmt.Sprintf("ppt/slides/slide%d.xml", i+1)
+		}
+
+		paragraphs, err := readSlideParagraphs(archive, target)
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(&out, "## Slide %d\n\n", i+1)
+		for _, p := range paragraphs {
+			fmt.Fprintf(&out, "%s\n\n", p)
+		}
+	}
+
+	return out.String(), nil
+}
+
+func readSlideParagraphs(archive *zip.Reader, name string) ([]string, error) {
+	dec, closer, err := openOfficePart(archive, name)
+	if err != nil {
+		return nil, err
+	}
+	if dec == nil {
+		return nil, fmt.Errorf("%s not found", name)
+	}
+	defer closer.Close()
+
+	var (
+		result []string
+		para   strings.Builder
+	)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+
+		switch el := tok.(type) {
+		case xml.StartElement:
+			switch el.Name.Local {
+			case "p":
+				para.Reset()
+			case "br":
+				para.WriteString("\n")
+			case "t":
+				var text string
+				if err := dec.DecodeElement(&text, &el); err != nil {
+					return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+				}
+				para.WriteString(text)
+			}
+		case xml.EndElement:
+			if el.Name.Local == "p" {
+				if text := strings.TrimSpace(para.String()); text != "" {
+					result = append(result, text)
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// maxTableCells is how many cells of a sheet are read, and how many cells
+// writeMarkdownTable writes. The rows past it are left out, so that a sparse
+// sheet with a cell far to the right and many rows can't be converted to a
+// huge table.
+var maxTableCells = 1 << 20
+
+// writeMarkdownTable writes rows as a markdown table, treating the first row
+// as the header. Columns that are empty in every row are left out at the end.
+func writeMarkdownTable(out *strings.Builder, rows [][]string) {
+	if len(rows) == 0 {
+		return
+	}
+
+	width := 0
+	for _, row := range rows {
+		for i := len(row) - 1; i >= width; i-- {
+			if row[i] != "" {
+				width = i + 1
+				break
+			}
+		}
+	}
+	if width == 0 {
+		return
+	}
+
+	writeRow := func(row []string) {
+		out.WriteString("|")
+		for i := range width {
+			var value string
+			if i < len(row) {
+				value = strings.NewReplacer("|", `\|`, "\n", " ").Replace(row[i])
+			}
+			fmt.Fprintf(out, " %s |", value)
+		}
+		out.WriteString("\n")
+	}
+
+	shown := rows[1:]
+	if maxRows := max(1, maxTableCells/width-1); len(shown) > maxRows {
+		shown = shown[:maxRows]
+	}
+
+	writeRow(rows[0])
+	out.WriteString("|" + strings.Repeat(" --- |", width) + "\n")
+	for _, row := range shown {
+		writeRow(row)
+	}
+	if omitted := len(rows) - 1 - len(shown); omitted > 0 {
+		fmt.Fprintf(out, "\n(%d more rows not shown)\n", omitted)
+	}
+}
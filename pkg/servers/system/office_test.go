@@ -0,0 +1,242 @@
+package system
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeZip(t *testing.T, path string, files map[string]string) string {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	for name, content := range files {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadOffice(t *testing.T) {
+	tmp := t.TempDir()
+	s := &Server{}
+
+	t.Run("docx headings, paragraphs and tables", func(t *testing.T) {
+		path := writeZip(t, filepath.Join(tmp, "doc.docx"), map[string]string{
+			"word/document.xml": `<?xml version="1.0"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t>Quarterly Report</w:t></w:r></w:p>
+<w:p><w:r><w:t xml:space="preserve">Revenue grew </w:t></w:r><w:r><w:t>12%.</w:t></w:r></w:p>
+<w:p><w:pPr><w:numPr/></w:pPr><w:r><w:t>First bullet</w:t></w:r></w:p>
+<w:tbl>
+<w:tr><w:tc><w:p><w:r><w:t>Region</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Sales</w:t></w:r></w:p></w:tc></w:tr>
+<w:tr><w:tc><w:p><w:r><w:t>EMEA</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>42</w:t></w:r></w:p></w:tc></w:tr>
+</w:tbl>
+</w:body></w:document>`,
+		})
+
+		result, err := s.read(t.Context(), ReadParams{FilePath: path})
+		if err != nil {
+			t.Fatal(err)
+		}
+		text := result.Content[0].Text
+		for _, want := range []string{"# Quarterly Report", "Revenue grew 12%.", "- First bullet", "| Region | Sales |", "| --- | --- |", "| EMEA | 42 |"} {
+			if !strings.Contains(text, want) {
+				t.Errorf("expected %q in output, got %q", want, text)
+			}
+		}
+	})
+
+	t.Run("xlsx sheets with shared strings", func(t *testing.T) {
+		path := writeZip(t, filepath.Join(tmp, "book.xlsx"), map[string]string{
+			"xl/workbook.xml": `<?xml version="1.0"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Data" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+			"xl/_rels/workbook.xml.rels": `<?xml version="1.0"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Target="worksheets/data.xml"/></Relationships>`,
+			"xl/sharedStrings.xml": `<?xml version="1.0"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><si><t>Name</t></si><si><t>Qty</t></si><si><r><t>Wid</t></r><r><t>get</t></r></si></sst>`,
+			"xl/worksheets/data.xml": `<?xml version="1.0"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>
+<row r="2"><c r="A2" t="s"><v>2</v></c><c r="C2"><v>7</v></c></row>
+</sheetData></worksheet>`,
+		})
+
+		result, err := s.read(t.Context(), ReadParams{FilePath: path})
+		if err != nil {
+			t.Fatal(err)
+		}
+		text := result.Content[0].Text
+		for _, want := range []string{"## Sheet: Data", "| Name | Qty |  |", "| Widget |  | 7 |"} {
+			if !strings.Contains(text, want) {
+				t.Errorf("expected %q in output, got %q", want, text)
+			}
+		}
+	})
+
+	t.Run("pptx slides in order", func(t *testing.T) {
+		path := writeZip(t, filepath.Join(tmp, "deck.pptx"), map[string]string{
+			"ppt/presentation.xml": `<?xml version="1.0"?>
+<p:presentation xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<p:sldIdLst><p:sldId id="256" r:id="rId2"/><p:sldId id="257" r:id="rId1"/></p:sldIdLst></p:presentation>`,
+			"ppt/_rels/presentation.xml.rels": `<?xml version="1.0"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Target="slides/slide1.xml"/><Relationship Id="rId2" Target="slides/slide2.xml"/></Relationships>`,
+			"ppt/slides/slide1.xml": `<?xml version="1.0"?>
+<p:sld xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"><a:p><a:r><a:t>Second</a:t></a:r></a:p></p:sld>`,
+			"ppt/slides/slide2.xml": `<?xml version="1.0"?>
+<p:sld xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"><a:p><a:r><a:t>First</a:t></a:r></a:p></p:sld>`,
+		})
+
+		result, err := s.read(t.Context(), ReadParams{FilePath: path})
+		if err != nil {
+			t.Fatal(err)
+		}
+		text := result.Content[0].Text
+		first, second := strings.Index(text, "First"), strings.Index(text, "Second")
+		if first < 0 || second < 0 || first > second {
+			t.Errorf("expected slides in presentation order, got %q", text)
+		}
+		if !strings.Contains(text, "## Slide 1") || !strings.Contains(text, "## Slide 2") {
+			t.Errorf("expected slide headings, got %q", text)
+		}
+	})
+
+	t.Run("rejects pages", func(t *testing.T) {
+		path := writeZip(t, filepath.Join(tmp, "p.docx"), map[string]string{"word/document.xml": "<document/>"})
+		pages := "1"
+		_, err := s.read(t.Context(), ReadParams{FilePath: path, Pages: &pages})
+		if err == nil || !strings.Contains(err.Error(), "not supported for office documents") {
+			t.Errorf("expected rejection, got %v", err)
+		}
+	})
+
+	t.Run("not a zip", func(t *testing.T) {
+		path := filepath.Join(tmp, "bad.xlsx")
+		os.WriteFile(path, []byte("not a zip"), 0644)
+		if _, err := s.read(t.Context(), ReadParams{FilePath: path}); err == nil {
+			t.Error("expected error for invalid archive")
+		}
+	})
+}
+
+func TestCellColumn(t *testing.T) {
+	for ref, want := range map[string]int{"A1": 0, "C12": 2, "Z3": 25, "AA1": 26, "AB7": 27} {
+		got, ok := cellColumn(ref)
+		if !ok || got != want {
+			t.Errorf("cellColumn(%q) = %d, %v; want %d", ref, got, ok, want)
+		}
+	}
+	if _, ok := cellColumn("12"); ok {
+		t.Error("expected invalid reference")
+	}
+	if got, _ := cellColumn("XFD1"); got != maxSheetColumns-1 {
+		t.Errorf("expected XFD to be the last column, got %d", got)
+	}
+	if got, _ := cellColumn("ZZZZZZZZZZZZZZZ1"); got != maxSheetColumns {
+		t.Errorf("expected columns past the last one to be clamped, got %d", got)
+	}
+}
+
+func TestReadSheetRowsRejectsLargeColumns(t *testing.T) {
+	path := writeZip(t, filepath.Join(t.TempDir(), "book.xlsx"), map[string]string{
+		"xl/worksheets/data.xml": `<?xml version="1.0"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="XFE1"><v>1</v></c></row>
+</sheetData></worksheet>`,
+	})
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	if _, _, err := readSheetRows(&archive.Reader, "xl/worksheets/data.xml", nil); err == nil {
+		t.Error("expected a cell past the last column to be rejected")
+	}
+}
+
+func TestOpenOfficePart(t *testing.T) {
+	path := writeZip(t, filepath.Join(t.TempDir(), "book.xlsx"), map[string]string{
+		"xl/workbook.xml": `<workbook/>`,
+	})
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	if dec, _, err := openOfficePart(&archive.Reader, "xl/missing.xml"); dec != nil || err != nil {
+		t.Errorf("expected a missing part to be nil without an error, got %v", err)
+	}
+	if _, _, err := openOfficePart(&archive.Reader, "../xl/workbook.xml"); err == nil {
+		t.Error("expected an invalid part name to fail")
+	}
+}
+
+func TestSparseSheet(t *testing.T) {
+	defer func(v int) { maxTableCells = v }(maxTableCells)
+	maxTableCells = 20000
+
+	var sheet strings.Builder
+	sheet.WriteString(`<?xml version="1.0"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="inlineStr"><is><t>name</t></is></c><c r="XFD1"/></row>
+`)
+	for i := 2; i <= 5; i++ {
+		fmt.Fprintf(&sheet, "<row r=\"%d\"><c r=\"A%d\"><v>%d</v></c></row>\n", i, i, i)
+	}
+	sheet.WriteString(`<row r="6"><c r="XFD6"><v>far</v></c></row>
+<row r="7"><c r="A7"><v>7</v></c></row>
+</sheetData></worksheet>`)
+
+	path := writeZip(t, filepath.Join(t.TempDir(), "book.xlsx"), map[string]string{
+		"xl/worksheets/data.xml": sheet.String(),
+	})
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	rows, truncated, err := readSheetRows(&archive.Reader, "xl/worksheets/data.xml", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 5 || !truncated {
+		t.Fatalf("expected the rows past the cell limit to be left out, got %d rows, truncated %v", len(rows), truncated)
+	}
+
+	// The empty cell at XFD1 is trimmed, the table is one column wide.
+	var out strings.Builder
+	writeMarkdownTable(&out, rows)
+	if want := "| name |\n| --- |\n| 2 |\n| 3 |\n| 4 |\n| 5 |\n"; out.String() != want {
+		t.Errorf("writeMarkdownTable = %q, want %q", out.String(), want)
+	}
+
+	// A value at XFD makes the table as wide as the sheet, and only the rows
+	// that fit in the cell limit are written.
+	out.Reset()
+	writeMarkdownTable(&out, append(rows, make([]string, maxSheetColumns-1), append(make([]string, maxSheetColumns-1), "far")))
+	if !strings.HasSuffix(out.String(), "\n(5 more rows not shown)\n") || strings.Count(out.String(), "\n") != 5 {
+		t.Errorf("expected the table to be cut after one row, got %d lines ending with %q", strings.Count(out.String(), "\n"), out.String()[max(0, out.Len()-40):])
+	}
+}
@@ -3,6 +3,7 @@ package system
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -64,14 +65,10 @@ func (s *Server) question(ctx context.Context, params QuestionParams) (string, e
 		return "", fmt.Errorf("failed to marshal question metadata: %w", err)
 	}
 
-	// Build PrimitiveSchema with one string property per question
+	// Build PrimitiveSchema with one property per question
 	properties := make(map[string]mcp.PrimitiveProperty, len(params.Questions))
 	for i, q := range params.Questions {
-		key := fmt.Sprintf("q%d", i)
-		properties[key] = mcp.PrimitiveProperty{
-			Type:  "string",
-			Title: q.Header,
-		}
+		properties[fmt.Sprintf("q%d", i)] = questionProperty(q)
 	}
 
 	// Build and send elicitation request
@@ -85,7 +82,9 @@ func (s *Server) question(ctx context.Context, params QuestionParams) (string, e
 	}
 
 	var result mcp.ElicitResult
-	if err := agent.ExchangeElicitation(ctx, session, elicit, &result); err != nil {
+	if err := agent.ExchangeElicitation(ctx, session, elicit, &result); errors.Is(err, agent.ErrElicitationUnsupported) {
+		return "The user can't be asked questions from this client. Proceed with your best judgment and state the assumptions you made.", nil
+	} else if err != nil {
 		return "", fmt.Errorf("failed to send question elicitation: %w", err)
 	}
 
@@ -99,6 +98,39 @@ func (s *Server) question(ctx context.Context, params QuestionParams) (string, e
 	}
 }
 
+// questionProperty returns the schema of the answer to a question: a string
+// whose description lists the options, as the user can type their own answer,
+// or a multi-select if it allows multiple answers, so clients without support
+// for the question _meta can still offer the choices.
+func questionProperty(q Question) mcp.PrimitiveProperty {
+	var (
+		labels       = make([]string, 0, len(q.Options))
+		descriptions []string
+	)
+	for _, opt := range q.Options {
+		labels = append(labels, opt.Label)
+		if opt.Description != "" {
+			descriptions = append(descriptions, fmt.Sprintf("%s: %s", opt.Label, opt.Description))
+		}
+	}
+
+	property := mcp.PrimitiveProperty{
+		Type:        "string",
+		Title:       q.Header,
+		Description: strings.Join(append([]string{q.Question}, descriptions...), "\n"),
+	}
+	if q.Multiple {
+		property.Type = "array"
+		property.Items = &mcp.PrimitiveItems{
+			Type: "string",
+			Enum: labels,
+		}
+	} else if len(labels) > 0 {
+		property.Description += "\nOptions: " + strings.Join(labels, ", ") + ", or your own answer"
+	}
+	return property
+}
+
 func buildQuestionMessage(questions []Question) string {
 	var sb strings.Builder
 	sb.WriteString("Please answer the following questions:\n\n")
@@ -117,11 +149,20 @@ func formatQuestionAnswers(questions []Question, content map[string]any) string
 			fmt.Fprintf(&sb, "%s: (skipped)\n", q.Header)
 			continue
 		}
-		rawStr, _ := rawVal.(string)
 		var answers []string
-		if err := json.Unmarshal([]byte(rawStr), &answers); err != nil {
-			// If not a JSON array, treat as plain string
-			answers = []string{rawStr}
+		switch val := rawVal.(type) {
+		case []any:
+			// A multi-select answered by a client following the schema
+			for _, answer := range val {
+				answers = append(answers, fmt.Sprint(answer))
+			}
+		case string:
+			if err := json.Unmarshal([]byte(val), &answers); err != nil {
+				// If not a JSON array, treat as plain string
+				answers = []string{val}
+			}
+		default:
+			answers = []string{fmt.Sprint(val)}
 		}
 		fmt.Fprintf(&sb, "%s: %s\n", q.Header, strings.Join(answers, ", "))
 	}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"strings"
 	"testing"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
 )
 
 func TestQuestionValidation(t *testing.T) {
@@ -69,6 +71,25 @@ func TestQuestionValidation(t *testing.T) {
 	}
 }
 
+func TestQuestionWithoutElicitation(t *testing.T) {
+	serverSession, err := mcp.NewExistingServerSession(t.Context(), mcp.SessionState{ID: "minimal-client"}, mcp.MessageHandlerFunc(func(context.Context, mcp.Message) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer serverSession.Close(false)
+	ctx := mcp.WithSession(t.Context(), serverSession.GetSession())
+
+	got, err := (&Server{}).question(ctx, QuestionParams{Questions: []Question{
+		{Question: "Q?", Header: "H", Options: []QuestionOption{{Label: "A"}}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, "best judgment") {
+		t.Errorf("question() = %q, want the model told to proceed without an answer", got)
+	}
+}
+
 func TestBuildQuestionMessage(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -128,6 +149,12 @@ func TestFormatQuestionAnswers(t *testing.T) {
 			content:   map[string]any{"q0": "custom text"},
 			want:      "Name: custom text",
 		},
+		{
+			name:      "multi-select array answer",
+			questions: []Question{{Header: "Languages"}},
+			content:   map[string]any{"q0": []any{"Go", "Rust"}},
+			want:      "Languages: Go, Rust",
+		},
 		{
 			name:      "skipped question",
 			questions: []Question{{Header: "Language"}},
@@ -158,3 +185,29 @@ func TestFormatQuestionAnswers(t *testing.T) {
 		})
 	}
 }
+
+func TestQuestionProperty(t *testing.T) {
+	single := questionProperty(Question{
+		Question: "Which language?",
+		Header:   "Language",
+		Options:  []QuestionOption{{Label: "Go", Description: "Fast builds"}, {Label: "Python"}},
+	})
+	// The user can type their own answer, so the options aren't an enum.
+	if single.Type != "string" || single.Items != nil || len(single.Enum) != 0 {
+		t.Errorf("unexpected single-select property: %+v", single)
+	}
+	if single.Title != "Language" || single.Description != "Which language?\nGo: Fast builds\nOptions: Go, Python, or your own answer" {
+		t.Errorf("unexpected title or description: %q, %q", single.Title, single.Description)
+	}
+
+	multiple := questionProperty(Question{
+		Question: "Which languages?",
+		Header:   "Languages",
+		Multiple: true,
+		Options:  []QuestionOption{{Label: "Go"}, {Label: "Rust"}},
+	})
+	if multiple.Type != "array" || len(multiple.Enum) != 0 || multiple.Items == nil ||
+		multiple.Items.Type != "string" || strings.Join(multiple.Items.Enum, ",") != "Go,Rust" {
+		t.Errorf("unexpected multi-select property: %+v", multiple)
+	}
+}
@@ -0,0 +1,206 @@
+package system
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/limits"
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// maxCleanupEntries is how many of the largest files and directories
+// cleanupFiles reports.
+const maxCleanupEntries = 20
+
+// bashDiskCheckInterval is how often the files of the session are measured
+// while a command runs, to stop it once they take more than the disk quotas.
+var bashDiskCheckInterval = 5 * time.Second
+
+// sessionLimiter returns the limiter of the session of the context, nil if
+// the session has none.
+func sessionLimiter(ctx context.Context) (limiter types.AccountLimiter, accountID, sessionID string) {
+	session := mcp.SessionFromContext(ctx)
+	if session == nil || !session.Root().Get(types.LimiterSessionKey, &limiter) {
+		return nil, "", ""
+	}
+	sessionID, accountID = types.GetSessionAndAccountID(ctx)
+	if sessionID == "" {
+		return nil, "", ""
+	}
+	return limiter, accountID, sessionID
+}
+
+// checkDiskQuota fails when writing adding bytes to the session directory
+// would take more than the disk quotas of the session or of its account.
+func checkDiskQuota(ctx context.Context, adding int64) error {
+	limiter, accountID, sessionID := sessionLimiter(ctx)
+	if limiter == nil {
+		return nil
+	}
+	return quotaError(limiter.CheckDisk(ctx, accountID, sessionID, max(adding, 0)))
+}
+
+// watchDiskQuota measures the files of the session every
+// bashDiskCheckInterval until the context is done or the returned func is
+// called, and calls stop with the error once they take more than the disk
+// quotas.
+func watchDiskQuota(ctx context.Context, stop context.CancelCauseFunc) func() {
+	limiter, accountID, sessionID := sessionLimiter(ctx)
+	if limiter == nil {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(bashDiskCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			err := limiter.MeasureDisk(ctx, accountID, sessionID)
+			if limitErr := (*limits.Error)(nil); errors.As(err, &limitErr) {
+				stop(quotaError(err))
+				return
+			} else if err != nil {
+				slog.Warn("failed to measure the files of the session", "session", sessionID, "error", err)
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// quotaError tells how to free up space in the error of a disk quota.
+func quotaError(err error) error {
+	if limitErr := (*limits.Error)(nil); errors.As(err, &limitErr) {
+		rpcErr := limitErr.RPCError()
+		rpcErr.Message += ", remove files with the cleanupFiles tool to free up space"
+		return rpcErr
+	}
+	return err
+}
+
+// fileGrowth returns how many bytes writing size bytes to the file adds to
+// the disk.
+func fileGrowth(path string, size int64) int64 {
+	if info, err := os.Stat(path); err == nil {
+		return size - info.Size()
+	}
+	return size
+}
+
+// CleanupFilesParams are the parameters for the cleanupFiles tool.
+type CleanupFilesParams struct {
+	// Paths are the files and directories to remove, relative to the session
+	// directory. Glob patterns, as in "build/*.o", are expanded.
+	Paths []string `json:"paths,omitempty"`
+	// DryRun reports what would be removed without removing it.
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// CleanupEntry is a file or directory of the session directory with its size.
+type CleanupEntry struct {
+	Path  string `json:"path"`
+	Bytes int64  `json:"bytes"`
+}
+
+// CleanupFilesResult reports what cleanupFiles removed, and what takes the
+// most space in the session directory.
+type CleanupFilesResult struct {
+	Removed    []CleanupEntry `json:"removed,omitempty"`
+	FreedBytes int64          `json:"freedBytes"`
+	UsedBytes  int64          `json:"usedBytes"`
+	Largest    []CleanupEntry `json:"largest,omitempty"`
+}
+
+func (s *Server) cleanupFiles(ctx context.Context, params CleanupFilesParams) (*CleanupFilesResult, error) {
+	sessionID, _ := types.GetSessionAndAccountID(ctx)
+	if sessionID == "" {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("session not found")
+	}
+	root, err := ensureSessionDir(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var result CleanupFilesResult
+	for _, pattern := range params.Paths {
+		absPattern, _, err := resolveSessionPath(ctx, pattern, true)
+		if err != nil {
+			return nil, err
+		}
+		matches, err := filepath.Glob(absPattern)
+		if err != nil {
+			return nil, mcp.ErrRPCInvalidParams.WithMessage("invalid pattern %q: %v", pattern, err)
+		}
+		for _, match := range matches {
+			rel, ok := workspaceRel(ctx, root, match)
+			if !ok {
+				continue
+			}
+			// Patterns can match through symbolic links, so every match is
+			// resolved again.
+			if resolved, _, err := resolveSessionPath(ctx, rel, true); err != nil || resolved != match {
+				continue
+			}
+			size := diskSize(match)
+			if !params.DryRun {
+				journalFile(ctx, match)
+				if err := os.RemoveAll(match); err != nil {
+					return nil, fmt.Errorf("failed to remove %s: %w", rel, err)
+				}
+			}
+			result.Removed = append(result.Removed, CleanupEntry{Path: rel, Bytes: size})
+			result.FreedBytes += size
+		}
+	}
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list session directory: %w", err)
+	}
+	for _, entry := range entries {
+		size := diskSize(filepath.Join(root, entry.Name()))
+		result.UsedBytes += size
+		result.Largest = append(result.Largest, CleanupEntry{Path: entry.Name(), Bytes: size})
+	}
+	if params.DryRun {
+		// Report the space used as it would be after removing the files.
+		result.UsedBytes -= result.FreedBytes
+	}
+	slices.SortFunc(result.Largest, func(a, b CleanupEntry) int {
+		return cmp.Or(cmp.Compare(b.Bytes, a.Bytes), strings.Compare(a.Path, b.Path))
+	})
+	if len(result.Largest) > maxCleanupEntries {
+		result.Largest = result.Largest[:maxCleanupEntries]
+	}
+	return &result, nil
+}
+
+// diskSize returns the size of the regular files of a file or directory.
+func diskSize(path string) (size int64) {
+	_ = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
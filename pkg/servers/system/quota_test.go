@@ -0,0 +1,97 @@
+package system
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/limits"
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// quotaLimiter fails disk checks that would take the session over its quota.
+type quotaLimiter struct {
+	types.AccountLimiter
+	quota int64
+}
+
+func (q *quotaLimiter) CheckDisk(_ context.Context, _, sessionID string, adding int64) error {
+	if diskSize(sessionDir(sessionID))+adding > q.quota {
+		return &limits.Error{Limit: limits.SessionDiskMB, Max: 1}
+	}
+	return nil
+}
+
+func (q *quotaLimiter) MeasureDisk(ctx context.Context, accountID, sessionID string) error {
+	return q.CheckDisk(ctx, accountID, sessionID, 0)
+}
+
+func TestBashStoppedOverDiskQuota(t *testing.T) {
+	defer func(interval time.Duration) { bashDiskCheckInterval = interval }(bashDiskCheckInterval)
+	bashDiskCheckInterval = 10 * time.Millisecond
+	t.Chdir(t.TempDir())
+
+	ctx := testContext(t)
+	mcp.SessionFromContext(ctx).Set(types.LimiterSessionKey, types.AccountLimiter(&quotaLimiter{quota: 100}))
+	s := NewServer("", ".nanobot")
+
+	_, err := s.bash(ctx, BashParams{Command: "while true; do echo xxxxxxxxxx >> big.txt; sleep 0.01; done"})
+	if err == nil || !strings.Contains(err.Error(), "cleanupFiles") {
+		t.Errorf("expected the command to be stopped over the disk quota, got %v", err)
+	}
+}
+
+func TestCleanupFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(oldWd)
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := testContext(t)
+	mcp.SessionFromContext(ctx).Set(types.LimiterSessionKey, types.AccountLimiter(&quotaLimiter{quota: 100}))
+	s := &Server{}
+
+	if _, err := s.write(ctx, WriteParams{FilePath: "build/big.o", Content: strings.Repeat("x", 80)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.write(ctx, WriteParams{FilePath: "notes.txt", Content: strings.Repeat("y", 30)}); err == nil {
+		t.Fatal("expected a write over the disk quota to fail")
+	} else if !strings.Contains(err.Error(), "cleanupFiles") {
+		t.Errorf("expected the error to mention cleanupFiles: %v", err)
+	}
+
+	result, err := s.cleanupFiles(ctx, CleanupFilesParams{Paths: []string{"build/*.o"}, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.FreedBytes != 80 || result.UsedBytes != 0 || len(result.Largest) != 1 || result.Largest[0].Path != "build" {
+		t.Errorf("unexpected dry run result: %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(sessionsDir, testSessionID, "build", "big.o")); err != nil {
+		t.Errorf("expected a dry run to keep the file: %v", err)
+	}
+
+	if _, err := s.cleanupFiles(ctx, CleanupFilesParams{Paths: []string{"../*"}}); err == nil {
+		t.Error("expected removing files outside of the session directory to fail")
+	}
+
+	result, err = s.cleanupFiles(ctx, CleanupFilesParams{Paths: []string{"build/*.o"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Removed) != 1 || result.Removed[0].Path != filepath.Join("build", "big.o") || result.UsedBytes != 0 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if _, err := s.write(ctx, WriteParams{FilePath: "notes.txt", Content: strings.Repeat("y", 30)}); err != nil {
+		t.Errorf("expected the write to succeed after cleaning up: %v", err)
+	}
+}
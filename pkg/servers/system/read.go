@@ -21,6 +21,8 @@ const (
 	maxLineLength    = 2000
 	truncatedSuffix  = "... (line truncated to 2000 chars)"
 	maxPDFPages      = 10
+	pdfModeImages    = "images"
+	pdfModeText      = "text"
 	maxImageBytes    = 10_000_000 // 10MB
 	// maxReadTextBytes caps the size of a readText result. Beyond this, we return
 	// a notice instructing the model to use bash to read relevant portions instead
@@ -33,7 +35,22 @@ func readText(p ReadParams) (*mcp.CallToolResult, error) {
 	if p.Pages != nil {
 		return nil, mcp.ErrRPCInvalidParams.WithMessage("pages is only supported for PDF files")
 	}
+	if p.Mode != nil {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("mode is only supported for PDF files")
+	}
+
+	file, err := os.Open(p.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+	defer file.Close()
 
+	return readLines(p, file)
+}
+
+// readLines formats the content of r in cat -n style, honoring the offset and
+// limit parameters and the maxReadTextBytes cap.
+func readLines(p ReadParams, r io.Reader) (*mcp.CallToolResult, error) {
 	var offset int
 	if p.Offset != nil {
 		offset = *p.Offset
@@ -50,17 +67,11 @@ func readText(p ReadParams) (*mcp.CallToolResult, error) {
 		return nil, mcp.ErrRPCInvalidParams.WithMessage("limit must be > 0")
 	}
 
-	file, err := os.Open(p.FilePath)
-	if err != nil {
-		return nil, fmt.Errorf("error reading file: %w", err)
-	}
-	defer file.Close()
-
 	var (
 		result    strings.Builder
 		linesRead int
 	)
-	reader := bufio.NewReader(file)
+	reader := bufio.NewReader(r)
 	lineNum := 1
 
 	for {
@@ -152,8 +163,8 @@ func tooLargeReadResult(p ReadParams, builtBytes, linesReturned int) *mcp.CallTo
 }
 
 func readImage(p ReadParams, mimeType string) (*mcp.CallToolResult, error) {
-	if p.Offset != nil || p.Limit != nil || p.Pages != nil {
-		return nil, mcp.ErrRPCInvalidParams.WithMessage("offset, limit, and pages are not supported for image files")
+	if p.Offset != nil || p.Limit != nil || p.Pages != nil || p.Mode != nil {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("offset, limit, pages, and mode are not supported for image files")
 	}
 
 	info, err := os.Stat(p.FilePath)
@@ -184,9 +195,22 @@ func readPDF(ctx context.Context, p ReadParams) (*mcp.CallToolResult, error) {
 		return nil, mcp.ErrRPCInvalidParams.WithMessage("offset and limit are not supported for PDF files")
 	}
 
+	mode := pdfModeImages
+	if p.Mode != nil && *p.Mode != "" {
+		mode = *p.Mode
+	}
+	if mode != pdfModeImages && mode != pdfModeText {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("invalid mode %q, must be %q or %q", mode, pdfModeImages, pdfModeText)
+	}
+
 	if _, err := exec.LookPath("pdftoppm"); err != nil {
 		return nil, fmt.Errorf("pdftoppm not found on PATH. Install poppler to read PDF files (e.g., brew install poppler, apt-get install poppler-utils)")
 	}
+	if mode == pdfModeText {
+		if _, err := exec.LookPath("pdftotext"); err != nil {
+			return nil, fmt.Errorf("pdftotext not found on PATH. Install poppler to read PDF files as text (e.g., brew install poppler, apt-get install poppler-utils)")
+		}
+	}
 
 	totalPages, err := pdfPageCount(ctx, p.FilePath)
 	if err != nil {
@@ -202,26 +226,65 @@ func readPDF(ctx context.Context, p ReadParams) (*mcp.CallToolResult, error) {
 		{Type: "text", Text: fmt.Sprintf("PDF: %s (pages %d-%d of %d)", filepath.Base(p.FilePath), first, last, totalPages)},
 	}
 	for page := first; page <= last; page++ {
-		data, err := exec.CommandContext(ctx, "pdftoppm",
-			"-jpeg", "-jpegopt", "quality=85",
-			"-f", strconv.Itoa(page), "-l", strconv.Itoa(page),
-			"-scale-to", "1024", "-singlefile",
-			p.FilePath,
-		).Output()
+		if mode == pdfModeText {
+			text, err := extractPDFPageText(ctx, p.FilePath, page)
+			if err != nil {
+				return nil, err
+			}
+			// Pages without a text layer (e.g. scanned documents) fall through
+			// and are rendered as images instead.
+			if strings.TrimSpace(text) != "" {
+				content = append(content, mcp.Content{
+					Type: "text",
+					Text: fmt.Sprintf("--- Page %d ---\n%s", page, strings.TrimRight(text, "\f\n ")),
+				})
+				continue
+			}
+		}
+
+		pageImage, err := renderPDFPage(ctx, p.FilePath, page)
 		if err != nil {
-			return nil, fmt.Errorf("failed to render page %d: %w", page, err)
+			return nil, err
 		}
-		content = append(content, mcp.Content{
-			Type:     "image",
-			Data:     base64.StdEncoding.EncodeToString(data),
-			MIMEType: "image/jpeg",
-			Meta:     map[string]any{types.SkipTruncationMetaKey: true},
-		})
+		content = append(content, pageImage)
 	}
 
 	return &mcp.CallToolResult{Content: content}, nil
 }
 
+// renderPDFPage rasterizes a single PDF page to a JPEG image content item.
+func renderPDFPage(ctx context.Context, filePath string, page int) (mcp.Content, error) {
+	data, err := exec.CommandContext(ctx, "pdftoppm",
+		"-jpeg", "-jpegopt", "quality=85",
+		"-f", strconv.Itoa(page), "-l", strconv.Itoa(page),
+		"-scale-to", "1024", "-singlefile",
+		filePath,
+	).Output()
+	if err != nil {
+		return mcp.Content{}, fmt.Errorf("failed to render page %d: %w", page, err)
+	}
+	return mcp.Content{
+		Type:     "image",
+		Data:     base64.StdEncoding.EncodeToString(data),
+		MIMEType: "image/jpeg",
+		Meta:     map[string]any{types.SkipTruncationMetaKey: true},
+	}, nil
+}
+
+// extractPDFPageText returns the text layer of a single PDF page, preserving
+// the physical layout so tables and columns stay readable.
+func extractPDFPageText(ctx context.Context, filePath string, page int) (string, error) {
+	data, err := exec.CommandContext(ctx, "pdftotext",
+		"-layout", "-enc", "UTF-8",
+		"-f", strconv.Itoa(page), "-l", strconv.Itoa(page),
+		filePath, "-",
+	).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to extract text from page %d: %w", page, err)
+	}
+	return string(data), nil
+}
+
 func pdfPageCount(ctx context.Context, filePath string) (int, error) {
 	output, err := exec.CommandContext(ctx, "pdfinfo", filePath).Output()
 	if err != nil {
@@ -161,6 +161,24 @@ func TestRead(t *testing.T) {
 		}
 	})
 
+	t.Run("text rejects mode", func(t *testing.T) {
+		path := write("t.txt", "x\n")
+		mode := pdfModeText
+		_, err := s.read(t.Context(), ReadParams{FilePath: path, Mode: &mode})
+		if err == nil || !strings.Contains(err.Error(), "mode is only supported for PDF") {
+			t.Errorf("expected mode rejection, got %v", err)
+		}
+	})
+
+	t.Run("pdf invalid mode", func(t *testing.T) {
+		path := write("t.pdf", "%PDF-1.4")
+		mode := "ocr"
+		_, err := s.read(t.Context(), ReadParams{FilePath: path, Mode: &mode})
+		if err == nil || !strings.Contains(err.Error(), "invalid mode") {
+			t.Errorf("expected invalid mode error, got %v", err)
+		}
+	})
+
 	t.Run("text negative offset", func(t *testing.T) {
 		path := write("t.txt", "x\n")
 		offset := -1
@@ -219,6 +237,15 @@ func TestRead(t *testing.T) {
 		}
 	})
 
+	t.Run("image rejects mode", func(t *testing.T) {
+		path := write("t.png", "fake")
+		mode := pdfModeText
+		_, err := s.read(t.Context(), ReadParams{FilePath: path, Mode: &mode})
+		if err == nil || !strings.Contains(err.Error(), "not supported for image") {
+			t.Errorf("expected rejection, got %v", err)
+		}
+	})
+
 	t.Run("image rejects pages", func(t *testing.T) {
 		path := write("t.png", "fake")
 		p := "1"
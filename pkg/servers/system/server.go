@@ -12,12 +12,14 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	htmltomarkdown "github.com/JohannesKaufmann/html-to-markdown/v2"
 	"github.com/obot-platform/nanobot/pkg/fswatch"
+	"github.com/obot-platform/nanobot/pkg/ignore"
 	"github.com/obot-platform/nanobot/pkg/mcp"
 	"github.com/obot-platform/nanobot/pkg/types"
 	"github.com/obot-platform/nanobot/pkg/version"
@@ -57,7 +59,15 @@ type Server struct {
 	tools          mcp.ServerTools
 	subscriptions  *fswatch.SubscriptionManager
 	fileWatchers   map[string]*fswatch.Watcher
+	fileIndexes    map[string]*fswatch.Index
 	fileWatchersMu sync.Mutex
+	commandsMu     sync.Mutex
+	artifacts      map[string]*artifact
+	artifactsMu    sync.Mutex
+	// snapshots holds the content of subscribed text files, by path, to
+	// diff their changes.
+	snapshots   map[string]string
+	snapshotsMu sync.Mutex
 }
 
 func NewServer(defaultModel, configDir string) *Server {
@@ -66,6 +76,9 @@ func NewServer(defaultModel, configDir string) *Server {
 		configDir:     configDir,
 		subscriptions: fswatch.NewSubscriptionManager(context.Background()),
 		fileWatchers:  make(map[string]*fswatch.Watcher),
+		fileIndexes:   make(map[string]*fswatch.Index),
+		artifacts:     make(map[string]*artifact),
+		snapshots:     make(map[string]string),
 	}
 
 	s.tools = mcp.NewServerTools(
@@ -114,7 +127,7 @@ Usage notes:
 
 The working directory defaults to your session directory. Always use absolute file paths. The session directory path is provided in your system prompt.`, s.bash),
 		// Read tool
-		mcp.NewServerTool("read", `Reads a file from the local filesystem. You can access any file directly by using this tool.
+		mcp.ReadOnly(mcp.NewServerTool("read", `Reads a file from the local filesystem. You can access any file directly by using this tool.
 Assume this tool is able to read all files on the machine. If the User provides a path to a file assume that path is valid. It is okay to read a file that does not exist; an error will be returned.
 
 Usage:
@@ -126,7 +139,18 @@ Usage:
 - You have the capability to call multiple tools in a single response. It is always better to speculatively read multiple files as a batch that are potentially useful.
 - If you read a file that exists but has empty contents you will receive a system reminder warning in place of file contents.
 - You can read image files using this tool.
-- This tool can read PDF files (.pdf). For large PDFs (more than 10 pages), you MUST provide the pages parameter to read specific page ranges (e.g., pages: "1-5"). Reading a large PDF without the pages parameter will fail. Maximum 10 pages per request.`, s.read),
+- Office documents (.docx, .xlsx, .pptx) are converted to markdown: paragraphs and headings for documents, one table per sheet for spreadsheets, and one section per slide for presentations. offset and limit apply to the converted markdown.
+- This tool can read PDF files (.pdf). For large PDFs (more than 10 pages), you MUST provide the pages parameter to read specific page ranges (e.g., pages: "1-5"). Reading a large PDF without the pages parameter will fail. Maximum 10 pages per request.
+- For text-heavy PDFs, set mode: "text" to return the extracted text of each page instead of page images. This is much cheaper than images; pages without selectable text (e.g., scanned pages) are still returned as images.`, s.read)),
+		// OCR tool
+		mcp.ReadOnly(mcp.NewServerTool("ocr", `Extracts text from an image or scanned PDF using OCR (tesseract).
+
+Use this for screenshots, photos of documents, and scanned PDFs when you need the text itself, or when images cannot be viewed directly. For PDFs with a text layer, prefer read with mode: "text".
+
+Parameters:
+- file_path (required): The absolute path to the image or PDF file
+- language (optional): Tesseract language code, e.g. "eng" (default), "deu", or "eng+fra" for mixed-language documents
+- pages (optional): Page range for PDF files (e.g., "1-5"). Maximum 10 pages per request`, s.ocr)),
 		// Write tool
 		mcp.NewServerTool("write", `Writes a file to the local filesystem.
 
@@ -151,16 +175,16 @@ Usage:
 
 Always use absolute file paths. The session directory path is provided in your system prompt.`, s.edit),
 		// Glob tool
-		mcp.NewServerTool("glob", `- Fast file pattern matching tool that works with any codebase size
+		mcp.ReadOnly(mcp.NewServerTool("glob", `- Fast file pattern matching tool that works with any codebase size
 - Supports glob patterns like "**/*.js" or "src/**/*.ts"
 - Returns matching file paths sorted by modification time
 - Use this tool when you need to find files by name patterns
 - When you are doing an open ended search that may require multiple rounds of globbing and grepping, use the Task tool instead
 - You can call multiple tools in a single response. It is always better to speculatively perform multiple searches in parallel if they are potentially useful.
 
-The search path defaults to your session directory. Use absolute paths for searching elsewhere. The session directory path is provided in your system prompt.`, s.glob),
+The search path defaults to your session directory. Use absolute paths for searching elsewhere. The session directory path is provided in your system prompt.`, s.glob)),
 		// Grep tool
-		mcp.NewServerTool("grep", `A powerful search tool built on ripgrep
+		mcp.ReadOnly(mcp.NewServerTool("grep", `A powerful search tool built on ripgrep
 
   Usage:
   - ALWAYS use Grep for search tasks. NEVER invoke `+"`grep`"+` or `+"`rg`"+` as a Bash command. The Grep tool has been optimized for correct permissions and access.
@@ -171,7 +195,7 @@ The search path defaults to your session directory. Use absolute paths for searc
   - Pattern syntax: Uses ripgrep (not grep) - literal braces need escaping (use `+"`interface\\{\\}`"+` to find `+"`interface{}`"+` in Go code)
   - Multiline matching: By default patterns match within single lines only. For cross-line patterns like `+"`struct \\{[\\s\\S]*?field`"+`, use `+"`multiline: true`"+`
 
-The search path defaults to your session directory. Use absolute paths for searching elsewhere. The session directory path is provided in your system prompt.`, s.grep),
+The search path defaults to your session directory. Use absolute paths for searching elsewhere. The session directory path is provided in your system prompt.`, s.grep)),
 		// TodoWrite tool
 		mcp.NewServerTool("todoWrite", `Use this tool to create and manage a structured task list for your current coding session. This helps you track progress, organize complex tasks, and demonstrate thoroughness to the user.
 It also helps the user understand the progress of the task and overall progress of their requests.
@@ -236,7 +260,7 @@ NOTE that you should not use this tool if there is only one trivial task to do.
 When in doubt, use this tool. Being proactive with task management demonstrates attentiveness and ensures you complete all requirements successfully.
 `, s.todoWrite),
 		// WebFetch tool
-		mcp.NewServerTool("webFetch", `
+		mcp.ReadOnly(mcp.NewServerTool("webFetch", `
 - Fetches content from a specified URL and returns it in the requested format
 - Takes a URL and format as input (text, markdown, or html)
 - Automatically converts HTML to the requested format
@@ -250,7 +274,7 @@ Usage notes:
   - Maximum response size: 5MB
   - Default timeout: 30 seconds, maximum: 120 seconds
   - This tool is read-only and does not modify any files
-  - When a URL redirects to a different host, the tool will inform you and provide the redirect URL`, s.webFetch),
+  - When a URL redirects to a different host, the tool will inform you and provide the redirect URL`, s.webFetch)),
 		// Question tool
 		mcp.NewServerTool("askUserQuestion", `Use this tool when you need to ask the user questions during execution. This allows you to:
 1. Gather user preferences or requirements
@@ -272,8 +296,16 @@ Usage notes:
 - Answers are returned as arrays of labels; set multiple: true to allow selecting more than one
 - If you recommend a specific option, make that the first option in the list and add "(Recommended)" at the end of the label`, s.question),
 		// Skills tools
-		mcp.NewServerTool("listSkills", "List all available skills with their names and descriptions", s.listSkills),
-		mcp.NewServerTool("getSkill", "Get the full content of a specific skill by name (with or without .md extension)", s.getSkill),
+		mcp.ReadOnly(mcp.NewServerTool("listSkills", "List all available skills with their names and descriptions", s.listSkills)),
+		mcp.ReadOnly(mcp.NewServerTool("getSkill", "Get the full content of a specific skill by name (with or without .md extension)", s.getSkill)),
+		mcp.ReadOnly(mcp.NewServerTool("searchSkills", `Searches the installed skills by keyword.
+
+Parameters:
+- query (optional): Keywords matched against skill names, tags, descriptions, and required tools. Omit to list all skills
+- tags (optional): Only return skills that have all of these tags
+- limit (optional): Maximum number of skills to return, default 10
+
+Returns matching skills, best match first. Use getSkill to load a skill's instructions.`, s.searchSkills)),
 		// File management tools
 		mcp.NewServerTool("uploadFile", `Uploads a file to the session directory from base64-encoded content.
 
@@ -283,12 +315,67 @@ Parameters:
 - mimeType (optional): MIME type of the file (auto-detected from extension if omitted)
 
 Returns a resource_link with the file:/// URI of the uploaded file.`, s.uploadFile),
+		mcp.NewServerTool("cleanupFiles", `Frees up space in the session directory, as when its disk quota is reached.
+
+Parameters:
+- paths (optional): Files or directories to remove, relative to the session directory. Glob patterns are expanded (e.g., "build/*.o", "node_modules")
+- dryRun (optional): Report what would be removed without removing it
+
+Returns the removed paths with the bytes freed, the bytes the session directory uses, and its largest files and directories. Call it without paths to see what takes the most space.`, s.cleanupFiles),
 		mcp.NewServerTool("deleteFile", `Deletes a file or directory in the session directory.
 
 Parameters:
 - uri (required): The file:/// URI of the file to delete
 
 For directories, all contents are removed recursively.`, s.deleteFile),
+		mcp.NewServerTool("extractArchive", `Extracts a zip, tar, or tar.gz archive in the session directory.
+
+Parameters:
+- path (required): Archive path relative to the session directory (e.g., "uploads/data.zip")
+- destination (optional): Directory to extract into, relative to the session directory. Defaults to the archive path without its extension (e.g., "uploads/data")
+
+Symbolic links in the archive are skipped. Returns the list of extracted files.`, s.extractArchive),
+		mcp.NewServerTool("imageTransform", `Crops, resizes, and converts images in the session directory.
+
+Use this to shrink large screenshots or photos before reading them, which saves vision tokens, or to convert an image to another format.
+
+Parameters:
+- path (required): Source image relative to the session directory. PNG, JPEG, GIF, and WebP are supported
+- output (optional): Where to write the result, relative to the session directory. If omitted, the transformed image is returned inline
+- crop (optional): {x, y, width, height} in source pixels, applied before resizing
+- width, height (optional): Target size. With only one set, the other follows the aspect ratio
+- fit (optional): "contain" (default) fits within width x height keeping the aspect ratio; "fill" stretches to exactly width x height
+- thumbnail (optional): Fit within a square of this many pixels, never enlarging. Cannot be combined with width or height
+- format (optional): "png", "jpeg", or "gif". Defaults to the output extension, then the source format (WebP sources become PNG)
+- quality (optional): JPEG quality from 1 to 100, default 85`, s.imageTransform),
+		mcp.NewServerTool("createArchive", `Packages files and directories from the session directory into a zip, tar, or tar.gz archive.
+
+Parameters:
+- paths (required): Files or directories to include, relative to the session directory. Use "." for the whole session directory
+- output (required): Archive path relative to the session directory (e.g., "deliverables/report.zip")
+- format (optional): "zip", "tar", or "tar.gz". Inferred from the output extension if omitted
+
+Entries are stored under their path relative to the session directory. Returns a resource_link with the file:/// URI of the archive.`, s.createArchive),
+		// Artifact tools
+		mcp.NewServerTool("startArtifact", `Starts writing a file, such as a generated report, to the session directory in chunks.
+
+Use this instead of write when the content is large: start the artifact, call appendArtifact for each part, then call finishArtifact. The user can watch the file grow while it is written.
+
+Parameters:
+- name (required): File path relative to the session directory (e.g., "reports/summary.md"). An existing file is replaced
+- mimeType (optional): MIME type of the file. Detected from the extension if omitted
+
+Returns the file:/// URI of the artifact, which the other artifact tools take.`, s.startArtifact),
+		mcp.NewServerTool("appendArtifact", `Appends content to an artifact started with startArtifact.
+
+Parameters:
+- uri (required): The file:/// URI returned by startArtifact
+- content (required): The content to append
+- encoding (optional): "text" (default) or "base64" for binary content`, s.appendArtifact),
+		mcp.NewServerTool("finishArtifact", `Finishes an artifact started with startArtifact. No more content can be appended after it is finished.
+
+Parameters:
+- uri (required): The file:/// URI returned by startArtifact`, s.finishArtifact),
 	)
 
 	return s
@@ -319,17 +406,21 @@ func (s *Server) OnMessage(ctx context.Context, msg mcp.Message) {
 	case "notifications/cancelled":
 		mcp.HandleCancelled(ctx, msg)
 	case "tools/list":
-		mcp.Invoke(ctx, msg, s.tools.List)
+		mcp.Invoke(ctx, msg, s.listTools)
 	case "tools/call":
-		mcp.Invoke(ctx, msg, s.tools.Call)
+		mcp.Invoke(ctx, msg, s.callTool)
 	case "resources/list":
 		mcp.Invoke(ctx, msg, s.resourcesList)
+	case "resources/templates/list":
+		mcp.Invoke(ctx, msg, s.resourceTemplatesList)
 	case "resources/read":
 		mcp.Invoke(ctx, msg, s.resourcesRead)
 	case "resources/subscribe":
 		mcp.Invoke(ctx, msg, s.resourcesSubscribe)
 	case "resources/unsubscribe":
 		mcp.Invoke(ctx, msg, s.resourcesUnsubscribe)
+	case "completion/complete":
+		mcp.Invoke(ctx, msg, s.complete)
 	default:
 		msg.SendError(ctx, mcp.ErrRPCMethodNotFound.WithMessage("%v", msg.Method))
 	}
@@ -371,7 +462,8 @@ func (s *Server) initialize(ctx context.Context, msg mcp.Message, params mcp.Ini
 	return &mcp.InitializeResult{
 		ProtocolVersion: params.ProtocolVersion,
 		Capabilities: mcp.ServerCapabilities{
-			Tools: &mcp.ToolsServerCapability{},
+			Completions: &struct{}{},
+			Tools:       &mcp.ToolsServerCapability{},
 			Resources: &mcp.ResourcesServerCapability{
 				Subscribe:   true,
 				ListChanged: true,
@@ -385,8 +477,8 @@ func (s *Server) initialize(ctx context.Context, msg mcp.Message, params mcp.Ini
 }
 
 // resourcesList returns all resources (todo + files).
-func (s *Server) resourcesList(ctx context.Context, _ mcp.Message, _ mcp.ListResourcesRequest) (*mcp.ListResourcesResult, error) {
-	resources := s.listTodoResources()
+func (s *Server) resourcesList(ctx context.Context, _ mcp.Message, request mcp.ListResourcesRequest) (*mcp.ListResourcesResult, error) {
+	resources := append(s.listTodoResources(), s.listCommandResources()...)
 
 	// Add file resources
 	fileResources, err := s.listFileResources(ctx)
@@ -394,16 +486,26 @@ func (s *Server) resourcesList(ctx context.Context, _ mcp.Message, _ mcp.ListRes
 		// Log but don't fail - still return todo resources
 		slog.Error("failed to list file resources", "error", err)
 	} else {
+		// Files are walked in parallel, sort them so pages are stable.
+		slices.SortFunc(fileResources, func(a, b mcp.Resource) int {
+			return strings.Compare(a.URI, b.URI)
+		})
 		resources = append(resources, fileResources...)
 	}
 
-	return &mcp.ListResourcesResult{Resources: resources}, nil
+	resources, next, err := mcp.Paginate(resources, request.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	return &mcp.ListResourcesResult{Resources: resources, NextCursor: next}, nil
 }
 
 // resourcesRead reads a resource by URI (delegates to todo or file handlers).
 func (s *Server) resourcesRead(ctx context.Context, msg mcp.Message, request mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
 	if strings.HasPrefix(request.URI, "todo:///") {
 		return s.readTodoResource(ctx, request.URI)
+	} else if strings.HasPrefix(request.URI, "commands:///") {
+		return s.readCommandResource(ctx, request.URI)
 	} else if strings.HasPrefix(request.URI, "file:///") {
 		return s.readFileResource(ctx, request.URI)
 	}
@@ -418,6 +520,8 @@ func (s *Server) resourcesSubscribe(ctx context.Context, msg mcp.Message, reques
 	var err error
 	if strings.HasPrefix(request.URI, "todo:///") {
 		err = s.subscribeTodoResource(request.URI)
+	} else if strings.HasPrefix(request.URI, "commands:///") {
+		err = s.subscribeCommandResource(request.URI)
 	} else if strings.HasPrefix(request.URI, "file:///") {
 		err = s.subscribeFileResource(ctx, request.URI)
 	} else {
@@ -436,6 +540,9 @@ func (s *Server) resourcesSubscribe(ctx context.Context, msg mcp.Message, reques
 func (s *Server) resourcesUnsubscribe(ctx context.Context, msg mcp.Message, request mcp.UnsubscribeRequest) (*mcp.UnsubscribeResult, error) {
 	sessionID, _ := types.GetSessionAndAccountID(ctx)
 	s.subscriptions.Unsubscribe(sessionID, request.URI)
+	if strings.HasPrefix(request.URI, "file:///") && !s.subscriptions.IsSubscribed(request.URI) {
+		s.unsubscribeFileResource(ctx, request.URI)
+	}
 	return &mcp.UnsubscribeResult{}, nil
 }
 
@@ -458,31 +565,33 @@ func (s *Server) bash(ctx context.Context, params BashParams) (string, error) {
 		timeout = max(time.Duration(*params.Timeout)*time.Millisecond, maxBashTimeout)
 	}
 
-	// Determine working directory
-	workdir := "."
+	// Determine working directory (session directory by default)
+	workdir, err := workspaceRoot(ctx)
+	if err != nil {
+		return "", err
+	}
+	// Commands write to their working directory, so it can't be in a
+	// read-only mount.
 	if params.Workdir != nil {
-		workdir = *params.Workdir
-	} else {
-		sessionID, _ := types.GetSessionAndAccountID(ctx)
-		if sessionID != "" {
-			dir, err := ensureSessionDir(sessionID)
-			if err == nil {
-				workdir = dir
-			}
-		}
-		if workdir == "." {
-			if cwd, err := os.Getwd(); err == nil {
-				workdir = cwd
-			}
+		if workdir, err = workspacePath(ctx, *params.Workdir, true); err != nil {
+			return "", err
 		}
 	}
 
+	// Commands can write any amount, so they only run while the session is
+	// within its disk quotas, and are stopped once they take more.
+	if err := checkDiskQuota(ctx, 0); err != nil {
+		return "", err
+	}
+
 	// Create context with timeout
 	cmdCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
+	quotaCtx, stopQuota := context.WithCancelCause(cmdCtx)
+	defer stopQuota(nil)
 
 	// Execute command
-	cmd := exec.CommandContext(cmdCtx, "bash", "-c", params.Command)
+	cmd := exec.CommandContext(quotaCtx, "bash", "-c", params.Command)
 	cmd.Dir = workdir
 
 	env, err := s.obotMCPBashEnvVars(ctx, params.Command)
@@ -491,18 +600,41 @@ func (s *Server) bash(ctx context.Context, params BashParams) (string, error) {
 	}
 	cmd.Env = append(os.Environ(), env...)
 
+	start := time.Now()
+	stopWatching := watchDiskQuota(quotaCtx, stopQuota)
 	output, err := cmd.CombinedOutput()
+	stopWatching()
+
+	// Record what actually ran on the host, whatever the outcome.
+	record := newCommandRecord(params, workdir, start, output)
+	defer func() {
+		if err := s.recordCommand(ctx, record); err != nil {
+			slog.Error("failed to record bash command", "error", err)
+		}
+	}()
 
 	// Check for timeout
 	if cmdCtx.Err() == context.DeadlineExceeded {
+		record.ExitCode = -1
+		record.TimedOut = true
 		return "", mcp.ErrRPCInvalidParams.WithMessage("command timed out after %v", timeout)
 	}
 
+	// Check for the disk quotas
+	if quotaCtx.Err() != nil && ctx.Err() == nil {
+		record.ExitCode = -1
+		record.Error = context.Cause(quotaCtx).Error()
+		return "", context.Cause(quotaCtx)
+	}
+
 	// Check exit code
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
+			record.ExitCode = exitErr.ExitCode()
 			return fmt.Sprintf("Exit code %d\n%s", exitErr.ExitCode(), output), nil
 		}
+		record.ExitCode = -1
+		record.Error = err.Error()
 		return "", fmt.Errorf("error executing command: %w", err)
 	}
 
@@ -527,12 +659,22 @@ type ReadParams struct {
 	// Pages is the page range for PDF files (e.g., "1-5", "3", "10-20").
 	// Only applicable to PDF files. Maximum 10 pages per request.
 	Pages *string `json:"pages,omitempty"`
+	// Mode controls how PDF pages are returned: "images" (default) renders
+	// each page as a JPEG, "text" returns the extracted text of each page and
+	// falls back to an image for pages without a text layer. Only applicable
+	// to PDF files.
+	Mode *string `json:"mode,omitempty"`
 }
 
 func (s *Server) read(ctx context.Context, params ReadParams) (*mcp.CallToolResult, error) {
 	if params.FilePath == "" {
 		return nil, mcp.ErrRPCInvalidParams.WithMessage("file_path is required")
 	}
+	filePath, err := workspacePath(ctx, params.FilePath, false)
+	if err != nil {
+		return nil, err
+	}
+	params.FilePath = filePath
 
 	mimeType := mime.TypeByExtension(filepath.Ext(params.FilePath))
 
@@ -542,6 +684,9 @@ func (s *Server) read(ctx context.Context, params ReadParams) (*mcp.CallToolResu
 	if _, ok := types.ImageMimeTypes[mimeType]; ok {
 		return readImage(params, mimeType)
 	}
+	if kind, ok := officeDocumentKind(params.FilePath); ok {
+		return readOffice(params, kind)
+	}
 
 	return readText(params)
 }
@@ -556,6 +701,15 @@ func (s *Server) write(ctx context.Context, params WriteParams) (string, error)
 	if params.FilePath == "" {
 		return "", mcp.ErrRPCInvalidParams.WithMessage("file_path is required")
 	}
+	filePath, err := workspacePath(ctx, params.FilePath, true)
+	if err != nil {
+		return "", err
+	}
+	params.FilePath = filePath
+
+	if err := checkDiskQuota(ctx, fileGrowth(params.FilePath, int64(len(params.Content)))); err != nil {
+		return "", err
+	}
 
 	// Create parent directories if needed
 	dir := filepath.Dir(params.FilePath)
@@ -563,6 +717,8 @@ func (s *Server) write(ctx context.Context, params WriteParams) (string, error)
 		return "", fmt.Errorf("error creating directories: %w", err)
 	}
 
+	journalFile(ctx, params.FilePath)
+
 	// Write file
 	if err := os.WriteFile(params.FilePath, []byte(params.Content), 0644); err != nil {
 		return "", fmt.Errorf("error writing file: %w", err)
@@ -589,6 +745,11 @@ func (s *Server) edit(ctx context.Context, params EditParams) (string, error) {
 	if params.OldString == params.NewString {
 		return "", mcp.ErrRPCInvalidParams.WithMessage("old_string and new_string must be different")
 	}
+	filePath, err := workspacePath(ctx, params.FilePath, true)
+	if err != nil {
+		return "", err
+	}
+	params.FilePath = filePath
 
 	// Read file
 	content, err := os.ReadFile(params.FilePath)
@@ -617,6 +778,12 @@ func (s *Server) edit(ctx context.Context, params EditParams) (string, error) {
 		newContent = strings.Replace(contentStr, params.OldString, params.NewString, 1)
 	}
 
+	if err := checkDiskQuota(ctx, int64(len(newContent)-len(content))); err != nil {
+		return "", err
+	}
+
+	journalFile(ctx, params.FilePath)
+
 	// Write back
 	if err := os.WriteFile(params.FilePath, []byte(newContent), 0644); err != nil {
 		return "", fmt.Errorf("error writing file: %w", err)
@@ -625,6 +792,20 @@ func (s *Server) edit(ctx context.Context, params EditParams) (string, error) {
 	return fmt.Sprintf("Successfully edited file: %s", params.FilePath), nil
 }
 
+// ignoreArgs returns the ripgrep flags that ignore what file listing does: the
+// defaults, .gitignore files even outside of a git repository, and the
+// .nanobotignore of the workspace. The cleanup func removes the ignore file the
+// flags name.
+func ignoreArgs(workdir string) ([]string, func()) {
+	args := []string{"--no-require-git"}
+	file, err := ignore.RipgrepIgnoreFile(workdir, ignore.Defaults...)
+	if err != nil {
+		slog.Warn("failed to write ripgrep ignore file", "error", err)
+		return args, func() {}
+	}
+	return append(args, "--ignore-file", file), func() { _ = os.Remove(file) }
+}
+
 // Glob tool
 type GlobParams struct {
 	Pattern string  `json:"pattern"`
@@ -636,26 +817,22 @@ func (s *Server) glob(ctx context.Context, params GlobParams) (string, error) {
 		return "", mcp.ErrRPCInvalidParams.WithMessage("pattern is required")
 	}
 
-	searchPath := "."
-	if params.Path != nil {
-		searchPath = *params.Path
-	}
-
 	// Determine working directory (session directory by default)
-	workdir := ""
-	sessionID, _ := types.GetSessionAndAccountID(ctx)
-	if sessionID != "" {
-		workdir = sessionDir(sessionID)
+	workdir, err := workspaceRoot(ctx)
+	if err != nil {
+		return "", err
 	}
-	if workdir == "" {
-		workdir, _ = os.Getwd()
-		if workdir == "" {
-			workdir = "."
+	searchPath := "."
+	if params.Path != nil {
+		if searchPath, err = workspacePath(ctx, *params.Path, false); err != nil {
+			return "", err
 		}
 	}
 
 	// Build ripgrep command
-	args := []string{"--files", "--glob", params.Pattern}
+	args, cleanup := ignoreArgs(workdir)
+	defer cleanup()
+	args = append(args, "--files", "--glob", params.Pattern)
 	if params.Path != nil {
 		args = append(args, searchPath)
 	}
@@ -764,22 +941,23 @@ func (s *Server) grep(ctx context.Context, params GrepParams) (string, error) {
 
 	// Path
 	if params.Path != nil {
-		args = append(args, *params.Path)
+		path, err := workspacePath(ctx, *params.Path, false)
+		if err != nil {
+			return "", err
+		}
+		args = append(args, path)
 	}
 
 	// Determine working directory (session directory by default)
-	workdir := ""
-	sessionID, _ := types.GetSessionAndAccountID(ctx)
-	if sessionID != "" {
-		workdir = sessionDir(sessionID)
-	}
-	if workdir == "" {
-		workdir, _ = os.Getwd()
-		if workdir == "" {
-			workdir = "."
-		}
+	workdir, err := workspaceRoot(ctx)
+	if err != nil {
+		return "", err
 	}
 
+	ignoreFlags, cleanup := ignoreArgs(workdir)
+	defer cleanup()
+	args = append(ignoreFlags, args...)
+
 	cmd := exec.CommandContext(ctx, "rg", args...)
 	cmd.Dir = workdir
 
@@ -5,21 +5,25 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"unicode"
 
 	"github.com/obot-platform/nanobot/pkg/mcp"
 	"github.com/obot-platform/nanobot/pkg/servers/system/skills"
 	"github.com/obot-platform/nanobot/pkg/skillformat"
-	"gopkg.in/yaml.v3"
 )
 
 // Skill represents a skill with its metadata
 type Skill struct {
-	Name        string `json:"name"`
-	DisplayName string `json:"displayName"`
-	Description string `json:"description"`
+	Name          string   `json:"name"`
+	DisplayName   string   `json:"displayName"`
+	Description   string   `json:"description"`
+	Tags          []string `json:"tags,omitempty"`
+	RequiredTools []string `json:"requiredTools,omitempty"`
 }
 
 // SkillList is the response type for list_skills
@@ -32,34 +36,29 @@ type GetSkillParams struct {
 	Name string `json:"name"`
 }
 
-// parseFrontmatter extracts YAML frontmatter from markdown content
-func parseFrontmatter(content string) (map[string]string, error) {
-	lines := strings.Split(content, "\n")
-	if len(lines) < 3 || strings.TrimSpace(lines[0]) != "---" {
-		return nil, fmt.Errorf("no frontmatter found")
-	}
+// SearchSkillsParams is the input type for searchSkills
+type SearchSkillsParams struct {
+	Query string   `json:"query,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+	Limit *int     `json:"limit,omitempty"`
+}
 
-	// Find the closing ---
-	endIdx := -1
-	for i := 1; i < len(lines); i++ {
-		if strings.TrimSpace(lines[i]) == "---" {
-			endIdx = i
-			break
-		}
-	}
+const defaultSearchSkillsLimit = 10
 
-	if endIdx == -1 {
-		return nil, fmt.Errorf("frontmatter not properly closed")
+// skillFromFrontmatter builds a Skill from markdown content, returning false if
+// the content has no frontmatter.
+func skillFromFrontmatter(name, content string) (Skill, bool) {
+	fm, _, err := skillformat.ParseFrontmatter(content)
+	if err != nil || (fm.Name == "" && fm.Description == "") {
+		return Skill{}, false
 	}
-
-	// Parse YAML from frontmatter
-	frontmatterYAML := strings.Join(lines[1:endIdx], "\n")
-	var frontmatter map[string]string
-	if err := yaml.Unmarshal([]byte(frontmatterYAML), &frontmatter); err != nil {
-		return nil, fmt.Errorf("failed to parse frontmatter: %w", err)
-	}
-
-	return frontmatter, nil
+	return Skill{
+		Name:          name,
+		DisplayName:   fm.Name,
+		Description:   fm.Description,
+		Tags:          fm.Tags,
+		RequiredTools: fm.RequiredTools,
+	}, true
 }
 
 func (s *Server) listSkills(ctx context.Context, _ struct{}) (*SkillList, error) {
@@ -83,18 +82,10 @@ func (s *Server) listSkills(ctx context.Context, _ struct{}) (*SkillList, error)
 			return fmt.Errorf("failed to read %s: %w", path, err)
 		}
 
-		// Parse frontmatter
-		frontmatter, err := parseFrontmatter(string(content))
-		if err != nil {
-			// Skip files without valid frontmatter
-			return nil
-		}
-
+		// Skip files without valid frontmatter
 		name := strings.TrimSuffix(filepath.Base(path), ".md")
-		skillMap[name] = Skill{
-			Name:        name,
-			DisplayName: frontmatter["name"],
-			Description: frontmatter["description"],
+		if skill, ok := skillFromFrontmatter(name, string(content)); ok {
+			skillMap[name] = skill
 		}
 
 		return nil
@@ -103,62 +94,16 @@ func (s *Server) listSkills(ctx context.Context, _ struct{}) (*SkillList, error)
 		return nil, err
 	}
 
-	// Then, load user skills from config directory (if it exists)
-	// User skills override built-in skills with the same name
-	if s.configDir != "" {
-		userSkillsDir := filepath.Join(s.configDir, "skills")
-		entries, err := os.ReadDir(userSkillsDir)
-		if err == nil {
-			// Load flat legacy skills first.
-			for _, entry := range entries {
-				if entry.IsDir() || filepath.Ext(entry.Name()) != ".md" {
-					continue
-				}
-
-				content, err := os.ReadFile(filepath.Join(userSkillsDir, entry.Name()))
-				if err != nil {
-					// Skip files we can't read
-					continue
-				}
-
-				frontmatter, err := parseFrontmatter(string(content))
-				if err != nil {
-					// Skip files without valid frontmatter
-					continue
-				}
-
-				// User skills override built-in skills
-				name := strings.TrimSuffix(entry.Name(), ".md")
-				skillMap[name] = Skill{
-					Name:        name,
-					DisplayName: frontmatter["name"],
-					Description: frontmatter["description"],
-				}
-			}
-
-			// Directory-based Agent Skills override both flat user skills and built-ins.
-			for _, entry := range entries {
-				if !entry.IsDir() {
-					continue
-				}
-
-				skill, present, err := loadDirectorySkill(userSkillsDir, entry.Name())
-				if err != nil {
-					delete(skillMap, entry.Name())
-					continue
-				}
-				if present {
-					skillMap[skill.Name] = skill
-				}
-			}
-		}
-		// If directory doesn't exist or can't be read, silently continue
+	// Then, load user skills from the search paths, lowest precedence first, so
+	// that user skills override built-in skills with the same name
+	for _, userSkillsDir := range slices.Backward(skillformat.SearchPaths(s.configDir)) {
+		loadUserSkills(userSkillsDir, skillMap)
 	}
 
 	// Convert map to slice
 	result := make([]Skill, 0, len(skillMap))
-	for _, skill := range skillMap {
-		result = append(result, skill)
+	for _, name := range slices.Sorted(maps.Keys(skillMap)) {
+		result = append(result, skillMap[name])
 	}
 
 	return &SkillList{
@@ -166,6 +111,51 @@ func (s *Server) listSkills(ctx context.Context, _ struct{}) (*SkillList, error)
 	}, nil
 }
 
+// loadUserSkills adds the skills in userSkillsDir to skillMap, overriding
+// existing entries. If the directory doesn't exist or can't be read it is
+// silently skipped.
+func loadUserSkills(userSkillsDir string, skillMap map[string]Skill) {
+	entries, err := os.ReadDir(userSkillsDir)
+	if err != nil {
+		return
+	}
+
+	// Load flat legacy skills first.
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".md" {
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(userSkillsDir, entry.Name()))
+		if err != nil {
+			// Skip files we can't read
+			continue
+		}
+
+		// Skip files without valid frontmatter
+		name := strings.TrimSuffix(entry.Name(), ".md")
+		if skill, ok := skillFromFrontmatter(name, string(content)); ok {
+			skillMap[name] = skill
+		}
+	}
+
+	// Directory-based Agent Skills override both flat user skills and built-ins.
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		skill, present, err := loadDirectorySkill(userSkillsDir, entry.Name())
+		if err != nil {
+			delete(skillMap, entry.Name())
+			continue
+		}
+		if present {
+			skillMap[skill.Name] = skill
+		}
+	}
+}
+
 func (s *Server) getSkill(ctx context.Context, params GetSkillParams) (string, error) {
 	if params.Name == "" {
 		return "", mcp.ErrRPCInvalidParams.WithMessage("skill name is required")
@@ -173,9 +163,7 @@ func (s *Server) getSkill(ctx context.Context, params GetSkillParams) (string, e
 
 	skillName := strings.TrimSuffix(params.Name, ".md")
 
-	if s.configDir != "" {
-		userSkillsDir := filepath.Join(s.configDir, "skills")
-
+	for _, userSkillsDir := range skillformat.SearchPaths(s.configDir) {
 		dirSkillPath := filepath.Join(userSkillsDir, skillName, skillformat.SkillMainFile)
 		if info, err := os.Stat(filepath.Join(userSkillsDir, skillName)); err == nil && info.IsDir() {
 			content, err := os.ReadFile(dirSkillPath)
@@ -209,6 +197,97 @@ func (s *Server) getSkill(ctx context.Context, params GetSkillParams) (string, e
 	return string(content), nil
 }
 
+// searchSkills ranks the available skills by how well their name, tags,
+// description, and required tools match the query keywords.
+func (s *Server) searchSkills(ctx context.Context, params SearchSkillsParams) (*SkillList, error) {
+	limit := defaultSearchSkillsLimit
+	if params.Limit != nil {
+		if *params.Limit <= 0 {
+			return nil, mcp.ErrRPCInvalidParams.WithMessage("limit must be > 0")
+		}
+		limit = *params.Limit
+	}
+
+	all, err := s.listSkills(ctx, struct{}{})
+	if err != nil {
+		return nil, err
+	}
+
+	terms := strings.FieldsFunc(strings.ToLower(params.Query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	type match struct {
+		skill Skill
+		score int
+	}
+	var matches []match
+	for _, skill := range all.Skills {
+		if !hasAllTags(skill, params.Tags) {
+			continue
+		}
+		score := scoreSkill(skill, terms)
+		if len(terms) > 0 && score == 0 {
+			continue
+		}
+		matches = append(matches, match{skill: skill, score: score})
+	}
+
+	// Skills are already sorted by name, so ties keep that order.
+	slices.SortStableFunc(matches, func(a, b match) int {
+		return b.score - a.score
+	})
+
+	result := &SkillList{Skills: []Skill{}}
+	for _, m := range matches[:min(limit, len(matches))] {
+		result.Skills = append(result.Skills, m.skill)
+	}
+	return result, nil
+}
+
+func hasAllTags(skill Skill, tags []string) bool {
+	for _, tag := range tags {
+		if !slices.ContainsFunc(skill.Tags, func(t string) bool {
+			return strings.EqualFold(t, tag)
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// scoreSkill weighs a term matching the skill name or a tag above one found in
+// the description or required tools.
+func scoreSkill(skill Skill, terms []string) int {
+	var (
+		score       int
+		name        = strings.ToLower(skill.Name + " " + skill.DisplayName)
+		description = strings.ToLower(skill.Description)
+	)
+	for _, term := range terms {
+		if strings.Contains(name, term) {
+			score += 3
+		}
+		for _, tag := range skill.Tags {
+			tag = strings.ToLower(tag)
+			if tag == term {
+				score += 3
+			} else if strings.Contains(tag, term) {
+				score += 2
+			}
+		}
+		if strings.Contains(description, term) {
+			score++
+		}
+		for _, tool := range skill.RequiredTools {
+			if strings.Contains(strings.ToLower(tool), term) {
+				score++
+			}
+		}
+	}
+	return score
+}
+
 func loadDirectorySkill(userSkillsDir, dirName string) (Skill, bool, error) {
 	skillPath := filepath.Join(userSkillsDir, dirName, skillformat.SkillMainFile)
 	content, err := os.ReadFile(skillPath)
@@ -228,8 +307,10 @@ func loadDirectorySkill(userSkillsDir, dirName string) (Skill, bool, error) {
 	}
 
 	return Skill{
-		Name:        fm.Name,
-		DisplayName: skillformat.DisplayName(fm.Name),
-		Description: fm.Description,
+		Name:          fm.Name,
+		DisplayName:   skillformat.DisplayName(fm.Name),
+		Description:   fm.Description,
+		Tags:          fm.Tags,
+		RequiredTools: fm.RequiredTools,
 	}, true, nil
 }
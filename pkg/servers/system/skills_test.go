@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"strings"
 	"testing"
@@ -442,12 +443,12 @@ func TestInvalidDirectorySkillFailsClosed(t *testing.T) {
 	}
 }
 
-func TestParseFrontmatter(t *testing.T) {
+func TestSkillFromFrontmatter(t *testing.T) {
 	tests := []struct {
-		name        string
-		content     string
-		expectError bool
-		expected    map[string]string
+		name     string
+		content  string
+		wantOK   bool
+		expected Skill
 	}{
 		{
 			name: "valid frontmatter",
@@ -457,10 +458,11 @@ description: A test skill
 ---
 
 # Content here`,
-			expectError: false,
-			expected: map[string]string{
-				"name":        "test-skill",
-				"description": "A test skill",
+			wantOK: true,
+			expected: Skill{
+				Name:        "test",
+				DisplayName: "test-skill",
+				Description: "A test skill",
 			},
 		},
 		{
@@ -469,51 +471,118 @@ description: A test skill
 name: test-skill
 description: A test skill
 author: test
+tags: [testing, qa]
+required-tools:
+  - bash
 ---
 
 Content`,
-			expectError: false,
-			expected: map[string]string{
-				"name":        "test-skill",
-				"description": "A test skill",
-				"author":      "test",
+			wantOK: true,
+			expected: Skill{
+				Name:          "test",
+				DisplayName:   "test-skill",
+				Description:   "A test skill",
+				Tags:          []string{"testing", "qa"},
+				RequiredTools: []string{"bash"},
 			},
 		},
 		{
-			name:        "no frontmatter",
-			content:     "Just regular content",
-			expectError: true,
+			name:    "no frontmatter",
+			content: "Just regular content",
 		},
 		{
 			name: "unclosed frontmatter",
 			content: `---
 name: test
 description: test`,
-			expectError: true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := parseFrontmatter(tt.content)
-
-			if tt.expectError {
-				if err == nil {
-					t.Error("expected error but got none")
-				}
-			} else {
-				if err != nil {
-					t.Fatalf("parseFrontmatter() failed: %v", err)
-				}
-				if len(result) != len(tt.expected) {
-					t.Errorf("got %d fields, want %d", len(result), len(tt.expected))
-				}
-				for k, v := range tt.expected {
-					if result[k] != v {
-						t.Errorf("field %q = %q, want %q", k, result[k], v)
-					}
-				}
+			result, ok := skillFromFrontmatter("test", tt.content)
+			if ok != tt.wantOK {
+				t.Fatalf("skillFromFrontmatter() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("skillFromFrontmatter() = %+v, want %+v", result, tt.expected)
 			}
 		})
 	}
 }
+
+func TestSkillSearchPaths(t *testing.T) {
+	configDir := t.TempDir()
+	extraDir := t.TempDir()
+	writeDirectorySkill(t, configDir, "shared", "From the config dir", "\n# Config\n")
+	writeDirectorySkill(t, extraDir, "shared", "From the extra path", "\n# Extra\n")
+	writeDirectorySkill(t, extraDir, "extra-only", "Only in the extra path", "\n# Extra only\n")
+	t.Setenv(skillformat.SkillsPathEnv, filepath.Join(extraDir, "skills"))
+
+	server := NewServer("", configDir)
+	result, err := server.listSkills(context.Background(), struct{}{})
+	if err != nil {
+		t.Fatalf("listSkills() failed: %v", err)
+	}
+
+	descriptions := map[string]string{}
+	for _, skill := range result.Skills {
+		descriptions[skill.Name] = skill.Description
+	}
+	if descriptions["shared"] != "From the config dir" {
+		t.Errorf("expected the config dir skill to take precedence, got %q", descriptions["shared"])
+	}
+	if descriptions["extra-only"] != "Only in the extra path" {
+		t.Errorf("expected skill from the extra search path, got %q", descriptions["extra-only"])
+	}
+
+	content, err := server.getSkill(context.Background(), GetSkillParams{Name: "extra-only"})
+	if err != nil {
+		t.Fatalf("getSkill() failed: %v", err)
+	}
+	if !strings.Contains(content, "# Extra only") {
+		t.Errorf("unexpected skill content: %s", content)
+	}
+}
+
+func TestSearchSkills(t *testing.T) {
+	configDir := t.TempDir()
+	skillsDir := filepath.Join(configDir, "skills")
+	if err := os.MkdirAll(skillsDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"pdf-forms.md":   "---\nname: pdf-forms\ndescription: Fill in PDF forms\ntags: [documents, pdf]\n---\n",
+		"spreadsheet.md": "---\nname: spreadsheet\ndescription: Build spreadsheets that summarize PDF invoices\ntags: [documents]\n---\n",
+	} {
+		if err := os.WriteFile(filepath.Join(skillsDir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server := NewServer("", configDir)
+
+	result, err := server.searchSkills(context.Background(), SearchSkillsParams{Query: "pdf"})
+	if err != nil {
+		t.Fatalf("searchSkills() failed: %v", err)
+	}
+	if len(result.Skills) != 2 || result.Skills[0].Name != "pdf-forms" || result.Skills[1].Name != "spreadsheet" {
+		t.Errorf("expected pdf-forms ranked above spreadsheet, got %+v", result.Skills)
+	}
+
+	result, err = server.searchSkills(context.Background(), SearchSkillsParams{Query: "invoices", Tags: []string{"Documents"}})
+	if err != nil {
+		t.Fatalf("searchSkills() failed: %v", err)
+	}
+	if len(result.Skills) != 1 || result.Skills[0].Name != "spreadsheet" {
+		t.Errorf("expected only spreadsheet, got %+v", result.Skills)
+	}
+
+	result, err = server.searchSkills(context.Background(), SearchSkillsParams{Query: "nonexistent-keyword"})
+	if err != nil {
+		t.Fatalf("searchSkills() failed: %v", err)
+	}
+	if len(result.Skills) != 0 {
+		t.Errorf("expected no matches, got %+v", result.Skills)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"github.com/obot-platform/nanobot/pkg/mcp"
 	"github.com/obot-platform/nanobot/pkg/types"
@@ -22,6 +23,62 @@ type TodoWriteParams struct {
 	Todos []TodoItem `json:"todos"`
 }
 
+// TodoProgressMetaKey is the _meta key of the progress notifications that
+// carry the state of the todo list, so UIs can render a live checklist.
+const TodoProgressMetaKey = "ai.nanobot.progress/todo"
+
+// TodoPlan is the state of the todo list, returned as the structured output
+// of todoWrite and sent with its progress notifications.
+type TodoPlan struct {
+	Todos      []TodoItem `json:"todos"`
+	Total      int        `json:"total"`
+	Completed  int        `json:"completed"`
+	InProgress string     `json:"inProgress,omitempty"`
+}
+
+func newTodoPlan(todos []TodoItem) TodoPlan {
+	plan := TodoPlan{
+		Todos: todos,
+		Total: len(todos),
+	}
+	if plan.Todos == nil {
+		plan.Todos = []TodoItem{}
+	}
+	for _, todo := range todos {
+		switch todo.Status {
+		case "completed":
+			plan.Completed++
+		case "in_progress":
+			plan.InProgress = todo.ActiveForm
+			if plan.InProgress == "" {
+				plan.InProgress = todo.Content
+			}
+		}
+	}
+	return plan
+}
+
+// sendTodoProgress reports the state of the todo list as progress of the
+// request that wrote it, if the caller asked for progress.
+func sendTodoProgress(ctx context.Context, plan TodoPlan) {
+	token := mcp.ProgressTokenFromContext(ctx)
+	session := mcp.SessionFromContext(ctx)
+	if token == nil || session == nil {
+		return
+	}
+
+	total := json.Number(strconv.Itoa(plan.Total))
+	_ = session.SendPayload(ctx, "notifications/progress", mcp.NotificationProgressRequest{
+		ProgressToken: token,
+		Progress:      json.Number(strconv.Itoa(plan.Completed)),
+		Total:         &total,
+		Message:       plan.InProgress,
+		Meta: map[string]any{
+			TodoProgressMetaKey: plan,
+		},
+	})
+}
+
 // listTodoResources returns the todo list resource.
 func (s *Server) listTodoResources() []mcp.Resource {
 	return []mcp.Resource{
@@ -81,7 +138,7 @@ func (s *Server) subscribeTodoResource(uri string) error {
 	return nil
 }
 
-func (s *Server) todoWrite(ctx context.Context, params TodoWriteParams) (string, error) {
+func (s *Server) todoWrite(ctx context.Context, params TodoWriteParams) (*mcp.CallToolResult, error) {
 	// Validate only one in_progress task
 	var inProgressCount int
 	for _, todo := range params.Todos {
@@ -91,7 +148,7 @@ func (s *Server) todoWrite(ctx context.Context, params TodoWriteParams) (string,
 	}
 
 	if inProgressCount > 1 {
-		return "", mcp.ErrRPCInvalidParams.WithMessage("only one task can be in_progress at a time")
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("only one task can be in_progress at a time")
 	}
 
 	// Get session ID
@@ -102,22 +159,35 @@ func (s *Server) todoWrite(ctx context.Context, params TodoWriteParams) (string,
 
 	// Create directories
 	if err := os.MkdirAll(filepath.Dir(todoPath), 0755); err != nil {
-		return "", fmt.Errorf("failed to create todo directory: %w", err)
+		return nil, fmt.Errorf("failed to create todo directory: %w", err)
 	}
 
 	// Marshal JSON
 	todoJSON, err := json.MarshalIndent(params.Todos, "", "  ")
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal todos: %w", err)
+		return nil, fmt.Errorf("failed to marshal todos: %w", err)
 	}
 
 	// Write file
 	if err := os.WriteFile(todoPath, todoJSON, 0644); err != nil {
-		return "", fmt.Errorf("failed to write todo file: %w", err)
+		return nil, fmt.Errorf("failed to write todo file: %w", err)
 	}
 
 	// Send resource updated notification to subscribed sessions
 	s.subscriptions.SendResourceUpdatedNotification("todo:///list")
 
-	return fmt.Sprintf("Todo list updated:\n\n%s", string(todoJSON)), nil
+	plan := newTodoPlan(params.Todos)
+	sendTodoProgress(ctx, plan)
+
+	structured := map[string]any{}
+	if err := mcp.JSONCoerce(plan, &structured); err != nil {
+		return nil, fmt.Errorf("failed to convert todos: %w", err)
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{{
+			Type: "text",
+			Text: fmt.Sprintf("Todo list updated:\n\n%s", string(todoJSON)),
+		}},
+		StructuredContent: structured,
+	}, nil
 }
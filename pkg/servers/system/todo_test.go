@@ -0,0 +1,47 @@
+package system
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestTodoWrite(t *testing.T) {
+	tmpDir := t.TempDir()
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(oldWd)
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewServer("", "")
+	result, err := s.todoWrite(testContext(t), TodoWriteParams{Todos: []TodoItem{
+		{Content: "Run tests", Status: "completed", ActiveForm: "Running tests"},
+		{Content: "Fix bug", Status: "in_progress", ActiveForm: "Fixing bug"},
+		{Content: "Write docs", Status: "pending", ActiveForm: "Writing docs"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(result.Content[0].Text, "Todo list updated:") {
+		t.Errorf("unexpected text: %s", result.Content[0].Text)
+	}
+	if result.StructuredContent["total"] != float64(3) || result.StructuredContent["completed"] != float64(1) ||
+		result.StructuredContent["inProgress"] != "Fixing bug" {
+		t.Errorf("unexpected structured content: %v", result.StructuredContent)
+	}
+	if todos, _ := result.StructuredContent["todos"].([]any); len(todos) != 3 {
+		t.Errorf("expected 3 todos, got %v", result.StructuredContent["todos"])
+	}
+
+	if _, err := s.todoWrite(testContext(t), TodoWriteParams{Todos: []TodoItem{
+		{Content: "A", Status: "in_progress"},
+		{Content: "B", Status: "in_progress"},
+	}}); err == nil {
+		t.Error("expected more than one in_progress task to fail")
+	}
+}
@@ -0,0 +1,181 @@
+package system
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// workspaceRoot returns the directory the tools of a session work in: the
+// session directory, or the current directory outside of a session.
+func workspaceRoot(ctx context.Context) (string, error) {
+	sessionID, _ := types.GetSessionAndAccountID(ctx)
+	if sessionID != "" {
+		return ensureSessionDir(sessionID)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return ".", nil
+	}
+	return cwd, nil
+}
+
+// workspacePath resolves a path given to a tool, that the tool writes to if
+// write is set. Relative paths are resolved against the workspace root. In a
+// session, paths in the mounts of the config are resolved to their host
+// directories, and other paths outside of the session directory are rejected
+// unless the config allows host file access.
+func workspacePath(ctx context.Context, path string, write bool) (string, error) {
+	root, err := workspaceRoot(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	path = filepath.Clean(path)
+
+	sessionID, _ := types.GetSessionAndAccountID(ctx)
+	if sessionID == "" {
+		return path, nil
+	}
+	config := types.ConfigFromContext(ctx)
+	if name, hostPath, ok := mountPath(config, root, path); ok {
+		mount := config.Mounts[name]
+		if write && !mount.Writable() {
+			return "", mcp.ErrRPCInvalidParams.WithMessage("mount %s is read-only", name)
+		}
+		if !config.HostFileAccess && !linksInside(mountRoot(mount), hostPath) {
+			return "", mcp.ErrRPCInvalidParams.WithMessage("path %s links outside of mount %s", path, name)
+		}
+		return hostPath, nil
+	}
+	if config.HostFileAccess {
+		return path, nil
+	}
+	if !insideDir(root, path) {
+		return "", mcp.ErrRPCInvalidParams.WithMessage("path %s is outside of the session directory %s", path, root)
+	}
+	// Symbolic links must not lead out of the workspace either.
+	if !linksInside(root, path) {
+		return "", mcp.ErrRPCInvalidParams.WithMessage("path %s links outside of the session directory %s", path, root)
+	}
+	return path, nil
+}
+
+// mountPath returns the name of the mount a path of the workspace is in, and
+// the path on the host.
+func mountPath(config types.Config, root, path string) (string, string, bool) {
+	rel, err := filepath.Rel(filepath.Join(root, types.MountsDir), path)
+	if err != nil || rel == "." || !insideDir(".", rel) {
+		return "", "", false
+	}
+	name, rest, _ := strings.Cut(filepath.ToSlash(rel), "/")
+	mount, ok := config.Mounts[name]
+	if !ok {
+		return "", "", false
+	}
+	return name, filepath.Join(mountRoot(mount), filepath.FromSlash(rest)), true
+}
+
+// workspaceRel returns the path of the workspace that workspacePath resolves
+// to the path, false for the workspace root, the mounts themselves, and paths
+// outside of them.
+func workspaceRel(ctx context.Context, root, path string) (string, bool) {
+	if rel, err := filepath.Rel(root, path); err == nil && rel != "." && insideDir(root, path) {
+		return rel, true
+	}
+	for name, mount := range types.ConfigFromContext(ctx).Mounts {
+		dir := mountRoot(mount)
+		if rel, err := filepath.Rel(dir, path); err == nil && rel != "." && insideDir(dir, path) {
+			return filepath.Join(types.MountsDir, name, rel), true
+		}
+	}
+	return "", false
+}
+
+// mountRoot returns the absolute host directory of a mount.
+func mountRoot(mount types.Mount) string {
+	if dir, err := filepath.Abs(mount.Path); err == nil {
+		return dir
+	}
+	return filepath.Clean(mount.Path)
+}
+
+// linksInside indicates if the path, after following its symbolic links, is
+// still inside the directory. Every component of the path is checked, so a
+// link to a file yet to be created outside of the directory is refused too.
+// Any error reading the links refuses the path.
+func linksInside(dir, path string) bool {
+	resolvedDir, err := filepath.EvalSymlinks(dir)
+	if err != nil || !insideDir(dir, path) {
+		return false
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	_, err = resolveInside(resolvedDir, resolvedDir, rel, 0)
+	return err == nil
+}
+
+// maxSymlinks is how many symbolic links resolveInside follows before it
+// gives up, like the limit of the kernel.
+const maxSymlinks = 40
+
+// resolveInside follows the symbolic links of rel, relative to cur, and
+// returns the path it leads to, or an error if a link leads outside of dir.
+// Components that don't exist yet are kept as they are.
+func resolveInside(dir, cur, rel string, links int) (string, error) {
+	if rel == "." {
+		return cur, nil
+	}
+	parts := strings.Split(rel, string(filepath.Separator))
+	for i, part := range parts {
+		next := filepath.Join(cur, part)
+		info, err := os.Lstat(next)
+		if os.IsNotExist(err) {
+			return filepath.Join(append([]string{next}, parts[i+1:]...)...), nil
+		} else if err != nil {
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			cur = next
+			continue
+		}
+
+		if links++; links > maxSymlinks {
+			return "", errors.New("too many symbolic links")
+		}
+		target, err := os.Readlink(next)
+		if err != nil {
+			return "", err
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(cur, target)
+		}
+		target = filepath.Clean(target)
+		if !insideDir(dir, target) {
+			return "", fmt.Errorf("%s links outside of %s", next, dir)
+		}
+		targetRel, err := filepath.Rel(dir, target)
+		if err != nil {
+			return "", err
+		}
+		if cur, err = resolveInside(dir, dir, targetRel, links); err != nil {
+			return "", err
+		}
+	}
+	return cur, nil
+}
+
+func insideDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,174 @@
+package system
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+func TestWorkspacePath(t *testing.T) {
+	tmpDir := t.TempDir()
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(oldWd)
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := testContext(t)
+	s := &Server{}
+	root := filepath.Join(tmpDir, sessionsDir, testSessionID)
+	outside := filepath.Join(tmpDir, "outside.txt")
+
+	if _, err := s.write(ctx, WriteParams{FilePath: "notes/todo.txt", Content: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filepath.Join(root, "notes", "todo.txt")); err != nil || string(data) != "hello" {
+		t.Errorf("expected a relative path to be written in the session directory, got %q, %v", data, err)
+	}
+
+	if _, err := s.write(ctx, WriteParams{FilePath: outside, Content: "x"}); err == nil {
+		t.Error("expected writing outside the session directory to fail")
+	}
+	if _, err := s.read(ctx, ReadParams{FilePath: "../../outside.txt"}); err == nil {
+		t.Error("expected reading outside the session directory to fail")
+	}
+
+	if err := os.Symlink(tmpDir, filepath.Join(root, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := workspacePath(ctx, "escape/outside.txt", true); err == nil {
+		t.Error("expected a link out of the session directory to fail")
+	}
+	if err := os.Symlink(filepath.Join(tmpDir, "created.txt"), filepath.Join(root, "dangling")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.write(ctx, WriteParams{FilePath: "dangling", Content: "x"}); err == nil {
+		t.Error("expected writing through a dangling link out of the session directory to fail")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "created.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected no file to be created outside the session directory, got %v", err)
+	}
+	if err := os.Symlink("notes/new.txt", filepath.Join(root, "inside")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := workspacePath(ctx, "inside", true); err != nil {
+		t.Errorf("expected a link inside the session directory to be allowed: %v", err)
+	}
+	if _, err := s.bash(ctx, BashParams{Command: "true", Workdir: &tmpDir}); err == nil {
+		t.Error("expected a bash working directory outside the session directory to fail")
+	}
+
+	hostCtx := types.WithConfig(ctx, types.Config{HostFileAccess: true})
+	if _, err := s.write(hostCtx, WriteParams{FilePath: outside, Content: "x"}); err != nil {
+		t.Errorf("expected host file access to allow writing outside the session directory: %v", err)
+	}
+}
+
+func TestWorkspaceMounts(t *testing.T) {
+	tmpDir := t.TempDir()
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(oldWd)
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+
+	project := filepath.Join(tmpDir, "project")
+	docs := filepath.Join(tmpDir, "docs")
+	for _, dir := range []string{project, docs} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(docs, "guide.md"), []byte("# Guide"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := types.WithConfig(testContext(t), types.Config{Mounts: map[string]types.Mount{
+		"project": {Path: project, Mode: types.MountReadWrite},
+		"docs":    {Path: docs},
+	}})
+	s := &Server{}
+
+	if _, err := s.write(ctx, WriteParams{FilePath: "mounts/project/main.go", Content: "package main"}); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filepath.Join(project, "main.go")); err != nil || string(data) != "package main" {
+		t.Errorf("expected the file to be written to the mount, got %q, %v", data, err)
+	}
+
+	if _, err := s.write(ctx, WriteParams{FilePath: "mounts/docs/guide.md", Content: "x"}); err == nil {
+		t.Error("expected writing to a read-only mount to fail")
+	}
+	result, err := s.read(ctx, ReadParams{FilePath: "mounts/docs/guide.md"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Content[0].Text, "# Guide") {
+		t.Errorf("unexpected content: %s", result.Content[0].Text)
+	}
+	if _, err := s.read(ctx, ReadParams{FilePath: "mounts/docs/../../../outside.txt"}); err == nil {
+		t.Error("expected reading outside the session directory through a mount to fail")
+	}
+
+	resources, err := s.listFileResources(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var uris []string
+	for _, resource := range resources {
+		uris = append(uris, resource.URI)
+	}
+	for _, want := range []string{"file:///mounts/docs/guide.md", "file:///mounts/project/main.go"} {
+		if !slices.Contains(uris, want) {
+			t.Errorf("expected %s in the resources, got %v", want, uris)
+		}
+	}
+
+	read, err := s.readFileResource(ctx, "file:///mounts/docs/guide.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *read.Contents[0].Text != "# Guide" {
+		t.Errorf("unexpected resource content: %s", *read.Contents[0].Text)
+	}
+
+	// The other file tools honor the mounts too.
+	blob := base64.StdEncoding.EncodeToString([]byte("x"))
+	if _, err := s.uploadFile(ctx, UploadFileParams{Name: "mounts/docs/upload.txt", Blob: blob}); err == nil {
+		t.Error("expected uploading to a read-only mount to fail")
+	}
+	if _, err := s.uploadFile(ctx, UploadFileParams{Name: "mounts/project/upload.txt", Blob: blob}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(project, "upload.txt")); err != nil {
+		t.Errorf("expected the file to be uploaded to the mount: %v", err)
+	}
+	if _, err := s.createArchive(ctx, CreateArchiveParams{Output: "mounts/docs/docs.zip", Paths: []string{"mounts/docs"}}); err == nil {
+		t.Error("expected archiving to a read-only mount to fail")
+	}
+	if _, err := s.cleanupFiles(ctx, CleanupFilesParams{Paths: []string{"mounts/docs/*"}}); err == nil {
+		t.Error("expected removing files of a read-only mount to fail")
+	}
+	cleaned, err := s.cleanupFiles(ctx, CleanupFilesParams{Paths: []string{"mounts/project/*.txt"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cleaned.Removed) != 1 || cleaned.Removed[0].Path != filepath.Join("mounts", "project", "upload.txt") {
+		t.Errorf("unexpected cleanup result: %+v", cleaned)
+	}
+	workdir := "mounts/docs"
+	if _, err := s.bash(ctx, BashParams{Command: "true", Workdir: &workdir}); err == nil {
+		t.Error("expected running a command in a read-only mount to fail")
+	}
+}
@@ -0,0 +1,93 @@
+package tasks
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/envvar"
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/session"
+)
+
+// ScheduledRunEvent is the event of the webhook POSTed when a run of a
+// scheduled task finished.
+const ScheduledRunEvent = "scheduledRun"
+
+const (
+	runCompleted = "completed"
+	runFailed    = "failed"
+)
+
+// runNotification is the data of a ScheduledRunEvent webhook.
+type runNotification struct {
+	TaskURI   string `json:"taskURI"`
+	Name      string `json:"name"`
+	SessionID string `json:"sessionId"`
+	// Status is completed or failed, or the status of a workflow run that
+	// didn't complete, like waiting when it waits for an approval.
+	Status     string    `json:"status"`
+	Output     any       `json:"output,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+}
+
+func newRunNotification(task session.ScheduledTask, sessionID string, startedAt time.Time, result *mcp.CallToolResult, err error) runNotification {
+	notification := runNotification{
+		TaskURI:    task.TaskURI,
+		Name:       task.Name,
+		SessionID:  sessionID,
+		Status:     runCompleted,
+		StartedAt:  startedAt.UTC(),
+		FinishedAt: time.Now().UTC(),
+	}
+	if err != nil {
+		notification.Status = runFailed
+		notification.Error = err.Error()
+		return notification
+	}
+
+	var text []string
+	for _, content := range result.Content {
+		if content.Type == "text" && content.Text != "" {
+			text = append(text, content.Text)
+		}
+	}
+	if result.StructuredContent != nil {
+		notification.Output = result.StructuredContent
+	} else if len(text) > 0 {
+		notification.Output = strings.Join(text, "\n")
+	}
+
+	if result.IsError {
+		notification.Status = runFailed
+		notification.Error = strings.Join(text, "\n")
+		notification.Output = nil
+	} else if status, _ := result.StructuredContent["status"].(string); task.WorkflowURI != "" && status != "" && status != runCompleted {
+		notification.Status = status
+		notification.Error, _ = result.StructuredContent["error"].(string)
+	}
+	return notification
+}
+
+// notify POSTs the notification to the webhook of the task. Only the webhooks
+// of the schedules of the config are expanded with the env, the others come
+// from the model and must not be able to read it.
+func (s *Server) notify(task session.ScheduledTask, notification runNotification) {
+	ctx, cancel := context.WithTimeout(s.ctx, 2*time.Minute)
+	defer cancel()
+
+	target := task.Webhook
+	if task.Source == session.ScheduledTaskSourceConfig {
+		target = envvar.ReplaceString(s.env, target)
+	} else if !strings.HasPrefix(target, "https://") {
+		slog.Error("scheduled task: refusing to send webhook to a non-https URL", "task_uri", task.TaskURI, "session_id", notification.SessionID)
+		return
+	}
+
+	if err := mcp.SendWebhook(ctx, s.env, target, ScheduledRunEvent, notification); err != nil {
+		slog.Error("scheduled task: failed to send webhook", "task_uri", task.TaskURI, "session_id", notification.SessionID, "error", err)
+	}
+}
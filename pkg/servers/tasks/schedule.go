@@ -64,3 +64,7 @@ func nextRunAt(spec cron.Schedule, loc *time.Location, expiresAt *time.Time, aft
 	}
 	return &next
 }
+
+// configTaskURIPrefix is the prefix of the task URIs of the schedules of the
+// config, which can't collide with the URIs of tasks named by users.
+const configTaskURIPrefix = "task:///schedules/"
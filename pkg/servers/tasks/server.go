@@ -1,11 +1,15 @@
 package tasks
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -21,17 +25,22 @@ import (
 // taskResult is the agent-facing JSON shape for a scheduled task.
 // Keeps gorm.Model internals out of the API.
 type taskResult struct {
-	URI        string     `json:"uri"`
-	Name       string     `json:"name"`
-	Prompt     string     `json:"prompt"`
-	Schedule   string     `json:"schedule"`
-	Timezone   string     `json:"timezone"`
-	Expiration string     `json:"expiration,omitempty"`
-	Enabled    bool       `json:"enabled"`
-	LastRunAt  *time.Time `json:"lastRunAt,omitempty"`
-	NextRunAt  *time.Time `json:"nextRunAt,omitempty"`
-	CreatedAt  time.Time  `json:"createdAt"`
-	UpdatedAt  time.Time  `json:"updatedAt"`
+	URI        string         `json:"uri"`
+	Name       string         `json:"name"`
+	Prompt     string         `json:"prompt"`
+	Agent      string         `json:"agent,omitempty"`
+	Workflow   string         `json:"workflow,omitempty"`
+	Inputs     map[string]any `json:"inputs,omitempty"`
+	Webhook    string         `json:"webhook,omitempty"`
+	Source     string         `json:"source,omitempty"`
+	Schedule   string         `json:"schedule"`
+	Timezone   string         `json:"timezone"`
+	Expiration string         `json:"expiration,omitempty"`
+	Enabled    bool           `json:"enabled"`
+	LastRunAt  *time.Time     `json:"lastRunAt,omitempty"`
+	NextRunAt  *time.Time     `json:"nextRunAt,omitempty"`
+	CreatedAt  time.Time      `json:"createdAt"`
+	UpdatedAt  time.Time      `json:"updatedAt"`
 }
 
 func toResult(task session.ScheduledTask) taskResult {
@@ -45,6 +54,11 @@ func toResult(task session.ScheduledTask) taskResult {
 		URI:        task.TaskURI,
 		Name:       task.Name,
 		Prompt:     task.Prompt,
+		Agent:      task.Agent,
+		Workflow:   task.WorkflowURI,
+		Inputs:     task.Inputs,
+		Webhook:    task.Webhook,
+		Source:     task.Source,
 		Schedule:   task.Schedule,
 		Timezone:   task.Timezone,
 		Expiration: expiration,
@@ -63,11 +77,16 @@ type Server struct {
 	tools       mcp.ServerTools
 	db          *session.Store
 	loopbackURL string
+	env         map[string]string
+	triggers    map[string]types.Trigger
 	ctx         context.Context
 	cancel      context.CancelFunc
 	wg          sync.WaitGroup
 	mu          sync.Mutex
 	jobs        map[string]*job
+	// deliveries are the signatures of the webhook payloads of triggers that
+	// were accepted, until their timestamp is too old to be accepted again.
+	deliveries map[string]time.Time
 }
 
 type job struct {
@@ -75,11 +94,23 @@ type job struct {
 	cancel     context.CancelFunc
 }
 
-// NewServer creates the task server, sets the DB, and loads persisted tasks.
-func NewServer(ctx context.Context, db *session.Store, loopbackURL string) (*Server, error) {
+// Options are the schedules and triggers of the config that the task server
+// runs, and the environment that the URLs of webhooks and the webhook secrets
+// are read from.
+type Options struct {
+	Schedules map[string]types.Schedule
+	Triggers  map[string]types.Trigger
+	Env       map[string]string
+}
+
+// NewServer creates the task server, sets the DB, syncs the schedules of the
+// config, loads persisted tasks, and starts the triggers.
+func NewServer(ctx context.Context, db *session.Store, loopbackURL string, opt Options) (*Server, error) {
 	s := &Server{
 		SubscriptionManager: fswatch.NewSubscriptionManager(ctx),
 		loopbackURL:         loopbackURL,
+		env:                 opt.Env,
+		triggers:            opt.Triggers,
 		jobs:                make(map[string]*job),
 		db:                  db,
 	}
@@ -90,8 +121,14 @@ func NewServer(ctx context.Context, db *session.Store, loopbackURL string) (*Ser
 		mcp.NewServerTool("updateScheduledTask", "Update a scheduled task", s.updateTask),
 		mcp.NewServerTool("deleteScheduledTask", "Delete a scheduled task", s.deleteTask),
 		mcp.NewServerTool("startScheduledTask", "Start a scheduled task now", s.startTask),
+		mcp.NewServerTool("scheduleRun", `Schedule an agent prompt or an executable workflow to run on a cron schedule or every few minutes. Every run is a new session that runs without a user.
+Unlike createScheduledTask, any cron expression or descriptor like @hourly can be used. The result of every run can be POSTed to a webhook.`, s.scheduleRun),
 	)
 
+	if err := s.syncSchedules(ctx, opt.Schedules); err != nil {
+		return nil, err
+	}
+
 	tasks, err := db.ListScheduledTasks(ctx)
 	if err != nil {
 		return nil, err
@@ -103,6 +140,11 @@ func NewServer(ctx context.Context, db *session.Store, loopbackURL string) (*Ser
 		}
 	}
 
+	if err := s.startTriggers(); err != nil {
+		s.cancel()
+		return nil, err
+	}
+
 	context.AfterFunc(ctx, func() {
 		s.cancel()
 		s.wg.Wait()
@@ -233,6 +275,9 @@ func (s *Server) updateTask(ctx context.Context, params struct {
 	if err != nil {
 		return nil, err
 	}
+	if task.Source == session.ScheduledTaskSourceConfig {
+		return nil, errConfigTask(task.TaskURI)
+	}
 
 	if params.Name != "" {
 		task.Name = params.Name
@@ -262,8 +307,12 @@ func (s *Server) updateTask(ctx context.Context, params struct {
 		}
 		task.ExpiresAt = expiresAt
 	}
-	if err := validateSchedule(task.Schedule, task.ExpiresAt != nil); err != nil {
-		return nil, err
+	// Tasks from scheduleRun can have any schedule, so only a changed
+	// schedule is held to the schedules of the UI.
+	if params.Schedule != "" || params.Expiration != nil {
+		if err := validateSchedule(task.Schedule, task.ExpiresAt != nil); err != nil {
+			return nil, err
+		}
 	}
 	if task.Enabled {
 		task.NextRunAt = nextRunAt(spec, loc, task.ExpiresAt, time.Now())
@@ -293,6 +342,9 @@ func (s *Server) deleteTask(ctx context.Context, params struct {
 	if params.URI == "" {
 		return "", mcp.ErrRPCInvalidParams.WithMessage("uri is required")
 	}
+	if task, err := s.db.GetScheduledTask(ctx, params.URI); err == nil && task.Source == session.ScheduledTaskSourceConfig {
+		return "", errConfigTask(task.TaskURI)
+	}
 	if err := s.db.DeleteScheduledTask(ctx, params.URI); err != nil {
 		return "", fmt.Errorf("failed to delete: %w", err)
 	}
@@ -302,6 +354,117 @@ func (s *Server) deleteTask(ctx context.Context, params struct {
 	return fmt.Sprintf("%s deleted", params.URI), nil
 }
 
+func errConfigTask(taskURI string) error {
+	return mcp.ErrRPCInvalidParams.WithMessage("task %q is a schedule of the config, change the config instead", taskURI)
+}
+
+type scheduleRunParams struct {
+	Name            string         `json:"name" jsonschema:"The name of the scheduled run"`
+	Cron            string         `json:"cron,omitempty" jsonschema:"A five-field cron expression, or a descriptor like @daily"`
+	IntervalMinutes int            `json:"intervalMinutes,omitempty" jsonschema:"Run every this many minutes instead of on a cron schedule"`
+	Timezone        string         `json:"timezone,omitempty" jsonschema:"The IANA timezone of the cron expression, defaults to UTC"`
+	Agent           string         `json:"agent,omitempty" jsonschema:"The agent that runs the prompt, defaults to the entrypoint agent"`
+	Prompt          string         `json:"prompt,omitempty" jsonschema:"The prompt to run"`
+	Workflow        string         `json:"workflow,omitempty" jsonschema:"The workflow:/// URI of an executable workflow to run instead of a prompt"`
+	Inputs          map[string]any `json:"inputs,omitempty" jsonschema:"The inputs of the workflow"`
+	Webhook         string         `json:"webhook,omitempty" jsonschema:"An https:// URL the result of every run is POSTed to"`
+}
+
+func (s *Server) scheduleRun(ctx context.Context, params scheduleRunParams) (*taskResult, error) {
+	if params.Name == "" {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("name is required")
+	}
+	schedule := types.Schedule{
+		Cron:            params.Cron,
+		IntervalMinutes: params.IntervalMinutes,
+		Timezone:        params.Timezone,
+		Agent:           params.Agent,
+		Prompt:          params.Prompt,
+		Workflow:        params.Workflow,
+		Inputs:          params.Inputs,
+		Webhook:         params.Webhook,
+	}
+	if err := schedule.Validate(); err != nil {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("%v", err)
+	}
+	if params.Webhook != "" && !strings.HasPrefix(params.Webhook, "https://") {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("webhook %q must be an https:// URL", params.Webhook)
+	}
+	if _, ok := types.ConfigFromContext(ctx).Agents[params.Agent]; params.Agent != "" && !ok {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("agent %q not found", params.Agent)
+	}
+
+	taskURI, err := s.db.NextScheduledTaskURI(ctx, params.Name)
+	if err != nil {
+		return nil, err
+	}
+	task, err := newScheduledTask(taskURI, params.Name, schedule, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.CreateScheduledTask(ctx, &task); err != nil {
+		return nil, fmt.Errorf("failed to create: %w", err)
+	}
+
+	s.scheduleTask(taskURI)
+	s.SendListChangedNotification()
+
+	result := toResult(task)
+	return &result, nil
+}
+
+// newScheduledTask returns the task of a schedule. Intervals are stored as
+// @every schedules.
+func newScheduledTask(taskURI, name string, schedule types.Schedule, now time.Time) (session.ScheduledTask, error) {
+	cronSchedule := schedule.Cron
+	if schedule.IntervalMinutes > 0 {
+		cronSchedule = fmt.Sprintf("@every %dm", schedule.IntervalMinutes)
+	}
+	timezone := cmp.Or(schedule.Timezone, "UTC")
+
+	spec, loc, err := parseSchedule(cronSchedule, timezone)
+	if err != nil {
+		return session.ScheduledTask{}, err
+	}
+
+	task := session.ScheduledTask{
+		TaskURI:     taskURI,
+		Name:        name,
+		Prompt:      schedule.Prompt,
+		Agent:       schedule.Agent,
+		WorkflowURI: schedule.Workflow,
+		Inputs:      schedule.Inputs,
+		Webhook:     schedule.Webhook,
+		Schedule:    cronSchedule,
+		Timezone:    timezone,
+		Enabled:     !schedule.Disabled,
+	}
+	if task.Enabled {
+		task.NextRunAt = nextRunAt(spec, loc, nil, now)
+	}
+	return task, nil
+}
+
+// syncSchedules stores the schedules of the config as the tasks
+// task:///schedules/<name>, and removes the tasks of schedules that were
+// removed from the config.
+func (s *Server) syncSchedules(ctx context.Context, schedules map[string]types.Schedule) error {
+	now := time.Now()
+	tasks := make([]session.ScheduledTask, 0, len(schedules))
+	for _, name := range slices.Sorted(maps.Keys(schedules)) {
+		task, err := newScheduledTask(configTaskURIPrefix+name, name, schedules[name], now)
+		if err != nil {
+			return fmt.Errorf("invalid schedule %q: %w", name, err)
+		}
+		tasks = append(tasks, task)
+	}
+
+	if _, err := s.db.SyncConfigScheduledTasks(ctx, tasks); err != nil {
+		return fmt.Errorf("failed to sync schedules: %w", err)
+	}
+	return nil
+}
+
 func (s *Server) startTask(ctx context.Context, params struct {
 	URI string `json:"uri"`
 }) (*struct {
@@ -495,12 +658,49 @@ func (s *Server) cancelTask(taskURI string) {
 }
 
 func (s *Server) startChat(ctx context.Context, task session.ScheduledTask) (string, error) {
+	r := run{
+		name:        task.Name,
+		taskURI:     task.TaskURI,
+		agent:       task.Agent,
+		prompt:      task.Prompt + "\n\nThis is an automated scheduled task. Execute immediately without asking for confirmation or approval.",
+		workflowURI: task.WorkflowURI,
+		inputs:      task.Inputs,
+	}
+	if task.Webhook != "" {
+		r.done = func(sessionID string, startedAt time.Time, result *mcp.CallToolResult, err error) {
+			s.notify(task, newRunNotification(task, sessionID, startedAt, result, err))
+		}
+	}
+	return s.startRun(ctx, r)
+}
+
+// run is a run of an agent or a workflow in a new session, without a user.
+type run struct {
+	name    string
+	taskURI string
+	agent   string
+	// prompt is the first user message of the chat with the agent, unless
+	// workflowURI is set.
+	prompt      string
+	workflowURI string
+	inputs      map[string]any
+	// done is called with the result when the run finished.
+	done func(sessionID string, startedAt time.Time, result *mcp.CallToolResult, err error)
+}
+
+func (s *Server) startRun(ctx context.Context, r run) (string, error) {
+	headers := map[string]string{
+		"X-Nanobot-Description": r.name,
+	}
+	if r.taskURI != "" {
+		headers["X-Nanobot-Task-URI"] = r.taskURI
+	}
+	if r.agent != "" {
+		headers["X-Nanobot-Default-Agent"] = r.agent
+	}
 	client, err := mcp.NewClient(ctx, "nanobot-scheduler", mcp.Server{
 		BaseURL: s.loopbackURL,
-		Headers: map[string]string{
-			"X-Nanobot-Description": task.Name,
-			"X-Nanobot-Task-URI":    task.TaskURI,
-		},
+		Headers: headers,
 	}, mcp.ClientOption{
 		ClientName: "nanobot-scheduler",
 		OnMessage: func(_ context.Context, msg mcp.Message) error {
@@ -529,14 +729,32 @@ func (s *Server) startChat(ctx context.Context, task session.ScheduledTask) (str
 	s.wg.Go(func() {
 		defer cancel()
 		defer client.Close(false)
-		if _, err := client.Call(callCtx, types.AgentTool+"nanobot", map[string]any{
-			"prompt": task.Prompt + "\n\nThis is an automated scheduled task. Execute immediately without asking for confirmation or approval.",
-		}, mcp.CallOption{
-			ProgressToken: uuid.String(),
-		}); err != nil {
-			slog.Error("scheduled task: chat failed", "task_uri", task.TaskURI, "session_id", sessionID, "error", err)
+
+		startedAt := time.Now()
+		result, err := r.call(callCtx, client)
+		if err != nil {
+			slog.Error("scheduled task: chat failed", "name", r.name, "task_uri", r.taskURI, "session_id", sessionID, "error", err)
+		}
+		if r.done != nil {
+			r.done(sessionID, startedAt, result, err)
 		}
 	})
 
 	return sessionID, nil
 }
+
+// call runs the workflow with the published runWorkflow tool and waits for
+// it, or else chats with the default agent of the session.
+func (r run) call(ctx context.Context, client *mcp.Client) (*mcp.CallToolResult, error) {
+	opt := mcp.CallOption{ProgressToken: uuid.String()}
+	if r.workflowURI != "" {
+		return client.Call(ctx, "runWorkflow", map[string]any{
+			"uri":    r.workflowURI,
+			"inputs": r.inputs,
+			"wait":   true,
+		}, opt)
+	}
+	return client.Call(ctx, types.AgentTool+"nanobot", map[string]any{
+		"prompt": r.prompt,
+	}, opt)
+}
@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/obot-platform/nanobot/pkg/mcp"
 	"github.com/obot-platform/nanobot/pkg/session"
+	"github.com/obot-platform/nanobot/pkg/types"
 )
 
 func testServer(t *testing.T) *Server {
@@ -19,7 +21,7 @@ func testServer(t *testing.T) *Server {
 		t.Fatalf("failed to create session store: %v", err)
 	}
 
-	srv, err := NewServer(t.Context(), store, "")
+	srv, err := NewServer(t.Context(), store, "", Options{})
 	if err != nil {
 		t.Fatalf("failed to start: %v", err)
 	}
@@ -191,3 +193,131 @@ func TestContiguousTaskURIsAfterDelete(t *testing.T) {
 		t.Fatalf("third.URI = %q, want %q", third.URI, "task:///daily-summary-3")
 	}
 }
+
+func TestSyncSchedules(t *testing.T) {
+	srv := testServer(t)
+	ctx := context.Background()
+
+	if err := srv.syncSchedules(ctx, map[string]types.Schedule{
+		"digest":  {Cron: "@daily", Timezone: "Europe/Berlin", Prompt: "Send the digest."},
+		"cleanup": {IntervalMinutes: 15, Workflow: "workflow:///ops/cleanup", Inputs: map[string]any{"dryRun": true}},
+	}); err != nil {
+		t.Fatalf("syncSchedules: %v", err)
+	}
+
+	cleanup, err := srv.db.GetScheduledTask(ctx, "task:///schedules/cleanup")
+	if err != nil {
+		t.Fatalf("GetScheduledTask: %v", err)
+	}
+	if cleanup.Schedule != "@every 15m" || cleanup.Timezone != "UTC" || cleanup.WorkflowURI != "workflow:///ops/cleanup" ||
+		cleanup.Inputs["dryRun"] != true || !cleanup.Enabled || cleanup.NextRunAt == nil {
+		t.Fatalf("unexpected task %+v", cleanup)
+	}
+
+	if _, err := srv.deleteTask(ctx, struct {
+		URI string `json:"uri"`
+	}{URI: cleanup.TaskURI}); err == nil {
+		t.Fatal("expected deleting a schedule of the config to fail")
+	}
+
+	if err := srv.syncSchedules(ctx, map[string]types.Schedule{
+		"digest": {Cron: "0 8 * * *", Prompt: "Send the digest.", Disabled: true},
+	}); err != nil {
+		t.Fatalf("syncSchedules: %v", err)
+	}
+
+	listed, err := srv.listTasks(ctx, struct{}{})
+	if err != nil {
+		t.Fatalf("listTasks: %v", err)
+	}
+	if len(listed.Tasks) != 1 {
+		t.Fatalf("len(listed.Tasks) = %d, want 1", len(listed.Tasks))
+	}
+	if digest := listed.Tasks[0]; digest.URI != "task:///schedules/digest" || digest.Schedule != "0 8 * * *" ||
+		digest.Enabled || digest.Source != session.ScheduledTaskSourceConfig {
+		t.Fatalf("unexpected task %+v", digest)
+	}
+
+	if err := srv.syncSchedules(ctx, map[string]types.Schedule{
+		"cleanup": {IntervalMinutes: 30, Workflow: "workflow:///ops/cleanup"},
+	}); err != nil {
+		t.Fatalf("expected a schedule removed before to be added again: %v", err)
+	}
+
+	if err := srv.syncSchedules(ctx, map[string]types.Schedule{
+		"broken": {Cron: "every day", Prompt: "Hi"},
+	}); err == nil || !strings.Contains(err.Error(), `"broken"`) {
+		t.Fatalf("expected the invalid cron expression to be reported, got %v", err)
+	}
+}
+
+func TestScheduleRun(t *testing.T) {
+	srv := testServer(t)
+	ctx := context.Background()
+
+	for _, params := range []scheduleRunParams{
+		{Name: "Both", Cron: "@hourly", IntervalMinutes: 5, Prompt: "Hi"},
+		{Name: "Neither", Cron: "@hourly"},
+		{Name: "Hook", Cron: "@hourly", Prompt: "Hi", Webhook: "ftp://example.com"},
+		{Name: "Plain Hook", Cron: "@hourly", Prompt: "Hi", Webhook: "http://example.com"},
+		{Name: "Agent", Cron: "@hourly", Prompt: "Hi", Agent: "missing"},
+	} {
+		if _, err := srv.scheduleRun(ctx, params); err == nil {
+			t.Errorf("expected scheduleRun %s to fail", params.Name)
+		}
+	}
+
+	task, err := srv.scheduleRun(ctx, scheduleRunParams{
+		Name:     "Hourly Report",
+		Cron:     "5 * * * *",
+		Prompt:   "Report.",
+		Webhook:  "https://example.com/hooks/${HOOK_ID}",
+		Timezone: "America/New_York",
+	})
+	if err != nil {
+		t.Fatalf("scheduleRun: %v", err)
+	}
+	if task.URI != "task:///hourly-report" || task.Schedule != "5 * * * *" || !task.Enabled || task.NextRunAt == nil {
+		t.Fatalf("unexpected task %+v", task)
+	}
+
+	disabled := false
+	if _, err := srv.updateTask(ctx, struct {
+		URI        string  `json:"uri"`
+		Name       string  `json:"name,omitempty"`
+		Prompt     string  `json:"prompt,omitempty"`
+		Schedule   string  `json:"schedule,omitempty"`
+		Timezone   string  `json:"timezone,omitempty"`
+		Expiration *string `json:"expiration,omitempty"`
+		Enabled    *bool   `json:"enabled,omitempty"`
+	}{URI: task.URI, Enabled: &disabled}); err != nil {
+		t.Fatalf("expected a task with an hourly schedule to be disabled: %v", err)
+	}
+}
+
+func TestRunNotification(t *testing.T) {
+	task := session.ScheduledTask{TaskURI: "task:///schedules/cleanup", Name: "cleanup", WorkflowURI: "workflow:///ops/cleanup"}
+
+	n := newRunNotification(task, "s1", time.Now(), &mcp.CallToolResult{
+		StructuredContent: map[string]any{"status": "waiting"},
+	}, nil)
+	if n.Status != "waiting" || n.SessionID != "s1" {
+		t.Errorf("unexpected notification %+v", n)
+	}
+
+	n = newRunNotification(task, "s1", time.Now(), &mcp.CallToolResult{
+		IsError: true,
+		Content: []mcp.Content{{Type: "text", Text: "workflow not found"}},
+	}, nil)
+	if n.Status != runFailed || n.Error != "workflow not found" || n.Output != nil {
+		t.Errorf("unexpected notification %+v", n)
+	}
+
+	task.WorkflowURI = ""
+	n = newRunNotification(task, "s1", time.Now(), &mcp.CallToolResult{
+		Content: []mcp.Content{{Type: "text", Text: "All clean."}},
+	}, nil)
+	if n.Status != runCompleted || n.Output != "All clean." {
+		t.Errorf("unexpected notification %+v", n)
+	}
+}
@@ -0,0 +1,209 @@
+package tasks
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/envvar"
+	"github.com/obot-platform/nanobot/pkg/fswatch"
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// TriggerPathPrefix is the path of the webhooks of triggers, which are
+// POSTed to /triggers/<name>.
+const TriggerPathPrefix = "/triggers/"
+
+const maxTriggerPayload = 1 << 20
+
+// startTriggers watches the directories of the triggers, which are closed
+// with the server.
+func (s *Server) startTriggers() error {
+	for _, name := range slices.Sorted(maps.Keys(s.triggers)) {
+		trigger := s.triggers[name]
+		if trigger.Disabled {
+			continue
+		}
+		if trigger.Webhook && s.triggerSecret(trigger) == "" {
+			slog.Warn("trigger webhook has no secret and rejects all payloads", "trigger", name, "env", mcp.WebhookSecretEnv)
+		}
+		if trigger.Watch == "" {
+			continue
+		}
+
+		if err := os.MkdirAll(trigger.Watch, 0755); err != nil {
+			return fmt.Errorf("failed to create %s of trigger %q: %w", trigger.Watch, name, err)
+		}
+		watcher := fswatch.NewWatcher(trigger.Watch, 0, nil, func(events []fswatch.Event) {
+			s.handleFileEvents(name, trigger, events)
+		})
+		if err := watcher.Start(); err != nil {
+			return fmt.Errorf("failed to watch %s of trigger %q: %w", trigger.Watch, name, err)
+		}
+		go func() {
+			<-s.ctx.Done()
+			_ = watcher.Close()
+		}()
+	}
+	return nil
+}
+
+func (s *Server) handleFileEvents(name string, trigger types.Trigger, events []fswatch.Event) {
+	triggerEvents := trigger.Events
+	if len(triggerEvents) == 0 {
+		triggerEvents = []string{types.TriggerEventCreate}
+	}
+
+	for _, event := range events {
+		var eventName, change string
+		switch event.Type {
+		case fswatch.EventCreate:
+			eventName, change = types.TriggerEventCreate, "created"
+		case fswatch.EventWrite:
+			eventName, change = types.TriggerEventWrite, "changed"
+		case fswatch.EventDelete:
+			eventName, change = types.TriggerEventDelete, "deleted"
+		}
+		if !slices.Contains(triggerEvents, eventName) {
+			continue
+		}
+		if matched, _ := path.Match(cmp.Or(trigger.Pattern, "*"), path.Base(filepath.ToSlash(event.Path))); !matched {
+			continue
+		}
+
+		file := filepath.Join(trigger.Watch, event.Path)
+		// New directories are reported as created too.
+		if info, err := os.Stat(file); err == nil && info.IsDir() {
+			continue
+		}
+
+		message := fmt.Sprintf("The file %s was %s.", filepath.ToSlash(file), change)
+		if _, err := s.startTrigger(name, trigger, message); err != nil {
+			slog.Error("trigger: failed to run", "trigger", name, "file", file, "error", err)
+		}
+	}
+}
+
+// startTrigger starts a run of the agent of the trigger with the message
+// after the prompt of the trigger.
+func (s *Server) startTrigger(name string, trigger types.Trigger, message string) (string, error) {
+	if trigger.Prompt != "" {
+		message = trigger.Prompt + "\n\n" + message
+	}
+	return s.startRun(s.ctx, run{
+		name:   name,
+		agent:  trigger.Agent,
+		prompt: message,
+	})
+}
+
+func (s *Server) triggerSecret(trigger types.Trigger) string {
+	if trigger.Secret != "" {
+		return envvar.ReplaceString(s.env, trigger.Secret)
+	}
+	return s.env[mcp.WebhookSecretEnv]
+}
+
+// TriggerHandler serves the webhooks of the triggers. A signed payload
+// POSTed to /triggers/<name> starts a run with the payload as its first
+// message, and the response has the ID of the session of the run.
+func (s *Server) TriggerHandler() http.Handler {
+	return http.HandlerFunc(s.serveTrigger)
+}
+
+func (s *Server) serveTrigger(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		rw.Header().Set("Allow", http.MethodPost)
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(req.URL.Path, TriggerPathPrefix)
+	trigger, ok := s.triggers[name]
+	if !ok || !trigger.Webhook || trigger.Disabled {
+		http.NotFound(rw, req)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxTriggerPayload+1))
+	if err != nil {
+		http.Error(rw, "failed to read payload", http.StatusBadRequest)
+		return
+	} else if len(body) > maxTriggerPayload {
+		http.Error(rw, "payload too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	secret := s.triggerSecret(trigger)
+	if secret == "" {
+		http.Error(rw, "trigger has no secret", http.StatusForbidden)
+		return
+	}
+	timestamp, signature := req.Header.Get(mcp.WebhookTimestampHeader), req.Header.Get(mcp.WebhookSignatureHeader)
+	if err := mcp.VerifyWebhook(secret, timestamp, signature, body, time.Now()); err != nil {
+		http.Error(rw, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if !s.acceptDelivery(name, timestamp, signature, time.Now()) {
+		http.Error(rw, "webhook payload was already delivered", http.StatusConflict)
+		return
+	}
+
+	sessionID, err := s.startTrigger(name, trigger, payloadMessage(body))
+	if err != nil {
+		slog.Error("trigger: failed to run", "trigger", name, "error", err)
+		http.Error(rw, "failed to start run", http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(rw).Encode(map[string]string{"sessionId": sessionID})
+}
+
+// acceptDelivery records the signature of a verified webhook payload of a
+// trigger, and returns false if it was already accepted, so that a payload
+// can't be replayed while its timestamp is still accepted.
+func (s *Server) acceptDelivery(name, timestamp, signature string, now time.Time) bool {
+	ts, _ := strconv.ParseInt(timestamp, 10, 64)
+	key := name + "/" + signature
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, expires := range s.deliveries {
+		if now.After(expires) {
+			delete(s.deliveries, key)
+		}
+	}
+	if _, ok := s.deliveries[key]; ok {
+		return false
+	}
+	if s.deliveries == nil {
+		s.deliveries = map[string]time.Time{}
+	}
+	s.deliveries[key] = time.Unix(ts, 0).Add(mcp.WebhookMaxAge)
+	return true
+}
+
+// payloadMessage is the message of a webhook payload, indented JSON in a code
+// block or else the text of the payload.
+func payloadMessage(body []byte) string {
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, body, "", "  "); err == nil {
+		return "```json\n" + indented.String() + "\n```"
+	}
+	return string(body)
+}
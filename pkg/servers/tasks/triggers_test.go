@@ -0,0 +1,75 @@
+package tasks
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+func TestServeTriggerRejects(t *testing.T) {
+	srv := testServer(t)
+	srv.env = map[string]string{"HOOK_SECRET": "s3cret"}
+	srv.triggers = map[string]types.Trigger{
+		"github":    {Webhook: true, Secret: "${HOOK_SECRET}"},
+		"unsigned":  {Webhook: true},
+		"watchOnly": {Watch: "drops"},
+	}
+
+	body := `{"action":"opened"}`
+	timestamp := time.Now().Unix()
+	for _, tt := range []struct {
+		name, method, path, signature string
+		status                        int
+	}{
+		{"unknown trigger", http.MethodPost, "/triggers/missing", "", http.StatusNotFound},
+		{"no webhook", http.MethodPost, "/triggers/watchOnly", "", http.StatusNotFound},
+		{"method", http.MethodGet, "/triggers/github", "", http.StatusMethodNotAllowed},
+		{"no secret", http.MethodPost, "/triggers/unsigned", mcp.SignWebhook("", timestamp, []byte(body)), http.StatusForbidden},
+		{"bad signature", http.MethodPost, "/triggers/github", mcp.SignWebhook("other", timestamp, []byte(body)), http.StatusUnauthorized},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(body))
+			req.Header.Set(mcp.WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+			req.Header.Set(mcp.WebhookSignatureHeader, tt.signature)
+			rec := httptest.NewRecorder()
+			srv.TriggerHandler().ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+		})
+	}
+}
+
+func TestAcceptDeliveryRejectsReplays(t *testing.T) {
+	srv := testServer(t)
+	now := time.Now()
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	if !srv.acceptDelivery("github", timestamp, "sig", now) {
+		t.Fatal("expected the first delivery to be accepted")
+	}
+	if srv.acceptDelivery("github", timestamp, "sig", now.Add(time.Minute)) {
+		t.Error("expected a replayed delivery to be rejected")
+	}
+	if !srv.acceptDelivery("other", timestamp, "sig", now) {
+		t.Error("expected the same signature to be accepted for another trigger")
+	}
+	if !srv.acceptDelivery("github", timestamp, "sig", now.Add(mcp.WebhookMaxAge+time.Second)) {
+		t.Error("expected the delivery to be forgotten once its timestamp is too old")
+	}
+}
+
+func TestPayloadMessage(t *testing.T) {
+	if got, want := payloadMessage([]byte(`{"file":"a.pdf"}`)), "```json\n{\n  \"file\": \"a.pdf\"\n}\n```"; got != want {
+		t.Errorf("payloadMessage = %q, want %q", got, want)
+	}
+	if got := payloadMessage([]byte("new file a.pdf")); got != "new file a.pdf" {
+		t.Errorf("payloadMessage = %q", got)
+	}
+}
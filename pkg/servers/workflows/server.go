@@ -3,12 +3,14 @@ package workflows
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"mime"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/obot-platform/nanobot/pkg/fileuri"
 	"github.com/obot-platform/nanobot/pkg/fswatch"
@@ -16,6 +18,7 @@ import (
 	"github.com/obot-platform/nanobot/pkg/skillformat"
 	"github.com/obot-platform/nanobot/pkg/types"
 	"github.com/obot-platform/nanobot/pkg/version"
+	"github.com/obot-platform/nanobot/pkg/workflow"
 	"log/slog"
 )
 
@@ -24,11 +27,13 @@ type Server struct {
 	subscriptions  *fswatch.SubscriptionManager
 	watcherOnce    sync.Once
 	watcherInitErr error
+	engine         *workflow.Engine
 }
 
-func NewServer() *Server {
+func NewServer(engine *workflow.Engine) *Server {
 	return &Server{
 		subscriptions: fswatch.NewSubscriptionManager(context.Background()),
+		engine:        engine,
 	}
 }
 
@@ -65,6 +70,7 @@ func (s *Server) initialize(ctx context.Context, msg mcp.Message, params mcp.Ini
 	// Track this session for sending list_changed notifications
 	sessionID, _ := types.GetSessionAndAccountID(ctx)
 	s.subscriptions.AddSession(sessionID, msg.Session.Root())
+	s.engine.AddSession(sessionID, msg.Session.Root())
 
 	// Start watcher when first session initializes
 	if err := s.ensureWatcher(); err != nil {
@@ -86,7 +92,9 @@ func (s *Server) initialize(ctx context.Context, msg mcp.Message, params mcp.Ini
 	}, nil
 }
 
-// parseWorkflowURI extracts the workflow name from a workflow:///name URI
+// parseWorkflowURI extracts the workflow name from a workflow:///name URI. Names
+// of workflows in categories have the category in front, e.g.
+// workflow:///ops/deploy/rollback.
 func parseWorkflowURI(uri string) (string, error) {
 	if !strings.HasPrefix(uri, "workflow:///") {
 		return "", mcp.ErrRPCInvalidParams.WithMessage("invalid workflow URI format, expected workflow:///name")
@@ -99,98 +107,146 @@ func parseWorkflowURI(uri string) (string, error) {
 
 	// Remove .md extension if present (we'll add it back when needed)
 	workflowName = strings.TrimSuffix(workflowName, ".md")
+	if !skillformat.ValidWorkflowName(workflowName) {
+		return "", mcp.ErrRPCInvalidParams.WithMessage("invalid workflow name: %s", workflowName)
+	}
 
 	return workflowName, nil
 }
 
-func (s *Server) resourcesList(ctx context.Context, msg mcp.Message, _ mcp.ListResourcesRequest) (*mcp.ListResourcesResult, error) {
+func (s *Server) resourcesList(ctx context.Context, msg mcp.Message, request mcp.ListResourcesRequest) (*mcp.ListResourcesResult, error) {
 	workflowsPath := filepath.Join(".", skillformat.WorkflowsDir)
+	category, _ := request.Meta[types.WorkflowCategoryMetaKey].(string)
 
-	entries, err := os.ReadDir(workflowsPath)
-	if err != nil {
+	if _, err := os.Stat(workflowsPath); err != nil {
 		// Directory doesn't exist or can't be read - return empty list
 		return &mcp.ListResourcesResult{Resources: []mcp.Resource{}}, nil
 	}
 
 	var result []mcp.Resource
-	for _, entry := range entries {
-		if !entry.IsDir() {
+	for _, found := range skillformat.FindWorkflows(workflowsPath) {
+		if !skillformat.InCategory(found.Category, category) {
 			continue
 		}
 
-		name := entry.Name()
-		workflowDir := filepath.Join(workflowsPath, name)
-
 		// Read the main workflow file from the subdirectory
-		contentBytes, err := os.ReadFile(filepath.Join(workflowDir, skillformat.SkillMainFile))
-		if err == nil {
-			fm, _, err := skillformat.ParseFrontmatter(string(contentBytes))
-			if err != nil {
-				slog.Debug("failed to parse frontmatter for workflow", "workflow", name, "error", err)
-			}
+		contentBytes, err := os.ReadFile(filepath.Join(found.Dir, skillformat.SkillMainFile))
+		if err != nil {
+			continue
+		}
 
-			resourceMeta := skillformat.FrontmatterToMeta(fm)
+		fm, _, err := skillformat.ParseFrontmatter(string(contentBytes))
+		if err != nil {
+			slog.Debug("failed to parse frontmatter for workflow", "workflow", found.Name, "error", err)
+		}
 
-			res := mcp.Resource{
-				URI:         fmt.Sprintf("workflow:///%s", name),
-				Name:        name,
-				Description: fm.Description,
-				MimeType:    "text/markdown",
-			}
-			if len(resourceMeta) > 0 {
-				res.Meta = resourceMeta
-			}
+		resourceMeta := skillformat.FrontmatterToMeta(fm)
+		if found.Category != "" {
+			resourceMeta["category"] = found.Category
+		}
+		if _, err := os.Stat(filepath.Join(found.Dir, workflow.DefinitionFile)); err == nil {
+			resourceMeta["executable"] = true
+		}
 
-			result = append(result, res)
+		res := mcp.Resource{
+			URI:         fmt.Sprintf("workflow:///%s", found.Name),
+			Name:        found.Name,
+			Description: fm.Description,
+			MimeType:    "text/markdown",
+		}
+		if len(resourceMeta) > 0 {
+			res.Meta = resourceMeta
 		}
 
-		// List supporting files in the workflow directory (even if SKILL.md doesn't exist yet)
-		_ = filepath.WalkDir(workflowDir, func(path string, d os.DirEntry, walkErr error) error {
-			if walkErr != nil || d.IsDir() {
-				return nil
-			}
-			if filepath.Base(path) == skillformat.SkillMainFile {
-				return nil
-			}
-			relPath, err := filepath.Rel(".", path)
-			if err != nil {
-				return nil
-			}
-			info, err := d.Info()
-			if err != nil {
-				return nil
-			}
-			mimeType := mime.TypeByExtension(filepath.Ext(relPath))
-			if mimeType == "" {
-				mimeType = "application/octet-stream"
-			}
-			result = append(result, mcp.Resource{
-				URI:      fileuri.Encode(relPath),
-				Name:     filepath.Base(relPath),
-				MimeType: mimeType,
-				Size:     info.Size(),
-				Annotations: &mcp.Annotations{
-					LastModified: info.ModTime(),
-				},
-			})
+		result = append(result, res)
+	}
+
+	// List supporting files in the workflow directories (even if SKILL.md doesn't exist yet)
+	_ = filepath.WalkDir(workflowsPath, func(path string, d os.DirEntry, walkErr error) error {
+		if walkErr != nil || d.IsDir() {
+			return nil
+		}
+		if filepath.Base(path) == skillformat.SkillMainFile {
+			return nil
+		}
+		dir, err := filepath.Rel(workflowsPath, filepath.Dir(path))
+		if err != nil || dir == "." || !skillformat.InCategory(filepath.ToSlash(dir), category) {
+			return nil
+		}
+		relPath, err := filepath.Rel(".", path)
+		if err != nil {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
 			return nil
+		}
+		mimeType := mime.TypeByExtension(filepath.Ext(relPath))
+		if mimeType == "" {
+			mimeType = "application/octet-stream"
+		}
+		result = append(result, mcp.Resource{
+			URI:      fileuri.Encode(relPath),
+			Name:     filepath.Base(relPath),
+			MimeType: mimeType,
+			Size:     info.Size(),
+			Annotations: &mcp.Annotations{
+				LastModified: info.ModTime(),
+			},
 		})
+		return nil
+	})
+
+	if category == "" {
+		result = append(result, s.runResources(ctx)...)
 	}
 
 	return &mcp.ListResourcesResult{Resources: result}, nil
 }
 
+// runResources returns the workflow runs of the session as resources. They
+// are updated with every step of the run.
+func (s *Server) runResources(ctx context.Context) []mcp.Resource {
+	runs, err := s.engine.List(ctx)
+	if err != nil {
+		slog.Debug("failed to list workflow runs", "error", err)
+		return nil
+	}
+
+	resources := make([]mcp.Resource, 0, len(runs))
+	for _, run := range runs {
+		resources = append(resources, mcp.Resource{
+			URI:         run.URI,
+			Name:        run.ID,
+			Description: fmt.Sprintf("Run of %s", run.WorkflowURI),
+			MimeType:    "application/json",
+			Annotations: &mcp.Annotations{LastModified: run.Updated},
+			Meta: map[string]any{
+				types.MetaPrefix + "workflowRun": map[string]any{
+					"workflowURI": run.WorkflowURI,
+					"status":      run.Status,
+					"createdAt":   run.Created.Format(time.RFC3339),
+				},
+			},
+		})
+	}
+	return resources
+}
+
 func (s *Server) resourcesRead(ctx context.Context, _ mcp.Message, request mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
 	if strings.HasPrefix(request.URI, "file:///") {
 		return s.readWorkflowFile(request.URI)
 	}
+	if strings.HasPrefix(request.URI, workflow.RunURIPrefix) {
+		return s.readRun(ctx, request.URI)
+	}
 
 	workflowName, err := parseWorkflowURI(request.URI)
 	if err != nil {
 		return nil, err
 	}
 
-	workflowPath := filepath.Join(".", skillformat.WorkflowsDir, workflowName, skillformat.SkillMainFile)
+	workflowPath := filepath.Join(".", skillformat.WorkflowsDir, filepath.FromSlash(workflowName), skillformat.SkillMainFile)
 	contentBytes, err := os.ReadFile(workflowPath)
 	if err != nil {
 		return nil, mcp.ErrRPCInvalidParams.WithMessage("workflow not found: %s", request.URI)
@@ -203,6 +259,9 @@ func (s *Server) resourcesRead(ctx context.Context, _ mcp.Message, request mcp.R
 	}
 
 	resourceMeta := skillformat.FrontmatterToMeta(fm)
+	if category := skillformat.WorkflowCategory(workflowName); category != "" {
+		resourceMeta["category"] = category
+	}
 
 	rc := mcp.ResourceContent{
 		URI:      request.URI,
@@ -219,6 +278,26 @@ func (s *Server) resourcesRead(ctx context.Context, _ mcp.Message, request mcp.R
 	}, nil
 }
 
+// readRun reads the state of a workflow run.
+func (s *Server) readRun(ctx context.Context, uri string) (*mcp.ReadResourceResult, error) {
+	run, err := s.engine.Get(ctx, uri)
+	if err != nil {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("%v", err)
+	}
+	data, err := json.Marshal(run)
+	if err != nil {
+		return nil, err
+	}
+	return &mcp.ReadResourceResult{
+		Contents: []mcp.ResourceContent{{
+			URI:      uri,
+			Name:     run.ID,
+			MIMEType: "application/json",
+			Text:     new(string(data)),
+		}},
+	}, nil
+}
+
 // readWorkflowFile reads a supporting file from a workflow directory.
 func (s *Server) readWorkflowFile(uri string) (*mcp.ReadResourceResult, error) {
 	relPath, err := fileuri.Decode(uri)
@@ -274,6 +353,15 @@ func (s *Server) readWorkflowFile(uri string) (*mcp.ReadResourceResult, error) {
 }
 
 func (s *Server) resourcesSubscribe(ctx context.Context, msg mcp.Message, request mcp.SubscribeRequest) (*mcp.SubscribeResult, error) {
+	if strings.HasPrefix(request.URI, workflow.RunURIPrefix) {
+		if _, err := s.engine.Get(ctx, request.URI); err != nil {
+			return nil, mcp.ErrRPCInvalidParams.WithMessage("%v", err)
+		}
+		sessionID, _ := types.GetSessionAndAccountID(ctx)
+		s.engine.Subscribe(sessionID, msg.Session.Root(), request.URI)
+		return &mcp.SubscribeResult{}, nil
+	}
+
 	if strings.HasPrefix(request.URI, "file:///") {
 		relPath, err := fileuri.Decode(request.URI)
 		if err != nil {
@@ -291,7 +379,7 @@ func (s *Server) resourcesSubscribe(ctx context.Context, msg mcp.Message, reques
 		if err != nil {
 			return nil, err
 		}
-		workflowPath := filepath.Join(".", skillformat.WorkflowsDir, workflowName, skillformat.SkillMainFile)
+		workflowPath := filepath.Join(".", skillformat.WorkflowsDir, filepath.FromSlash(workflowName), skillformat.SkillMainFile)
 		if _, err := os.Stat(workflowPath); os.IsNotExist(err) {
 			return nil, mcp.ErrRPCInvalidParams.WithMessage("workflow not found: %s", request.URI)
 		}
@@ -304,7 +392,11 @@ func (s *Server) resourcesSubscribe(ctx context.Context, msg mcp.Message, reques
 
 func (s *Server) resourcesUnsubscribe(ctx context.Context, msg mcp.Message, request mcp.UnsubscribeRequest) (*mcp.UnsubscribeResult, error) {
 	sessionID, _ := types.GetSessionAndAccountID(ctx)
-	s.subscriptions.Unsubscribe(sessionID, request.URI)
+	if strings.HasPrefix(request.URI, workflow.RunURIPrefix) {
+		s.engine.Unsubscribe(sessionID, request.URI)
+	} else {
+		s.subscriptions.Unsubscribe(sessionID, request.URI)
+	}
 	return &mcp.UnsubscribeResult{}, nil
 }
 
@@ -319,8 +411,9 @@ func (s *Server) ensureWatcher() error {
 			return
 		}
 
-		// Depth 3 to watch nested files like <workflow>/scripts/analyze.py
-		s.watcher = fswatch.NewWatcher(workflowsPath, 3, nil, s.handleFileEvents)
+		// Watch the whole tree, workflows can be nested in category folders
+		// and have nested files like <workflow>/scripts/analyze.py
+		s.watcher = fswatch.NewWatcher(workflowsPath, skillformat.MaxWorkflowDepth, nil, s.handleFileEvents)
 		if err := s.watcher.Start(); err != nil {
 			s.watcherInitErr = err
 			return
@@ -335,21 +428,21 @@ func (s *Server) ensureWatcher() error {
 // handleFileEvents processes filesystem events from the watcher
 func (s *Server) handleFileEvents(events []fswatch.Event) {
 	for _, event := range events {
-		// Event paths are relative to the workflows dir, e.g. "code-review/SKILL.md"
-		// or "code-review/scripts/analyze.py"
-		parts := strings.SplitN(event.Path, string(filepath.Separator), 2)
-		workflowName := parts[0]
-		workflowURI := fmt.Sprintf("workflow:///%s", workflowName)
+		// Event paths are relative to the workflows dir, e.g. "code-review/SKILL.md",
+		// "ops/deploy/SKILL.md" or "code-review/scripts/analyze.py"
+		dir := filepath.Dir(event.Path)
+		isNested := dir != "."
+		workflowURI := fmt.Sprintf("workflow:///%s", filepath.ToSlash(dir))
 
 		// Determine if this is the main workflow file or a supporting file
-		isMainFile := len(parts) == 2 && parts[1] == skillformat.SkillMainFile
+		isMainFile := isNested && filepath.Base(event.Path) == skillformat.SkillMainFile
 
 		switch event.Type {
 		case fswatch.EventDelete:
 			if isMainFile {
 				s.subscriptions.SendResourceUpdatedNotification(workflowURI)
 				s.subscriptions.AutoUnsubscribe(workflowURI)
-			} else if len(parts) == 2 {
+			} else if isNested {
 				fileURI := fileuri.Encode(filepath.Join(skillformat.WorkflowsDir, event.Path))
 				s.subscriptions.SendResourceUpdatedNotification(fileURI)
 				s.subscriptions.AutoUnsubscribe(fileURI)
@@ -362,7 +455,7 @@ func (s *Server) handleFileEvents(events []fswatch.Event) {
 		case fswatch.EventWrite:
 			if isMainFile {
 				s.subscriptions.SendResourceUpdatedNotification(workflowURI)
-			} else if len(parts) == 2 {
+			} else if isNested {
 				fileURI := fileuri.Encode(filepath.Join(skillformat.WorkflowsDir, event.Path))
 				s.subscriptions.SendResourceUpdatedNotification(fileURI)
 			}
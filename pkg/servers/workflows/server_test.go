@@ -5,10 +5,13 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"testing"
 
 	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+	"github.com/obot-platform/nanobot/pkg/workflow"
 )
 
 // testdataDir returns the absolute path to the testdata directory
@@ -42,7 +45,7 @@ func TestResourcesList(t *testing.T) {
 	restore := withWorkingDir(t, testdataDir(t, "with-workflows"))
 	defer restore()
 
-	server := NewServer()
+	server := NewServer(workflow.NewEngine(nil, nil))
 	ctx := context.Background()
 
 	result, err := server.resourcesList(ctx, mcp.Message{}, mcp.ListResourcesRequest{})
@@ -145,7 +148,7 @@ func TestResourcesListMissingDirectory(t *testing.T) {
 	restore := withWorkingDir(t, tempDir)
 	defer restore()
 
-	server := NewServer()
+	server := NewServer(workflow.NewEngine(nil, nil))
 	ctx := context.Background()
 
 	result, err := server.resourcesList(ctx, mcp.Message{}, mcp.ListResourcesRequest{})
@@ -166,7 +169,7 @@ func TestResourcesListEmptyDirectory(t *testing.T) {
 	restore := withWorkingDir(t, testdataDir(t, "empty"))
 	defer restore()
 
-	server := NewServer()
+	server := NewServer(workflow.NewEngine(nil, nil))
 	ctx := context.Background()
 
 	result, err := server.resourcesList(ctx, mcp.Message{}, mcp.ListResourcesRequest{})
@@ -187,7 +190,7 @@ func TestResourcesRead(t *testing.T) {
 	restore := withWorkingDir(t, testdataDir(t, "with-workflows"))
 	defer restore()
 
-	server := NewServer()
+	server := NewServer(workflow.NewEngine(nil, nil))
 	ctx := context.Background()
 
 	tests := []struct {
@@ -293,7 +296,7 @@ func TestResourcesListSupportingFilesWithoutSkillMD(t *testing.T) {
 	restore := withWorkingDir(t, testdataDir(t, "with-workflows"))
 	defer restore()
 
-	server := NewServer()
+	server := NewServer(workflow.NewEngine(nil, nil))
 	ctx := context.Background()
 
 	result, err := server.resourcesList(ctx, mcp.Message{}, mcp.ListResourcesRequest{})
@@ -326,7 +329,7 @@ func TestResourcesReadSupportingFileWithoutSkillMD(t *testing.T) {
 	restore := withWorkingDir(t, testdataDir(t, "with-workflows"))
 	defer restore()
 
-	server := NewServer()
+	server := NewServer(workflow.NewEngine(nil, nil))
 	ctx := context.Background()
 
 	// Read the supporting file directly via file:/// URI
@@ -342,3 +345,81 @@ func TestResourcesReadSupportingFileWithoutSkillMD(t *testing.T) {
 		t.Error("expected non-empty text content")
 	}
 }
+
+func TestResourcesListNestedCategories(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{
+		"workflows/top/SKILL.md",
+		"workflows/ops/deploy/rollback/SKILL.md",
+		"workflows/ops/deploy/rollback/scripts/run.sh",
+		"workflows/ops/triage/SKILL.md",
+		"workflows/sales/report/SKILL.md",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("---\nname: "+filepath.Base(filepath.Dir(path))+"\n---\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	restore := withWorkingDir(t, dir)
+	defer restore()
+
+	tests := []struct {
+		category string
+		want     []string
+	}{
+		{category: "", want: []string{
+			"file:///workflows/ops/deploy/rollback/scripts/run.sh",
+			"workflow:///ops/deploy/rollback",
+			"workflow:///ops/triage",
+			"workflow:///sales/report",
+			"workflow:///top",
+		}},
+		{category: "ops", want: []string{
+			"file:///workflows/ops/deploy/rollback/scripts/run.sh",
+			"workflow:///ops/deploy/rollback",
+			"workflow:///ops/triage",
+		}},
+		{category: "ops/deploy/", want: []string{
+			"file:///workflows/ops/deploy/rollback/scripts/run.sh",
+			"workflow:///ops/deploy/rollback",
+		}},
+		{category: "op", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.category, func(t *testing.T) {
+			result, err := NewServer(workflow.NewEngine(nil, nil)).resourcesList(t.Context(), mcp.Message{}, mcp.ListResourcesRequest{
+				Meta: map[string]any{types.WorkflowCategoryMetaKey: tt.category},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var uris []string
+			for _, res := range result.Resources {
+				uris = append(uris, res.URI)
+				if res.URI == "workflow:///ops/deploy/rollback" && res.Meta["category"] != "ops/deploy" {
+					t.Errorf("category of %s = %v, want ops/deploy", res.URI, res.Meta["category"])
+				}
+			}
+			slices.Sort(uris)
+			if !slices.Equal(uris, tt.want) {
+				t.Errorf("resources = %v, want %v", uris, tt.want)
+			}
+		})
+	}
+
+	result, err := NewServer(workflow.NewEngine(nil, nil)).resourcesRead(t.Context(), mcp.Message{}, mcp.ReadResourceRequest{URI: "workflow:///ops/deploy/rollback"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := result.Contents[0].Meta["category"]; got != "ops/deploy" {
+		t.Errorf("read category = %v, want ops/deploy", got)
+	}
+
+	if _, err := parseWorkflowURI("workflow:///ops/../../secrets"); err == nil {
+		t.Error("expected an error for a workflow name that leaves the workflows directory")
+	}
+}
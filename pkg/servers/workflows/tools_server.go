@@ -1,25 +1,44 @@
 package workflows
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 
 	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/servers/agent"
 	"github.com/obot-platform/nanobot/pkg/session"
 	"github.com/obot-platform/nanobot/pkg/skillformat"
 	"github.com/obot-platform/nanobot/pkg/version"
+	"github.com/obot-platform/nanobot/pkg/workflow"
 )
 
 type ToolsServer struct {
-	tools mcp.ServerTools
+	tools  mcp.ServerTools
+	engine *workflow.Engine
+	// askApproval asks the user to approve the step a run is waiting for.
+	askApproval func(ctx context.Context, run *workflow.Run, message string) (mcp.ElicitResult, error)
 }
 
-func NewToolsServer() *ToolsServer {
-	s := &ToolsServer{}
+func NewToolsServer(engine *workflow.Engine) *ToolsServer {
+	s := &ToolsServer{
+		engine:      engine,
+		askApproval: defaultAskApproval,
+	}
 
 	s.tools = mcp.NewServerTools(
+		mcp.NewServerTool("listWorkflows", "List the workflows, optionally only those in a category. Executable workflows can be run with runWorkflow", s.listWorkflows),
+		mcp.NewServerTool("runWorkflow", `Run an executable workflow, one with a workflow.yaml next to its SKILL.md. The run continues in the background and its progress is the workflowrun:/// resource it returns.
+
+Runs stop at approval steps until the user approves them, call requestWorkflowApproval to ask the user.`, s.runWorkflow),
+		mcp.NewServerTool("getWorkflowRun", "Get the status and step outputs of a workflow run", s.getWorkflowRun),
+		mcp.NewServerTool("requestWorkflowApproval", "Ask the user to approve or reject the step a workflow run is waiting for. The run continues or fails as the user decides", s.requestWorkflowApproval),
+		mcp.NewServerTool("resumeWorkflowRun", "Resume a failed, cancelled, or interrupted workflow run from the step where it stopped", s.resumeWorkflowRun),
+		mcp.NewServerTool("cancelWorkflowRun", "Cancel a workflow run", s.cancelWorkflowRun),
 		mcp.NewServerTool("recordWorkflowRun", "Record that a workflow was executed in the current chat session", s.recordWorkflowRun),
 		mcp.NewServerTool("deleteWorkflow", "Delete a workflow by its URI", s.deleteWorkflow),
 	)
@@ -57,6 +76,47 @@ func (s *ToolsServer) initialize(ctx context.Context, _ mcp.Message, params mcp.
 	}, nil
 }
 
+type WorkflowList struct {
+	Workflows []Workflow `json:"workflows"`
+}
+
+type Workflow struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Category    string `json:"category,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Executable is set for workflows with a workflow.yaml that runWorkflow
+	// can run.
+	Executable bool `json:"executable,omitempty"`
+}
+
+func (s *ToolsServer) listWorkflows(_ context.Context, data struct {
+	Category string `json:"category,omitempty" jsonschema:"Only list workflows in this category folder and its subcategories, e.g. ops or ops/deploy"`
+}) (*WorkflowList, error) {
+	result := &WorkflowList{Workflows: []Workflow{}}
+	for _, found := range skillformat.FindWorkflows(filepath.Join(".", skillformat.WorkflowsDir)) {
+		if !skillformat.InCategory(found.Category, data.Category) {
+			continue
+		}
+
+		contentBytes, err := os.ReadFile(filepath.Join(found.Dir, skillformat.SkillMainFile))
+		if err != nil {
+			continue
+		}
+		fm, _, _ := skillformat.ParseFrontmatter(string(contentBytes))
+		_, err = os.Stat(filepath.Join(found.Dir, workflow.DefinitionFile))
+
+		result.Workflows = append(result.Workflows, Workflow{
+			URI:         fmt.Sprintf("workflow:///%s", found.Name),
+			Name:        found.Name,
+			Category:    found.Category,
+			Description: fm.Description,
+			Executable:  err == nil,
+		})
+	}
+	return result, nil
+}
+
 func (s *ToolsServer) recordWorkflowRun(ctx context.Context, data struct {
 	URI string `json:"uri"`
 }) (string, error) {
@@ -90,10 +150,126 @@ func (s *ToolsServer) deleteWorkflow(_ context.Context, data struct {
 		return "", fmt.Errorf("failed to parse workflow URI: %w", err)
 	}
 
-	workflowPath := filepath.Join(".", skillformat.WorkflowsDir, workflowName)
+	workflowPath := filepath.Join(".", skillformat.WorkflowsDir, filepath.FromSlash(workflowName))
 	if err := os.RemoveAll(workflowPath); err != nil {
 		return "", fmt.Errorf("failed to delete workflow: %w", err)
 	}
 
 	return fmt.Sprintf("%s deleted", data.URI), nil
 }
+
+func (s *ToolsServer) runWorkflow(ctx context.Context, data struct {
+	URI    string         `json:"uri" jsonschema:"The URI of the workflow, e.g. workflow:///ops/deploy"`
+	Inputs map[string]any `json:"inputs,omitempty" jsonschema:"The inputs of the workflow"`
+	Wait   bool           `json:"wait,omitempty" jsonschema:"Wait until the run completed, failed, or is waiting for approval instead of returning right away"`
+}) (*workflow.Run, error) {
+	workflowName, err := parseWorkflowURI(data.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	def, err := workflow.LoadDefinition(filepath.Join(".", skillformat.WorkflowsDir, filepath.FromSlash(workflowName)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("workflow %s is not executable, it has no %s", data.URI, workflow.DefinitionFile)
+	} else if err != nil {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("%v", err)
+	}
+
+	run, err := s.engine.Start(ctx, data.URI, *def, data.Inputs)
+	if err != nil {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("%v", err)
+	}
+	if !data.Wait {
+		return run, nil
+	}
+	return s.engine.Wait(ctx, run.ID)
+}
+
+type runParams struct {
+	RunID string `json:"runId" jsonschema:"The ID or workflowrun:/// URI of the run"`
+}
+
+func (s *ToolsServer) getWorkflowRun(ctx context.Context, data runParams) (*workflow.Run, error) {
+	return runResult(s.engine.Get(ctx, data.RunID))
+}
+
+// requestWorkflowApproval asks the user to decide the approval step a run is
+// waiting for. The decision comes from the user through an elicitation, never
+// from the model, so a model can't approve steps on its own. The run keeps
+// waiting when the user cancels.
+func (s *ToolsServer) requestWorkflowApproval(ctx context.Context, data runParams) (*workflow.Run, error) {
+	run, err := runResult(s.engine.Get(ctx, data.RunID))
+	if err != nil {
+		return nil, err
+	}
+	if run.Status != workflow.RunWaiting {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("workflow run %s is %s, not waiting for approval", data.RunID, run.Status)
+	}
+
+	var message string
+	for _, step := range run.Steps {
+		if step.Status == workflow.StepWaiting {
+			output, _ := step.Output.(map[string]any)
+			message, _ = output["message"].(string)
+			message = cmp.Or(message, fmt.Sprintf("Approve step %s?", step.ID))
+		}
+	}
+
+	result, err := s.askApproval(ctx, run, message)
+	if err != nil {
+		return nil, err
+	}
+	comment, _ := result.Content["comment"].(string)
+	switch result.Action {
+	case "accept":
+		return runResult(s.engine.Approve(ctx, run.ID, true, comment))
+	case "decline":
+		return runResult(s.engine.Approve(ctx, run.ID, false, comment))
+	default:
+		return run, nil
+	}
+}
+
+func defaultAskApproval(ctx context.Context, run *workflow.Run, message string) (mcp.ElicitResult, error) {
+	session := mcp.SessionFromContext(ctx)
+	if session == nil {
+		return mcp.ElicitResult{}, fmt.Errorf("no session found in context")
+	}
+
+	elicit := mcp.ElicitRequest{
+		Message: fmt.Sprintf("Workflow %s is waiting for your approval: %s", run.WorkflowURI, message),
+		RequestedSchema: mcp.PrimitiveSchema{
+			Type: "object",
+			Properties: map[string]mcp.PrimitiveProperty{
+				"comment": {
+					Type:        "string",
+					Title:       "Comment",
+					Description: "Anything to add about the decision",
+				},
+			},
+		},
+	}
+
+	var result mcp.ElicitResult
+	if err := agent.ExchangeElicitation(ctx, session, elicit, &result); errors.Is(err, agent.ErrElicitationUnsupported) {
+		return mcp.ElicitResult{}, mcp.ErrRPCInvalidRequest.WithMessage("the client can't ask the user to approve workflow run %s", run.ID)
+	} else if err != nil {
+		return mcp.ElicitResult{}, fmt.Errorf("failed to ask for approval: %w", err)
+	}
+	return result, nil
+}
+
+func (s *ToolsServer) resumeWorkflowRun(ctx context.Context, data runParams) (*workflow.Run, error) {
+	return runResult(s.engine.Resume(ctx, data.RunID))
+}
+
+func (s *ToolsServer) cancelWorkflowRun(ctx context.Context, data runParams) (*workflow.Run, error) {
+	return runResult(s.engine.Cancel(ctx, data.RunID))
+}
+
+func runResult(run *workflow.Run, err error) (*workflow.Run, error) {
+	if err != nil {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("%v", err)
+	}
+	return run, nil
+}
@@ -11,10 +11,11 @@ import (
 	"github.com/obot-platform/nanobot/pkg/mcp"
 	"github.com/obot-platform/nanobot/pkg/session"
 	"github.com/obot-platform/nanobot/pkg/skillformat"
+	"github.com/obot-platform/nanobot/pkg/workflow"
 )
 
 func TestRecordWorkflowRun_DeduplicatesURI(t *testing.T) {
-	s := NewToolsServer()
+	s := NewToolsServer(workflow.NewEngine(nil, nil))
 	ctx := t.Context()
 	store, err := session.NewStoreFromDSN("sqlite::memory:")
 	if err != nil {
@@ -80,7 +81,7 @@ func TestDeleteWorkflow_RemovesDirectory(t *testing.T) {
 		t.Fatalf("failed to write workflow file: %v", err)
 	}
 
-	s := NewToolsServer()
+	s := NewToolsServer(workflow.NewEngine(nil, nil))
 	if _, err := s.deleteWorkflow(t.Context(), struct {
 		URI string `json:"uri"`
 	}{URI: "workflow:///to-delete"}); err != nil {
@@ -91,3 +92,61 @@ func TestDeleteWorkflow_RemovesDirectory(t *testing.T) {
 		t.Fatalf("expected workflow directory to be deleted, stat err: %v", err)
 	}
 }
+
+func TestRequestWorkflowApproval_AsksUser(t *testing.T) {
+	def, err := workflow.ParseDefinition([]byte(`
+steps:
+- id: approve
+  approval:
+    message: Deploy?
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := session.NewStoreFromDSN("sqlite::memory:")
+	if err != nil {
+		t.Fatalf("failed to create session store: %v", err)
+	}
+	serverSession, err := mcp.NewExistingServerSession(t.Context(), mcp.SessionState{ID: "test-session"}, mcp.MessageHandlerFunc(func(context.Context, mcp.Message) {}))
+	if err != nil {
+		t.Fatalf("failed to create test server session: %v", err)
+	}
+	defer serverSession.Close(false)
+	serverSession.GetSession().Set(session.ManagerSessionKey, session.NewManager(store))
+	ctx := mcp.WithSession(t.Context(), serverSession.GetSession())
+
+	engine := workflow.NewEngine(nil, nil)
+	s := NewToolsServer(engine)
+	run, err := engine.Start(ctx, "workflow:///deploy", *def, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.Wait(ctx, run.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	var asked string
+	s.askApproval = func(_ context.Context, _ *workflow.Run, message string) (mcp.ElicitResult, error) {
+		asked = message
+		return mcp.ElicitResult{Action: "cancel"}, nil
+	}
+	result, err := s.requestWorkflowApproval(ctx, runParams{RunID: run.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if asked != "Deploy?" || result.Status != workflow.RunWaiting {
+		t.Fatalf("expected a cancelled approval to keep the run waiting, asked %q, got %s", asked, result.Status)
+	}
+
+	s.askApproval = func(context.Context, *workflow.Run, string) (mcp.ElicitResult, error) {
+		return mcp.ElicitResult{Action: "decline", Content: map[string]any{"comment": "not today"}}, nil
+	}
+	result, err = s.requestWorkflowApproval(ctx, runParams{RunID: run.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Status != workflow.RunFailed || result.Error != "step approve: rejected: not today" {
+		t.Fatalf("expected the declined run to fail, got %s: %s", result.Status, result.Error)
+	}
+}
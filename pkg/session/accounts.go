@@ -0,0 +1,122 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+	"gorm.io/gorm"
+)
+
+// Account is what admins set on an account. Accounts exist from their first
+// session on, their record is only stored once an admin changes them.
+type Account struct {
+	AccountID string                `json:"accountId" gorm:"primaryKey"`
+	CreatedAt time.Time             `json:"createdAt,omitzero"`
+	UpdatedAt time.Time             `json:"updatedAt,omitzero"`
+	Disabled  bool                  `json:"disabled,omitempty"`
+	Settings  types.AccountSettings `json:"settings,omitzero" gorm:"type:json;serializer:json"`
+}
+
+// AccountSummary is an account with how many sessions it has.
+type AccountSummary struct {
+	Account
+	Sessions     int        `json:"sessions"`
+	LastActiveAt *time.Time `json:"lastActiveAt,omitempty"`
+}
+
+// GetAccount returns the account, with its defaults if no admin changed it.
+func (s *Store) GetAccount(ctx context.Context, accountID string) (*Account, error) {
+	account := Account{AccountID: accountID}
+	err := s.withContext(ctx).Where("account_id = ?", accountID).First(&account).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &account, nil
+	}
+	return &account, err
+}
+
+// SaveAccount stores the account, creating its record if needed.
+func (s *Store) SaveAccount(ctx context.Context, account *Account) error {
+	return s.withContext(ctx).Save(account).Error
+}
+
+// ListAccounts returns the accounts that have sessions or a record, by ID.
+func (s *Store) ListAccounts(ctx context.Context) ([]AccountSummary, error) {
+	var records []Account
+	if err := s.withContext(ctx).Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	var sessions []Session
+	if err := s.withContext(ctx).Select("account_id", "updated_at").Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+
+	summaries := map[string]*AccountSummary{}
+	for _, record := range records {
+		summaries[record.AccountID] = &AccountSummary{Account: record}
+	}
+	for _, session := range sessions {
+		summary, ok := summaries[session.AccountID]
+		if !ok {
+			summary = &AccountSummary{Account: Account{AccountID: session.AccountID}}
+			summaries[session.AccountID] = summary
+		}
+		summary.Sessions++
+		if summary.LastActiveAt == nil || session.UpdatedAt.After(*summary.LastActiveAt) {
+			summary.LastActiveAt = &session.UpdatedAt
+		}
+	}
+
+	result := make([]AccountSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		result = append(result, *summary)
+	}
+	slices.SortFunc(result, func(a, b AccountSummary) int {
+		return strings.Compare(a.AccountID, b.AccountID)
+	})
+	return result, nil
+}
+
+// ListAccountSessions returns the sessions of the account, most recently
+// updated first.
+func (s *Store) ListAccountSessions(ctx context.Context, accountID string) ([]Session, error) {
+	var sessions []Session
+	err := s.withContext(ctx).Where("account_id = ?", accountID).
+		Order("updated_at desc").Find(&sessions).Error
+	return sessions, err
+}
+
+// AccountUsage adds up the token usage of the sessions of the account.
+func (s *Store) AccountUsage(ctx context.Context, accountID string) (types.SessionUsage, error) {
+	var total types.SessionUsage
+
+	sessions, err := s.ListAccountSessions(ctx, accountID)
+	if err != nil {
+		return total, err
+	}
+
+	for _, session := range sessions {
+		data, ok := session.State.Attributes[types.UsageSessionKey]
+		if !ok {
+			continue
+		}
+		var usage types.SessionUsage
+		if err := mcp.JSONCoerce(data, &usage); err != nil {
+			return total, err
+		}
+		for model, modelUsage := range usage.Models {
+			total.Add(model, modelUsage)
+		}
+	}
+	return total, nil
+}
+
+// IsAdmin returns whether the account can manage accounts.
+func (m *Manager) IsAdmin(accountID string) bool {
+	return accountID != "" && slices.Contains(m.admins, accountID)
+}
@@ -0,0 +1,70 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+func TestAccounts(t *testing.T) {
+	store := newTestStore(t, "accounts")
+	ctx := t.Context()
+
+	for _, s := range []*Session{
+		{SessionID: "a1", AccountID: "alice", State: State{Attributes: map[string]any{
+			types.UsageSessionKey: map[string]any{"models": map[string]any{
+				"gpt-4.1": map[string]any{"inputTokens": 100, "outputTokens": 10, "completions": 1},
+			}},
+		}}},
+		{SessionID: "a2", AccountID: "alice", State: State{Attributes: map[string]any{
+			types.UsageSessionKey: map[string]any{"models": map[string]any{
+				"gpt-4.1": map[string]any{"inputTokens": 50, "outputTokens": 5, "completions": 1},
+			}},
+		}}},
+		{SessionID: "b1", AccountID: "bob"},
+	} {
+		if err := store.Create(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	account, err := store.GetAccount(ctx, "carol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	account.Disabled = true
+	account.Settings.AllowedAgents = []string{"support"}
+	if err := store.SaveAccount(ctx, account); err != nil {
+		t.Fatal(err)
+	}
+
+	accounts, err := store.ListAccounts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 3 {
+		t.Fatalf("expected 3 accounts, got %+v", accounts)
+	}
+	if accounts[0].AccountID != "alice" || accounts[0].Sessions != 2 || accounts[0].LastActiveAt == nil {
+		t.Fatalf("unexpected summary of alice %+v", accounts[0])
+	}
+	if accounts[2].AccountID != "carol" || !accounts[2].Disabled || accounts[2].Sessions != 0 {
+		t.Fatalf("unexpected summary of carol %+v", accounts[2])
+	}
+
+	stored, err := store.GetAccount(ctx, "carol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.Disabled || stored.Settings.AgentAllowed("coder") || !stored.Settings.AgentAllowed("support") {
+		t.Fatalf("unexpected stored account %+v", stored)
+	}
+
+	usage, err := store.AccountUsage(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total := usage.Models["gpt-4.1"]; total.InputTokens != 150 || total.OutputTokens != 15 || total.Completions != 2 {
+		t.Fatalf("unexpected usage %+v", usage)
+	}
+}
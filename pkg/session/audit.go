@@ -0,0 +1,109 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/mcp/auditlogs"
+)
+
+// defaultAuditLimit is the number of tool call records returned when the
+// query doesn't set a limit.
+const defaultAuditLimit = 100
+
+var _ auditlogs.ToolCallRecorder = (*Store)(nil)
+
+// ToolCallAudit is the audit record of a tool call. Records are only ever
+// appended, they are kept when their session is deleted.
+type ToolCallAudit struct {
+	ID            uint            `json:"id" gorm:"primarykey"`
+	CreatedAt     time.Time       `json:"createdAt" gorm:"index"`
+	SessionID     string          `json:"sessionId,omitempty" gorm:"index"`
+	AccountID     string          `json:"accountId,omitempty" gorm:"index"`
+	Agent         string          `json:"agent,omitempty"`
+	Server        string          `json:"server"`
+	Tool          string          `json:"tool,omitempty" gorm:"index"`
+	ArgumentsHash string          `json:"argumentsHash"`
+	Arguments     json.RawMessage `json:"arguments,omitempty" gorm:"type:json;serializer:encrypted"`
+	DurationMS    int64           `json:"durationMs"`
+	IsError       bool            `json:"isError,omitempty"`
+	Error         string          `json:"error,omitempty"`
+}
+
+// ToolCallAuditQuery selects tool call records, the zero value selects the
+// latest ones.
+type ToolCallAuditQuery struct {
+	SessionID string
+	AccountID string
+	// Tool matches the tool or the server of the call.
+	Tool       string
+	Since      time.Time
+	Until      time.Time
+	ErrorsOnly bool
+	Limit      int
+}
+
+// RecordToolCall appends a tool call to the audit log.
+func (s *Store) RecordToolCall(ctx context.Context, call auditlogs.ToolCall) error {
+	return s.withContext(ctx).Create(&ToolCallAudit{
+		CreatedAt:     call.CreatedAt,
+		SessionID:     call.SessionID,
+		AccountID:     call.AccountID,
+		Agent:         call.Agent,
+		Server:        call.Server,
+		Tool:          call.Tool,
+		ArgumentsHash: call.ArgumentsHash,
+		Arguments:     call.Arguments,
+		DurationMS:    call.DurationMS,
+		IsError:       call.IsError,
+		Error:         call.Error,
+	}).Error
+}
+
+// ToolCallAudits returns the tool call records matching the query, oldest
+// first.
+func (s *Store) ToolCallAudits(ctx context.Context, query ToolCallAuditQuery) ([]ToolCallAudit, error) {
+	db := s.withContext(ctx).Model(&ToolCallAudit{})
+	if query.SessionID != "" {
+		db = db.Where("session_id = ?", query.SessionID)
+	}
+	if query.AccountID != "" {
+		db = db.Where("account_id = ?", query.AccountID)
+	}
+	if query.Tool != "" {
+		db = db.Where("tool = ? OR server = ?", query.Tool, query.Tool)
+	}
+	if !query.Since.IsZero() {
+		db = db.Where("created_at >= ?", query.Since)
+	}
+	if !query.Until.IsZero() {
+		db = db.Where("created_at < ?", query.Until)
+	}
+	if query.ErrorsOnly {
+		db = db.Where("is_error = ?", true)
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultAuditLimit
+	}
+
+	var records []ToolCallAudit
+	if err := db.Order("id desc").Limit(limit).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	slices.Reverse(records)
+	return records, nil
+}
+
+// ToolCallAudits returns the tool call records matching the query that the
+// account can read: those of all accounts for audit admins, only its own
+// otherwise.
+func (m *Manager) ToolCallAudits(ctx context.Context, accountID string, query ToolCallAuditQuery) ([]ToolCallAudit, error) {
+	if !slices.Contains(m.auditAdmins, accountID) && !m.IsAdmin(accountID) {
+		query.AccountID = accountID
+	}
+	return m.DB.ToolCallAudits(ctx, query)
+}
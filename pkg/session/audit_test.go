@@ -0,0 +1,90 @@
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/mcp/auditlogs"
+)
+
+func TestToolCallAudits(t *testing.T) {
+	store := newTestStore(t, "audit")
+	ctx := context.Background()
+	now := time.Now()
+
+	calls := []auditlogs.ToolCall{
+		{CreatedAt: now.Add(-2 * time.Hour), SessionID: "s1", AccountID: "alice", Server: "github", Tool: "create_issue"},
+		{CreatedAt: now.Add(-time.Hour), SessionID: "s1", AccountID: "alice", Server: "shell", Tool: "bash", IsError: true, Error: "exit status 1"},
+		{CreatedAt: now, SessionID: "s2", AccountID: "bob", Server: "shell", Tool: "bash"},
+	}
+	for _, call := range calls {
+		if err := store.RecordToolCall(ctx, call); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query ToolCallAuditQuery
+		want  []string
+	}{
+		{name: "all", want: []string{"create_issue", "bash", "bash"}},
+		{name: "session", query: ToolCallAuditQuery{SessionID: "s1"}, want: []string{"create_issue", "bash"}},
+		{name: "server", query: ToolCallAuditQuery{Tool: "github"}, want: []string{"create_issue"}},
+		{name: "tool", query: ToolCallAuditQuery{Tool: "bash"}, want: []string{"bash", "bash"}},
+		{name: "errors", query: ToolCallAuditQuery{ErrorsOnly: true}, want: []string{"bash"}},
+		{name: "since", query: ToolCallAuditQuery{Since: now.Add(-90 * time.Minute)}, want: []string{"bash", "bash"}},
+		{name: "limit keeps the latest", query: ToolCallAuditQuery{Limit: 1}, want: []string{"bash"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := store.ToolCallAudits(ctx, tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, record := range records {
+				got = append(got, record.Tool)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got tools %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got tools %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	manager := NewManager(store, ManagerOptions{AuditAdmins: []string{"admin"}})
+	records, err := manager.ToolCallAudits(ctx, "bob", ToolCallAuditQuery{AccountID: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].AccountID != "bob" {
+		t.Fatalf("expected bob to only read their own record, got %+v", records)
+	}
+	records, err = manager.ToolCallAudits(ctx, "admin", ToolCallAuditQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != len(calls) {
+		t.Fatalf("expected the admin to read all %d records, got %d", len(calls), len(records))
+	}
+}
+
+func TestRedactArguments(t *testing.T) {
+	got := string(auditlogs.RedactArguments(map[string]any{
+		"query": "open issues",
+		"headers": map[string]any{
+			"Authorization": "Bearer abc",
+		},
+		"api_key": "123",
+	}))
+	want := `{"api_key":"[REDACTED]","headers":{"Authorization":"[REDACTED]"},"query":"open issues"}`
+	if got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,116 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+)
+
+// memoryBroker is a Broker for managers of the same process, standing in for
+// replicas sharing a Redis server.
+type memoryBroker struct {
+	lock sync.Mutex
+	subs []chan []byte
+}
+
+func (b *memoryBroker) Publish(_ context.Context, data []byte) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for _, sub := range b.subs {
+		sub <- data
+	}
+	return nil
+}
+
+func (b *memoryBroker) Subscribe(ctx context.Context) (<-chan []byte, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	ch := make(chan []byte, 10)
+	b.subs = append(b.subs, ch)
+	context.AfterFunc(ctx, func() {
+		b.lock.Lock()
+		defer b.lock.Unlock()
+		for i, sub := range b.subs {
+			if sub == ch {
+				b.subs = append(b.subs[:i], b.subs[i+1:]...)
+				close(ch)
+				return
+			}
+		}
+	})
+	return ch, nil
+}
+
+func (b *memoryBroker) subscribers() int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return len(b.subs)
+}
+
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestBrokerRelaysBetweenReplicas(t *testing.T) {
+	store := newTestStore(t, "db")
+	ctx := t.Context()
+
+	if err := store.Create(ctx, &Session{SessionID: "shared", State: State{ID: "shared"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	broker := &memoryBroker{}
+	replicaA := NewManager(store, ManagerOptions{Broker: broker})
+	replicaB := NewManager(store, ManagerOptions{Broker: broker})
+	t.Cleanup(replicaA.close)
+	t.Cleanup(replicaB.close)
+	eventually(t, "replicas to subscribe", func() bool { return broker.subscribers() == 2 })
+
+	handler := mcp.MessageHandlerFunc(func(context.Context, mcp.Message) {})
+	sessionA, ok, err := replicaA.Acquire(ctx, handler, "shared")
+	if err != nil || !ok {
+		t.Fatalf("failed to acquire session on replica A: %v", err)
+	}
+	defer replicaA.Release(sessionA)
+	sessionB, ok, err := replicaB.Acquire(ctx, handler, "shared")
+	if err != nil || !ok {
+		t.Fatalf("failed to acquire session on replica B: %v", err)
+	}
+	defer replicaB.Release(sessionB)
+
+	// The event stream of the client is connected to replica B.
+	_, events, _ := sessionB.Subscribe(ctx, 0)
+
+	if err := sessionA.GetSession().SendPayload(ctx, "notifications/resources/updated", map[string]string{"uri": "file:///todo.md"}); err != nil && !errors.Is(err, mcp.ErrNoReader) {
+		t.Fatal(err)
+	}
+
+	select {
+	case event := <-events:
+		if event.Message.Method != "notifications/resources/updated" {
+			t.Fatalf("expected the relayed notification, got %s", event.Message.Method)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the relayed notification")
+	}
+
+	sessionA.GetSession().Set("color", mcp.SavedString("blue"))
+	if err := replicaA.Store(ctx, "shared", sessionA); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "replica B to reload the session", func() bool {
+		var color string
+		return sessionB.GetSession().Get("color", &color) && color == "blue"
+	})
+}
@@ -0,0 +1,34 @@
+package session
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// ChannelThread maps a thread of a channel, like a Slack thread or an email
+// thread, to the session that answers it.
+type ChannelThread struct {
+	ID        uint   `json:"-" gorm:"primarykey"`
+	Channel   string `json:"channel" gorm:"uniqueIndex:idx_channel_thread;not null"`
+	ThreadKey string `json:"threadKey" gorm:"uniqueIndex:idx_channel_thread;not null"`
+	SessionID string `json:"sessionId" gorm:"index;not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// GetChannelThread returns the thread of a channel by its key.
+func (s *Store) GetChannelThread(ctx context.Context, channel, threadKey string) (*ChannelThread, error) {
+	var thread ChannelThread
+	err := s.withContext(ctx).Where("channel = ? AND thread_key = ?", channel, threadKey).First(&thread).Error
+	return &thread, err
+}
+
+// SaveChannelThread creates the thread or points it at another session.
+func (s *Store) SaveChannelThread(ctx context.Context, thread *ChannelThread) error {
+	return s.withContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "channel"}, {Name: "thread_key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"session_id", "updated_at"}),
+	}).Create(thread).Error
+}
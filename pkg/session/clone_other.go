@@ -0,0 +1,13 @@
+//go:build !linux
+
+package session
+
+import (
+	"errors"
+	"os"
+)
+
+// cloneFile is only supported on Linux, elsewhere files are copied.
+func cloneFile(_, _ *os.File) error {
+	return errors.ErrUnsupported
+}
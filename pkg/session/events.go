@@ -0,0 +1,68 @@
+package session
+
+import (
+	"context"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"gorm.io/gorm"
+)
+
+// maxStoredEvents is the number of events kept per session for event streams
+// to resume from.
+const maxStoredEvents = 256
+
+var _ mcp.EventStore = (*Store)(nil)
+
+// SessionEvent is a message sent to the event streams of a session. Its ID is
+// the event ID, so events are numbered across replicas.
+type SessionEvent struct {
+	ID        uint64      `json:"id" gorm:"primarykey"`
+	SessionID string      `json:"sessionId" gorm:"index;not null"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Message   mcp.Message `json:"message" gorm:"type:json;serializer:encrypted"`
+}
+
+// AppendEvent stores an event of a session, dropping the oldest ones past
+// maxStoredEvents.
+func (s *Store) AppendEvent(ctx context.Context, sessionID string, msg mcp.Message) (uint64, error) {
+	event := SessionEvent{
+		SessionID: sessionID,
+		Message:   msg,
+	}
+	err := s.withContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&event).Error; err != nil {
+			return err
+		}
+		return tx.Where("session_id = ? AND id <= (?)", sessionID,
+			tx.Model(&SessionEvent{}).Select("id").Where("session_id = ?", sessionID).
+				Order("id desc").Offset(maxStoredEvents).Limit(1)).
+			Delete(&SessionEvent{}).Error
+	})
+	return event.ID, err
+}
+
+// EventsSince returns the stored events of a session after lastEventID.
+func (s *Store) EventsSince(ctx context.Context, sessionID string, lastEventID uint64) ([]mcp.StreamEvent, error) {
+	var events []SessionEvent
+	if err := s.withContext(ctx).Where("session_id = ? AND id > ?", sessionID, lastEventID).
+		Order("id").Find(&events).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]mcp.StreamEvent, 0, len(events))
+	for _, event := range events {
+		result = append(result, mcp.StreamEvent{
+			ID:      event.ID,
+			Message: event.Message,
+		})
+	}
+	return result, nil
+}
+
+// UpdatedAt returns when a session was last stored.
+func (s *Store) UpdatedAt(ctx context.Context, id string) (time.Time, error) {
+	var session Session
+	err := s.withContext(ctx).Select("updated_at").Where("session_id = ?", id).First(&session).Error
+	return session.UpdatedAt, err
+}
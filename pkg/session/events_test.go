@@ -0,0 +1,106 @@
+package session
+
+import (
+	"context"
+	"testing"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+)
+
+func TestDistributedSessionResumesOnAnotherReplica(t *testing.T) {
+	store := newTestStore(t, "db")
+	ctx := t.Context()
+
+	if err := store.Create(ctx, &Session{SessionID: "shared", State: State{ID: "shared"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	replicaA := NewManager(store, ManagerOptions{Distributed: true})
+	replicaB := NewManager(store, ManagerOptions{Distributed: true})
+	t.Cleanup(replicaA.close)
+	t.Cleanup(replicaB.close)
+
+	handler := mcp.MessageHandlerFunc(func(context.Context, mcp.Message) {})
+	sessionA, ok, err := replicaA.Acquire(ctx, handler, "shared")
+	if err != nil || !ok {
+		t.Fatalf("failed to acquire session on replica A: %v", err)
+	}
+	defer replicaA.Release(sessionA)
+
+	// The client's event stream is on replica A and saw the first event.
+	streamCtx, closeStream := context.WithCancel(ctx)
+	_, events, _ := sessionA.Subscribe(streamCtx, 0)
+	for _, uri := range []string{"file:///a.md", "file:///b.md"} {
+		if err := sessionA.GetSession().SendPayload(ctx, "notifications/resources/updated", map[string]string{"uri": uri}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	first := <-events
+	closeStream()
+
+	sessionA.GetSession().Set("color", mcp.SavedString("blue"))
+	if err := replicaA.Store(ctx, "shared", sessionA); err != nil {
+		t.Fatal(err)
+	}
+
+	// The load balancer sends the reconnecting client to replica B.
+	sessionB, ok, err := replicaB.Acquire(ctx, handler, "shared")
+	if err != nil || !ok {
+		t.Fatalf("failed to acquire session on replica B: %v", err)
+	}
+	defer replicaB.Release(sessionB)
+
+	replay, _, _ := sessionB.Subscribe(ctx, first.ID)
+	if len(replay) != 1 || replay[0].ID <= first.ID || string(replay[0].Message.Params) != `{"uri":"file:///b.md"}` {
+		t.Fatalf("expected the missed event to be replayed, got %+v", replay)
+	}
+
+	// Replica B changes the session, replica A reloads it when it next
+	// serves it.
+	sessionB.GetSession().Set("color", mcp.SavedString("green"))
+	if err := replicaB.Store(ctx, "shared", sessionB); err != nil {
+		t.Fatal(err)
+	}
+	again, ok, err := replicaA.Acquire(ctx, handler, "shared")
+	if err != nil || !ok {
+		t.Fatalf("failed to acquire session on replica A again: %v", err)
+	}
+	defer replicaA.Release(again)
+
+	var color string
+	if !again.GetSession().Get("color", &color) || color != "green" {
+		t.Fatalf("expected replica A to reload the session, got color %q", color)
+	}
+}
+
+func TestAppendEventKeepsRecentEvents(t *testing.T) {
+	store := newTestStore(t, "db")
+	ctx := t.Context()
+
+	var last uint64
+	for range maxStoredEvents + 10 {
+		id, err := store.AppendEvent(ctx, "session", mcp.Message{Method: "notifications/message"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		last = id
+	}
+	if _, err := store.AppendEvent(ctx, "other", mcp.Message{Method: "notifications/message"}); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := store.EventsSince(ctx, "session", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != maxStoredEvents || events[len(events)-1].ID != last {
+		t.Fatalf("expected the last %d events, got %d ending with %d", maxStoredEvents, len(events), events[len(events)-1].ID)
+	}
+
+	if err := store.Purge(ctx, "session"); err != nil {
+		t.Fatal(err)
+	}
+	if events, _ := store.EventsSince(ctx, "session", 0); len(events) != 0 {
+		t.Fatalf("expected purging the session to remove its events, got %d", len(events))
+	}
+}
@@ -0,0 +1,482 @@
+package session
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+	"github.com/obot-platform/nanobot/pkg/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	ExportFormatTar  = "tar"
+	ExportFormatJSON = "json"
+
+	bundleVersion = 1
+	// bundleManifest is the name of the session in a tar bundle, it comes
+	// before the files so they can be written as they are read.
+	bundleManifest = "session.json"
+	bundleFilesDir = "files"
+)
+
+// Bundle is a session in a portable form, to move it to another machine or
+// attach it to a bug report.
+type Bundle struct {
+	Version  int           `json:"version"`
+	Exported time.Time     `json:"exported"`
+	Session  BundleSession `json:"session"`
+	// ConfigHash is the hash of the config the session last ran with, to tell
+	// whether it runs with the same config elsewhere.
+	ConfigHash string `json:"configHash,omitempty"`
+	// Messages of the chat, including the compacted ones and tool results.
+	// They are part of the session state too, this is a readable copy.
+	Messages []types.Message `json:"messages,omitempty"`
+	// Files of the session's workspace and nanobot's own files for it, with
+	// paths relative to the working directory and without the session ID.
+	Files []BundleFile `json:"files,omitempty"`
+}
+
+type BundleSession struct {
+	SessionID   string           `json:"sessionId"`
+	Type        string           `json:"type,omitempty"`
+	Description string           `json:"description,omitempty"`
+	AccountID   string           `json:"accountId,omitempty"`
+	State       mcp.SessionState `json:"state"`
+	Config      types.Config     `json:"config,omitzero"`
+}
+
+type BundleFile struct {
+	Path   string      `json:"path"`
+	Size   int64       `json:"size"`
+	Mode   fs.FileMode `json:"mode"`
+	SHA256 string      `json:"sha256"`
+}
+
+type ExportOptions struct {
+	// AccountID is the account the exported session must belong to, empty to
+	// export the session of any account.
+	AccountID string
+	// Format of the bundle, ExportFormatTar for a gzipped tar of the session
+	// and its files, ExportFormatJSON for the session and a manifest of its
+	// files without their contents. Defaults to ExportFormatTar.
+	Format string
+}
+
+type ImportOptions struct {
+	// AccountID is the account the imported session belongs to. Defaults to
+	// the account of the exported session.
+	AccountID string
+	// NewID gives the imported session a new ID instead of the exported one.
+	// A new ID is also used when the exported one is taken.
+	NewID bool
+	// Cwd is the working directory of the imported session. Defaults to the
+	// current directory.
+	Cwd string
+}
+
+// Export writes a session and its files as a bundle that Import recreates it
+// from.
+func (m *Manager) Export(ctx context.Context, id string, w io.Writer, opts ExportOptions) error {
+	stored, state, err := m.current(ctx, id, opts.AccountID)
+	if err != nil {
+		return err
+	}
+
+	bundle, err := newBundle(stored, state)
+	if err != nil {
+		return err
+	}
+
+	switch opts.Format {
+	case ExportFormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(bundle)
+	case "", ExportFormatTar:
+		return writeBundleTar(w, stored, bundle)
+	default:
+		return fmt.Errorf("unknown export format %q, must be %s or %s", opts.Format, ExportFormatTar, ExportFormatJSON)
+	}
+}
+
+func newBundle(stored *Session, state mcp.SessionState) (*Bundle, error) {
+	bundle := &Bundle{
+		Version:  bundleVersion,
+		Exported: time.Now().UTC(),
+		Session: BundleSession{
+			SessionID:   stored.SessionID,
+			Type:        stored.Type,
+			Description: stored.Description,
+			AccountID:   stored.AccountID,
+			State:       state,
+			Config:      types.Config(stored.Config),
+		},
+	}
+
+	if hash, ok := state.Attributes[types.ConfigHashSessionKey]; ok {
+		_ = mcp.JSONCoerce(hash, &bundle.ConfigHash)
+	}
+
+	messages, err := stateMessages(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the chat of session %s: %w", stored.SessionID, err)
+	}
+	bundle.Messages = messages
+
+	err = walkSessionFiles(stored.Cwd, stored.SessionID, func(name, file string, info fs.FileInfo) error {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		hash := sha256.New()
+		size, err := io.Copy(hash, f)
+		if err != nil {
+			return err
+		}
+		bundle.Files = append(bundle.Files, BundleFile{
+			Path:   name,
+			Size:   size,
+			Mode:   info.Mode().Perm(),
+			SHA256: hex.EncodeToString(hash.Sum(nil)),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the files of session %s: %w", stored.SessionID, err)
+	}
+
+	return bundle, nil
+}
+
+// stateMessages returns the messages of the chat in a session's state.
+func stateMessages(state mcp.SessionState) ([]types.Message, error) {
+	data, ok := state.Attributes[types.PreviousExecutionKey]
+	if !ok {
+		return nil, nil
+	}
+	var run types.Execution
+	if err := mcp.JSONCoerce(data, &run); err != nil {
+		return nil, err
+	}
+	return run.Messages(), nil
+}
+
+// Messages returns the messages of a session's chat, including the ones
+// archived by compaction. Sessions of other accounts aren't found unless
+// accountID is empty.
+func (m *Manager) Messages(ctx context.Context, id, accountID string) ([]types.Message, error) {
+	_, state, err := m.current(ctx, id, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return stateMessages(state)
+}
+
+// walkSessionFiles calls fn with the regular files of a session, named by
+// their slash separated path relative to the working directory without the
+// session ID. Symlinks aren't followed, their targets may not exist on
+// another machine.
+func walkSessionFiles(cwd, id string, fn func(name, file string, info fs.FileInfo) error) error {
+	cwd = sessionCwd(cwd)
+	for _, dir := range sessionDirs {
+		root := filepath.Join(cwd, dir, id)
+		if _, err := os.Stat(root); errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		err := filepath.WalkDir(root, func(file string, d fs.DirEntry, err error) error {
+			if err != nil || !d.Type().IsRegular() {
+				return err
+			}
+			rel, err := filepath.Rel(root, file)
+			if err != nil {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			return fn(path.Join(dir, filepath.ToSlash(rel)), file, info)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeBundleTar(w io.Writer, stored *Session, bundle *Bundle) error {
+	manifest, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    bundleManifest,
+		Mode:    0600,
+		Size:    int64(len(manifest)),
+		ModTime: bundle.Exported,
+	}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(manifest); err != nil {
+		return err
+	}
+
+	files := make(map[string]BundleFile, len(bundle.Files))
+	for _, file := range bundle.Files {
+		files[file.Path] = file
+	}
+
+	err = walkSessionFiles(stored.Cwd, stored.SessionID, func(name, file string, info fs.FileInfo) error {
+		// Files created since the manifest was written aren't in the bundle.
+		entry, ok := files[name]
+		if !ok {
+			return nil
+		}
+
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    path.Join(bundleFilesDir, name),
+			Mode:    int64(entry.Mode),
+			Size:    entry.Size,
+			ModTime: info.ModTime(),
+		}); err != nil {
+			return err
+		}
+		// Copy no more than the manifest says, the file may have grown.
+		_, err = io.CopyN(tw, f, entry.Size)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write the files of session %s: %w", stored.SessionID, err)
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Import recreates a session from a bundle written by Export, either a tar
+// bundle or a JSON one. The files of a JSON bundle aren't imported, it has
+// none.
+func (m *Manager) Import(ctx context.Context, r io.Reader, opts ImportOptions) (*Session, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(2)
+
+	if !bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		var bundle Bundle
+		if err := json.NewDecoder(br).Decode(&bundle); err != nil {
+			return nil, fmt.Errorf("failed to read session bundle: %w", err)
+		}
+		record, err := m.importRecord(ctx, &bundle, opts)
+		if err != nil {
+			return nil, err
+		}
+		if err := m.DB.Create(ctx, record); err != nil {
+			return nil, fmt.Errorf("failed to create imported session: %w", err)
+		}
+		return record, nil
+	}
+
+	gz, err := gzip.NewReader(br)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session bundle: %w", err)
+	}
+	tr := tar.NewReader(gz)
+
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session bundle: %w", err)
+	} else if hdr.Name != bundleManifest {
+		return nil, fmt.Errorf("invalid session bundle, it must start with %s, not %s", bundleManifest, hdr.Name)
+	}
+
+	var bundle Bundle
+	if err := json.NewDecoder(tr).Decode(&bundle); err != nil {
+		return nil, fmt.Errorf("failed to read %s of session bundle: %w", bundleManifest, err)
+	}
+
+	record, err := m.importRecord(ctx, &bundle, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := readBundleFiles(tr, &bundle, record); err != nil {
+		removeSessionFiles(record.Cwd, record.SessionID)
+		return nil, err
+	}
+
+	if err := m.DB.Create(ctx, record); err != nil {
+		removeSessionFiles(record.Cwd, record.SessionID)
+		return nil, fmt.Errorf("failed to create imported session: %w", err)
+	}
+	return record, nil
+}
+
+// importRecord returns the record of the session in a bundle.
+func (m *Manager) importRecord(ctx context.Context, bundle *Bundle, opts ImportOptions) (*Session, error) {
+	if bundle.Version != bundleVersion {
+		return nil, fmt.Errorf("unsupported session bundle version %d, expected %d", bundle.Version, bundleVersion)
+	}
+
+	id := bundle.Session.SessionID
+	if id != "" && !isPathElement(id) {
+		return nil, fmt.Errorf("invalid session bundle, session ID %q is not a valid ID", id)
+	}
+	if opts.NewID || id == "" {
+		id = uuid.String()
+	} else if _, err := m.DB.Get(ctx, id); err == nil {
+		id = uuid.String()
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	cwd := opts.Cwd
+	if cwd == "" {
+		cwd = sessionCwd("")
+	}
+
+	record := &Session{
+		Type:        bundle.Session.Type,
+		SessionID:   id,
+		Description: bundle.Session.Description,
+		AccountID:   bundle.Session.AccountID,
+		Config:      ConfigWrapper(bundle.Session.Config),
+		Cwd:         cwd,
+	}
+	if opts.AccountID != "" {
+		record.AccountID = opts.AccountID
+	}
+
+	state := bundle.Session.State
+	if state.Attributes == nil {
+		state.Attributes = map[string]any{}
+	}
+	// The imported session starts idle, like a fork.
+	for _, key := range unforkedAttributes {
+		delete(state.Attributes, key)
+	}
+	state.ID = id
+	state.Attributes[types.AccountIDSessionKey] = record.AccountID
+	record.State = State(state)
+
+	return record, nil
+}
+
+// readBundleFiles writes the files of a tar bundle to the session, checking
+// them against the manifest.
+func readBundleFiles(tr *tar.Reader, bundle *Bundle, record *Session) error {
+	files := make(map[string]BundleFile, len(bundle.Files))
+	for _, file := range bundle.Files {
+		files[file.Path] = file
+	}
+
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to read session bundle: %w", err)
+		}
+
+		name, ok := strings.CutPrefix(hdr.Name, bundleFilesDir+"/")
+		if !ok || hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		entry, ok := files[name]
+		if !ok {
+			return fmt.Errorf("invalid session bundle, file %s is not in the manifest", name)
+		}
+		target, err := bundleFileTarget(record, name)
+		if err != nil {
+			return err
+		}
+		if err := writeBundleFile(tr, target, entry); err != nil {
+			return fmt.Errorf("failed to import file %s: %w", name, err)
+		}
+		delete(files, name)
+	}
+
+	if len(files) > 0 {
+		missing := slices.Sorted(maps.Keys(files))
+		return fmt.Errorf("invalid session bundle, files are missing: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// bundleFileTarget returns where a file of a bundle is written, refusing paths
+// that leave the session's directories.
+func bundleFileTarget(record *Session, name string) (string, error) {
+	if !isPathElement(record.SessionID) {
+		return "", fmt.Errorf("invalid session ID %q", record.SessionID)
+	}
+	dir, rel, _ := strings.Cut(name, "/")
+	if !slices.Contains(sessionDirs, dir) || !filepath.IsLocal(filepath.FromSlash(rel)) {
+		return "", fmt.Errorf("invalid session bundle, file %s is outside of the session", name)
+	}
+	return filepath.Join(record.Cwd, dir, record.SessionID, filepath.FromSlash(rel)), nil
+}
+
+// isPathElement reports whether id is a single path element, so that joining
+// it to a directory can't leave that directory.
+func isPathElement(id string) bool {
+	return filepath.IsLocal(id) && !strings.ContainsAny(id, `/\`) && id != "."
+}
+
+func writeBundleFile(r io.Reader, target string, entry BundleFile) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, entry.Mode.Perm()|0600)
+	if err != nil {
+		return err
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(out, hash), r)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if size != entry.Size || hex.EncodeToString(hash.Sum(nil)) != entry.SHA256 {
+		return errors.New("the file doesn't match the manifest")
+	}
+	return nil
+}
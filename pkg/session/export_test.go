@@ -0,0 +1,166 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+func newTestStore(t *testing.T, name string, opts ...StoreOptions) *Store {
+	t.Helper()
+	store, err := NewStoreFromDSN(fmt.Sprintf("sqlite:file:%s?mode=memory&cache=shared",
+		strings.ReplaceAll(t.Name()+"_"+name, "/", "_")), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestExportImport(t *testing.T) {
+	store := newTestStore(t, "laptop")
+
+	cwd := t.TempDir()
+	source := &Session{
+		Type:        "thread",
+		SessionID:   "source",
+		Description: "Original",
+		AccountID:   "account",
+		Cwd:         cwd,
+		State: State{
+			ID: "source",
+			Attributes: map[string]any{
+				types.ConfigHashSessionKey: "abc123",
+				types.TurnSessionKey:       map[string]any{"id": "turn"},
+				types.PreviousExecutionKey: map[string]any{
+					"populatedRequest": map[string]any{
+						"input": []any{
+							map[string]any{"id": "m1", "role": "user", "items": []any{
+								map[string]any{"content": map[string]any{"type": "text", "text": "Hello"}},
+							}},
+						},
+					},
+				},
+			},
+		},
+	}
+	if err := store.Create(t.Context(), source); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range map[string]string{
+		filepath.Join("sessions", "source", "notes", "plan.md"): "the plan",
+		filepath.Join(".nanobot", "source", "journal.json"):     "[]",
+	} {
+		file := filepath.Join(cwd, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manager := NewManager(store)
+
+	var manifest bytes.Buffer
+	if err := manager.Export(t.Context(), "source", &manifest, ExportOptions{Format: ExportFormatJSON}); err != nil {
+		t.Fatal(err)
+	}
+	var bundle Bundle
+	if err := json.Unmarshal(manifest.Bytes(), &bundle); err != nil {
+		t.Fatal(err)
+	}
+	if bundle.ConfigHash != "abc123" || len(bundle.Messages) != 1 || bundle.Messages[0].ID != "m1" {
+		t.Errorf("unexpected bundle %+v", bundle)
+	}
+	if len(bundle.Files) != 2 || bundle.Files[0].Path != "sessions/notes/plan.md" || bundle.Files[1].Path != ".nanobot/journal.json" {
+		t.Errorf("unexpected files %+v", bundle.Files)
+	}
+
+	var archive bytes.Buffer
+	if err := manager.Export(t.Context(), "source", &archive, ExportOptions{AccountID: "account"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Importing on another machine keeps the ID.
+	serverCwd := t.TempDir()
+	imported, err := NewManager(newTestStore(t, "server")).Import(t.Context(), bytes.NewReader(archive.Bytes()), ImportOptions{
+		Cwd: serverCwd,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.SessionID != "source" || imported.AccountID != "account" || imported.Description != "Original" || imported.Cwd != serverCwd {
+		t.Errorf("unexpected imported session %+v", imported)
+	}
+	if _, ok := imported.State.Attributes[types.PreviousExecutionKey]; !ok {
+		t.Error("expected the imported session to have the chat")
+	}
+	if _, ok := imported.State.Attributes[types.TurnSessionKey]; ok {
+		t.Error("expected the imported session to start without a turn")
+	}
+	data, err := os.ReadFile(filepath.Join(serverCwd, "sessions", "source", "notes", "plan.md"))
+	if err != nil || string(data) != "the plan" {
+		t.Errorf("expected the files to be imported, got %q, %v", data, err)
+	}
+
+	// Importing where the ID is taken gives the session a new one.
+	again, err := manager.Import(t.Context(), bytes.NewReader(archive.Bytes()), ImportOptions{
+		AccountID: "other",
+		Cwd:       cwd,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.SessionID == "source" || again.State.ID != again.SessionID || again.AccountID != "other" {
+		t.Errorf("unexpected imported session %+v", again)
+	}
+	if _, err := os.Stat(filepath.Join(cwd, ".nanobot", again.SessionID, "journal.json")); err != nil {
+		t.Error(err)
+	}
+
+	// A JSON bundle imports the session without its files.
+	fromJSON, err := manager.Import(t.Context(), bytes.NewReader(manifest.Bytes()), ImportOptions{Cwd: cwd})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(cwd, "sessions", fromJSON.SessionID)); !os.IsNotExist(err) {
+		t.Errorf("expected no files for a JSON bundle, got %v", err)
+	}
+}
+
+func TestBundleFileTarget(t *testing.T) {
+	record := &Session{SessionID: "id", Cwd: "/work"}
+	for _, name := range []string{"sessions/../../etc/passwd", "other/file", "sessions//etc/passwd", ".nanobot/"} {
+		if target, err := bundleFileTarget(record, name); err == nil {
+			t.Errorf("expected %s to be refused, got %s", name, target)
+		}
+	}
+	if target, err := bundleFileTarget(record, "sessions/a/b.txt"); err != nil || target != filepath.Join("/work", "sessions", "id", "a", "b.txt") {
+		t.Errorf("unexpected target %s, %v", target, err)
+	}
+	for _, id := range []string{"..", "../escape", "a/b", `a\b`, "/abs"} {
+		if target, err := bundleFileTarget(&Session{SessionID: id, Cwd: "/work"}, "sessions/a.txt"); err == nil {
+			t.Errorf("expected session ID %s to be refused, got %s", id, target)
+		}
+	}
+}
+
+func TestImportRejectsSessionIDPath(t *testing.T) {
+	manager := NewManager(newTestStore(t, "server"))
+	bundle := Bundle{Version: bundleVersion}
+	bundle.Session.SessionID = "../../escape"
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.Import(t.Context(), bytes.NewReader(data), ImportOptions{Cwd: t.TempDir()}); err == nil {
+		t.Error("expected a session ID with a path to be refused")
+	}
+}
@@ -0,0 +1,216 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+	"github.com/obot-platform/nanobot/pkg/uuid"
+)
+
+// sessionDirs are the directories, relative to the session's working
+// directory, that hold the files of a session under its ID: the workspace
+// files and nanobot's own, like the journal used to undo turns.
+var sessionDirs = []string{"sessions", ".nanobot"}
+
+// unforkedAttributes are session attributes that describe the activity of a
+// session rather than its conversation, a fork starts without them.
+var unforkedAttributes = []string{
+	types.TurnSessionKey,
+	types.TaskURISessionKey,
+	types.UsageSessionKey,
+	types.ResourceSubscriptionsSessionKey,
+	types.ResourcesListedSessionKey,
+}
+
+type ForkOptions struct {
+	// AccountID is the account the forked session must belong to, empty to
+	// fork the session of any account.
+	AccountID string
+	// Description of the fork. Defaults to the description of the session.
+	Description string
+	// CopyFiles copies the files of the session to the fork, cloning them
+	// copy-on-write where the file system can.
+	CopyFiles bool
+}
+
+// Fork copies a session with its conversation and environment into a new
+// session, so the copy can go on differently.
+func (m *Manager) Fork(ctx context.Context, id string, opts ForkOptions) (*Session, error) {
+	source, state, err := m.current(ctx, id, opts.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Copy the state so the fork doesn't share attributes with the source.
+	var forkState mcp.SessionState
+	if err := mcp.JSONCoerce(state, &forkState); err != nil {
+		return nil, fmt.Errorf("failed to copy session state: %w", err)
+	}
+	if forkState.Attributes == nil {
+		forkState.Attributes = map[string]any{}
+	}
+	for _, key := range unforkedAttributes {
+		delete(forkState.Attributes, key)
+	}
+
+	fork := &Session{
+		Type:        source.Type,
+		SessionID:   uuid.String(),
+		Description: source.Description,
+		AccountID:   source.AccountID,
+		Config:      source.Config,
+		Cwd:         source.Cwd,
+	}
+	if opts.Description != "" {
+		fork.Description = opts.Description
+	}
+	forkState.ID = fork.SessionID
+	forkState.Attributes[types.DescriptionSessionKey] = fork.Description
+	fork.State = State(forkState)
+
+	if opts.CopyFiles {
+		if err := copySessionFiles(source.Cwd, source.SessionID, fork.SessionID); err != nil {
+			removeSessionFiles(source.Cwd, fork.SessionID)
+			return nil, fmt.Errorf("failed to copy the files of session %s: %w", source.SessionID, err)
+		}
+	}
+
+	if err := m.DB.Create(ctx, fork); err != nil {
+		if opts.CopyFiles {
+			removeSessionFiles(source.Cwd, fork.SessionID)
+		}
+		return nil, fmt.Errorf("failed to create forked session: %w", err)
+	}
+	return fork, nil
+}
+
+// current returns the record of a session and its current state. The state of
+// a live session is taken from memory, it may be newer than the stored one.
+// Sessions of other accounts aren't found unless accountID is empty.
+func (m *Manager) current(ctx context.Context, id, accountID string) (*Session, mcp.SessionState, error) {
+	var (
+		stored *Session
+		err    error
+	)
+	if accountID != "" {
+		stored, err = m.DB.GetByIDByAccountID(ctx, id, accountID)
+	} else {
+		stored, err = m.DB.Get(ctx, id)
+	}
+	if err != nil {
+		return nil, mcp.SessionState{}, err
+	}
+
+	state := mcp.SessionState(stored.State)
+	if live := m.liveSession(id); live != nil {
+		liveState, err := live.GetSession().State()
+		if err != nil {
+			return nil, mcp.SessionState{}, fmt.Errorf("failed to get session state: %w", err)
+		}
+		state = *liveState
+	}
+	return stored, state, nil
+}
+
+// liveSession returns the session with the ID if it is loaded.
+func (m *Manager) liveSession(id string) *mcp.ServerSession {
+	m.liveSessionsLock.Lock()
+	defer m.liveSessionsLock.Unlock()
+	return m.liveSessions[id].session
+}
+
+// sessionCwd returns the working directory of a session, the current one for
+// sessions that didn't record theirs.
+func sessionCwd(cwd string) string {
+	if cwd == "" {
+		cwd, _ = os.Getwd()
+	}
+	return cwd
+}
+
+// removeSessionFiles removes the files of a session.
+func removeSessionFiles(cwd, id string) {
+	cwd = sessionCwd(cwd)
+	for _, dir := range sessionDirs {
+		_ = os.RemoveAll(filepath.Join(cwd, dir, id))
+	}
+}
+
+// copySessionFiles copies the files of a session to another session.
+func copySessionFiles(cwd, from, to string) error {
+	cwd = sessionCwd(cwd)
+	for _, dir := range sessionDirs {
+		src := filepath.Join(cwd, dir, from)
+		if _, err := os.Stat(src); errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if err := copyTree(src, filepath.Join(cwd, dir, to)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		}
+		// Sockets, pipes, and devices aren't copied.
+		return nil
+	})
+}
+
+// copyFile copies a file, cloning it copy-on-write when the file system
+// supports it.
+func copyFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+
+	if cloneFile(out, in) != nil {
+		_, err = io.Copy(out, in)
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,87 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+func TestFork(t *testing.T) {
+	store, err := NewStoreFromDSN(fmt.Sprintf("sqlite:file:%s?mode=memory&cache=shared",
+		strings.ReplaceAll(t.Name(), "/", "_")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cwd := t.TempDir()
+	source := &Session{
+		Type:        "thread",
+		SessionID:   "source",
+		Description: "Original",
+		AccountID:   "account",
+		Cwd:         cwd,
+		State: State{
+			ID: "source",
+			Attributes: map[string]any{
+				"env":                 map[string]any{"KEY": "value"},
+				types.UsageSessionKey: map[string]any{"models": map[string]any{}},
+			},
+		},
+	}
+	if err := store.Create(t.Context(), source); err != nil {
+		t.Fatal(err)
+	}
+
+	workspaceFile := filepath.Join(cwd, "sessions", "source", "notes", "plan.md")
+	if err := os.MkdirAll(filepath.Dir(workspaceFile), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(workspaceFile, []byte("the plan"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := NewManager(store)
+	if _, err := manager.Fork(t.Context(), "source", ForkOptions{AccountID: "someone-else"}); err == nil {
+		t.Fatal("expected forking the session of another account to fail")
+	}
+
+	fork, err := manager.Fork(t.Context(), "source", ForkOptions{
+		AccountID:   "account",
+		Description: "Alternative",
+		CopyFiles:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := store.Get(t.Context(), fork.SessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.SessionID == "source" || stored.State.ID != stored.SessionID || stored.Description != "Alternative" || stored.AccountID != "account" {
+		t.Errorf("unexpected fork %+v", stored)
+	}
+	if _, ok := stored.State.Attributes["env"]; !ok {
+		t.Error("expected the fork to have the environment of the session")
+	}
+	if _, ok := stored.State.Attributes[types.UsageSessionKey]; ok {
+		t.Error("expected the fork to start without usage")
+	}
+
+	data, err := os.ReadFile(filepath.Join(cwd, "sessions", fork.SessionID, "notes", "plan.md"))
+	if err != nil || string(data) != "the plan" {
+		t.Errorf("expected the files to be copied, got %q, %v", data, err)
+	}
+
+	// The copy is independent of the original.
+	if err := os.WriteFile(workspaceFile, []byte("changed"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(cwd, "sessions", fork.SessionID, "notes", "plan.md")); string(data) != "the plan" {
+		t.Errorf("expected the fork's file to be unchanged, got %q", data)
+	}
+}
@@ -4,29 +4,65 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"maps"
 	"net/http"
 	"os"
 	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/obot-platform/nanobot/pkg/complete"
 	"github.com/obot-platform/nanobot/pkg/mcp"
 	"github.com/obot-platform/nanobot/pkg/types"
 	"github.com/obot-platform/nanobot/pkg/uuid"
 	"gorm.io/gorm"
 )
 
-func NewManager(store *Store) *Manager {
+type ManagerOptions struct {
+	// Broker relays notifications and stored sessions between replicas.
+	Broker Broker
+	// Distributed lets any replica serve any session: the events of sessions
+	// are stored so that event streams can resume on another replica, and
+	// sessions are reloaded when another replica stored them.
+	Distributed bool
+	// AuditAdmins are the IDs of the accounts that can read the tool call
+	// audit records of all accounts.
+	AuditAdmins []string
+	// Admins are the IDs of the accounts that can manage accounts. They can
+	// also read the audit records of all accounts.
+	Admins []string
+}
+
+func (o ManagerOptions) Merge(other ManagerOptions) (result ManagerOptions) {
+	result.Broker = complete.Last(o.Broker, other.Broker)
+	result.Distributed = complete.Last(o.Distributed, other.Distributed)
+	result.AuditAdmins = append(o.AuditAdmins, other.AuditAdmins...)
+	result.Admins = append(o.Admins, other.Admins...)
+	return
+}
+
+func NewManager(store *Store, opts ...ManagerOptions) *Manager {
+	opt := complete.Complete(opts...)
 	ctx, cancel := context.WithCancel(context.Background())
-	return &Manager{
+	m := &Manager{
 		ctx:          ctx,
 		close:        cancel,
 		DB:           store,
 		root:         &Session{},
 		liveSessions: make(map[string]liveSession),
+		broker:       opt.Broker,
+		replicaID:    uuid.String(),
+		distributed:  opt.Distributed,
+		auditAdmins:  opt.AuditAdmins,
+		admins:       opt.Admins,
+	}
+	if m.broker != nil {
+		go m.runBroker()
 	}
+	return m
 }
 
 type Manager struct {
@@ -35,6 +71,16 @@ type Manager struct {
 	DB    *Store
 	root  *Session
 
+	broker      Broker
+	replicaID   string
+	distributed bool
+	auditAdmins []string
+	admins      []string
+
+	// checkpointed is set by Checkpoint, later changes to sessions aren't
+	// stored so that the checkpoint is what sessions resume from.
+	checkpointed atomic.Bool
+
 	liveSessionsLock sync.Mutex
 	liveSessions     map[string]liveSession
 }
@@ -43,6 +89,9 @@ type liveSession struct {
 	session *mcp.ServerSession
 	count   int
 	cancel  context.CancelFunc
+	// storedAt is when the record of the session was last updated, as of when
+	// this manager loaded or stored it.
+	storedAt time.Time
 }
 
 func (m *Manager) newRecord(id, accountID string) *Session {
@@ -82,6 +131,10 @@ func (m *Manager) Store(ctx context.Context, id string, session *mcp.ServerSessi
 	if id == "" {
 		return nil
 	}
+	if m.checkpointed.Load() {
+		slog.Debug("not storing session changed after the shutdown checkpoint", "session", id)
+		return nil
+	}
 
 	var accountID string
 	session.GetSession().Get(types.AccountIDSessionKey, &accountID)
@@ -122,25 +175,106 @@ func (m *Manager) Store(ctx context.Context, id string, session *mcp.ServerSessi
 			}
 			live.count++
 			live.session = session
+			live.storedAt = stored.UpdatedAt
 
 			m.liveSessions[id] = live
 		} else {
 			m.liveSessions[id] = liveSession{
-				session: session,
-				count:   1,
+				session:  session,
+				count:    1,
+				storedAt: stored.UpdatedAt,
 			}
 		}
 		m.liveSessionsLock.Unlock()
+		m.attach(session)
 	} else {
 		if err := m.DB.Update(ctx, stored); err != nil {
 			return err
 		}
+		m.setStoredAt(id, stored.UpdatedAt)
+		m.publish(ctx, brokerEvent{
+			Type:      brokerEventInvalidate,
+			SessionID: id,
+		})
+	}
+
+	if err := m.DB.IndexMessages(ctx, stored); err != nil {
+		slog.Warn("failed to index session messages for search", "session", id, "error", err)
 	}
 
 	m.loadAttributesFromRecord(stored, session)
 	return nil
 }
 
+// Checkpoint stores the live sessions as they are, with the progress of the
+// runs in flight, so that the runs interrupted by a shutdown can resume from
+// it on the next message. Sessions aren't stored anymore after it.
+func (m *Manager) Checkpoint(ctx context.Context) error {
+	m.liveSessionsLock.Lock()
+	sessions := make(map[string]*mcp.ServerSession, len(m.liveSessions))
+	for id, live := range m.liveSessions {
+		sessions[id] = live.session
+	}
+	m.liveSessionsLock.Unlock()
+
+	var errs []error
+	for id, session := range sessions {
+		if err := m.Store(ctx, id, session); err != nil {
+			errs = append(errs, fmt.Errorf("failed to checkpoint session %s: %w", id, err))
+		}
+	}
+	m.checkpointed.Store(true)
+	return errors.Join(errs...)
+}
+
+var _ types.SessionCheckpointer = (*Manager)(nil)
+
+// CheckpointSession stores a live session so that the progress of its runs
+// survives a restart. Sessions that aren't live are left alone.
+func (m *Manager) CheckpointSession(ctx context.Context, id string) error {
+	session := m.liveSession(id)
+	if session == nil {
+		return nil
+	}
+	return m.Store(ctx, id, session)
+}
+
+// LiveSessions returns how many sessions of the account this manager has in
+// use.
+func (m *Manager) LiveSessions(accountID string) int {
+	m.liveSessionsLock.Lock()
+	defer m.liveSessionsLock.Unlock()
+
+	var count int
+	for _, live := range m.liveSessions {
+		if live.session == nil || live.session.GetSession().Context().Err() != nil {
+			continue
+		}
+		var account string
+		live.session.GetSession().Get(types.AccountIDSessionKey, &account)
+		if account == accountID {
+			count++
+		}
+	}
+	return count
+}
+
+// Close closes the live sessions, with their clients and watchers.
+func (m *Manager) Close() {
+	m.liveSessionsLock.Lock()
+	sessions := slices.Collect(maps.Values(m.liveSessions))
+	clear(m.liveSessions)
+	m.liveSessionsLock.Unlock()
+
+	for _, live := range sessions {
+		if live.cancel != nil {
+			live.cancel()
+		}
+		live.session.Close(false)
+	}
+	m.close()
+}
+
 func (m *Manager) ExtractID(req *http.Request) string {
 	id := req.Header.Get("Mcp-Session-Id")
 	if id != "" {
@@ -196,12 +330,19 @@ func (m *Manager) Acquire(ctx context.Context, server mcp.MessageHandler, id str
 		live.count++
 		m.liveSessions[id] = live
 		m.liveSessionsLock.Unlock()
+
+		if m.distributed {
+			if err := m.refresh(ctx, live.session); err != nil {
+				m.Release(live.session)
+				return nil, false, err
+			}
+		}
 		return live.session, true, nil
 	}
 	m.liveSessionsLock.Unlock()
 
-	serverSession, ok, err := m.loadSessionFromDatabase(ctx, server, id)
-	if err != nil || !ok {
+	serverSession, stored, err := m.loadSessionFromDatabase(ctx, server, id)
+	if err != nil || stored == nil {
 		return nil, false, err
 	}
 
@@ -223,14 +364,51 @@ func (m *Manager) Acquire(ctx context.Context, server mcp.MessageHandler, id str
 		return live.session, true, nil
 	}
 	m.liveSessions[id] = liveSession{
-		session: serverSession,
-		count:   1,
+		session:  serverSession,
+		count:    1,
+		storedAt: stored.UpdatedAt,
 	}
 	m.liveSessionsLock.Unlock()
+	m.attach(serverSession)
 
 	return serverSession, true, err
 }
 
+// attach connects a session that became live to the other replicas, with the
+// event store and the broker.
+func (m *Manager) attach(session *mcp.ServerSession) {
+	if m.distributed {
+		session.SetEventStore(m.DB)
+	}
+	m.relay(session)
+}
+
+func (m *Manager) setStoredAt(id string, storedAt time.Time) {
+	m.liveSessionsLock.Lock()
+	defer m.liveSessionsLock.Unlock()
+	if live, ok := m.liveSessions[id]; ok {
+		live.storedAt = storedAt
+		m.liveSessions[id] = live
+	}
+}
+
+// refresh reloads a live session if another replica stored it since this
+// manager loaded or stored it.
+func (m *Manager) refresh(ctx context.Context, session *mcp.ServerSession) error {
+	m.liveSessionsLock.Lock()
+	storedAt := m.liveSessions[session.ID()].storedAt
+	m.liveSessionsLock.Unlock()
+
+	updatedAt, err := m.DB.UpdatedAt(ctx, session.ID())
+	if err != nil {
+		return err
+	}
+	if !updatedAt.After(storedAt) {
+		return nil
+	}
+	return m.reload(ctx, session)
+}
+
 func (m *Manager) Release(session *mcp.ServerSession) {
 	m.liveSessionsLock.Lock()
 	defer m.liveSessionsLock.Unlock()
@@ -270,12 +448,14 @@ func (m *Manager) Release(session *mcp.ServerSession) {
 	}
 }
 
-func (m *Manager) loadSessionFromDatabase(ctx context.Context, server mcp.MessageHandler, id string) (*mcp.ServerSession, bool, error) {
+// loadSessionFromDatabase loads a session and returns it with its record, or
+// no record if there is none.
+func (m *Manager) loadSessionFromDatabase(ctx context.Context, server mcp.MessageHandler, id string) (*mcp.ServerSession, *Session, error) {
 	storedSession, err := m.DB.Get(ctx, id)
 	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return nil, false, nil
+		return nil, nil, nil
 	} else if err != nil {
-		return nil, false, err
+		return nil, nil, err
 	}
 
 	if storedSession.State.Attributes == nil {
@@ -287,11 +467,11 @@ func (m *Manager) loadSessionFromDatabase(ctx context.Context, server mcp.Messag
 	serverSession, err := mcp.NewExistingServerSession(m.ctx,
 		mcp.SessionState(storedSession.State), server)
 	if err != nil {
-		return nil, false, err
+		return nil, nil, err
 	}
 
 	m.loadAttributesFromRecord(storedSession, serverSession)
-	return serverSession, true, nil
+	return serverSession, storedSession, nil
 }
 
 func (m *Manager) LoadAndDelete(ctx context.Context, server mcp.MessageHandler, id string) (*mcp.ServerSession, bool, error) {
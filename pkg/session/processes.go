@@ -0,0 +1,48 @@
+package session
+
+import (
+	"context"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"gorm.io/gorm"
+)
+
+// ServerProcess is a process of a stdio MCP server, as last reported by the
+// nanobot instance running it.
+type ServerProcess struct {
+	ID uint `json:"-" gorm:"primarykey"`
+	// Instance is the nanobot instance running the process.
+	Instance          string    `json:"instance" gorm:"index;not null"`
+	UpdatedAt         time.Time `json:"updatedAt"`
+	mcp.ProcessStatus `gorm:"embedded"`
+}
+
+// ReplaceServerProcesses replaces the processes reported by an instance.
+func (s *Store) ReplaceServerProcesses(ctx context.Context, instance string, processes []mcp.ProcessStatus) error {
+	return s.withContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("instance = ?", instance).Delete(&ServerProcess{}).Error; err != nil {
+			return err
+		}
+		if len(processes) == 0 {
+			return nil
+		}
+		rows := make([]ServerProcess, 0, len(processes))
+		for _, process := range processes {
+			rows = append(rows, ServerProcess{
+				Instance:      instance,
+				ProcessStatus: process,
+			})
+		}
+		return tx.Create(&rows).Error
+	})
+}
+
+// ListServerProcesses returns the processes reported since the time given,
+// by instance, server and session.
+func (s *Store) ListServerProcesses(ctx context.Context, since time.Time) (result []ServerProcess, _ error) {
+	return result, s.withContext(ctx).
+		Where("updated_at >= ?", since).
+		Order("instance, server, session_id").
+		Find(&result).Error
+}
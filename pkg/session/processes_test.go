@@ -0,0 +1,46 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+)
+
+func TestServerProcesses(t *testing.T) {
+	store := newTestStore(t, "processes")
+	ctx := t.Context()
+
+	if err := store.ReplaceServerProcesses(ctx, "host:1", []mcp.ProcessStatus{
+		{Server: "search", SessionID: "s1", PID: 10, State: mcp.ProcessRunning},
+		{Server: "files", SessionID: "s1", State: mcp.ProcessStopped},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.ReplaceServerProcesses(ctx, "host:2", []mcp.ProcessStatus{
+		{Server: "search", SessionID: "s2", PID: 20, State: mcp.ProcessRunning, Restarts: 2},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.ReplaceServerProcesses(ctx, "host:1", []mcp.ProcessStatus{
+		{Server: "search", SessionID: "s1", PID: 11, State: mcp.ProcessRunning, Restarts: 1},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	processes, err := store.ListServerProcesses(ctx, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(processes) != 2 || processes[0].Instance != "host:1" || processes[0].PID != 11 || processes[1].Restarts != 2 {
+		t.Errorf("expected the last report of each instance, got %+v", processes)
+	}
+
+	processes, err = store.ListServerProcesses(ctx, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(processes) != 0 {
+		t.Errorf("expected stale reports to be left out, got %+v", processes)
+	}
+}
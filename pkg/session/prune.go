@@ -0,0 +1,250 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/types"
+	"gorm.io/gorm"
+)
+
+const defaultGCInterval = time.Hour
+
+type PruneOptions struct {
+	// MaxAge removes sessions that weren't updated for this long. Zero keeps
+	// them.
+	MaxAge time.Duration
+	// MaxDiskBytes removes the least recently updated sessions while the
+	// files of all sessions take more than this many bytes. Zero doesn't limit
+	// them.
+	MaxDiskBytes int64
+	// DryRun reports the sessions that would be removed without removing
+	// them.
+	DryRun bool
+}
+
+// PruneOptionsFromRetention returns the options to prune sessions by the
+// retention settings of the config.
+func PruneOptionsFromRetention(retention types.RetentionSettings) PruneOptions {
+	return PruneOptions{
+		MaxAge:       time.Duration(retention.MaxAgeHours) * time.Hour,
+		MaxDiskBytes: int64(retention.MaxDiskMB) << 20,
+	}
+}
+
+type PrunedSession struct {
+	SessionID   string    `json:"sessionId"`
+	Description string    `json:"description,omitempty"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+	// Bytes is the size of the session's files.
+	Bytes int64 `json:"bytes"`
+	// Reason is why the session was removed, maxAge or maxDisk.
+	Reason string `json:"reason"`
+}
+
+type PruneResult struct {
+	Removed []PrunedSession `json:"removed,omitempty"`
+	// FreedBytes is the size of the files of the removed sessions.
+	FreedBytes int64 `json:"freedBytes"`
+	// DiskBytes is the size of the files of the sessions that were kept.
+	DiskBytes int64 `json:"diskBytes"`
+}
+
+// Prune removes old sessions with their files. Sessions in use by this
+// manager are kept.
+func (m *Manager) Prune(ctx context.Context, opts PruneOptions) (*PruneResult, error) {
+	if opts.MaxAge <= 0 && opts.MaxDiskBytes <= 0 {
+		return &PruneResult{}, nil
+	}
+
+	sessions, err := m.DB.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Oldest first, they are removed first.
+	slices.Reverse(sessions)
+
+	type candidate struct {
+		session *Session
+		bytes   int64
+	}
+	var (
+		result     PruneResult
+		candidates []candidate
+		cutoff     = time.Now().Add(-opts.MaxAge)
+	)
+	for i := range sessions {
+		s := &sessions[i]
+		if m.liveSession(s.SessionID) != nil {
+			continue
+		}
+
+		size, err := sessionDiskUsage(s.Cwd, s.SessionID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the size of session %s: %w", s.SessionID, err)
+		}
+
+		if opts.MaxAge > 0 && s.UpdatedAt.Before(cutoff) {
+			if err := m.prune(ctx, s, size, "maxAge", opts.DryRun, &result); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		result.DiskBytes += size
+		candidates = append(candidates, candidate{session: s, bytes: size})
+	}
+
+	for _, c := range candidates {
+		if opts.MaxDiskBytes <= 0 || result.DiskBytes <= opts.MaxDiskBytes {
+			break
+		}
+		if c.bytes == 0 {
+			continue
+		}
+		if err := m.prune(ctx, c.session, c.bytes, "maxDisk", opts.DryRun, &result); err != nil {
+			return nil, err
+		}
+		result.DiskBytes -= c.bytes
+	}
+
+	return &result, nil
+}
+
+func (m *Manager) prune(ctx context.Context, s *Session, size int64, reason string, dryRun bool, result *PruneResult) error {
+	if !dryRun {
+		if err := m.DB.Purge(ctx, s.SessionID); err != nil {
+			return fmt.Errorf("failed to remove session %s: %w", s.SessionID, err)
+		}
+		removeSessionFiles(s.Cwd, s.SessionID)
+	}
+
+	result.Removed = append(result.Removed, PrunedSession{
+		SessionID:   s.SessionID,
+		Description: s.Description,
+		UpdatedAt:   s.UpdatedAt,
+		Bytes:       size,
+		Reason:      reason,
+	})
+	result.FreedBytes += size
+	return nil
+}
+
+// RunGC prunes sessions by the retention settings until the context or the
+// manager is done.
+func (m *Manager) RunGC(ctx context.Context, retention types.RetentionSettings) {
+	opts := PruneOptionsFromRetention(retention)
+	if opts.MaxAge <= 0 && opts.MaxDiskBytes <= 0 {
+		return
+	}
+
+	interval := defaultGCInterval
+	if retention.IntervalMinutes > 0 {
+		interval = time.Duration(retention.IntervalMinutes) * time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		result, err := m.Prune(ctx, opts)
+		if err != nil {
+			slog.Error("failed to prune sessions", "error", err)
+		} else if len(result.Removed) > 0 {
+			slog.Info("pruned sessions", "removed", len(result.Removed), "freedBytes", result.FreedBytes, "diskBytes", result.DiskBytes)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DiskUsage returns the size of the files of all the sessions of the account,
+// and of the session, which counts even if it isn't saved yet. A session
+// without an account is measured on its own.
+func (m *Manager) DiskUsage(ctx context.Context, accountID, sessionID string) (account, session int64, _ error) {
+	if accountID == "" {
+		session, err := sessionDiskUsage("", sessionID)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to get the size of session %s: %w", sessionID, err)
+		}
+		return session, session, nil
+	}
+
+	sessions, err := m.DB.FindAllByAccount(ctx, accountID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var found bool
+	for _, s := range sessions {
+		size, err := sessionDiskUsage(s.Cwd, s.SessionID)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to get the size of session %s: %w", s.SessionID, err)
+		}
+		account += size
+		if s.SessionID == sessionID {
+			session, found = size, true
+		}
+	}
+	if !found {
+		if session, err = sessionDiskUsage("", sessionID); err != nil {
+			return 0, 0, fmt.Errorf("failed to get the size of session %s: %w", sessionID, err)
+		}
+		account += session
+	}
+	return account, session, nil
+}
+
+// sessionDiskUsage returns the size of the files of a session.
+func sessionDiskUsage(cwd, id string) (size int64, _ error) {
+	cwd = sessionCwd(cwd)
+	for _, dir := range sessionDirs {
+		err := filepath.WalkDir(filepath.Join(cwd, dir, id), func(_ string, d fs.DirEntry, err error) error {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			} else if err != nil || !d.Type().IsRegular() {
+				return err
+			}
+			info, err := d.Info()
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			size += info.Size()
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	return size, nil
+}
+
+// Purge removes a session for good, with its search index, events, and
+// workflow runs, unlike Delete which keeps the record.
+func (s *Store) Purge(ctx context.Context, id string) error {
+	if id == "" {
+		return fmt.Errorf("session ID cannot be empty")
+	}
+	return s.withContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, model := range []any{&SessionMessage{}, &SessionEvent{}, &WorkflowRun{}, &WorkflowExecution{}, &ChannelThread{}, &Session{}} {
+			if err := tx.Unscoped().Where("session_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,79 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrune(t *testing.T) {
+	store := newTestStore(t, "prune")
+	ctx := t.Context()
+	cwd := t.TempDir()
+
+	now := time.Now()
+	for _, s := range []struct {
+		id      string
+		updated time.Time
+		size    int
+	}{
+		{"ancient", now.Add(-100 * time.Hour), 10},
+		{"old", now.Add(-10 * time.Hour), 300},
+		{"recent", now.Add(-time.Hour), 200},
+		{"new", now, 100},
+	} {
+		record := &Session{SessionID: s.id, Cwd: cwd, State: State{ID: s.id}}
+		if err := store.Create(ctx, record); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.db.Model(record).UpdateColumn("updated_at", s.updated).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		file := filepath.Join(cwd, "sessions", s.id, "truncated-outputs", "out.txt")
+		if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(strings.Repeat("x", s.size)), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manager := NewManager(store)
+	opts := PruneOptions{MaxAge: 24 * time.Hour, MaxDiskBytes: 350, DryRun: true}
+
+	result, err := manager.Prune(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Removed) != 2 || result.Removed[0].SessionID != "ancient" || result.Removed[0].Reason != "maxAge" ||
+		result.Removed[1].SessionID != "old" || result.Removed[1].Reason != "maxDisk" {
+		t.Fatalf("unexpected removed sessions %+v", result.Removed)
+	}
+	if result.FreedBytes != 310 || result.DiskBytes != 300 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if _, err := store.Get(ctx, "ancient"); err != nil {
+		t.Errorf("expected a dry run to keep the session, got %v", err)
+	}
+
+	opts.DryRun = false
+	if _, err := manager.Prune(ctx, opts); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"ancient", "old"} {
+		if _, err := store.Get(ctx, id); err == nil {
+			t.Errorf("expected session %s to be removed", id)
+		}
+		if _, err := os.Stat(filepath.Join(cwd, "sessions", id)); !os.IsNotExist(err) {
+			t.Errorf("expected the files of session %s to be removed, got %v", id, err)
+		}
+	}
+	for _, id := range []string{"recent", "new"} {
+		if _, err := store.Get(ctx, id); err != nil {
+			t.Errorf("expected session %s to be kept, got %v", id, err)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	// snippetRunes is the length of a snippet on databases without full-text
+	// search, around the first match.
+	snippetRunes = 160
+)
+
+// SessionMessage is the searchable text of a message of a session, with the
+// tool calls and results of the message. On SQLite it is indexed by the
+// session_messages_fts full-text index.
+type SessionMessage struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	SessionID string    `json:"sessionId" gorm:"uniqueIndex:idx_session_messages_message;not null"`
+	MessageID string    `json:"messageId" gorm:"uniqueIndex:idx_session_messages_message;not null"`
+	AccountID string    `json:"accountId,omitempty" gorm:"index"`
+	Role      string    `json:"role,omitempty"`
+	Created   time.Time `json:"created"`
+	Content   string    `json:"content" gorm:"type:text"`
+}
+
+type SearchResult struct {
+	SessionID   string    `json:"sessionId"`
+	Description string    `json:"description,omitempty"`
+	MessageID   string    `json:"messageId"`
+	Role        string    `json:"role,omitempty"`
+	Created     time.Time `json:"created"`
+	// Snippet is the text around the matches, which are in bold.
+	Snippet string `json:"snippet"`
+}
+
+var sqliteSearchSetup = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS session_messages_fts USING fts5(content, content='session_messages', content_rowid='id')`,
+	`CREATE TRIGGER IF NOT EXISTS session_messages_ai AFTER INSERT ON session_messages BEGIN
+		INSERT INTO session_messages_fts(rowid, content) VALUES (new.id, new.content);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS session_messages_ad AFTER DELETE ON session_messages BEGIN
+		INSERT INTO session_messages_fts(session_messages_fts, rowid, content) VALUES ('delete', old.id, old.content);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS session_messages_au AFTER UPDATE ON session_messages BEGIN
+		INSERT INTO session_messages_fts(session_messages_fts, rowid, content) VALUES ('delete', old.id, old.content);
+		INSERT INTO session_messages_fts(rowid, content) VALUES (new.id, new.content);
+	END`,
+}
+
+func isSQLite(db *gorm.DB) bool {
+	return db.Dialector.Name() == "sqlite"
+}
+
+// migrateSessionSearch creates the search index. The messages of existing
+// sessions are indexed when the index is new, later ones are indexed as the
+// sessions are stored. Messages aren't indexed when sessions are encrypted,
+// the index would keep them in plain text, and the messages indexed before
+// encryption was enabled are removed from it.
+func migrateSessionSearch(tx *gorm.DB, encrypted bool) error {
+	exists := tx.Migrator().HasTable(&SessionMessage{})
+	if err := tx.AutoMigrate(&SessionMessage{}); err != nil {
+		return err
+	}
+
+	if isSQLite(tx) {
+		for _, stmt := range sqliteSearchSetup {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return fmt.Errorf("failed to create full-text index: %w", err)
+			}
+		}
+	}
+
+	if encrypted {
+		return purgeSessionSearch(tx)
+	}
+	if exists {
+		return nil
+	}
+
+	var sessions []Session
+	return tx.FindInBatches(&sessions, 100, func(tx *gorm.DB, _ int) error {
+		for i := range sessions {
+			if err := indexMessages(tx, &sessions[i]); err != nil {
+				return fmt.Errorf("failed to index the messages of session %s: %w", sessions[i].SessionID, err)
+			}
+		}
+		return nil
+	}).Error
+}
+
+// purgeSessionSearch removes every message from the search index.
+func purgeSessionSearch(tx *gorm.DB) error {
+	if err := tx.Where("1 = 1").Delete(&SessionMessage{}).Error; err != nil {
+		return fmt.Errorf("failed to remove the indexed messages: %w", err)
+	}
+	if isSQLite(tx) {
+		// Rebuilding the full-text index from the now empty messages drops
+		// whatever text it still holds.
+		if err := tx.Exec(`INSERT INTO session_messages_fts(session_messages_fts) VALUES ('rebuild')`).Error; err != nil {
+			return fmt.Errorf("failed to rebuild full-text index: %w", err)
+		}
+	}
+	return nil
+}
+
+// IndexMessages adds the messages of a session that aren't indexed yet to the
+// search index. Nothing is indexed when sessions are encrypted.
+func (s *Store) IndexMessages(ctx context.Context, session *Session) error {
+	if s.encryption != nil {
+		return nil
+	}
+	return indexMessages(s.withContext(ctx), session)
+}
+
+func indexMessages(db *gorm.DB, session *Session) error {
+	messages, err := stateMessages(mcp.SessionState(session.State))
+	if err != nil || len(messages) == 0 {
+		return err
+	}
+
+	var indexed []string
+	if err := db.Model(&SessionMessage{}).Where("session_id = ?", session.SessionID).
+		Pluck("message_id", &indexed).Error; err != nil {
+		return err
+	}
+	seen := make(map[string]struct{}, len(indexed))
+	for _, id := range indexed {
+		seen[id] = struct{}{}
+	}
+
+	var rows []SessionMessage
+	for _, msg := range messages {
+		if _, ok := seen[msg.ID]; ok || msg.ID == "" {
+			continue
+		}
+		seen[msg.ID] = struct{}{}
+
+		content := searchableText(msg)
+		if content == "" {
+			continue
+		}
+		row := SessionMessage{
+			SessionID: session.SessionID,
+			MessageID: msg.ID,
+			AccountID: session.AccountID,
+			Role:      msg.Role,
+			Created:   session.UpdatedAt,
+			Content:   content,
+		}
+		if msg.Created != nil {
+			row.Created = *msg.Created
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	return db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(rows, 100).Error
+}
+
+// searchableText returns the text of a message, its tool calls, and their
+// results.
+func searchableText(msg types.Message) string {
+	var parts []string
+	addContent := func(content mcp.Content) {
+		switch {
+		case content.Text != "":
+			parts = append(parts, content.Text)
+		case content.Resource != nil && content.Resource.Text != "":
+			parts = append(parts, content.Resource.Text)
+		}
+	}
+
+	for _, item := range msg.Items {
+		if item.Content != nil {
+			addContent(*item.Content)
+		}
+		if item.ToolCall != nil {
+			parts = append(parts, item.ToolCall.Name+" "+item.ToolCall.Arguments)
+		}
+		if item.ToolCallResult != nil {
+			for _, content := range item.ToolCallResult.Output.Content {
+				addContent(content)
+			}
+		}
+	}
+	return strings.TrimSpace(strings.Join(parts, "\n"))
+}
+
+// SearchMessages searches the messages and tool results of the sessions of an
+// account for all the words of the query, best matches first on SQLite and
+// newest first elsewhere.
+func (s *Store) SearchMessages(ctx context.Context, accountID, query string, limit int) ([]SearchResult, error) {
+	terms := strings.Fields(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	limit = min(limit, maxSearchLimit)
+
+	if s.encryption != nil {
+		return nil, errors.New("search is not available when sessions are encrypted")
+	}
+
+	db := s.withContext(ctx)
+	var results []SearchResult
+
+	if isSQLite(db) {
+		// Quote the terms so they aren't read as FTS5 query syntax.
+		quoted := make([]string, 0, len(terms))
+		for _, term := range terms {
+			quoted = append(quoted, `"`+strings.ReplaceAll(term, `"`, `""`)+`"`)
+		}
+
+		err := db.Raw(`SELECT m.session_id, s.description, m.message_id, m.role, m.created,
+				snippet(session_messages_fts, 0, '**', '**', '…', 16) AS snippet
+			FROM session_messages_fts
+			JOIN session_messages m ON m.id = session_messages_fts.rowid
+			JOIN sessions s ON s.session_id = m.session_id AND s.deleted_at IS NULL
+			WHERE session_messages_fts MATCH ? AND m.account_id = ?
+			ORDER BY rank
+			LIMIT ?`, strings.Join(quoted, " "), accountID, limit).
+			Scan(&results).Error
+		return results, err
+	}
+
+	tx := db.Table("session_messages m").
+		Select("m.session_id, s.description, m.message_id, m.role, m.created, m.content AS snippet").
+		Joins("JOIN sessions s ON s.session_id = m.session_id AND s.deleted_at IS NULL").
+		Where("m.account_id = ?", accountID)
+	for _, term := range terms {
+		tx = tx.Where("LOWER(m.content) LIKE ?", "%"+escapeLike(strings.ToLower(term))+"%")
+	}
+	if err := tx.Order("m.created desc").Limit(limit).Scan(&results).Error; err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Snippet = snippet(results[i].Snippet, terms[0])
+	}
+	return results, nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// snippet returns the text around the first match of term, the way FTS5's
+// snippet function does.
+func snippet(content, term string) string {
+	match := regexp.MustCompile("(?i)" + regexp.QuoteMeta(term)).FindStringIndex(content)
+	if match == nil {
+		return truncateRunes(content, snippetRunes)
+	}
+
+	start := match[0]
+	for n := 0; start > 0 && n < snippetRunes/2; n++ {
+		_, size := utf8.DecodeLastRuneInString(content[:start])
+		start -= size
+	}
+	result := content[start:match[0]] + "**" + content[match[0]:match[1]] + "**" + truncateRunes(content[match[1]:], snippetRunes/2)
+	if start > 0 {
+		result = "…" + result
+	}
+	return result
+}
+
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "…"
+}
@@ -0,0 +1,150 @@
+package session
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/obot-platform/nanobot/pkg/encryption"
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+func textMessage(id, role, text string) types.Message {
+	return types.Message{
+		ID:    id,
+		Role:  role,
+		Items: []types.CompletionItem{{Content: &mcp.Content{Type: "text", Text: text}}},
+	}
+}
+
+func chatState(id string, messages ...types.Message) State {
+	return State{
+		ID: id,
+		Attributes: map[string]any{
+			types.PreviousExecutionKey: &types.Execution{
+				PopulatedRequest: &types.CompletionRequest{Input: messages},
+			},
+		},
+	}
+}
+
+func TestSearchMessages(t *testing.T) {
+	store := newTestStore(t, "search")
+	ctx := t.Context()
+
+	deploy := &Session{
+		SessionID:   "deploy",
+		Description: "Deploying",
+		AccountID:   "account",
+		State: chatState("deploy",
+			textMessage("m1", "user", "How do I deploy the billing service?"),
+			types.Message{ID: "m2", Role: "assistant", Items: []types.CompletionItem{{
+				ToolCall: &types.ToolCall{CallID: "c1", Name: "kubectl", Arguments: `{"args":"rollout status"}`},
+			}}},
+			types.Message{ID: "m3", Role: "user", Items: []types.CompletionItem{{
+				ToolCallResult: &types.ToolCallResult{CallID: "c1", Output: types.CallResult{
+					Content: []mcp.Content{{Type: "text", Text: "deployment billing-api successfully rolled out"}},
+				}},
+			}}},
+		),
+	}
+	other := &Session{
+		SessionID: "other",
+		AccountID: "someone-else",
+		State:     chatState("other", textMessage("m1", "user", "billing report")),
+	}
+	for _, s := range []*Session{deploy, other} {
+		if err := store.Create(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.IndexMessages(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Indexing again only adds the new messages.
+	deploy.State = chatState("deploy",
+		textMessage("m1", "user", "How do I deploy the billing service?"),
+		textMessage("m4", "user", "And roll it back?"),
+	)
+	if err := store.IndexMessages(ctx, deploy); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := store.SearchMessages(ctx, "account", "billing", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %+v", results)
+	}
+	for _, result := range results {
+		if result.SessionID != "deploy" || result.Description != "Deploying" || !strings.Contains(result.Snippet, "**billing") {
+			t.Errorf("unexpected result %+v", result)
+		}
+	}
+
+	results, err = store.SearchMessages(ctx, "account", `"roll" back`, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].MessageID != "m4" {
+		t.Errorf("expected the new message to be found, got %+v", results)
+	}
+
+	if err := store.Delete(ctx, "deploy"); err != nil {
+		t.Fatal(err)
+	}
+	if results, err := store.SearchMessages(ctx, "account", "billing", 0); err != nil || len(results) != 0 {
+		t.Errorf("expected no results for a deleted session, got %+v, %v", results, err)
+	}
+}
+
+func TestSearchIndexPurgedWhenEncrypted(t *testing.T) {
+	store := newTestStore(t, "search")
+	ctx := t.Context()
+
+	session := &Session{
+		SessionID: "plain",
+		AccountID: "account",
+		State:     chatState("plain", textMessage("m1", "user", "the launch codes are 1234")),
+	}
+	if err := store.Create(ctx, session); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.IndexMessages(ctx, session); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := encryption.NewLocalKey("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted := newTestStore(t, "search", StoreOptions{Encryption: encryption.New(key)})
+
+	var count int64
+	if err := encrypted.db.Model(&SessionMessage{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("expected the indexed messages to be removed, got %d", count)
+	}
+	var matches int64
+	if err := encrypted.db.Raw(`SELECT count(*) FROM session_messages_fts WHERE session_messages_fts MATCH 'launch'`).Scan(&matches).Error; err != nil {
+		t.Fatal(err)
+	}
+	if matches != 0 {
+		t.Errorf("expected the full-text index to be empty, got %d matches", matches)
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	content := strings.Repeat("padding ", 20) + "the Needle in the haystack"
+	got := snippet(content, "needle")
+	if !strings.HasPrefix(got, "…") || !strings.Contains(got, "**Needle**") || !strings.HasSuffix(got, "haystack") {
+		t.Errorf("unexpected snippet %q", got)
+	}
+	if got := snippet("no match", "needle"); got != "no match" {
+		t.Errorf("unexpected snippet %q", got)
+	}
+}
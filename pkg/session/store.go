@@ -2,13 +2,14 @@ package session
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/obot-platform/nanobot/pkg/complete"
+	"github.com/obot-platform/nanobot/pkg/encryption"
 	"github.com/obot-platform/nanobot/pkg/gormdsn"
 	"github.com/obot-platform/nanobot/pkg/mcp"
 	"github.com/obot-platform/nanobot/pkg/types"
@@ -23,14 +24,29 @@ const (
 )
 
 type Store struct {
-	db *gorm.DB
+	db         *gorm.DB
+	encryption *encryption.Envelope
 }
 
-func NewStore(db *gorm.DB) *Store {
-	return &Store{db: db}
+type StoreOptions struct {
+	// Encryption seals the state and config of sessions before they are
+	// written. Sessions stored without it are still read.
+	Encryption *encryption.Envelope
 }
 
-func NewStoreFromDSN(dsn string) (store *Store, err error) {
+func (o StoreOptions) Merge(other StoreOptions) (result StoreOptions) {
+	result.Encryption = complete.Last(o.Encryption, other.Encryption)
+	return
+}
+
+func NewStore(db *gorm.DB, opts ...StoreOptions) *Store {
+	opt := complete.Complete(opts...)
+	return &Store{db: db, encryption: opt.Encryption}
+}
+
+func NewStoreFromDSN(dsn string, opts ...StoreOptions) (store *Store, err error) {
+	opt := complete.Complete(opts...)
+
 	db, err := gormdsn.NewDBFromDSN(dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create database connection: %w", err)
@@ -45,7 +61,7 @@ func NewStoreFromDSN(dsn string) (store *Store, err error) {
 		}
 	}()
 
-	if err := tx.AutoMigrate(&Session{}, &Token{}, &WorkflowRun{}, &ScheduledTask{}); err != nil {
+	if err := tx.AutoMigrate(&Session{}, &Token{}, &WorkflowRun{}, &ScheduledTask{}, &Memory{}, &SessionEvent{}, &ToolCallAudit{}, &Account{}, &WorkflowExecution{}, &ChannelThread{}, &ServerProcess{}); err != nil {
 		return nil, fmt.Errorf("failed to migrate schema: %w", err)
 	}
 
@@ -53,7 +69,30 @@ func NewStoreFromDSN(dsn string) (store *Store, err error) {
 		return nil, fmt.Errorf("failed to migrate session workflow URIs: %w", err)
 	}
 
-	return &Store{db: db}, nil
+	if err := migrateSessionSearch(tx, opt.Encryption != nil); err != nil {
+		return nil, fmt.Errorf("failed to migrate session search: %w", err)
+	}
+
+	return &Store{db: db, encryption: opt.Encryption}, nil
+}
+
+// Encryption returns the envelope that seals the sessions of the store, nil if
+// they aren't encrypted.
+func (s *Store) Encryption() *encryption.Envelope {
+	return s.encryption
+}
+
+// Ping checks that the database of the store is reachable.
+func (s *Store) Ping(ctx context.Context) error {
+	db, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return db.PingContext(ctx)
+}
+
+func (s *Store) withContext(ctx context.Context) *gorm.DB {
+	return s.db.WithContext(encryption.WithEnvelope(ctx, s.encryption))
 }
 
 func (s *Store) Create(ctx context.Context, session *Session) error {
@@ -70,20 +109,20 @@ func (s *Store) Create(ctx context.Context, session *Session) error {
 	if session.Type == "" {
 		session.Type = "thread"
 	}
-	return s.db.WithContext(ctx).Create(session).Error
+	return s.withContext(ctx).Create(session).Error
 }
 
 func (s *Store) Update(ctx context.Context, session *Session) error {
-	return s.db.WithContext(ctx).Save(session).Error
+	return s.withContext(ctx).Save(session).Error
 }
 
 func (s *Store) FindByPrefix(ctx context.Context, sessionIDPrefix string) ([]Session, error) {
 	var sessions []Session
 	if sessionIDPrefix == "last" {
-		err := s.db.WithContext(ctx).Order("updated_at desc").First(&sessions).Error
+		err := s.withContext(ctx).Order("updated_at desc").First(&sessions).Error
 		return sessions, err
 	}
-	err := s.db.WithContext(ctx).Where("session_id LIKE ?", sessionIDPrefix+"%").Find(&sessions).Error
+	err := s.withContext(ctx).Where("session_id LIKE ?", sessionIDPrefix+"%").Find(&sessions).Error
 	if err != nil {
 		return nil, err
 	}
@@ -94,24 +133,24 @@ func (s *Store) Delete(ctx context.Context, id string) error {
 	if id == "" {
 		return fmt.Errorf("session ID cannot be empty")
 	}
-	return s.db.WithContext(ctx).Where("session_id = ?", id).Delete(&Session{}).Error
+	return s.withContext(ctx).Where("session_id = ?", id).Delete(&Session{}).Error
 }
 
 func (s *Store) Get(ctx context.Context, id string) (*Session, error) {
 	var session Session
-	err := s.db.WithContext(ctx).Where("session_id = ?", id).First(&session).Error
+	err := s.withContext(ctx).Where("session_id = ?", id).First(&session).Error
 	return &session, err
 }
 
 func (s *Store) GetByIDByAccountID(ctx context.Context, id, accountID string) (*Session, error) {
 	var session Session
-	err := s.db.WithContext(ctx).Where("session_id = ? and account_id = ?", id, accountID).First(&session).Error
+	err := s.withContext(ctx).Where("session_id = ? and account_id = ?", id, accountID).First(&session).Error
 	return &session, err
 }
 
 func (s *Store) FindByAccount(ctx context.Context, sessionType, accountID string) ([]Session, error) {
 	var sessions []Session
-	err := s.db.WithContext(ctx).Where("type = ? and account_id = ?", sessionType, accountID).
+	err := s.withContext(ctx).Where("type = ? and account_id = ?", sessionType, accountID).
 		Order("created_at desc").Find(&sessions).Error
 	if err != nil {
 		return nil, err
@@ -119,17 +158,56 @@ func (s *Store) FindByAccount(ctx context.Context, sessionType, accountID string
 	return sessions, nil
 }
 
+// FindAllByAccount returns the sessions of an account of any type.
+func (s *Store) FindAllByAccount(ctx context.Context, accountID string) ([]Session, error) {
+	var sessions []Session
+	if err := s.withContext(ctx).Where("account_id = ?", accountID).Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// ListMemories returns the memories of an account ordered newest-first.
+func (s *Store) ListMemories(ctx context.Context, accountID string) ([]Memory, error) {
+	var memories []Memory
+	err := s.withContext(ctx).
+		Where("account_id = ?", accountID).
+		Order("created_at desc").
+		Find(&memories).Error
+	return memories, err
+}
+
+// CreateMemory inserts a new memory record.
+func (s *Store) CreateMemory(ctx context.Context, memory *Memory) error {
+	return s.withContext(ctx).Create(memory).Error
+}
+
+// DeleteMemory deletes a memory of an account, returning
+// gorm.ErrRecordNotFound if the account has no memory with the ID.
+func (s *Store) DeleteMemory(ctx context.Context, accountID string, id uint) error {
+	result := s.withContext(ctx).
+		Where("id = ? AND account_id = ?", id, accountID).
+		Delete(&Memory{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
 // GetScheduledTask returns a scheduled task by its task URI.
 func (s *Store) GetScheduledTask(ctx context.Context, taskURI string) (*ScheduledTask, error) {
 	var task ScheduledTask
-	err := s.db.WithContext(ctx).Where("task_uri = ?", taskURI).First(&task).Error
+	err := s.withContext(ctx).Where("task_uri = ?", taskURI).First(&task).Error
 	return &task, err
 }
 
 // ListScheduledTasks returns all scheduled tasks ordered newest-first.
 func (s *Store) ListScheduledTasks(ctx context.Context) ([]ScheduledTask, error) {
 	var tasks []ScheduledTask
-	err := s.db.WithContext(ctx).
+	err := s.withContext(ctx).
 		Order("created_at desc").
 		Find(&tasks).Error
 	return tasks, err
@@ -137,21 +215,75 @@ func (s *Store) ListScheduledTasks(ctx context.Context) ([]ScheduledTask, error)
 
 // CreateScheduledTask inserts a new scheduled task record.
 func (s *Store) CreateScheduledTask(ctx context.Context, task *ScheduledTask) error {
-	return s.db.WithContext(ctx).Create(task).Error
+	return s.withContext(ctx).Create(task).Error
 }
 
 // UpdateScheduledTask persists definition changes to an existing scheduled task.
 // Only writes configuration columns — never touches LastRunAt.
 func (s *Store) UpdateScheduledTask(ctx context.Context, task *ScheduledTask) error {
-	return s.db.WithContext(ctx).
+	return s.withContext(ctx).
 		Model(task).
-		Select("Name", "Prompt", "Schedule", "Timezone", "Enabled", "ExpiresAt", "NextRunAt").
+		Select("Name", "Prompt", "Agent", "WorkflowURI", "Inputs", "Webhook", "Schedule", "Timezone", "Enabled", "ExpiresAt", "NextRunAt").
 		Updates(task).Error
 }
 
+// SyncConfigScheduledTasks makes the scheduled tasks with the config source
+// the given tasks, creating and updating them by task URI and deleting the
+// ones that aren't given anymore. It returns the URIs of the deleted tasks.
+func (s *Store) SyncConfigScheduledTasks(ctx context.Context, tasks []ScheduledTask) (deleted []string, _ error) {
+	return deleted, s.withContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing []ScheduledTask
+		if err := tx.Unscoped().Where("source = ?", ScheduledTaskSourceConfig).Find(&existing).Error; err != nil {
+			return err
+		}
+
+		keep := make(map[string]bool, len(tasks))
+		for _, task := range tasks {
+			keep[task.TaskURI] = true
+		}
+		for _, task := range existing {
+			if keep[task.TaskURI] {
+				continue
+			}
+			if err := tx.Unscoped().Delete(&task).Error; err != nil {
+				return err
+			}
+			if !task.DeletedAt.Valid {
+				deleted = append(deleted, task.TaskURI)
+			}
+		}
+
+		for _, task := range tasks {
+			task.Source = ScheduledTaskSourceConfig
+
+			var current ScheduledTask
+			err := tx.Unscoped().Where("task_uri = ?", task.TaskURI).First(&current).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				if err := tx.Create(&task).Error; err != nil {
+					return err
+				}
+				continue
+			} else if err != nil {
+				return err
+			}
+			if current.Source != ScheduledTaskSourceConfig {
+				return fmt.Errorf("scheduled task %s already exists", task.TaskURI)
+			}
+
+			task.ID = current.ID
+			task.CreatedAt = current.CreatedAt
+			task.LastRunAt = current.LastRunAt
+			if err := tx.Unscoped().Save(&task).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 // RecordScheduledTaskRun records when a scheduled task last ran and when it will next run.
 func (s *Store) RecordScheduledTaskRun(ctx context.Context, taskURI string, lastRunAt time.Time, nextRunAt *time.Time) error {
-	return s.db.WithContext(ctx).
+	return s.withContext(ctx).
 		Model(&ScheduledTask{}).
 		Where("task_uri = ?", taskURI).
 		Updates(map[string]any{
@@ -162,7 +294,7 @@ func (s *Store) RecordScheduledTaskRun(ctx context.Context, taskURI string, last
 
 // DeleteScheduledTask deletes a scheduled task by its task URI.
 func (s *Store) DeleteScheduledTask(ctx context.Context, taskURI string) error {
-	return s.db.WithContext(ctx).
+	return s.withContext(ctx).
 		Where("task_uri = ?", taskURI).
 		Delete(&ScheduledTask{}).Error
 }
@@ -193,7 +325,7 @@ func (s *Store) NextScheduledTaskURI(ctx context.Context, name string) (string,
 
 	prefix := "task:///"
 	var ids []string
-	err := s.db.WithContext(ctx).
+	err := s.withContext(ctx).
 		Model(&ScheduledTask{}).
 		Where("task_uri = ? OR task_uri LIKE ?", prefix+slug, prefix+slug+"-%").
 		Pluck("task_uri", &ids).Error
@@ -223,7 +355,7 @@ func (s *Store) ListWorkflowURIs(ctx context.Context, sessionIDs ...string) (map
 	}
 
 	var runs []WorkflowRun
-	err := s.db.WithContext(ctx).
+	err := s.withContext(ctx).
 		Where("session_id IN ?", sessionIDs).
 		Order("session_id ASC, workflow_uri ASC").
 		Find(&runs).Error
@@ -253,41 +385,38 @@ func (s *Store) AddWorkflowRun(ctx context.Context, sessionID, workflowURI strin
 		WorkflowURI: workflowURI,
 	}
 
-	return s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&run).Error
+	return s.withContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&run).Error
 }
 
 func (s *Store) List(ctx context.Context) ([]Session, error) {
 	var sessions []Session
-	err := s.db.WithContext(ctx).Order("updated_at desc").Find(&sessions).Error
+	err := s.withContext(ctx).Order("updated_at desc").Find(&sessions).Error
 	return sessions, err
 }
 
 func (s *Store) GetTokenConfig(ctx context.Context, url string) (*oauth2.Config, *oauth2.Token, error) {
 	var (
-		accountID    string
-		token        Token
-		oauth2Config oauth2.Config
-		oauth2Token  oauth2.Token
+		accountID string
+		token     Token
 	)
 	session := mcp.SessionFromContext(ctx)
 	if !session.Get(types.AccountIDSessionKey, &accountID) {
 		return nil, nil, nil
 	}
-	err := s.db.WithContext(ctx).Where("account_id = ? and url = ?", accountID, url).First(&token).Error
+	err := s.withContext(ctx).Where("account_id = ? and url = ?", accountID, url).First(&token).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, nil, nil
 	} else if err != nil {
 		return nil, nil, err
 	}
 
-	err = json.Unmarshal([]byte(token.Data), &struct {
-		Config *oauth2.Config `json:"config,omitempty"`
-		Token  *oauth2.Token  `json:"token,omitempty"`
-	}{
-		Config: &oauth2Config,
-		Token:  &oauth2Token,
-	})
-	return &oauth2Config, &oauth2Token, err
+	if token.Data.Config == nil {
+		token.Data.Config = &oauth2.Config{}
+	}
+	if token.Data.Token == nil {
+		token.Data.Token = &oauth2.Token{}
+	}
+	return token.Data.Config, token.Data.Token, nil
 }
 
 func (s *Store) SetTokenConfig(ctx context.Context, url string, oauth2Config *oauth2.Config, oauth2token *oauth2.Token) error {
@@ -300,7 +429,7 @@ func (s *Store) SetTokenConfig(ctx context.Context, url string, oauth2Config *oa
 		return fmt.Errorf("account ID not found in session")
 	}
 
-	err := s.db.WithContext(ctx).Where("account_id = ? and url = ?", accountID, url).First(&token).Error
+	err := s.withContext(ctx).Where("account_id = ? and url = ?", accountID, url).First(&token).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		token = Token{
 			AccountID: accountID,
@@ -310,19 +439,14 @@ func (s *Store) SetTokenConfig(ctx context.Context, url string, oauth2Config *oa
 		return fmt.Errorf("failed to get token: %w", err)
 	}
 
-	tokenData, err := json.Marshal(map[string]any{
-		"config": oauth2Config,
-		"token":  oauth2token,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to marshal token data: %w", err)
+	token.Data = TokenData{
+		Config: oauth2Config,
+		Token:  oauth2token,
 	}
-
-	token.Data = string(tokenData)
 	if token.ID == 0 {
-		return s.db.WithContext(ctx).Create(&token).Error
+		return s.withContext(ctx).Create(&token).Error
 	}
-	return s.db.WithContext(ctx).Save(&token).Error
+	return s.withContext(ctx).Save(&token).Error
 }
 
 func (s *Store) DeleteTokenConfig(ctx context.Context, url string) error {
@@ -332,5 +456,5 @@ func (s *Store) DeleteTokenConfig(ctx context.Context, url string) error {
 		return nil
 	}
 
-	return s.db.WithContext(ctx).Where("account_id = ? AND url = ?", accountID, url).Delete(&Token{}).Error
+	return s.withContext(ctx).Where("account_id = ? AND url = ?", accountID, url).Delete(&Token{}).Error
 }
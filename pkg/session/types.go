@@ -1,16 +1,25 @@
 package session
 
 import (
+	"context"
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"time"
 
+	"github.com/obot-platform/nanobot/pkg/encryption"
 	"github.com/obot-platform/nanobot/pkg/mcp"
 	"github.com/obot-platform/nanobot/pkg/types"
+	"golang.org/x/oauth2"
 	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
+func init() {
+	schema.RegisterSerializer("encrypted", encryptedSerializer{})
+}
+
 type ConfigWrapper types.Config
 
 func (c ConfigWrapper) Value() (driver.Value, error) {
@@ -54,6 +63,50 @@ func scan(value any, obj any) error {
 	return fmt.Errorf("cannot scan %T into %T", value, obj)
 }
 
+// encryptedSerializer stores a field as JSON, sealed by the encryption
+// envelope of the statement's context if there is one. Fields stored before
+// encryption was enabled are read as they are.
+type encryptedSerializer struct{}
+
+func (encryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue any) error {
+	fieldValue := reflect.New(field.FieldType)
+	if dbValue != nil {
+		var data []byte
+		switch v := dbValue.(type) {
+		case []byte:
+			data = v
+		case string:
+			data = []byte(v)
+		default:
+			return fmt.Errorf("cannot scan %T into %s", dbValue, field.Name)
+		}
+
+		data, err := encryption.Open(ctx, data)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", field.Name, err)
+		}
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, fieldValue.Interface()); err != nil {
+				return err
+			}
+		}
+	}
+	field.ReflectValueOf(ctx, dst).Set(fieldValue.Elem())
+	return nil
+}
+
+func (encryptedSerializer) Value(ctx context.Context, _ *schema.Field, _ reflect.Value, fieldValue any) (any, error) {
+	data, err := json.Marshal(fieldValue)
+	if err != nil {
+		return nil, err
+	}
+	data, err = encryption.Seal(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
 type Session struct {
 	gorm.Model
 	Type        string        `json:"type,omitempty"`
@@ -61,8 +114,8 @@ type Session struct {
 	Description string        `json:"description,omitempty"`
 	AccountID   string        `json:"accountId,omitempty"`
 	TaskURI     string        `json:"taskURI,omitempty" gorm:"index"`
-	State       State         `json:"state" gorm:"type:json"`
-	Config      ConfigWrapper `json:"config,omitempty" gorm:"type:json"`
+	State       State         `json:"state" gorm:"type:json;serializer:encrypted"`
+	Config      ConfigWrapper `json:"config,omitempty" gorm:"type:json;serializer:encrypted"`
 	Cwd         string        `json:"cwd,omitempty"`
 }
 
@@ -74,17 +127,40 @@ type WorkflowRun struct {
 
 type Token struct {
 	gorm.Model
-	AccountID string `json:"accountID,omitempty"`
-	URL       string `json:"url,omitempty"`
-	Data      string `json:"data,omitempty"`
+	AccountID string    `json:"accountID,omitempty"`
+	URL       string    `json:"url,omitempty"`
+	Data      TokenData `json:"data,omitempty" gorm:"type:text;serializer:encrypted"`
+}
+
+// TokenData is the OAuth client and token of an account for an MCP server.
+// It holds the refresh token, so it is encrypted when the store is.
+type TokenData struct {
+	Config *oauth2.Config `json:"config,omitempty"`
+	Token  *oauth2.Token  `json:"token,omitempty"`
 }
 
-// ScheduledTask is the persisted definition for a scheduled chat run.
+// Memory is a fact an agent remembered for an account, recalled in later
+// sessions.
+type Memory struct {
+	gorm.Model
+	AccountID string `json:"accountID,omitempty" gorm:"index"`
+	Fact      string `json:"fact" gorm:"type:text;not null"`
+}
+
+// ScheduledTask is the persisted definition for a scheduled chat run. A task
+// runs its prompt with Agent, or the workflow of WorkflowURI with Inputs.
 type ScheduledTask struct {
 	gorm.Model
-	TaskURI   string     `json:"taskURI" gorm:"uniqueIndex;not null"`
-	Name      string     `json:"name"`
-	Prompt    string     `json:"prompt" gorm:"type:text"`
+	TaskURI     string         `json:"taskURI" gorm:"uniqueIndex;not null"`
+	Name        string         `json:"name"`
+	Prompt      string         `json:"prompt" gorm:"type:text"`
+	Agent       string         `json:"agent,omitempty"`
+	WorkflowURI string         `json:"workflowURI,omitempty"`
+	Inputs      map[string]any `json:"inputs,omitempty" gorm:"type:json;serializer:json"`
+	Webhook     string         `json:"webhook,omitempty"`
+	// Source is ScheduledTaskSourceConfig for the tasks of the schedules
+	// of the config, which only change with the config.
+	Source    string     `json:"source,omitempty"`
 	Schedule  string     `json:"schedule"`
 	Timezone  string     `json:"timezone"`
 	Enabled   bool       `json:"enabled" gorm:"not null"`
@@ -92,3 +168,5 @@ type ScheduledTask struct {
 	LastRunAt *time.Time `json:"lastRunAt,omitempty"`
 	NextRunAt *time.Time `json:"nextRunAt,omitempty" gorm:"index"`
 }
+
+const ScheduledTaskSourceConfig = "config"
@@ -0,0 +1,118 @@
+package session
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/obot-platform/nanobot/pkg/encryption"
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+	"golang.org/x/oauth2"
+)
+
+func TestEncryptedStore(t *testing.T) {
+	key, err := encryption.NewLocalKey("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := newTestStore(t, "db", StoreOptions{Encryption: encryption.New(key)})
+	ctx := t.Context()
+
+	plain := &Session{
+		SessionID: "plain",
+		AccountID: "account",
+		State:     chatState("plain", textMessage("m1", "user", "written before encryption")),
+	}
+	if err := NewStore(store.db).Create(ctx, plain); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Session{
+		SessionID: "secret",
+		AccountID: "account",
+		State:     chatState("secret", textMessage("m1", "user", "the launch code is 1234")),
+	}
+	s.State.Attributes["env"] = map[string]string{"API_KEY": "hunter2"}
+	if err := store.Create(ctx, s); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.IndexMessages(ctx, s); err != nil {
+		t.Fatal(err)
+	}
+
+	var raw string
+	if err := store.db.Table("sessions").Select("state").Where("session_id = ?", "secret").Scan(&raw).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !encryption.IsSealed([]byte(raw)) || strings.Contains(raw, "launch code") || strings.Contains(raw, "hunter2") {
+		t.Fatalf("expected the state to be encrypted, got %s", raw)
+	}
+
+	var indexed int64
+	store.db.Model(&SessionMessage{}).Where("session_id = ?", "secret").Count(&indexed)
+	if indexed != 0 {
+		t.Fatalf("expected encrypted messages not to be indexed, got %d", indexed)
+	}
+
+	for _, id := range []string{"plain", "secret"} {
+		got, err := store.GetByIDByAccountID(ctx, id, "account")
+		if err != nil {
+			t.Fatal(err)
+		}
+		messages, err := stateMessages(mcp.SessionState(got.State))
+		if err != nil || len(messages) != 1 {
+			t.Fatalf("expected one message in %s, got %v, %v", id, messages, err)
+		}
+	}
+
+	if _, err := NewStore(store.db).GetByIDByAccountID(ctx, "secret", "account"); !errors.Is(err, encryption.ErrNoKey) {
+		t.Fatalf("expected reading without the key to fail with ErrNoKey, got %v", err)
+	}
+}
+
+func TestEncryptedTokens(t *testing.T) {
+	key, err := encryption.NewLocalKey("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := newTestStore(t, "db", StoreOptions{Encryption: encryption.New(key)})
+	session := mcp.NewEmptySession(t.Context())
+	session.Set(types.AccountIDSessionKey, "account")
+	ctx := mcp.WithSession(t.Context(), session)
+
+	// Tokens stored before encryption was enabled are still read.
+	legacy := Token{AccountID: "account", URL: "https://legacy.example.com/mcp", Data: TokenData{
+		Config: &oauth2.Config{ClientID: "legacy"},
+		Token:  &oauth2.Token{RefreshToken: "legacy-refresh"},
+	}}
+	if err := store.db.WithContext(t.Context()).Create(&legacy).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.SetTokenConfig(ctx, "https://example.com/mcp", &oauth2.Config{ClientID: "client"},
+		&oauth2.Token{AccessToken: "access", RefreshToken: "refresh-secret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var raw string
+	if err := store.db.Table("tokens").Select("data").Where("url = ?", "https://example.com/mcp").Scan(&raw).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !encryption.IsSealed([]byte(raw)) || strings.Contains(raw, "refresh-secret") {
+		t.Fatalf("expected the token to be encrypted, got %s", raw)
+	}
+
+	for url, refreshToken := range map[string]string{
+		"https://example.com/mcp":        "refresh-secret",
+		"https://legacy.example.com/mcp": "legacy-refresh",
+	} {
+		_, tok, err := store.GetTokenConfig(ctx, url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.RefreshToken != refreshToken {
+			t.Fatalf("expected refresh token %s for %s, got %+v", refreshToken, url, tok)
+		}
+	}
+}
@@ -16,6 +16,10 @@ import (
 
 var browserProxy = newBrowserProxy()
 
+// UIPath is the documented entry point of the web UI. The UI itself is served
+// from the root, so links to /ui keep working whichever client follows them.
+const UIPath = "/ui"
+
 func UISession(next http.Handler, sessionStore *Manager, apiHandler http.Handler) http.Handler {
 	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
 		if req.URL.Path == "/browser" || strings.HasPrefix(req.URL.Path, "/browser/") {
@@ -23,6 +27,18 @@ func UISession(next http.Handler, sessionStore *Manager, apiHandler http.Handler
 			return
 		}
 
+		if req.URL.Path == UIPath || strings.HasPrefix(req.URL.Path, UIPath+"/") {
+			redirectToUI(rw, req)
+			return
+		}
+
+		// The API is also for clients other than browsers, like dashboards
+		// following the activity of a session.
+		if strings.HasPrefix(req.URL.Path, "/api") {
+			apiHandler.ServeHTTP(rw, req)
+			return
+		}
+
 		if !strings.Contains(strings.ToLower(req.UserAgent()), "mozilla") {
 			next.ServeHTTP(rw, req)
 			return
@@ -91,11 +107,6 @@ func UISession(next http.Handler, sessionStore *Manager, apiHandler http.Handler
 			return
 		}
 
-		if strings.HasPrefix(req.URL.Path, "/api") {
-			apiHandler.ServeHTTP(rw, req)
-			return
-		}
-
 		uiFS, _ := fs.Sub(ui.FS, "dist")
 		_, err := fs.Stat(uiFS, "fallback.html")
 		if err == nil {
@@ -117,6 +128,18 @@ func UISession(next http.Handler, sessionStore *Manager, apiHandler http.Handler
 	})
 }
 
+// redirectToUI redirects a request under UIPath to the same page of the UI.
+// All the leading slashes are trimmed, so that /ui//host doesn't redirect to
+// the protocol-relative //host, and backslashes too, which browsers treat as
+// slashes.
+func redirectToUI(rw http.ResponseWriter, req *http.Request) {
+	target := url.URL{
+		Path:     "/" + strings.TrimLeft(strings.TrimPrefix(req.URL.Path, UIPath), `/\`),
+		RawQuery: req.URL.RawQuery,
+	}
+	http.Redirect(rw, req, target.String(), http.StatusFound)
+}
+
 func setNoCacheHeaders(rw http.ResponseWriter) {
 	rw.Header().Set("Cache-Control", "no-store, no-cache, must-revalidate, proxy-revalidate, max-age=0")
 	rw.Header().Set("Pragma", "no-cache")
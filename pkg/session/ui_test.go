@@ -0,0 +1,35 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUISessionRedirectsUIPath(t *testing.T) {
+	next := http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		rw.WriteHeader(http.StatusTeapot)
+	})
+	handler := UISession(next, nil, next)
+
+	for path, want := range map[string]string{
+		"/ui":                      "/",
+		"/ui/":                     "/",
+		"/ui/c/123":                "/c/123",
+		"/ui/c/123?agent=reviewer": "/c/123?agent=reviewer",
+		"/ui//evil.com":            "/evil.com",
+		"/ui/\\evil.com":           "/evil.com",
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusFound || rec.Header().Get("Location") != want {
+			t.Errorf("GET %s: got %d to %q, expected a redirect to %q", path, rec.Code, rec.Header().Get("Location"), want)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/uix", nil))
+	if rec.Code != http.StatusTeapot {
+		t.Errorf("GET /uix: expected it to be passed on, got %d", rec.Code)
+	}
+}
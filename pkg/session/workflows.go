@@ -0,0 +1,59 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WorkflowExecution is the persisted state of a run of an executable
+// workflow. State holds the run as the workflow engine sees it, the other
+// columns are copies of it for finding runs.
+type WorkflowExecution struct {
+	ID          uint            `json:"-" gorm:"primarykey"`
+	RunID       string          `json:"runId" gorm:"uniqueIndex;not null"`
+	SessionID   string          `json:"sessionId" gorm:"index;not null"`
+	WorkflowURI string          `json:"workflowURI"`
+	Status      string          `json:"status"`
+	State       json.RawMessage `json:"state" gorm:"type:json;serializer:encrypted"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
+}
+
+// SaveWorkflowExecution creates or updates the workflow execution with the
+// run ID of execution.
+func (s *Store) SaveWorkflowExecution(ctx context.Context, execution *WorkflowExecution) error {
+	return s.withContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing WorkflowExecution
+		err := tx.Select("id", "created_at").Where("run_id = ?", execution.RunID).First(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return tx.Create(execution).Error
+		} else if err != nil {
+			return err
+		}
+		execution.ID = existing.ID
+		execution.CreatedAt = existing.CreatedAt
+		return tx.Save(execution).Error
+	})
+}
+
+// GetWorkflowExecution returns a workflow execution by its run ID.
+func (s *Store) GetWorkflowExecution(ctx context.Context, runID string) (*WorkflowExecution, error) {
+	var execution WorkflowExecution
+	err := s.withContext(ctx).Where("run_id = ?", runID).First(&execution).Error
+	return &execution, err
+}
+
+// ListWorkflowExecutions returns the workflow executions started in a
+// session, newest first.
+func (s *Store) ListWorkflowExecutions(ctx context.Context, sessionID string) ([]WorkflowExecution, error) {
+	var executions []WorkflowExecution
+	err := s.withContext(ctx).
+		Where("session_id = ?", sessionID).
+		Order("created_at desc, id desc").
+		Find(&executions).Error
+	return executions, err
+}
@@ -235,6 +235,15 @@ func initSubscriptions(session *mcp.Session) {
 	}
 
 	session.AddFilter(func(ctx context.Context, msg *mcp.Message) (*mcp.Message, error) {
+		if msg.Method == "notifications/resources/list_changed" {
+			// Minimal clients that never list resources have no use for
+			// notifications that the list changed.
+			var listed bool
+			if session.Get(types.ResourcesListedSessionKey, &listed) && listed {
+				return msg, nil
+			}
+			return nil, nil
+		}
 		if msg.Method != "notifications/resources/updated" {
 			return msg, nil
 		}
@@ -360,6 +369,16 @@ func (d *Data) Sync(ctx context.Context, defaultConfig types.ConfigFactory) erro
 
 	session.Get(types.ConfigHashSessionKey, &existingHash)
 
+	hash := configHash(session, config)
+	if hash != existingHash {
+		d.Refresh(ctx, true)
+	}
+
+	session.Set(types.ConfigHashSessionKey, mcp.SavedString(hash))
+	return nil
+}
+
+func configHash(session *mcp.Session, config types.Config) string {
 	digest := sha256.New()
 	_ = json.NewEncoder(digest).Encode(struct {
 		Config types.Config      `json:"config"`
@@ -368,14 +387,29 @@ func (d *Data) Sync(ctx context.Context, defaultConfig types.ConfigFactory) erro
 		Config: config,
 		Env:    session.GetEnvMap(),
 	})
-	hash := fmt.Sprintf("%x", digest.Sum(nil))
+	return fmt.Sprintf("%x", digest.Sum(nil))
+}
 
-	if hash != existingHash {
-		d.Refresh(ctx, true)
-	}
+// SetConfig replaces the config of the session, as when agents are defined
+// at runtime, so that it is used without waiting for the next Sync.
+func (d *Data) SetConfig(ctx context.Context, config types.Config) {
+	var (
+		session      = mcp.SessionFromContext(ctx)
+		root         = session.Root()
+		currentAgent string
+	)
 
-	session.Set(types.ConfigHashSessionKey, mcp.SavedString(hash))
-	return nil
+	root.Get(types.CurrentAgentSessionKey, &currentAgent)
+	root.Set(types.ConfigSessionKey, &config)
+	root.Set(types.ConfigHashSessionKey, mcp.SavedString(configHash(root, config)))
+
+	d.Refresh(mcp.WithSession(ctx, root), false)
+	if session != root {
+		d.Refresh(ctx, false)
+	}
+	if currentAgent != "" && slices.Contains(config.Publish.Entrypoint, currentAgent) {
+		root.Set(types.CurrentAgentSessionKey, mcp.SavedString(currentAgent))
+	}
 }
 
 func (d *Data) Refresh(ctx context.Context, close bool) {
@@ -628,6 +662,31 @@ func (d *Data) MatchResource(ctx context.Context, uri string, refs []string) (re
 	return "", "", fmt.Errorf("resource %q not found: %w", uri, ErrResourceNotFound)
 }
 
+// CompletionTarget returns the server that completes the arguments of the
+// prompt or resource template of a completion reference, among the mappings,
+// and the reference as that server knows it. Inline prompts have no server to
+// complete their arguments.
+func (d *Data) CompletionTarget(ctx context.Context, ref mcp.CompleteReference, prompts types.PromptMappings, templates types.ResourceTemplateMappings) (string, mcp.CompleteReference, bool) {
+	switch ref.Type {
+	case mcp.CompleteRefPrompt:
+		mapping, ok := prompts[ref.Name]
+		if !ok {
+			break
+		}
+		if _, inline := types.ConfigFromContext(ctx).Prompts[mapping.MCPServer]; inline && mapping.MCPServer == mapping.TargetName {
+			break
+		}
+		return mapping.MCPServer, mcp.CompleteReference{Type: ref.Type, Name: mapping.TargetName}, true
+	case mcp.CompleteRefResource:
+		for _, key := range slices.Sorted(maps.Keys(templates)) {
+			if mapping := templates[key]; mapping.Target.ResourceTemplate.URITemplate == ref.URI {
+				return mapping.MCPServer, mcp.CompleteReference{Type: ref.Type, URI: mapping.TargetName}, true
+			}
+		}
+	}
+	return "", ref, false
+}
+
 func (d *Data) PublishedResourceMappings(ctx context.Context) (types.ResourceMappings, error) {
 	var (
 		session = mcp.SessionFromContext(ctx)
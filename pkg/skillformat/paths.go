@@ -0,0 +1,48 @@
+package skillformat
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// SkillsDir is the directory name under the config directory where user
+// skills are stored.
+const SkillsDir = "skills"
+
+// SkillsPathEnv names the environment variable listing additional directories
+// to load skills from, separated by the OS path list separator.
+const SkillsPathEnv = "NANOBOT_SKILLS_PATH"
+
+// SearchPaths returns the directories skills are loaded from, highest
+// precedence first: the skills directory under configDir, followed by the
+// entries of NANOBOT_SKILLS_PATH in order. Skills in an earlier directory
+// override skills of the same name in later ones and the built-in skills.
+func SearchPaths(configDir string) []string {
+	var paths []string
+	if configDir != "" {
+		paths = append(paths, filepath.Join(configDir, SkillsDir))
+	}
+	for _, path := range filepath.SplitList(os.Getenv(SkillsPathEnv)) {
+		if path == "" {
+			continue
+		}
+		path = filepath.Clean(path)
+		if !slices.Contains(paths, path) {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// FindSkillDir returns the directory of the named directory-based skill in
+// the first search path that contains it.
+func FindSkillDir(searchPaths []string, name string) (string, bool) {
+	for _, searchPath := range searchPaths {
+		dir := filepath.Join(searchPath, name)
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir, true
+		}
+	}
+	return "", false
+}
@@ -26,6 +26,10 @@ type Frontmatter struct {
 	Compatibility string            `yaml:"compatibility,omitempty"`
 	Metadata      map[string]string `yaml:"metadata,omitempty"`
 	AllowedTools  string            `yaml:"allowed-tools,omitempty"`
+	// Tags are keywords used when searching for skills.
+	Tags []string `yaml:"tags,omitempty"`
+	// RequiredTools are the tools the skill's instructions depend on.
+	RequiredTools []string `yaml:"required-tools,omitempty"`
 }
 
 var nameRegexp = regexp.MustCompile(`^[a-z0-9-]+$`)
@@ -175,6 +179,12 @@ func FrontmatterToMeta(fm Frontmatter) map[string]any {
 	if fm.AllowedTools != "" {
 		meta["allowedTools"] = fm.AllowedTools
 	}
+	if len(fm.Tags) > 0 {
+		meta["tags"] = fm.Tags
+	}
+	if len(fm.RequiredTools) > 0 {
+		meta["requiredTools"] = fm.RequiredTools
+	}
 	for k, v := range fm.Metadata {
 		meta[k] = v
 	}
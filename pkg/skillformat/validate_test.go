@@ -3,6 +3,7 @@ package skillformat
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -434,3 +435,24 @@ func TestValidateSkillDirectory(t *testing.T) {
 		}
 	})
 }
+
+func TestSearchPaths(t *testing.T) {
+	extra := t.TempDir()
+	t.Setenv(SkillsPathEnv, strings.Join([]string{extra, "", filepath.Join("config", SkillsDir)}, string(filepath.ListSeparator)))
+
+	got := SearchPaths("config")
+	want := []string{filepath.Join("config", SkillsDir), extra}
+	if !slices.Equal(got, want) {
+		t.Errorf("SearchPaths() = %v, want %v", got, want)
+	}
+
+	if err := os.MkdirAll(filepath.Join(extra, "my-skill"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if dir, ok := FindSkillDir(got, "my-skill"); !ok || dir != filepath.Join(extra, "my-skill") {
+		t.Errorf("FindSkillDir() = %q, %v", dir, ok)
+	}
+	if _, ok := FindSkillDir(got, "missing"); ok {
+		t.Error("expected missing skill not to be found")
+	}
+}
@@ -0,0 +1,87 @@
+package skillformat
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// MaxWorkflowDepth is how deep under the workflows directory workflows and
+// their supporting files are found and watched.
+const MaxWorkflowDepth = 10
+
+// Workflow is a directory under the workflows directory that contains a
+// SKILL.md file.
+type Workflow struct {
+	// Name is the path of the workflow directory relative to the workflows
+	// directory, separated by forward slashes, e.g. "ops/deploy/rollback".
+	Name string
+	// Category is the path of the folder the workflow is in, e.g. "ops/deploy",
+	// and empty for workflows at the top level.
+	Category string
+	// Dir is the path of the workflow directory.
+	Dir string
+}
+
+// WorkflowCategory returns the category of the named workflow.
+func WorkflowCategory(name string) string {
+	category := path.Dir(name)
+	if category == "." {
+		return ""
+	}
+	return category
+}
+
+// InCategory checks whether a workflow category is the filter category or
+// one of its subcategories. An empty filter matches all categories.
+func InCategory(category, filter string) bool {
+	filter = strings.Trim(filter, "/")
+	return filter == "" || category == filter || strings.HasPrefix(category, filter+"/")
+}
+
+// ValidWorkflowName checks that a workflow name is a relative path that stays
+// within the workflows directory.
+func ValidWorkflowName(name string) bool {
+	if name == "" || strings.Contains(name, `\`) {
+		return false
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// FindWorkflows walks the workflows directory for workflows. Folders without
+// a SKILL.md are categories, folders inside a workflow belong to it and are
+// not searched for more workflows.
+func FindWorkflows(workflowsPath string) []Workflow {
+	var workflows []Workflow
+	_ = filepath.WalkDir(workflowsPath, func(dir string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() || dir == workflowsPath {
+			return nil
+		}
+		rel, err := filepath.Rel(workflowsPath, dir)
+		if err != nil {
+			return nil
+		}
+		if strings.Count(rel, string(filepath.Separator)) >= MaxWorkflowDepth {
+			return filepath.SkipDir
+		}
+		if info, err := os.Stat(filepath.Join(dir, SkillMainFile)); err != nil || info.IsDir() {
+			return nil
+		}
+
+		name := filepath.ToSlash(rel)
+		workflows = append(workflows, Workflow{
+			Name:     name,
+			Category: WorkflowCategory(name),
+			Dir:      dir,
+		})
+		return filepath.SkipDir
+	})
+	return workflows
+}
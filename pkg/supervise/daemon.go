@@ -15,6 +15,14 @@ func Daemon() error {
 		return err
 	}
 
+	// The limits are applied to the command once started, it doesn't inherit
+	// them from the environment.
+	limits, err := parseLimits(os.Getenv(LimitsEnv))
+	if err != nil {
+		return err
+	}
+	_ = os.Unsetenv(LimitsEnv)
+
 	cmd := exec.CommandContext(ctx, os.Args[2], os.Args[3:]...)
 	cmd.Stderr = os.Stderr
 	cmd.Stdout = os.Stdout
@@ -34,6 +42,13 @@ func Daemon() error {
 		return err
 	}
 
+	if err := limitDaemonCommand(cmd, limits); err != nil {
+		_ = processIn.Close()
+		_ = cmd.Cancel()
+		_ = cmd.Wait()
+		return err
+	}
+
 	go func() {
 		defer processIn.Close()
 
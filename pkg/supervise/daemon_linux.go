@@ -4,6 +4,7 @@ package supervise
 
 import (
 	"errors"
+	"fmt"
 	"os/exec"
 	"syscall"
 	"time"
@@ -96,3 +97,21 @@ func reapDaemonChildren() error {
 		return err
 	}
 }
+
+// limitDaemonCommand applies the limits to the started command, and to the
+// processes it starts from then on.
+func limitDaemonCommand(cmd *exec.Cmd, limits Limits) error {
+	if limits.MemoryBytes > 0 {
+		rlimit := unix.Rlimit{Cur: limits.MemoryBytes, Max: limits.MemoryBytes}
+		if err := unix.Prlimit(cmd.Process.Pid, unix.RLIMIT_DATA, &rlimit, nil); err != nil {
+			return fmt.Errorf("failed to limit memory: %w", err)
+		}
+	}
+	if limits.CPUSeconds > 0 {
+		rlimit := unix.Rlimit{Cur: limits.CPUSeconds, Max: limits.CPUSeconds}
+		if err := unix.Prlimit(cmd.Process.Pid, unix.RLIMIT_CPU, &rlimit, nil); err != nil {
+			return fmt.Errorf("failed to limit CPU time: %w", err)
+		}
+	}
+	return nil
+}
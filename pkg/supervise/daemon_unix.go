@@ -50,3 +50,8 @@ func afterDaemonCommandExit(cmd *exec.Cmd, waitErr error) error {
 	}
 	return waitErr
 }
+
+// limitDaemonCommand doesn't limit commands outside of Linux.
+func limitDaemonCommand(*exec.Cmd, Limits) error {
+	return nil
+}
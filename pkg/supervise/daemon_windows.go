@@ -21,3 +21,8 @@ func cancelDaemonCommand(cmd *exec.Cmd) error {
 func afterDaemonCommandExit(_ *exec.Cmd, waitErr error) error {
 	return waitErr
 }
+
+// limitDaemonCommand doesn't limit commands outside of Linux.
+func limitDaemonCommand(*exec.Cmd, Limits) error {
+	return nil
+}
@@ -0,0 +1,52 @@
+package supervise
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// LimitsEnv passes the resource limits of a command to the _exec process
+// that supervises it.
+const LimitsEnv = "NANOBOT_EXEC_LIMITS"
+
+// Limits are the resource limits of a supervised command, zero is
+// unlimited. They are applied on Linux only.
+type Limits struct {
+	// MemoryBytes limits the memory the command can allocate.
+	MemoryBytes uint64
+	// CPUSeconds limits the CPU time the command can use.
+	CPUSeconds uint64
+}
+
+func (l Limits) IsZero() bool {
+	return l == Limits{}
+}
+
+// Env returns the LimitsEnv entry of the environment of a command with the
+// limits.
+func (l Limits) Env() string {
+	return fmt.Sprintf("%s=memory=%d,cpu=%d", LimitsEnv, l.MemoryBytes, l.CPUSeconds)
+}
+
+func parseLimits(s string) (result Limits, _ error) {
+	if s == "" {
+		return result, nil
+	}
+	for field := range strings.SplitSeq(s, ",") {
+		k, v, _ := strings.Cut(field, "=")
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return result, fmt.Errorf("invalid %s %q: %w", LimitsEnv, s, err)
+		}
+		switch k {
+		case "memory":
+			result.MemoryBytes = n
+		case "cpu":
+			result.CPUSeconds = n
+		default:
+			return result, fmt.Errorf("invalid %s %q: unknown limit %q", LimitsEnv, s, k)
+		}
+	}
+	return result, nil
+}
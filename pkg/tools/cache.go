@@ -0,0 +1,112 @@
+package tools
+
+import (
+	"encoding/json"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// maxCachedResults is how many tool results the cache holds, across sessions.
+// The oldest are evicted first.
+var maxCachedResults = 1000
+
+// resultCache holds tool results for servers configured with a cache TTL.
+type resultCache struct {
+	lock    sync.Mutex
+	entries map[string]cachedResult
+}
+
+type cachedResult struct {
+	result  types.CallResult
+	stored  time.Time
+	expires time.Time
+}
+
+func resultCacheKey(sessionID, server, tool string, args any) (string, bool) {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return "", false
+	}
+	return sessionID + "\x00" + server + "\x00" + tool + "\x00" + string(data), true
+}
+
+func (c *resultCache) get(key string) (*types.CallResult, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	result := copyCallResult(entry.result)
+	return &result, true
+}
+
+func (c *resultCache) put(key string, result types.CallResult, ttl time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := time.Now()
+	for k, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+
+	if c.entries == nil {
+		c.entries = map[string]cachedResult{}
+	}
+	if _, ok := c.entries[key]; !ok {
+		for len(c.entries) >= maxCachedResults {
+			var oldest string
+			for k, entry := range c.entries {
+				if oldest == "" || entry.stored.Before(c.entries[oldest].stored) {
+					oldest = k
+				}
+			}
+			delete(c.entries, oldest)
+		}
+	}
+	c.entries[key] = cachedResult{
+		result:  copyCallResult(result),
+		stored:  now,
+		expires: now.Add(ttl),
+	}
+}
+
+// copyCallResult copies a result so that changes to the copy, like truncating
+// its content, don't change the cached result.
+func copyCallResult(result types.CallResult) types.CallResult {
+	result.Meta = maps.Clone(result.Meta)
+	result.StructuredContent = maps.Clone(result.StructuredContent)
+	result.Content = copyContent(result.Content)
+	return result
+}
+
+func copyContent(content []mcp.Content) []mcp.Content {
+	if content == nil {
+		return nil
+	}
+	result := make([]mcp.Content, len(content))
+	for i, c := range content {
+		if c.Resource != nil {
+			resource := *c.Resource
+			resource.Meta = maps.Clone(resource.Meta)
+			c.Resource = &resource
+		}
+		c.Meta = maps.Clone(c.Meta)
+		c.Input = maps.Clone(c.Input)
+		c.StructuredContent = maps.Clone(c.StructuredContent)
+		c.Content = copyContent(c.Content)
+		result[i] = c
+	}
+	return result
+}
@@ -0,0 +1,122 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/obot-platform/nanobot/pkg/expr"
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// defaultCompositeInputSchema is the input schema of composite tools without
+// an input.
+var defaultCompositeInputSchema = json.RawMessage(`{"type":"object"}`)
+
+// compositeTool returns the tool the model sees for a composite tool of the
+// config.
+func compositeTool(name string, composite types.CompositeTool) mcp.Tool {
+	schema := composite.Input.ToSchema()
+	if len(schema) == 0 {
+		schema = defaultCompositeInputSchema
+	}
+	return mcp.Tool{
+		Name:        name,
+		Description: composite.Description,
+		InputSchema: schema,
+	}
+}
+
+// callComposite calls the steps of a composite tool in order. The arguments
+// of each step are evaluated with the inputs of the tool and the outputs of
+// the earlier steps, and the result is the result of the last step.
+func (s *Service) callComposite(ctx context.Context, name string, composite types.CompositeTool, args any) (*types.CallResult, error) {
+	inputs, err := compositeInputs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		env    map[string]string
+		steps  = map[string]any{}
+		data   = map[string]any{"inputs": inputs, "steps": steps}
+		result *types.CallResult
+	)
+	if session := mcp.SessionFromContext(ctx); session != nil {
+		env = session.GetEnvMap()
+	}
+
+	for i, step := range composite.Steps {
+		stepArgs, err := expr.EvalObject(ctx, env, data, map[string]any(step.Args))
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate arguments of step %d of %s: %w", i, name, err)
+		}
+
+		server, tool, _ := strings.Cut(step.Tool, "/")
+		result, err = s.Call(ctx, server, tool, stepArgs)
+		if err != nil {
+			return nil, fmt.Errorf("step %d of %s, %s, failed: %w", i, name, step.Tool, err)
+		}
+		if result.IsError {
+			return &types.CallResult{
+				IsError: true,
+				Content: []mcp.Content{{
+					Type: "text",
+					Text: fmt.Sprintf("step %d of %s, %s, failed: %s", i, name, step.Tool, compositeResultText(result)),
+				}},
+			}, nil
+		}
+
+		if step.ID != "" {
+			steps[step.ID] = map[string]any{
+				"output": compositeOutput(result),
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func compositeInputs(args any) (map[string]any, error) {
+	inputs := map[string]any{}
+	if args == nil {
+		return inputs, nil
+	}
+	data, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal arguments: %w", err)
+	}
+	if err := json.Unmarshal(data, &inputs); err != nil {
+		return nil, mcp.ErrRPCInvalidParams.WithMessage("arguments must be an object: %v", err)
+	}
+	if inputs == nil {
+		inputs = map[string]any{}
+	}
+	return inputs, nil
+}
+
+// compositeOutput is the output of a step, its structured content, or its
+// text parsed as JSON if it is JSON.
+func compositeOutput(result *types.CallResult) any {
+	if result.StructuredContent != nil {
+		return result.StructuredContent
+	}
+	text := compositeResultText(result)
+	var value any
+	if json.Valid([]byte(text)) && json.Unmarshal([]byte(text), &value) == nil {
+		return value
+	}
+	return text
+}
+
+func compositeResultText(result *types.CallResult) string {
+	var texts []string
+	for _, content := range result.Content {
+		if content.Text != "" {
+			texts = append(texts, content.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
@@ -0,0 +1,54 @@
+package tools
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// answerElicitation answers an elicitation request of a server with the
+// elicitation policy of the agent using it. It returns false when the
+// request is forwarded to the client of the session instead.
+func answerElicitation(ctx context.Context, server string, request mcp.ElicitRequest) (mcp.ElicitResult, bool) {
+	agent := types.CurrentAgent(ctx)
+	policy := types.ConfigFromContext(ctx).Agents[agent].Elicitation
+
+	switch action := policy.ActionFor(server); action {
+	case types.ElicitationAccept:
+		// Only a user can follow the URL of a URL elicitation.
+		if request.Mode == "url" {
+			return mcp.ElicitResult{}, false
+		}
+		return acceptElicitation(agent, server, request, policy.Content), true
+	case types.ElicitationDecline, types.ElicitationCancel:
+		return mcp.ElicitResult{Action: string(action)}, true
+	default:
+		return mcp.ElicitResult{}, false
+	}
+}
+
+// acceptElicitation accepts the request with the content of the policy,
+// falling back to the defaults of the requested schema. It declines when a
+// required field has no value.
+func acceptElicitation(agent, server string, request mcp.ElicitRequest, content map[string]any) mcp.ElicitResult {
+	result := mcp.ElicitResult{
+		Action:  "accept",
+		Content: map[string]any{},
+	}
+	for name, property := range request.RequestedSchema.Properties {
+		if value, ok := content[name]; ok {
+			result.Content[name] = value
+		} else if property.Default != nil {
+			result.Content[name] = property.Default
+		}
+	}
+	for _, name := range request.RequestedSchema.Required {
+		if _, ok := result.Content[name]; !ok {
+			slog.Warn("declining elicitation without a value for a required field", "agent", agent, "server", server, "field", name)
+			return mcp.ElicitResult{Action: "decline"}
+		}
+	}
+	return result
+}
@@ -0,0 +1,215 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+)
+
+// The MCP servers of the config are pinged while a session uses them. A server
+// that fails unavailableAfter times in a row is unavailable: its tools are
+// hidden and calls to it fail fast until it reconnects, retried with a backoff
+// from reconnectDelay to maxReconnectDelay.
+var (
+	healthCheckInterval = 30 * time.Second
+	healthCheckTimeout  = 10 * time.Second
+	reconnectDelay      = time.Second
+	maxReconnectDelay   = 5 * time.Minute
+)
+
+const unavailableAfter = 3
+
+// ErrServerUnavailable is returned for an MCP server that failed its health
+// checks and is waiting to reconnect.
+var ErrServerUnavailable = errors.New("MCP server is unavailable")
+
+type ServerStatus string
+
+const (
+	ServerHealthy ServerStatus = "healthy"
+	// ServerDegraded is a server that failed, but not enough times in a row
+	// to be unavailable.
+	ServerDegraded    ServerStatus = "degraded"
+	ServerUnavailable ServerStatus = "unavailable"
+)
+
+// serverHealth is the health of an MCP server of a session.
+type serverHealth struct {
+	lock     sync.Mutex
+	failures int
+	lastErr  error
+	delay    time.Duration
+	retryAt  time.Time
+	// client is the client being supervised.
+	client *mcp.Client
+}
+
+type healthKey struct {
+	session *mcp.Session
+	server  string
+}
+
+func (h *serverHealth) status() ServerStatus {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.statusLocked()
+}
+
+func (h *serverHealth) statusLocked() ServerStatus {
+	switch {
+	case h.failures >= unavailableAfter:
+		return ServerUnavailable
+	case h.failures > 0:
+		return ServerDegraded
+	default:
+		return ServerHealthy
+	}
+}
+
+// record records the outcome of talking to the server. It returns true when
+// the server became unavailable or available again.
+func (h *serverHealth) record(err error) bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	wasUnavailable := h.statusLocked() == ServerUnavailable
+	if err == nil {
+		h.failures, h.lastErr, h.delay, h.retryAt = 0, nil, 0, time.Time{}
+	} else {
+		h.failures++
+		h.lastErr = err
+		if h.failures >= unavailableAfter {
+			h.delay = min(max(2*h.delay, reconnectDelay), maxReconnectDelay)
+			h.retryAt = time.Now().Add(h.delay)
+		}
+	}
+	return wasUnavailable != (h.statusLocked() == ServerUnavailable)
+}
+
+// unavailable returns an error if the server is unavailable and isn't due to
+// reconnect yet.
+func (h *serverHealth) unavailable(server string) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.statusLocked() != ServerUnavailable || !time.Now().Before(h.retryAt) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s failed %d times in a row, last with: %v, reconnecting in %s",
+		ErrServerUnavailable, server, h.failures, h.lastErr, time.Until(h.retryAt).Round(time.Second))
+}
+
+// watch records the client in use, returning true if it is a new client to
+// supervise.
+func (h *serverHealth) watch(client *mcp.Client) bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.client == client {
+		return false
+	}
+	h.client = client
+	return true
+}
+
+// serverHealth returns the health of an MCP server of a session, forgotten
+// when the session ends.
+func (s *Service) serverHealth(session *mcp.Session, server string) *serverHealth {
+	key := healthKey{session: session, server: server}
+	health, loaded := s.health.LoadOrStore(key, &serverHealth{})
+	if !loaded {
+		context.AfterFunc(session.Context(), func() {
+			s.health.Delete(key)
+		})
+	}
+	return health.(*serverHealth)
+}
+
+// ServerStatus returns the health of an MCP server of the session of the
+// context.
+func (s *Service) ServerStatus(ctx context.Context, server string) ServerStatus {
+	session := mcp.SessionFromContext(ctx).Root()
+	if session == nil {
+		return ServerHealthy
+	}
+	health, ok := s.health.Load(healthKey{session: session, server: server})
+	if !ok {
+		return ServerHealthy
+	}
+	return health.(*serverHealth).status()
+}
+
+// supervise pings the client of an MCP server until it or the session is
+// closed. Once the server is unavailable the client is closed and reconnected.
+func (s *Service) supervise(ctx context.Context, session *mcp.Session, server string, client *mcp.Client, health *serverHealth) {
+	ctx = context.WithoutCancel(ctx)
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-session.Context().Done():
+			return
+		case <-client.Session.Context().Done():
+			return
+		case <-ticker.C:
+		}
+
+		pingCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+		_, err := client.Ping(pingCtx)
+		cancel()
+		if err != nil {
+			slog.Warn("MCP server failed its health check", "server", server, "status", health.status(), "error", err)
+		}
+		if health.record(err) {
+			s.notifyToolsChanged(ctx, session, server)
+		}
+		if health.status() == ServerUnavailable {
+			client.Close(false)
+			s.reconnect(ctx, session, server, health)
+			return
+		}
+	}
+}
+
+// reconnect reconnects to an unavailable MCP server, waiting longer after
+// each failed attempt, until it succeeds or the session is closed.
+func (s *Service) reconnect(ctx context.Context, session *mcp.Session, server string, health *serverHealth) {
+	for {
+		health.lock.Lock()
+		wait := time.Until(health.retryAt)
+		health.lock.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-session.Context().Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if _, err := s.GetClient(mcp.WithSession(ctx, session), server); err == nil {
+			slog.Info("reconnected to MCP server", "server", server)
+			return
+		} else if !errors.Is(err, ErrServerUnavailable) {
+			slog.Warn("failed to reconnect to MCP server", "server", server, "error", err)
+		}
+	}
+}
+
+// notifyToolsChanged tells the client of the session that the tools changed,
+// as the tools of an unavailable server are hidden.
+func (s *Service) notifyToolsChanged(ctx context.Context, session *mcp.Session, server string) {
+	slog.Info("MCP server availability changed", "server", server, "status", s.ServerStatus(mcp.WithSession(ctx, session), server))
+	_ = session.SendPayload(ctx, "notifications/tools/list_changed", struct{}{})
+}
+
+// ServerProcesses returns the state of the processes of the stdio MCP servers
+// of every session.
+func (s *Service) ServerProcesses() []mcp.ProcessStatus {
+	return s.runner.Processes()
+}
@@ -0,0 +1,83 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+func TestServerHealth(t *testing.T) {
+	var h serverHealth
+	for i, want := range []ServerStatus{ServerDegraded, ServerDegraded, ServerUnavailable} {
+		changed := h.record(errors.New("connection refused"))
+		if got := h.status(); got != want {
+			t.Fatalf("after %d failures: expected %s, got %s", i+1, want, got)
+		}
+		if changed != (want == ServerUnavailable) {
+			t.Errorf("after %d failures: unexpected change %v", i+1, changed)
+		}
+	}
+	if err := h.unavailable("search"); !errors.Is(err, ErrServerUnavailable) {
+		t.Errorf("expected calls to fail fast, got %v", err)
+	}
+
+	first := h.delay
+	h.record(errors.New("connection refused"))
+	if h.delay != 2*first {
+		t.Errorf("expected the reconnect delay to double from %s, got %s", first, h.delay)
+	}
+
+	h.retryAt = time.Now().Add(-time.Second)
+	if err := h.unavailable("search"); err != nil {
+		t.Errorf("expected a reconnect to be allowed once due, got %v", err)
+	}
+
+	if !h.record(nil) || h.status() != ServerHealthy || h.delay != 0 {
+		t.Errorf("expected a success to make the server available, got %s", h.status())
+	}
+}
+
+func TestUnavailableServerToolsHidden(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		http.Error(rw, "down", http.StatusBadGateway)
+	}))
+	defer down.Close()
+
+	serverSession, err := mcp.NewExistingServerSession(t.Context(), mcp.SessionState{ID: "s1"}, mcp.MessageHandlerFunc(func(context.Context, mcp.Message) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { serverSession.Close(false) })
+
+	session := serverSession.GetSession()
+	session.Set(types.ConfigSessionKey, types.Config{
+		MCPServers: map[string]mcp.Server{
+			"search": {BaseURL: down.URL},
+		},
+	})
+	ctx := mcp.WithSession(t.Context(), session)
+
+	s := &Service{}
+	for range unavailableAfter {
+		if _, err := s.ListTools(ctx); err == nil || errors.Is(err, ErrServerUnavailable) {
+			t.Fatalf("expected the connection error, got %v", err)
+		}
+	}
+	if status := s.ServerStatus(ctx, "search"); status != ServerUnavailable {
+		t.Fatalf("expected search to be unavailable, got %s", status)
+	}
+
+	tools, err := s.ListTools(ctx)
+	if err != nil || len(tools) != 0 {
+		t.Errorf("expected the tools of search to be hidden, got %+v, %v", tools, err)
+	}
+	if _, err := s.Call(ctx, "search", "query", nil); !errors.Is(err, ErrServerUnavailable) {
+		t.Errorf("expected the call to fail fast, got %v", err)
+	}
+}
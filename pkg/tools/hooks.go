@@ -0,0 +1,102 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/obot-platform/nanobot/pkg/expr"
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// toolResultHookName is the name of the hooks run with the result of every
+// call to a tool of an MCP server.
+const toolResultHookName = "toolResult"
+
+type inHookKey struct{}
+
+// isExpressionHook indicates if the target of a hook is an inline expression,
+// as in ${...}, instead of a tool or a webhook.
+func isExpressionHook(target string) bool {
+	return strings.HasPrefix(target, "${") && strings.HasSuffix(target, "}")
+}
+
+// runExpressionHook evaluates the expression with the fields of the input of
+// the hook, and its value is the output of the hook.
+func runExpressionHook(ctx context.Context, in, out any, target string) (bool, error) {
+	var data map[string]any
+	if err := mcp.JSONCoerce(in, &data); err != nil {
+		return false, fmt.Errorf("failed to convert hook input: %w", err)
+	}
+
+	var env map[string]string
+	if session := mcp.SessionFromContext(ctx); session != nil {
+		env = session.GetEnvMap()
+	}
+	value, err := expr.EvalAny(ctx, env, data, target)
+	if err != nil {
+		return false, err
+	}
+	if value == nil {
+		return false, nil
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal hook output: %w", err)
+	}
+	if err := json.Unmarshal(b, out); err != nil {
+		return false, fmt.Errorf("failed to unmarshal hook output: %w", err)
+	}
+	return true, nil
+}
+
+// applyToolResultHooks runs the toolResult hooks of the config and of the
+// server with the result of a call. Calls made by hooks don't run them again.
+// A failing hook fails the call, so results aren't passed on unfiltered.
+func (s *Service) applyToolResultHooks(ctx context.Context, server, tool string, args any, result *mcp.CallToolResult) (*mcp.CallToolResult, error) {
+	config := types.ConfigFromContext(ctx)
+	hooks := slices.Concat(config.Hooks, config.MCPServers[server].Hooks)
+	params := map[string]string{
+		"server": server,
+		"tool":   tool,
+	}
+	if ctx.Value(inHookKey{}) != nil || !slices.ContainsFunc(hooks, func(hook mcp.HookMapping) bool {
+		return hook.Matches(toolResultHookName, params)
+	}) {
+		return result, nil
+	}
+
+	in := mcp.ToolResultHook{
+		Server:    server,
+		Tool:      tool,
+		Arguments: args,
+		Result:    result,
+	}
+	// The output of a hook is decoded over the output of the previous one, so
+	// the last accepted output is kept as JSON to restore it.
+	last, err := json.Marshal(in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal tool result: %w", err)
+	}
+	out, err := mcp.InvokeHooks(context.WithValue(ctx, inHookKey{}, true), s, hooks, &in, toolResultHookName, params,
+		func(_ mcp.HookMapping, target mcp.HookTarget, resp mcp.ToolResultHook, err error) mcp.ToolResultHook {
+			if err != nil || target.MutateDisallowed {
+				var restored mcp.ToolResultHook
+				_ = json.Unmarshal(last, &restored)
+				return restored
+			}
+			last, _ = json.Marshal(resp)
+			return resp
+		})
+	if err != nil {
+		return nil, err
+	}
+	if out.Result == nil {
+		return result, nil
+	}
+	return out.Result, nil
+}
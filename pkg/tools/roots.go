@@ -0,0 +1,94 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// sessionRootName is the name of the root of the directory of the session.
+const sessionRootName = "session"
+
+// listRoots returns the roots offered to the MCP servers of a session: the
+// roots of its client, the roots of nanobot, the roots of the config, and
+// the directory of the session.
+func (s *Service) listRoots(ctx context.Context, session *mcp.Session) ([]mcp.Root, error) {
+	var roots mcp.ListRootsResult
+	if session.InitializeRequest.Capabilities.Roots != nil {
+		err := session.Exchange(ctx, "roots/list", mcp.ListRootsRequest{}, &roots)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list roots: %w", err)
+		}
+	}
+
+	roots.Roots = append(roots.Roots, s.roots...)
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	config := types.ConfigFromContext(mcp.WithSession(ctx, session))
+	for _, name := range slices.Sorted(maps.Keys(config.Roots)) {
+		dir := config.Roots[name]
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(cwd, dir)
+		}
+		roots.Roots = append(roots.Roots, mcp.Root{
+			Name: name,
+			URI:  "file://" + filepath.ToSlash(dir),
+		})
+	}
+
+	if id := session.ID(); id != "" {
+		dir := filepath.Join(cwd, "sessions", id)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create session directory: %w", err)
+		}
+		roots.Roots = append(roots.Roots, mcp.Root{
+			Name: sessionRootName,
+			URI:  "file://" + filepath.ToSlash(dir),
+		})
+	}
+
+	return roots.Roots, nil
+}
+
+// NotifyRootsChanged tells the MCP servers the session is connected to that
+// its roots changed, so they list them again.
+func (s *Service) NotifyRootsChanged(ctx context.Context) {
+	session := mcp.SessionFromContext(ctx).Root()
+	if session == nil {
+		return
+	}
+
+	for key, value := range session.Attributes() {
+		name, ok := strings.CutPrefix(key, "clients/")
+		if !ok {
+			continue
+		}
+		factory, ok := value.(*clientFactory)
+		if !ok {
+			continue
+		}
+
+		factory.clientLock.Lock()
+		client := factory.client
+		factory.clientLock.Unlock()
+		if client == nil {
+			continue
+		}
+
+		if err := client.Session.SendPayload(ctx, "notifications/roots/list_changed", struct{}{}); err != nil {
+			slog.Warn("failed to send roots list changed notification", "server", name, "error", err)
+		}
+	}
+}
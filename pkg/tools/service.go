@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"maps"
 	"net/http"
 	"net/url"
@@ -19,6 +20,7 @@ import (
 	"github.com/obot-platform/nanobot/pkg/envvar"
 	"github.com/obot-platform/nanobot/pkg/expr"
 	"github.com/obot-platform/nanobot/pkg/fileuri"
+	"github.com/obot-platform/nanobot/pkg/log"
 	"github.com/obot-platform/nanobot/pkg/mcp"
 	"github.com/obot-platform/nanobot/pkg/mcp/auditlogs"
 	"github.com/obot-platform/nanobot/pkg/sampling"
@@ -39,6 +41,15 @@ type Service struct {
 	tokenExchangeClientID     string
 	tokenExchangeClientSecret string
 	auditLogCollector         *auditlogs.Collector
+	toolCallRecorder          auditlogs.ToolCallRecorder
+	auditToolArguments        bool
+	httpClientPool            *mcp.HTTPClientPool
+	results                   resultCache
+	// health is the health of the MCP servers of each session.
+	health sync.Map
+	// warmSessions are the contexts of the sessions WarmUp started the eager
+	// MCP servers in, keyed by warmKey.
+	warmSessions sync.Map
 }
 
 type Sampler interface {
@@ -55,6 +66,11 @@ type Options struct {
 	TokenExchangeClientID     string
 	TokenExchangeClientSecret string
 	AuditLogCollector         *auditlogs.Collector
+	// ToolCallRecorder records every tool call to the audit store.
+	ToolCallRecorder auditlogs.ToolCallRecorder
+	// AuditToolArguments records the redacted arguments of tool calls, not
+	// only their hash.
+	AuditToolArguments bool
 }
 
 func (r Options) Merge(other Options) (result Options) {
@@ -67,6 +83,8 @@ func (r Options) Merge(other Options) (result Options) {
 	result.TokenExchangeClientID = complete.Last(r.TokenExchangeClientID, other.TokenExchangeClientID)
 	result.TokenExchangeClientSecret = complete.Last(r.TokenExchangeClientSecret, other.TokenExchangeClientSecret)
 	result.AuditLogCollector = complete.Last(r.AuditLogCollector, other.AuditLogCollector)
+	result.ToolCallRecorder = complete.Last(r.ToolCallRecorder, other.ToolCallRecorder)
+	result.AuditToolArguments = complete.Last(r.AuditToolArguments, other.AuditToolArguments)
 	return result
 }
 
@@ -89,6 +107,9 @@ func NewToolsService(opts ...Options) *Service {
 		tokenExchangeClientID:     opt.TokenExchangeClientID,
 		tokenExchangeClientSecret: opt.TokenExchangeClientSecret,
 		auditLogCollector:         opt.AuditLogCollector,
+		toolCallRecorder:          opt.ToolCallRecorder,
+		auditToolArguments:        opt.AuditToolArguments,
+		httpClientPool:            mcp.NewHTTPClientPool(),
 	}
 }
 
@@ -104,6 +125,12 @@ func (s *Service) AddServer(name string, factory func(name string) mcp.MessageHa
 	s.serverFactories[name] = factory
 }
 
+// HasServer reports whether a built-in server is registered under name.
+func (s *Service) HasServer(name string) bool {
+	_, ok := s.serverFactories[name]
+	return ok
+}
+
 func (s *Service) SetSampler(sampler Sampler) {
 	s.sampler = sampler
 }
@@ -143,6 +170,41 @@ func (s *Service) collectAuditLog(auditLog *auditlogs.MCPAuditLog) {
 	s.auditLogCollector.CollectMCPAuditEntry(*auditLog)
 }
 
+// recordToolCall appends the tool call to the audit store. Failing to record
+// it doesn't fail the call.
+func (s *Service) recordToolCall(ctx context.Context, server, tool string, args any, start time.Time, ret *types.CallResult, err error) {
+	sessionID, accountID := types.GetSessionAndAccountID(ctx)
+	call := auditlogs.ToolCall{
+		CreatedAt:     start,
+		SessionID:     sessionID,
+		AccountID:     accountID,
+		Agent:         types.CurrentAgent(ctx),
+		Server:        server,
+		Tool:          tool,
+		ArgumentsHash: auditlogs.HashArguments(args),
+		DurationMS:    time.Since(start).Milliseconds(),
+	}
+	if s.auditToolArguments {
+		call.Arguments = auditlogs.RedactArguments(args)
+	}
+	if err != nil {
+		call.IsError = true
+		call.Error = log.RedactSensitive(err.Error())
+	} else if ret != nil && ret.IsError {
+		call.IsError = true
+		for _, content := range ret.Content {
+			if content.Type == "text" {
+				call.Error = log.RedactSensitive(content.Text)
+				break
+			}
+		}
+	}
+
+	if err := s.toolCallRecorder.RecordToolCall(context.WithoutCancel(ctx), call); err != nil {
+		slog.ErrorContext(ctx, "failed to record tool call to the audit log", "server", server, "tool", tool, "error", err)
+	}
+}
+
 func (s *Service) GetDynamicInstruction(ctx context.Context, instruction types.DynamicInstructions) (string, error) {
 	if !instruction.IsSet() {
 		return "", nil
@@ -239,6 +301,13 @@ func (c *clientFactory) get(envHash string) (*mcp.Client, error) {
 	return c.client, nil
 }
 
+// closed reports whether the client of the factory was closed.
+func (c *clientFactory) closed() bool {
+	c.clientLock.Lock()
+	defer c.clientLock.Unlock()
+	return c.client != nil && c.client.Session.Context().Err() != nil
+}
+
 func (c *clientFactory) Serialize() (any, error) {
 	if c.client == nil || c.client.Session.ID() == "" {
 		return nil, nil
@@ -281,19 +350,46 @@ func (s *Service) GetClient(ctx context.Context, name string) (*mcp.Client, erro
 		return nil, fmt.Errorf("failed to hash env map: %w", err)
 	}
 
-	sessionKey := "clients/" + name
-	factory := newClientFactory(func(state *mcp.SessionState) (*mcp.Client, error) {
-		return s.newClient(ctx, name, state)
-	})
-	if session.Get(sessionKey, &factory) {
-		return factory.get(envHash)
+	var health *serverHealth
+	if _, ok := types.ConfigFromContext(ctx).MCPServers[name]; ok && !s.HasServer(name) {
+		health = s.serverHealth(session, name)
+		if err := health.unavailable(name); err != nil {
+			return nil, err
+		}
 	}
 
-	// ensure we are holding the same object
-	session.Set(sessionKey, &factory)
-	session.Get(sessionKey, &factory)
+	sessionKey := "clients/" + name
+	newFactory := func() clientFactory {
+		return newClientFactory(func(state *mcp.SessionState) (*mcp.Client, error) {
+			return s.newClient(ctx, name, state)
+		})
+	}
+	factory := newFactory()
+	// A client closed by its supervisor is replaced by a new one.
+	if !session.Get(sessionKey, &factory) || factory.closed() {
+		factory = newFactory()
+		// ensure we are holding the same object
+		session.Set(sessionKey, &factory)
+		session.Get(sessionKey, &factory)
+	}
 
-	return factory.get(envHash)
+	client, err := factory.get(envHash)
+	if health == nil {
+		return client, err
+	}
+	if err != nil {
+		if health.record(err) {
+			s.notifyToolsChanged(ctx, session, name)
+		}
+		return nil, err
+	}
+	if health.watch(client) {
+		if health.status() == ServerUnavailable && health.record(nil) {
+			s.notifyToolsChanged(ctx, session, name)
+		}
+		go s.supervise(ctx, session, name, client, health)
+	}
+	return client, nil
 }
 
 func envMapHash(env map[string]string) (string, error) {
@@ -336,6 +432,9 @@ func (s *Service) newClient(ctx context.Context, name string, state *mcp.Session
 	if !ok {
 		return nil, fmt.Errorf("MCP server %s not found in config", name)
 	}
+	if mcpConfig.Startup == mcp.StartupDisabled {
+		return nil, fmt.Errorf("MCP server %s is disabled", name)
+	}
 
 	if serverFactory != nil {
 		serverSession, err := mcp.NewExistingServerSession(session.Context(), mcp.SessionState{}, serverFactory(name))
@@ -346,17 +445,7 @@ func (s *Service) newClient(ctx context.Context, name string, state *mcp.Session
 	}
 
 	roots := func(ctx context.Context) ([]mcp.Root, error) {
-		var roots mcp.ListRootsResult
-		if session.InitializeRequest.Capabilities.Roots != nil {
-			err := session.Exchange(ctx, "roots/list", mcp.ListRootsRequest{}, &roots)
-			if err != nil {
-				return nil, fmt.Errorf("failed to list roots: %w", err)
-			}
-		}
-
-		roots.Roots = append(roots.Roots, s.roots...)
-
-		return roots.Roots, nil
+		return s.listRoots(ctx, session)
 	}
 
 	var oauthRedirectURL string
@@ -458,20 +547,20 @@ func (s *Service) newClient(ctx context.Context, name string, state *mcp.Session
 			TokenExchangeEndpoint:     s.tokenExchangeEndpoint,
 			TokenExchangeClientID:     s.tokenExchangeClientID,
 			TokenExchangeClientSecret: s.tokenExchangeClientSecret,
+			Pool:                      s.httpClientPool,
 		},
 		Wire:         wire,
 		SessionState: state,
 		HookRunner:   s,
 	}
 
-	if session.InitializeRequest.Capabilities.Elicitation == nil {
-		clientOpts.OnElicit = func(ctx context.Context, _ mcp.Message, elicitation mcp.ElicitRequest) (result mcp.ElicitResult, _ error) {
-			return mcp.ElicitResult{
-				Action: "cancel",
-			}, nil
-		}
-	} else {
-		clientOpts.OnElicit = func(ctx context.Context, msg mcp.Message, elicitation mcp.ElicitRequest) (result mcp.ElicitResult, err error) {
+	forwardElicit := func(context.Context, mcp.Message, mcp.ElicitRequest) (mcp.ElicitResult, error) {
+		return mcp.ElicitResult{
+			Action: "cancel",
+		}, nil
+	}
+	if session.InitializeRequest.Capabilities.Elicitation != nil {
+		forwardElicit = func(ctx context.Context, msg mcp.Message, elicitation mcp.ElicitRequest) (result mcp.ElicitResult, err error) {
 			auditLog := buildAuditLog(&msg, session)
 			defer func() {
 				if err != nil {
@@ -494,12 +583,22 @@ func (s *Service) newClient(ctx context.Context, name string, state *mcp.Session
 			return result, err
 		}
 	}
-	if s.sampler != nil {
+	clientOpts.OnElicit = func(ctx context.Context, msg mcp.Message, elicitation mcp.ElicitRequest) (mcp.ElicitResult, error) {
+		if result, ok := answerElicitation(mcp.WithSession(ctx, session), name, elicitation); ok {
+			return result, nil
+		}
+		return forwardElicit(ctx, msg, elicitation)
+	}
+	if s.sampler != nil && (mcpConfig.Sampling == nil || !mcpConfig.Sampling.Disabled) {
 		clientOpts.OnSampling = func(ctx context.Context, samplingRequest mcp.CreateMessageRequest) (mcp.CreateMessageResult, error) {
 			msg, err := mcp.NewMessageWithID("sampling/createMessage", samplingRequest)
 			if err != nil {
 				return mcp.CreateMessageResult{}, fmt.Errorf("failed to create message: %w", err)
 			}
+			policy, err := samplingPolicy(mcp.WithSession(ctx, session), name, mcpConfig.Sampling)
+			if err != nil {
+				return mcp.CreateMessageResult{}, err
+			}
 			includeContext := samplingRequest.IncludeContext
 			if includeContext == "" {
 				includeContext = "none"
@@ -511,7 +610,7 @@ func (s *Service) newClient(ctx context.Context, name string, state *mcp.Session
 				Tools:              samplingRequest.Tools,
 				ProgressToken:      uuid.String(),
 				Chat:               new(bool),
-			})
+			}, policy)
 			if err != nil {
 				if errors.Is(err, sampling.ErrNoMatchingModel) && session.InitializeRequest.Capabilities.Sampling != nil {
 					auditLog := buildAuditLog(msg, session)
@@ -561,6 +660,27 @@ func (s *Service) newClient(ctx context.Context, name string, state *mcp.Session
 	return mcp.NewClient(sessionCtx, name, mcpConfig, clientOpts)
 }
 
+// samplingPermission is the permission an agent needs for the servers it
+// uses to sample with its model.
+const samplingPermission = "sampling"
+
+// samplingPolicy returns the sampler options that apply the sampling policy
+// of a server to its requests. The agent using the server needs the sampling
+// permission.
+func samplingPolicy(ctx context.Context, server string, policy *mcp.SamplingPolicy) (sampling.SamplerOptions, error) {
+	opt := sampling.SamplerOptions{
+		Agent: types.CurrentAgent(ctx),
+	}
+	if agent, ok := types.ConfigFromContext(ctx).Agents[opt.Agent]; ok && agent.Permissions != nil && !agent.Permissions.IsAllowed(samplingPermission) {
+		return opt, fmt.Errorf("agent %s is not allowed to sample for %s", opt.Agent, server)
+	}
+	if policy != nil {
+		opt.Agents = policy.Agents
+		opt.MaxTokens = policy.MaxTokens
+	}
+	return opt, nil
+}
+
 func (s *Service) sampleCall(ctx context.Context, agent string, args any, opts ...SampleCallOptions) (*types.CallResult, error) {
 	config := types.ConfigFromContext(ctx)
 	createMessageRequest, err := s.convertToSampleRequest(config, agent, args)
@@ -603,6 +723,10 @@ func (o CallOptions) Merge(other CallOptions) (result CallOptions) {
 }
 
 func (s *Service) RunHook(ctx context.Context, in, out any, target string) (hasOutput bool, _ error) {
+	if isExpressionHook(target) {
+		return runExpressionHook(ctx, in, out, target)
+	}
+
 	server, tool, _ := strings.Cut(target, "/")
 	result, err := s.Call(ctx, server, tool, in)
 	if err != nil {
@@ -635,6 +759,17 @@ func (s *Service) RunHook(ctx context.Context, in, out any, target string) (hasO
 	return false, nil
 }
 
+// allowToolCall counts the call against the tool calls per minute of the
+// account, if the server limits them.
+func allowToolCall(ctx context.Context) error {
+	var limiter types.AccountLimiter
+	if !mcp.SessionFromContext(ctx).Root().Get(types.LimiterSessionKey, &limiter) {
+		return nil
+	}
+	_, accountID := types.GetSessionAndAccountID(ctx)
+	return limiter.AllowToolCall(accountID)
+}
+
 func (s *Service) Call(ctx context.Context, server, tool string, args any, opts ...CallOptions) (ret *types.CallResult, err error) {
 	defer func() {
 		if ret == nil {
@@ -660,6 +795,18 @@ func (s *Service) Call(ctx context.Context, server, tool string, args any, opts
 	if tool != "" {
 		target = server + "/" + tool
 	}
+	ctx = log.WithAttrs(ctx, slog.String(log.ToolNameKey, target))
+
+	if s.toolCallRecorder != nil {
+		auditCtx, start := ctx, time.Now()
+		defer func() {
+			s.recordToolCall(auditCtx, server, tool, args, start, ret, err)
+		}()
+	}
+
+	if err := allowToolCall(ctx); err != nil {
+		return nil, err
+	}
 
 	targetType := "tool"
 	if _, ok := config.Agents[server]; ok {
@@ -750,28 +897,93 @@ func (s *Service) Call(ctx context.Context, server, tool string, args any, opts
 		})
 	}
 
+	if composite, ok := config.CompositeTools[server]; ok && (tool == "" || tool == server) {
+		return s.callComposite(ctx, server, composite, args)
+	}
+
 	c, err := s.GetClient(ctx, server)
 	if err != nil {
 		return nil, err
 	}
 
+	settings := config.MCPServers[server].SettingsForTool(tool)
+
+	if fixed := config.MCPServers[server].CurationForTool(tool).FixedArguments(); len(fixed) > 0 {
+		args, err = withFixedArguments(args, fixed)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var cacheKey string
+	if settings.CacheTTLMS > 0 && session != nil {
+		if key, ok := resultCacheKey(session.Root().ID(), server, tool, args); ok {
+			if cached, ok := s.results.get(key); ok {
+				return cached, nil
+			}
+			cacheKey = key
+		}
+	}
+
 	if targetType != "agent" {
 		// For tools, use the user context so that tool calls can be cancelled by the user.
 		ctx = mcp.UserContext(ctx)
 	}
-	mcpCallResult, err := c.Call(ctx, tool, args, mcp.CallOption{
+	mcpCallResult, err := callWithSettings(ctx, c, target, tool, args, mcp.CallOption{
 		ProgressToken: opt.ProgressToken,
 		Meta:          opt.Meta,
-	})
+	}, settings)
 	if err != nil {
 		return nil, err
 	}
-	return addHookMutationContent(&types.CallResult{
+	mcpCallResult, err = s.applyToolResultHooks(ctx, server, tool, args, mcpCallResult)
+	if err != nil {
+		return nil, err
+	}
+	result := addHookMutationContent(&types.CallResult{
 		Meta:              mcpCallResult.Meta,
 		StructuredContent: mcpCallResult.StructuredContent,
 		Content:           mcpCallResult.Content,
 		IsError:           mcpCallResult.IsError,
-	}), nil
+	})
+	if cacheKey != "" && !result.IsError {
+		s.results.put(cacheKey, *result, time.Duration(settings.CacheTTLMS)*time.Millisecond)
+	}
+	return result, nil
+}
+
+// toolRetryDelay is the delay before the first retry of a failed tool call,
+// doubled for each later retry.
+var toolRetryDelay = 500 * time.Millisecond
+
+// callWithSettings calls the tool, applying the configured per-attempt timeout
+// and retrying calls that fail with an error.
+func callWithSettings(ctx context.Context, c *mcp.Client, target, tool string, args any, callOpt mcp.CallOption, settings mcp.ToolSettings) (*mcp.CallToolResult, error) {
+	for attempt := 0; ; attempt++ {
+		callCtx, cancel := ctx, context.CancelFunc(func() {})
+		if settings.TimeoutMS > 0 {
+			callCtx, cancel = context.WithTimeout(ctx, time.Duration(settings.TimeoutMS)*time.Millisecond)
+		}
+		result, err := c.Call(callCtx, tool, args, callOpt)
+		cancel()
+		if err == nil {
+			return result, nil
+		}
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			err = fmt.Errorf("call to %s timed out after %dms: %w", target, settings.TimeoutMS, err)
+		}
+		if attempt >= settings.MaxRetries || ctx.Err() != nil {
+			return nil, err
+		}
+
+		delay := toolRetryDelay << attempt
+		slog.WarnContext(ctx, "retrying failed tool call", "target", target, "attempt", attempt+1, "delay", delay, "error", err)
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(delay):
+		}
+	}
 }
 
 func addHookMutationContent(response *types.CallResult) *types.CallResult {
@@ -835,22 +1047,31 @@ func (s *Service) ListTools(ctx context.Context, opts ...ListToolsOptions) (resu
 	serverList := slices.Sorted(maps.Keys(config.MCPServers))
 	agentsList := slices.Sorted(maps.Keys(config.Agents))
 	if len(opt.Servers) == 0 {
-		opt.Servers = append(serverList, agentsList...)
+		opt.Servers = slices.Concat(serverList, agentsList, slices.Sorted(maps.Keys(config.CompositeTools)))
 	}
 
 	for _, server := range opt.Servers {
-		if !slices.Contains(serverList, server) {
+		if !slices.Contains(serverList, server) || config.MCPServers[server].Startup == mcp.StartupDisabled {
 			continue
 		}
 
-		c, err := s.GetClient(ctx, server)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get client for %s: %w", server, err)
-		}
+		// Eager servers are already running, so their tools are listed
+		// without starting them for the session.
+		tools, ok := s.warmToolList(ctx, server, config.MCPServers[server])
+		if !ok {
+			c, err := s.GetClient(ctx, server)
+			if errors.Is(err, ErrServerUnavailable) {
+				// The tools of an unavailable server are hidden until it
+				// reconnects.
+				continue
+			} else if err != nil {
+				return nil, fmt.Errorf("failed to get client for %s: %w", server, err)
+			}
 
-		tools, err := c.ListTools(ctx)
-		if err != nil {
-			return nil, fmt.Errorf("failed to list tools for %s: %w", server, err)
+			tools, err = c.ListTools(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list tools for %s: %w", server, err)
+			}
 		}
 
 		tools = filterTools(tools, opt.Tools)
@@ -891,6 +1112,26 @@ func (s *Service) ListTools(ctx context.Context, opts ...ListToolsOptions) (resu
 		})
 	}
 
+	for _, name := range opt.Servers {
+		composite, ok := config.CompositeTools[name]
+		if !ok {
+			continue
+		}
+
+		tools := filterTools(&mcp.ListToolsResult{
+			Tools: []mcp.Tool{compositeTool(name, composite)},
+		}, opt.Tools)
+
+		if len(tools.Tools) == 0 {
+			continue
+		}
+
+		result = append(result, ListToolsResult{
+			Server: name,
+			Tools:  tools.Tools,
+		})
+	}
+
 	return
 }
 
@@ -972,6 +1213,45 @@ func (s *Service) BuildToolMappings(ctx context.Context, toolList []string, opts
 		maps.Copy(result, s.getMatches(ref, tools, opts...))
 	}
 
+	config := types.ConfigFromContext(ctx)
+	for name, mapping := range result {
+		curation := config.MCPServers[mapping.MCPServer].CurationForTool(mapping.TargetName)
+		if curation.IsZero() {
+			continue
+		}
+		tool, err := curation.Apply(mapping.Target.Tool)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to curate tool", "server", mapping.MCPServer, "tool", mapping.TargetName, "error", err)
+			continue
+		}
+		mapping.Target.Tool = tool
+		result[name] = mapping
+	}
+
+	return result, nil
+}
+
+// withFixedArguments returns the arguments of a call with the values of the
+// fixed parameters of the tool, which the model can't change.
+func withFixedArguments(args any, fixed map[string]any) (map[string]any, error) {
+	var result map[string]any
+	switch a := args.(type) {
+	case nil:
+	case map[string]any:
+		result = maps.Clone(a)
+	default:
+		data, err := json.Marshal(args)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal arguments: %w", err)
+		}
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, mcp.ErrRPCInvalidParams.WithMessage("arguments must be an object: %v", err)
+		}
+	}
+	if result == nil {
+		result = map[string]any{}
+	}
+	maps.Copy(result, fixed)
 	return result, nil
 }
 
@@ -1,8 +1,14 @@
 package tools
 
 import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/obot-platform/nanobot/pkg/mcp"
 	"github.com/obot-platform/nanobot/pkg/types"
@@ -170,3 +176,304 @@ func TestAddHookMutationContentOmitsUnmutatedDirections(t *testing.T) {
 		t.Fatalf("original tool output was not preserved after request mutation content: %#v", response.Content)
 	}
 }
+
+func TestSettingsForToolLayersOverAllTools(t *testing.T) {
+	server := mcp.Server{
+		ToolSettings: map[string]mcp.ToolSettings{
+			mcp.AllToolsSettingsKey: {TimeoutMS: 1000, MaxRetries: 2},
+			"slow":                  {TimeoutMS: 60000, CacheTTLMS: 5000},
+		},
+	}
+
+	got := server.SettingsForTool("slow")
+	want := mcp.ToolSettings{TimeoutMS: 60000, MaxRetries: 2, CacheTTLMS: 5000}
+	if got != want {
+		t.Fatalf("SettingsForTool(slow) = %+v, want %+v", got, want)
+	}
+
+	got = server.SettingsForTool("other")
+	want = mcp.ToolSettings{TimeoutMS: 1000, MaxRetries: 2}
+	if got != want {
+		t.Fatalf("SettingsForTool(other) = %+v, want %+v", got, want)
+	}
+}
+
+func TestResultCacheExpires(t *testing.T) {
+	var cache resultCache
+
+	key, ok := resultCacheKey("session", "server", "tool", map[string]any{"q": "x"})
+	if !ok {
+		t.Fatal("expected cache key")
+	}
+	otherKey, _ := resultCacheKey("session", "server", "tool", map[string]any{"q": "y"})
+
+	cache.put(key, types.CallResult{Content: []mcp.Content{{Type: "text", Text: "cached"}}}, time.Minute)
+	if result, ok := cache.get(key); !ok || result.Content[0].Text != "cached" {
+		t.Fatalf("get() = %v, %v", result, ok)
+	}
+	if _, ok := cache.get(otherKey); ok {
+		t.Fatal("expected a miss for different arguments")
+	}
+
+	cache.put(otherKey, types.CallResult{}, -time.Second)
+	if _, ok := cache.get(otherKey); ok {
+		t.Fatal("expected expired entry to miss")
+	}
+}
+
+func TestResultCacheCopiesContent(t *testing.T) {
+	var cache resultCache
+
+	result := types.CallResult{Content: []mcp.Content{{Type: "text", Text: "cached"}}}
+	cache.put("key", result, time.Minute)
+	result.Content[0].Text = "changed after put"
+
+	got, ok := cache.get("key")
+	if !ok || got.Content[0].Text != "cached" {
+		t.Fatalf("get() = %v, %v", got, ok)
+	}
+	got.Content[0].Text = "truncated"
+
+	if got, _ := cache.get("key"); got.Content[0].Text != "cached" {
+		t.Errorf("expected the cached result to be unchanged, got %q", got.Content[0].Text)
+	}
+}
+
+func TestResultCacheEvictsOldest(t *testing.T) {
+	defer func(v int) { maxCachedResults = v }(maxCachedResults)
+	maxCachedResults = 2
+
+	var cache resultCache
+	for _, key := range []string{"a", "b", "c"} {
+		cache.put(key, types.CallResult{}, time.Minute)
+	}
+	if len(cache.entries) != 2 {
+		t.Errorf("expected 2 cached results, got %d", len(cache.entries))
+	}
+	if _, ok := cache.get("a"); ok {
+		t.Error("expected the oldest result to be evicted")
+	}
+	if _, ok := cache.get("c"); !ok {
+		t.Error("expected the newest result to be cached")
+	}
+}
+
+func TestListRoots(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	serverSession, err := mcp.NewExistingServerSession(t.Context(), mcp.SessionState{ID: "s1"}, mcp.MessageHandlerFunc(func(context.Context, mcp.Message) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { serverSession.Close(false) })
+
+	session := serverSession.GetSession()
+	session.Set(types.ConfigSessionKey, types.Config{
+		Roots: map[string]string{"project": "src", "shared": "/srv/shared"},
+	})
+
+	s := &Service{roots: []mcp.Root{{Name: "cli", URI: "file:///cli"}}}
+	roots, err := s.listRoots(t.Context(), session)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []mcp.Root{
+		{Name: "cli", URI: "file:///cli"},
+		{Name: "project", URI: "file://" + filepath.ToSlash(filepath.Join(dir, "src"))},
+		{Name: "shared", URI: "file:///srv/shared"},
+		{Name: sessionRootName, URI: "file://" + filepath.ToSlash(filepath.Join(dir, "sessions", "s1"))},
+	}
+	if !reflect.DeepEqual(roots, want) {
+		t.Errorf("expected roots %v, got %v", want, roots)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sessions", "s1")); err != nil {
+		t.Errorf("expected the session directory to be created: %v", err)
+	}
+}
+
+func TestAnswerElicitation(t *testing.T) {
+	serverSession, err := mcp.NewExistingServerSession(t.Context(), mcp.SessionState{ID: "s1"}, mcp.MessageHandlerFunc(func(context.Context, mcp.Message) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { serverSession.Close(false) })
+
+	session := serverSession.GetSession()
+	session.Set(types.CurrentAgentSessionKey, "ops")
+	ctx := types.WithConfig(mcp.WithSession(t.Context(), session), types.Config{
+		Agents: map[string]types.Agent{
+			"ops": {HookAgent: types.HookAgent{Elicitation: &types.AgentElicitation{
+				Action:  types.ElicitationAccept,
+				Servers: map[string]types.ElicitationAction{"deploy": types.ElicitationDecline, "auth": types.ElicitationForward},
+				Content: map[string]any{"confirm": true},
+			}}},
+		},
+	})
+
+	request := mcp.ElicitRequest{
+		Message: "Continue?",
+		RequestedSchema: mcp.PrimitiveSchema{
+			Type: "object",
+			Properties: map[string]mcp.PrimitiveProperty{
+				"confirm": {Type: "boolean"},
+				"region":  {Type: "string", Default: "us-east-1"},
+			},
+			Required: []string{"confirm"},
+		},
+	}
+
+	result, ok := answerElicitation(ctx, "files", request)
+	if !ok || result.Action != "accept" || result.Content["confirm"] != true || result.Content["region"] != "us-east-1" {
+		t.Errorf("expected the request to be accepted with the configured content, got %v %+v", ok, result)
+	}
+	if result, ok := answerElicitation(ctx, "deploy", request); !ok || result.Action != "decline" {
+		t.Errorf("expected the request of deploy to be declined, got %v %+v", ok, result)
+	}
+	if _, ok := answerElicitation(ctx, "auth", request); ok {
+		t.Error("expected the request of auth to be forwarded")
+	}
+	if _, ok := answerElicitation(ctx, "files", mcp.ElicitRequest{Mode: "url", URL: "https://example.com/login"}); ok {
+		t.Error("expected a URL elicitation to be forwarded")
+	}
+
+	request.RequestedSchema.Required = []string{"reason"}
+	if result, ok := answerElicitation(ctx, "files", request); !ok || result.Action != "decline" {
+		t.Errorf("expected a request with a required field without a value to be declined, got %v %+v", ok, result)
+	}
+}
+
+func TestCallComposite(t *testing.T) {
+	var calls []string
+	s := &Service{}
+	s.AddServer("shell", func(string) mcp.MessageHandler {
+		return mcp.MessageHandlerFunc(func(ctx context.Context, msg mcp.Message) {
+			switch msg.Method {
+			case "initialize":
+				_ = msg.Reply(ctx, mcp.InitializeResult{
+					ProtocolVersion: "2025-06-18",
+					Capabilities:    mcp.ServerCapabilities{Tools: &mcp.ToolsServerCapability{}},
+				})
+			case "tools/call":
+				var call mcp.CallToolRequest
+				_ = json.Unmarshal(msg.Params, &call)
+				args, _ := json.Marshal(call.Arguments)
+				calls = append(calls, call.Name+" "+string(args))
+				if call.Name == "fail" {
+					_ = msg.Reply(ctx, mcp.CallToolResult{IsError: true, Content: []mcp.Content{{Type: "text", Text: "boom"}}})
+					return
+				}
+				_ = msg.Reply(ctx, mcp.CallToolResult{
+					Content:           []mcp.Content{{Type: "text", Text: call.Name + " done"}},
+					StructuredContent: map[string]any{"image": "app:1"},
+				})
+			}
+		})
+	})
+
+	serverSession, err := mcp.NewExistingServerSession(t.Context(), mcp.SessionState{ID: "s1"}, mcp.MessageHandlerFunc(func(context.Context, mcp.Message) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { serverSession.Close(false) })
+
+	session := serverSession.GetSession()
+	session.Set(types.ConfigSessionKey, types.Config{
+		CompositeTools: map[string]types.CompositeTool{
+			"deploy": {Steps: []types.CompositeToolStep{
+				{ID: "build", Tool: "shell/build", Args: map[string]any{"dir": "${inputs.dir}"}},
+				{Tool: "shell/apply", Args: map[string]any{"image": "${steps.build.output.image}"}},
+			}},
+			"broken": {Steps: []types.CompositeToolStep{
+				{Tool: "shell/fail"},
+				{Tool: "shell/apply"},
+			}},
+		},
+	})
+	ctx := mcp.WithSession(t.Context(), session)
+
+	tools, err := s.ListTools(ctx, ListToolsOptions{Servers: []string{"deploy"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tools) != 1 || tools[0].Tools[0].Name != "deploy" || string(tools[0].Tools[0].InputSchema) != `{"type":"object"}` {
+		t.Errorf("unexpected tools %+v", tools)
+	}
+
+	result, err := s.Call(ctx, "deploy", "deploy", map[string]any{"dir": "web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.IsError || result.Content[0].Text != "apply done" {
+		t.Errorf("expected the result of the last step, got %+v", result)
+	}
+	if want := []string{`build {"dir":"web"}`, `apply {"image":"app:1"}`}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("expected calls %v, got %v", want, calls)
+	}
+
+	calls = nil
+	result, err = s.Call(ctx, "broken", "broken", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.IsError || !strings.Contains(result.Content[0].Text, "boom") || len(calls) != 1 {
+		t.Errorf("expected the failed step to stop the tool, got %+v after %v", result, calls)
+	}
+}
+
+func TestToolResultHooks(t *testing.T) {
+	s := &Service{}
+	s.AddServer("shell", func(string) mcp.MessageHandler {
+		return mcp.MessageHandlerFunc(func(ctx context.Context, msg mcp.Message) {
+			switch msg.Method {
+			case "initialize":
+				_ = msg.Reply(ctx, mcp.InitializeResult{
+					ProtocolVersion: "2025-06-18",
+					Capabilities:    mcp.ServerCapabilities{Tools: &mcp.ToolsServerCapability{}},
+				})
+			case "tools/call":
+				_ = msg.Reply(ctx, mcp.CallToolResult{
+					Content: []mcp.Content{{Type: "text", Text: "token=s3cr3t"}},
+				})
+			}
+		})
+	})
+
+	serverSession, err := mcp.NewExistingServerSession(t.Context(), mcp.SessionState{ID: "s1"}, mcp.MessageHandlerFunc(func(context.Context, mcp.Message) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { serverSession.Close(false) })
+
+	session := serverSession.GetSession()
+	session.Set(types.ConfigSessionKey, types.Config{
+		Hooks: mcp.Hooks{
+			{
+				Name:   "toolResult",
+				Params: map[string]string{"server": "shell", "tool": "env"},
+				Targets: []mcp.HookTarget{
+					{Target: `${({result: {content: result.content.map(c => ({type: c.type, text: c.text.replaceAll("s3cr3t", "[REDACTED]")}))}})}`},
+					{Target: `${({result: {content: [{type: "text", text: "ignored"}]}})}`, MutateDisallowed: true},
+				},
+			},
+		},
+	})
+	ctx := mcp.WithSession(t.Context(), session)
+
+	result, err := s.Call(ctx, "shell", "env", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Content) != 1 || result.Content[0].Text != "token=[REDACTED]" {
+		t.Errorf("expected the result to be redacted, got %+v", result.Content)
+	}
+
+	result, err = s.Call(ctx, "shell", "ls", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Content[0].Text != "token=s3cr3t" {
+		t.Errorf("expected the hook to only apply to the env tool, got %+v", result.Content)
+	}
+}
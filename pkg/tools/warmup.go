@@ -0,0 +1,99 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+// warmKey identifies an eager MCP server by its name and config, so a server
+// whose config changed is started again by the session that uses it.
+func warmKey(name string, server mcp.Server) string {
+	data, err := json.Marshal(server)
+	if err != nil {
+		return ""
+	}
+	hash := sha256.Sum256(data)
+	return name + "@" + hex.EncodeToString(hash[:])
+}
+
+// sessionIndependent reports whether a server looks the same to every
+// session: its config doesn't reference the env of the session, and it gets
+// neither the headers nor the token of the user of the session.
+func sessionIndependent(ctx context.Context, server mcp.Server) bool {
+	if len(server.PassthroughHeaders) > 0 || server.BaseURL != "" && mcp.TokenFromContext(ctx) != "" {
+		return false
+	}
+	data, err := json.Marshal(server)
+	return err == nil && !strings.Contains(string(data), "${")
+}
+
+// WarmUp starts the eager MCP servers of the config and fetches their tools,
+// so that listing the tools of a new session doesn't wait for them to start.
+// The servers run in a session of their own until the context is done, and
+// only list the tools of the sessions they are sessionIndependent of. Tool
+// calls still start a client of the session, so the env, headers and token of
+// its user apply. Servers that fail to start are logged and left to start
+// lazily.
+func (s *Service) WarmUp(ctx context.Context, config types.Config, env map[string]string) {
+	session := mcp.NewEmptySession(ctx)
+	session.SetEnv(env)
+	session.Set(types.ConfigSessionKey, &config)
+	ctx = mcp.WithSession(types.WithConfig(ctx, config), session)
+
+	var wg sync.WaitGroup
+	for name, server := range config.MCPServers {
+		if server.Startup != mcp.StartupEager {
+			continue
+		}
+		wg.Go(func() {
+			start := time.Now()
+			c, err := s.GetClient(ctx, name)
+			if err != nil {
+				slog.Error("failed to start eager MCP server", "server", name, "error", err)
+				return
+			}
+			tools, err := c.ListTools(ctx)
+			if err != nil {
+				slog.Error("failed to list tools of eager MCP server", "server", name, "error", err)
+				return
+			}
+			s.warmSessions.Store(warmKey(name, server), ctx)
+			slog.Info("started eager MCP server", "server", name, "tools", len(tools.Tools), "duration", time.Since(start))
+		})
+	}
+	wg.Wait()
+}
+
+// warmToolList lists the tools of an eager MCP server with the client of the
+// session started by WarmUp, if the server is sessionIndependent and available
+// to the session of the context. The client is looked up on every call, so a
+// client reconnected after failing its health checks is used.
+func (s *Service) warmToolList(ctx context.Context, name string, server mcp.Server) (*mcp.ListToolsResult, bool) {
+	if server.Startup != mcp.StartupEager || !sessionIndependent(ctx, server) || s.ServerStatus(ctx, name) == ServerUnavailable {
+		return nil, false
+	}
+	warmCtx, ok := s.warmSessions.Load(warmKey(name, server))
+	if !ok || warmCtx.(context.Context).Err() != nil {
+		return nil, false
+	}
+	c, err := s.GetClient(warmCtx.(context.Context), name)
+	if err != nil {
+		slog.Warn("eager MCP server is not running, starting it for the session", "server", name, "error", err)
+		return nil, false
+	}
+	tools, err := c.ListTools(ctx)
+	if err != nil {
+		slog.Warn("failed to list tools of eager MCP server, starting it for the session", "server", name, "error", err)
+		return nil, false
+	}
+	return tools, true
+}
@@ -0,0 +1,77 @@
+package tools
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+func TestWarmUp(t *testing.T) {
+	var initialized atomic.Int32
+	handler := mcp.MessageHandlerFunc(func(ctx context.Context, msg mcp.Message) {
+		switch msg.Method {
+		case "initialize":
+			initialized.Add(1)
+			_ = msg.Reply(ctx, mcp.InitializeResult{
+				ProtocolVersion: "2025-06-18",
+				Capabilities:    mcp.ServerCapabilities{Tools: &mcp.ToolsServerCapability{}},
+			})
+		case "tools/list":
+			_ = msg.Reply(ctx, mcp.ListToolsResult{Tools: []mcp.Tool{{Name: "query"}}})
+		case "ping":
+			_ = msg.Reply(ctx, struct{}{})
+		}
+	})
+	mcpServer, err := mcp.NewHTTPServer(t.Context(), nil, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	upstream := httptest.NewServer(mcpServer)
+	// The warm-up session is closed with the context of the test, before the
+	// cleanup.
+	t.Cleanup(upstream.Close)
+
+	config := types.Config{
+		MCPServers: map[string]mcp.Server{
+			"search": {BaseURL: upstream.URL, Startup: mcp.StartupEager},
+			// The tools of a server using the env of the session are listed
+			// by a client of the session.
+			"keyed": {BaseURL: upstream.URL, Startup: mcp.StartupEager, Headers: map[string]string{"Authorization": "Bearer ${TOKEN}"}},
+			"off":   {BaseURL: "http://127.0.0.1:1", Startup: mcp.StartupDisabled},
+		},
+	}
+
+	s := &Service{}
+	s.WarmUp(t.Context(), config, nil)
+	if got := initialized.Load(); got != 2 {
+		t.Fatalf("expected search and keyed to be started once, got %d", got)
+	}
+
+	serverSession, err := mcp.NewExistingServerSession(t.Context(), mcp.SessionState{ID: "s1"}, mcp.MessageHandlerFunc(func(context.Context, mcp.Message) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { serverSession.Close(false) })
+	session := serverSession.GetSession()
+	session.Set(types.ConfigSessionKey, config)
+	ctx := mcp.WithSession(t.Context(), session)
+
+	tools, err := s.ListTools(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tools) != 2 || tools[0].Server != "keyed" || tools[1].Server != "search" || tools[1].Tools[0].Name != "query" {
+		t.Errorf("expected the tools of keyed and search, got %+v", tools)
+	}
+	if got := initialized.Load(); got != 3 {
+		t.Errorf("expected the session to start only keyed, got %d starts", got)
+	}
+
+	if _, err := s.GetClient(ctx, "off"); err == nil {
+		t.Error("expected a disabled server not to start")
+	}
+}
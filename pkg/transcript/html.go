@@ -0,0 +1,69 @@
+package transcript
+
+import (
+	"html/template"
+	"io"
+	"strings"
+	"time"
+)
+
+var htmlTemplate = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"summary": toolSummary,
+	"time": func(t *time.Time) string {
+		return t.Format(time.RFC1123)
+	},
+	// html/template refuses data URIs, the ones of images and audio are safe
+	// to use as a source.
+	"src": func(uri string) any {
+		if strings.HasPrefix(uri, "data:image/") || strings.HasPrefix(uri, "data:audio/") {
+			return template.URL(uri)
+		}
+		return uri
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 50rem; margin: 2rem auto; padding: 0 1rem; line-height: 1.5; }
+.message { border-radius: 0.5rem; padding: 0.5rem 1rem; margin: 1rem 0; background: #f6f6f6; }
+.message.user { background: #e8f0fe; }
+.role { font-weight: bold; }
+.created { color: #666; font-size: 0.85em; margin-left: 0.5rem; }
+.text { white-space: pre-wrap; }
+pre { background: #fff; border: 1px solid #ddd; padding: 0.5rem; overflow-x: auto; }
+details { margin: 0.5rem 0; }
+summary { cursor: pointer; color: #444; }
+.error > summary { color: #b00020; }
+img { max-width: 100%; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{range .Entries}}<div class="message {{if eq .Role "User"}}user{{end}}">
+<div><span class="role">{{.Role}}</span>{{with .Created}}<span class="created">{{time .}}</span>{{end}}</div>
+{{range .Blocks}}{{template "block" .}}{{end}}</div>
+{{end}}</body>
+</html>
+{{define "block"}}{{if .Text}}<div class="text">{{.Text}}</div>
+{{else if .Image}}<img alt="{{.Image.Alt}}" src="{{src .Image.DataURI}}">
+{{else if .Audio}}<audio controls src="{{src .Audio.DataURI}}"></audio>
+{{else if .Link}}<p><a href="{{.Link.URI}}">{{.Link.Name}}</a></p>
+{{else if .Code}}<p><code>{{.Code.Name}}</code></p><pre>{{.Code.Text}}</pre>
+{{else if .Reasoning}}<details><summary>Reasoning</summary><div class="text">{{.Reasoning}}</div></details>
+{{else if .Tool}}<details{{if .Tool.IsError}} class="error"{{end}}><summary>{{summary .Tool}}</summary>
+{{if .Tool.Arguments}}<pre>{{.Tool.Arguments}}</pre>
+{{end}}{{range .Tool.Result}}{{if .Text}}<pre>{{.Text}}</pre>
+{{else}}{{template "block" .}}{{end}}{{end}}</details>
+{{end}}{{end}}`))
+
+func renderHTML(w io.Writer, title string, entries []entry) error {
+	return htmlTemplate.Execute(w, struct {
+		Title   string
+		Entries []entry
+	}{
+		Title:   title,
+		Entries: entries,
+	})
+}
@@ -0,0 +1,85 @@
+package transcript
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+)
+
+func renderMarkdown(w io.Writer, title string, entries []entry) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", title)
+
+	for _, e := range entries {
+		fmt.Fprintf(&b, "\n## %s", e.Role)
+		if e.Created != nil {
+			fmt.Fprintf(&b, " · %s", e.Created.Format(time.RFC1123))
+		}
+		b.WriteString("\n")
+		for _, blk := range e.Blocks {
+			b.WriteString("\n")
+			writeMarkdownBlock(&b, blk, false)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeMarkdownBlock writes a block. Text of tool results is quoted as code,
+// it is rarely Markdown.
+func writeMarkdownBlock(b *strings.Builder, blk block, quoteText bool) {
+	switch {
+	case blk.Text != "" && quoteText:
+		writeFence(b, "", blk.Text)
+	case blk.Text != "":
+		b.WriteString(strings.TrimRight(blk.Text, "\n"))
+		b.WriteString("\n")
+	case blk.Image != nil:
+		fmt.Fprintf(b, "![%s](%s)\n", blk.Image.Alt, blk.Image.DataURI)
+	case blk.Audio != nil:
+		fmt.Fprintf(b, "[%s](%s)\n", blk.Audio.Alt, blk.Audio.DataURI)
+	case blk.Link != nil:
+		fmt.Fprintf(b, "[%s](<%s>)\n", blk.Link.Name, blk.Link.URI)
+	case blk.Code != nil:
+		fmt.Fprintf(b, "`%s`\n\n", blk.Code.Name)
+		writeFence(b, "", blk.Code.Text)
+	case blk.Reasoning != "":
+		b.WriteString("<details>\n<summary>Reasoning</summary>\n\n")
+		b.WriteString(blk.Reasoning)
+		b.WriteString("\n\n</details>\n")
+	case blk.Tool != nil:
+		t := blk.Tool
+		fmt.Fprintf(b, "<details>\n<summary>%s</summary>\n\n", html.EscapeString(toolSummary(t)))
+		if t.Arguments != "" {
+			writeFence(b, "json", t.Arguments)
+		}
+		for _, result := range t.Result {
+			b.WriteString("\n")
+			writeMarkdownBlock(b, result, true)
+		}
+		b.WriteString("\n</details>\n")
+	}
+}
+
+// writeFence writes text as a code block, with a fence longer than any run of
+// backticks in the text.
+func writeFence(b *strings.Builder, lang, text string) {
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	fmt.Fprintf(b, "%s%s\n%s\n%s\n", fence, lang, strings.TrimRight(text, "\n"), fence)
+}
+
+func toolSummary(t *tool) string {
+	switch {
+	case !t.Done:
+		return "Tool call: " + t.Name + " (no result)"
+	case t.IsError:
+		return "Tool call: " + t.Name + " (failed)"
+	}
+	return "Tool call: " + t.Name
+}
@@ -0,0 +1,209 @@
+// Package transcript renders the messages of a chat as a readable Markdown or
+// HTML document.
+package transcript
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+type Options struct {
+	// Title of the transcript, defaults to "Chat Transcript".
+	Title string
+	// Reasoning includes the summaries of the model's reasoning.
+	Reasoning bool
+}
+
+// MimeType returns the mime type of a transcript format.
+func MimeType(format string) string {
+	if format == FormatHTML {
+		return "text/html"
+	}
+	return "text/markdown"
+}
+
+// Render writes the transcript of messages in a format. Tool calls are
+// collapsed with their results and images are embedded as data URIs, so the
+// transcript is a single file.
+func Render(w io.Writer, format string, messages []types.Message, opts Options) error {
+	if opts.Title == "" {
+		opts.Title = "Chat Transcript"
+	}
+
+	entries := toEntries(types.ConsolidateTools(messages), opts)
+	switch format {
+	case "", FormatMarkdown:
+		return renderMarkdown(w, opts.Title, entries)
+	case FormatHTML:
+		return renderHTML(w, opts.Title, entries)
+	default:
+		return fmt.Errorf("unknown transcript format %q, must be %s or %s", format, FormatMarkdown, FormatHTML)
+	}
+}
+
+// entry is a message of the transcript.
+type entry struct {
+	Role    string
+	Created *time.Time
+	Blocks  []block
+}
+
+// block is a part of a message, exactly one of its fields is set.
+type block struct {
+	Text      string
+	Image     *media
+	Audio     *media
+	Link      *link
+	Code      *code
+	Tool      *tool
+	Reasoning string
+}
+
+type media struct {
+	Alt     string
+	DataURI string
+}
+
+type link struct {
+	Name string
+	URI  string
+}
+
+type code struct {
+	Name string
+	Text string
+}
+
+type tool struct {
+	Name      string
+	Arguments string
+	Done      bool
+	IsError   bool
+	Result    []block
+}
+
+func toEntries(messages []types.Message, opts Options) (entries []entry) {
+	for _, msg := range messages {
+		e := entry{
+			Role:    roleName(msg.Role),
+			Created: msg.Created,
+		}
+		for _, item := range msg.Items {
+			switch {
+			case item.ToolCall != nil:
+				t := &tool{
+					Name:      item.ToolCall.Name,
+					Arguments: prettyJSON(item.ToolCall.Arguments),
+				}
+				if item.ToolCallResult != nil {
+					t.Done = true
+					t.IsError = item.ToolCallResult.Output.IsError
+					t.Result = contentBlocks(item.ToolCallResult.Output.Content)
+				}
+				e.Blocks = append(e.Blocks, block{Tool: t})
+			case item.ToolCallResult != nil:
+				// A result without its call, the call was compacted away.
+				e.Blocks = append(e.Blocks, block{Tool: &tool{
+					Name:    "result of " + item.ToolCallResult.CallID,
+					Done:    true,
+					IsError: item.ToolCallResult.Output.IsError,
+					Result:  contentBlocks(item.ToolCallResult.Output.Content),
+				}})
+			case item.Reasoning != nil:
+				if !opts.Reasoning {
+					continue
+				}
+				var summary []string
+				for _, s := range item.Reasoning.Summary {
+					summary = append(summary, s.Text)
+				}
+				if text := strings.TrimSpace(strings.Join(summary, "\n\n")); text != "" {
+					e.Blocks = append(e.Blocks, block{Reasoning: text})
+				}
+			case item.Content != nil:
+				e.Blocks = append(e.Blocks, contentBlocks([]mcp.Content{*item.Content})...)
+			}
+		}
+		if len(e.Blocks) > 0 {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+func contentBlocks(contents []mcp.Content) (blocks []block) {
+	for _, c := range contents {
+		switch c.Type {
+		case "text":
+			if c.Text != "" {
+				blocks = append(blocks, block{Text: c.Text})
+			}
+		case "image":
+			blocks = append(blocks, block{Image: &media{Alt: "image", DataURI: dataURI(c.MIMEType, c.Data)}})
+		case "audio":
+			blocks = append(blocks, block{Audio: &media{Alt: "audio", DataURI: dataURI(c.MIMEType, c.Data)}})
+		case "resource_link":
+			blocks = append(blocks, block{Link: &link{Name: linkName(c.Name, c.URI), URI: c.URI}})
+		case "resource":
+			if c.Resource == nil {
+				continue
+			}
+			r := c.Resource
+			switch {
+			case r.Text != "":
+				blocks = append(blocks, block{Code: &code{Name: linkName(r.Name, r.URI), Text: r.Text}})
+			case r.Blob != "" && strings.HasPrefix(r.MIMEType, "image/"):
+				blocks = append(blocks, block{Image: &media{Alt: linkName(r.Name, r.URI), DataURI: dataURI(r.MIMEType, r.Blob)}})
+			default:
+				blocks = append(blocks, block{Link: &link{Name: linkName(r.Name, r.URI), URI: r.URI}})
+			}
+		}
+	}
+	return blocks
+}
+
+func roleName(role string) string {
+	switch role {
+	case "user":
+		return "User"
+	case "assistant", "":
+		return "Assistant"
+	}
+	return strings.ToUpper(role[:1]) + role[1:]
+}
+
+func linkName(name, uri string) string {
+	if name != "" {
+		return name
+	}
+	return uri
+}
+
+func dataURI(mimeType, data string) string {
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	return "data:" + mimeType + ";base64," + data
+}
+
+// prettyJSON indents the JSON arguments of a tool call, leaving anything else
+// as it is.
+func prettyJSON(s string) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(s), "", "  "); err != nil {
+		return s
+	}
+	return buf.String()
+}
@@ -0,0 +1,85 @@
+package transcript
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hexops/autogold/v2"
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+var testMessages = []types.Message{
+	{
+		ID:   "m1",
+		Role: "user",
+		Items: []types.CompletionItem{
+			{Content: &mcp.Content{Type: "text", Text: "What is in `notes.md`?"}},
+			{Content: &mcp.Content{Type: "image", MIMEType: "image/png", Data: "iVBORw0K"}},
+		},
+	},
+	{
+		ID:   "m2",
+		Role: "assistant",
+		Items: []types.CompletionItem{
+			{Reasoning: &types.Reasoning{Summary: []types.SummaryText{{Text: "I should read the file."}}}},
+			{ToolCall: &types.ToolCall{CallID: "c1", Name: "read", Arguments: `{"path":"notes.md"}`}},
+		},
+	},
+	{
+		ID:   "m3",
+		Role: "user",
+		Items: []types.CompletionItem{
+			{ToolCallResult: &types.ToolCallResult{CallID: "c1", Output: types.CallResult{
+				Content: []mcp.Content{{Type: "text", Text: "```go\nfmt.Println()\n```"}},
+			}}},
+		},
+	},
+	{
+		ID:   "m4",
+		Role: "assistant",
+		Items: []types.CompletionItem{
+			{Content: &mcp.Content{Type: "text", Text: "It prints an empty line."}},
+		},
+	},
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	var out strings.Builder
+	if err := Render(&out, FormatMarkdown, testMessages, Options{Title: "Notes"}); err != nil {
+		t.Fatal(err)
+	}
+	autogold.Expect("# Notes\n\n## User\n\nWhat is in `notes.md`?\n\n![image](data:image/png;base64,iVBORw0K)\n\n## Assistant\n\n<details>\n<summary>Tool call: read</summary>\n\n```json\n{\n  \"path\": \"notes.md\"\n}\n```\n\n````\n```go\nfmt.Println()\n```\n````\n\n</details>\n\n## Assistant\n\nIt prints an empty line.\n").Equal(t, out.String())
+
+	out.Reset()
+	if err := Render(&out, FormatMarkdown, testMessages, Options{Reasoning: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "I should read the file.") {
+		t.Errorf("expected the reasoning in the transcript, got %s", out.String())
+	}
+}
+
+func TestRenderHTML(t *testing.T) {
+	var out strings.Builder
+	if err := Render(&out, FormatHTML, []types.Message{{
+		Role: "user",
+		Items: []types.CompletionItem{
+			{Content: &mcp.Content{Type: "text", Text: "<script>alert(1)</script>"}},
+			{Content: &mcp.Content{Type: "image", MIMEType: "image/png", Data: "iVBORw0K"}},
+			{Content: &mcp.Content{Type: "resource_link", Name: "bad", URI: "javascript:alert(1)"}},
+		},
+	}}, Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	html := out.String()
+	for _, want := range []string{"&lt;script&gt;", `src="data:image/png;base64,iVBORw0K"`, "<title>Chat Transcript</title>"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected %s in the transcript, got %s", want, html)
+		}
+	}
+	if strings.Contains(html, "<script>") || strings.Contains(html, "javascript:") {
+		t.Errorf("expected the transcript to be escaped, got %s", html)
+	}
+}
@@ -0,0 +1,39 @@
+package types
+
+import "time"
+
+// ActivityNotification is the method of the notifications a session receives
+// about the activity of its agent runs, relayed by the session activity feed.
+const ActivityNotification = "notifications/nanobot/activity"
+
+const (
+	ActivityTurnStarted      = "turnStarted"
+	ActivityToolCalled       = "toolCalled"
+	ActivityMessageCompleted = "messageCompleted"
+	ActivityTurnFailed       = "turnFailed"
+	ActivityCompaction       = "compaction"
+	ActivityHandoff          = "handoff"
+)
+
+// SessionActivity is a high-level event of an agent run of a session. It only
+// summarizes the run, the messages themselves are read from the session.
+type SessionActivity struct {
+	Type  string    `json:"type"`
+	Agent string    `json:"agent"`
+	RunID string    `json:"runId,omitempty"`
+	Time  time.Time `json:"time"`
+	// Tool and Target are the tool of a toolCalled event.
+	Tool   string `json:"tool,omitempty"`
+	Target string `json:"target,omitempty"`
+	// IsError is set when the tool call or turn failed.
+	IsError bool `json:"isError,omitempty"`
+	// Text is the final reply of a messageCompleted event, truncated.
+	Text string `json:"text,omitempty"`
+	// Error is the error of a turnFailed event.
+	Error string `json:"error,omitempty"`
+	// Archived and Kept are the message counts of a compaction event.
+	Archived int `json:"archived,omitempty"`
+	Kept     int `json:"kept,omitempty"`
+	// To is the agent taking over on a handoff event.
+	To string `json:"to,omitempty"`
+}
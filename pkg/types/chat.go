@@ -12,6 +12,9 @@ const (
 	AgentToolDescription  = "Chat with the agent"
 	AttachmentMetaKey     = "ai.nanobot.meta/attachment"
 	SkipTruncationMetaKey = "ai.nanobot.meta/skip-truncation"
+	// PinnedMetaKey set to true on the content of a message, or on the _meta
+	// of a tool result, pins the message so compaction never summarizes it.
+	PinnedMetaKey = "ai.nanobot.meta/pinned"
 )
 
 var ChatInputSchema = []byte(`{
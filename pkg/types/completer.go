@@ -3,6 +3,7 @@ package types
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"slices"
 	"time"
 
@@ -22,6 +23,16 @@ type CompletionOptions struct {
 	Tools              []mcp.Tool
 	ToolIncludeContext string
 	ToolSource         string
+	// AllowedTools, when set, limits the tools offered to the model to these
+	// names. DeniedTools are never offered.
+	AllowedTools []string
+	DeniedTools  []string
+	// MaxTurns, MaxTotalTokens, and MaxToolCalls bound a run together with the
+	// agent's own limits, the lower of the two wins. When one is reached the
+	// model is asked to finish without tools. Zero means unbounded.
+	MaxTurns       int
+	MaxTotalTokens int
+	MaxToolCalls   int
 }
 
 func (c CompletionOptions) Merge(other CompletionOptions) (result CompletionOptions) {
@@ -31,11 +42,47 @@ func (c CompletionOptions) Merge(other CompletionOptions) (result CompletionOpti
 	result.Tools = append(c.Tools, other.Tools...)
 	result.ToolIncludeContext = complete.Last(c.ToolIncludeContext, other.ToolIncludeContext)
 	result.ToolSource = complete.Last(c.ToolSource, other.ToolSource)
+	result.AllowedTools = append(c.AllowedTools, other.AllowedTools...)
+	result.DeniedTools = append(c.DeniedTools, other.DeniedTools...)
+	result.MaxTurns = complete.Last(c.MaxTurns, other.MaxTurns)
+	result.MaxTotalTokens = complete.Last(c.MaxTotalTokens, other.MaxTotalTokens)
+	result.MaxToolCalls = complete.Last(c.MaxToolCalls, other.MaxToolCalls)
 	return
 }
 
+// ModelInfo is what a completer knows about a model.
+type ModelInfo struct {
+	// ContextWindow is the context window of the model in tokens, zero when
+	// unknown.
+	ContextWindow int
+	// MaxOutputTokens is the most tokens the model generates in a response,
+	// zero when unknown.
+	MaxOutputTokens int
+	// Local is true for models served from the local machine.
+	Local bool
+}
+
+// ModelDescriber is implemented by completers that know about the models they
+// serve.
+type ModelDescriber interface {
+	ModelInfo(ctx context.Context, model string) ModelInfo
+}
+
+// ErrTokenCountingUnsupported is returned by a TokenCounter that can't count
+// the tokens of a request for its model.
+var ErrTokenCountingUnsupported = errors.New("token counting is not supported for this model")
+
+// TokenCounter is implemented by completers that can ask the provider of a
+// model how many input tokens a request has.
+type TokenCounter interface {
+	CountTokens(ctx context.Context, req CompletionRequest) (int, error)
+}
+
 type CompletionRequest struct {
-	Model            string               `json:"model,omitempty"`
+	Model string `json:"model,omitempty"`
+	// FallbackModels are tried in order when Model keeps failing with errors
+	// that are worth retrying, like rate limits and overloaded servers.
+	FallbackModels   []string             `json:"fallbackModels,omitempty"`
 	Agent            string               `json:"agent,omitempty"`
 	ThreadName       string               `json:"threadName,omitempty"`
 	NewThread        bool                 `json:"newThread,omitempty"`
@@ -51,6 +98,13 @@ type CompletionRequest struct {
 	Metadata         map[string]any       `json:"metadata,omitempty"`
 	Tools            []ToolUseDefinition  `json:"tools,omitzero"`
 	Reasoning        *AgentReasoning      `json:"reasoning,omitempty"`
+
+	// ParallelToolCalls set to false asks the model for at most one tool
+	// call per response.
+	ParallelToolCalls *bool `json:"parallelToolCalls,omitempty"`
+	// PromptCaching set to false stops asking providers that need to be
+	// asked, like Anthropic, to cache the prompt.
+	PromptCaching *bool `json:"promptCaching,omitempty"`
 }
 
 func (r CompletionRequest) GetAgent() string {
@@ -210,14 +264,7 @@ func (c CompletionItem) MarshalJSON() ([]byte, error) {
 		if c.ToolCallResult != nil {
 			output = c.ToolCallResult.Output
 		}
-		return json.Marshal(struct {
-			ID      string     `json:"id,omitempty"`
-			HasMore bool       `json:"hasMore,omitempty"`
-			Partial bool       `json:"partial,omitempty"`
-			Type    string     `json:"type,omitempty"`
-			Output  CallResult `json:"output,omitzero"`
-			ToolCall
-		}{
+		return json.Marshal(toolCompletionItem{
 			ID:       c.ID,
 			Type:     "tool",
 			HasMore:  c.HasMore,
@@ -226,13 +273,7 @@ func (c CompletionItem) MarshalJSON() ([]byte, error) {
 			Output:   output,
 		})
 	} else if c.Reasoning != nil {
-		return json.Marshal(struct {
-			ID      string `json:"id,omitempty"`
-			Type    string `json:"type,omitempty"`
-			HasMore bool   `json:"hasMore,omitempty"`
-			Partial bool   `json:"partial,omitempty"`
-			*Reasoning
-		}{
+		return json.Marshal(reasoningCompletionItem{
 			ID:        c.ID,
 			Type:      "reasoning",
 			HasMore:   c.HasMore,
@@ -244,9 +285,35 @@ func (c CompletionItem) MarshalJSON() ([]byte, error) {
 	return json.Marshal(Alias(c))
 }
 
+// toolCompletionItem is the wire format of a CompletionItem holding a tool call
+// and/or its result.
+type toolCompletionItem struct {
+	ID      string     `json:"id,omitempty"`
+	HasMore bool       `json:"hasMore,omitempty"`
+	Partial bool       `json:"partial,omitempty"`
+	Type    string     `json:"type,omitempty"`
+	Output  CallResult `json:"output,omitzero"`
+	ToolCall
+}
+
+// reasoningCompletionItem is the wire format of a CompletionItem holding reasoning.
+type reasoningCompletionItem struct {
+	ID      string `json:"id,omitempty"`
+	Type    string `json:"type,omitempty"`
+	HasMore bool   `json:"hasMore,omitempty"`
+	Partial bool   `json:"partial,omitempty"`
+	*Reasoning
+}
+
 type Reasoning struct {
 	EncryptedContent string        `json:"encryptedContent,omitempty"`
 	Summary          []SummaryText `json:"summary,omitempty"`
+	// Signature verifies Anthropic thinking, whose text is the summary, when
+	// it is sent back to the model.
+	Signature string `json:"signature,omitempty"`
+	// RedactedContent is Anthropic thinking that was encrypted by the
+	// provider. It has no summary.
+	RedactedContent string `json:"redactedContent,omitempty"`
 }
 
 type SummaryText struct {
@@ -270,6 +337,28 @@ type CompletionResponse struct {
 	// by the proxy due to a policy violation. The value is the explanation to return as
 	// error tool_results instead of executing the tools.
 	ToolCallPolicyViolation string `json:"toolCallPolicyViolation,omitempty"`
+
+	// BudgetExhausted, if set, indicates that the run was stopped early because
+	// it reached one of its budgets.
+	BudgetExhausted *BudgetExhausted `json:"budgetExhausted,omitempty"`
+
+	// Usage is the number of tokens the completion used, as reported by the
+	// provider. The response of an agent run has the usage of all its turns.
+	Usage *Usage `json:"usage,omitempty"`
+}
+
+// Budgets that bound an agent run.
+const (
+	BudgetTurns       = "turns"
+	BudgetTotalTokens = "totalTokens"
+	BudgetToolCalls   = "toolCalls"
+)
+
+// BudgetExhausted describes the budget that stopped an agent run.
+type BudgetExhausted struct {
+	Budget string `json:"budget"`
+	Limit  int    `json:"limit"`
+	Used   int    `json:"used"`
 }
 
 func (c *CompletionResponse) Serialize() (any, error) {
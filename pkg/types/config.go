@@ -6,11 +6,15 @@ import (
 	"errors"
 	"fmt"
 	"iter"
+	"maps"
+	"path"
 	"regexp"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/obot-platform/nanobot/pkg/complete"
+	"github.com/obot-platform/nanobot/pkg/log"
 	"github.com/obot-platform/nanobot/pkg/mcp"
 	"gopkg.in/yaml.v3"
 )
@@ -26,6 +30,12 @@ const (
 	TaskURISessionKey               = "taskURI"
 	ResourceSubscriptionsSessionKey = "resourceSubscriptions"
 	PublicURLSessionKey             = "publicURL"
+	// ResourcesListedSessionKey is set once the client lists resources,
+	// clients that never do aren't sent list_changed notifications.
+	ResourcesListedSessionKey = "resourcesListed"
+	// TurnSessionKey holds the ID of the chat turn in progress, the ID of
+	// the message that started it.
+	TurnSessionKey = "turn"
 )
 
 type configContextKey struct{}
@@ -74,6 +84,89 @@ type LLMProvider struct {
 	APIKey  string            `json:"apiKey,omitempty"`
 	BaseURL string            `json:"baseURL,omitempty"`
 	Headers map[string]string `json:"headers,omitempty"`
+	// Bedrock sends the requests of an AnthropicMessages provider to the AWS
+	// Bedrock runtime instead of the Anthropic API.
+	Bedrock *BedrockProvider `json:"bedrock,omitempty"`
+	// Vertex sends the requests of an AnthropicMessages provider to Google
+	// Vertex AI instead of the Anthropic API.
+	Vertex *VertexProvider `json:"vertex,omitempty"`
+	// Local marks a provider that serves models from the local machine, like
+	// Ollama. Local models default to a smaller context window and a local
+	// base URL.
+	Local bool `json:"local,omitempty"`
+	// Models holds settings of individual models served by this provider.
+	Models map[string]LLMModel `json:"models,omitempty"`
+	// Retry configures how failed completions are retried and when a model
+	// is taken out of rotation.
+	Retry *LLMRetry `json:"retry,omitempty"`
+	// CountTokens asks the provider how many tokens a request has when
+	// deciding whether to compact, instead of estimating them locally.
+	CountTokens bool `json:"countTokens,omitempty"`
+}
+
+type LLMRetry struct {
+	MaxRetries     *int `json:"maxRetries,omitempty"`
+	InitialDelayMS int  `json:"initialDelayMS,omitempty"`
+	MaxDelayMS     int  `json:"maxDelayMS,omitempty"`
+	// FailureThreshold is how many failures in a row open the circuit
+	// breaker of a model, CooldownMS how long it stays open.
+	FailureThreshold int `json:"failureThreshold,omitempty"`
+	CooldownMS       int `json:"cooldownMS,omitempty"`
+}
+
+type LLMModel struct {
+	// BaseURL overrides the base URL of the provider for this model.
+	BaseURL string `json:"baseURL,omitempty"`
+	// ToolCalling set to false emulates tool calls in the prompt for models
+	// without native tool calling. When unset it is detected from the errors
+	// of the provider.
+	ToolCalling *bool `json:"toolCalling,omitempty"`
+	// ContextWindow is the context window of the model in tokens.
+	ContextWindow int `json:"contextWindow,omitempty"`
+	// MaxOutputTokens is the most tokens the model generates in a response.
+	MaxOutputTokens int `json:"maxOutputTokens,omitempty"`
+}
+
+type BedrockProvider struct {
+	Region          string `json:"region,omitempty"`
+	AccessKeyID     string `json:"accessKeyId,omitempty"`
+	SecretAccessKey string `json:"secretAccessKey,omitempty"`
+	SessionToken    string `json:"sessionToken,omitempty"`
+	// Models maps model names to Bedrock model IDs or inference profile
+	// ARNs. Models that aren't mapped are sent by their name.
+	Models map[string]string `json:"models,omitempty"`
+}
+
+type VertexProvider struct {
+	Region    string `json:"region,omitempty"`
+	ProjectID string `json:"projectId,omitempty"`
+	// CredentialsFile is a service account key or the application default
+	// credentials of gcloud.
+	CredentialsFile string `json:"credentialsFile,omitempty"`
+	// AccessToken is an OAuth access token used instead of the credentials
+	// file.
+	AccessToken string `json:"accessToken,omitempty"`
+	// Models maps model names to Vertex AI model IDs. Models that aren't
+	// mapped are sent by their name.
+	Models map[string]string `json:"models,omitempty"`
+}
+
+func (p LLMProvider) validate(name string) error {
+	if r := p.Retry; r != nil {
+		if (r.MaxRetries != nil && *r.MaxRetries < 0) || r.InitialDelayMS < 0 || r.MaxDelayMS < 0 || r.FailureThreshold < 0 || r.CooldownMS < 0 {
+			return fmt.Errorf("llmProvider %q retry settings can not be negative", name)
+		}
+	}
+	if p.Bedrock == nil && p.Vertex == nil {
+		return nil
+	}
+	if p.Bedrock != nil && p.Vertex != nil {
+		return fmt.Errorf("llmProvider %q can not set both bedrock and vertex", name)
+	}
+	if p.Dialect != DialectAnthropicMessages {
+		return fmt.Errorf("llmProvider %q must use the %s dialect to set bedrock or vertex", name, DialectAnthropicMessages)
+	}
+	return nil
 }
 
 type Config struct {
@@ -89,6 +182,480 @@ type Config struct {
 	Hooks            mcp.Hooks              `json:"hooks,omitempty"`
 	WorkspaceID      string                 `json:"workspaceId,omitempty"`
 	WorkspaceBaseURI string                 `json:"workspaceBaseUri,omitempty"`
+	// SystemModules turns built-in system modules on or off by name. Modules
+	// not listed are enabled.
+	SystemModules map[string]bool `json:"systemModules,omitempty"`
+	// HostFileAccess lets the file tools of the system server reach paths
+	// outside of the session directory. Only enable it for trusted local use.
+	HostFileAccess bool `json:"hostFileAccess,omitempty"`
+	// ToolResults configures how large tool results are truncated before
+	// they are sent to the model, and where their full output is kept.
+	ToolResults *ToolResultSettings `json:"toolResults,omitempty"`
+	// Retention removes old sessions, so long-running servers don't grow
+	// without bound.
+	Retention *RetentionSettings `json:"retention,omitempty"`
+	// Limits caps what each account can use of the server.
+	Limits *LimitSettings `json:"limits,omitempty"`
+	// Schedules run agents or workflows on a schedule while nanobot serve is
+	// running, by name.
+	Schedules map[string]Schedule `json:"schedules,omitempty"`
+	// Triggers start runs of agents when files change or when their webhook
+	// receives a payload, by name.
+	Triggers map[string]Trigger `json:"triggers,omitempty"`
+	// Channels bridge front ends that don't speak MCP, like Slack and
+	// email, to an agent, by name.
+	Channels map[string]Channel `json:"channels,omitempty"`
+	// Roots are directories offered to MCP servers as roots, by name. A
+	// relative path is relative to the working directory of nanobot.
+	Roots map[string]string `json:"roots,omitempty"`
+	// CompositeTools are tools that call a sequence of tools, by name. They
+	// are referenced from the tools of agents by their name.
+	CompositeTools map[string]CompositeTool `json:"compositeTools,omitempty"`
+	// Mounts are host directories that appear in the session directory as
+	// mounts/<name>, by name, so the file tools can reach just them. Commands
+	// run by the bash tool can only start in writable mounts, but aren't
+	// confined to them.
+	Mounts map[string]Mount `json:"mounts,omitempty"`
+	// MergeStrategies changes how this config is merged over the configs it
+	// extends, or this profile over the config, by the JSON pointer of a
+	// field. A * matches any key, as in /agents/*/tools.
+	MergeStrategies map[string]MergeStrategy `json:"mergeStrategies,omitempty"`
+}
+
+// Modes of mounts.
+const (
+	MountReadOnly  = "ro"
+	MountReadWrite = "rw"
+)
+
+// MountsDir is the directory of the session directory that mounts appear in.
+const MountsDir = "mounts"
+
+type Mount struct {
+	// Path is the host directory. A relative path is relative to the working
+	// directory of nanobot.
+	Path string `json:"path,omitempty"`
+	// Mode is ro or rw. Defaults to ro.
+	Mode string `json:"mode,omitempty"`
+}
+
+// Writable indicates if the file tools can change the files of the mount.
+func (m Mount) Writable() bool {
+	return m.Mode == MountReadWrite
+}
+
+func (m Mount) validate(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("mount %q must be a name without slashes", name)
+	}
+	if strings.TrimSpace(m.Path) == "" {
+		return fmt.Errorf("mount %q must have a path", name)
+	}
+	switch m.Mode {
+	case "", MountReadOnly, MountReadWrite:
+	default:
+		return fmt.Errorf("mount %q mode must be %q or %q, got %q", name, MountReadOnly, MountReadWrite, m.Mode)
+	}
+	return nil
+}
+
+// MergeStrategy is how a map or list of a config is merged with the same
+// field of the config it is merged over.
+type MergeStrategy string
+
+const (
+	// MergeStrategyMerge merges the keys of maps, it is the default for maps.
+	MergeStrategyMerge MergeStrategy = "merge"
+	// MergeStrategyAppend appends to lists, it is the default for lists.
+	MergeStrategyAppend MergeStrategy = "append"
+	// MergeStrategyReplace replaces the value of the field.
+	MergeStrategyReplace MergeStrategy = "replace"
+)
+
+// Where the full output of truncated tool results is written.
+const (
+	// ToolResultStorageSession writes it to the session's directory of the
+	// workspace, sessions/<session>/truncated-outputs.
+	ToolResultStorageSession = "session"
+	// ToolResultStorageNanobot writes it out of the workspace's way, to
+	// .nanobot/<session>/truncated-outputs.
+	ToolResultStorageNanobot = "nanobot"
+)
+
+type ToolResultSettings struct {
+	// MaxTokens is the size at which tool results are truncated, unless the
+	// toolSettings of their MCP server set maxResultTokens. Defaults to
+	// 50 KiB of text.
+	MaxTokens int `json:"maxTokens,omitempty"`
+	// TailPercent is the share of a truncated result kept from its end,
+	// unless the toolSettings of its MCP server set truncationTailPercent.
+	// Defaults to 30.
+	TailPercent *int `json:"tailPercent,omitempty"`
+	// Storage is where the full output of truncated results is written,
+	// session or nanobot. Defaults to session.
+	Storage string `json:"storage,omitempty"`
+	// RetentionHours removes full outputs older than this many hours. Zero
+	// keeps them.
+	RetentionHours int `json:"retentionHours,omitempty"`
+	// MaxFiles keeps at most this many full outputs per session, removing
+	// the oldest. Zero keeps them all.
+	MaxFiles int `json:"maxFiles,omitempty"`
+}
+
+func (s ToolResultSettings) validate() error {
+	if s.MaxTokens < 0 || s.RetentionHours < 0 || s.MaxFiles < 0 {
+		return fmt.Errorf("toolResults must not have negative values")
+	}
+	if tail := s.TailPercent; tail != nil && (*tail < 0 || *tail > 100) {
+		return fmt.Errorf("toolResults tailPercent must be between 0 and 100")
+	}
+	switch s.Storage {
+	case "", ToolResultStorageSession, ToolResultStorageNanobot:
+	default:
+		return fmt.Errorf("toolResults storage must be %q or %q, got %q", ToolResultStorageSession, ToolResultStorageNanobot, s.Storage)
+	}
+	return nil
+}
+
+type RetentionSettings struct {
+	// MaxAgeHours removes sessions that weren't updated for this many hours.
+	// Zero keeps them.
+	MaxAgeHours int `json:"maxAgeHours,omitempty"`
+	// MaxDiskMB removes the least recently updated sessions while the files
+	// of all sessions, including the full outputs of truncated tool results,
+	// take more than this many megabytes. Zero doesn't limit them.
+	MaxDiskMB int `json:"maxDiskMB,omitempty"`
+	// IntervalMinutes is how often the server removes sessions. Defaults to
+	// 60.
+	IntervalMinutes int `json:"intervalMinutes,omitempty"`
+}
+
+func (r RetentionSettings) validate() error {
+	if r.MaxAgeHours < 0 || r.MaxDiskMB < 0 || r.IntervalMinutes < 0 {
+		return fmt.Errorf("retention must not have negative values")
+	}
+	return nil
+}
+
+// LimitSettings are the per account limits of nanobot serve. Zero doesn't
+// limit.
+type LimitSettings struct {
+	// MaxSessions is how many sessions an account can have in use at once.
+	MaxSessions int `json:"maxSessions,omitempty"`
+	// MaxConcurrentRuns is how many agent runs an account can have in
+	// progress at once.
+	MaxConcurrentRuns int `json:"maxConcurrentRuns,omitempty"`
+	// ToolCallsPerMinute is how many tools an account can call in a minute,
+	// counting the calls made by its agents.
+	ToolCallsPerMinute int `json:"toolCallsPerMinute,omitempty"`
+	// TokensPerDay is how many LLM tokens the completions of an account can
+	// use in a UTC day.
+	TokensPerDay int `json:"tokensPerDay,omitempty"`
+	// SessionDiskMB is how many megabytes the files of a session can take.
+	SessionDiskMB int `json:"sessionDiskMB,omitempty"`
+	// AccountDiskMB is how many megabytes the files of all the sessions of
+	// an account can take.
+	AccountDiskMB int `json:"accountDiskMB,omitempty"`
+}
+
+func (l LimitSettings) validate() error {
+	if l.MaxSessions < 0 || l.MaxConcurrentRuns < 0 || l.ToolCallsPerMinute < 0 || l.TokensPerDay < 0 ||
+		l.SessionDiskMB < 0 || l.AccountDiskMB < 0 {
+		return fmt.Errorf("limits must not have negative values")
+	}
+	return nil
+}
+
+// AccountSettingsSessionKey holds the AccountSettings of the account of the
+// session.
+const AccountSettingsSessionKey = "accountSettings"
+
+// AccountSettings are what admins allow an account to use, on top of the
+// config. Empty lists allow everything.
+type AccountSettings struct {
+	// AllowedAgents are the agents the account can chat with.
+	AllowedAgents []string `json:"allowedAgents,omitempty"`
+	// AllowedModels are the models the agents of the account can use, as
+	// names or patterns like gpt-4.1*.
+	AllowedModels []string `json:"allowedModels,omitempty"`
+}
+
+func (a AccountSettings) AgentAllowed(agent string) bool {
+	return len(a.AllowedAgents) == 0 || slices.Contains(a.AllowedAgents, agent)
+}
+
+func (a AccountSettings) ModelAllowed(model string) bool {
+	if len(a.AllowedModels) == 0 {
+		return true
+	}
+	for _, pattern := range a.AllowedModels {
+		if ok, _ := path.Match(pattern, model); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// LimiterSessionKey holds the AccountLimiter that enforces the limits of the
+// server.
+const LimiterSessionKey = "limiter"
+
+// AccountLimiter enforces the LimitSettings of the server on the runs, tool
+// calls, and completions of an account. Its errors are JSON-RPC errors that
+// say which limit was reached.
+type AccountLimiter interface {
+	// StartRun takes a slot of the concurrent runs of the account, done
+	// frees it.
+	StartRun(accountID string) (done func(), err error)
+	// AllowToolCall counts a tool call of the account.
+	AllowToolCall(accountID string) error
+	// CheckTokens fails when the account used its tokens of the day.
+	CheckTokens(accountID string) error
+	// AddTokens counts tokens used by a completion of the account.
+	AddTokens(accountID string, tokens int)
+	// CheckDisk fails when adding bytes to the files of the session would
+	// take more than the disk quotas of the session or of the account.
+	CheckDisk(ctx context.Context, accountID, sessionID string, adding int64) error
+	// MeasureDisk measures the files of the session again, to see what a
+	// running command wrote, and fails like CheckDisk.
+	MeasureDisk(ctx context.Context, accountID, sessionID string) error
+}
+
+// CompositeTool is a tool that calls its steps in order on the server, so the
+// model calls one tool instead of learning a sequence of calls. Its result is
+// the result of the last step.
+type CompositeTool struct {
+	Description string `json:"description,omitempty"`
+	// Input is the input schema of the tool. Defaults to an object with any
+	// properties.
+	Input InputSchema         `json:"input,omitzero"`
+	Steps []CompositeToolStep `json:"steps,omitempty"`
+}
+
+// CompositeToolStep is a call of a CompositeTool.
+type CompositeToolStep struct {
+	// ID names the step, so later steps can use its output, as in
+	// ${steps.build.output}.
+	ID string `json:"id,omitempty"`
+	// Tool is the server/tool to call with Args.
+	Tool string `json:"tool,omitempty"`
+	// Args are the arguments of the call. Strings can use ${...} expressions
+	// of the inputs of the tool, as in ${inputs.image}, and the outputs of
+	// earlier steps.
+	Args map[string]any `json:"args,omitempty"`
+}
+
+func (t CompositeTool) validate(name string, c Config) error {
+	var errs []error
+	if len(t.Steps) == 0 {
+		errs = append(errs, fmt.Errorf("compositeTool %q must have at least one step", name))
+	}
+	ids := map[string]bool{}
+	for i, step := range t.Steps {
+		if step.ID != "" {
+			if ids[step.ID] {
+				errs = append(errs, fmt.Errorf("compositeTool %q has more than one step with id %q", name, step.ID))
+			}
+			ids[step.ID] = true
+		}
+		server, tool, ok := strings.Cut(step.Tool, "/")
+		if !ok || server == "" || tool == "" {
+			errs = append(errs, fmt.Errorf("compositeTool %q step %d tool %q must be server/tool", name, i, step.Tool))
+			continue
+		}
+		_, isServer := c.MCPServers[server]
+		_, isAgent := c.Agents[server]
+		if !isServer && !isAgent {
+			errs = append(errs, fmt.Errorf("compositeTool %q step %d server %q not found", name, i, server))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Schedule runs a prompt of an agent or an executable workflow in a new
+// session on a cron schedule or an interval, without a user.
+type Schedule struct {
+	// Cron is a five-field cron expression, or a descriptor like @daily.
+	Cron string `json:"cron,omitempty"`
+	// IntervalMinutes runs it every this many minutes instead of on a cron
+	// schedule.
+	IntervalMinutes int `json:"intervalMinutes,omitempty"`
+	// Timezone is the IANA timezone of the cron expression. Defaults to UTC.
+	Timezone string `json:"timezone,omitempty"`
+	// Agent runs the prompt. Defaults to the first entrypoint agent.
+	Agent  string `json:"agent,omitempty"`
+	Prompt string `json:"prompt,omitempty"`
+	// Workflow is the workflow:/// URI of an executable workflow that is run
+	// with Inputs instead of a prompt.
+	Workflow string         `json:"workflow,omitempty"`
+	Inputs   map[string]any `json:"inputs,omitempty"`
+	// Webhook is a URL the result of every run is POSTed to.
+	Webhook  string `json:"webhook,omitempty"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
+
+// Validate checks that the schedule has exactly one of a cron expression or
+// an interval, and exactly one of a prompt or a workflow. The cron expression
+// itself is parsed by the scheduler.
+func (s Schedule) Validate() error {
+	if (s.Cron == "") == (s.IntervalMinutes == 0) {
+		return fmt.Errorf("must have exactly one of cron or intervalMinutes")
+	}
+	if s.IntervalMinutes < 0 {
+		return fmt.Errorf("intervalMinutes must not be negative")
+	}
+	if s.Timezone != "" {
+		if _, err := time.LoadLocation(s.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone %q", s.Timezone)
+		}
+	}
+	if (s.Prompt == "") == (s.Workflow == "") {
+		return fmt.Errorf("must have exactly one of prompt or workflow")
+	}
+	if s.Workflow != "" && !strings.HasPrefix(s.Workflow, "workflow:///") {
+		return fmt.Errorf("workflow %q must be a workflow:/// URI", s.Workflow)
+	}
+	if len(s.Inputs) > 0 && s.Workflow == "" {
+		return fmt.Errorf("inputs only apply to workflows")
+	}
+	if s.Webhook != "" && !mcp.IsWebhook(s.Webhook) {
+		return fmt.Errorf("webhook %q must be an http:// or https:// URL", s.Webhook)
+	}
+	return nil
+}
+
+// The file changes that start a run of a Trigger.
+const (
+	TriggerEventCreate = "create"
+	TriggerEventWrite  = "write"
+	TriggerEventDelete = "delete"
+)
+
+// Trigger starts a run of an agent in a new session when a file in a watched
+// directory changes, or when the webhook of the trigger, POST
+// /triggers/<name>, receives a payload.
+type Trigger struct {
+	// Watch is the directory whose files start a run when they change,
+	// relative to the working directory of nanobot serve.
+	Watch string `json:"watch,omitempty"`
+	// Pattern matches the names of the changed files, like *.pdf. Defaults
+	// to all files.
+	Pattern string `json:"pattern,omitempty"`
+	// Events are the file changes that start a run, create, write, or
+	// delete. Defaults to create.
+	Events []string `json:"events,omitempty"`
+	// Webhook starts a run for every payload POSTed to /triggers/<name>.
+	// Payloads must be signed with Secret.
+	Webhook bool `json:"webhook,omitempty"`
+	// Secret is the key webhook payloads are signed with, like the
+	// webhooks nanobot sends. Defaults to the NANOBOT_WEBHOOK_SECRET env var.
+	Secret string `json:"secret,omitempty"`
+	// Agent runs the prompt. Defaults to the first entrypoint agent.
+	Agent string `json:"agent,omitempty"`
+	// Prompt comes before the changed file or the payload in the first
+	// user message of the run.
+	Prompt   string `json:"prompt,omitempty"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
+
+func (t Trigger) validate(name string, c Config) error {
+	if t.Watch == "" && !t.Webhook {
+		return fmt.Errorf("trigger %q must have a watch directory or a webhook", name)
+	}
+	if t.Watch == "" && (t.Pattern != "" || len(t.Events) > 0) {
+		return fmt.Errorf("trigger %q pattern and events only apply to a watch directory", name)
+	}
+	if _, err := path.Match(t.Pattern, ""); err != nil {
+		return fmt.Errorf("trigger %q has invalid pattern %q", name, t.Pattern)
+	}
+	for _, event := range t.Events {
+		switch event {
+		case TriggerEventCreate, TriggerEventWrite, TriggerEventDelete:
+		default:
+			return fmt.Errorf("trigger %q has invalid event %q, must be %q, %q, or %q", name, event, TriggerEventCreate, TriggerEventWrite, TriggerEventDelete)
+		}
+	}
+	if _, ok := c.Agents[t.Agent]; t.Agent != "" && !ok {
+		return fmt.Errorf("trigger %q agent %q not found", name, t.Agent)
+	}
+	return nil
+}
+
+// Channel answers the messages of a Slack app or of an email address with an
+// agent. Every Slack thread or email thread is a session, and the files
+// attached to its messages are uploaded to the session. Slack events and
+// inbound emails are POSTed to /channels/<name>.
+type Channel struct {
+	// Agent answers the messages. Defaults to the first entrypoint agent.
+	Agent    string        `json:"agent,omitempty"`
+	Slack    *SlackChannel `json:"slack,omitempty"`
+	Email    *EmailChannel `json:"email,omitempty"`
+	Disabled bool          `json:"disabled,omitempty"`
+}
+
+// SlackChannel is a Slack app whose Events API request URL is the channel.
+// It answers mentions of the app and direct messages.
+type SlackChannel struct {
+	// BotToken is the xoxb- token that replies are posted and files are
+	// downloaded with.
+	BotToken string `json:"botToken"`
+	// SigningSecret verifies the requests of Slack.
+	SigningSecret string `json:"signingSecret"`
+}
+
+// EmailChannel receives emails parsed by a mail service, POSTed as signed
+// JSON, and replies to them over SMTP.
+type EmailChannel struct {
+	// Secret is the key inbound emails are signed with, like the webhooks
+	// nanobot sends. Defaults to the NANOBOT_WEBHOOK_SECRET env var.
+	Secret string     `json:"secret,omitempty"`
+	SMTP   SMTPServer `json:"smtp"`
+}
+
+type SMTPServer struct {
+	Host string `json:"host"`
+	// Port defaults to 587.
+	Port     int    `json:"port,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// From is the address replies are sent from.
+	From string `json:"from"`
+}
+
+func (ch Channel) validate(name string, c Config) error {
+	if (ch.Slack == nil) == (ch.Email == nil) {
+		return fmt.Errorf("channel %q must have exactly one of slack or email", name)
+	}
+	if ch.Slack != nil && (ch.Slack.BotToken == "" || ch.Slack.SigningSecret == "") {
+		return fmt.Errorf("channel %q slack needs a botToken and a signingSecret", name)
+	}
+	if ch.Email != nil && (ch.Email.SMTP.Host == "" || ch.Email.SMTP.From == "" || ch.Email.SMTP.Port < 0) {
+		return fmt.Errorf("channel %q email smtp needs a host and a from address", name)
+	}
+	if _, ok := c.Agents[ch.Agent]; ch.Agent != "" && !ok {
+		return fmt.Errorf("channel %q agent %q not found", name, ch.Agent)
+	}
+	return nil
+}
+
+func (ch Channel) redacted() Channel {
+	if ch.Slack != nil {
+		slack := *ch.Slack
+		slack.BotToken = log.Redact(slack.BotToken)
+		slack.SigningSecret = log.Redact(slack.SigningSecret)
+		ch.Slack = &slack
+	}
+	if ch.Email != nil {
+		email := *ch.Email
+		if email.Secret != "" {
+			email.Secret = log.Redact(email.Secret)
+		}
+		if email.SMTP.Password != "" {
+			email.SMTP.Password = log.Redact(email.SMTP.Password)
+		}
+		ch.Email = &email
+	}
+	return ch
 }
 
 type ConfigFactory func(ctx context.Context, profiles string) (Config, error)
@@ -97,19 +664,19 @@ func (c Config) Redacted() Config {
 	redacted := c
 
 	for i, env := range redacted.Env {
-		if env.Sensitive == nil || *env.Sensitive {
-			env.Default = fmt.Sprintf("%s...", env.Default[:min(10, len(env.Default)/2)])
+		if env.IsSensitive() {
+			env.Default = log.Redact(env.Default)
 			redacted.Env[i] = env
 		}
 	}
 
 	for _, mcpServer := range redacted.MCPServers {
 		for key, val := range mcpServer.Env {
-			mcpServer.Env[key] = fmt.Sprintf("%s...", val[:min(10, len(val)/2)])
+			mcpServer.Env[key] = log.Redact(val)
 		}
 
 		for key, val := range mcpServer.Headers {
-			mcpServer.Headers[key] = fmt.Sprintf("%s...", val[:min(10, len(val)/2)])
+			mcpServer.Headers[key] = log.Redact(val)
 		}
 
 	}
@@ -118,6 +685,23 @@ func (c Config) Redacted() Config {
 		c.Profiles[key] = val.Redacted()
 	}
 
+	if len(c.Triggers) > 0 {
+		redacted.Triggers = make(map[string]Trigger, len(c.Triggers))
+		for name, trigger := range c.Triggers {
+			if trigger.Secret != "" {
+				trigger.Secret = log.Redact(trigger.Secret)
+			}
+			redacted.Triggers[name] = trigger
+		}
+	}
+
+	if len(c.Channels) > 0 {
+		redacted.Channels = make(map[string]Channel, len(c.Channels))
+		for name, channel := range c.Channels {
+			redacted.Channels[name] = channel.redacted()
+		}
+	}
+
 	return redacted
 }
 
@@ -137,6 +721,12 @@ func (c Config) Validate(allowLocal bool) error {
 		}
 	}
 
+	for providerName, provider := range c.LLMProviders {
+		if err := provider.validate(providerName); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
 	for agentName, agent := range c.Agents {
 		if err := checkDup(seenNames, "agents", agentName); err != nil {
 			errs = append(errs, err)
@@ -153,6 +743,112 @@ func (c Config) Validate(allowLocal bool) error {
 		if err := validateMCPServer(mcpServerName, mcpServer, allowLocal); err != nil {
 			errs = append(errs, err)
 		}
+		for toolName, settings := range mcpServer.ToolSettings {
+			if settings.TimeoutMS < 0 || settings.MaxRetries < 0 || settings.MaxResultTokens < 0 || settings.CacheTTLMS < 0 {
+				errs = append(errs, fmt.Errorf("mcpServer %q toolSettings %q must not have negative values", mcpServerName, toolName))
+			}
+			if tail := settings.TruncationTailPercent; tail != nil && (*tail < 0 || *tail > 100) {
+				errs = append(errs, fmt.Errorf("mcpServer %q toolSettings %q truncationTailPercent must be between 0 and 100", mcpServerName, toolName))
+			}
+		}
+		if err := mcpServer.Tools.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("mcpServer %q tools: %w", mcpServerName, err))
+		}
+		if process := mcpServer.Process; process != nil {
+			if process.IdleTimeoutMS < 0 || process.MaxLifetimeMS < 0 || process.MaxMemoryMB < 0 || process.MaxCPUSeconds < 0 {
+				errs = append(errs, fmt.Errorf("mcpServer %q process must not have negative values", mcpServerName))
+			}
+			if mcpServer.Command == "" || mcpServer.BaseURL != "" {
+				errs = append(errs, fmt.Errorf("mcpServer %q process only applies to servers started with a command that talks over stdio", mcpServerName))
+			}
+			if mcpServer.Sandboxed && (process.MaxMemoryMB > 0 || process.MaxCPUSeconds > 0) {
+				errs = append(errs, fmt.Errorf("mcpServer %q process maxMemoryMb and maxCpuSeconds are not supported for sandboxed servers", mcpServerName))
+			}
+		}
+		switch mcpServer.Startup {
+		case "", mcp.StartupLazy, mcp.StartupEager, mcp.StartupDisabled:
+		default:
+			errs = append(errs, fmt.Errorf("mcpServer %q startup must be %q, %q or %q, not %q",
+				mcpServerName, mcp.StartupLazy, mcp.StartupEager, mcp.StartupDisabled, mcpServer.Startup))
+		}
+		if sampling := mcpServer.Sampling; sampling != nil {
+			if sampling.MaxTokens < 0 {
+				errs = append(errs, fmt.Errorf("mcpServer %q sampling maxTokens must not be negative", mcpServerName))
+			}
+			for _, agent := range sampling.Agents {
+				if _, ok := c.Agents[agent]; !ok {
+					errs = append(errs, fmt.Errorf("mcpServer %q sampling agent %q not found", mcpServerName, agent))
+				}
+			}
+		}
+	}
+
+	for toolName, tool := range c.CompositeTools {
+		if err := checkDup(seenNames, "compositeTools", toolName); err != nil {
+			errs = append(errs, err)
+		}
+		if err := tool.validate(toolName, c); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if c.ToolResults != nil {
+		if err := c.ToolResults.validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if c.Retention != nil {
+		if err := c.Retention.validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if c.Limits != nil {
+		if err := c.Limits.validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for scheduleName, schedule := range c.Schedules {
+		if err := schedule.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("schedule %q %w", scheduleName, err))
+		} else if _, ok := c.Agents[schedule.Agent]; schedule.Agent != "" && !ok {
+			errs = append(errs, fmt.Errorf("schedule %q agent %q not found", scheduleName, schedule.Agent))
+		}
+	}
+
+	for triggerName, trigger := range c.Triggers {
+		if err := trigger.validate(triggerName, c); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for channelName, channel := range c.Channels {
+		if err := channel.validate(channelName, c); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for rootName, root := range c.Roots {
+		if strings.TrimSpace(root) == "" {
+			errs = append(errs, fmt.Errorf("root %q must have a path", rootName))
+		}
+	}
+
+	for mountName, mount := range c.Mounts {
+		if err := mount.validate(mountName); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for promptName, prompt := range c.Prompts {
+		for fieldName, field := range prompt.Input {
+			if field.Type != "" && field.Type != FieldTypeString && field.Type != FieldTypeResource {
+				errs = append(errs, fmt.Errorf("prompt %q input %q has invalid type %q, must be %q or %q",
+					promptName, fieldName, field.Type, FieldTypeString, FieldTypeResource))
+			}
+		}
 	}
 
 	return errors.Join(errs...)
@@ -187,15 +883,33 @@ func (p Prompt) ToPrompt(name string) mcp.Prompt {
 		Description: p.Description,
 	}
 	for fieldName, field := range p.Input {
+		description := field.Description
+		if field.Type == FieldTypeResource {
+			// Prompt arguments are always strings in MCP, so tell the client a
+			// resource URI is expected.
+			description = strings.TrimSpace(description + " (resource URI)")
+		}
 		result.Arguments = append(result.Arguments, mcp.PromptArgument{
 			Name:        fieldName,
-			Description: field.Description,
+			Description: description,
 			Required:    field.Required == nil || *field.Required,
 		})
 	}
 	return result
 }
 
+// ResourceInputs returns the names of the inputs with type "resource", sorted.
+func (p Prompt) ResourceInputs() []string {
+	var names []string
+	for fieldName, field := range p.Input {
+		if field.Type == FieldTypeResource {
+			names = append(names, fieldName)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
 type Auth struct {
 	OAuthClientID                    string         `json:"oauthClientId"`
 	OAuthClientSecret                string         `json:"oauthClientSecret"`
@@ -215,6 +929,12 @@ type EnvDef struct {
 	UseBearerToken bool       `json:"useBearerToken,omitempty"`
 }
 
+// IsSensitive indicates if the value of the env var is a secret, which it is
+// unless sensitive is set to false.
+func (e EnvDef) IsSensitive() bool {
+	return e.Sensitive == nil || *e.Sensitive
+}
+
 func (e *EnvDef) UnmarshalJSON(data []byte) error {
 	if data[0] == '"' && data[len(data)-1] == '"' {
 		var raw string
@@ -403,14 +1123,261 @@ func (s *StringList) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// AgentModel is the model of an agent followed by the models to fail over to
+// when it keeps failing. It is written as a model name or a list of them.
+type AgentModel []string
+
+// Primary returns the model the agent uses while it is available.
+func (m AgentModel) Primary() string {
+	if len(m) == 0 {
+		return ""
+	}
+	return m[0]
+}
+
+// Fallbacks returns the models to fail over to, in order.
+func (m AgentModel) Fallbacks() []string {
+	if len(m) < 2 {
+		return nil
+	}
+	return m[1:]
+}
+
+func (m *AgentModel) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '[' {
+		var raw []string
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return err
+		}
+		*m = raw
+		return nil
+	}
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*m = nil
+	if raw != "" {
+		*m = AgentModel{raw}
+	}
+	return nil
+}
+
+func (m AgentModel) MarshalJSON() ([]byte, error) {
+	if len(m) == 1 {
+		return json.Marshal(m[0])
+	}
+	return json.Marshal([]string(m))
+}
+
+func (m *AgentModel) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.SequenceNode {
+		var raw []string
+		if err := value.Decode(&raw); err != nil {
+			return err
+		}
+		*m = raw
+		return nil
+	}
+	*m = nil
+	if value.Value != "" {
+		*m = AgentModel{value.Value}
+	}
+	return nil
+}
+
 type Agent struct {
 	HookAgent `json:",inline" yaml:",inline"`
 	Output    *OutputSchema `json:"output,omitempty"`
 }
 
+// ElicitationAction is what is done with an elicitation request of an MCP
+// server.
+type ElicitationAction string
+
+const (
+	// ElicitationForward sends the request to the client of the session.
+	ElicitationForward ElicitationAction = "forward"
+	// ElicitationAccept answers the request with the content of the policy
+	// and the defaults of the requested schema.
+	ElicitationAccept  ElicitationAction = "accept"
+	ElicitationDecline ElicitationAction = "decline"
+	ElicitationCancel  ElicitationAction = "cancel"
+)
+
+// AgentElicitation is how the elicitation requests of the MCP servers an
+// agent uses are answered.
+type AgentElicitation struct {
+	// Action applies to the requests of all servers. Defaults to forward.
+	Action ElicitationAction `json:"action,omitempty"`
+	// Servers overrides Action for the requests of MCP servers, by name.
+	Servers map[string]ElicitationAction `json:"servers,omitempty"`
+	// Content answers accepted requests, by field name.
+	Content map[string]any `json:"content,omitempty"`
+}
+
+// ActionFor returns the action for the requests of the server.
+func (e *AgentElicitation) ActionFor(server string) ElicitationAction {
+	if e == nil {
+		return ElicitationForward
+	}
+	if action, ok := e.Servers[server]; ok && action != "" {
+		return action
+	}
+	if e.Action == "" {
+		return ElicitationForward
+	}
+	return e.Action
+}
+
+func (e AgentElicitation) validate() error {
+	for _, action := range append([]ElicitationAction{e.Action}, slices.Collect(maps.Values(e.Servers))...) {
+		switch action {
+		case "", ElicitationForward, ElicitationAccept, ElicitationDecline, ElicitationCancel:
+		default:
+			return fmt.Errorf("elicitation action %q must be forward, accept, decline, or cancel", action)
+		}
+	}
+	return nil
+}
+
+// GuardrailAction is what is done with a message a guardrail matches.
+type GuardrailAction string
+
+const (
+	// GuardrailBlock replaces the message, a blocked user message isn't sent
+	// to the model and a blocked answer isn't returned.
+	GuardrailBlock GuardrailAction = "block"
+	// GuardrailFlag only records that the guardrail matched.
+	GuardrailFlag GuardrailAction = "flag"
+	// GuardrailRedact replaces the matched text.
+	GuardrailRedact GuardrailAction = "redact"
+)
+
+// The kinds of personal information a guardrail detects.
+const (
+	PIIEmail      = "email"
+	PIIPhone      = "phone"
+	PIICreditCard = "creditCard"
+	PIISSN        = "ssn"
+	PIIIPAddress  = "ipAddress"
+)
+
+// AgentGuardrails check the messages of users before they reach the model
+// and the answers of the model before they reach the user.
+type AgentGuardrails struct {
+	Input  []Guardrail `json:"input,omitempty"`
+	Output []Guardrail `json:"output,omitempty"`
+	// Message replaces blocked messages.
+	Message string `json:"message,omitempty"`
+}
+
+// Guardrail matches text with any of its checks.
+type Guardrail struct {
+	// Name identifies the guardrail in logs and hooks.
+	Name string `json:"name,omitempty"`
+	// Patterns are regular expressions.
+	Patterns []string `json:"patterns,omitempty"`
+	// Keywords are matched as whole words, ignoring case.
+	Keywords []string `json:"keywords,omitempty"`
+	// PII are the kinds of personal information that are detected.
+	PII []string `json:"pii,omitempty"`
+	// Moderation asks a model if the text is allowed.
+	Moderation *GuardrailModeration `json:"moderation,omitempty"`
+	// Action defaults to block.
+	Action GuardrailAction `json:"action,omitempty"`
+}
+
+// GuardrailModeration asks a model if text is allowed.
+type GuardrailModeration struct {
+	// Model is the model that is asked, or an agent whose model is used.
+	Model string `json:"model,omitempty"`
+	// Policy describes what isn't allowed. Defaults to harmful, hateful,
+	// violent, sexual, or illegal content.
+	Policy string `json:"policy,omitempty"`
+}
+
+func (g AgentGuardrails) validate() error {
+	var errs []error
+	for direction, guardrails := range map[string][]Guardrail{"input": g.Input, "output": g.Output} {
+		for i, guardrail := range guardrails {
+			if err := guardrail.validate(); err != nil {
+				errs = append(errs, fmt.Errorf("guardrails %s %d: %w", direction, i, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (g Guardrail) validate() error {
+	switch g.Action {
+	case "", GuardrailBlock, GuardrailFlag, GuardrailRedact:
+	default:
+		return fmt.Errorf("action %q must be block, flag, or redact", g.Action)
+	}
+	if len(g.Patterns) == 0 && len(g.Keywords) == 0 && len(g.PII) == 0 && g.Moderation == nil {
+		return fmt.Errorf("must have patterns, keywords, pii, or moderation")
+	}
+	for _, pattern := range g.Patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	for _, kind := range g.PII {
+		switch kind {
+		case PIIEmail, PIIPhone, PIICreditCard, PIISSN, PIIIPAddress:
+		default:
+			return fmt.Errorf("pii %q must be email, phone, creditCard, ssn, or ipAddress", kind)
+		}
+	}
+	if g.Moderation != nil {
+		if g.Moderation.Model == "" {
+			return fmt.Errorf("moderation must have a model")
+		}
+		if g.Action == GuardrailRedact {
+			return fmt.Errorf("moderation can not redact, only block or flag")
+		}
+	}
+	return nil
+}
+
+type AgentPinning struct {
+	Rules []PinRule `json:"rules,omitempty"`
+	// MaxTokens bounds the pinned messages carried forward by compaction.
+	// Pinned messages past it are summarized, oldest first. Defaults to a
+	// quarter of the context window.
+	MaxTokens int `json:"maxTokens,omitempty"`
+}
+
+// PinRule pins the messages that match all of its fields.
+type PinRule struct {
+	// Role is the role of the message, user or assistant.
+	Role string `json:"role,omitempty"`
+	// Tool pins the results of calls to the tool.
+	Tool string `json:"tool,omitempty"`
+	// Match is a regular expression matched against the text of the message.
+	Match string `json:"match,omitempty"`
+}
+
+func (r PinRule) validate() error {
+	if r.Role == "" && r.Tool == "" && r.Match == "" {
+		return fmt.Errorf("pin rule must set role, tool, or match")
+	}
+	if r.Role != "" && r.Role != "user" && r.Role != "assistant" {
+		return fmt.Errorf("pin rule role must be user or assistant, got %q", r.Role)
+	}
+	if _, err := regexp.Compile(r.Match); err != nil {
+		return fmt.Errorf("pin rule match is invalid: %w", err)
+	}
+	return nil
+}
+
 type AgentReasoning struct {
 	Effort  string `json:"effort,omitempty"`
 	Summary string `json:"summary,omitempty"`
+	// BudgetTokens is how many tokens Anthropic models may think for. If
+	// unset it is derived from Effort.
+	BudgetTokens int `json:"budgetTokens,omitempty"`
 }
 
 func (a Agent) ToDisplay(id string) AgentDisplay {
@@ -638,6 +1605,12 @@ func validateReferences(c Config, tools, agents StringList) (bool, map[string]st
 	)
 
 	for _, ref := range tools {
+		if toolRef := ParseToolRef(ref); toolRef.Tool == "" {
+			if _, ok := c.CompositeTools[toolRef.Server]; ok {
+				resolvedToolNames[toolRef.PublishedName("")] = struct{}{}
+				continue
+			}
+		}
 		targetName, err := validateReference(ref, mcpServerName, c.MCPServers)
 		if err != nil {
 			errs = append(errs, fmt.Errorf("error validating tool reference %q: %w", ref, err))
@@ -676,6 +1649,45 @@ func (a Agent) validate(agentName string, c Config) error {
 		}
 	}
 
+	for _, handoff := range a.Handoffs {
+		if handoff == agentName {
+			errs = append(errs, fmt.Errorf("agent %q can not hand off to itself", agentName))
+		} else if _, ok := c.Agents[handoff]; !ok {
+			errs = append(errs, fmt.Errorf("agent %q has handoff %q that is not an agent defined in config", agentName, handoff))
+		}
+	}
+
+	if a.MaxParallelToolCalls < 0 || a.MaxTurns < 0 || a.MaxTotalTokens < 0 || a.MaxToolCalls < 0 {
+		errs = append(errs, fmt.Errorf("agent %q must not have negative maxParallelToolCalls, maxTurns, maxTotalTokens, or maxToolCalls", agentName))
+	}
+
+	if a.Reasoning != nil && a.Reasoning.BudgetTokens != 0 && a.Reasoning.BudgetTokens < 1024 {
+		errs = append(errs, fmt.Errorf("agent %q reasoning budgetTokens must be at least 1024", agentName))
+	}
+
+	if a.Pinning != nil {
+		if a.Pinning.MaxTokens < 0 {
+			errs = append(errs, fmt.Errorf("agent %q must not have negative pinning maxTokens", agentName))
+		}
+		for i, rule := range a.Pinning.Rules {
+			if err := rule.validate(); err != nil {
+				errs = append(errs, fmt.Errorf("agent %q pinning rule %d: %w", agentName, i, err))
+			}
+		}
+	}
+
+	if a.Elicitation != nil {
+		if err := a.Elicitation.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("agent %q %w", agentName, err))
+		}
+	}
+
+	if a.Guardrails != nil {
+		if err := a.Guardrails.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("agent %q %w", agentName, err))
+		}
+	}
+
 	if !unknownNames && a.ToolChoice != "" && a.ToolChoice != "none" && a.ToolChoice != "auto" {
 		if _, ok := resolvedToolNames[a.ToolChoice]; !ok {
 			errs = append(errs, fmt.Errorf("agent %q has tool choice %q that is not defined in tools", agentName, a.ToolChoice))
@@ -729,10 +1741,20 @@ type OutputSchema struct {
 	Fields      map[string]Field `json:"fields,omitempty"`
 }
 
+const (
+	FieldTypeString = "string"
+	// FieldTypeResource marks a prompt input whose value is a resource URI. The
+	// resource is read and embedded in the prompt result.
+	FieldTypeResource = "resource"
+)
+
 type Field struct {
 	Description string           `json:"description,omitempty"`
 	Fields      map[string]Field `json:"fields,omitempty"`
 	Required    *bool            `json:"required,omitempty"`
+	// Type is only used for prompt inputs and is either "string" (default) or
+	// "resource".
+	Type string `json:"type,omitempty"`
 }
 
 func (f *Field) UnmarshalJSON(data []byte) error {
@@ -750,7 +1772,7 @@ func (f *Field) UnmarshalJSON(data []byte) error {
 }
 
 func (f Field) MarshalJSON() ([]byte, error) {
-	if len(f.Fields) > 0 {
+	if len(f.Fields) > 0 || f.Type != "" || f.Required != nil {
 		type Alias Field
 		return json.Marshal(Alias(f))
 	}
@@ -784,6 +1806,17 @@ func (i InputSchema) ToSchema() json.RawMessage {
 // but it is used to detect if a field is an enum based on the presence of parentheses.
 var enumSyntaxRegexp = regexp.MustCompile(`^.+\(.+,`)
 
+// arrayItemTypes are the JSON schema types of the items of arrays like
+// name(int)[]. Arrays without a type hold strings.
+var arrayItemTypes = map[string]string{
+	"int":     "integer",
+	"integer": "integer",
+	"float":   "number",
+	"number":  "number",
+	"bool":    "boolean",
+	"boolean": "boolean",
+}
+
 func buildSimpleSchema(name, description string, args map[string]Field) map[string]any {
 	required := make([]string, 0)
 	jsonschema := map[string]any{
@@ -803,11 +1836,15 @@ func buildSimpleSchema(name, description string, args map[string]Field) map[stri
 	for name, field := range args {
 		if strings.HasSuffix(name, "[]") {
 			name = strings.TrimSuffix(name, "[]")
+			itemType := "string"
+			if base, kind, ok := strings.Cut(name, "("); ok && arrayItemTypes[strings.TrimSuffix(kind, ")")] != "" {
+				name, itemType = base, arrayItemTypes[strings.TrimSuffix(kind, ")")]
+			}
 			jsonschema["properties"].(map[string]any)[name] = map[string]any{
 				"type":        "array",
 				"description": field.Description,
 				"items": map[string]any{
-					"type": "string",
+					"type": itemType,
 				},
 			}
 			if len(field.Fields) > 0 {
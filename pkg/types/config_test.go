@@ -487,3 +487,55 @@ third: allow
 		})
 	}
 }
+
+func TestPrompt_ResourceInputs(t *testing.T) {
+	var prompt Prompt
+	if err := json.Unmarshal([]byte(`{
+		"description": "Review a file",
+		"input": {
+			"file": {"description": "The file to review", "type": "resource"},
+			"focus": "What to focus on"
+		},
+		"template": "Review ${file} focusing on ${focus}"
+	}`), &prompt); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := prompt.ResourceInputs(); !reflect.DeepEqual(got, []string{"file"}) {
+		t.Errorf("ResourceInputs() = %v, want [file]", got)
+	}
+
+	for _, arg := range prompt.ToPrompt("review").Arguments {
+		want := "What to focus on"
+		if arg.Name == "file" {
+			want = "The file to review (resource URI)"
+		}
+		if arg.Description != want {
+			t.Errorf("argument %s description = %q, want %q", arg.Name, arg.Description, want)
+		}
+	}
+
+	data, err := json.Marshal(prompt.Input["file"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"description":"The file to review","type":"resource"}` {
+		t.Errorf("unexpected field JSON: %s", data)
+	}
+}
+
+func TestConfig_ValidatePromptInputType(t *testing.T) {
+	config := Config{
+		Prompts: map[string]Prompt{
+			"review": {Input: map[string]Field{"file": {Type: "file"}}},
+		},
+	}
+	if err := config.Validate(true); err == nil {
+		t.Error("expected an error for an invalid prompt input type")
+	}
+
+	config.Prompts["review"].Input["file"] = Field{Type: FieldTypeResource}
+	if err := config.Validate(true); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -1,8 +1,24 @@
 package types
 
-import "github.com/obot-platform/nanobot/pkg/mcp"
+import (
+	"context"
+	"slices"
 
-const PreviousExecutionKey = "thread"
+	"github.com/obot-platform/nanobot/pkg/mcp"
+)
+
+const (
+	PreviousExecutionKey = "thread"
+	// CheckpointerSessionKey holds the SessionCheckpointer that stores the
+	// progress of runs.
+	CheckpointerSessionKey = "checkpointer"
+)
+
+// SessionCheckpointer stores a session as it is so that its runs in progress
+// survive a restart.
+type SessionCheckpointer interface {
+	CheckpointSession(ctx context.Context, id string) error
+}
 
 type Execution struct {
 	Request           CompletionRequest     `json:"request,omitempty"`
@@ -12,6 +28,29 @@ type Execution struct {
 	Response          *CompletionResponse   `json:"response,omitempty"`
 	ToolOutputs       map[string]ToolOutput `json:"toolOutputs,omitempty"`
 	CompactedMessages []Message             `json:"compactedMessages,omitempty"`
+	// UndoneMessages are the messages of undone turns. They are kept for the
+	// record but are no longer part of the conversation.
+	UndoneMessages []Message `json:"undoneMessages,omitempty"`
+	// Interrupted is set on a run that was found unfinished by the next one,
+	// after the process running it stopped. Its unfinished tool calls have an
+	// error result.
+	Interrupted bool `json:"interrupted,omitempty"`
+	// Handoff is set when the agent of the run handed the conversation to
+	// another agent, which the next run is made with.
+	Handoff *Handoff `json:"handoff,omitempty"`
+}
+
+// Messages returns the messages of the chat: the ones archived by compaction
+// followed by the input of the last run and its reply.
+func (e *Execution) Messages() []Message {
+	messages := slices.Clone(e.CompactedMessages)
+	if e.PopulatedRequest != nil {
+		messages = append(messages, e.PopulatedRequest.Input...)
+	}
+	if e.Response != nil && len(e.Response.Output.Items) > 0 {
+		messages = append(messages, e.Response.Output)
+	}
+	return messages
 }
 
 func (e *Execution) Serialize() (any, error) {
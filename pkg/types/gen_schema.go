@@ -0,0 +1,25 @@
+//go:build ignore
+
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+func main() {
+	data, err := types.SessionSchemaJSON()
+	if err != nil {
+		log.Fatal(err)
+	}
+	path := filepath.Join("..", "..", "docs", "schemas", "session.v1.schema.json")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
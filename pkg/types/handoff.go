@@ -0,0 +1,36 @@
+package types
+
+import "time"
+
+const (
+	// HandoffToolName is the tool that agents with handoffs call to hand the
+	// conversation to one of them.
+	HandoffToolName = "handoff"
+	// HandoffsSessionKey holds the handoffs of the chat of a session, oldest
+	// first.
+	HandoffsSessionKey = "handoffs"
+)
+
+// Handoff is a transfer of a conversation from one agent to another.
+type Handoff struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Summary is what the agent handing off tells the agent taking over
+	// about the conversation so far.
+	Summary string    `json:"summary,omitempty"`
+	RunID   string    `json:"runId,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+// HandoffTarget returns the agent that now has the conversation of agent,
+// following the handoffs made since it was handed to agent. That is agent
+// itself if it didn't hand the conversation off.
+func HandoffTarget(handoffs []Handoff, agent string) string {
+	target := agent
+	for _, handoff := range handoffs {
+		if handoff.From == target {
+			target = handoff.To
+		}
+	}
+	return target
+}
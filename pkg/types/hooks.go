@@ -14,6 +14,9 @@ type AgentConfigHook struct {
 	Meta       map[string]any                      `json:"_meta,omitempty"`
 	SessionID  string                              `json:"sessionId,omitempty"`
 	MCPServers map[string]AgentConfigHookMCPServer `json:"mcpServers,omitempty"`
+	// ClientCapabilities are the capabilities the connected client declared,
+	// so hooks can leave out features the client can't handle.
+	ClientCapabilities mcp.ClientCapabilities `json:"clientCapabilities,omitzero"`
 }
 
 type HookAgent struct {
@@ -24,11 +27,14 @@ type HookAgent struct {
 	IconDark        string                    `json:"iconDark,omitempty"`
 	StarterMessages StringList                `json:"starterMessages,omitempty"`
 	Instructions    DynamicInstructions       `json:"instructions,omitzero"`
-	Model           string                    `json:"model,omitempty"`
+	Model           AgentModel                `json:"model,omitempty"`
 	Permissions     *AgentPermissions         `json:"permissions,omitempty"`
+	Elicitation     *AgentElicitation         `json:"elicitation,omitempty"`
+	Guardrails      *AgentGuardrails          `json:"guardrails,omitempty"`
 	MCPServers      StringList                `json:"mcpServers,omitempty"`
 	Tools           StringList                `json:"tools,omitempty"`
 	Agents          StringList                `json:"agents,omitempty"`
+	Handoffs        StringList                `json:"handoffs,omitempty"`
 	Prompts         StringList                `json:"prompts,omitzero"`
 	Resources       StringList                `json:"resources,omitzero"`
 	Reasoning       *AgentReasoning           `json:"reasoning,omitempty"`
@@ -44,6 +50,24 @@ type HookAgent struct {
 	MimeTypes       []string                  `json:"mimeTypes,omitempty"`
 	Hooks           mcp.Hooks                 `json:"hooks,omitempty"`
 
+	// ParallelToolCalls set to false asks the model to make one tool call
+	// per response, for models that handle parallel calls poorly.
+	ParallelToolCalls *bool `json:"parallelToolCalls,omitempty"`
+	// PromptCaching set to false turns off prompt caching for providers
+	// that cache only when asked to.
+	PromptCaching *bool `json:"promptCaching,omitempty"`
+	// MaxParallelToolCalls limits how many read-only tool calls from one
+	// response run at once, 1 runs them one at a time.
+	MaxParallelToolCalls int `json:"maxParallelToolCalls,omitempty"`
+	// MaxTurns, MaxTotalTokens, and MaxToolCalls bound a single run of the
+	// agent. Zero means unbounded.
+	MaxTurns       int `json:"maxTurns,omitempty"`
+	MaxTotalTokens int `json:"maxTotalTokens,omitempty"`
+	MaxToolCalls   int `json:"maxToolCalls,omitempty"`
+	// Pinning selects messages that compaction carries forward as they are
+	// instead of summarizing them.
+	Pinning *AgentPinning `json:"pinning,omitempty"`
+
 	// Selection criteria fields
 
 	Aliases      []string `json:"aliases,omitempty"`
@@ -75,10 +99,84 @@ type AgentRequestHook struct {
 // Hook Name = "response"
 type AgentResponseHook = AgentRequestHook
 
+// AgentBudgetHook is sent when an agent run exhausts one of its budgets. The
+// run always finishes, so the hook's output is ignored.
+// Hook Name = "budgetExhausted"
+type AgentBudgetHook struct {
+	Agent     string           `json:"agent"`
+	SessionID string           `json:"sessionId,omitempty"`
+	Budget    *BudgetExhausted `json:"budget"`
+}
+
+// AgentRunHook is sent when an agent run starts, finishes, or fails. RunID is
+// the ID of the message that started the run. Run hooks only observe the run,
+// they run in the background and their output is ignored.
+// Hook Name = "runStart", "runFinish", "error"
+type AgentRunHook struct {
+	Agent     string              `json:"agent"`
+	SessionID string              `json:"sessionId,omitempty"`
+	RunID     string              `json:"runId,omitempty"`
+	Input     []Message           `json:"input,omitempty"`
+	Response  *CompletionResponse `json:"response,omitempty"`
+	Error     string              `json:"error,omitempty"`
+}
+
+// AgentToolCallHook is sent when a tool call of an agent run finished. The
+// hook can be limited to a tool with the "tool" parameter, as in
+// "toolCall?tool=deploy".
+// Hook Name = "toolCall"
+type AgentToolCallHook struct {
+	Agent     string          `json:"agent"`
+	SessionID string          `json:"sessionId,omitempty"`
+	RunID     string          `json:"runId,omitempty"`
+	ToolCall  *ToolCall       `json:"toolCall"`
+	Result    *ToolCallResult `json:"result,omitempty"`
+}
+
+// AgentCompactionHook is sent when the conversation of an agent run was
+// compacted.
+// Hook Name = "compaction"
+type AgentCompactionHook struct {
+	Agent     string `json:"agent"`
+	SessionID string `json:"sessionId,omitempty"`
+	RunID     string `json:"runId,omitempty"`
+	Archived  int    `json:"archived"`
+	Kept      int    `json:"kept"`
+}
+
+// AgentHandoffHook is sent when an agent hands the conversation to another
+// agent.
+// Hook Name = "handoff"
+type AgentHandoffHook struct {
+	Agent     string  `json:"agent"`
+	SessionID string  `json:"sessionId,omitempty"`
+	RunID     string  `json:"runId,omitempty"`
+	Handoff   Handoff `json:"handoff"`
+}
+
+// AgentGuardrailHook is sent when a guardrail of an agent matched a message.
+// Direction is input for messages of the user and output for answers of the
+// model. The matched text isn't sent.
+// Hook Name = "guardrail"
+type AgentGuardrailHook struct {
+	Agent     string          `json:"agent"`
+	SessionID string          `json:"sessionId,omitempty"`
+	RunID     string          `json:"runId,omitempty"`
+	Guardrail string          `json:"guardrail"`
+	Direction string          `json:"direction"`
+	Action    GuardrailAction `json:"action"`
+	// Matches are the checks that matched, as in pattern, keyword, pii:email,
+	// or moderation.
+	Matches []string `json:"matches"`
+	// Reason is the reason of the moderation model.
+	Reason string `json:"reason,omitempty"`
+}
+
 type SessionInitHook struct {
-	URL       string         `json:"url"`
-	SessionID string         `json:"sessionId"`
-	Meta      map[string]any `json:"_meta,omitempty"`
+	URL                string                 `json:"url"`
+	SessionID          string                 `json:"sessionId"`
+	ClientCapabilities mcp.ClientCapabilities `json:"clientCapabilities,omitzero"`
+	Meta               map[string]any         `json:"_meta,omitempty"`
 }
 
 func (s *SessionInitHook) Serialize() (any, error) {
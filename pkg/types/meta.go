@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/obot-platform/nanobot/pkg/mcp"
 )
@@ -14,8 +15,63 @@ var (
 	ToolCallConfirmType = "toolcall/confirm"
 
 	AsyncMetaKey = "ai.nanobot.async"
+
+	// ResponseFormatMetaKey is set on a tools/call request by programmatic
+	// clients to select how the result is returned. The only supported value
+	// is ResponseFormatRaw.
+	ResponseFormatMetaKey = "ai.nanobot.responseFormat"
+	// ResponseFormatRaw returns structuredContent as-is, with the content
+	// replaced by its JSON encoding instead of any rendered text.
+	ResponseFormatRaw = "raw"
+
+	// WorkflowCategoryMetaKey is set on a resources/list request to only list
+	// the workflows in a category and its subcategories.
+	WorkflowCategoryMetaKey = "ai.nanobot.meta/workflow-category"
+
+	// ResourceChangeMetaKey is set on a notifications/resources/updated
+	// message to a ResourceChange describing how a file changed.
+	ResourceChangeMetaKey = "ai.nanobot.meta/resource-change"
 )
 
+// ResourceChange describes how a file changed, so UIs can show it without
+// reading the whole resource again.
+type ResourceChange struct {
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	// Diff is a unified diff of the change, for text files small enough.
+	Diff string `json:"diff,omitempty"`
+}
+
+// IsRawResponseFormat returns true if the request meta asks for the result's
+// structuredContent to be passed through without text rendering or truncation.
+func IsRawResponseFormat(meta map[string]any) bool {
+	format, _ := meta[ResponseFormatMetaKey].(string)
+	return format == ResponseFormatRaw
+}
+
+// RawCallToolResult converts a tool result into its raw passthrough form. When
+// structuredContent is present the rendered content is dropped in favor of a
+// single text item holding the JSON encoding of structuredContent, marked so
+// that it is never truncated. Results without structuredContent are returned
+// unchanged.
+func RawCallToolResult(result mcp.CallToolResult) (mcp.CallToolResult, error) {
+	if result.StructuredContent == nil {
+		return result, nil
+	}
+
+	data, err := json.Marshal(result.StructuredContent)
+	if err != nil {
+		return result, fmt.Errorf("failed to marshal structured content: %w", err)
+	}
+
+	result.Content = []mcp.Content{{
+		Type: "text",
+		Text: string(data),
+		Meta: map[string]any{SkipTruncationMetaKey: true},
+	}}
+	return result, nil
+}
+
 type ToolCallConfirm struct {
 	Type       string    `json:"type"`
 	MCPServer  string    `json:"mcpServer,omitempty"`
@@ -0,0 +1,39 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+)
+
+func TestRawCallToolResult(t *testing.T) {
+	if !IsRawResponseFormat(map[string]any{ResponseFormatMetaKey: ResponseFormatRaw}) {
+		t.Error("expected raw response format")
+	}
+	if IsRawResponseFormat(map[string]any{ResponseFormatMetaKey: "text"}) || IsRawResponseFormat(nil) {
+		t.Error("expected non-raw response format")
+	}
+
+	result, err := RawCallToolResult(mcp.CallToolResult{
+		StructuredContent: map[string]any{"count": 2},
+		Content:           []mcp.Content{{Type: "text", Text: "There are 2 items"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Content) != 1 || result.Content[0].Text != `{"count":2}` {
+		t.Errorf("unexpected content: %+v", result.Content)
+	}
+	if skip, _ := result.Content[0].Meta[SkipTruncationMetaKey].(bool); !skip {
+		t.Error("expected raw content to skip truncation")
+	}
+
+	plain := mcp.CallToolResult{Content: []mcp.Content{{Type: "text", Text: "hello"}}}
+	result, err = RawCallToolResult(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Content) != 1 || result.Content[0].Text != "hello" {
+		t.Errorf("expected result without structured content to be unchanged, got %+v", result.Content)
+	}
+}
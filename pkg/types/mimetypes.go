@@ -13,12 +13,22 @@ const (
 	AgentMimeType       = "application/vnd.nanobot.agent+json"
 	SessionMimeType     = "application/vnd.nanobot.session+json"
 	ElicitationMimeType = "application/vnd.nanobot.elicitation+json"
+	UsageMimeType       = "application/vnd.nanobot.usage+json"
 	MetaNanobot         = "ai.nanobot"
 
 	MessageURI     = "chat://message/%s"
 	HistoryURI     = "chat://history"
+	ArchiveURI     = "chat://history/archive"
 	ProgressURI    = "chat://progress"
 	ElicitationURI = "chat://elicitation"
+	UsageURI       = "chat://usage"
+	// TruncatedOutputURI is the full output of a truncated tool result, by
+	// file name.
+	TruncatedOutputURI = "chat://truncated-outputs/%s"
+	// TranscriptURI is the readable transcript of the chat, by format:
+	// markdown or html. The reasoning=true query includes the model's
+	// reasoning.
+	TranscriptURI = "transcript:///%s"
 )
 
 var (
@@ -0,0 +1,200 @@
+package types
+
+//go:generate go run gen_schema.go
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/obot-platform/nanobot/pkg/mcp"
+)
+
+const (
+	// SessionSchemaVersion is the current version of the public session/message
+	// schema. It is only incremented for breaking changes (removing or renaming
+	// a field, changing a field's type or meaning). Adding optional fields is
+	// not a breaking change, so consumers must ignore properties they do not
+	// recognize.
+	SessionSchemaVersion = 1
+	SessionSchemaID      = "https://nanobot.dev/schemas/session.v1.schema.json"
+)
+
+// SessionExport is the versioned, portable representation of a session's
+// conversation. It is the top level document described by SessionSchemaID.
+type SessionExport struct {
+	SchemaVersion int       `json:"schemaVersion"`
+	SessionID     string    `json:"sessionId,omitempty"`
+	Messages      []Message `json:"messages"`
+}
+
+// sessionExportMigrations upgrade a decoded export document in place. The
+// entry at index i migrates a document from version i+1 to version i+2, so a
+// breaking change adds an entry here alongside the SessionSchemaVersion bump.
+var sessionExportMigrations []func(doc map[string]any) error
+
+// DecodeSessionExport parses a session export written by this or any earlier
+// schema version, migrating it to SessionSchemaVersion. A bare JSON array of
+// messages, as served by the chat history resource, is accepted as well.
+func DecodeSessionExport(data []byte) (SessionExport, error) {
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return SessionExport{}, fmt.Errorf("invalid session export: %w", err)
+	}
+
+	var obj map[string]any
+	switch v := doc.(type) {
+	case []any:
+		obj = map[string]any{"messages": v}
+	case map[string]any:
+		obj = v
+	default:
+		return SessionExport{}, fmt.Errorf("invalid session export: expected an object or an array of messages")
+	}
+
+	version := 1
+	if v, ok := obj["schemaVersion"]; ok {
+		f, ok := v.(float64)
+		if !ok || f < 1 || f != float64(int(f)) {
+			return SessionExport{}, fmt.Errorf("invalid session export: schemaVersion must be a positive integer, got %v", v)
+		}
+		version = int(f)
+	}
+	if version > SessionSchemaVersion {
+		return SessionExport{}, fmt.Errorf("session export schema version %d is newer than the supported version %d", version, SessionSchemaVersion)
+	}
+
+	for ; version < SessionSchemaVersion; version++ {
+		if err := sessionExportMigrations[version-1](obj); err != nil {
+			return SessionExport{}, fmt.Errorf("failed to migrate session export from version %d: %w", version, err)
+		}
+	}
+	obj["schemaVersion"] = SessionSchemaVersion
+
+	var result SessionExport
+	if err := mcp.JSONCoerce(obj, &result); err != nil {
+		return SessionExport{}, fmt.Errorf("invalid session export: %w", err)
+	}
+	return result, nil
+}
+
+// SessionSchema returns the JSON schema for SessionExport and the types it is
+// built from. The schema is generated from the Go types; the published copy
+// under docs/schemas is kept in sync by go generate.
+func SessionSchema() (*jsonschema.Schema, error) {
+	ref := func(name string) *jsonschema.Schema {
+		return &jsonschema.Schema{Ref: "#/$defs/" + name}
+	}
+	opts := &jsonschema.ForOptions{
+		TypeSchemas: map[reflect.Type]*jsonschema.Schema{
+			reflect.TypeFor[[]mcp.Content]():    {Type: "array", Items: ref("content")},
+			reflect.TypeFor[CallResult]():       ref("callResult"),
+			reflect.TypeFor[CompletionItem]():   ref("completionItem"),
+			reflect.TypeFor[Message]():          ref("message"),
+			reflect.TypeFor[time.Time]():        {Type: "string", Format: "date-time"},
+			reflect.TypeFor[[]CompletionItem](): {Type: "array", Items: ref("completionItem")},
+		},
+	}
+
+	defs := map[string]*jsonschema.Schema{}
+	for name, t := range map[string]reflect.Type{
+		"content":       reflect.TypeFor[mcp.Content](),
+		"toolCall":      reflect.TypeFor[ToolCall](),
+		"callResult":    reflect.TypeFor[CallResult](),
+		"message":       reflect.TypeFor[Message](),
+		"toolItem":      reflect.TypeFor[toolCompletionItem](),
+		"reasoningItem": reflect.TypeFor[reasoningCompletionItem](),
+		"sessionExport": reflect.TypeFor[SessionExport](),
+	} {
+		// Generate without the self reference so the definition is expanded.
+		typeOpts := &jsonschema.ForOptions{TypeSchemas: map[reflect.Type]*jsonschema.Schema{}}
+		for k, v := range opts.TypeSchemas {
+			if k != t {
+				typeOpts.TypeSchemas[k] = v
+			}
+		}
+		s, err := jsonschema.ForType(t, typeOpts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate schema for %s: %w", name, err)
+		}
+		defs[name] = s
+	}
+
+	// A content item is an mcp.Content with the item header fields merged in,
+	// see CompletionItem.MarshalJSON.
+	contentItem := defs["content"].CloneSchemas()
+	for _, field := range []string{"id", "hasMore", "partial"} {
+		contentItem.Properties[field] = defs["toolItem"].Properties[field]
+	}
+	contentItem.PropertyOrder = append([]string{"id", "hasMore", "partial"}, slices.DeleteFunc(slices.Clone(contentItem.PropertyOrder), func(field string) bool {
+		return field == "id" || field == "hasMore" || field == "partial"
+	})...)
+	contentItem.Required = []string{"type"}
+	contentItem.Properties["type"] = &jsonschema.Schema{Type: "string", Enum: []any{"text", "image", "audio", "resource", "resource_link"}}
+	defs["contentItem"] = contentItem
+
+	toolType, reasoningType := any("tool"), any("reasoning")
+	defs["toolItem"].Properties["type"] = &jsonschema.Schema{Type: "string", Const: &toolType}
+	defs["toolItem"].Required = []string{"type"}
+	defs["reasoningItem"].Properties["type"] = &jsonschema.Schema{Type: "string", Const: &reasoningType}
+	defs["reasoningItem"].Required = []string{"type"}
+
+	defs["completionItem"] = &jsonschema.Schema{
+		Description: "A single item of a message: content, a tool call and/or its result, or reasoning.",
+		OneOf:       []*jsonschema.Schema{ref("contentItem"), ref("toolItem"), ref("reasoningItem")},
+	}
+
+	export := defs["sessionExport"]
+	delete(defs, "sessionExport")
+	export.Required = []string{"schemaVersion", "messages"}
+	export.Properties["messages"].Types = nil
+	export.Properties["messages"].Type = "array"
+	version := any(SessionSchemaVersion)
+	export.Properties["schemaVersion"] = &jsonschema.Schema{Type: "integer", Const: &version}
+
+	export.Schema = "https://json-schema.org/draft/2020-12/schema"
+	export.ID = SessionSchemaID
+	export.Title = "Nanobot Session Export"
+	export.Description = fmt.Sprintf("Version %d of the nanobot session and message model.", SessionSchemaVersion)
+	export.Defs = defs
+
+	// Consumers must ignore unknown properties, so never forbid them. This is
+	// what allows optional fields to be added without a version bump.
+	allowAdditionalProperties(export)
+	return export, nil
+}
+
+// SessionSchemaJSON returns the indented JSON encoding of SessionSchema.
+func SessionSchemaJSON() ([]byte, error) {
+	s, err := SessionSchema()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func allowAdditionalProperties(s *jsonschema.Schema) {
+	if s == nil {
+		return
+	}
+	if s.AdditionalProperties != nil && s.AdditionalProperties.Not != nil {
+		s.AdditionalProperties = nil
+	}
+	allowAdditionalProperties(s.Items)
+	for _, child := range s.Properties {
+		allowAdditionalProperties(child)
+	}
+	for _, child := range s.Defs {
+		allowAdditionalProperties(child)
+	}
+	for _, child := range s.OneOf {
+		allowAdditionalProperties(child)
+	}
+}
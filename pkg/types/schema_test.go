@@ -0,0 +1,126 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+func TestSessionSchemaUpToDate(t *testing.T) {
+	generated, err := SessionSchemaJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	published, err := os.ReadFile(filepath.Join("..", "..", "docs", "schemas", "session.v1.schema.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(generated, published) {
+		t.Error("docs/schemas/session.v1.schema.json is out of date, run go generate ./pkg/types")
+	}
+}
+
+func TestSessionSchemaValidatesMessages(t *testing.T) {
+	data, err := SessionSchemaJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := jsonschema.NewCompiler()
+	if err := c.AddResource(SessionSchemaID, doc); err != nil {
+		t.Fatal(err)
+	}
+	schema, err := c.Compile(SessionSchemaID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	export := SessionExport{
+		SchemaVersion: SessionSchemaVersion,
+		SessionID:     "abc",
+		Messages: []Message{
+			{
+				ID:      "m1",
+				Created: &created,
+				Role:    "user",
+				Items:   []CompletionItem{{Content: &mcp.Content{Type: "text", Text: "hi"}}},
+			},
+			{
+				ID:   "m2",
+				Role: "assistant",
+				Items: []CompletionItem{
+					{Reasoning: &Reasoning{Summary: []SummaryText{{Text: "thinking"}}}},
+					{
+						ToolCall: &ToolCall{CallID: "c1", Name: "read", Arguments: `{"file_path":"a"}`},
+						ToolCallResult: &ToolCallResult{CallID: "c1", Output: CallResult{
+							Content: []mcp.Content{{Type: "text", Text: "contents"}},
+						}},
+					},
+				},
+			},
+		},
+	}
+
+	data, err = json.Marshal(export)
+	if err != nil {
+		t.Fatal(err)
+	}
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := schema.Validate(instance); err != nil {
+		t.Errorf("expected export to validate: %v", err)
+	}
+
+	invalid, _ := jsonschema.UnmarshalJSON(strings.NewReader(`{"schemaVersion":1,"messages":[{"items":[{"type":"bogus"}]}]}`))
+	if err := schema.Validate(invalid); err == nil {
+		t.Error("expected unknown item type to fail validation")
+	}
+}
+
+func TestDecodeSessionExport(t *testing.T) {
+	t.Run("current version", func(t *testing.T) {
+		export, err := DecodeSessionExport([]byte(`{"schemaVersion":1,"sessionId":"abc","messages":[{"id":"m1","role":"user","items":[{"type":"text","text":"hi"}]}]}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if export.SessionID != "abc" || len(export.Messages) != 1 || export.Messages[0].Items[0].Content.Text != "hi" {
+			t.Errorf("unexpected export: %+v", export)
+		}
+	})
+
+	t.Run("bare message array", func(t *testing.T) {
+		export, err := DecodeSessionExport([]byte(`[{"id":"m1","role":"user"}]`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if export.SchemaVersion != SessionSchemaVersion || len(export.Messages) != 1 {
+			t.Errorf("unexpected export: %+v", export)
+		}
+	})
+
+	t.Run("newer version", func(t *testing.T) {
+		_, err := DecodeSessionExport([]byte(`{"schemaVersion":99,"messages":[]}`))
+		if err == nil || !strings.Contains(err.Error(), "newer than the supported version") {
+			t.Errorf("expected version error, got %v", err)
+		}
+	})
+
+	t.Run("invalid version", func(t *testing.T) {
+		if _, err := DecodeSessionExport([]byte(`{"schemaVersion":"1","messages":[]}`)); err == nil {
+			t.Error("expected error for non-integer schemaVersion")
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package types
+
+import "github.com/obot-platform/nanobot/pkg/mcp"
+
+// UsageSessionKey holds the SessionUsage of the session.
+const UsageSessionKey = "usage"
+
+// Usage is the number of tokens used by completions, as reported by the
+// provider. InputTokens doesn't include input tokens that were read from or
+// written to the provider's prompt cache, those are counted separately because
+// they are priced differently.
+type Usage struct {
+	InputTokens      int `json:"inputTokens,omitempty"`
+	OutputTokens     int `json:"outputTokens,omitempty"`
+	CacheReadTokens  int `json:"cacheReadTokens,omitempty"`
+	CacheWriteTokens int `json:"cacheWriteTokens,omitempty"`
+	// ReasoningTokens is the part of OutputTokens the model spent reasoning.
+	ReasoningTokens int `json:"reasoningTokens,omitempty"`
+	// Completions is the number of completions the usage was added up from.
+	Completions int `json:"completions,omitempty"`
+}
+
+func (u *Usage) Add(other Usage) {
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.CacheReadTokens += other.CacheReadTokens
+	u.CacheWriteTokens += other.CacheWriteTokens
+	u.ReasoningTokens += other.ReasoningTokens
+	u.Completions += other.Completions
+}
+
+func (u Usage) TotalTokens() int {
+	return u.InputTokens + u.OutputTokens + u.CacheReadTokens + u.CacheWriteTokens
+}
+
+// SessionUsage is the token usage of a session, by model.
+type SessionUsage struct {
+	Models map[string]Usage `json:"models,omitempty"`
+}
+
+func (s *SessionUsage) Add(model string, usage Usage) {
+	if s.Models == nil {
+		s.Models = map[string]Usage{}
+	}
+	total := s.Models[model]
+	total.Add(usage)
+	s.Models[model] = total
+}
+
+func (s SessionUsage) Total() (result Usage) {
+	for _, usage := range s.Models {
+		result.Add(usage)
+	}
+	return result
+}
+
+func (s *SessionUsage) Serialize() (any, error) {
+	return s, nil
+}
+
+func (s *SessionUsage) Deserialize(data any) (any, error) {
+	return s, mcp.JSONCoerce(data, s)
+}
+
+// ModelUsage is the usage of one model and what it cost.
+type ModelUsage struct {
+	Model string `json:"model"`
+	Usage
+	// Cost is the estimated cost in US dollars, unset if the prices of the
+	// model are unknown.
+	Cost *float64 `json:"cost,omitempty"`
+}
+
+// UsageReport is the usage of a session with its estimated cost.
+type UsageReport struct {
+	Models []ModelUsage `json:"models,omitempty"`
+	Total  Usage        `json:"total"`
+	// Cost is the estimated cost in US dollars of the models with known
+	// prices.
+	Cost float64 `json:"cost"`
+}
@@ -0,0 +1,289 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/obot-platform/nanobot/pkg/agents"
+	"sigs.k8s.io/yaml"
+)
+
+// DefinitionFile is the file in a workflow directory that makes the workflow
+// executable. The SKILL.md of the workflow still describes it.
+const DefinitionFile = "workflow.yaml"
+
+const (
+	// MaxRetries is the most times a step can be retried.
+	MaxRetries = 10
+	// MaxLoopIterations is the most times a loop can run its steps, and the
+	// number of times an until loop runs them when maxIterations isn't set.
+	MaxLoopIterations = 100
+)
+
+var validStepID = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Definition is an executable workflow. Its steps run one after another, and
+// strings in them can use ${...} expressions over the inputs of the run and
+// the outputs of the steps that ran before, as in ${steps.fetch.output}.
+type Definition struct {
+	Description string           `json:"description,omitempty"`
+	Inputs      map[string]Input `json:"inputs,omitempty"`
+	Steps       []Step           `json:"steps"`
+}
+
+type Input struct {
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	Default     any    `json:"default,omitempty"`
+}
+
+// Step is one step of a workflow. It has exactly one of a tool, a prompt, a
+// fan-out, a condition, a loop, or an approval.
+type Step struct {
+	// ID names the step in expressions. It is unique in the workflow.
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+
+	// Tool is the server/tool to call with Args.
+	Tool string         `json:"tool,omitempty"`
+	Args map[string]any `json:"args,omitempty"`
+
+	// Prompt is run by Agent in a sub-agent, which reports back the output
+	// of the step. Agent defaults to the agent that started the run.
+	Agent    string   `json:"agent,omitempty"`
+	Prompt   string   `json:"prompt,omitempty"`
+	Tools    []string `json:"tools,omitempty"`
+	MaxTurns int      `json:"maxTurns,omitempty"`
+
+	FanOut    *FanOut    `json:"fanout,omitempty"`
+	Condition *Condition `json:"condition,omitempty"`
+	Loop      *Loop      `json:"loop,omitempty"`
+	Approval  *Approval  `json:"approval,omitempty"`
+
+	// TimeoutMS bounds each attempt of a tool, prompt, or fan-out step.
+	TimeoutMS int `json:"timeoutMs,omitempty"`
+	// MaxRetries is the number of times a failed tool, prompt, or fan-out
+	// step is tried again.
+	MaxRetries int `json:"maxRetries,omitempty"`
+	// RetryDelayMS is the wait before the first retry, which doubles for
+	// every retry after it. Defaults to one second.
+	RetryDelayMS int `json:"retryDelayMs,omitempty"`
+}
+
+// FanOut runs a prompt in several sub-agents concurrently. Its output has the
+// results of all branches.
+type FanOut struct {
+	Prompt   string   `json:"prompt,omitempty"`
+	Branches []Branch `json:"branches"`
+}
+
+type Branch struct {
+	Agent     string   `json:"agent,omitempty"`
+	Prompt    string   `json:"prompt,omitempty"`
+	Tools     []string `json:"tools,omitempty"`
+	TimeoutMS int      `json:"timeoutMs,omitempty"`
+	MaxTurns  int      `json:"maxTurns,omitempty"`
+}
+
+// Condition runs Then when If is true and Else otherwise.
+type Condition struct {
+	If   string `json:"if"`
+	Then []Step `json:"then,omitempty"`
+	Else []Step `json:"else,omitempty"`
+}
+
+// Loop runs its steps for each item of ForEach, with ${loop.item} and
+// ${loop.index} set, or until Until is true after an iteration.
+type Loop struct {
+	ForEach       any    `json:"forEach,omitempty"`
+	Until         string `json:"until,omitempty"`
+	MaxIterations int    `json:"maxIterations,omitempty"`
+	Steps         []Step `json:"steps"`
+}
+
+// Approval pauses the run until a user approves or rejects Message. A
+// rejection fails the run.
+type Approval struct {
+	Message string `json:"message"`
+}
+
+type StepType string
+
+const (
+	StepTool      StepType = "tool"
+	StepPrompt    StepType = "prompt"
+	StepFanOut    StepType = "fanout"
+	StepCondition StepType = "condition"
+	StepLoop      StepType = "loop"
+	StepApproval  StepType = "approval"
+)
+
+// Types returns the types of the step, which is exactly one for a valid step.
+func (s Step) Types() (result []StepType) {
+	if s.Tool != "" {
+		result = append(result, StepTool)
+	}
+	if s.Prompt != "" {
+		result = append(result, StepPrompt)
+	}
+	if s.FanOut != nil {
+		result = append(result, StepFanOut)
+	}
+	if s.Condition != nil {
+		result = append(result, StepCondition)
+	}
+	if s.Loop != nil {
+		result = append(result, StepLoop)
+	}
+	if s.Approval != nil {
+		result = append(result, StepApproval)
+	}
+	return
+}
+
+func (s Step) Type() StepType {
+	if types := s.Types(); len(types) == 1 {
+		return types[0]
+	}
+	return ""
+}
+
+// LoadDefinition reads the definition of the workflow in dir.
+func LoadDefinition(dir string) (*Definition, error) {
+	data, err := os.ReadFile(filepath.Join(dir, DefinitionFile))
+	if err != nil {
+		return nil, err
+	}
+	return ParseDefinition(data)
+}
+
+// ParseDefinition parses and validates a workflow definition.
+func ParseDefinition(data []byte) (*Definition, error) {
+	var def Definition
+	if err := yaml.UnmarshalStrict(data, &def); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", DefinitionFile, err)
+	}
+	if err := def.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", DefinitionFile, err)
+	}
+	return &def, nil
+}
+
+func (d Definition) Validate() error {
+	if len(d.Steps) == 0 {
+		return errors.New("a workflow needs at least one step")
+	}
+	return validateSteps(d.Steps, map[string]bool{})
+}
+
+func validateSteps(steps []Step, ids map[string]bool) error {
+	var errs []error
+	for _, step := range steps {
+		if err := validateStep(step, ids); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func validateStep(step Step, ids map[string]bool) error {
+	if !validStepID.MatchString(step.ID) {
+		return fmt.Errorf("step id %q must start with a letter or underscore and only contain letters, digits, and underscores", step.ID)
+	}
+	if ids[step.ID] {
+		return fmt.Errorf("step id %q is used more than once", step.ID)
+	}
+	ids[step.ID] = true
+
+	stepTypes := step.Types()
+	if len(stepTypes) != 1 {
+		return fmt.Errorf("step %s must have exactly one of tool, prompt, fanout, condition, loop, or approval, got %d", step.ID, len(stepTypes))
+	}
+
+	if step.TimeoutMS < 0 || step.RetryDelayMS < 0 || step.MaxTurns < 0 {
+		return fmt.Errorf("step %s: timeoutMs, retryDelayMs, and maxTurns must not be negative", step.ID)
+	}
+	if step.MaxRetries < 0 || step.MaxRetries > MaxRetries {
+		return fmt.Errorf("step %s: maxRetries must be between 0 and %d", step.ID, MaxRetries)
+	}
+	if (step.Args != nil && stepTypes[0] != StepTool) ||
+		((step.Agent != "" || len(step.Tools) > 0 || step.MaxTurns > 0) && stepTypes[0] != StepPrompt) {
+		return fmt.Errorf("step %s: args only apply to tool steps, and agent, tools, and maxTurns only to prompt steps", step.ID)
+	}
+
+	switch stepTypes[0] {
+	case StepTool:
+		if server, tool, ok := strings.Cut(step.Tool, "/"); !ok || server == "" || tool == "" {
+			return fmt.Errorf("step %s: tool %q must be a server/tool reference", step.ID, step.Tool)
+		}
+	case StepFanOut:
+		if len(step.FanOut.Branches) == 0 || len(step.FanOut.Branches) > agents.MaxFanOutBranches {
+			return fmt.Errorf("step %s: a fan-out needs between 1 and %d branches", step.ID, agents.MaxFanOutBranches)
+		}
+		for i, branch := range step.FanOut.Branches {
+			if branch.Prompt == "" && step.FanOut.Prompt == "" {
+				return fmt.Errorf("step %s: branch %d has no prompt", step.ID, i)
+			}
+			if branch.TimeoutMS < 0 || branch.MaxTurns < 0 {
+				return fmt.Errorf("step %s: timeoutMs and maxTurns of branch %d must not be negative", step.ID, i)
+			}
+		}
+	case StepCondition, StepLoop, StepApproval:
+		if step.TimeoutMS > 0 || step.MaxRetries > 0 || step.RetryDelayMS > 0 {
+			return fmt.Errorf("step %s: timeouts and retries only apply to tool, prompt, and fanout steps", step.ID)
+		}
+	}
+
+	switch stepTypes[0] {
+	case StepCondition:
+		if strings.TrimSpace(step.Condition.If) == "" {
+			return fmt.Errorf("step %s: the condition needs an if expression", step.ID)
+		}
+		if len(step.Condition.Then) == 0 && len(step.Condition.Else) == 0 {
+			return fmt.Errorf("step %s: the condition needs then or else steps", step.ID)
+		}
+		return errors.Join(validateSteps(step.Condition.Then, ids), validateSteps(step.Condition.Else, ids))
+	case StepLoop:
+		if (step.Loop.ForEach == nil) == (step.Loop.Until == "") {
+			return fmt.Errorf("step %s: the loop needs exactly one of forEach or until", step.ID)
+		}
+		if step.Loop.MaxIterations < 0 || step.Loop.MaxIterations > MaxLoopIterations {
+			return fmt.Errorf("step %s: maxIterations must be between 0 and %d", step.ID, MaxLoopIterations)
+		}
+		if len(step.Loop.Steps) == 0 {
+			return fmt.Errorf("step %s: the loop needs at least one step", step.ID)
+		}
+		return validateSteps(step.Loop.Steps, ids)
+	case StepApproval:
+		if strings.TrimSpace(step.Approval.Message) == "" {
+			return fmt.Errorf("step %s: the approval needs a message", step.ID)
+		}
+	}
+	return nil
+}
+
+// ApplyInputs returns the inputs of a run, with the defaults of the missing
+// ones. It fails for missing required inputs and for unknown ones.
+func (d Definition) ApplyInputs(inputs map[string]any) (map[string]any, error) {
+	result := make(map[string]any, len(d.Inputs))
+	for name := range inputs {
+		if _, ok := d.Inputs[name]; !ok {
+			return nil, fmt.Errorf("unknown input %q", name)
+		}
+	}
+	for name, input := range d.Inputs {
+		value, ok := inputs[name]
+		if !ok || value == nil {
+			value = input.Default
+		}
+		if value == nil && input.Required {
+			return nil, fmt.Errorf("input %q is required", name)
+		}
+		result[name] = value
+	}
+	return result, nil
+}
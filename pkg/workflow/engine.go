@@ -0,0 +1,883 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/obot-platform/nanobot/pkg/agents"
+	"github.com/obot-platform/nanobot/pkg/expr"
+	"github.com/obot-platform/nanobot/pkg/fswatch"
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/session"
+	"github.com/obot-platform/nanobot/pkg/tools"
+	"github.com/obot-platform/nanobot/pkg/types"
+	"github.com/obot-platform/nanobot/pkg/uuid"
+	"gorm.io/gorm"
+)
+
+// RunURIPrefix is the prefix of the resource URIs of workflow runs.
+const RunURIPrefix = "workflowrun:///"
+
+const (
+	defaultRetryDelay    = time.Second
+	defaultAgentMaxTurns = 25
+)
+
+// ErrRunNotFound is returned for runs that aren't in the session store.
+var ErrRunNotFound = errors.New("workflow run not found")
+
+// errWaiting stops a run at an approval step until it is approved.
+var errWaiting = errors.New("waiting for approval")
+
+type RunStatus string
+
+const (
+	RunRunning   RunStatus = "running"
+	RunWaiting   RunStatus = "waiting"
+	RunCompleted RunStatus = "completed"
+	RunFailed    RunStatus = "failed"
+	RunCancelled RunStatus = "cancelled"
+)
+
+type StepStatus string
+
+const (
+	StepRunning   StepStatus = "running"
+	StepWaiting   StepStatus = "waiting"
+	StepCompleted StepStatus = "completed"
+	StepFailed    StepStatus = "failed"
+)
+
+// Run is a run of a workflow. It keeps the definition it was started with,
+// so it can be resumed after the workflow changed.
+type Run struct {
+	ID          string         `json:"id"`
+	URI         string         `json:"uri"`
+	WorkflowURI string         `json:"workflowURI"`
+	SessionID   string         `json:"sessionId"`
+	Status      RunStatus      `json:"status"`
+	Inputs      map[string]any `json:"inputs,omitempty"`
+	// Steps are the states of the steps that ran, in the order they started.
+	// Steps in loops have the iteration in their key, as in each[2]/notify.
+	Steps      []StepState `json:"steps"`
+	Error      string      `json:"error,omitempty"`
+	Definition Definition  `json:"definition"`
+	Created    time.Time   `json:"created"`
+	Updated    time.Time   `json:"updated"`
+}
+
+type StepState struct {
+	Key      string     `json:"key"`
+	ID       string     `json:"id"`
+	Name     string     `json:"name,omitempty"`
+	Type     StepType   `json:"type"`
+	Status   StepStatus `json:"status"`
+	Attempts int        `json:"attempts,omitempty"`
+	Output   any        `json:"output,omitempty"`
+	Error    string     `json:"error,omitempty"`
+	Started  time.Time  `json:"started"`
+	Finished *time.Time `json:"finished,omitempty"`
+}
+
+// clone copies the run, so that it can be returned while an execution
+// changes its steps.
+func (r *Run) clone() *Run {
+	result := *r
+	result.Steps = slices.Clone(r.Steps)
+	return &result
+}
+
+func (r *Run) step(key string) *StepState {
+	for i := range r.Steps {
+		if r.Steps[i].Key == key {
+			return &r.Steps[i]
+		}
+	}
+	return nil
+}
+
+// ToolCaller calls the tool steps of workflows, with the tools of the agent
+// running the workflow.
+type ToolCaller interface {
+	Call(ctx context.Context, server, tool string, args any, opts ...tools.CallOptions) (*types.CallResult, error)
+	BuildToolMappings(ctx context.Context, toolList []string, opts ...types.BuildToolMappingsOptions) (types.ToolMappings, error)
+}
+
+// AgentRunner runs the prompt and fan-out steps of workflows.
+type AgentRunner interface {
+	RunTask(ctx context.Context, task agents.Task) (*agents.TaskResult, error)
+	RunFanOut(ctx context.Context, fanOut agents.FanOut) (*agents.FanOutResult, error)
+}
+
+// Engine runs workflows in the background and keeps their state in the
+// session store, so runs can be followed as resources and resumed after they
+// failed or were waiting for approval.
+type Engine struct {
+	*fswatch.SubscriptionManager
+	tools  ToolCaller
+	agents AgentRunner
+
+	mu     sync.Mutex
+	active map[string]*execution
+}
+
+func NewEngine(toolCaller ToolCaller, agentRunner AgentRunner) *Engine {
+	return &Engine{
+		SubscriptionManager: fswatch.NewSubscriptionManager(context.Background()),
+		tools:               toolCaller,
+		agents:              agentRunner,
+		active:              map[string]*execution{},
+	}
+}
+
+// Start starts a run of a workflow in the session of ctx and returns it
+// right away.
+func (e *Engine) Start(ctx context.Context, workflowURI string, def Definition, inputs map[string]any) (*Run, error) {
+	store, err := storeFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	inputs, err = def.ApplyInputs(inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	id := uuid.String()
+	now := time.Now()
+	run := &Run{
+		ID:          id,
+		URI:         RunURIPrefix + id,
+		WorkflowURI: workflowURI,
+		SessionID:   mcp.SessionFromContext(ctx).Root().ID(),
+		Status:      RunRunning,
+		Inputs:      inputs,
+		Steps:       []StepState{},
+		Definition:  def,
+		Created:     now,
+		Updated:     now,
+	}
+	if err := saveRun(ctx, store, run); err != nil {
+		return nil, err
+	}
+	e.SendListChangedNotification()
+
+	result := run.clone()
+	if err := e.launch(ctx, store, run); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Get returns a run of the session of ctx.
+func (e *Engine) Get(ctx context.Context, runID string) (*Run, error) {
+	store, err := storeFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return getRun(ctx, store, runID)
+}
+
+// List returns the runs of the session of ctx, newest first.
+func (e *Engine) List(ctx context.Context) ([]Run, error) {
+	store, err := storeFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	executions, err := store.ListWorkflowExecutions(ctx, mcp.SessionFromContext(ctx).Root().ID())
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]Run, 0, len(executions))
+	for _, execution := range executions {
+		var run Run
+		if err := json.Unmarshal(execution.State, &run); err != nil {
+			return nil, fmt.Errorf("failed to read workflow run %s: %w", execution.RunID, err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// Wait waits until a run started by this engine stopped or is waiting for
+// approval, and returns it.
+func (e *Engine) Wait(ctx context.Context, runID string) (*Run, error) {
+	e.mu.Lock()
+	x := e.active[runID]
+	e.mu.Unlock()
+
+	if x != nil {
+		select {
+		case <-x.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return e.Get(ctx, runID)
+}
+
+// Approve decides the approval step a run is waiting for. An approved run
+// continues with the next step, a rejected one fails.
+func (e *Engine) Approve(ctx context.Context, runID string, approved bool, comment string) (*Run, error) {
+	store, err := storeFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	run, err := getRun(ctx, store, runID)
+	if err != nil {
+		return nil, err
+	}
+	if run.Status != RunWaiting {
+		return nil, fmt.Errorf("workflow run %s is %s, not waiting for approval", runID, run.Status)
+	}
+
+	var state *StepState
+	for i := range run.Steps {
+		if run.Steps[i].Status == StepWaiting {
+			state = &run.Steps[i]
+		}
+	}
+	if state == nil {
+		return nil, fmt.Errorf("workflow run %s has no step waiting for approval", runID)
+	}
+
+	now := time.Now()
+	state.Finished = &now
+	output, _ := state.Output.(map[string]any)
+	if output == nil {
+		output = map[string]any{}
+	}
+	output["approved"] = approved
+	if comment != "" {
+		output["comment"] = comment
+	}
+	state.Output = output
+
+	if !approved {
+		state.Status = StepFailed
+		state.Error = "rejected"
+		if comment != "" {
+			state.Error += ": " + comment
+		}
+		run.Status = RunFailed
+		run.Error = fmt.Sprintf("step %s: %s", state.ID, state.Error)
+		if err := saveRun(ctx, store, run); err != nil {
+			return nil, err
+		}
+		e.SendResourceUpdatedNotification(run.URI)
+		return run, nil
+	}
+
+	state.Status = StepCompleted
+	run.Status = RunRunning
+	if err := saveRun(ctx, store, run); err != nil {
+		return nil, err
+	}
+	result := run.clone()
+	if err := e.launch(ctx, store, run); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Resume runs a failed, cancelled, or interrupted run again from the step
+// where it stopped. The steps that completed are not run again.
+func (e *Engine) Resume(ctx context.Context, runID string) (*Run, error) {
+	store, err := storeFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	run, err := getRun(ctx, store, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	_, active := e.active[runID]
+	e.mu.Unlock()
+
+	switch {
+	case active:
+		return nil, fmt.Errorf("workflow run %s is still running", runID)
+	case run.Status == RunCompleted:
+		return nil, fmt.Errorf("workflow run %s already completed", runID)
+	case run.Status == RunWaiting:
+		return nil, fmt.Errorf("workflow run %s is waiting for approval", runID)
+	}
+
+	// Steps that were cut short run again, the ones in progress are
+	// conditions and loops that resume with their own steps.
+	steps := run.Steps[:0]
+	for _, state := range run.Steps {
+		if state.Status != StepFailed {
+			steps = append(steps, state)
+		}
+	}
+	run.Steps = steps
+	run.Status = RunRunning
+	run.Error = ""
+	if err := saveRun(ctx, store, run); err != nil {
+		return nil, err
+	}
+	result := run.clone()
+	if err := e.launch(ctx, store, run); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Cancel stops a run. A run started by another process stops at its next
+// step.
+func (e *Engine) Cancel(ctx context.Context, runID string) (*Run, error) {
+	e.mu.Lock()
+	x := e.active[runID]
+	e.mu.Unlock()
+
+	if x != nil {
+		x.cancel()
+		<-x.done
+		return e.Get(ctx, runID)
+	}
+
+	store, err := storeFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	run, err := getRun(ctx, store, runID)
+	if err != nil {
+		return nil, err
+	}
+	if run.Status == RunCompleted || run.Status == RunFailed || run.Status == RunCancelled {
+		return run, nil
+	}
+	run.Status = RunCancelled
+	run.Error = "cancelled"
+	if err := saveRun(ctx, store, run); err != nil {
+		return nil, err
+	}
+	e.SendResourceUpdatedNotification(run.URI)
+	return run, nil
+}
+
+// launch runs the steps of run in the background. The run outlives the
+// request that started it, but keeps its session.
+func (e *Engine) launch(ctx context.Context, store *session.Store, run *Run) error {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	x := &execution{
+		engine: e,
+		store:  store,
+		run:    run,
+		env:    mcp.SessionFromContext(ctx).GetEnvMap(),
+		steps:  map[string]any{},
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	e.mu.Lock()
+	if _, ok := e.active[run.ID]; ok {
+		e.mu.Unlock()
+		cancel()
+		return fmt.Errorf("workflow run %s is already running", run.ID)
+	}
+	e.active[run.ID] = x
+	e.mu.Unlock()
+
+	go func() {
+		defer func() {
+			e.mu.Lock()
+			delete(e.active, run.ID)
+			e.mu.Unlock()
+			cancel()
+			close(x.done)
+		}()
+		x.execute(ctx)
+	}()
+	return nil
+}
+
+func storeFromContext(ctx context.Context) (*session.Store, error) {
+	var manager session.Manager
+	if !mcp.SessionFromContext(ctx).Root().Get(session.ManagerSessionKey, &manager) || manager.DB == nil {
+		return nil, errors.New("workflow runs need a session store")
+	}
+	return manager.DB, nil
+}
+
+func getRun(ctx context.Context, store *session.Store, runID string) (*Run, error) {
+	execution, err := store.GetWorkflowExecution(ctx, strings.TrimPrefix(runID, RunURIPrefix))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrRunNotFound, runID)
+	} else if err != nil {
+		return nil, err
+	}
+	if sessionID := mcp.SessionFromContext(ctx).Root().ID(); execution.SessionID != sessionID {
+		return nil, fmt.Errorf("%w: %s", ErrRunNotFound, runID)
+	}
+
+	var run Run
+	if err := json.Unmarshal(execution.State, &run); err != nil {
+		return nil, fmt.Errorf("failed to read workflow run %s: %w", runID, err)
+	}
+	return &run, nil
+}
+
+func saveRun(ctx context.Context, store *session.Store, run *Run) error {
+	run.Updated = time.Now()
+	state, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	if err := store.SaveWorkflowExecution(ctx, &session.WorkflowExecution{
+		RunID:       run.ID,
+		SessionID:   run.SessionID,
+		WorkflowURI: run.WorkflowURI,
+		Status:      string(run.Status),
+		State:       state,
+	}); err != nil {
+		return fmt.Errorf("failed to save workflow run %s: %w", run.ID, err)
+	}
+	return nil
+}
+
+// execution is a run being executed by this engine.
+type execution struct {
+	engine *Engine
+	store  *session.Store
+	run    *Run
+	env    map[string]string
+	// steps holds the output and status of every step by ID, for
+	// expressions. In loops it has the ones of the current iteration.
+	steps  map[string]any
+	loop   map[string]any
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func (x *execution) execute(ctx context.Context) {
+	for _, state := range x.run.Steps {
+		if state.Status == StepCompleted {
+			x.setOutput(state.ID, state.Status, state.Output)
+		}
+	}
+
+	err := x.runSteps(ctx, x.run.Definition.Steps, "")
+	switch {
+	case errors.Is(err, errWaiting):
+		x.run.Status = RunWaiting
+	case err == nil:
+		x.run.Status = RunCompleted
+	case ctx.Err() != nil:
+		x.run.Status = RunCancelled
+		x.run.Error = "cancelled"
+	default:
+		x.run.Status = RunFailed
+		x.run.Error = err.Error()
+	}
+	x.save(ctx)
+}
+
+// save stores the run and notifies its subscribers. It also stores the
+// state of runs that were just cancelled.
+func (x *execution) save(ctx context.Context) {
+	if err := saveRun(context.WithoutCancel(ctx), x.store, x.run); err != nil {
+		slog.Error("workflow: failed to save run", "run_id", x.run.ID, "error", err)
+	}
+	x.engine.SendResourceUpdatedNotification(x.run.URI)
+}
+
+func (x *execution) data() map[string]any {
+	data := map[string]any{
+		"inputs": x.run.Inputs,
+		"steps":  x.steps,
+	}
+	if x.loop != nil {
+		data["loop"] = x.loop
+	}
+	return data
+}
+
+func (x *execution) setOutput(id string, status StepStatus, output any) {
+	x.steps[id] = map[string]any{
+		"status": string(status),
+		"output": output,
+	}
+}
+
+func (x *execution) runSteps(ctx context.Context, steps []Step, prefix string) error {
+	for _, step := range steps {
+		if err := x.runStep(ctx, step, prefix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (x *execution) runStep(ctx context.Context, step Step, prefix string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	key := prefix + step.ID
+	state := x.run.step(key)
+	if state != nil && state.Status == StepCompleted {
+		x.setOutput(step.ID, state.Status, state.Output)
+		return nil
+	}
+	if state == nil {
+		x.run.Steps = append(x.run.Steps, StepState{
+			Key:  key,
+			ID:   step.ID,
+			Name: step.Name,
+			Type: step.Type(),
+		})
+		state = &x.run.Steps[len(x.run.Steps)-1]
+	}
+	state.Status = StepRunning
+	state.Error = ""
+	state.Started = time.Now()
+	x.save(ctx)
+
+	output, err := x.do(ctx, step, key)
+	if errors.Is(err, errWaiting) {
+		return err
+	}
+
+	// Nested steps may have grown the step states, so find this one again.
+	state = x.run.step(key)
+	now := time.Now()
+	state.Finished = &now
+	if err != nil {
+		state.Status = StepFailed
+		state.Error = err.Error()
+		x.save(ctx)
+		if _, nested := err.(stepError); nested {
+			return err
+		}
+		return stepError{id: step.ID, err: err}
+	}
+
+	state.Status = StepCompleted
+	state.Output = output
+	x.setOutput(step.ID, state.Status, output)
+	x.save(ctx)
+	return nil
+}
+
+// stepError is the error of the step a run failed at, so the steps around it
+// don't wrap it again.
+type stepError struct {
+	id  string
+	err error
+}
+
+func (e stepError) Error() string {
+	return fmt.Sprintf("step %s: %v", e.id, e.err)
+}
+
+func (e stepError) Unwrap() error {
+	return e.err
+}
+
+func (x *execution) do(ctx context.Context, step Step, key string) (any, error) {
+	switch step.Type() {
+	case StepTool:
+		return x.retry(ctx, step, key, x.callTool)
+	case StepPrompt:
+		return x.retry(ctx, step, key, x.runPrompt)
+	case StepFanOut:
+		return x.retry(ctx, step, key, x.runFanOut)
+	case StepCondition:
+		return x.runCondition(ctx, step, key)
+	case StepLoop:
+		return x.runLoop(ctx, step, key)
+	case StepApproval:
+		return x.waitForApproval(ctx, step, key)
+	}
+	return nil, fmt.Errorf("invalid step %s", step.ID)
+}
+
+// retry tries a step until it succeeds or runs out of retries, waiting
+// longer before every retry.
+func (x *execution) retry(ctx context.Context, step Step, key string, fn func(context.Context, Step) (any, error)) (any, error) {
+	delay := defaultRetryDelay
+	if step.RetryDelayMS > 0 {
+		delay = time.Duration(step.RetryDelayMS) * time.Millisecond
+	}
+
+	for attempt := 0; ; attempt++ {
+		x.run.step(key).Attempts++
+
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if step.TimeoutMS > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, time.Duration(step.TimeoutMS)*time.Millisecond)
+		}
+		output, err := fn(attemptCtx, step)
+		cancel()
+		if err == nil {
+			return output, nil
+		}
+		if ctx.Err() != nil || attempt >= step.MaxRetries {
+			return nil, err
+		}
+
+		slog.Debug("workflow: retrying step", "run_id", x.run.ID, "step", key, "attempt", attempt+1, "error", err)
+		x.run.step(key).Error = err.Error()
+		x.save(ctx)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+func (x *execution) callTool(ctx context.Context, step Step) (any, error) {
+	args, err := expr.EvalObject(ctx, x.env, x.data(), map[string]any(step.Args))
+	if err != nil {
+		return nil, err
+	}
+	server, tool, _ := strings.Cut(step.Tool, "/")
+	if err := x.checkTool(ctx, server, tool); err != nil {
+		return nil, err
+	}
+	result, err := x.engine.tools.Call(ctx, server, tool, args)
+	if err != nil {
+		return nil, err
+	}
+	if result.IsError {
+		return nil, fmt.Errorf("%s failed: %s", step.Tool, resultText(result))
+	}
+	if result.StructuredContent != nil {
+		return toJSONValue(result.StructuredContent)
+	}
+
+	text := resultText(result)
+	var value any
+	if json.Valid([]byte(text)) && json.Unmarshal([]byte(text), &value) == nil {
+		return value, nil
+	}
+	return text, nil
+}
+
+// checkTool checks that the agent running the workflow has the tool, so a
+// workflow can't call tools its agent isn't allowed to.
+func (x *execution) checkTool(ctx context.Context, server, tool string) error {
+	name := types.CurrentAgent(ctx)
+	agent, ok := types.ConfigFromContext(ctx).Agents[name]
+	if !ok {
+		return fmt.Errorf("tool %s/%s can't be called without an agent", server, tool)
+	}
+	mappings, err := x.engine.tools.BuildToolMappings(ctx, slices.Concat(agent.Tools, agent.Agents, agent.MCPServers))
+	if err != nil {
+		return fmt.Errorf("failed to list the tools of agent %s: %w", name, err)
+	}
+	for _, mapping := range mappings {
+		if mapping.MCPServer == server && mapping.TargetName == tool {
+			return nil
+		}
+	}
+	return fmt.Errorf("agent %s doesn't have tool %s/%s", name, server, tool)
+}
+
+func resultText(result *types.CallResult) string {
+	var texts []string
+	for _, content := range result.Content {
+		if content.Text != "" {
+			texts = append(texts, content.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+func (x *execution) runPrompt(ctx context.Context, step Step) (any, error) {
+	prompt, err := x.evalText(ctx, step.Prompt)
+	if err != nil {
+		return nil, err
+	}
+	agent := step.Agent
+	if agent == "" {
+		agent = types.CurrentAgent(ctx)
+	}
+	maxTurns := step.MaxTurns
+	if maxTurns == 0 {
+		maxTurns = defaultAgentMaxTurns
+	}
+
+	result, err := x.engine.agents.RunTask(ctx, agents.Task{
+		Agent:    agent,
+		Prompt:   prompt,
+		Tools:    step.Tools,
+		MaxTurns: maxTurns,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.Report, nil
+}
+
+func (x *execution) runFanOut(ctx context.Context, step Step) (any, error) {
+	prompt, err := x.evalText(ctx, step.FanOut.Prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	fanOut := agents.FanOut{
+		Prompt: prompt,
+	}
+	for _, branch := range step.FanOut.Branches {
+		branchPrompt, err := x.evalText(ctx, branch.Prompt)
+		if err != nil {
+			return nil, err
+		}
+		agent := branch.Agent
+		if agent == "" {
+			agent = types.CurrentAgent(ctx)
+		}
+		maxTurns := branch.MaxTurns
+		if maxTurns == 0 {
+			maxTurns = defaultAgentMaxTurns
+		}
+		fanOut.Branches = append(fanOut.Branches, agents.Branch{
+			Agent:    agent,
+			Prompt:   branchPrompt,
+			Tools:    branch.Tools,
+			Timeout:  time.Duration(branch.TimeoutMS) * time.Millisecond,
+			MaxTurns: maxTurns,
+		})
+	}
+
+	result, err := x.engine.agents.RunFanOut(ctx, fanOut)
+	if err != nil {
+		return nil, err
+	}
+	return toJSONValue(result)
+}
+
+func (x *execution) runCondition(ctx context.Context, step Step, key string) (any, error) {
+	ok, err := expr.EvalBool(ctx, x.env, x.data(), step.Condition.If)
+	if err != nil {
+		return nil, err
+	}
+	steps := step.Condition.Else
+	if ok {
+		steps = step.Condition.Then
+	}
+	if err := x.runSteps(ctx, steps, key+"/"); err != nil {
+		return nil, err
+	}
+	return ok, nil
+}
+
+func (x *execution) runLoop(ctx context.Context, step Step, key string) (any, error) {
+	maxIterations := step.Loop.MaxIterations
+	if maxIterations == 0 {
+		maxIterations = MaxLoopIterations
+	}
+
+	var items []any
+	if step.Loop.ForEach != nil {
+		var err error
+		items, err = expr.EvalList(ctx, x.env, x.data(), step.Loop.ForEach)
+		if err != nil {
+			return nil, err
+		}
+		if len(items) > maxIterations {
+			return nil, fmt.Errorf("the loop has %d items, more than the %d iterations it may run", len(items), maxIterations)
+		}
+	}
+
+	outer := x.loop
+	defer func() {
+		x.loop = outer
+	}()
+
+	var iterations []any
+	for i := 0; i < maxIterations; i++ {
+		if step.Loop.ForEach != nil && i >= len(items) {
+			break
+		}
+
+		x.loop = map[string]any{"index": i}
+		if step.Loop.ForEach != nil {
+			x.loop["item"] = items[i]
+		}
+		if err := x.runSteps(ctx, step.Loop.Steps, fmt.Sprintf("%s[%d]/", key, i)); err != nil {
+			return nil, err
+		}
+
+		outputs := map[string]any{}
+		for _, inner := range step.Loop.Steps {
+			if output, ok := x.steps[inner.ID].(map[string]any); ok {
+				outputs[inner.ID] = output["output"]
+			}
+		}
+		iterations = append(iterations, outputs)
+
+		if step.Loop.Until != "" {
+			done, err := expr.EvalBool(ctx, x.env, x.data(), step.Loop.Until)
+			if err != nil {
+				return nil, err
+			}
+			if done {
+				return iterations, nil
+			}
+		}
+	}
+
+	if step.Loop.Until != "" {
+		return nil, fmt.Errorf("the loop didn't finish within %d iterations", maxIterations)
+	}
+	return iterations, nil
+}
+
+// waitForApproval asks for the approval of the step and stops the run until
+// it is approved.
+func (x *execution) waitForApproval(ctx context.Context, step Step, key string) (any, error) {
+	message, err := x.evalText(ctx, step.Approval.Message)
+	if err != nil {
+		return nil, err
+	}
+	state := x.run.step(key)
+	state.Status = StepWaiting
+	state.Output = map[string]any{"message": message}
+	return nil, errWaiting
+}
+
+// evalText evaluates the expressions of a prompt or message. Values that
+// aren't strings are added as JSON.
+func (x *execution) evalText(ctx context.Context, text string) (string, error) {
+	value, err := expr.EvalAny(ctx, x.env, x.data(), text)
+	if err != nil {
+		return "", err
+	}
+	switch value := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return value, nil
+	default:
+		data, err := json.Marshal(value)
+		return string(data), err
+	}
+}
+
+// toJSONValue converts a value to the maps, slices, and scalars it is stored
+// as, so expressions see the same value before and after a run resumes.
+func toJSONValue(value any) (any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var result any
+	return result, json.Unmarshal(data, &result)
+}
@@ -0,0 +1,292 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/obot-platform/nanobot/pkg/agents"
+	"github.com/obot-platform/nanobot/pkg/mcp"
+	"github.com/obot-platform/nanobot/pkg/session"
+	"github.com/obot-platform/nanobot/pkg/tools"
+	"github.com/obot-platform/nanobot/pkg/types"
+)
+
+type fakeRunner struct {
+	mu       sync.Mutex
+	calls    []string
+	failures map[string]int
+}
+
+func (f *fakeRunner) Call(_ context.Context, server, tool string, args any, _ ...tools.CallOptions) (*types.CallResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, server+"/"+tool)
+	if f.failures[tool] > 0 {
+		f.failures[tool]--
+		return nil, errors.New("unavailable")
+	}
+	if tool == "list" {
+		return &types.CallResult{Content: []mcp.Content{{Type: "text", Text: `["a", "b"]`}}}, nil
+	}
+	name, _ := args.(map[string]any)["name"].(string)
+	return &types.CallResult{Content: []mcp.Content{{Type: "text", Text: "hello " + name}}}, nil
+}
+
+// fakeTools are the tools of the servers of fakeRunner.
+var fakeTools = map[string][]string{
+	"people": {"list", "greet"},
+	"ops":    {"deploy"},
+	"admin":  {"wipe"},
+}
+
+func (f *fakeRunner) BuildToolMappings(_ context.Context, toolList []string, _ ...types.BuildToolMappingsOptions) (types.ToolMappings, error) {
+	mappings := types.ToolMappings{}
+	for _, server := range toolList {
+		for _, tool := range fakeTools[server] {
+			mappings[tool] = types.TargetMapping[types.TargetTool]{MCPServer: server, TargetName: tool}
+		}
+	}
+	return mappings, nil
+}
+
+func (f *fakeRunner) RunTask(_ context.Context, task agents.Task) (*agents.TaskResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, task.Agent)
+	return &agents.TaskResult{Report: "reviewed: " + task.Prompt}, nil
+}
+
+func (f *fakeRunner) RunFanOut(context.Context, agents.FanOut) (*agents.FanOutResult, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeRunner) count(call string) (n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range f.calls {
+		if c == call {
+			n++
+		}
+	}
+	return
+}
+
+func newTestContext(t *testing.T) context.Context {
+	t.Helper()
+
+	store, err := session.NewStoreFromDSN("sqlite::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverSession, err := mcp.NewExistingServerSession(t.Context(), mcp.SessionState{ID: "test-session"}, mcp.MessageHandlerFunc(func(context.Context, mcp.Message) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { serverSession.Close(false) })
+
+	serverSession.GetSession().Set(session.ManagerSessionKey, session.NewManager(store))
+	serverSession.GetSession().Set(types.CurrentAgentSessionKey, "main")
+	return types.WithConfig(mcp.WithSession(t.Context(), serverSession.GetSession()), types.Config{
+		Agents: map[string]types.Agent{
+			"main": {HookAgent: types.HookAgent{MCPServers: []string{"people", "ops"}}},
+		},
+	})
+}
+
+func TestEngine(t *testing.T) {
+	def, err := ParseDefinition([]byte(`
+inputs:
+  greeting:
+    default: hi
+steps:
+- id: names
+  tool: people/list
+  maxRetries: 2
+  retryDelayMs: 1
+- id: each
+  loop:
+    forEach: ${steps.names.output}
+    steps:
+    - id: greet
+      tool: people/greet
+      args:
+        name: ${loop.item}
+- id: check
+  condition:
+    if: ${steps.each.output.length == 2}
+    then:
+    - id: approve
+      approval:
+        message: Send ${inputs.greeting} to ${steps.each.output.length} people?
+- id: review
+  agent: reviewer
+  prompt: ${steps.each.output[1].greet}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runner := &fakeRunner{failures: map[string]int{"list": 1, "greet": 1}}
+	engine := NewEngine(runner, runner)
+	ctx := newTestContext(t)
+
+	run, err := engine.Start(ctx, "workflow:///greet", *def, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run, err = engine.Wait(ctx, run.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != RunFailed || !strings.Contains(run.Error, "step greet") {
+		t.Fatalf("expected the run to fail at the greet step that isn't retried, got %s: %s", run.Status, run.Error)
+	}
+	if n := runner.count("people/list"); n != 2 {
+		t.Fatalf("expected the list step to be retried once, got %d calls", n)
+	}
+
+	if _, err := engine.Resume(ctx, run.ID); err != nil {
+		t.Fatal(err)
+	}
+	run, err = engine.Wait(ctx, run.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != RunWaiting {
+		t.Fatalf("expected the run to wait for approval, got %s: %s", run.Status, run.Error)
+	}
+	if n := runner.count("people/list"); n != 2 {
+		t.Fatalf("expected the completed list step not to run again, got %d calls", n)
+	}
+	if output := run.step("check/approve").Output.(map[string]any); output["message"] != "Send hi to 2 people?" {
+		t.Fatalf("unexpected approval message %v", output["message"])
+	}
+
+	if _, err := engine.Approve(ctx, run.ID, true, "go ahead"); err != nil {
+		t.Fatal(err)
+	}
+	run, err = engine.Wait(ctx, run.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != RunCompleted {
+		t.Fatalf("expected the run to complete, got %s: %s", run.Status, run.Error)
+	}
+	if output := run.step("review").Output; output != "reviewed: hello b" {
+		t.Errorf("unexpected review output %v", output)
+	}
+	if runner.count("people/greet") != 3 || runner.count("reviewer") != 1 {
+		t.Errorf("unexpected calls %v", runner.calls)
+	}
+
+	runs, err := engine.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 || runs[0].ID != run.ID || runs[0].Status != RunCompleted {
+		t.Errorf("unexpected runs %+v", runs)
+	}
+}
+
+func TestApproveRejected(t *testing.T) {
+	def, err := ParseDefinition([]byte(`
+steps:
+- id: approve
+  approval:
+    message: Deploy?
+- id: deploy
+  tool: ops/deploy
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runner := &fakeRunner{}
+	engine := NewEngine(runner, runner)
+	ctx := newTestContext(t)
+
+	run, err := engine.Start(ctx, "workflow:///deploy", *def, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.Wait(ctx, run.ID); err != nil {
+		t.Fatal(err)
+	}
+	run, err = engine.Approve(ctx, run.ID, false, "not today")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != RunFailed || run.Error != "step approve: rejected: not today" {
+		t.Errorf("expected the rejected run to fail, got %s: %s", run.Status, run.Error)
+	}
+	if runner.count("ops/deploy") != 0 {
+		t.Error("expected the step after the rejected approval not to run")
+	}
+}
+
+func TestParseDefinition(t *testing.T) {
+	for _, tt := range []struct {
+		name, definition, err string
+	}{
+		{"no steps", `steps: []`, "at least one step"},
+		{"unknown field", "steps:\n- id: a\n  tool: s/t\n  retries: 2", "unknown field"},
+		{"two kinds", "steps:\n- id: a\n  tool: s/t\n  prompt: hi", "exactly one of"},
+		{"duplicate id", "steps:\n- id: a\n  tool: s/t\n- id: b\n  loop:\n    forEach: [1]\n    steps:\n    - id: a\n      tool: s/t", `"a" is used more than once`},
+		{"invalid id", "steps:\n- id: my-step\n  tool: s/t", "must start with a letter"},
+		{"tool reference", "steps:\n- id: a\n  tool: t", "server/tool"},
+		{"loop kind", "steps:\n- id: a\n  loop:\n    steps:\n    - id: b\n      tool: s/t", "exactly one of forEach or until"},
+		{"retried approval", "steps:\n- id: a\n  maxRetries: 1\n  approval:\n    message: ok?", "only apply to tool"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseDefinition([]byte(tt.definition)); err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("expected an error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+
+	def, err := ParseDefinition([]byte("inputs:\n  env:\n    required: true\nsteps:\n- id: a\n  tool: s/t"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := def.ApplyInputs(nil); err == nil {
+		t.Error("expected a missing required input to fail")
+	}
+	if _, err := def.ApplyInputs(map[string]any{"env": "prod", "other": 1}); err == nil {
+		t.Error("expected an unknown input to fail")
+	}
+}
+
+func TestToolStepNeedsAgentTool(t *testing.T) {
+	def, err := ParseDefinition([]byte(`
+steps:
+- id: wipe
+  tool: admin/wipe
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runner := &fakeRunner{}
+	engine := NewEngine(runner, runner)
+	ctx := newTestContext(t)
+
+	run, err := engine.Start(ctx, "workflow:///wipe", *def, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run, err = engine.Wait(ctx, run.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != RunFailed || !strings.Contains(run.Error, "doesn't have tool admin/wipe") {
+		t.Fatalf("expected the run to fail at a tool its agent doesn't have, got %s: %s", run.Status, run.Error)
+	}
+	if n := runner.count("admin/wipe"); n != 0 {
+		t.Errorf("expected the tool not to be called, got %d calls", n)
+	}
+}